@@ -0,0 +1,7 @@
+-- +goose Up
+CREATE INDEX idx_changelog_relation on changelog (store, object_type, relation, ulid);
+CREATE INDEX idx_changelog_user on changelog (store, _user, ulid);
+
+-- +goose Down
+DROP INDEX idx_changelog_relation on changelog;
+DROP INDEX idx_changelog_user on changelog;
@@ -0,0 +1,421 @@
+// Package bench contains the command to benchmark Check and ListObjects with a synthetic store.
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	parser "github.com/openfga/language/pkg/go/transformer"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/openfga/openfga/internal/build"
+	serverconfig "github.com/openfga/openfga/internal/server/config"
+	"github.com/openfga/openfga/pkg/server"
+)
+
+const (
+	serverAddrFlag                       = "server-addr"
+	metricsAddrFlag                      = "metrics-addr"
+	apiTokenFlag                         = "api-token"
+	seedFlag                             = "seed"
+	typesFlag                            = "types"
+	objectsFlag                          = "objects"
+	usersFlag                            = "users"
+	groupsFlag                           = "groups"
+	fanOutFlag                           = "fan-out"
+	depthFlag                            = "depth"
+	checksFlag                           = "checks"
+	listObjectsFlag                      = "list-objects"
+	concurrencyFlag                      = "concurrency"
+	resolveNodeLimitFlag                 = "resolve-node-limit"
+	resolveNodeBreadthLimitFlag          = "resolve-node-breadth-limit"
+	maxConcurrentReadsForCheckFlag       = "max-concurrent-reads-for-check"
+	maxConcurrentReadsForListObjectsFlag = "max-concurrent-reads-for-list-objects"
+	checkQueryCacheEnabledFlag           = "check-query-cache-enabled"
+
+	// writeBatchSize is the number of tuples written per Write, the default maximum of the server.
+	writeBatchSize = serverconfig.DefaultMaxTuplesPerWrite
+)
+
+func NewBenchCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bench",
+		Short: "Benchmark Check and ListObjects with a synthetic store. NOTE: this command is in beta and may be removed in future releases.",
+		Long: "Generate a synthetic store, with configurable type counts, fan-out and nesting depth, and replay a Check and ListObjects workload against it, " +
+			"on a running server or on an in-process one, reporting the latency percentiles and the dispatch counts.\n" +
+			"The store and the workload only depend on the flags, so the runs with the same flags are comparable, e.g. when tuning the resolver flags.\n" +
+			"NOTE: this command is in beta and may be removed in future releases.",
+		RunE: runBench,
+		Args: cobra.NoArgs,
+	}
+
+	defaultConfig := serverconfig.DefaultConfig()
+
+	flags := cmd.Flags()
+	flags.String(serverAddrFlag, "", "the gRPC address of a running server to benchmark, e.g. 'localhost:8081'. If empty, an in-process server with a memory datastore is benchmarked")
+	flags.String(metricsAddrFlag, "", "the address of the metrics endpoint of the running server, e.g. 'localhost:2112', to report the dispatch counts")
+	flags.String(apiTokenFlag, "", "the preshared key to authenticate to the running server with, if any")
+
+	flags.Int64(seedFlag, 1, "the seed of the generated store and workload")
+	flags.Int(typesFlag, 3, "the number of resource types of the generated model")
+	flags.Int(objectsFlag, 100, "the number of objects of each resource type")
+	flags.Int(usersFlag, 1000, "the number of users")
+	flags.Int(groupsFlag, 100, "the number of groups")
+	flags.Int(fanOutFlag, 10, "the number of viewers of each object and of members of each group")
+	flags.Int(depthFlag, 3, "the number of levels of the hierarchies of the objects and of the groups. 1 means no nesting")
+	flags.Int(checksFlag, 1000, "the number of Check requests of the workload")
+	flags.Int(listObjectsFlag, 100, "the number of ListObjects requests of the workload")
+	flags.Int(concurrencyFlag, 10, "the number of requests made concurrently")
+
+	flags.Uint32(resolveNodeLimitFlag, defaultConfig.ResolveNodeLimit, "the resolve node limit of the in-process server")
+	flags.Uint32(resolveNodeBreadthLimitFlag, defaultConfig.ResolveNodeBreadthLimit, "the resolve node breadth limit of the in-process server")
+	flags.Uint32(maxConcurrentReadsForCheckFlag, defaultConfig.MaxConcurrentReadsForCheck, "the maximum number of concurrent datastore reads of a Check of the in-process server")
+	flags.Uint32(maxConcurrentReadsForListObjectsFlag, defaultConfig.MaxConcurrentReadsForListObjects, "the maximum number of concurrent datastore reads of a ListObjects of the in-process server")
+	flags.Bool(checkQueryCacheEnabledFlag, defaultConfig.CheckQueryCache.Enabled, "enable the check query cache of the in-process server")
+
+	// NOTE: if you add a new flag here, update the function below, too
+
+	cmd.PreRun = bindRunFlagsFunc(flags)
+
+	return cmd
+}
+
+// report is the result of a benchmark, printed as JSON.
+type report struct {
+	Seed        int64     `json:"seed"`
+	Tuples      int       `json:"tuples"`
+	Check       apiReport `json:"check"`
+	ListObjects apiReport `json:"list_objects"`
+}
+
+type apiReport struct {
+	Requests     int     `json:"requests"`
+	Errors       int     `json:"errors"`
+	FirstError   string  `json:"first_error,omitempty"`
+	LatencyP50Ms float64 `json:"latency_p50_ms"`
+	LatencyP90Ms float64 `json:"latency_p90_ms"`
+	LatencyP99Ms float64 `json:"latency_p99_ms"`
+	LatencyMaxMs float64 `json:"latency_max_ms"`
+
+	// MeanDispatchCount is not reported for a running server whose metrics endpoint is not given.
+	MeanDispatchCount *float64 `json:"mean_dispatch_count,omitempty"`
+}
+
+// dispatchTotals are the sum and the count of the dispatch count histogram of a method.
+type dispatchTotals struct {
+	sum   float64
+	count uint64
+}
+
+// target is the server the workload is replayed against.
+type target struct {
+	client openfgav1.OpenFGAServiceClient
+
+	// dispatchTotals returns the dispatchTotals by method (e.g. 'check'), nil if they are not available.
+	dispatchTotals func(ctx context.Context) (map[string]dispatchTotals, error)
+
+	close func()
+}
+
+func runBench(cmd *cobra.Command, _ []string) error {
+	opts := workloadOptions{
+		seed:        viper.GetInt64(seedFlag),
+		types:       viper.GetInt(typesFlag),
+		objects:     viper.GetInt(objectsFlag),
+		users:       viper.GetInt(usersFlag),
+		groups:      viper.GetInt(groupsFlag),
+		fanOut:      viper.GetInt(fanOutFlag),
+		depth:       viper.GetInt(depthFlag),
+		checks:      viper.GetInt(checksFlag),
+		listObjects: viper.GetInt(listObjectsFlag),
+	}
+	if err := opts.validate(); err != nil {
+		return err
+	}
+
+	concurrency := viper.GetInt(concurrencyFlag)
+	if concurrency < 1 {
+		return fmt.Errorf("the concurrency must be at least 1")
+	}
+
+	ctx := context.Background()
+	if token := viper.GetString(apiTokenFlag); token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+	}
+
+	t, err := newTarget()
+	if err != nil {
+		return err
+	}
+	defer t.close()
+
+	res, err := bench(ctx, t, opts, concurrency)
+	if err != nil {
+		return err
+	}
+
+	marshalled, err := json.MarshalIndent(res, "", "    ")
+	if err != nil {
+		return fmt.Errorf("error marshalling the report: %w", err)
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), string(marshalled))
+
+	return nil
+}
+
+func newTarget() (*target, error) {
+	addr := viper.GetString(serverAddrFlag)
+	if addr == "" {
+		embedded, err := server.NewEmbeddedServer(nil,
+			server.WithResolveNodeLimit(viper.GetUint32(resolveNodeLimitFlag)),
+			server.WithResolveNodeBreadthLimit(viper.GetUint32(resolveNodeBreadthLimitFlag)),
+			server.WithMaxConcurrentReadsForCheck(viper.GetUint32(maxConcurrentReadsForCheckFlag)),
+			server.WithMaxConcurrentReadsForListObjects(viper.GetUint32(maxConcurrentReadsForListObjectsFlag)),
+			server.WithCheckQueryCacheEnabled(viper.GetBool(checkQueryCacheEnabledFlag)),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start the in-process server: %w", err)
+		}
+
+		return &target{
+			client: embedded.Client(),
+			dispatchTotals: func(context.Context) (map[string]dispatchTotals, error) {
+				families, err := prometheus.DefaultGatherer.Gather()
+				if err != nil {
+					return nil, err
+				}
+				return dispatchTotalsOf(families), nil
+			},
+			close: embedded.Close,
+		}, nil
+	}
+
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the server: %w", err)
+	}
+
+	t := &target{
+		client:         openfgav1.NewOpenFGAServiceClient(conn),
+		dispatchTotals: func(context.Context) (map[string]dispatchTotals, error) { return nil, nil },
+		close:          func() { _ = conn.Close() },
+	}
+
+	if metricsAddr := viper.GetString(metricsAddrFlag); metricsAddr != "" {
+		t.dispatchTotals = func(ctx context.Context) (map[string]dispatchTotals, error) {
+			return scrapeDispatchTotals(ctx, "http://"+metricsAddr+"/metrics")
+		}
+	}
+
+	return t, nil
+}
+
+// bench creates the store of the workload on the target and replays the requests of the workload against it.
+func bench(ctx context.Context, t *target, opts workloadOptions, concurrency int) (*report, error) {
+	w := generateWorkload(opts)
+
+	model, err := parser.TransformDSLToProto(w.model)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate the model: %w", err)
+	}
+
+	store, err := t.client.CreateStore(ctx, &openfgav1.CreateStoreRequest{Name: fmt.Sprintf("bench-%d", opts.seed)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the store: %w", err)
+	}
+	storeID := store.GetId()
+
+	writtenModel, err := t.client.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:         storeID,
+		SchemaVersion:   model.GetSchemaVersion(),
+		TypeDefinitions: model.GetTypeDefinitions(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to write the model: %w", err)
+	}
+	modelID := writtenModel.GetAuthorizationModelId()
+
+	for i := 0; i < len(w.tuples); i += writeBatchSize {
+		_, err := t.client.Write(ctx, &openfgav1.WriteRequest{
+			StoreId:              storeID,
+			AuthorizationModelId: modelID,
+			Writes:               &openfgav1.WriteRequestWrites{TupleKeys: w.tuples[i:min(i+writeBatchSize, len(w.tuples))]},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to write the tuples: %w", err)
+		}
+	}
+
+	res := &report{Seed: opts.seed, Tuples: len(w.tuples)}
+
+	before, err := t.dispatchTotals(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the dispatch counts: %w", err)
+	}
+
+	res.Check = replay(ctx, concurrency, len(w.checks), func(ctx context.Context, i int) error {
+		_, err := t.client.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:              storeID,
+			AuthorizationModelId: modelID,
+			TupleKey:             w.checks[i],
+		})
+		return err
+	})
+
+	res.ListObjects = replay(ctx, concurrency, len(w.listObjects), func(ctx context.Context, i int) error {
+		q := w.listObjects[i]
+		_, err := t.client.ListObjects(ctx, &openfgav1.ListObjectsRequest{
+			StoreId:              storeID,
+			AuthorizationModelId: modelID,
+			Type:                 q.objectType,
+			Relation:             q.relation,
+			User:                 q.user,
+		})
+		return err
+	})
+
+	after, err := t.dispatchTotals(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the dispatch counts: %w", err)
+	}
+
+	if before != nil && after != nil {
+		res.Check.MeanDispatchCount = meanDispatchCount(before["check"], after["check"])
+		res.ListObjects.MeanDispatchCount = meanDispatchCount(before["listobjects"], after["listobjects"])
+	}
+
+	return res, nil
+}
+
+// replay makes the n calls, concurrency at a time, and reports their latencies and errors.
+func replay(ctx context.Context, concurrency, n int, call func(ctx context.Context, i int) error) apiReport {
+	latencies := make([]time.Duration, n)
+
+	var (
+		next       atomic.Int64
+		errs       atomic.Int64
+		firstError atomic.Value
+		wg         sync.WaitGroup
+	)
+	for c := 0; c < concurrency; c++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= n {
+					return
+				}
+
+				start := time.Now()
+				if err := call(ctx, i); err != nil {
+					errs.Add(1)
+					firstError.CompareAndSwap(nil, err.Error())
+				}
+				latencies[i] = time.Since(start)
+			}
+		}()
+	}
+	wg.Wait()
+
+	res := apiReport{Requests: n, Errors: int(errs.Load())}
+	if err, ok := firstError.Load().(string); ok {
+		res.FirstError = err
+	}
+
+	if n == 0 {
+		return res
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	percentile := func(p float64) float64 {
+		i := int(p*float64(n)+0.5) - 1
+		return float64(latencies[max(i, 0)].Microseconds()) / 1000
+	}
+	res.LatencyP50Ms = percentile(0.5)
+	res.LatencyP90Ms = percentile(0.9)
+	res.LatencyP99Ms = percentile(0.99)
+	res.LatencyMaxMs = float64(latencies[n-1].Microseconds()) / 1000
+
+	return res
+}
+
+func meanDispatchCount(before, after dispatchTotals) *float64 {
+	if after.count <= before.count {
+		return nil
+	}
+
+	mean := (after.sum - before.sum) / float64(after.count-before.count)
+	return &mean
+}
+
+// dispatchTotalsOf returns the totals of the dispatch count histogram of the server among the metric families,
+// by method.
+func dispatchTotalsOf(families []*dto.MetricFamily) map[string]dispatchTotals {
+	res := make(map[string]dispatchTotals)
+	for _, family := range families {
+		if family.GetName() != build.ProjectName+"_dispatch_count" {
+			continue
+		}
+
+		for _, m := range family.GetMetric() {
+			for _, label := range m.GetLabel() {
+				if label.GetName() != "grpc_method" {
+					continue
+				}
+
+				totals := res[label.GetValue()]
+				totals.sum += m.GetHistogram().GetSampleSum()
+				totals.count += m.GetHistogram().GetSampleCount()
+				res[label.GetValue()] = totals
+			}
+		}
+	}
+
+	return res
+}
+
+// scrapeDispatchTotals returns the totals of the dispatch count histogram exposed by the metrics endpoint at url.
+func scrapeDispatchTotals(ctx context.Context, url string) (map[string]dispatchTotals, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s from %s", resp.Status, url)
+	}
+
+	var textParser expfmt.TextParser
+	families, err := textParser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*dto.MetricFamily, 0, len(families))
+	for _, family := range families {
+		res = append(res, family)
+	}
+
+	return dispatchTotalsOf(res), nil
+}
@@ -0,0 +1,100 @@
+package bench
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/openfga/openfga/pkg/tuple"
+)
+
+func TestGenerateWorkload(t *testing.T) {
+	opts := workloadOptions{
+		seed:        1,
+		types:       2,
+		objects:     20,
+		users:       50,
+		groups:      10,
+		fanOut:      3,
+		depth:       3,
+		checks:      10,
+		listObjects: 5,
+	}
+	require.NoError(t, opts.validate())
+
+	w := generateWorkload(opts)
+	require.Len(t, w.checks, 10)
+	require.Len(t, w.listObjects, 5)
+	require.NotEmpty(t, w.tuples)
+
+	seen := make(map[string]struct{})
+	for _, tk := range w.tuples {
+		key := tuple.TupleKeyToString(tk)
+		require.NotContains(t, seen, key)
+		seen[key] = struct{}{}
+
+		// the parent of an object is one level up, so that the hierarchies are acyclic and of the depth
+		if tk.GetRelation() == "parent" {
+			_, objectID := tuple.SplitObject(tk.GetObject())
+			_, parentID := tuple.SplitObject(tk.GetUser())
+			require.Equal(t, levelOf(t, objectID)-1, levelOf(t, parentID), key)
+		}
+	}
+
+	t.Run("same_seed_same_workload", func(t *testing.T) {
+		require.Equal(t, w, generateWorkload(opts))
+	})
+
+	t.Run("other_seed_other_workload", func(t *testing.T) {
+		opts := opts
+		opts.seed = 2
+		require.NotEqual(t, w.tuples, generateWorkload(opts).tuples)
+	})
+
+	t.Run("invalid_options", func(t *testing.T) {
+		opts := opts
+		opts.depth = 0
+		require.EqualError(t, opts.validate(), "the depth must be at least 1")
+	})
+}
+
+func levelOf(t *testing.T, id string) int {
+	i, err := strconv.Atoi(id)
+	require.NoError(t, err)
+	return i % 3
+}
+
+func TestBenchCommand(t *testing.T) {
+	benchCmd := NewBenchCommand()
+
+	var out bytes.Buffer
+	benchCmd.SetOut(&out)
+	benchCmd.SetArgs([]string{
+		"--types", "2",
+		"--objects", "20",
+		"--users", "50",
+		"--groups", "10",
+		"--fan-out", "3",
+		"--checks", "50",
+		"--list-objects", "10",
+		"--concurrency", "4",
+	})
+	require.NoError(t, benchCmd.Execute())
+
+	var res report
+	require.NoError(t, json.Unmarshal(out.Bytes(), &res))
+	require.Equal(t, int64(1), res.Seed)
+	require.Positive(t, res.Tuples)
+
+	require.Equal(t, 50, res.Check.Requests)
+	require.Zero(t, res.Check.Errors)
+	require.LessOrEqual(t, res.Check.LatencyP50Ms, res.Check.LatencyMaxMs)
+	require.NotNil(t, res.Check.MeanDispatchCount)
+
+	require.Equal(t, 10, res.ListObjects.Requests)
+	require.Zero(t, res.ListObjects.Errors)
+	require.NotNil(t, res.ListObjects.MeanDispatchCount)
+}
@@ -0,0 +1,37 @@
+package bench
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/openfga/openfga/cmd/util"
+)
+
+// bindRunFlagsFunc binds the cobra cmd flags to the equivalent config value being managed
+// by viper. This bridges the config between cobra flags and viper flags.
+func bindRunFlagsFunc(flags *pflag.FlagSet) func(*cobra.Command, []string) {
+	return func(cmd *cobra.Command, args []string) {
+		for _, flag := range []string{
+			serverAddrFlag,
+			metricsAddrFlag,
+			apiTokenFlag,
+			seedFlag,
+			typesFlag,
+			objectsFlag,
+			usersFlag,
+			groupsFlag,
+			fanOutFlag,
+			depthFlag,
+			checksFlag,
+			listObjectsFlag,
+			concurrencyFlag,
+			resolveNodeLimitFlag,
+			resolveNodeBreadthLimitFlag,
+			maxConcurrentReadsForCheckFlag,
+			maxConcurrentReadsForListObjectsFlag,
+			checkQueryCacheEnabledFlag,
+		} {
+			util.MustBindPFlag(flag, flags.Lookup(flag))
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package bench
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+
+	"github.com/openfga/openfga/pkg/tuple"
+)
+
+// workloadOptions are the parameters of a synthetic store and of the requests replayed against it.
+type workloadOptions struct {
+	seed int64
+
+	// types is the number of resource types of the model.
+	types int
+
+	// objects is the number of objects of each resource type, users the number of users and groups the
+	// number of groups.
+	objects int
+	users   int
+	groups  int
+
+	// fanOut is the number of viewers of each object and of members of each group.
+	fanOut int
+
+	// depth is the number of levels of the hierarchies of the objects, through their parent, and of the
+	// groups, through their member groups. 1 means no nesting.
+	depth int
+
+	// checks and listObjects are the numbers of requests of the workload.
+	checks      int
+	listObjects int
+}
+
+func (o workloadOptions) validate() error {
+	switch {
+	case o.types < 1:
+		return fmt.Errorf("the number of types must be at least 1")
+	case o.objects < 1:
+		return fmt.Errorf("the number of objects must be at least 1")
+	case o.users < 1:
+		return fmt.Errorf("the number of users must be at least 1")
+	case o.groups < 0:
+		return fmt.Errorf("the number of groups must not be negative")
+	case o.fanOut < 1:
+		return fmt.Errorf("the fan-out must be at least 1")
+	case o.depth < 1:
+		return fmt.Errorf("the depth must be at least 1")
+	case o.checks < 0 || o.listObjects < 0:
+		return fmt.Errorf("the number of requests must not be negative")
+	}
+
+	return nil
+}
+
+// workload is a synthetic store and the requests replayed against it. It only depends on the options it is
+// generated with, so that the runs with the same options are comparable.
+type workload struct {
+	model       string
+	tuples      []*openfgav1.TupleKey
+	checks      []*openfgav1.CheckRequestTupleKey
+	listObjects []listObjectsQuery
+}
+
+type listObjectsQuery struct {
+	objectType string
+	relation   string
+	user       string
+}
+
+// generateWorkload generates the workload of the options. The model has a 'group' type, whose members may be
+// users or the members of other groups, and the resource types 'resource0' to 'resourceN', whose viewers are
+// users, group members and the viewers of their parent. All the requests are about the viewers.
+func generateWorkload(o workloadOptions) *workload {
+	r := rand.New(rand.NewSource(o.seed))
+	w := &workload{}
+
+	var model strings.Builder
+	model.WriteString("model\n  schema 1.1\n\ntype user\n\ntype group\n  relations\n    define member: [user, group#member]\n")
+	for t := 0; t < o.types; t++ {
+		fmt.Fprintf(&model, "\ntype %s\n  relations\n    define parent: [%s]\n    define viewer: [user, group#member] or viewer from parent\n",
+			resourceType(t), resourceType(t))
+	}
+	w.model = model.String()
+
+	seen := make(map[string]struct{})
+	add := func(tk *openfgav1.TupleKey) {
+		key := tuple.TupleKeyToString(tk)
+		if _, ok := seen[key]; ok {
+			return
+		}
+		seen[key] = struct{}{}
+		w.tuples = append(w.tuples, tk)
+	}
+
+	randomUser := func() string {
+		return fmt.Sprintf("user:%d", r.Intn(o.users))
+	}
+
+	for g := 0; g < o.groups; g++ {
+		group := fmt.Sprintf("group:%d", g)
+		if level := g % o.depth; level > 0 {
+			add(tuple.NewTupleKey(group, "member", fmt.Sprintf("group:%d#member", pickAtLevel(r, o.groups, o.depth, level-1))))
+		}
+		for i := 0; i < o.fanOut; i++ {
+			add(tuple.NewTupleKey(group, "member", randomUser()))
+		}
+	}
+
+	for t := 0; t < o.types; t++ {
+		for i := 0; i < o.objects; i++ {
+			object := fmt.Sprintf("%s:%d", resourceType(t), i)
+			if level := i % o.depth; level > 0 {
+				add(tuple.NewTupleKey(object, "parent", fmt.Sprintf("%s:%d", resourceType(t), pickAtLevel(r, o.objects, o.depth, level-1))))
+			}
+			for j := 0; j < o.fanOut; j++ {
+				if o.groups > 0 && r.Intn(2) == 0 {
+					add(tuple.NewTupleKey(object, "viewer", fmt.Sprintf("group:%d#member", r.Intn(o.groups))))
+					continue
+				}
+				add(tuple.NewTupleKey(object, "viewer", randomUser()))
+			}
+		}
+	}
+
+	for i := 0; i < o.checks; i++ {
+		object := fmt.Sprintf("%s:%d", resourceType(r.Intn(o.types)), r.Intn(o.objects))
+		w.checks = append(w.checks, tuple.NewCheckRequestTupleKey(object, "viewer", randomUser()))
+	}
+
+	for i := 0; i < o.listObjects; i++ {
+		w.listObjects = append(w.listObjects, listObjectsQuery{
+			objectType: resourceType(r.Intn(o.types)),
+			relation:   "viewer",
+			user:       randomUser(),
+		})
+	}
+
+	return w
+}
+
+func resourceType(t int) string {
+	return fmt.Sprintf("resource%d", t)
+}
+
+// pickAtLevel returns a random index below n at the level, the level of an index being its remainder by depth.
+// The level must be below n.
+func pickAtLevel(r *rand.Rand, n, depth, level int) int {
+	count := (n - level + depth - 1) / depth
+	return level + r.Intn(count)*depth
+}
@@ -0,0 +1,129 @@
+// Package modelgraph contains the command to export the graph of an authorization model.
+package modelgraph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/mysql"
+	"github.com/openfga/openfga/pkg/storage/postgres"
+	"github.com/openfga/openfga/pkg/storage/sqlcommon"
+	"github.com/openfga/openfga/pkg/typesystem"
+)
+
+const (
+	datastoreEngineFlag = "datastore-engine"
+	datastoreURIFlag    = "datastore-uri"
+	storeIDFlag         = "store-id"
+	modelIDFlag         = "model-id"
+	formatFlag          = "format"
+)
+
+// The formats the graph can be exported in.
+const (
+	formatDOT  = "dot"
+	formatJSON = "json"
+)
+
+func NewModelGraphCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "model-graph",
+		Short: "Export the graph of an authorization model.",
+		Long: "Export the graph of an authorization model of a store, annotated with its rewrite operators and type restrictions, " +
+			"as a GraphViz DOT graph or as a JSON list of nodes and edges.",
+		RunE: runModelGraph,
+		Args: cobra.NoArgs,
+	}
+
+	flags := cmd.Flags()
+	flags.String(datastoreEngineFlag, "", "the datastore engine")
+	flags.String(datastoreURIFlag, "", "the connection uri to the datastore")
+	flags.String(storeIDFlag, "", "the id of the store")
+	flags.String(modelIDFlag, "", "the id of the authorization model, the latest authorization model of the store if empty")
+	flags.String(formatFlag, formatDOT, "the format of the graph: 'dot' or 'json'")
+
+	// NOTE: if you add a new flag here, update the function below, too
+
+	cmd.PreRun = bindRunFlagsFunc(flags)
+
+	return cmd
+}
+
+func runModelGraph(cmd *cobra.Command, _ []string) error {
+	engine := viper.GetString(datastoreEngineFlag)
+	uri := viper.GetString(datastoreURIFlag)
+
+	var (
+		db  storage.OpenFGADatastore
+		err error
+	)
+	switch engine {
+	case "mysql":
+		db, err = mysql.New(uri, sqlcommon.NewConfig())
+	case "postgres":
+		db, err = postgres.New(uri, sqlcommon.NewConfig())
+	case "":
+		return fmt.Errorf("missing datastore engine type")
+	case "memory":
+		fallthrough
+	default:
+		return fmt.Errorf("storage engine '%s' is unsupported", engine)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to open a connection to the datastore: %v", err)
+	}
+	defer db.Close()
+
+	graph, err := exportModelGraph(context.Background(), db, viper.GetString(storeIDFlag), viper.GetString(modelIDFlag), viper.GetString(formatFlag))
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprint(cmd.OutOrStdout(), graph)
+	return err
+}
+
+// exportModelGraph returns the graph of the authorization model of the store in the format, or of its latest
+// authorization model if modelID is empty.
+func exportModelGraph(ctx context.Context, db storage.AuthorizationModelReadBackend, storeID, modelID, format string) (string, error) {
+	if format != formatDOT && format != formatJSON {
+		return "", fmt.Errorf("unsupported format '%s'", format)
+	}
+
+	if storeID == "" {
+		return "", fmt.Errorf("missing store id")
+	}
+
+	var (
+		model *openfgav1.AuthorizationModel
+		err   error
+	)
+	if modelID == "" {
+		model, err = db.FindLatestAuthorizationModel(ctx, storeID)
+	} else {
+		model, err = db.ReadAuthorizationModel(ctx, storeID, modelID)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error reading the authorization model: %w", err)
+	}
+
+	graph := typesystem.New(model).ModelGraph()
+
+	if format == formatDOT {
+		return graph.DOT(), nil
+	}
+
+	marshalled, err := json.MarshalIndent(graph, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("error marshalling the graph: %w", err)
+	}
+
+	return string(marshalled) + "\n", nil
+}
@@ -0,0 +1,66 @@
+package modelgraph
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/testutils"
+	"github.com/openfga/openfga/pkg/typesystem"
+)
+
+func TestExportModelGraph(t *testing.T) {
+	ctx := context.Background()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	storeID := ulid.Make().String()
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type document
+			relations
+				define viewer: [user]`)
+	require.NoError(t, ds.WriteAuthorizationModel(ctx, storeID, model))
+
+	t.Run("dot", func(t *testing.T) {
+		graph, err := exportModelGraph(ctx, ds, storeID, model.GetId(), formatDOT)
+		require.NoError(t, err)
+		require.Equal(t, `digraph {
+	"document" [shape=box];
+	"document#viewer";
+	"user" [shape=box];
+	"document#viewer" -> "user" [label="direct"];
+}
+`, graph)
+	})
+
+	t.Run("json_of_the_latest_model", func(t *testing.T) {
+		graph, err := exportModelGraph(ctx, ds, storeID, "", formatJSON)
+		require.NoError(t, err)
+
+		var decoded typesystem.ModelGraph
+		require.NoError(t, json.Unmarshal([]byte(graph), &decoded))
+		require.Len(t, decoded.Nodes, 3)
+		require.Equal(t, []typesystem.ModelGraphEdge{
+			{From: "document#viewer", To: "user", Kind: typesystem.ModelGraphEdgeDirect},
+		}, decoded.Edges)
+	})
+
+	t.Run("unknown_model", func(t *testing.T) {
+		_, err := exportModelGraph(ctx, ds, storeID, ulid.Make().String(), formatDOT)
+		require.ErrorContains(t, err, "error reading the authorization model")
+	})
+
+	t.Run("unsupported_format", func(t *testing.T) {
+		_, err := exportModelGraph(ctx, ds, storeID, "", "svg")
+		require.EqualError(t, err, "unsupported format 'svg'")
+	})
+}
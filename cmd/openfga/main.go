@@ -5,7 +5,9 @@ import (
 	"os"
 
 	"github.com/openfga/openfga/cmd"
+	"github.com/openfga/openfga/cmd/bench"
 	"github.com/openfga/openfga/cmd/migrate"
+	"github.com/openfga/openfga/cmd/modelgraph"
 	"github.com/openfga/openfga/cmd/run"
 	"github.com/openfga/openfga/cmd/validatemodels"
 )
@@ -22,6 +24,12 @@ func main() {
 	validateModelsCmd := validatemodels.NewValidateCommand()
 	rootCmd.AddCommand(validateModelsCmd)
 
+	modelGraphCmd := modelgraph.NewModelGraphCommand()
+	rootCmd.AddCommand(modelGraphCmd)
+
+	benchCmd := bench.NewBenchCommand()
+	rootCmd.AddCommand(benchCmd)
+
 	versionCmd := cmd.NewVersionCommand()
 	rootCmd.AddCommand(versionCmd)
 
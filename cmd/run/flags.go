@@ -69,6 +69,60 @@ func bindRunFlagsFunc(flags *pflag.FlagSet) func(*cobra.Command, []string) {
 		util.MustBindPFlag("authn.oidc.issuerAliases", flags.Lookup("authn-oidc-issuer-aliases"))
 		util.MustBindEnv("authn.oidc.issuerAliases", "OPENFGA_AUTHN_OIDC_ISSUER_ALIASES")
 
+		util.MustBindPFlag("accessControl.enabled", flags.Lookup("access-control-enabled"))
+		util.MustBindEnv("accessControl.enabled", "OPENFGA_ACCESS_CONTROL_ENABLED")
+
+		util.MustBindPFlag("accessControl.grants", flags.Lookup("access-control-grants"))
+		util.MustBindEnv("accessControl.grants", "OPENFGA_ACCESS_CONTROL_GRANTS")
+
+		util.MustBindPFlag("admissionControl.enabled", flags.Lookup("admission-control-enabled"))
+		util.MustBindEnv("admissionControl.enabled", "OPENFGA_ADMISSION_CONTROL_ENABLED")
+
+		util.MustBindPFlag("admissionControl.classes", flags.Lookup("admission-control-classes"))
+		util.MustBindEnv("admissionControl.classes", "OPENFGA_ADMISSION_CONTROL_CLASSES")
+
+		util.MustBindPFlag("admissionControl.methods", flags.Lookup("admission-control-methods"))
+		util.MustBindEnv("admissionControl.methods", "OPENFGA_ADMISSION_CONTROL_METHODS")
+
+		util.MustBindPFlag("admissionControl.clients", flags.Lookup("admission-control-clients"))
+		util.MustBindEnv("admissionControl.clients", "OPENFGA_ADMISSION_CONTROL_CLIENTS")
+
+		util.MustBindPFlag("admissionControl.defaultClass", flags.Lookup("admission-control-default-class"))
+		util.MustBindEnv("admissionControl.defaultClass", "OPENFGA_ADMISSION_CONTROL_DEFAULT_CLASS")
+
+		util.MustBindPFlag("admissionControl.queueTimeout", flags.Lookup("admission-control-queue-timeout"))
+		util.MustBindEnv("admissionControl.queueTimeout", "OPENFGA_ADMISSION_CONTROL_QUEUE_TIMEOUT")
+
+		util.MustBindPFlag("audit.enabled", flags.Lookup("audit-enabled"))
+		util.MustBindEnv("audit.enabled", "OPENFGA_AUDIT_ENABLED")
+
+		util.MustBindPFlag("audit.sink", flags.Lookup("audit-sink"))
+		util.MustBindEnv("audit.sink", "OPENFGA_AUDIT_SINK")
+
+		util.MustBindPFlag("audit.filePath", flags.Lookup("audit-file-path"))
+		util.MustBindEnv("audit.filePath", "OPENFGA_AUDIT_FILE_PATH")
+
+		util.MustBindPFlag("audit.fileMaxSize", flags.Lookup("audit-file-max-size"))
+		util.MustBindEnv("audit.fileMaxSize", "OPENFGA_AUDIT_FILE_MAX_SIZE")
+
+		util.MustBindPFlag("audit.fileMaxBackups", flags.Lookup("audit-file-max-backups"))
+		util.MustBindEnv("audit.fileMaxBackups", "OPENFGA_AUDIT_FILE_MAX_BACKUPS")
+
+		util.MustBindPFlag("audit.webhookURL", flags.Lookup("audit-webhook-url"))
+		util.MustBindEnv("audit.webhookURL", "OPENFGA_AUDIT_WEBHOOK_URL")
+
+		util.MustBindPFlag("audit.checkSampleRate", flags.Lookup("audit-check-sample-rate"))
+		util.MustBindEnv("audit.checkSampleRate", "OPENFGA_AUDIT_CHECK_SAMPLE_RATE")
+
+		util.MustBindPFlag("audit.bufferSize", flags.Lookup("audit-buffer-size"))
+		util.MustBindEnv("audit.bufferSize", "OPENFGA_AUDIT_BUFFER_SIZE")
+
+		util.MustBindPFlag("authn.mtls.clientCA", flags.Lookup("authn-mtls-client-ca"))
+		util.MustBindEnv("authn.mtls.clientCA", "OPENFGA_AUTHN_MTLS_CLIENT_CA")
+
+		util.MustBindPFlag("authn.mtls.allowedSubjects", flags.Lookup("authn-mtls-allowed-subjects"))
+		util.MustBindEnv("authn.mtls.allowedSubjects", "OPENFGA_AUTHN_MTLS_ALLOWED_SUBJECTS")
+
 		util.MustBindPFlag("datastore.engine", flags.Lookup("datastore-engine"))
 		util.MustBindEnv("datastore.engine", "OPENFGA_DATASTORE_ENGINE")
 
@@ -96,6 +150,36 @@ func bindRunFlagsFunc(flags *pflag.FlagSet) func(*cobra.Command, []string) {
 		util.MustBindPFlag("datastore.connMaxLifetime", flags.Lookup("datastore-conn-max-lifetime"))
 		util.MustBindEnv("datastore.connMaxLifetime", "OPENFGA_DATASTORE_CONN_MAX_LIFETIME", "OPENFGA_DATASTORE_CONNMAXLIFETIME")
 
+		util.MustBindPFlag("datastore.replicaURI", flags.Lookup("datastore-replica-uri"))
+		util.MustBindEnv("datastore.replicaURI", "OPENFGA_DATASTORE_REPLICA_URI")
+
+		util.MustBindPFlag("datastore.hedgingPercentile", flags.Lookup("datastore-hedging-percentile"))
+		util.MustBindEnv("datastore.hedgingPercentile", "OPENFGA_DATASTORE_HEDGING_PERCENTILE")
+
+		util.MustBindPFlag("datastore.hedgingMinDelay", flags.Lookup("datastore-hedging-min-delay"))
+		util.MustBindEnv("datastore.hedgingMinDelay", "OPENFGA_DATASTORE_HEDGING_MIN_DELAY")
+
+		util.MustBindPFlag("datastore.usersetBatchWindow", flags.Lookup("datastore-userset-batch-window"))
+		util.MustBindEnv("datastore.usersetBatchWindow", "OPENFGA_DATASTORE_USERSET_BATCH_WINDOW")
+
+		util.MustBindPFlag("datastore.usersetBatchMaxSize", flags.Lookup("datastore-userset-batch-max-size"))
+		util.MustBindEnv("datastore.usersetBatchMaxSize", "OPENFGA_DATASTORE_USERSET_BATCH_MAX_SIZE")
+
+		util.MustBindPFlag("datastore.memorySnapshotFile", flags.Lookup("datastore-memory-snapshot-file"))
+		util.MustBindEnv("datastore.memorySnapshotFile", "OPENFGA_DATASTORE_MEMORY_SNAPSHOT_FILE")
+
+		util.MustBindPFlag("datastore.memorySnapshotInterval", flags.Lookup("datastore-memory-snapshot-interval"))
+		util.MustBindEnv("datastore.memorySnapshotInterval", "OPENFGA_DATASTORE_MEMORY_SNAPSHOT_INTERVAL")
+
+		util.MustBindPFlag("datastore.storeStatsRefreshInterval", flags.Lookup("datastore-store-stats-refresh-interval"))
+		util.MustBindEnv("datastore.storeStatsRefreshInterval", "OPENFGA_DATASTORE_STORE_STATS_REFRESH_INTERVAL")
+
+		util.MustBindPFlag("datastore.tupleExpiryReaperInterval", flags.Lookup("datastore-tuple-expiry-reaper-interval"))
+		util.MustBindEnv("datastore.tupleExpiryReaperInterval", "OPENFGA_DATASTORE_TUPLE_EXPIRY_REAPER_INTERVAL")
+
+		util.MustBindPFlag("datastore.readBudget", flags.Lookup("datastore-read-budget"))
+		util.MustBindEnv("datastore.readBudget", "OPENFGA_DATASTORE_READ_BUDGET")
+
 		util.MustBindPFlag("datastore.metrics.enabled", flags.Lookup("datastore-metrics-enabled"))
 		util.MustBindEnv("datastore.metrics.enabled", "OPENFGA_DATASTORE_METRICS_ENABLED")
 
@@ -174,12 +258,24 @@ func bindRunFlagsFunc(flags *pflag.FlagSet) func(*cobra.Command, []string) {
 		util.MustBindPFlag("resolveNodeBreadthLimit", flags.Lookup("resolve-node-breadth-limit"))
 		util.MustBindEnv("resolveNodeBreadthLimit", "OPENFGA_RESOLVE_NODE_BREADTH_LIMIT", "OPENFGA_RESOLVENODEBREADTHLIMIT")
 
+		util.MustBindPFlag("resolveNodeUnionBreadthLimit", flags.Lookup("resolve-node-union-breadth-limit"))
+		util.MustBindEnv("resolveNodeUnionBreadthLimit", "OPENFGA_RESOLVE_NODE_UNION_BREADTH_LIMIT", "OPENFGA_RESOLVENODEUNIONBREADTHLIMIT")
+
+		util.MustBindPFlag("resolveNodeTTUBreadthLimit", flags.Lookup("resolve-node-ttu-breadth-limit"))
+		util.MustBindEnv("resolveNodeTTUBreadthLimit", "OPENFGA_RESOLVE_NODE_TTU_BREADTH_LIMIT", "OPENFGA_RESOLVENODETTUBREADTHLIMIT")
+
+		util.MustBindPFlag("checkResolverOrder", flags.Lookup("check-resolver-order"))
+		util.MustBindEnv("checkResolverOrder", "OPENFGA_CHECK_RESOLVER_ORDER", "OPENFGA_CHECKRESOLVERORDER")
+
 		util.MustBindPFlag("listObjectsDeadline", flags.Lookup("listObjects-deadline"))
 		util.MustBindEnv("listObjectsDeadline", "OPENFGA_LIST_OBJECTS_DEADLINE", "OPENFGA_LISTOBJECTSDEADLINE")
 
 		util.MustBindPFlag("listObjectsMaxResults", flags.Lookup("listObjects-max-results"))
 		util.MustBindEnv("listObjectsMaxResults", "OPENFGA_LIST_OBJECTS_MAX_RESULTS", "OPENFGA_LISTOBJECTSMAXRESULTS")
 
+		util.MustBindPFlag("listObjectsCandidateCheckConcurrencyLimit", flags.Lookup("listObjects-candidate-check-concurrency-limit"))
+		util.MustBindEnv("listObjectsCandidateCheckConcurrencyLimit", "OPENFGA_LIST_OBJECTS_CANDIDATE_CHECK_CONCURRENCY_LIMIT", "OPENFGA_LISTOBJECTSCANDIDATECHECKCONCURRENCYLIMIT")
+
 		util.MustBindPFlag("listUsersDeadline", flags.Lookup("listUsers-deadline"))
 		util.MustBindEnv("listUsersDeadline", "OPENFGA_LIST_USERS_DEADLINE", "OPENFGA_LISTUSERSDEADLINE")
 
@@ -195,6 +291,15 @@ func bindRunFlagsFunc(flags *pflag.FlagSet) func(*cobra.Command, []string) {
 		util.MustBindPFlag("checkQueryCache.ttl", flags.Lookup("check-query-cache-ttl"))
 		util.MustBindEnv("checkQueryCache.ttl", "OPENFGA_CHECK_QUERY_CACHE_TTL")
 
+		util.MustBindPFlag("listObjectsReadCache.enabled", flags.Lookup("list-objects-read-cache-enabled"))
+		util.MustBindEnv("listObjectsReadCache.enabled", "OPENFGA_LIST_OBJECTS_READ_CACHE_ENABLED")
+
+		util.MustBindPFlag("listObjectsReadCache.limit", flags.Lookup("list-objects-read-cache-limit"))
+		util.MustBindEnv("listObjectsReadCache.limit", "OPENFGA_LIST_OBJECTS_READ_CACHE_LIMIT")
+
+		util.MustBindPFlag("listObjectsReadCache.ttl", flags.Lookup("list-objects-read-cache-ttl"))
+		util.MustBindEnv("listObjectsReadCache.ttl", "OPENFGA_LIST_OBJECTS_READ_CACHE_TTL")
+
 		util.MustBindPFlag("requestDurationDatastoreQueryCountBuckets", flags.Lookup("request-duration-datastore-query-count-buckets"))
 		util.MustBindEnv("requestDurationDatastoreQueryCountBuckets", "OPENFGA_REQUEST_DURATION_DATASTORE_QUERY_COUNT_BUCKETS")
 
@@ -243,5 +348,8 @@ func bindRunFlagsFunc(flags *pflag.FlagSet) func(*cobra.Command, []string) {
 
 		util.MustBindPFlag("requestTimeout", flags.Lookup("request-timeout"))
 		util.MustBindEnv("requestTimeout", "OPENFGA_REQUEST_TIMEOUT")
+
+		util.MustBindPFlag("shutdownDrainTimeout", flags.Lookup("shutdown-drain-timeout"))
+		util.MustBindEnv("shutdownDrainTimeout", "OPENFGA_SHUTDOWN_DRAIN_TIMEOUT")
 	}
 }
@@ -0,0 +1,98 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	serverconfig "github.com/openfga/openfga/internal/server/config"
+	"github.com/openfga/openfga/pkg/middleware"
+	"github.com/openfga/openfga/pkg/server"
+)
+
+// waitForShutdown blocks until the process is interrupted or terminated, or ctx is done. In the meantime, a
+// SIGHUP reloads the runtime-tunable settings of the configuration, see reloadConfig.
+func (s *ServerContext) waitForShutdown(ctx context.Context, svr *server.Server, timeoutInterceptor *middleware.TimeoutInterceptor) {
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	defer signal.Stop(reload)
+
+	done := make(chan os.Signal, 1)
+	signal.Notify(done, syscall.SIGINT, syscall.SIGTERM)
+
+	for {
+		select {
+		case <-reload:
+			s.Logger.Info("reloading the configuration...")
+			if err := s.reloadConfig(svr, timeoutInterceptor, ReadConfig); err != nil {
+				s.Logger.Error("failed to reload the configuration, the previous settings remain in effect", zap.Error(err))
+			}
+		case <-done:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// reloadConfig reads the configuration with readConfig and applies its runtime-tunable settings to the running
+// server, without restarting it and dropping its in-memory caches: the Check query cache TTL, the Check
+// dispatch throttling thresholds, the request timeout (if one was set at start, see timeoutInterceptor) and
+// the log level (if s.LogLevel is set). The other settings only take effect on a restart. No setting is
+// changed if the configuration is invalid.
+func (s *ServerContext) reloadConfig(
+	svr *server.Server,
+	timeoutInterceptor *middleware.TimeoutInterceptor,
+	readConfig func() (*serverconfig.Config, error),
+) error {
+	config, err := readConfig()
+	if err != nil {
+		return err
+	}
+
+	if err := config.Verify(); err != nil {
+		return err
+	}
+
+	var logLevel zapcore.Level
+	if s.LogLevel != nil {
+		logLevel, err = zapcore.ParseLevel(config.Log.Level)
+		if err != nil {
+			return fmt.Errorf("unknown log level: %s, error: %w", config.Log.Level, err)
+		}
+	}
+
+	checkDispatchThrottlingConfig := serverconfig.GetCheckDispatchThrottlingConfig(s.Logger, config)
+
+	err = svr.UpdateRuntimeConfig(server.RuntimeConfig{
+		CheckQueryCacheTTL:                      config.CheckQueryCache.TTL,
+		CheckDispatchThrottlingDefaultThreshold: checkDispatchThrottlingConfig.Threshold,
+		CheckDispatchThrottlingMaxThreshold:     checkDispatchThrottlingConfig.MaxThreshold,
+	})
+	if err != nil {
+		return err
+	}
+
+	if timeoutInterceptor != nil && config.RequestTimeout > 0 {
+		timeoutInterceptor.SetTimeout(config.RequestTimeout)
+	}
+
+	if s.LogLevel != nil {
+		s.LogLevel.SetLevel(logLevel)
+	}
+
+	s.Logger.Info("configuration reloaded",
+		zap.Duration("check_query_cache_ttl", config.CheckQueryCache.TTL),
+		zap.Uint32("check_dispatch_throttling_threshold", checkDispatchThrottlingConfig.Threshold),
+		zap.Uint32("check_dispatch_throttling_max_threshold", checkDispatchThrottlingConfig.MaxThreshold),
+		zap.Duration("request_timeout", config.RequestTimeout),
+		zap.String("log_level", config.Log.Level),
+	)
+
+	return nil
+}
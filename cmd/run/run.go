@@ -3,6 +3,8 @@ package run
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"html/template"
@@ -10,11 +12,9 @@ import (
 	"net/http"
 	"net/http/pprof"
 	"os"
-	"os/signal"
 	goruntime "runtime"
 	"strconv"
 	"strings"
-	"syscall"
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
@@ -41,17 +41,24 @@ import (
 	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/status"
 
+	"github.com/openfga/openfga/pkg/audit"
 	"github.com/openfga/openfga/pkg/gateway"
 
 	"github.com/openfga/openfga/assets"
+	"github.com/openfga/openfga/internal/accesscontrol"
+	"github.com/openfga/openfga/internal/admission"
 	"github.com/openfga/openfga/internal/authn"
+	"github.com/openfga/openfga/internal/authn/mtls"
 	"github.com/openfga/openfga/internal/authn/oidc"
 	"github.com/openfga/openfga/internal/authn/presharedkey"
 	"github.com/openfga/openfga/internal/build"
+	accesscontrolmw "github.com/openfga/openfga/internal/middleware/accesscontrol"
+	admissionmw "github.com/openfga/openfga/internal/middleware/admission"
 	authnmw "github.com/openfga/openfga/internal/middleware/authn"
 	serverconfig "github.com/openfga/openfga/internal/server/config"
 	"github.com/openfga/openfga/pkg/logger"
 	"github.com/openfga/openfga/pkg/middleware"
+	"github.com/openfga/openfga/pkg/middleware/consistency"
 	httpmiddleware "github.com/openfga/openfga/pkg/middleware/http"
 	"github.com/openfga/openfga/pkg/middleware/logging"
 	"github.com/openfga/openfga/pkg/middleware/recovery"
@@ -62,10 +69,12 @@ import (
 	serverErrors "github.com/openfga/openfga/pkg/server/errors"
 	"github.com/openfga/openfga/pkg/server/health"
 	"github.com/openfga/openfga/pkg/storage"
-	"github.com/openfga/openfga/pkg/storage/memory"
-	"github.com/openfga/openfga/pkg/storage/mysql"
-	"github.com/openfga/openfga/pkg/storage/postgres"
+	// the built-in datastore engines register themselves with the storage package
+	_ "github.com/openfga/openfga/pkg/storage/memory"
+	_ "github.com/openfga/openfga/pkg/storage/mysql"
+	_ "github.com/openfga/openfga/pkg/storage/postgres"
 	"github.com/openfga/openfga/pkg/storage/sqlcommon"
+	"github.com/openfga/openfga/pkg/storage/storagewrappers"
 	"github.com/openfga/openfga/pkg/telemetry"
 )
 
@@ -86,7 +95,7 @@ func NewRunCommand() *cobra.Command {
 	defaultConfig := serverconfig.DefaultConfig()
 	flags := cmd.Flags()
 
-	flags.StringSlice("experimentals", defaultConfig.Experimentals, "a list of experimental features to enable. Allowed values: `enable-list-users`")
+	flags.StringSlice("experimentals", defaultConfig.Experimentals, "a list of experimental features to enable. Allowed values: `enable-list-users`, `enable-list-objects-intersection-planning`")
 
 	flags.String("grpc-addr", defaultConfig.GRPC.Addr, "the host:port address to serve the grpc server on")
 
@@ -126,6 +135,42 @@ func NewRunCommand() *cobra.Command {
 
 	flags.StringSlice("authn-oidc-issuer-aliases", defaultConfig.Authn.IssuerAliases, "the OIDC issuer DNS aliases that will be accepted as valid when verifying tokens")
 
+	flags.Bool("access-control-enabled", defaultConfig.AccessControl.Enabled, "enable/disable the access control of the authenticated clients to the stores")
+
+	flags.StringSlice("access-control-grants", defaultConfig.AccessControl.Grants, "the grants of the access control, each of the form '<subject>:<store ID>:<level>', where the subject is the subject of the auth claims of a client or '*' for any client, the store ID is '*' for any store, and the level is 'read', 'write' or 'admin'")
+
+	flags.Bool("admission-control-enabled", defaultConfig.AdmissionControl.Enabled, "enable/disable the admission control, which bounds the number of concurrent requests of each priority class and queues the others, so that e.g. bulk ListObjects requests cannot delay the interactive Check requests")
+
+	flags.StringSlice("admission-control-classes", defaultConfig.AdmissionControl.Classes, "the priority classes of the admission control, each of the form '<name>:<max concurrent>:<max queued>', from the highest priority to the lowest")
+
+	flags.StringSlice("admission-control-methods", defaultConfig.AdmissionControl.Methods, "the assignments of the APIs to the priority classes, each of the form '<method>:<class>', e.g. 'ListObjects:bulk'")
+
+	flags.StringSlice("admission-control-clients", defaultConfig.AdmissionControl.Clients, "the assignments of the clients to the priority classes, each of the form '<subject>:<class>', where the subject is the subject of the auth claims of a client. They take precedence over the class of the method. A client may only lower the class of its requests with the 'Openfga-Priority-Class' request header")
+
+	flags.String("admission-control-default-class", defaultConfig.AdmissionControl.DefaultClass, "the priority class of the requests no assignment selects a class for")
+
+	flags.Duration("admission-control-queue-timeout", defaultConfig.AdmissionControl.QueueTimeout, "the maximum time a request waits for a slot of its priority class before being rejected. If 0, it waits until it is canceled")
+
+	flags.Bool("audit-enabled", defaultConfig.Audit.Enabled, "enable/disable the audit events recording the Write, WriteAuthorizationModel, CreateStore and DeleteStore requests, and the sampled Check decisions, with the principal of each request")
+
+	flags.String("audit-sink", defaultConfig.Audit.Sink, "where the audit events are written to as JSON: 'stdout', 'file' or 'webhook'")
+
+	flags.String("audit-file-path", defaultConfig.Audit.FilePath, "the path of the file of the 'file' audit sink")
+
+	flags.Int64("audit-file-max-size", defaultConfig.Audit.FileMaxSize, "the size in bytes beyond which the file of the 'file' audit sink is rotated")
+
+	flags.Int("audit-file-max-backups", defaultConfig.Audit.FileMaxBackups, "the number of rotated files the 'file' audit sink keeps")
+
+	flags.String("audit-webhook-url", defaultConfig.Audit.WebhookURL, "the URL the 'webhook' audit sink posts the events to, e.g. the HTTP bridge of a Kafka cluster")
+
+	flags.Float64("audit-check-sample-rate", defaultConfig.Audit.CheckSampleRate, "the fraction of the Check decisions that are audited. 1 means all, 0 means none")
+
+	flags.Int("audit-buffer-size", defaultConfig.Audit.BufferSize, "the number of audit events buffered while the sink writes the previous ones. The events emitted while the buffer is full are dropped")
+
+	flags.String("authn-mtls-client-ca", defaultConfig.Authn.ClientCAPath, "the (absolute) file path of the certificates of the CAs that must sign the client certificates")
+
+	flags.StringSlice("authn-mtls-allowed-subjects", defaultConfig.Authn.AllowedSubjects, "the common names of the client certificates that are accepted, all are accepted if empty")
+
 	flags.String("datastore-engine", defaultConfig.Datastore.Engine, "the datastore engine that will be used for persistence")
 
 	flags.String("datastore-uri", defaultConfig.Datastore.URI, "the connection uri to use to connect to the datastore (for any engine other than 'memory')")
@@ -144,6 +189,26 @@ func NewRunCommand() *cobra.Command {
 
 	flags.Duration("datastore-conn-max-lifetime", defaultConfig.Datastore.ConnMaxLifetime, "the maximum amount of time a connection to the datastore may be reused")
 
+	flags.String("datastore-replica-uri", defaultConfig.Datastore.ReplicaURI, "the connection uri of a read replica of the datastore. If set, the datastore reads of the Checks that take longer than usual are also sent to the replica, and the first answer is used")
+
+	flags.Float64("datastore-hedging-percentile", defaultConfig.Datastore.HedgingPercentile, "the percentile of the latest latencies of the datastore after which the reads of the Checks are also sent to the replica")
+
+	flags.Duration("datastore-hedging-min-delay", defaultConfig.Datastore.HedgingMinDelay, "the minimum delay after which the reads of the Checks are also sent to the replica")
+
+	flags.Duration("datastore-userset-batch-window", defaultConfig.Datastore.UsersetBatchWindow, "how long the userset reads of a Check wait for each other to be coalesced into one IN-clause query, if the datastore supports it (mysql and postgres). It reduces the round trips of wide group expansions. 0 disables the batching")
+
+	flags.Int("datastore-userset-batch-max-size", defaultConfig.Datastore.UsersetBatchMaxSize, "the maximum number of objects whose userset tuples are read by one batched query")
+
+	flags.String("datastore-memory-snapshot-file", defaultConfig.Datastore.MemorySnapshotFile, "the file the data of the 'memory' datastore engine is persisted to, so that it survives restarts. The data is loaded from the file on start, if it exists")
+
+	flags.Duration("datastore-memory-snapshot-interval", defaultConfig.Datastore.MemorySnapshotInterval, "the interval the data of the 'memory' datastore engine is saved to the snapshot file at, if it changed. It is also saved on shutdown")
+
+	flags.Duration("datastore-store-stats-refresh-interval", defaultConfig.Datastore.StoreStatsRefreshInterval, "the age after which the tuple counts and the changelog growth statistics of a store are recomputed in the background, by the 'mysql' and 'postgres' datastore engines")
+
+	flags.Duration("datastore-tuple-expiry-reaper-interval", defaultConfig.Datastore.TupleExpiryReaperInterval, "the interval the tuples that expired are deleted at. It requires a datastore that supports expiring tuples, such as the 'memory' datastore engine. 0 disables the deletes")
+
+	flags.Uint32("datastore-read-budget", defaultConfig.Datastore.ReadBudget, "the maximum number of datastore reads one Check, ListObjects or ListUsers request will make before failing. 0 means unbounded.")
+
 	flags.Bool("datastore-metrics-enabled", defaultConfig.Datastore.Metrics.Enabled, "enable/disable sql metrics")
 
 	flags.Bool("playground-enabled", defaultConfig.Playground.Enabled, "enable/disable the OpenFGA Playground")
@@ -196,8 +261,16 @@ func NewRunCommand() *cobra.Command {
 
 	flags.Uint32("resolve-node-breadth-limit", defaultConfig.ResolveNodeBreadthLimit, "defines how many nodes on a given level can be evaluated concurrently in a Check resolution tree")
 
+	flags.Uint32("resolve-node-union-breadth-limit", defaultConfig.ResolveNodeUnionBreadthLimit, "defines how many children of a union rewrite can be evaluated concurrently in a Check resolution tree. If 0, the resolve-node-breadth-limit is used")
+
+	flags.Uint32("resolve-node-ttu-breadth-limit", defaultConfig.ResolveNodeTTUBreadthLimit, "defines how many tuples of a tuple to userset rewrite can be evaluated concurrently in a Check resolution tree. If 0, the resolve-node-breadth-limit is used")
+
+	flags.StringSlice("check-resolver-order", defaultConfig.CheckResolverOrder, "the order of the layers of the Check resolver chain, among `cycle_detection`, `cached` and `dispatch_throttling`. The layers left out are disabled")
+
 	flags.Duration("listObjects-deadline", defaultConfig.ListObjectsDeadline, "the timeout deadline for serving ListObjects and StreamedListObjects requests")
 
+	flags.Uint32("listObjects-candidate-check-concurrency-limit", defaultConfig.ListObjectsCandidateCheckConcurrencyLimit, "defines how many candidate objects of a ListObjects request can be checked concurrently. If 0, the resolve-node-breadth-limit is used")
+
 	flags.Uint32("listObjects-max-results", defaultConfig.ListObjectsMaxResults, "the maximum results to return in non-streaming ListObjects API responses. If 0, all results can be returned")
 
 	flags.Duration("listUsers-deadline", defaultConfig.ListUsersDeadline, "the timeout deadline for serving ListUsers requests. If 0, there is no deadline")
@@ -210,6 +283,12 @@ func NewRunCommand() *cobra.Command {
 
 	flags.Duration("check-query-cache-ttl", defaultConfig.CheckQueryCache.TTL, "if caching of Check and ListObjects is enabled, this is the TTL of each value")
 
+	flags.Bool("list-objects-read-cache-enabled", defaultConfig.ListObjectsReadCache.Enabled, "when executing ListObjects requests, enables caching of the tuples read by the reverse expansion across requests. This will turn ListObjects responses into eventually consistent responses")
+
+	flags.Uint32("list-objects-read-cache-limit", defaultConfig.ListObjectsReadCache.Limit, "if caching of the ListObjects reads is enabled, this is the size limit of the cache")
+
+	flags.Duration("list-objects-read-cache-ttl", defaultConfig.ListObjectsReadCache.TTL, "if caching of the ListObjects reads is enabled, this is the TTL of each value")
+
 	// Unfortunately UintSlice/IntSlice does not work well when used as environment variable, we need to stick with string slice and convert back to integer
 	flags.StringSlice("request-duration-datastore-query-count-buckets", defaultConfig.RequestDurationDatastoreQueryCountBuckets, "datastore query count buckets used in labelling request_duration_ms.")
 
@@ -249,6 +328,8 @@ func NewRunCommand() *cobra.Command {
 
 	flags.Duration("request-timeout", defaultConfig.RequestTimeout, "configures request timeout.  If both HTTP upstream timeout and request timeout are specified, request timeout will be used.")
 
+	flags.Duration("shutdown-drain-timeout", defaultConfig.ShutdownDrainTimeout, "how long the Check and ListObjects requests in flight on shutdown are given to complete before they are canceled. The new requests are rejected as soon as the shutdown starts")
+
 	// NOTE: if you add a new flag here, update the function below, too
 
 	cmd.PreRun = bindRunFlagsFunc(flags)
@@ -287,9 +368,18 @@ func run(_ *cobra.Command, _ []string) {
 		panic(err)
 	}
 
-	logger := logger.MustNewLogger(config.Log.Format, config.Log.Level, config.Log.TimestampFormat)
+	logLevel := zap.NewAtomicLevel()
+	logger, err := logger.NewLogger(
+		logger.WithFormat(config.Log.Format),
+		logger.WithLevel(config.Log.Level),
+		logger.WithTimestampFormat(config.Log.TimestampFormat),
+		logger.WithAtomicLevel(logLevel),
+	)
+	if err != nil {
+		panic(err)
+	}
 
-	serverCtx := &ServerContext{Logger: logger}
+	serverCtx := &ServerContext{Logger: logger, LogLevel: &logLevel}
 	if err := serverCtx.Run(context.Background(), config); err != nil {
 		panic(err)
 	}
@@ -297,6 +387,9 @@ func run(_ *cobra.Command, _ []string) {
 
 type ServerContext struct {
 	Logger logger.Logger
+
+	// LogLevel, if set, is the level Logger logs at, which is changed when the configuration is reloaded.
+	LogLevel *zap.AtomicLevel
 }
 
 func convertStringArrayToUintArray(stringArray []string) []uint {
@@ -344,49 +437,53 @@ func (s *ServerContext) telemetryConfig(config *serverconfig.Config) func(ctx co
 }
 
 func (s *ServerContext) datastoreConfig(config *serverconfig.Config) (storage.OpenFGADatastore, error) {
+	cfg := sqlDatastoreConfig(s.Logger, config)
+	cfg.MemorySnapshotFile = config.Datastore.MemorySnapshotFile
+	cfg.MemorySnapshotInterval = config.Datastore.MemorySnapshotInterval
+
+	datastore, err := storage.OpenDatastore(config.Datastore.Engine, config.Datastore.URI, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Logger.Info(fmt.Sprintf("using '%v' storage engine", config.Datastore.Engine))
+	return datastore, nil
+}
+
+// replicaDatastoreConfig opens the read replica of the datastore, or returns nil if none is configured.
+func (s *ServerContext) replicaDatastoreConfig(config *serverconfig.Config) (storage.OpenFGADatastore, error) {
+	if config.Datastore.ReplicaURI == "" {
+		return nil, nil
+	}
+
+	replica, err := storage.OpenDatastore(config.Datastore.Engine, config.Datastore.ReplicaURI, sqlDatastoreConfig(s.Logger, config))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the replica datastore: %w", err)
+	}
+
+	s.Logger.Info("hedging the datastore reads of the Checks with a read replica")
+	return replica, nil
+}
+
+func sqlDatastoreConfig(l logger.Logger, config *serverconfig.Config) *storage.DatastoreConfig {
 	datastoreOptions := []sqlcommon.DatastoreOption{
 		sqlcommon.WithUsername(config.Datastore.Username),
 		sqlcommon.WithPassword(config.Datastore.Password),
-		sqlcommon.WithLogger(s.Logger),
+		sqlcommon.WithLogger(l),
 		sqlcommon.WithMaxTuplesPerWrite(config.MaxTuplesPerWrite),
 		sqlcommon.WithMaxTypesPerAuthorizationModel(config.MaxTypesPerAuthorizationModel),
 		sqlcommon.WithMaxOpenConns(config.Datastore.MaxOpenConns),
 		sqlcommon.WithMaxIdleConns(config.Datastore.MaxIdleConns),
 		sqlcommon.WithConnMaxIdleTime(config.Datastore.ConnMaxIdleTime),
 		sqlcommon.WithConnMaxLifetime(config.Datastore.ConnMaxLifetime),
+		sqlcommon.WithStoreStatsRefreshInterval(config.Datastore.StoreStatsRefreshInterval),
 	}
 
 	if config.Datastore.Metrics.Enabled {
 		datastoreOptions = append(datastoreOptions, sqlcommon.WithMetrics())
 	}
 
-	dsCfg := sqlcommon.NewConfig(datastoreOptions...)
-
-	var datastore storage.OpenFGADatastore
-	var err error
-	switch config.Datastore.Engine {
-	case "memory":
-		opts := []memory.StorageOption{
-			memory.WithMaxTypesPerAuthorizationModel(config.MaxTypesPerAuthorizationModel),
-			memory.WithMaxTuplesPerWrite(config.MaxTuplesPerWrite),
-		}
-		datastore = memory.New(opts...)
-	case "mysql":
-		datastore, err = mysql.New(config.Datastore.URI, dsCfg)
-		if err != nil {
-			return nil, fmt.Errorf("initialize mysql datastore: %w", err)
-		}
-	case "postgres":
-		datastore, err = postgres.New(config.Datastore.URI, dsCfg)
-		if err != nil {
-			return nil, fmt.Errorf("initialize postgres datastore: %w", err)
-		}
-	default:
-		return nil, fmt.Errorf("storage engine '%s' is unsupported", config.Datastore.Engine)
-	}
-
-	s.Logger.Info(fmt.Sprintf("using '%v' storage engine", config.Datastore.Engine))
-	return datastore, nil
+	return sqlcommon.NewConfig(datastoreOptions...)
 }
 
 func (s *ServerContext) authenticatorConfig(config *serverconfig.Config) (authn.Authenticator, error) {
@@ -402,7 +499,26 @@ func (s *ServerContext) authenticatorConfig(config *serverconfig.Config) (authn.
 		authenticator, err = presharedkey.NewPresharedKeyAuthenticator(config.Authn.Keys)
 	case "oidc":
 		s.Logger.Info("using 'oidc' authentication")
-		authenticator, err = oidc.NewRemoteOidcAuthenticator(config.Authn.Issuer, config.Authn.IssuerAliases, config.Authn.Audience)
+		if len(config.Authn.Issuers) == 0 {
+			authenticator, err = oidc.NewRemoteOidcAuthenticator(config.Authn.Issuer, config.Authn.IssuerAliases, config.Authn.Audience)
+			break
+		}
+
+		var issuers []oidc.IssuerConfig
+		if config.Authn.Issuer != "" {
+			issuers = append(issuers, oidc.IssuerConfig{
+				Issuer:        config.Authn.Issuer,
+				IssuerAliases: config.Authn.IssuerAliases,
+				Audience:      config.Authn.Audience,
+			})
+		}
+		for _, issuer := range config.Authn.Issuers {
+			issuers = append(issuers, oidc.IssuerConfig(issuer))
+		}
+		authenticator, err = oidc.NewMultiIssuerOidcAuthenticator(issuers)
+	case "mtls":
+		s.Logger.Info("using 'mtls' authentication")
+		authenticator = mtls.NewClientCertificateAuthenticator(config.Authn.AllowedSubjects)
 	default:
 		return nil, fmt.Errorf("unsupported authentication method '%v'", config.Authn.Method)
 	}
@@ -412,6 +528,185 @@ func (s *ServerContext) authenticatorConfig(config *serverconfig.Config) (authn.
 	return authenticator, nil
 }
 
+// grpcServerTLSCredentials returns the TLS credentials of the gRPC server. With the 'mtls' authn method,
+// the clients must present a certificate signed by one of the configured client CAs.
+func grpcServerTLSCredentials(config *serverconfig.Config) (credentials.TransportCredentials, error) {
+	if config.Authn.Method != "mtls" {
+		return credentials.NewServerTLSFromFile(config.GRPC.TLS.CertPath, config.GRPC.TLS.KeyPath)
+	}
+
+	cert, err := tls.LoadX509KeyPair(config.GRPC.TLS.CertPath, config.GRPC.TLS.KeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	clientCAs, err := os.ReadFile(config.Authn.ClientCAPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the client CAs: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(clientCAs) {
+		return nil, fmt.Errorf("no certificate found in '%s'", config.Authn.ClientCAPath)
+	}
+
+	return credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}), nil
+}
+
+// newAuditLogger returns the audit logger writing to the sink of the config.
+func newAuditLogger(config serverconfig.AuditConfig, l logger.Logger) (*audit.Logger, error) {
+	var sink audit.Sink
+	switch config.Sink {
+	case "stdout":
+		sink = audit.NewJSONSink(os.Stdout)
+	case "file":
+		fileSink, err := audit.NewFileSink(config.FilePath, config.FileMaxSize, config.FileMaxBackups)
+		if err != nil {
+			return nil, err
+		}
+		sink = fileSink
+	case "webhook":
+		sink = audit.NewWebhookSink(config.WebhookURL)
+	default:
+		return nil, fmt.Errorf("unsupported audit sink '%s'", config.Sink)
+	}
+
+	return audit.NewLogger([]audit.Sink{sink},
+		audit.WithBufferSize(config.BufferSize),
+		audit.WithCheckSampleRate(config.CheckSampleRate),
+		audit.WithLogger(l),
+	), nil
+}
+
+// auditLoggerConfig returns the audit logger of the config, or nil if the audit is disabled.
+func (s *ServerContext) auditLoggerConfig(config *serverconfig.Config) (*audit.Logger, error) {
+	if !config.Audit.Enabled {
+		return nil, nil
+	}
+
+	auditLogger, err := newAuditLogger(config.Audit, s.Logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize audit logger: %w", err)
+	}
+
+	s.Logger.Info(fmt.Sprintf("audit is enabled with the '%s' sink", config.Audit.Sink))
+
+	return auditLogger, nil
+}
+
+// authInterceptors returns the interceptors authenticating the clients, followed by the ones relying on their
+// auth claims: the access control and the admission control, if enabled.
+func (s *ServerContext) authInterceptors(
+	config *serverconfig.Config,
+	authenticator authn.Authenticator,
+) ([]grpc.UnaryServerInterceptor, []grpc.StreamServerInterceptor, error) {
+	unaryAuthInterceptors := []grpc.UnaryServerInterceptor{
+		grpcauth.UnaryServerInterceptor(authnmw.AuthFunc(authenticator)),
+	}
+	streamAuthInterceptors := []grpc.StreamServerInterceptor{
+		grpcauth.StreamServerInterceptor(authnmw.AuthFunc(authenticator)),
+	}
+
+	if config.AccessControl.Enabled {
+		policy, err := accesscontrol.NewPolicyFromStrings(config.AccessControl.Grants)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to initialize access control: %w", err)
+		}
+
+		unaryAuthInterceptors = append(unaryAuthInterceptors, accesscontrolmw.NewUnaryInterceptor(policy))
+		streamAuthInterceptors = append(streamAuthInterceptors, accesscontrolmw.NewStreamingInterceptor(policy))
+
+		s.Logger.Info(fmt.Sprintf("access control is enabled with %d grants", len(config.AccessControl.Grants)))
+	}
+
+	if config.AdmissionControl.Enabled {
+		controller, err := admission.NewController(config.AdmissionControl.ControllerConfig())
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to initialize admission control: %w", err)
+		}
+
+		unaryAuthInterceptors = append(unaryAuthInterceptors, admissionmw.NewUnaryInterceptor(controller))
+		streamAuthInterceptors = append(streamAuthInterceptors, admissionmw.NewStreamingInterceptor(controller))
+
+		s.Logger.Info(fmt.Sprintf("admission control is enabled with %d priority classes", len(config.AdmissionControl.Classes)))
+	}
+
+	return unaryAuthInterceptors, streamAuthInterceptors, nil
+}
+
+// checkReadOptions returns the server options of the Check datastore reads: their hedging with the read
+// replica, if one is configured, and the batching of their userset reads, if enabled.
+func checkReadOptions(config *serverconfig.Config, replica storage.OpenFGADatastore) []server.OpenFGAServiceV1Option {
+	var opts []server.OpenFGAServiceV1Option
+	if replica != nil {
+		opts = append(opts, server.WithCheckReadHedging(
+			replica,
+			storagewrappers.WithHedgingPercentile(config.Datastore.HedgingPercentile),
+			storagewrappers.WithHedgingMinDelay(config.Datastore.HedgingMinDelay),
+		))
+	}
+
+	if config.Datastore.UsersetBatchWindow > 0 {
+		opts = append(opts, server.WithCheckUsersetReadBatching(
+			storagewrappers.WithUsersetBatchWindow(config.Datastore.UsersetBatchWindow),
+			storagewrappers.WithUsersetBatchMaxSize(config.Datastore.UsersetBatchMaxSize),
+		))
+	}
+
+	return opts
+}
+
+// startProfilerServer starts serving the pprof profiles on the profiler address.
+func (s *ServerContext) startProfilerServer(config *serverconfig.Config) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	profilerServer := &http.Server{Addr: config.Profiler.Addr, Handler: mux}
+
+	go func() {
+		s.Logger.Info(fmt.Sprintf("🔬 starting pprof profiler on '%s'", config.Profiler.Addr))
+
+		if err := profilerServer.ListenAndServe(); err != nil {
+			if err != http.ErrServerClosed {
+				s.Logger.Fatal("failed to start pprof profiler", zap.Error(err))
+			}
+		}
+		s.Logger.Info("profiler shut down.")
+	}()
+
+	return profilerServer
+}
+
+// startMetricsServer starts serving the prometheus metrics on the metrics address.
+func (s *ServerContext) startMetricsServer(config *serverconfig.Config) *http.Server {
+	s.Logger.Info(fmt.Sprintf("📈 starting prometheus metrics server on '%s'", config.Metrics.Addr))
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	metricsServer := &http.Server{Addr: config.Metrics.Addr, Handler: mux}
+
+	go func() {
+		if err := metricsServer.ListenAndServe(); err != nil {
+			if err != http.ErrServerClosed {
+				s.Logger.Fatal("failed to start prometheus metrics server", zap.Error(err))
+			}
+		}
+		s.Logger.Info("metrics server shut down.")
+	}()
+
+	return metricsServer
+}
+
 // Run returns an error if the server was unable to start successfully.
 // If it started and terminated successfully, it returns a nil error.
 func (s *ServerContext) Run(ctx context.Context, config *serverconfig.Config) error {
@@ -431,6 +726,11 @@ func (s *ServerContext) Run(ctx context.Context, config *serverconfig.Config) er
 		return err
 	}
 
+	replica, err := s.replicaDatastoreConfig(config)
+	if err != nil {
+		return err
+	}
+
 	authenticator, err := s.authenticatorConfig(config)
 
 	if err != nil {
@@ -462,8 +762,9 @@ func (s *ServerContext) Run(ctx context.Context, config *serverconfig.Config) er
 		),
 	}
 
+	var timeoutMiddleware *middleware.TimeoutInterceptor
 	if config.RequestTimeout > 0 {
-		timeoutMiddleware := middleware.NewTimeoutInterceptor(config.RequestTimeout, s.Logger)
+		timeoutMiddleware = middleware.NewTimeoutInterceptor(config.RequestTimeout, s.Logger)
 
 		serverOpts = append(serverOpts, grpc.ChainUnaryInterceptor(timeoutMiddleware.NewUnaryTimeoutInterceptor()))
 		serverOpts = append(serverOpts, grpc.ChainStreamInterceptor(timeoutMiddleware.NewStreamTimeoutInterceptor()))
@@ -475,12 +776,14 @@ func (s *ServerContext) Run(ctx context.Context, config *serverconfig.Config) er
 				storeid.NewUnaryInterceptor(),           // if available, add store_id to ctxtags
 				logging.NewLoggingInterceptor(s.Logger), // needed to log invalid requests
 				validator.UnaryServerInterceptor(),
+				consistency.NewUnaryInterceptor(),
 			}...,
 		),
 		grpc.ChainStreamInterceptor(
 			[]grpc.StreamServerInterceptor{
 				validator.StreamServerInterceptor(),
 				grpc_ctxtags.StreamServerInterceptor(),
+				consistency.NewStreamingInterceptor(),
 			}...,
 		),
 	)
@@ -499,18 +802,19 @@ func (s *ServerContext) Run(ctx context.Context, config *serverconfig.Config) er
 		serverOpts = append(serverOpts, grpc.StatsHandler(otelgrpc.NewServerHandler()))
 	}
 
-	serverOpts = append(serverOpts, grpc.ChainUnaryInterceptor(
-		[]grpc.UnaryServerInterceptor{
-			grpcauth.UnaryServerInterceptor(authnmw.AuthFunc(authenticator)),
-		}...),
+	unaryAuthInterceptors, streamAuthInterceptors, err := s.authInterceptors(config, authenticator)
+	if err != nil {
+		return err
+	}
+
+	serverOpts = append(serverOpts, grpc.ChainUnaryInterceptor(unaryAuthInterceptors...),
 		grpc.ChainStreamInterceptor(
-			[]grpc.StreamServerInterceptor{
-				grpcauth.StreamServerInterceptor(authnmw.AuthFunc(authenticator)),
+			append(streamAuthInterceptors,
 				// The following interceptors wrap the server stream with our own
 				// wrapper and must come last.
 				storeid.NewStreamingInterceptor(),
 				logging.NewStreamingLoggingInterceptor(s.Logger),
-			}...,
+			)...,
 		),
 	)
 
@@ -518,7 +822,7 @@ func (s *ServerContext) Run(ctx context.Context, config *serverconfig.Config) er
 		if config.GRPC.TLS.CertPath == "" || config.GRPC.TLS.KeyPath == "" {
 			return errors.New("'grpc.tls.cert' and 'grpc.tls.key' configs must be set")
 		}
-		creds, err := credentials.NewServerTLSFromFile(config.GRPC.TLS.CertPath, config.GRPC.TLS.KeyPath)
+		creds, err := grpcServerTLSCredentials(config)
 		if err != nil {
 			return err
 		}
@@ -532,55 +836,36 @@ func (s *ServerContext) Run(ctx context.Context, config *serverconfig.Config) er
 
 	var profilerServer *http.Server
 	if config.Profiler.Enabled {
-		mux := http.NewServeMux()
-		mux.HandleFunc("/debug/pprof/", pprof.Index)
-		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
-		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
-		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
-		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
-
-		profilerServer = &http.Server{Addr: config.Profiler.Addr, Handler: mux}
-
-		go func() {
-			s.Logger.Info(fmt.Sprintf("🔬 starting pprof profiler on '%s'", config.Profiler.Addr))
-
-			if err := profilerServer.ListenAndServe(); err != nil {
-				if err != http.ErrServerClosed {
-					s.Logger.Fatal("failed to start pprof profiler", zap.Error(err))
-				}
-			}
-			s.Logger.Info("profiler shut down.")
-		}()
+		profilerServer = s.startProfilerServer(config)
 	}
 
 	var metricsServer *http.Server
 	if config.Metrics.Enabled {
-		s.Logger.Info(fmt.Sprintf("📈 starting prometheus metrics server on '%s'", config.Metrics.Addr))
-
-		go func() {
-			mux := http.NewServeMux()
-			mux.Handle("/metrics", promhttp.Handler())
-
-			metricsServer = &http.Server{Addr: config.Metrics.Addr, Handler: mux}
-			if err := metricsServer.ListenAndServe(); err != nil {
-				if err != http.ErrServerClosed {
-					s.Logger.Fatal("failed to start prometheus metrics server", zap.Error(err))
-				}
-			}
-			s.Logger.Info("metrics server shut down.")
-		}()
+		metricsServer = s.startMetricsServer(config)
 	}
 
 	checkDispatchThrottlingConfig := serverconfig.GetCheckDispatchThrottlingConfig(s.Logger, config)
 
-	svr := server.MustNewServerWithOpts(
+	auditLogger, err := s.auditLoggerConfig(config)
+	if err != nil {
+		return err
+	}
+
+	serverOptions := []server.OpenFGAServiceV1Option{
 		server.WithDatastore(datastore),
 		server.WithAuthorizationModelCacheSize(config.Datastore.MaxCacheSize),
 		server.WithLogger(s.Logger),
 		server.WithTransport(gateway.NewRPCTransport(s.Logger)),
 		server.WithResolveNodeLimit(config.ResolveNodeLimit),
+		server.WithDatastoreReadBudget(config.Datastore.ReadBudget),
 		server.WithResolveNodeBreadthLimit(config.ResolveNodeBreadthLimit),
+		server.WithResolveNodeUnionBreadthLimit(config.ResolveNodeUnionBreadthLimit),
+		server.WithResolveNodeTTUBreadthLimit(config.ResolveNodeTTUBreadthLimit),
+		server.WithCheckResolverOrder(config.CheckResolverOrder...),
+		server.WithListObjectsCandidateCheckConcurrencyLimit(config.ListObjectsCandidateCheckConcurrencyLimit),
 		server.WithChangelogHorizonOffset(config.ChangelogHorizonOffset),
+		server.WithTupleExpiryReaperInterval(config.Datastore.TupleExpiryReaperInterval),
+		server.WithShutdownDrainTimeout(config.ShutdownDrainTimeout),
 		server.WithListObjectsDeadline(config.ListObjectsDeadline),
 		server.WithListObjectsMaxResults(config.ListObjectsMaxResults),
 		server.WithListUsersDeadline(config.ListUsersDeadline),
@@ -591,6 +876,9 @@ func (s *ServerContext) Run(ctx context.Context, config *serverconfig.Config) er
 		server.WithCheckQueryCacheEnabled(config.CheckQueryCache.Enabled),
 		server.WithCheckQueryCacheLimit(config.CheckQueryCache.Limit),
 		server.WithCheckQueryCacheTTL(config.CheckQueryCache.TTL),
+		server.WithListObjectsReadCacheEnabled(config.ListObjectsReadCache.Enabled),
+		server.WithListObjectsReadCacheLimit(config.ListObjectsReadCache.Limit),
+		server.WithListObjectsReadCacheTTL(config.ListObjectsReadCache.TTL),
 		server.WithRequestDurationByQueryHistogramBuckets(convertStringArrayToUintArray(config.RequestDurationDatastoreQueryCountBuckets)),
 		server.WithRequestDurationByDispatchCountHistogramBuckets(convertStringArrayToUintArray(config.RequestDurationDispatchCountBuckets)),
 		server.WithMaxAuthorizationModelSizeInBytes(config.MaxAuthorizationModelSizeInBytes),
@@ -602,8 +890,12 @@ func (s *ServerContext) Run(ctx context.Context, config *serverconfig.Config) er
 		server.WithListObjectsDispatchThrottlingFrequency(config.ListObjectsDispatchThrottling.Frequency),
 		server.WithListObjectsDispatchThrottlingThreshold(config.ListObjectsDispatchThrottling.Threshold),
 		server.WithListObjectsDispatchThrottlingMaxThreshold(config.ListObjectsDispatchThrottling.MaxThreshold),
+		server.WithAuditLogger(auditLogger),
 		server.WithExperimentals(experimentals...),
-	)
+	}
+	serverOptions = append(serverOptions, checkReadOptions(config, replica)...)
+
+	svr := server.MustNewServerWithOpts(serverOptions...)
 
 	s.Logger.Info(
 		"starting openfga service...",
@@ -799,15 +1091,13 @@ func (s *ServerContext) Run(ctx context.Context, config *serverconfig.Config) er
 		}()
 	}
 
-	done := make(chan os.Signal, 1)
-	signal.Notify(done, syscall.SIGINT, syscall.SIGTERM)
-
-	select {
-	case <-done:
-	case <-ctx.Done():
-	}
+	s.waitForShutdown(ctx, svr, timeoutMiddleware)
 	s.Logger.Info("attempting to shutdown gracefully...")
 
+	// the requests in flight are drained before the servers stop, which would otherwise wait for them for as
+	// long as they take
+	svr.Drain()
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -839,6 +1129,14 @@ func (s *ServerContext) Run(ctx context.Context, config *serverconfig.Config) er
 
 	svr.Close()
 
+	if auditLogger != nil {
+		auditLogger.Close()
+	}
+
+	if replica != nil {
+		replica.Close()
+	}
+
 	authenticator.Close()
 
 	ctx, cancel = context.WithTimeout(context.Background(), 3*time.Second)
@@ -28,10 +28,13 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	"go.uber.org/goleak"
+	"go.uber.org/zap"
 
+	"github.com/openfga/openfga/pkg/middleware"
 	"github.com/openfga/openfga/pkg/middleware/requestid"
 	"github.com/openfga/openfga/pkg/middleware/storeid"
 	"github.com/openfga/openfga/pkg/server"
+	"github.com/openfga/openfga/pkg/storage/memory"
 	"github.com/openfga/openfga/pkg/testutils"
 
 	"github.com/openfga/openfga/cmd"
@@ -48,7 +51,10 @@ import (
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/require"
 	"github.com/tidwall/gjson"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/encoding/protojson"
 )
 
@@ -646,6 +652,77 @@ func TestBuildServerWithOIDCAuthenticationAlias(t *testing.T) {
 	})
 }
 
+func TestBuildServerWithAccessControl(t *testing.T) {
+	oidcServerPort, oidcServerPortReleaser := testutils.TCPRandomPort()
+	localOIDCServerURL := fmt.Sprintf("http://localhost:%d", oidcServerPort)
+
+	cfg := testutils.MustDefaultConfigWithRandomPorts()
+	cfg.Authn.Method = "oidc"
+	cfg.Authn.AuthnOIDCConfig = &serverconfig.AuthnOIDCConfig{
+		Audience: "openfga.dev",
+		Issuer:   localOIDCServerURL,
+	}
+	cfg.AccessControl = serverconfig.AccessControlConfig{
+		Enabled: true,
+		Grants:  []string{"some-user:*:read"},
+	}
+
+	oidcServerPortReleaser()
+
+	trustedIssuerServer, err := mocks.NewMockOidcServer(localOIDCServerURL)
+	require.NoError(t, err)
+	t.Cleanup(trustedIssuerServer.Stop)
+
+	readerToken, err := trustedIssuerServer.GetToken("openfga.dev", "some-user")
+	require.NoError(t, err)
+
+	otherToken, err := trustedIssuerServer.GetToken("openfga.dev", "other-user")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		if err := runServer(ctx, cfg); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
+	testutils.EnsureServiceHealthy(t, cfg.GRPC.Addr, cfg.HTTP.Addr, nil)
+
+	retryClient := retryablehttp.NewClient()
+
+	t.Run("granted_read_succeeds", func(t *testing.T) {
+		tryGetStores(t, authTest{
+			authHeader:         "Bearer " + readerToken,
+			expectedStatusCode: 200,
+		}, cfg.HTTP.Addr, retryClient)
+	})
+
+	t.Run("read_without_grant_is_forbidden", func(t *testing.T) {
+		tryGetStores(t, authTest{
+			authHeader: "Bearer " + otherToken,
+			expectedErrorResponse: &serverErrors.ErrorResponse{
+				Code:    "PermissionDenied",
+				Message: "the client is not allowed to call ListStores, it requires 'read' access to all the stores",
+			},
+			expectedStatusCode: 403,
+		}, cfg.HTTP.Addr, retryClient)
+	})
+
+	t.Run("create_store_with_read_grant_is_forbidden", func(t *testing.T) {
+		req, err := retryablehttp.NewRequest("POST", fmt.Sprintf("http://%s/stores", cfg.HTTP.Addr), strings.NewReader(`{"name":"store"}`))
+		require.NoError(t, err, "Failed to construct request")
+		req.Header.Set("content-type", "application/json")
+		req.Header.Set("authorization", "Bearer "+readerToken)
+
+		res, err := retryClient.Do(req)
+		require.NoError(t, err, "Failed to execute request")
+		defer res.Body.Close()
+		require.Equal(t, 403, res.StatusCode)
+	})
+}
+
 func TestHTTPServingTLS(t *testing.T) {
 	t.Cleanup(func() {
 		goleak.VerifyNone(t)
@@ -768,6 +845,109 @@ func TestGRPCServingTLS(t *testing.T) {
 	})
 }
 
+func TestGRPCServingMTLSAuthentication(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	caKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		Subject:               pkix.Name{Organization: []string{"Starfleet"}},
+	}
+	caCert, caPEM := genCert(t, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+
+	_, serverPEM, serverKey := genServerCert(t, caCert, caKey)
+
+	clientCredentials := func(commonName string) credentials.TransportCredentials {
+		clientKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+
+		_, clientPEM := genCert(t, &x509.Certificate{
+			SerialNumber: big.NewInt(2),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			NotBefore:    time.Now().Add(-time.Minute),
+			NotAfter:     time.Now().Add(time.Hour),
+			Subject:      pkix.Name{CommonName: commonName},
+		}, caCert, &clientKey.PublicKey, caKey)
+
+		clientCert, err := tls.X509KeyPair(clientPEM, pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(clientKey),
+		}))
+		require.NoError(t, err)
+
+		certPool := x509.NewCertPool()
+		certPool.AddCert(caCert)
+
+		return credentials.NewTLS(&tls.Config{
+			Certificates: []tls.Certificate{clientCert},
+			RootCAs:      certPool,
+			MinVersion:   tls.VersionTLS12,
+		})
+	}
+
+	serverCertFile := writeToTempFile(t, serverPEM)
+	serverKeyFile := writeToTempFile(t, pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(serverKey),
+	}))
+	clientCAFile := writeToTempFile(t, caPEM)
+	t.Cleanup(func() {
+		os.Remove(serverCertFile.Name())
+		os.Remove(serverKeyFile.Name())
+		os.Remove(clientCAFile.Name())
+	})
+
+	cfg := testutils.MustDefaultConfigWithRandomPorts()
+	cfg.HTTP.Enabled = false
+	cfg.Playground.Enabled = false
+	cfg.GRPC.TLS = &serverconfig.TLSConfig{
+		Enabled:  true,
+		CertPath: serverCertFile.Name(),
+		KeyPath:  serverKeyFile.Name(),
+	}
+	// Port for TLS cannot be 0.0.0.0
+	cfg.GRPC.Addr = strings.ReplaceAll(cfg.GRPC.Addr, "0.0.0.0", "localhost")
+	cfg.Authn.Method = "mtls"
+	cfg.Authn.AuthnMTLSConfig = &serverconfig.AuthnMTLSConfig{
+		ClientCAPath:    clientCAFile.Name(),
+		AllowedSubjects: []string{"openfga client"},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		if err := runServer(ctx, cfg); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
+	testutils.EnsureServiceHealthy(t, cfg.GRPC.Addr, "", clientCredentials("openfga client"))
+
+	listStores := func(creds credentials.TransportCredentials) error {
+		conn, err := grpc.NewClient(cfg.GRPC.Addr, grpc.WithTransportCredentials(creds))
+		require.NoError(t, err)
+		defer conn.Close()
+
+		_, err = openfgav1.NewOpenFGAServiceClient(conn).ListStores(context.Background(), &openfgav1.ListStoresRequest{})
+		return err
+	}
+
+	require.NoError(t, listStores(clientCredentials("openfga client")))
+
+	err = listStores(clientCredentials("other client"))
+	require.Equal(t, codes.Code(openfgav1.AuthErrorCode_auth_failed_invalid_subject), status.Code(err))
+}
+
 func TestServerMetricsReporting(t *testing.T) {
 	t.Cleanup(func() {
 		goleak.VerifyNone(t)
@@ -1006,6 +1186,22 @@ func TestDefaultConfig(t *testing.T) {
 	val = res.Get("properties.datastore.properties.connMaxLifetime.default")
 	require.True(t, val.Exists())
 
+	val = res.Get("properties.datastore.properties.tupleExpiryReaperInterval.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.String(), cfg.Datastore.TupleExpiryReaperInterval.String())
+
+	val = res.Get("properties.datastore.properties.storeStatsRefreshInterval.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.String(), cfg.Datastore.StoreStatsRefreshInterval.String())
+
+	val = res.Get("properties.datastore.properties.usersetBatchWindow.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.String(), cfg.Datastore.UsersetBatchWindow.String())
+
+	val = res.Get("properties.datastore.properties.usersetBatchMaxSize.default")
+	require.True(t, val.Exists())
+	require.EqualValues(t, val.Int(), cfg.Datastore.UsersetBatchMaxSize)
+
 	val = res.Get("properties.datastore.properties.metrics.properties.enabled.default")
 	require.True(t, val.Exists())
 	require.False(t, val.Bool())
@@ -1078,6 +1274,25 @@ func TestDefaultConfig(t *testing.T) {
 	require.True(t, val.Exists())
 	require.EqualValues(t, val.Int(), cfg.ResolveNodeBreadthLimit)
 
+	val = res.Get("properties.resolveNodeUnionBreadthLimit.default")
+	require.True(t, val.Exists())
+	require.EqualValues(t, val.Int(), cfg.ResolveNodeUnionBreadthLimit)
+
+	val = res.Get("properties.resolveNodeTTUBreadthLimit.default")
+	require.True(t, val.Exists())
+	require.EqualValues(t, val.Int(), cfg.ResolveNodeTTUBreadthLimit)
+
+	val = res.Get("properties.checkResolverOrder.default")
+	require.True(t, val.Exists())
+	require.Len(t, val.Array(), len(cfg.CheckResolverOrder))
+	for i, layer := range val.Array() {
+		require.Equal(t, layer.String(), cfg.CheckResolverOrder[i])
+	}
+
+	val = res.Get("properties.listObjectsCandidateCheckConcurrencyLimit.default")
+	require.True(t, val.Exists())
+	require.EqualValues(t, val.Int(), cfg.ListObjectsCandidateCheckConcurrencyLimit)
+
 	val = res.Get("properties.resolveNodeLimit.default")
 	require.True(t, val.Exists())
 	require.EqualValues(t, val.Int(), cfg.ResolveNodeLimit)
@@ -1146,6 +1361,54 @@ func TestDefaultConfig(t *testing.T) {
 	require.True(t, val.Exists())
 	require.Equal(t, val.String(), cfg.CheckQueryCache.TTL.String())
 
+	val = res.Get("properties.admissionControl.properties.enabled.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.Bool(), cfg.AdmissionControl.Enabled)
+
+	val = res.Get("properties.admissionControl.properties.defaultClass.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.String(), cfg.AdmissionControl.DefaultClass)
+
+	val = res.Get("properties.admissionControl.properties.queueTimeout.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.String(), cfg.AdmissionControl.QueueTimeout.String())
+
+	val = res.Get("properties.audit.properties.enabled.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.Bool(), cfg.Audit.Enabled)
+
+	val = res.Get("properties.audit.properties.sink.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.String(), cfg.Audit.Sink)
+
+	val = res.Get("properties.audit.properties.fileMaxSize.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.Int(), cfg.Audit.FileMaxSize)
+
+	val = res.Get("properties.audit.properties.fileMaxBackups.default")
+	require.True(t, val.Exists())
+	require.EqualValues(t, val.Int(), cfg.Audit.FileMaxBackups)
+
+	val = res.Get("properties.audit.properties.checkSampleRate.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.Float(), cfg.Audit.CheckSampleRate)
+
+	val = res.Get("properties.audit.properties.bufferSize.default")
+	require.True(t, val.Exists())
+	require.EqualValues(t, val.Int(), cfg.Audit.BufferSize)
+
+	val = res.Get("properties.listObjectsReadCache.properties.enabled.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.Bool(), cfg.ListObjectsReadCache.Enabled)
+
+	val = res.Get("properties.listObjectsReadCache.properties.limit.default")
+	require.True(t, val.Exists())
+	require.EqualValues(t, val.Int(), cfg.ListObjectsReadCache.Limit)
+
+	val = res.Get("properties.listObjectsReadCache.properties.ttl.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.String(), cfg.ListObjectsReadCache.TTL.String())
+
 	val = res.Get("properties.requestDurationDatastoreQueryCountBuckets.default")
 	require.True(t, val.Exists())
 	require.Equal(t, len(val.Array()), len(cfg.RequestDurationDatastoreQueryCountBuckets))
@@ -1207,6 +1470,10 @@ func TestDefaultConfig(t *testing.T) {
 	val = res.Get("properties.requestTimeout.default")
 	require.True(t, val.Exists())
 	require.EqualValues(t, val.String(), cfg.RequestTimeout.String())
+
+	val = res.Get("properties.shutdownDrainTimeout.default")
+	require.True(t, val.Exists())
+	require.EqualValues(t, val.String(), cfg.ShutdownDrainTimeout.String())
 }
 
 func TestRunCommandNoConfigDefaultValues(t *testing.T) {
@@ -1430,3 +1697,62 @@ func TestHTTPHeaders(t *testing.T) {
 		})
 	}
 }
+
+func TestReloadConfig(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	svr := server.MustNewServerWithOpts(
+		server.WithDatastore(ds),
+		server.WithCheckQueryCacheEnabled(true),
+		server.WithCheckQueryCacheTTL(time.Minute),
+	)
+	t.Cleanup(svr.Close)
+
+	logLevel := zap.NewAtomicLevelAt(zap.InfoLevel)
+	serverCtx := &ServerContext{Logger: logger.NewNoopLogger(), LogLevel: &logLevel}
+	timeoutInterceptor := middleware.NewTimeoutInterceptor(time.Second, serverCtx.Logger)
+
+	t.Run("applies_the_runtime_tunable_settings", func(t *testing.T) {
+		err := serverCtx.reloadConfig(svr, timeoutInterceptor, func() (*serverconfig.Config, error) {
+			cfg := serverconfig.DefaultConfig()
+			cfg.CheckQueryCache.TTL = 5 * time.Second
+			cfg.CheckDispatchThrottling.Threshold = 20
+			cfg.CheckDispatchThrottling.MaxThreshold = 40
+			cfg.Log.Level = "debug"
+			return cfg, nil
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, server.RuntimeConfig{
+			CheckQueryCacheTTL:                      5 * time.Second,
+			CheckDispatchThrottlingDefaultThreshold: 20,
+			CheckDispatchThrottlingMaxThreshold:     40,
+		}, svr.RuntimeConfig())
+		require.Equal(t, zap.DebugLevel, logLevel.Level())
+	})
+
+	t.Run("invalid_config_is_not_applied", func(t *testing.T) {
+		err := serverCtx.reloadConfig(svr, timeoutInterceptor, func() (*serverconfig.Config, error) {
+			cfg := serverconfig.DefaultConfig()
+			cfg.CheckQueryCache.TTL = time.Hour
+			cfg.Log.Level = "loud"
+			return cfg, nil
+		})
+		require.Error(t, err)
+
+		require.Equal(t, 5*time.Second, svr.RuntimeConfig().CheckQueryCacheTTL)
+		require.Equal(t, zap.DebugLevel, logLevel.Level())
+	})
+
+	t.Run("unreadable_config", func(t *testing.T) {
+		err := serverCtx.reloadConfig(svr, timeoutInterceptor, func() (*serverconfig.Config, error) {
+			return nil, fmt.Errorf("failed to load server config")
+		})
+		require.Error(t, err)
+	})
+}
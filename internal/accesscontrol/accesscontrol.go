@@ -0,0 +1,191 @@
+// Package accesscontrol scopes the authenticated clients of the server to stores and operations.
+package accesscontrol
+
+import (
+	"fmt"
+	"strings"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// AnySubject is the subject of the grants that apply to every authenticated client.
+	AnySubject = "*"
+
+	// AnyStore is the store ID of the grants that apply to every store, and to the operations
+	// that are not about one store, e.g. CreateStore.
+	AnyStore = "*"
+)
+
+// Level is the level of access of a grant, each level allows the operations of the lower ones.
+type Level int
+
+const (
+	// LevelRead allows the queries, e.g. Read, Check and ListObjects.
+	LevelRead Level = iota + 1
+	// LevelWrite allows to write tuples and assertions.
+	LevelWrite
+	// LevelAdmin allows to write authorization models, and to create, update and delete stores.
+	LevelAdmin
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelRead:
+		return "read"
+	case LevelWrite:
+		return "write"
+	case LevelAdmin:
+		return "admin"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseLevel returns the Level named 'read', 'write' or 'admin'.
+func ParseLevel(s string) (Level, error) {
+	switch s {
+	case "read":
+		return LevelRead, nil
+	case "write":
+		return LevelWrite, nil
+	case "admin":
+		return LevelAdmin, nil
+	default:
+		return 0, fmt.Errorf("unknown access level '%s', must be one of 'read', 'write' or 'admin'", s)
+	}
+}
+
+// Grant gives a level of access to a store to a client, identified by the subject of its auth claims.
+type Grant struct {
+	Subject string
+	StoreID string
+	Level   Level
+}
+
+// ParseGrant parses a grant of the form '<subject>:<store ID>:<level>', e.g. 'client-a:01HVMMBCMGZNT3SED4Z17ECXCA:write'.
+// The subject may contain ':' since the store ID and the level never do.
+func ParseGrant(s string) (Grant, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) < 3 {
+		return Grant{}, fmt.Errorf("invalid grant '%s', must be of the form '<subject>:<store ID>:<level>'", s)
+	}
+
+	level, err := ParseLevel(parts[len(parts)-1])
+	if err != nil {
+		return Grant{}, fmt.Errorf("invalid grant '%s': %w", s, err)
+	}
+
+	grant := Grant{
+		Subject: strings.Join(parts[:len(parts)-2], ":"),
+		StoreID: parts[len(parts)-2],
+		Level:   level,
+	}
+	if grant.Subject == "" || grant.StoreID == "" {
+		return Grant{}, fmt.Errorf("invalid grant '%s', the subject and the store ID must not be empty", s)
+	}
+
+	return grant, nil
+}
+
+// Policy decides which operations the clients are allowed to perform according to their grants.
+type Policy struct {
+	grants []Grant
+}
+
+// NewPolicy returns a Policy allowing the operations of the grants, any other is denied.
+func NewPolicy(grants []Grant) *Policy {
+	return &Policy{grants: grants}
+}
+
+// NewPolicyFromStrings returns a Policy with the grants parsed by ParseGrant.
+func NewPolicyFromStrings(grants []string) (*Policy, error) {
+	parsed := make([]Grant, 0, len(grants))
+	for _, g := range grants {
+		grant, err := ParseGrant(g)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, grant)
+	}
+
+	return NewPolicy(parsed), nil
+}
+
+// Allowed returns true if a grant gives the subject at least the level of access to the store.
+// An empty store ID, for the operations that are not about one store, is only matched by grants on AnyStore.
+func (p *Policy) Allowed(subject, storeID string, level Level) bool {
+	for _, grant := range p.grants {
+		if grant.Subject != AnySubject && grant.Subject != subject {
+			continue
+		}
+
+		if grant.StoreID != AnyStore && grant.StoreID != storeID {
+			continue
+		}
+
+		if grant.Level >= level {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Authorize returns a PermissionDenied error if the subject isn't allowed to call the method of the
+// OpenFGA service on the store. The methods of other services are always allowed.
+func (p *Policy) Authorize(subject, fullMethod, storeID string) error {
+	level, ok := RequiredLevel(fullMethod)
+	if !ok {
+		return nil
+	}
+
+	if p.Allowed(subject, storeID, level) {
+		return nil
+	}
+
+	method := strings.TrimPrefix(fullMethod, "/"+openfgav1.OpenFGAService_ServiceDesc.ServiceName+"/")
+	if storeID == "" {
+		return status.Errorf(codes.PermissionDenied, "the client is not allowed to call %s, it requires '%s' access to all the stores", method, level)
+	}
+
+	return status.Errorf(codes.PermissionDenied, "the client is not allowed to call %s on store '%s', it requires '%s' access", method, storeID, level)
+}
+
+var requiredLevels = map[string]Level{
+	openfgav1.OpenFGAService_Read_FullMethodName:                    LevelRead,
+	openfgav1.OpenFGAService_Check_FullMethodName:                   LevelRead,
+	openfgav1.OpenFGAService_Expand_FullMethodName:                  LevelRead,
+	openfgav1.OpenFGAService_ReadAuthorizationModels_FullMethodName: LevelRead,
+	openfgav1.OpenFGAService_ReadAuthorizationModel_FullMethodName:  LevelRead,
+	openfgav1.OpenFGAService_ReadAssertions_FullMethodName:          LevelRead,
+	openfgav1.OpenFGAService_ReadChanges_FullMethodName:             LevelRead,
+	openfgav1.OpenFGAService_GetStore_FullMethodName:                LevelRead,
+	openfgav1.OpenFGAService_ListStores_FullMethodName:              LevelRead,
+	openfgav1.OpenFGAService_StreamedListObjects_FullMethodName:     LevelRead,
+	openfgav1.OpenFGAService_ListObjects_FullMethodName:             LevelRead,
+	openfgav1.OpenFGAService_ListUsers_FullMethodName:               LevelRead,
+	openfgav1.OpenFGAService_Write_FullMethodName:                   LevelWrite,
+	openfgav1.OpenFGAService_WriteAssertions_FullMethodName:         LevelWrite,
+	openfgav1.OpenFGAService_WriteAuthorizationModel_FullMethodName: LevelAdmin,
+	openfgav1.OpenFGAService_CreateStore_FullMethodName:             LevelAdmin,
+	openfgav1.OpenFGAService_UpdateStore_FullMethodName:             LevelAdmin,
+	openfgav1.OpenFGAService_DeleteStore_FullMethodName:             LevelAdmin,
+}
+
+// RequiredLevel returns the level of access required to call a method of the OpenFGA service, and false
+// if the method belongs to another service. The methods of the OpenFGA service this package doesn't know
+// about require LevelAdmin.
+func RequiredLevel(fullMethod string) (Level, bool) {
+	if !strings.HasPrefix(fullMethod, "/"+openfgav1.OpenFGAService_ServiceDesc.ServiceName+"/") {
+		return 0, false
+	}
+
+	if level, ok := requiredLevels[fullMethod]; ok {
+		return level, true
+	}
+
+	return LevelAdmin, true
+}
@@ -0,0 +1,124 @@
+package accesscontrol
+
+import (
+	"testing"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseGrant(t *testing.T) {
+	tests := map[string]struct {
+		grant         string
+		expected      Grant
+		expectedError string
+	}{
+		"store_grant": {
+			grant:    "client-a:01HVMMBCMGZNT3SED4Z17ECXCA:write",
+			expected: Grant{Subject: "client-a", StoreID: "01HVMMBCMGZNT3SED4Z17ECXCA", Level: LevelWrite},
+		},
+		"subject_with_colons": {
+			grant:    "https://issuer.example.com/client:*:admin",
+			expected: Grant{Subject: "https://issuer.example.com/client", StoreID: AnyStore, Level: LevelAdmin},
+		},
+		"any_subject": {
+			grant:    "*:*:read",
+			expected: Grant{Subject: AnySubject, StoreID: AnyStore, Level: LevelRead},
+		},
+		"unknown_level": {
+			grant:         "client-a:01HVMMBCMGZNT3SED4Z17ECXCA:owner",
+			expectedError: "invalid grant 'client-a:01HVMMBCMGZNT3SED4Z17ECXCA:owner': unknown access level 'owner'",
+		},
+		"too_few_parts": {
+			grant:         "client-a:read",
+			expectedError: "invalid grant 'client-a:read', must be of the form '<subject>:<store ID>:<level>'",
+		},
+		"empty_subject": {
+			grant:         ":*:read",
+			expectedError: "the subject and the store ID must not be empty",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			grant, err := ParseGrant(test.grant)
+			if test.expectedError != "" {
+				require.ErrorContains(t, err, test.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, test.expected, grant)
+		})
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	policy, err := NewPolicyFromStrings([]string{
+		"reader:store-1:read",
+		"writer:store-1:write",
+		"admin:*:admin",
+		"*:store-2:read",
+	})
+	require.NoError(t, err)
+
+	t.Run("allowed", func(t *testing.T) {
+		require.True(t, policy.Allowed("reader", "store-1", LevelRead))
+		require.False(t, policy.Allowed("reader", "store-1", LevelWrite))
+		require.False(t, policy.Allowed("reader", "store-3", LevelRead))
+
+		require.True(t, policy.Allowed("writer", "store-1", LevelRead))
+		require.True(t, policy.Allowed("writer", "store-1", LevelWrite))
+		require.False(t, policy.Allowed("writer", "store-1", LevelAdmin))
+
+		require.True(t, policy.Allowed("admin", "store-3", LevelAdmin))
+		require.True(t, policy.Allowed("admin", "", LevelAdmin))
+
+		// grants on a store don't allow the operations that are not about one store
+		require.False(t, policy.Allowed("writer", "", LevelRead))
+
+		require.True(t, policy.Allowed("anyone", "store-2", LevelRead))
+		require.True(t, policy.Allowed("", "store-2", LevelRead))
+		require.False(t, policy.Allowed("anyone", "store-2", LevelWrite))
+	})
+
+	t.Run("authorize", func(t *testing.T) {
+		require.NoError(t, policy.Authorize("reader", openfgav1.OpenFGAService_Check_FullMethodName, "store-1"))
+		require.NoError(t, policy.Authorize("writer", openfgav1.OpenFGAService_Write_FullMethodName, "store-1"))
+		require.NoError(t, policy.Authorize("admin", openfgav1.OpenFGAService_CreateStore_FullMethodName, ""))
+
+		err := policy.Authorize("reader", openfgav1.OpenFGAService_Write_FullMethodName, "store-1")
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+		require.ErrorContains(t, err, "the client is not allowed to call Write on store 'store-1', it requires 'write' access")
+
+		err = policy.Authorize("writer", openfgav1.OpenFGAService_ListStores_FullMethodName, "")
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+		require.ErrorContains(t, err, "the client is not allowed to call ListStores, it requires 'read' access to all the stores")
+
+		// the methods of other services are not controlled
+		require.NoError(t, policy.Authorize("reader", "/grpc.health.v1.Health/Check", ""))
+	})
+}
+
+func TestRequiredLevel(t *testing.T) {
+	level, ok := RequiredLevel(openfgav1.OpenFGAService_ListObjects_FullMethodName)
+	require.True(t, ok)
+	require.Equal(t, LevelRead, level)
+
+	level, ok = RequiredLevel(openfgav1.OpenFGAService_WriteAssertions_FullMethodName)
+	require.True(t, ok)
+	require.Equal(t, LevelWrite, level)
+
+	level, ok = RequiredLevel(openfgav1.OpenFGAService_WriteAuthorizationModel_FullMethodName)
+	require.True(t, ok)
+	require.Equal(t, LevelAdmin, level)
+
+	level, ok = RequiredLevel("/openfga.v1.OpenFGAService/SomeNewMethod")
+	require.True(t, ok)
+	require.Equal(t, LevelAdmin, level)
+
+	_, ok = RequiredLevel("/grpc.health.v1.Health/Check")
+	require.False(t, ok)
+}
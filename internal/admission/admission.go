@@ -0,0 +1,270 @@
+// Package admission bounds the number of requests of the server running concurrently, separately for
+// each priority class of requests, so that e.g. bulk ListObjects jobs cannot delay the interactive Checks.
+package admission
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/openfga/openfga/internal/build"
+)
+
+// PriorityClassHeader is the request header a client may lower the class of its request with, e.g. to run a
+// backfill as bulk. Over HTTP, it must be sent as 'Grpc-Metadata-Openfga-Priority-Class'.
+const PriorityClassHeader = "openfga-priority-class"
+
+var (
+	admissionQueueDurationHistogram = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:                       build.ProjectName,
+		Name:                            "admission_queue_duration_ms",
+		Help:                            "The time the requests admitted by the admission control waited for a slot of their priority class.",
+		Buckets:                         []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 5000},
+		NativeHistogramBucketFactor:     1.1,
+		NativeHistogramMaxBucketNumber:  100,
+		NativeHistogramMinResetDuration: time.Hour,
+	}, []string{"class"})
+
+	admissionRejectedCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: build.ProjectName,
+		Name:      "admission_rejected_count",
+		Help:      "The total number of requests rejected by the admission control, by priority class and by reason: the queue of the class was full (queue_full), or the request waited for longer than the queue timeout (queue_timeout).",
+	}, []string{"class", "reason"})
+
+	admissionInFlightGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: build.ProjectName,
+		Name:      "admission_in_flight_requests",
+		Help:      "The number of requests admitted by the admission control that are running, by priority class.",
+	}, []string{"class"})
+
+	admissionQueuedGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: build.ProjectName,
+		Name:      "admission_queued_requests",
+		Help:      "The number of requests waiting for a slot of their priority class, by priority class.",
+	}, []string{"class"})
+)
+
+// Class is a priority class of requests, with its own concurrency limit and queue.
+type Class struct {
+	Name string
+
+	// MaxConcurrent is the maximum number of requests of the class running concurrently.
+	MaxConcurrent uint32
+
+	// MaxQueued is the maximum number of requests of the class waiting for one of the running ones to
+	// complete. The requests beyond it are rejected right away.
+	MaxQueued uint32
+}
+
+// ParseClass parses a class of the form '<name>:<max concurrent>:<max queued>', e.g. 'bulk:20:100'.
+func ParseClass(s string) (Class, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 || parts[0] == "" {
+		return Class{}, fmt.Errorf("invalid priority class '%s', must be of the form '<name>:<max concurrent>:<max queued>'", s)
+	}
+
+	maxConcurrent, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil || maxConcurrent == 0 {
+		return Class{}, fmt.Errorf("invalid priority class '%s', the max concurrent requests must be a positive integer", s)
+	}
+
+	maxQueued, err := strconv.ParseUint(parts[2], 10, 32)
+	if err != nil {
+		return Class{}, fmt.Errorf("invalid priority class '%s', the max queued requests must be a non-negative integer", s)
+	}
+
+	return Class{Name: parts[0], MaxConcurrent: uint32(maxConcurrent), MaxQueued: uint32(maxQueued)}, nil
+}
+
+// ParseAssignment parses an assignment of a key to a class of the form '<key>:<class>', e.g. 'ListObjects:bulk'
+// or 'client-a:bulk'. The key may contain ':' since the class name never does.
+func ParseAssignment(s string) (string, string, error) {
+	i := strings.LastIndex(s, ":")
+	if i <= 0 || i == len(s)-1 {
+		return "", "", fmt.Errorf("invalid priority class assignment '%s', must be of the form '<key>:<class>'", s)
+	}
+
+	return s[:i], s[i+1:], nil
+}
+
+// Config is the configuration of a Controller, whose classes and assignments are parsed by ParseClass and
+// ParseAssignment.
+type Config struct {
+	// Classes are the priority classes, each of the form '<name>:<max concurrent>:<max queued>', from the
+	// highest priority to the lowest.
+	Classes []string
+
+	// Methods assign the methods of the OpenFGA service to classes, each of the form '<method>:<class>',
+	// e.g. 'ListObjects:bulk'.
+	Methods []string
+
+	// Clients assign the clients, identified by the subject of their auth claims, to classes, each of the
+	// form '<subject>:<class>'. They take precedence over the methods.
+	Clients []string
+
+	// DefaultClass is the class of the requests no assignment selects a class for.
+	DefaultClass string
+
+	// QueueTimeout is the maximum time a request waits in the queue of its class, 0 if it waits until it is
+	// canceled.
+	QueueTimeout time.Duration
+}
+
+// Controller admits the requests of each priority class up to the concurrency limit of the class, queues the
+// ones beyond it, up to the queue limit of the class, and rejects the others with a ResourceExhausted error.
+// It is safe for concurrent use.
+type Controller struct {
+	classes       map[string]*limiter
+	methodClasses map[string]string
+	clientClasses map[string]string
+	defaultClass  string
+	queueTimeout  time.Duration
+}
+
+type limiter struct {
+	class Class
+	// rank is the position of the class in the configuration, 0 for the highest priority
+	rank   int
+	slots  chan struct{}
+	queued atomic.Int64
+}
+
+// NewController returns a Controller for the configuration, or an error if it is invalid, e.g. if it assigns
+// a class it does not define.
+func NewController(cfg Config) (*Controller, error) {
+	c := &Controller{
+		classes:       map[string]*limiter{},
+		methodClasses: map[string]string{},
+		clientClasses: map[string]string{},
+		defaultClass:  cfg.DefaultClass,
+		queueTimeout:  cfg.QueueTimeout,
+	}
+
+	for i, s := range cfg.Classes {
+		class, err := ParseClass(s)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := c.classes[class.Name]; ok {
+			return nil, fmt.Errorf("priority class '%s' is defined twice", class.Name)
+		}
+
+		c.classes[class.Name] = &limiter{class: class, rank: i, slots: make(chan struct{}, class.MaxConcurrent)}
+	}
+
+	if _, ok := c.classes[c.defaultClass]; !ok {
+		return nil, fmt.Errorf("the default priority class '%s' is not defined", c.defaultClass)
+	}
+
+	for _, s := range cfg.Methods {
+		method, class, err := ParseAssignment(s)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := c.classes[class]; !ok {
+			return nil, fmt.Errorf("invalid priority class assignment '%s', the class is not defined", s)
+		}
+
+		c.methodClasses["/"+openfgav1.OpenFGAService_ServiceDesc.ServiceName+"/"+method] = class
+	}
+
+	for _, s := range cfg.Clients {
+		subject, class, err := ParseAssignment(s)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := c.classes[class]; !ok {
+			return nil, fmt.Errorf("invalid priority class assignment '%s', the class is not defined", s)
+		}
+
+		c.clientClasses[subject] = class
+	}
+
+	return c, nil
+}
+
+// Classify returns the class of a call to the full method by the client with the subject, which selected the
+// headerClass with the PriorityClassHeader, if any. The class assigned to the client comes first, then the
+// class assigned to the method, then the default class. The class of the header replaces it only if it is
+// defined and has a lower priority, so that a client cannot jump the queue of its class.
+func (c *Controller) Classify(subject, headerClass, fullMethod string) string {
+	class := c.defaultClass
+	if clientClass, ok := c.clientClasses[subject]; ok && subject != "" {
+		class = clientClass
+	} else if methodClass, ok := c.methodClasses[fullMethod]; ok {
+		class = methodClass
+	}
+
+	if l, ok := c.classes[headerClass]; ok && l.rank > c.classes[class].rank {
+		return headerClass
+	}
+
+	return class
+}
+
+// Admit waits for a slot of the class, and returns the function releasing it once the request is complete. It
+// returns a ResourceExhausted error if the queue of the class is full or if the request waits for longer than
+// the queue timeout, or the error of ctx if it is done first.
+func (c *Controller) Admit(ctx context.Context, class string) (func(), error) {
+	l, ok := c.classes[class]
+	if !ok {
+		l = c.classes[c.defaultClass]
+	}
+	name := l.class.Name
+
+	release := func() {
+		<-l.slots
+		admissionInFlightGauge.WithLabelValues(name).Dec()
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		admissionInFlightGauge.WithLabelValues(name).Inc()
+		admissionQueueDurationHistogram.WithLabelValues(name).Observe(0)
+		return release, nil
+	default:
+	}
+
+	if l.queued.Add(1) > int64(l.class.MaxQueued) {
+		l.queued.Add(-1)
+		admissionRejectedCounter.WithLabelValues(name, "queue_full").Inc()
+		return nil, status.Errorf(codes.ResourceExhausted, "too many requests of priority class '%s' are queued", name)
+	}
+	admissionQueuedGauge.WithLabelValues(name).Inc()
+	defer func() {
+		l.queued.Add(-1)
+		admissionQueuedGauge.WithLabelValues(name).Dec()
+	}()
+
+	var timeout <-chan time.Time
+	if c.queueTimeout > 0 {
+		timer := time.NewTimer(c.queueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	start := time.Now()
+	select {
+	case l.slots <- struct{}{}:
+		admissionInFlightGauge.WithLabelValues(name).Inc()
+		admissionQueueDurationHistogram.WithLabelValues(name).Observe(float64(time.Since(start).Milliseconds()))
+		return release, nil
+	case <-timeout:
+		admissionRejectedCounter.WithLabelValues(name, "queue_timeout").Inc()
+		return nil, status.Errorf(codes.ResourceExhausted, "the request of priority class '%s' was queued for longer than %s", name, c.queueTimeout)
+	case <-ctx.Done():
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+}
@@ -0,0 +1,151 @@
+package admission
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseClass(t *testing.T) {
+	tests := map[string]struct {
+		class         string
+		expected      Class
+		expectedError string
+	}{
+		"valid": {
+			class:    "bulk:20:100",
+			expected: Class{Name: "bulk", MaxConcurrent: 20, MaxQueued: 100},
+		},
+		"no_queue": {
+			class:    "bulk:20:0",
+			expected: Class{Name: "bulk", MaxConcurrent: 20},
+		},
+		"too_few_parts": {
+			class:         "bulk:20",
+			expectedError: "invalid priority class 'bulk:20', must be of the form '<name>:<max concurrent>:<max queued>'",
+		},
+		"no_concurrency": {
+			class:         "bulk:0:100",
+			expectedError: "invalid priority class 'bulk:0:100', the max concurrent requests must be a positive integer",
+		},
+		"negative_queue": {
+			class:         "bulk:20:-1",
+			expectedError: "invalid priority class 'bulk:20:-1', the max queued requests must be a non-negative integer",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			class, err := ParseClass(test.class)
+			if test.expectedError != "" {
+				require.EqualError(t, err, test.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, test.expected, class)
+		})
+	}
+}
+
+func TestNewController(t *testing.T) {
+	_, err := NewController(Config{Classes: []string{"interactive:1:1"}, DefaultClass: "bulk"})
+	require.EqualError(t, err, "the default priority class 'bulk' is not defined")
+
+	_, err = NewController(Config{Classes: []string{"interactive:1:1", "interactive:2:2"}, DefaultClass: "interactive"})
+	require.EqualError(t, err, "priority class 'interactive' is defined twice")
+
+	_, err = NewController(Config{Classes: []string{"interactive:1:1"}, Clients: []string{"client-a"}, DefaultClass: "interactive"})
+	require.EqualError(t, err, "invalid priority class assignment 'client-a', must be of the form '<key>:<class>'")
+}
+
+func TestClassify(t *testing.T) {
+	controller, err := NewController(Config{
+		Classes:      []string{"interactive:1:1", "bulk:1:1"},
+		Methods:      []string{"ListObjects:bulk"},
+		Clients:      []string{"https://issuer.example.com/batch-job:bulk"},
+		DefaultClass: "interactive",
+	})
+	require.NoError(t, err)
+
+	check := openfgav1.OpenFGAService_Check_FullMethodName
+	listObjects := openfgav1.OpenFGAService_ListObjects_FullMethodName
+
+	require.Equal(t, "interactive", controller.Classify("", "", check))
+	require.Equal(t, "bulk", controller.Classify("", "", listObjects))
+
+	// the header only lowers the class to a defined one
+	require.Equal(t, "bulk", controller.Classify("", "bulk", check))
+	require.Equal(t, "bulk", controller.Classify("", "interactive", listObjects))
+	require.Equal(t, "interactive", controller.Classify("", "unknown", check))
+
+	// the class of the client comes before the one of the method, and cannot be raised by the header either
+	require.Equal(t, "bulk", controller.Classify("https://issuer.example.com/batch-job", "", check))
+	require.Equal(t, "bulk", controller.Classify("https://issuer.example.com/batch-job", "interactive", check))
+}
+
+func TestAdmit(t *testing.T) {
+	ctx := context.Background()
+
+	controller, err := NewController(Config{
+		Classes:      []string{"interactive:1:1", "bulk:1:0"},
+		DefaultClass: "interactive",
+		QueueTimeout: 50 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	release, err := controller.Admit(ctx, "interactive")
+	require.NoError(t, err)
+
+	t.Run("classes_have_separate_limits", func(t *testing.T) {
+		releaseBulk, err := controller.Admit(ctx, "bulk")
+		require.NoError(t, err)
+		defer releaseBulk()
+
+		// the queue of the bulk class is empty
+		_, err = controller.Admit(ctx, "bulk")
+		require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	})
+
+	t.Run("queued_requests_time_out", func(t *testing.T) {
+		_, err := controller.Admit(ctx, "interactive")
+		require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	})
+
+	t.Run("queued_requests_are_admitted_once_a_slot_is_released", func(t *testing.T) {
+		admitted := make(chan error)
+		go func() {
+			release, err := controller.Admit(ctx, "interactive")
+			if err == nil {
+				release()
+			}
+			admitted <- err
+		}()
+
+		// the queue holds one request only
+		require.Eventually(t, func() bool {
+			_, err := controller.Admit(ctx, "interactive")
+			return status.Code(err) == codes.ResourceExhausted && controller.classes["interactive"].queued.Load() == 1
+		}, time.Second, time.Millisecond)
+
+		release()
+		require.NoError(t, <-admitted)
+	})
+
+	t.Run("queued_requests_are_canceled_with_their_context", func(t *testing.T) {
+		release, err := controller.Admit(ctx, "interactive")
+		require.NoError(t, err)
+		defer release()
+
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		_, err = controller.Admit(ctx, "interactive")
+		require.Equal(t, codes.Canceled, status.Code(err))
+	})
+}
@@ -0,0 +1,63 @@
+package mtls
+
+import (
+	"context"
+	"slices"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/openfga/openfga/internal/authn"
+)
+
+var (
+	errMissingClientCertificate = status.Error(codes.Code(openfgav1.AuthErrorCode_unauthenticated), "missing verified client certificate")
+	errInvalidSubject           = status.Error(codes.Code(openfgav1.AuthErrorCode_auth_failed_invalid_subject), "invalid subject")
+)
+
+// ClientCertificateAuthenticator authenticates the clients presenting a TLS client certificate verified
+// by the server, i.e. signed by one of the client CAs the TLS configuration of the server trusts.
+// The subject of the claims is the common name of the certificate.
+type ClientCertificateAuthenticator struct {
+	// AllowedSubjects are the common names of the certificates that are accepted, if not empty.
+	AllowedSubjects []string
+}
+
+var _ authn.Authenticator = (*ClientCertificateAuthenticator)(nil)
+
+func NewClientCertificateAuthenticator(allowedSubjects []string) *ClientCertificateAuthenticator {
+	return &ClientCertificateAuthenticator{AllowedSubjects: allowedSubjects}
+}
+
+func (c *ClientCertificateAuthenticator) Authenticate(ctx context.Context) (*authn.AuthClaims, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, errMissingClientCertificate
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil, errMissingClientCertificate
+	}
+
+	// the chains are only set if the certificate of the client was verified during the handshake
+	chains := tlsInfo.State.VerifiedChains
+	if len(chains) == 0 || len(chains[0]) == 0 {
+		return nil, errMissingClientCertificate
+	}
+
+	subject := chains[0][0].Subject.CommonName
+	if len(c.AllowedSubjects) > 0 && !slices.Contains(c.AllowedSubjects, subject) {
+		return nil, errInvalidSubject
+	}
+
+	return &authn.AuthClaims{
+		Subject: subject,
+		Scopes:  make(map[string]bool),
+	}, nil
+}
+
+func (c *ClientCertificateAuthenticator) Close() {}
@@ -0,0 +1,55 @@
+package mtls
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+func TestClientCertificateAuthenticator_Authenticate(t *testing.T) {
+	contextWithClientCertificate := func(commonName string) context.Context {
+		return peer.NewContext(context.Background(), &peer.Peer{
+			AuthInfo: credentials.TLSInfo{
+				State: tls.ConnectionState{
+					VerifiedChains: [][]*x509.Certificate{
+						{{Subject: pkix.Name{CommonName: commonName}}},
+					},
+				},
+			},
+		})
+	}
+
+	t.Run("verified_certificate_is_authenticated", func(t *testing.T) {
+		claims, err := NewClientCertificateAuthenticator(nil).Authenticate(contextWithClientCertificate("openfga client"))
+		require.NoError(t, err)
+		require.Equal(t, "openfga client", claims.Subject)
+	})
+
+	t.Run("allowed_subject_is_authenticated", func(t *testing.T) {
+		claims, err := NewClientCertificateAuthenticator([]string{"other client", "openfga client"}).Authenticate(contextWithClientCertificate("openfga client"))
+		require.NoError(t, err)
+		require.Equal(t, "openfga client", claims.Subject)
+	})
+
+	t.Run("subject_not_allowed_is_rejected", func(t *testing.T) {
+		_, err := NewClientCertificateAuthenticator([]string{"other client"}).Authenticate(contextWithClientCertificate("openfga client"))
+		require.ErrorIs(t, err, errInvalidSubject)
+	})
+
+	t.Run("connection_without_tls_is_rejected", func(t *testing.T) {
+		_, err := NewClientCertificateAuthenticator(nil).Authenticate(context.Background())
+		require.ErrorIs(t, err, errMissingClientCertificate)
+	})
+
+	t.Run("connection_without_verified_certificate_is_rejected", func(t *testing.T) {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{}})
+		_, err := NewClientCertificateAuthenticator(nil).Authenticate(ctx)
+		require.ErrorIs(t, err, errMissingClientCertificate)
+	})
+}
@@ -0,0 +1,78 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/golang-jwt/jwt/v4"
+	grpcauth "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
+
+	"github.com/openfga/openfga/internal/authn"
+)
+
+// IssuerConfig is the configuration of one of the issuers trusted by a MultiIssuerOidcAuthenticator.
+type IssuerConfig struct {
+	Issuer        string
+	IssuerAliases []string
+	Audience      string
+}
+
+// MultiIssuerOidcAuthenticator authenticates the tokens signed by any of several trusted issuers.
+// A token is verified against the keys and the audience of the issuer named by its 'iss' claim.
+type MultiIssuerOidcAuthenticator struct {
+	Authenticators []*RemoteOidcAuthenticator
+}
+
+var _ authn.Authenticator = (*MultiIssuerOidcAuthenticator)(nil)
+
+func NewMultiIssuerOidcAuthenticator(issuers []IssuerConfig) (*MultiIssuerOidcAuthenticator, error) {
+	if len(issuers) < 1 {
+		return nil, errors.New("invalid auth configuration, please specify at least one issuer")
+	}
+
+	multi := &MultiIssuerOidcAuthenticator{}
+	for _, issuer := range issuers {
+		oidc, err := NewRemoteOidcAuthenticator(issuer.Issuer, issuer.IssuerAliases, issuer.Audience)
+		if err != nil {
+			multi.Close()
+			return nil, err
+		}
+
+		multi.Authenticators = append(multi.Authenticators, oidc)
+	}
+
+	return multi, nil
+}
+
+func (m *MultiIssuerOidcAuthenticator) Authenticate(requestContext context.Context) (*authn.AuthClaims, error) {
+	authHeader, err := grpcauth.AuthFromMD(requestContext, "Bearer")
+	if err != nil {
+		return nil, authn.ErrMissingBearerToken
+	}
+
+	// the claims are only used to pick the issuer, the authenticator of the issuer verifies the token
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(authHeader, claims); err != nil {
+		return nil, errInvalidToken
+	}
+
+	issuer, ok := claims["iss"].(string)
+	if !ok {
+		return nil, errInvalidIssuer
+	}
+
+	for _, oidc := range m.Authenticators {
+		if oidc.MainIssuer == issuer || slices.Contains(oidc.IssuerAliases, issuer) {
+			return oidc.Authenticate(requestContext)
+		}
+	}
+
+	return nil, errInvalidIssuer
+}
+
+func (m *MultiIssuerOidcAuthenticator) Close() {
+	for _, oidc := range m.Authenticators {
+		oidc.Close()
+	}
+}
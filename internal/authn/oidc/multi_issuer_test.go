@@ -0,0 +1,98 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/MicahParks/keyfunc"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/openfga/openfga/internal/authn"
+)
+
+func TestMultiIssuerOidcAuthenticator_Authenticate(t *testing.T) {
+	firstPrivateKey, firstPublicKey := generateJWTSignatureKeys()
+	secondPrivateKey, secondPublicKey := generateJWTSignatureKeys()
+
+	publicKeys := map[string]*rsa.PublicKey{
+		"first_issuer":  firstPublicKey,
+		"second_issuer": secondPublicKey,
+	}
+	fetchJWKs = func(oidc *RemoteOidcAuthenticator) error {
+		oidc.JWKs = keyfunc.NewGiven(map[string]keyfunc.GivenKey{
+			"kid_1": keyfunc.NewGivenRSACustomWithOptions(publicKeys[oidc.MainIssuer], keyfunc.GivenKeyOptions{
+				Algorithm: "RS256",
+			}),
+		})
+		return nil
+	}
+	t.Cleanup(func() {
+		fetchJWKs = fetchJWK
+	})
+
+	authenticator, err := NewMultiIssuerOidcAuthenticator([]IssuerConfig{
+		{Issuer: "first_issuer", Audience: "first_audience"},
+		{Issuer: "second_issuer", IssuerAliases: []string{"second_issuer_alias"}, Audience: "second_audience"},
+	})
+	require.NoError(t, err)
+	t.Cleanup(authenticator.Close)
+
+	t.Run("token_of_each_issuer_is_verified_with_its_keys_and_audience", func(t *testing.T) {
+		claims, err := authenticator.Authenticate(generateContext(generateJWT(firstPrivateKey, "kid_1", jwt.MapClaims{
+			"iss": "first_issuer",
+			"aud": "first_audience",
+			"sub": "first client",
+		})))
+		require.NoError(t, err)
+		require.Equal(t, "first client", claims.Subject)
+
+		claims, err = authenticator.Authenticate(generateContext(generateJWT(secondPrivateKey, "kid_1", jwt.MapClaims{
+			"iss": "second_issuer_alias",
+			"aud": "second_audience",
+			"sub": "second client",
+		})))
+		require.NoError(t, err)
+		require.Equal(t, "second client", claims.Subject)
+	})
+
+	t.Run("audience_of_another_issuer_is_rejected", func(t *testing.T) {
+		_, err := authenticator.Authenticate(generateContext(generateJWT(firstPrivateKey, "kid_1", jwt.MapClaims{
+			"iss": "first_issuer",
+			"aud": "second_audience",
+		})))
+		require.ErrorContains(t, err, "invalid audience")
+	})
+
+	t.Run("token_signed_with_the_keys_of_another_issuer_is_rejected", func(t *testing.T) {
+		_, err := authenticator.Authenticate(generateContext(generateJWT(secondPrivateKey, "kid_1", jwt.MapClaims{
+			"iss": "first_issuer",
+			"aud": "first_audience",
+		})))
+		require.ErrorContains(t, err, "invalid bearer token")
+	})
+
+	t.Run("untrusted_issuer_is_rejected", func(t *testing.T) {
+		_, err := authenticator.Authenticate(generateContext(generateJWT(firstPrivateKey, "kid_1", jwt.MapClaims{
+			"iss": "third_issuer",
+			"aud": "first_audience",
+		})))
+		require.ErrorContains(t, err, "invalid issuer")
+	})
+
+	t.Run("malformed_token_is_rejected", func(t *testing.T) {
+		_, err := authenticator.Authenticate(generateContext("not a token"))
+		require.ErrorContains(t, err, "invalid bearer token")
+	})
+
+	t.Run("missing_token_is_rejected", func(t *testing.T) {
+		_, err := authenticator.Authenticate(context.Background())
+		require.Equal(t, authn.ErrMissingBearerToken, err)
+	})
+}
+
+func TestNewMultiIssuerOidcAuthenticator(t *testing.T) {
+	_, err := NewMultiIssuerOidcAuthenticator(nil)
+	require.ErrorContains(t, err, "at least one issuer")
+}
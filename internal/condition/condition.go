@@ -10,6 +10,7 @@ import (
 	"github.com/google/cel-go/cel"
 	"github.com/google/cel-go/common"
 	celtypes "github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/ext"
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	"go.opentelemetry.io/otel"
 	"golang.org/x/exp/maps"
@@ -29,7 +30,13 @@ func init() {
 		envOpts = append(envOpts, customTypeOpts...)
 	}
 
-	envOpts = append(envOpts, types.IPAddressEnvOption(), cel.EagerlyValidateDeclarations(true))
+	envOpts = append(envOpts,
+		types.IPAddressEnvOption(),
+		// list helpers, e.g. sets.contains(list, sublist) and sets.intersects(list, list)
+		ext.Sets(),
+		ext.Lists(),
+		cel.EagerlyValidateDeclarations(true),
+	)
 
 	env, err := cel.NewEnv(envOpts...)
 	if err != nil {
@@ -55,6 +62,7 @@ type EvaluableCondition struct {
 	*openfgav1.Condition
 
 	celProgramOpts []cel.ProgramOption
+	celBaseEnv     *cel.Env
 	celEnv         *cel.Env
 	celProgram     cel.Program
 	compileOnce    sync.Once
@@ -104,7 +112,12 @@ func (e *EvaluableCondition) compile() error {
 		envOpts = append(envOpts, cel.Variable(paramName, paramType.CelType()))
 	}
 
-	env, err := celBaseEnv.Extend(envOpts...)
+	baseEnv := celBaseEnv
+	if e.celBaseEnv != nil {
+		baseEnv = e.celBaseEnv
+	}
+
+	env, err := baseEnv.Extend(envOpts...)
 	if err != nil {
 		return &CompilationError{
 			Condition: e.Name,
@@ -174,7 +187,8 @@ func (e *EvaluableCondition) CastContextToTypedParameters(contextMap map[string]
 		if err != nil {
 			return nil, &ParameterTypeError{
 				Condition: e.Name,
-				Cause:     fmt.Errorf("failed to decode condition parameter type '%s': %v", paramTypeRef.GetTypeName(), err),
+				Parameter: parameterKey,
+				Cause:     fmt.Errorf("failed to decode type '%s' of condition parameter '%s': %v", paramTypeRef.GetTypeName(), parameterKey, err),
 			}
 		}
 
@@ -182,6 +196,7 @@ func (e *EvaluableCondition) CastContextToTypedParameters(contextMap map[string]
 		if err != nil {
 			return nil, &ParameterTypeError{
 				Condition: e.Name,
+				Parameter: parameterKey,
 				Cause:     fmt.Errorf("failed to convert context parameter '%s': %w", parameterKey, err),
 			}
 		}
@@ -314,6 +329,15 @@ func (e *EvaluableCondition) WithInterruptCheckFrequency(checkFrequency uint) *E
 	return e
 }
 
+// WithEnv sets the CEL environment, returned by NewEnv, that the condition expression is compiled in
+// and returns the mutated EvaluableCondition. The expectation is that this is called on the Uncompiled
+// condition because the environment is only used by Compile.
+func (e *EvaluableCondition) WithEnv(env *cel.Env) *EvaluableCondition {
+	e.celBaseEnv = env
+
+	return e
+}
+
 // NewUncompiled returns a new EvaluableCondition that has not
 // validated and compiled its expression.
 func NewUncompiled(condition *openfgav1.Condition) *EvaluableCondition {
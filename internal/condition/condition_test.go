@@ -291,6 +291,112 @@ func TestEvaluate(t *testing.T) {
 				Cause:     fmt.Errorf("failed to evaluate condition expression: ParseAddr(\"192.168.0\"): IPv4 address too short"),
 			},
 		},
+		{
+			name: "ipaddress_in_any_cidr",
+			condition: &openfgav1.Condition{
+				Name:       "condition1",
+				Expression: `user_ip.in_cidr(cidrs)`,
+				Parameters: map[string]*openfgav1.ConditionParamTypeRef{
+					"user_ip": {
+						TypeName: openfgav1.ConditionParamTypeRef_TYPE_NAME_IPADDRESS,
+					},
+					"cidrs": {
+						TypeName:     openfgav1.ConditionParamTypeRef_TYPE_NAME_LIST,
+						GenericTypes: []*openfgav1.ConditionParamTypeRef{{TypeName: openfgav1.ConditionParamTypeRef_TYPE_NAME_STRING}},
+					},
+				},
+			},
+			context: map[string]interface{}{
+				"user_ip": "10.0.1.2",
+				"cidrs":   []interface{}{"192.168.0.0/24", "10.0.0.0/16"},
+			},
+			result: condition.EvaluationResult{ConditionMet: true},
+		},
+		{
+			name: "ipaddress_in_any_cidr_malformed_cidr",
+			condition: &openfgav1.Condition{
+				Name:       "condition1",
+				Expression: `ipaddress("10.0.1.2").in_cidr(["192.168.0.0/24", "10.0.0.0"])`,
+				Parameters: map[string]*openfgav1.ConditionParamTypeRef{},
+			},
+			context: map[string]interface{}{},
+			result:  condition.EvaluationResult{ConditionMet: false},
+			err: &condition.EvaluationError{
+				Condition: "condition1",
+				Cause:     fmt.Errorf("failed to evaluate condition expression: '10.0.0.0' is a malformed CIDR string"),
+			},
+		},
+		{
+			name: "list_membership",
+			condition: &openfgav1.Condition{
+				Name:       "condition1",
+				Expression: `sets.contains(granted, required) && !sets.intersects(granted, ["admin"])`,
+				Parameters: map[string]*openfgav1.ConditionParamTypeRef{
+					"granted": {
+						TypeName:     openfgav1.ConditionParamTypeRef_TYPE_NAME_LIST,
+						GenericTypes: []*openfgav1.ConditionParamTypeRef{{TypeName: openfgav1.ConditionParamTypeRef_TYPE_NAME_STRING}},
+					},
+					"required": {
+						TypeName:     openfgav1.ConditionParamTypeRef_TYPE_NAME_LIST,
+						GenericTypes: []*openfgav1.ConditionParamTypeRef{{TypeName: openfgav1.ConditionParamTypeRef_TYPE_NAME_STRING}},
+					},
+				},
+			},
+			context: map[string]interface{}{
+				"granted":  []interface{}{"read", "write"},
+				"required": []interface{}{"write"},
+			},
+			result: condition.EvaluationResult{ConditionMet: true},
+		},
+		{
+			name: "timestamp_arithmetic",
+			condition: &openfgav1.Condition{
+				Name:       "condition1",
+				Expression: `current_time < grant_time + grant_duration && current_time - grant_time >= duration("1m")`,
+				Parameters: map[string]*openfgav1.ConditionParamTypeRef{
+					"current_time": {
+						TypeName: openfgav1.ConditionParamTypeRef_TYPE_NAME_TIMESTAMP,
+					},
+					"grant_time": {
+						TypeName: openfgav1.ConditionParamTypeRef_TYPE_NAME_TIMESTAMP,
+					},
+					"grant_duration": {
+						TypeName: openfgav1.ConditionParamTypeRef_TYPE_NAME_DURATION,
+					},
+				},
+			},
+			// the timestamp and the duration given as JSON numbers of seconds are coerced
+			context: map[string]interface{}{
+				"current_time":   "2023-01-01T00:10:00Z",
+				"grant_time":     1672531200,
+				"grant_duration": 3600,
+			},
+			result: condition.EvaluationResult{ConditionMet: true},
+		},
+		{
+			name: "fail_uint_out_of_range",
+			condition: &openfgav1.Condition{
+				Name:       "condition1",
+				Expression: "param1 > 10u",
+				Parameters: map[string]*openfgav1.ConditionParamTypeRef{
+					"param1": {
+						TypeName: openfgav1.ConditionParamTypeRef_TYPE_NAME_UINT,
+					},
+				},
+			},
+			context: map[string]interface{}{
+				"param1": 1e20,
+			},
+			result: condition.EvaluationResult{ConditionMet: false},
+			err: &condition.EvaluationError{
+				Condition: "condition1",
+				Cause: &condition.ParameterTypeError{
+					Condition: "condition1",
+					Parameter: "param1",
+					Cause:     fmt.Errorf("failed to convert context parameter 'param1': expected a uint value, but found out of range numeric value '1e+20'"),
+				},
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -516,7 +622,8 @@ func TestCastContextToTypedParameters(t *testing.T) {
 			expectedParams: nil,
 			expectedError: &condition.ParameterTypeError{
 				Condition: "condition1",
-				Cause:     fmt.Errorf("failed to decode condition parameter type 'TYPE_NAME_UNSPECIFIED': unknown condition parameter type `TYPE_NAME_UNSPECIFIED`"),
+				Parameter: "param1",
+				Cause:     fmt.Errorf("failed to decode type 'TYPE_NAME_UNSPECIFIED' of condition parameter 'param1': unknown condition parameter type `TYPE_NAME_UNSPECIFIED`"),
 			},
 		},
 	}
@@ -0,0 +1,63 @@
+package condition
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+)
+
+// NewEnv returns a CEL environment that extends the one conditions are compiled in by default
+// with the given options, e.g. to declare custom functions. The returned environment can be set
+// on conditions with WithEnv. Environments returned by different calls share none of the options.
+func NewEnv(opts ...cel.EnvOption) (*cel.Env, error) {
+	env, err := celBaseEnv.Extend(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to construct CEL env: %w", err)
+	}
+
+	return env, nil
+}
+
+// StoreEnvOptionsFunc returns the CEL environment options of the conditions of a store,
+// e.g. the declarations of the custom functions the store may use.
+type StoreEnvOptionsFunc func(storeID string) []cel.EnvOption
+
+// StoreEnvs constructs and caches a CEL environment per store, so that the custom functions
+// of a store are not visible to the conditions of the other stores.
+//
+// StoreEnvs is designed for concurrent use.
+type StoreEnvs struct {
+	options StoreEnvOptionsFunc
+
+	mu   sync.Mutex
+	envs map[string]*cel.Env
+}
+
+// NewStoreEnvs returns a StoreEnvs constructing the environment of each store with the
+// options returned for the store.
+func NewStoreEnvs(options StoreEnvOptionsFunc) *StoreEnvs {
+	return &StoreEnvs{
+		options: options,
+		envs:    map[string]*cel.Env{},
+	}
+}
+
+// Get returns the CEL environment of the store, constructing it on the first call for the store.
+func (s *StoreEnvs) Get(storeID string) (*cel.Env, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if env, ok := s.envs[storeID]; ok {
+		return env, nil
+	}
+
+	env, err := NewEnv(s.options(storeID)...)
+	if err != nil {
+		return nil, fmt.Errorf("store '%s': %w", storeID, err)
+	}
+
+	s.envs[storeID] = env
+
+	return env, nil
+}
@@ -0,0 +1,87 @@
+package condition_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	celtypes "github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/openfga/openfga/internal/condition"
+)
+
+// stringFunction declares a CEL function of one string returning a bool.
+func stringFunction(name string, fn func(string) bool) cel.EnvOption {
+	return cel.Function(name, cel.Overload(name+"_string", []*cel.Type{cel.StringType}, cel.BoolType,
+		cel.UnaryBinding(func(arg ref.Val) ref.Val {
+			return celtypes.Bool(fn(arg.Value().(string)))
+		}),
+	))
+}
+
+func TestStoreEnvs(t *testing.T) {
+	var constructed []string
+	envs := condition.NewStoreEnvs(func(storeID string) []cel.EnvOption {
+		constructed = append(constructed, storeID)
+
+		switch storeID {
+		case "store1":
+			return []cel.EnvOption{stringFunction("is_internal", func(s string) bool { return strings.HasSuffix(s, ".internal") })}
+		case "store2":
+			return []cel.EnvOption{stringFunction("is_admin", func(s string) bool { return s == "admin" })}
+		default:
+			return nil
+		}
+	})
+
+	newCondition := func(expression string) *openfgav1.Condition {
+		return &openfgav1.Condition{
+			Name:       "condition1",
+			Expression: expression,
+			Parameters: map[string]*openfgav1.ConditionParamTypeRef{
+				"x": {TypeName: openfgav1.ConditionParamTypeRef_TYPE_NAME_STRING},
+			},
+		}
+	}
+
+	env1, err := envs.Get("store1")
+	require.NoError(t, err)
+	env2, err := envs.Get("store2")
+	require.NoError(t, err)
+
+	t.Run("functions_of_the_store_are_available", func(t *testing.T) {
+		c := condition.NewUncompiled(newCondition("is_internal(x)")).WithEnv(env1)
+		require.NoError(t, c.Compile())
+
+		result, err := c.Evaluate(context.Background(), map[string]*structpb.Value{
+			"x": structpb.NewStringValue("db.internal"),
+		})
+		require.NoError(t, err)
+		require.True(t, result.ConditionMet)
+	})
+
+	t.Run("functions_of_other_stores_are_not_available", func(t *testing.T) {
+		err := condition.NewUncompiled(newCondition("is_internal(x)")).WithEnv(env2).Compile()
+		require.ErrorContains(t, err, "undeclared reference to 'is_internal'")
+
+		err = condition.NewUncompiled(newCondition("is_admin(x)")).WithEnv(env1).Compile()
+		require.ErrorContains(t, err, "undeclared reference to 'is_admin'")
+	})
+
+	t.Run("functions_are_not_available_by_default", func(t *testing.T) {
+		_, err := condition.NewCompiled(newCondition("is_internal(x)"))
+		require.ErrorContains(t, err, "undeclared reference to 'is_internal'")
+	})
+
+	t.Run("environments_are_cached", func(t *testing.T) {
+		cached, err := envs.Get("store1")
+		require.NoError(t, err)
+		require.Same(t, env1, cached)
+		require.Equal(t, []string{"store1", "store2"}, constructed)
+	})
+}
@@ -47,6 +47,8 @@ func (e *EvaluationError) Unwrap() error {
 
 type ParameterTypeError struct {
 	Condition string
+	// Parameter is the name of the parameter whose value could not be converted, if any
+	Parameter string
 	Cause     error
 }
 
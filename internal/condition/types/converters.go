@@ -2,11 +2,19 @@ package types
 
 import (
 	"fmt"
+	"math"
 	"math/big"
 	"reflect"
 	"time"
 )
 
+// The range of the timestamps supported by CEL, from 0001-01-01T00:00:00Z to 9999-12-31T23:59:59Z,
+// in seconds since the Unix epoch.
+const (
+	minUnixSeconds = -62135596800
+	maxUnixSeconds = 253402300799
+)
+
 func primitiveTypeConverterFunc[T any](value any) (any, error) {
 	v, ok := value.(T)
 	if !ok {
@@ -45,7 +53,10 @@ func numericTypeConverterFunc[T int64 | uint64 | float64](value any) (any, error
 			return nil, fmt.Errorf("expected an int value, but found numeric value '%s'", bigFloat.String())
 		}
 
-		numericValue, _ := bigFloat.Int64()
+		numericValue, accuracy := bigFloat.Int64()
+		if accuracy != big.Exact {
+			return nil, fmt.Errorf("expected an int value, but found out of range numeric value '%s'", bigFloat.String())
+		}
 		return numericValue, nil
 
 	case uint64:
@@ -53,11 +64,15 @@ func numericTypeConverterFunc[T int64 | uint64 | float64](value any) (any, error
 			return nil, fmt.Errorf("expected a uint value, but found numeric value '%s'", bigFloat.String())
 		}
 
-		numericValue, _ := bigFloat.Int64()
-		if numericValue < 0 {
+		if bigFloat.Sign() < 0 {
 			return nil, fmt.Errorf("expected a uint value, but found int64 value '%s'", bigFloat.String())
 		}
-		return uint64(numericValue), nil
+
+		numericValue, accuracy := bigFloat.Uint64()
+		if accuracy != big.Exact {
+			return nil, fmt.Errorf("expected a uint value, but found out of range numeric value '%s'", bigFloat.String())
+		}
+		return numericValue, nil
 
 	case float64:
 		numericValue, a := bigFloat.Float64()
@@ -75,7 +90,18 @@ func anyTypeConverterFunc(value any) (any, error) {
 	return value, nil
 }
 
+// durationTypeConverterFunc converts a duration string, e.g. '1h30m', or a number of seconds, since
+// JSON has no duration type.
 func durationTypeConverterFunc(value any) (any, error) {
+	if seconds, ok := value.(float64); ok {
+		d := seconds * float64(time.Second)
+		if d >= math.MaxInt64 || d < math.MinInt64 {
+			return nil, fmt.Errorf("expected a duration, but found out of range number of seconds '%v'", value)
+		}
+
+		return time.Duration(d), nil
+	}
+
 	v, ok := value.(string)
 	if !ok {
 		return nil, fmt.Errorf("expected a duration string, but found: %T '%v'", value, value)
@@ -89,7 +115,18 @@ func durationTypeConverterFunc(value any) (any, error) {
 	return d, nil
 }
 
+// timestampTypeConverterFunc converts an RFC 3339 formatted timestamp string, or a number of seconds
+// since the Unix epoch, since JSON has no timestamp type.
 func timestampTypeConverterFunc(value any) (any, error) {
+	if seconds, ok := value.(float64); ok {
+		if seconds > maxUnixSeconds || seconds < minUnixSeconds {
+			return nil, fmt.Errorf("expected a timestamp, but found out of range number of seconds '%v'", value)
+		}
+
+		whole, fraction := math.Modf(seconds)
+		return time.Unix(int64(whole), int64(fraction*float64(time.Second))).UTC(), nil
+	}
+
 	v, ok := value.(string)
 	if !ok {
 		return nil, fmt.Errorf("expected RFC 3339 formatted timestamp string, but found: %T '%v'", value, value)
@@ -47,6 +47,11 @@ var IPAddressType = registerCustomParamType(
 			cel.BoolType,
 			cel.BinaryBinding(ipaddressCELBinaryBinding),
 		),
+		cel.MemberOverload("ipaddr_in_any_cidr",
+			[]*cel.Type{cel.ObjectType("IPAddress"), cel.ListType(cel.StringType)},
+			cel.BoolType,
+			cel.BinaryBinding(ipaddressInAnyCIDRBinaryBinding),
+		),
 	),
 )
 
@@ -149,6 +154,27 @@ func ipaddressCELBinaryBinding(lhs, rhs ref.Val) ref.Val {
 	return types.Bool(network.Contains(ipaddr.addr))
 }
 
+// ipaddressInAnyCIDRBinaryBinding implements a cel.BinaryBinding that is used as a receiver overload for
+// comparing an ipaddress value against a list of network CIDRs defined as strings. If the ipaddress is
+// within any of the CIDR ranges this binding will return true, otherwise it will return false or an error.
+//
+// See https://pkg.go.dev/github.com/google/cel-go/cel#BinaryBinding
+func ipaddressInAnyCIDRBinaryBinding(lhs, rhs ref.Val) ref.Val {
+	cidrs, ok := rhs.(traits.Lister)
+	if !ok {
+		return types.NewErr("a list of CIDR strings is required for comparison")
+	}
+
+	for it := cidrs.Iterator(); it.HasNext() == types.True; {
+		contained := ipaddressCELBinaryBinding(lhs, it.Next())
+		if types.IsError(contained) || contained == types.True {
+			return contained
+		}
+	}
+
+	return types.False
+}
+
 func stringToIPAddress(arg ref.Val) ref.Val {
 	ipStr, ok := arg.Value().(string)
 	if !ok {
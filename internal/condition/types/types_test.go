@@ -174,6 +174,57 @@ func TestPrimitives(t *testing.T) {
 				"expected RFC 3339 formatted timestamp string, but found '2023-0914'",
 			),
 		},
+		{
+			name:      "valid_number_to_timestamp",
+			paramType: TimestampParamType,
+			input:     float64(63108020.5),
+			output:    time.Date(1972, time.January, 1, 10, 0, 20, 500000000, time.UTC),
+			repr:      "timestamp",
+		},
+		{
+			name:      "invalid_number_to_timestamp",
+			paramType: TimestampParamType,
+			input:     float64(1e12),
+			output:    nil,
+			repr:      "timestamp",
+			expectedError: fmt.Errorf(
+				"expected a timestamp, but found out of range number of seconds '1e+12'",
+			),
+		},
+		{
+			name:      "valid_number_to_duration",
+			paramType: DurationParamType,
+			input:     float64(1.5),
+			output:    1500 * time.Millisecond,
+			repr:      "duration",
+		},
+		{
+			name:      "invalid_number_to_duration",
+			paramType: DurationParamType,
+			input:     float64(1e10),
+			output:    nil,
+			repr:      "duration",
+			expectedError: fmt.Errorf(
+				"expected a duration, but found out of range number of seconds '1e+10'",
+			),
+		},
+		{
+			name:      "valid_large_double_to_uint",
+			paramType: UIntParamType,
+			input:     float64(1 << 63),
+			output:    uint64(1 << 63),
+			repr:      "uint",
+		},
+		{
+			name:      "invalid_large_double_to_int",
+			paramType: IntParamType,
+			input:     float64(1 << 63),
+			output:    nil,
+			repr:      "int",
+			expectedError: fmt.Errorf(
+				"expected an int value, but found out of range numeric value '9.223372037e+18'",
+			),
+		},
 		{
 			name:      "valid_ipaddress",
 			paramType: IPAddressType,
@@ -0,0 +1,197 @@
+package graph
+
+import (
+	"fmt"
+	"slices"
+)
+
+// The names of the built-in layers of a Check resolver chain, see WithCheckResolverOrder.
+const (
+	CycleDetectionCheckResolverLayer     = "cycle_detection"
+	CachedCheckResolverLayer             = "cached"
+	DispatchThrottlingCheckResolverLayer = "dispatch_throttling"
+)
+
+// DefaultCheckResolverOrder returns the default order of the layers of a Check resolver chain.
+func DefaultCheckResolverOrder() []string {
+	return []string{CycleDetectionCheckResolverLayer, CachedCheckResolverLayer, DispatchThrottlingCheckResolverLayer}
+}
+
+// CheckResolverMiddleware is a layer of a Check resolver chain, which resolves the Checks with the next layer
+// of the chain, its delegate.
+type CheckResolverMiddleware interface {
+	CheckResolver
+
+	SetDelegate(delegate CheckResolver)
+	GetDelegate() CheckResolver
+}
+
+// CheckResolverOrderedBuilder builds a Check resolver chain out of ordered layers, the last of which delegates to
+// a LocalChecker that delegates back to the first layer.
+type CheckResolverOrderedBuilder struct {
+	order       []string
+	middlewares map[string]CheckResolverMiddleware
+	// middlewareNames are the names of the middlewares in the order they were registered
+	middlewareNames []string
+
+	localCheckerOptions                    []LocalCheckerOption
+	cycleDetectionCheckResolverOptions     []CycleDetectionCheckResolverOpt
+	cachedCheckResolverEnabled             bool
+	cachedCheckResolverOptions             []CachedCheckResolverOpt
+	dispatchThrottlingCheckResolverEnabled bool
+	dispatchThrottlingCheckResolverOptions []DispatchThrottlingCheckResolverOpt
+
+	// set by Build
+	localChecker                    *LocalChecker
+	cachedCheckResolver             *CachedCheckResolver
+	dispatchThrottlingCheckResolver *DispatchThrottlingCheckResolver
+}
+
+// CheckResolverOrderedBuilderOpt configures a CheckResolverOrderedBuilder.
+type CheckResolverOrderedBuilderOpt func(*CheckResolverOrderedBuilder)
+
+// WithCheckResolverOrder sets the order of the layers of the chain, by name. The layers that are not named are
+// left out of the chain, and so are the built-in layers that are not enabled. It defaults to
+// DefaultCheckResolverOrder followed by the middlewares, in the order they were registered.
+func WithCheckResolverOrder(layers ...string) CheckResolverOrderedBuilderOpt {
+	return func(b *CheckResolverOrderedBuilder) {
+		b.order = layers
+	}
+}
+
+// WithCheckResolverMiddleware registers a custom layer under the name, e.g. to trace or to rate limit the
+// dispatches of the Checks. The chain sets its delegate and closes it.
+func WithCheckResolverMiddleware(name string, middleware CheckResolverMiddleware) CheckResolverOrderedBuilderOpt {
+	return func(b *CheckResolverOrderedBuilder) {
+		if _, ok := b.middlewares[name]; !ok {
+			b.middlewareNames = append(b.middlewareNames, name)
+		}
+		b.middlewares[name] = middleware
+	}
+}
+
+// WithLocalCheckerOpts sets the options of the LocalChecker of the chain.
+func WithLocalCheckerOpts(opts ...LocalCheckerOption) CheckResolverOrderedBuilderOpt {
+	return func(b *CheckResolverOrderedBuilder) {
+		b.localCheckerOptions = opts
+	}
+}
+
+// WithCycleDetectionCheckResolverOpts sets the options of the CycleDetectionCheckResolver of the chain.
+func WithCycleDetectionCheckResolverOpts(opts ...CycleDetectionCheckResolverOpt) CheckResolverOrderedBuilderOpt {
+	return func(b *CheckResolverOrderedBuilder) {
+		b.cycleDetectionCheckResolverOptions = opts
+	}
+}
+
+// WithCachedCheckResolverOpts enables the CachedCheckResolver layer and sets its options.
+func WithCachedCheckResolverOpts(enabled bool, opts ...CachedCheckResolverOpt) CheckResolverOrderedBuilderOpt {
+	return func(b *CheckResolverOrderedBuilder) {
+		b.cachedCheckResolverEnabled = enabled
+		b.cachedCheckResolverOptions = opts
+	}
+}
+
+// WithDispatchThrottlingCheckResolverOpts enables the DispatchThrottlingCheckResolver layer and sets its options.
+func WithDispatchThrottlingCheckResolverOpts(enabled bool, opts ...DispatchThrottlingCheckResolverOpt) CheckResolverOrderedBuilderOpt {
+	return func(b *CheckResolverOrderedBuilder) {
+		b.dispatchThrottlingCheckResolverEnabled = enabled
+		b.dispatchThrottlingCheckResolverOptions = opts
+	}
+}
+
+// NewOrderedCheckResolvers returns a builder of a Check resolver chain. By default, the chain only has a
+// CycleDetectionCheckResolver and a LocalChecker.
+func NewOrderedCheckResolvers(opts ...CheckResolverOrderedBuilderOpt) *CheckResolverOrderedBuilder {
+	b := &CheckResolverOrderedBuilder{
+		middlewares: map[string]CheckResolverMiddleware{},
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	if b.order == nil {
+		b.order = append(DefaultCheckResolverOrder(), b.middlewareNames...)
+	}
+
+	return b
+}
+
+// Validate returns an error if the order of the layers names a layer twice, or a layer that is neither built in
+// nor registered with WithCheckResolverMiddleware.
+func (b *CheckResolverOrderedBuilder) Validate() error {
+	for i, layer := range b.order {
+		if slices.Contains(b.order[:i], layer) {
+			return fmt.Errorf("the Check resolver layer '%s' is ordered more than once", layer)
+		}
+
+		if _, ok := b.middlewares[layer]; !ok && !slices.Contains(DefaultCheckResolverOrder(), layer) {
+			return fmt.Errorf("unknown Check resolver layer '%s'", layer)
+		}
+	}
+
+	return nil
+}
+
+// Build constructs the chain and returns its first layer. The returned CheckResolverCloser closes all the layers of
+// the chain.
+func (b *CheckResolverOrderedBuilder) Build() (CheckResolver, CheckResolverCloser, error) {
+	if err := b.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	var layers []CheckResolverMiddleware
+	for _, layer := range b.order {
+		switch layer {
+		case CycleDetectionCheckResolverLayer:
+			layers = append(layers, NewCycleDetectionCheckResolver(b.cycleDetectionCheckResolverOptions...))
+		case CachedCheckResolverLayer:
+			if b.cachedCheckResolverEnabled {
+				b.cachedCheckResolver = NewCachedCheckResolver(b.cachedCheckResolverOptions...)
+				layers = append(layers, b.cachedCheckResolver)
+			}
+		case DispatchThrottlingCheckResolverLayer:
+			if b.dispatchThrottlingCheckResolverEnabled {
+				b.dispatchThrottlingCheckResolver = NewDispatchThrottlingCheckResolver(b.dispatchThrottlingCheckResolverOptions...)
+				layers = append(layers, b.dispatchThrottlingCheckResolver)
+			}
+		default:
+			layers = append(layers, b.middlewares[layer])
+		}
+	}
+
+	b.localChecker = NewLocalChecker(b.localCheckerOptions...)
+	layers = append(layers, b.localChecker)
+
+	for i, layer := range layers {
+		layer.SetDelegate(layers[(i+1)%len(layers)])
+	}
+
+	return layers[0], func() {
+		for _, layer := range layers {
+			layer.Close()
+		}
+	}, nil
+}
+
+// Order returns the names of the layers of the chain, in order.
+func (b *CheckResolverOrderedBuilder) Order() []string {
+	return b.order
+}
+
+// LocalChecker returns the LocalChecker of the chain once built.
+func (b *CheckResolverOrderedBuilder) LocalChecker() *LocalChecker {
+	return b.localChecker
+}
+
+// CachedCheckResolver returns the CachedCheckResolver of the chain once built, or nil if the chain has none.
+func (b *CheckResolverOrderedBuilder) CachedCheckResolver() *CachedCheckResolver {
+	return b.cachedCheckResolver
+}
+
+// DispatchThrottlingCheckResolver returns the DispatchThrottlingCheckResolver of the chain once built, or nil if the
+// chain has none.
+func (b *CheckResolverOrderedBuilder) DispatchThrottlingCheckResolver() *DispatchThrottlingCheckResolver {
+	return b.dispatchThrottlingCheckResolver
+}
@@ -0,0 +1,145 @@
+package graph
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/testutils"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+)
+
+// countingCheckResolver is a CheckResolverMiddleware counting the Checks it resolves.
+type countingCheckResolver struct {
+	delegate CheckResolver
+	count    atomic.Int64
+	closed   atomic.Bool
+}
+
+var _ CheckResolverMiddleware = (*countingCheckResolver)(nil)
+
+func (c *countingCheckResolver) ResolveCheck(ctx context.Context, req *ResolveCheckRequest) (*ResolveCheckResponse, error) {
+	c.count.Add(1)
+	return c.delegate.ResolveCheck(ctx, req)
+}
+
+func (c *countingCheckResolver) BatchResolveCheck(ctx context.Context, reqs []*ResolveCheckRequest) ([]*ResolveCheckResponse, error) {
+	c.count.Add(int64(len(reqs)))
+	return c.delegate.BatchResolveCheck(ctx, reqs)
+}
+
+func (c *countingCheckResolver) Close() {
+	c.closed.Store(true)
+}
+
+func (c *countingCheckResolver) SetDelegate(delegate CheckResolver) {
+	c.delegate = delegate
+}
+
+func (c *countingCheckResolver) GetDelegate() CheckResolver {
+	return c.delegate
+}
+
+func TestCheckResolverOrderedBuilder(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	t.Run("default_order", func(t *testing.T) {
+		checkResolver, closer, err := NewOrderedCheckResolvers(WithCachedCheckResolverOpts(true)).Build()
+		require.NoError(t, err)
+		t.Cleanup(closer)
+
+		cycleDetectionCheckResolver, ok := checkResolver.(*CycleDetectionCheckResolver)
+		require.True(t, ok)
+
+		cachedCheckResolver, ok := cycleDetectionCheckResolver.GetDelegate().(*CachedCheckResolver)
+		require.True(t, ok)
+
+		localChecker, ok := cachedCheckResolver.GetDelegate().(*LocalChecker)
+		require.True(t, ok)
+		require.Equal(t, checkResolver, localChecker.GetDelegate())
+	})
+
+	t.Run("middleware_resolves_the_dispatches", func(t *testing.T) {
+		ctx := context.Background()
+		storeID := ulid.Make().String()
+
+		ds := memory.New()
+		t.Cleanup(ds.Close)
+
+		model := testutils.MustTransformDSLToProtoWithID(`
+			model
+				schema 1.1
+
+			type user
+			type document
+				relations
+					define editor: [user]
+					define viewer: [user] or editor`)
+		require.NoError(t, ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:1", "editor", "user:anne"),
+		}))
+
+		counting := &countingCheckResolver{}
+		builder := NewOrderedCheckResolvers(
+			WithCheckResolverMiddleware("counting", counting),
+			WithCheckResolverOrder("counting", CycleDetectionCheckResolverLayer),
+		)
+		require.Equal(t, []string{"counting", CycleDetectionCheckResolverLayer}, builder.Order())
+
+		checkResolver, closer, err := builder.Build()
+		require.NoError(t, err)
+		require.Equal(t, counting, checkResolver)
+		require.Equal(t, counting, builder.LocalChecker().GetDelegate())
+
+		ctx = typesystem.ContextWithTypesystem(ctx, typesystem.New(model))
+		ctx = storage.ContextWithRelationshipTupleReader(ctx, ds)
+
+		resp, err := checkResolver.ResolveCheck(ctx, &ResolveCheckRequest{
+			StoreID:              storeID,
+			AuthorizationModelID: model.GetId(),
+			TupleKey:             tuple.NewTupleKey("document:1", "viewer", "user:anne"),
+			RequestMetadata:      NewCheckRequestMetadata(defaultResolveNodeLimit),
+		})
+		require.NoError(t, err)
+		require.True(t, resp.GetAllowed())
+
+		// the Check and its dispatch of 'editor'
+		require.EqualValues(t, 2, counting.count.Load())
+
+		closer()
+		require.True(t, counting.closed.Load())
+	})
+
+	t.Run("disabled_layers_are_left_out", func(t *testing.T) {
+		checkResolver, closer, err := NewOrderedCheckResolvers(
+			WithCachedCheckResolverOpts(false),
+			WithCheckResolverOrder(CachedCheckResolverLayer),
+		).Build()
+		require.NoError(t, err)
+		t.Cleanup(closer)
+
+		localChecker, ok := checkResolver.(*LocalChecker)
+		require.True(t, ok)
+		require.Equal(t, checkResolver, localChecker.GetDelegate())
+	})
+
+	t.Run("invalid_order", func(t *testing.T) {
+		_, _, err := NewOrderedCheckResolvers(WithCheckResolverOrder("tracker")).Build()
+		require.EqualError(t, err, "unknown Check resolver layer 'tracker'")
+
+		_, _, err = NewOrderedCheckResolvers(
+			WithCheckResolverOrder(CycleDetectionCheckResolverLayer, CycleDetectionCheckResolverLayer),
+		).Build()
+		require.EqualError(t, err, "the Check resolver layer 'cycle_detection' is ordered more than once")
+	})
+}
@@ -2,12 +2,16 @@ package graph
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cespare/xxhash/v2"
 	"github.com/karlseguin/ccache/v3"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"go.opentelemetry.io/otel/attribute"
@@ -17,7 +21,9 @@ import (
 	"github.com/openfga/openfga/internal/build"
 	"github.com/openfga/openfga/internal/keys"
 	"github.com/openfga/openfga/pkg/logger"
+	"github.com/openfga/openfga/pkg/storage"
 	"github.com/openfga/openfga/pkg/telemetry"
+	"github.com/openfga/openfga/pkg/tuple"
 )
 
 const (
@@ -46,11 +52,14 @@ type CachedCheckResolver struct {
 	delegate     CheckResolver
 	cache        *ccache.Cache[*ResolveCheckResponse]
 	maxCacheSize int64
-	cacheTTL     time.Duration
+	cacheTTL     atomic.Int64 // time.Duration
 	logger       logger.Logger
 	// allocatedCache is used to denote whether the cache is allocated by this struct.
 	// If so, CachedCheckResolver is responsible for cleaning up.
 	allocatedCache bool
+	// bypassedRelations is the set of 'type#relation' pairs that are never cached.
+	bypassedRelations map[string]struct{}
+	closeOnce         sync.Once
 }
 
 var _ CheckResolver = (*CachedCheckResolver)(nil)
@@ -70,7 +79,7 @@ func WithMaxCacheSize(size int64) CachedCheckResolverOpt {
 // WithCacheTTL sets the TTL (as a duration) for any single Check cache key value.
 func WithCacheTTL(ttl time.Duration) CachedCheckResolverOpt {
 	return func(ccr *CachedCheckResolver) {
-		ccr.cacheTTL = ttl
+		ccr.cacheTTL.Store(int64(ttl))
 	}
 }
 
@@ -90,6 +99,17 @@ func WithLogger(logger logger.Logger) CachedCheckResolverOpt {
 	}
 }
 
+// WithBypassedRelation excludes the given relation of the given object type from the cache, so that
+// Check sub-problems involving it are always resolved by the delegate. It may be provided multiple times.
+func WithBypassedRelation(objectType, relation string) CachedCheckResolverOpt {
+	return func(ccr *CachedCheckResolver) {
+		if ccr.bypassedRelations == nil {
+			ccr.bypassedRelations = map[string]struct{}{}
+		}
+		ccr.bypassedRelations[tuple.ToObjectRelationString(objectType, relation)] = struct{}{}
+	}
+}
+
 // NewCachedCheckResolver constructs a CheckResolver that delegates Check resolution to the provided delegate,
 // but before delegating the query to the delegate a cache-key lookup is made to see if the Check sub-problem
 // has already recently been computed. If the Check sub-problem is in the cache, then the response is returned
@@ -98,9 +118,9 @@ func WithLogger(logger logger.Logger) CachedCheckResolverOpt {
 func NewCachedCheckResolver(opts ...CachedCheckResolverOpt) *CachedCheckResolver {
 	checker := &CachedCheckResolver{
 		maxCacheSize: defaultMaxCacheSize,
-		cacheTTL:     defaultCacheTTL,
 		logger:       logger.NewNoopLogger(),
 	}
+	checker.cacheTTL.Store(int64(defaultCacheTTL))
 	checker.delegate = checker
 
 	for _, opt := range opts {
@@ -117,6 +137,13 @@ func NewCachedCheckResolver(opts ...CachedCheckResolverOpt) *CachedCheckResolver
 	return checker
 }
 
+// SetCacheTTL changes the TTL of the Check sub-problems cached from now on, e.g. on a configuration reload.
+// The entries already in the cache keep the TTL they were cached with. It is safe to call concurrently with
+// ResolveCheck.
+func (c *CachedCheckResolver) SetCacheTTL(ttl time.Duration) {
+	c.cacheTTL.Store(int64(ttl))
+}
+
 // SetDelegate sets this CachedCheckResolver's dispatch delegate.
 func (c *CachedCheckResolver) SetDelegate(delegate CheckResolver) {
 	c.delegate = delegate
@@ -129,10 +156,13 @@ func (c *CachedCheckResolver) GetDelegate() CheckResolver {
 
 // Close will deallocate resource allocated by the CachedCheckResolver
 // It will not deallocate cache if it has been passed in from WithExistingCache.
+// Only the first call has an effect.
 func (c *CachedCheckResolver) Close() {
-	if c.allocatedCache {
-		c.cache.Stop()
-	}
+	c.closeOnce.Do(func() {
+		if c.allocatedCache {
+			c.cache.Stop()
+		}
+	})
 }
 
 func (c *CachedCheckResolver) ResolveCheck(
@@ -140,6 +170,12 @@ func (c *CachedCheckResolver) ResolveCheck(
 	req *ResolveCheckRequest,
 ) (*ResolveCheckResponse, error) {
 	span := trace.SpanFromContext(ctx)
+
+	if c.isBypassed(req) {
+		span.SetAttributes(attribute.Bool("cache_bypassed", true))
+		return c.delegate.ResolveCheck(ctx, req)
+	}
+
 	checkCacheTotalCounter.Inc()
 
 	cacheKey, err := CheckRequestCacheKey(req)
@@ -149,7 +185,11 @@ func (c *CachedCheckResolver) ResolveCheck(
 		return nil, err
 	}
 
-	cachedResp := c.cache.Get(cacheKey)
+	// the requests preferring higher consistency are resolved anew, and their results cached for the others
+	var cachedResp *ccache.Item[*ResolveCheckResponse]
+	if storage.ConsistencyFromContext(ctx) != storage.ConsistencyHigher {
+		cachedResp = c.cache.Get(cacheKey)
+	}
 	isCached := cachedResp != nil && !cachedResp.Expired()
 	span.SetAttributes(attribute.Bool("is_cached", isCached))
 	if isCached {
@@ -171,16 +211,122 @@ func (c *CachedCheckResolver) ResolveCheck(
 	clonedResp := CloneResolveCheckResponse(resp)
 	clonedResp.ResolutionMetadata.DatastoreQueryCount = 0
 
-	c.cache.Set(cacheKey, clonedResp, c.cacheTTL)
+	c.cache.Set(cacheKey, clonedResp, time.Duration(c.cacheTTL.Load()))
 	return resp, nil
 }
 
+// BatchResolveCheck implements CheckResolver. The cached requests are answered from the cache, and the others are
+// forwarded to the delegate in a single batch. The responses of the forwarded requests that succeed are cached.
+func (c *CachedCheckResolver) BatchResolveCheck(
+	ctx context.Context,
+	reqs []*ResolveCheckRequest,
+) ([]*ResolveCheckResponse, error) {
+	span := trace.SpanFromContext(ctx)
+
+	resps := make([]*ResolveCheckResponse, len(reqs))
+	cacheKeys := make([]string, len(reqs))
+
+	var forwarded []*ResolveCheckRequest
+	// index of a forwarded request => index of the request in the batch
+	var forwardedIndexes []int
+	higherConsistency := storage.ConsistencyFromContext(ctx) == storage.ConsistencyHigher
+	for i, req := range reqs {
+		if !c.isBypassed(req) {
+			checkCacheTotalCounter.Inc()
+
+			cacheKey, err := CheckRequestCacheKey(req)
+			if err != nil {
+				c.logger.Error("cache key computation failed with error", zap.Error(err))
+				telemetry.TraceError(span, err)
+				return nil, err
+			}
+			cacheKeys[i] = cacheKey
+
+			// see ResolveCheck for the requests preferring higher consistency
+			if cachedResp := c.cache.Get(cacheKey); cachedResp != nil && !cachedResp.Expired() && !higherConsistency {
+				checkCacheHitCounter.Inc()
+
+				// return a copy to avoid races across goroutines
+				resps[i] = CloneResolveCheckResponse(cachedResp.Value())
+				continue
+			}
+		}
+
+		forwarded = append(forwarded, req)
+		forwardedIndexes = append(forwardedIndexes, i)
+	}
+
+	span.SetAttributes(attribute.Int("cached_requests", len(reqs)-len(forwarded)))
+
+	if len(forwarded) == 0 {
+		return resps, nil
+	}
+
+	forwardedResps, err := c.delegate.BatchResolveCheck(ctx, forwarded)
+
+	var batchErr *BatchResolveCheckError
+	if err != nil && !errors.As(err, &batchErr) {
+		telemetry.TraceError(span, err)
+		return nil, err
+	}
+
+	var errs []error
+	if batchErr != nil {
+		errs = make([]error, len(reqs))
+	}
+
+	for j, resp := range forwardedResps {
+		i := forwardedIndexes[j]
+		if batchErr != nil && batchErr.Errors[j] != nil {
+			errs[i] = batchErr.Errors[j]
+			continue
+		}
+
+		resps[i] = resp
+		if cacheKeys[i] == "" {
+			continue
+		}
+
+		// see ResolveCheck
+		clonedResp := CloneResolveCheckResponse(resp)
+		clonedResp.ResolutionMetadata.DatastoreQueryCount = 0
+
+		c.cache.Set(cacheKeys[i], clonedResp, time.Duration(c.cacheTTL.Load()))
+	}
+
+	if batchErr != nil {
+		err := &BatchResolveCheckError{Errors: errs}
+		telemetry.TraceError(span, err)
+		return resps, err
+	}
+
+	return resps, nil
+}
+
+// InvalidateStore drops the cached results of the Checks of the store, e.g. after tuples of the store were written
+// or deleted, so that they are resolved anew rather than served stale until they expire.
+func (c *CachedCheckResolver) InvalidateStore(storeID string) {
+	c.cache.DeletePrefix(storeID + "/")
+}
+
+// isBypassed returns true if the relation being checked has been excluded from the cache with WithBypassedRelation.
+func (c *CachedCheckResolver) isBypassed(req *ResolveCheckRequest) bool {
+	if len(c.bypassedRelations) == 0 {
+		return false
+	}
+
+	tk := req.GetTupleKey()
+	_, ok := c.bypassedRelations[tuple.ToObjectRelationString(tuple.GetType(tk.GetObject()), tk.GetRelation())]
+	return ok
+}
+
 // CheckRequestCacheKey converts the ResolveCheckRequest into a canonical cache key that can be
 // used for Check resolution cache key lookups in a stable way.
 //
 // For one store and model ID, the same tuple provided with the same contextual tuples and context
 // should produce the same cache key. Contextual tuple order and context parameter order is ignored,
-// only the contents are compared.
+// only the contents are compared. The key starts with the store ID followed by a '/', so that the
+// keys of a store can be dropped at once, see InvalidateStore.
 func CheckRequestCacheKey(req *ResolveCheckRequest) (string, error) {
 	hasher := keys.NewCacheKeyHasher(xxhash.New())
 
@@ -212,5 +358,40 @@ func CheckRequestCacheKey(req *ResolveCheckRequest) (string, error) {
 		}
 	}
 
-	return strconv.FormatUint(hasher.Key().ToUInt64(), 10), nil
+	// the result depends on how many more userset tuples may be followed
+	if maxDepth := req.GetMaxIndirectionDepth(); maxDepth > 0 {
+		if err := hasher.WriteString(fmt.Sprintf("/indirection:%d", maxDepth-req.GetIndirectionDepth())); err != nil {
+			return "", err
+		}
+	}
+
+	// a traced result carries its resolution path, which an untraced one doesn't
+	if req.GetResolutionTrace() {
+		if err := hasher.WriteString("/trace"); err != nil {
+			return "", err
+		}
+	}
+
+	// the result of a what-if Check only holds with the changes of its overlay
+	if overlay := req.GetTupleOverlay(); overlay != nil {
+		if err := hasher.WriteString("/overlay"); err != nil {
+			return "", err
+		}
+		if err := keys.NewTupleKeysHasher(overlay.AddedTuples...).Append(hasher); err != nil {
+			return "", err
+		}
+
+		removed := make([]*openfgav1.TupleKey, 0, len(overlay.RemovedTuples))
+		for _, tk := range overlay.RemovedTuples {
+			removed = append(removed, tuple.TupleKeyWithoutConditionToTupleKey(tk))
+		}
+		if err := hasher.WriteString("/removed"); err != nil {
+			return "", err
+		}
+		if err := keys.NewTupleKeysHasher(removed...).Append(hasher); err != nil {
+			return "", err
+		}
+	}
+
+	return req.GetStoreID() + "/" + strconv.FormatUint(hasher.Key().ToUInt64(), 10), nil
 }
@@ -2,6 +2,7 @@ package graph
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"testing"
@@ -17,6 +18,7 @@ import (
 
 	"github.com/openfga/openfga/pkg/storage"
 	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/storage/storagewrappers"
 	"github.com/openfga/openfga/pkg/testutils"
 	"github.com/openfga/openfga/pkg/tuple"
 	"github.com/openfga/openfga/pkg/typesystem"
@@ -514,6 +516,96 @@ func TestResolveCheckExpired(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestResolveCheckBypassedRelation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+
+	bypassedReq := &ResolveCheckRequest{
+		StoreID:              "12",
+		AuthorizationModelID: "33",
+		TupleKey:             tuple.NewTupleKey("document:abc", "reader", "user:XYZ"),
+		RequestMetadata:      NewCheckRequestMetadata(20),
+	}
+
+	cachedReq := &ResolveCheckRequest{
+		StoreID:              "12",
+		AuthorizationModelID: "33",
+		TupleKey:             tuple.NewTupleKey("document:abc", "writer", "user:XYZ"),
+		RequestMetadata:      NewCheckRequestMetadata(20),
+	}
+
+	result := &ResolveCheckResponse{Allowed: true}
+	mockResolver := NewMockCheckResolver(ctrl)
+	mockResolver.EXPECT().ResolveCheck(gomock.Any(), bypassedReq).Times(3).Return(result, nil)
+	mockResolver.EXPECT().ResolveCheck(gomock.Any(), cachedReq).Times(1).Return(result, nil)
+
+	dut := NewCachedCheckResolver(
+		WithBypassedRelation("document", "reader"),
+		WithBypassedRelation("folder", "writer"),
+	)
+	defer dut.Close()
+
+	dut.SetDelegate(mockResolver)
+
+	for i := 0; i < 3; i++ {
+		actualResult, err := dut.ResolveCheck(ctx, bypassedReq)
+		require.NoError(t, err)
+		require.Equal(t, result.Allowed, actualResult.Allowed)
+
+		actualResult, err = dut.ResolveCheck(ctx, cachedReq)
+		require.NoError(t, err)
+		require.Equal(t, result.Allowed, actualResult.Allowed)
+	}
+}
+
+func TestResolveCheckHigherConsistency(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+
+	req := &ResolveCheckRequest{
+		StoreID:              "12",
+		AuthorizationModelID: "33",
+		TupleKey:             tuple.NewTupleKey("document:abc", "reader", "user:XYZ"),
+		RequestMetadata:      NewCheckRequestMetadata(20),
+	}
+
+	mockResolver := NewMockCheckResolver(ctrl)
+	gomock.InOrder(
+		mockResolver.EXPECT().ResolveCheck(gomock.Any(), req).Times(1).Return(&ResolveCheckResponse{Allowed: false}, nil),
+		mockResolver.EXPECT().ResolveCheck(gomock.Any(), req).Times(1).Return(&ResolveCheckResponse{Allowed: true}, nil),
+	)
+
+	dut := NewCachedCheckResolver()
+	defer dut.Close()
+
+	dut.SetDelegate(mockResolver)
+
+	resp, err := dut.ResolveCheck(ctx, req)
+	require.NoError(t, err)
+	require.False(t, resp.GetAllowed())
+
+	// the cached result is not used by the requests preferring higher consistency
+	resp, err = dut.ResolveCheck(storage.ContextWithConsistency(ctx, storage.ConsistencyHigher), req)
+	require.NoError(t, err)
+	require.True(t, resp.GetAllowed())
+
+	// but their result is cached for the others
+	resp, err = dut.ResolveCheck(ctx, req)
+	require.NoError(t, err)
+	require.True(t, resp.GetAllowed())
+
+	mockResolver.EXPECT().BatchResolveCheck(gomock.Any(), []*ResolveCheckRequest{req}).Times(1).
+		Return([]*ResolveCheckResponse{{Allowed: true}}, nil)
+
+	resps, err := dut.BatchResolveCheck(storage.ContextWithConsistency(ctx, storage.ConsistencyHigher), []*ResolveCheckRequest{req})
+	require.NoError(t, err)
+	require.True(t, resps[0].GetAllowed())
+}
+
 func TestCachedCheckResolver_CycleDetected(t *testing.T) {
 	cachedCheckResolver := NewCachedCheckResolver()
 	defer cachedCheckResolver.Close()
@@ -874,6 +966,37 @@ func TestCheckCacheKeyWithContext(t *testing.T) {
 	require.NotEqual(t, key3, key4)
 }
 
+func TestCheckCacheKeyWithTupleOverlay(t *testing.T) {
+	storeID := ulid.Make().String()
+	modelID := ulid.Make().String()
+
+	key := func(overlay *storagewrappers.TupleOverlay) string {
+		key, err := CheckRequestCacheKey(&ResolveCheckRequest{
+			StoreID:              storeID,
+			AuthorizationModelID: modelID,
+			TupleKey:             tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+			RequestMetadata:      NewCheckRequestMetadata(25),
+			TupleOverlay:         overlay,
+		})
+		require.NoError(t, err)
+		return key
+	}
+
+	added := &storagewrappers.TupleOverlay{
+		AddedTuples: []*openfgav1.TupleKey{tuple.NewTupleKey("document:1", "viewer", "user:jon")},
+	}
+	removed := &storagewrappers.TupleOverlay{
+		RemovedTuples: []*openfgav1.TupleKeyWithoutCondition{
+			tuple.TupleKeyToTupleKeyWithoutCondition(tuple.NewTupleKey("document:1", "viewer", "user:jon")),
+		},
+	}
+
+	require.NotEqual(t, key(nil), key(added))
+	require.NotEqual(t, key(nil), key(removed))
+	require.NotEqual(t, key(added), key(removed))
+	require.Equal(t, key(added), key(&storagewrappers.TupleOverlay{AddedTuples: added.AddedTuples}))
+}
+
 func BenchmarkCheckRequestCacheKey(b *testing.B) {
 	storeID := ulid.Make().String()
 	modelID := ulid.Make().String()
@@ -944,3 +1067,114 @@ func BenchmarkCheckRequestCacheKeyWithContext(b *testing.B) {
 		require.NoError(b, err)
 	}
 }
+
+func TestCachedCheckResolverInvalidateStore(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockResolver := NewMockCheckResolver(ctrl)
+
+	cachedCheckResolver := NewCachedCheckResolver()
+	t.Cleanup(cachedCheckResolver.Close)
+	cachedCheckResolver.SetDelegate(mockResolver)
+
+	request := func(storeID string) *ResolveCheckRequest {
+		return &ResolveCheckRequest{
+			StoreID:              storeID,
+			AuthorizationModelID: "33",
+			TupleKey:             tuple.NewTupleKey("document:abc", "reader", "user:XYZ"),
+			RequestMetadata:      NewCheckRequestMetadata(20),
+		}
+	}
+
+	invalidated := request("12")
+	other := request("22")
+
+	// the result of the invalidated store is resolved anew, while the other store's is still cached
+	mockResolver.EXPECT().ResolveCheck(gomock.Any(), invalidated).Times(2).Return(&ResolveCheckResponse{Allowed: true}, nil)
+	mockResolver.EXPECT().ResolveCheck(gomock.Any(), other).Times(1).Return(&ResolveCheckResponse{Allowed: true}, nil)
+
+	for _, req := range []*ResolveCheckRequest{invalidated, other} {
+		_, err := cachedCheckResolver.ResolveCheck(ctx, req)
+		require.NoError(t, err)
+	}
+
+	cachedCheckResolver.InvalidateStore("12")
+
+	for _, req := range []*ResolveCheckRequest{invalidated, other} {
+		resp, err := cachedCheckResolver.ResolveCheck(ctx, req)
+		require.NoError(t, err)
+		require.True(t, resp.GetAllowed())
+	}
+}
+
+func TestCachedCheckResolverBatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+
+	newRequest := func(object string) *ResolveCheckRequest {
+		return &ResolveCheckRequest{
+			StoreID:              "12",
+			AuthorizationModelID: "33",
+			TupleKey:             tuple.NewTupleKey(object, "reader", "user:XYZ"),
+			RequestMetadata:      NewCheckRequestMetadata(20),
+		}
+	}
+
+	cachedReq := newRequest("document:cached")
+	missedReq := newRequest("document:missed")
+	failedReq := newRequest("document:failed")
+
+	resolveErr := errors.New("resolve failed")
+
+	mockResolver := NewMockCheckResolver(ctrl)
+	mockResolver.EXPECT().ResolveCheck(gomock.Any(), cachedReq).Times(1).Return(&ResolveCheckResponse{
+		Allowed:            true,
+		ResolutionMetadata: &ResolveCheckResponseMetadata{DatastoreQueryCount: 1},
+	}, nil)
+	// only the requests missing from the cache are forwarded
+	mockResolver.EXPECT().BatchResolveCheck(gomock.Any(), []*ResolveCheckRequest{missedReq, failedReq}).Times(1).Return(
+		[]*ResolveCheckResponse{
+			{Allowed: true, ResolutionMetadata: &ResolveCheckResponseMetadata{DatastoreQueryCount: 1}},
+			nil,
+		},
+		&BatchResolveCheckError{Errors: []error{nil, resolveErr}},
+	)
+
+	dut := NewCachedCheckResolver()
+	defer dut.Close()
+
+	dut.SetDelegate(mockResolver)
+
+	_, err := dut.ResolveCheck(ctx, cachedReq)
+	require.NoError(t, err)
+
+	resps, err := dut.BatchResolveCheck(ctx, []*ResolveCheckRequest{cachedReq, missedReq, failedReq})
+	require.ErrorIs(t, err, resolveErr)
+
+	var batchErr *BatchResolveCheckError
+	require.ErrorAs(t, err, &batchErr)
+	require.Equal(t, []error{nil, nil, resolveErr}, batchErr.Errors)
+
+	require.True(t, resps[0].GetAllowed())
+	require.True(t, resps[1].GetAllowed())
+	require.Nil(t, resps[2])
+
+	// the forwarded request that succeeded is cached, the failed one is not
+	mockResolver.EXPECT().BatchResolveCheck(gomock.Any(), []*ResolveCheckRequest{failedReq}).Times(1).Return(
+		[]*ResolveCheckResponse{{Allowed: false, ResolutionMetadata: &ResolveCheckResponseMetadata{}}}, nil,
+	)
+
+	resps, err = dut.BatchResolveCheck(ctx, []*ResolveCheckRequest{missedReq, failedReq})
+	require.NoError(t, err)
+	require.True(t, resps[0].GetAllowed())
+	require.False(t, resps[1].GetAllowed())
+}
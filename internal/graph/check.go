@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"sync"
+	"sync/atomic"
 
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	"go.opentelemetry.io/otel"
@@ -18,6 +20,7 @@ import (
 	serverconfig "github.com/openfga/openfga/internal/server/config"
 	"github.com/openfga/openfga/internal/validation"
 	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/storagewrappers"
 	"github.com/openfga/openfga/pkg/telemetry"
 	"github.com/openfga/openfga/pkg/tuple"
 	"github.com/openfga/openfga/pkg/typesystem"
@@ -33,6 +36,19 @@ type ResolveCheckRequest struct {
 	Context              *structpb.Struct
 	RequestMetadata      *ResolveCheckRequestMetadata
 	VisitedPaths         map[string]struct{}
+
+	// MaxIndirectionDepth, if not 0, is the maximum number of userset tuples (e.g. 'document:1#viewer@group:eng#member')
+	// followed to find the user, so 1 only considers direct grants and grants through one level of group membership.
+	MaxIndirectionDepth uint32
+	// IndirectionDepth is the number of userset tuples followed to reach this request.
+	IndirectionDepth uint32
+
+	// ResolutionTrace, if true, makes the LocalChecker report the ResolutionPath of the response.
+	ResolutionTrace bool
+
+	// TupleOverlay, if not nil, holds the hypothetical changes of the tuples the request is resolved with, so that
+	// its results are not cached for the requests resolved against the persisted tuples.
+	TupleOverlay *storagewrappers.TupleOverlay
 }
 
 func clone(r *ResolveCheckRequest) *ResolveCheckRequest {
@@ -45,10 +61,17 @@ func clone(r *ResolveCheckRequest) *ResolveCheckRequest {
 		RequestMetadata: &ResolveCheckRequestMetadata{
 			DispatchCounter:     r.GetRequestMetadata().DispatchCounter,
 			Depth:               r.GetRequestMetadata().Depth,
+			ResolveNodeLimit:    r.GetRequestMetadata().ResolveNodeLimit,
 			DatastoreQueryCount: r.GetRequestMetadata().DatastoreQueryCount,
 			WasThrottled:        r.GetRequestMetadata().WasThrottled,
+
+			ConditionEvaluationCounter: r.GetRequestMetadata().ConditionEvaluationCounter,
 		},
-		VisitedPaths: maps.Clone(r.VisitedPaths),
+		VisitedPaths:        maps.Clone(r.VisitedPaths),
+		MaxIndirectionDepth: r.MaxIndirectionDepth,
+		IndirectionDepth:    r.IndirectionDepth,
+		ResolutionTrace:     r.ResolutionTrace,
+		TupleOverlay:        r.TupleOverlay,
 	}
 }
 
@@ -70,12 +93,40 @@ func CloneResolveCheckResponse(r *ResolveCheckResponse) *ResolveCheckResponse {
 	return &ResolveCheckResponse{
 		Allowed:            r.GetAllowed(),
 		ResolutionMetadata: resolutionMetadata,
+		ResolutionPath:     r.GetResolutionPath(),
+		Obligations:        r.GetObligations(),
 	}
 }
 
 type ResolveCheckResponse struct {
 	Allowed            bool
 	ResolutionMetadata *ResolveCheckResponseMetadata
+
+	// ResolutionPath is the chain of 'object#relation' nodes, from the one of the request to the one the
+	// decision was made on, e.g. ["document:1#viewer", "document:1#editor"]. A node prefixed with "but not "
+	// is the subtracted side of an exclusion that denied the request. It is only set for requests with
+	// ResolutionTrace.
+	ResolutionPath []string
+
+	// Obligations are the distinct names of the conditions of the tuples granting access, in the order they
+	// were followed from the request, e.g. ["step_up"]. They are only set for allowed responses.
+	Obligations []string
+}
+
+func (r *ResolveCheckResponse) GetResolutionPath() []string {
+	if r != nil {
+		return r.ResolutionPath
+	}
+
+	return nil
+}
+
+func (r *ResolveCheckResponse) GetObligations() []string {
+	if r != nil {
+		return r.Obligations
+	}
+
+	return nil
 }
 
 func (r *ResolveCheckResponse) GetCycleDetected() bool {
@@ -149,6 +200,43 @@ func (r *ResolveCheckRequest) GetContext() *structpb.Struct {
 	return nil
 }
 
+func (r *ResolveCheckRequest) GetMaxIndirectionDepth() uint32 {
+	if r != nil {
+		return r.MaxIndirectionDepth
+	}
+
+	return 0
+}
+
+func (r *ResolveCheckRequest) GetIndirectionDepth() uint32 {
+	if r != nil {
+		return r.IndirectionDepth
+	}
+
+	return 0
+}
+
+func (r *ResolveCheckRequest) GetResolutionTrace() bool {
+	if r != nil {
+		return r.ResolutionTrace
+	}
+
+	return false
+}
+
+func (r *ResolveCheckRequest) GetTupleOverlay() *storagewrappers.TupleOverlay {
+	if r != nil {
+		return r.TupleOverlay
+	}
+
+	return nil
+}
+
+// indirectionLimitReached returns true if no more userset tuples may be followed for the request.
+func (r *ResolveCheckRequest) indirectionLimitReached() bool {
+	return r.GetMaxIndirectionDepth() > 0 && r.GetIndirectionDepth() >= r.GetMaxIndirectionDepth()
+}
+
 type setOperatorType int
 
 const (
@@ -165,7 +253,19 @@ type checkOutcome struct {
 type LocalChecker struct {
 	delegate           CheckResolver
 	concurrencyLimit   uint32
+	unionLimit         uint32
+	ttuLimit           uint32
 	maxConcurrentReads uint32
+	maxNodeFanout      uint32
+	workerPool         *workerPool
+
+	maxConditionEvaluations  uint32
+	conditionEvaluationCache bool
+
+	unassignableRelationError      bool
+	undefinedComputedRelationError bool
+
+	relationWeigher RelationWeigher
 }
 
 type LocalCheckerOption func(d *LocalChecker)
@@ -177,6 +277,20 @@ func WithResolveNodeBreadthLimit(limit uint32) LocalCheckerOption {
 	}
 }
 
+// WithUnionConcurrencyLimit see server.WithResolveNodeUnionBreadthLimit.
+func WithUnionConcurrencyLimit(limit uint32) LocalCheckerOption {
+	return func(d *LocalChecker) {
+		d.unionLimit = limit
+	}
+}
+
+// WithTTUConcurrencyLimit see server.WithResolveNodeTTUBreadthLimit.
+func WithTTUConcurrencyLimit(limit uint32) LocalCheckerOption {
+	return func(d *LocalChecker) {
+		d.ttuLimit = limit
+	}
+}
+
 // WithMaxConcurrentReads see server.WithMaxConcurrentReadsForCheck.
 func WithMaxConcurrentReads(limit uint32) LocalCheckerOption {
 	return func(d *LocalChecker) {
@@ -184,6 +298,87 @@ func WithMaxConcurrentReads(limit uint32) LocalCheckerOption {
 	}
 }
 
+// WithMaxNodeFanout limits the number of children of a union or intersection rewrite that are evaluated
+// at the same time. Rewrites with more than 'limit' children are evaluated in sequential batches of at most
+// 'limit' children, and evaluation stops as soon as a batch determines the outcome. A limit of 0 (the default)
+// evaluates all the children at once.
+func WithMaxNodeFanout(limit uint32) LocalCheckerOption {
+	return func(d *LocalChecker) {
+		d.maxNodeFanout = limit
+	}
+}
+
+// WithDispatchWorkerPool makes the LocalChecker evaluate the children of its rewrites on reusable goroutines
+// instead of starting new goroutines for each of them, keeping up to maxIdleWorkers idle goroutines around
+// between evaluations. The LocalChecker must then be closed to stop them. A maxIdleWorkers of 0 (the default)
+// disables the pool.
+func WithDispatchWorkerPool(maxIdleWorkers uint32) LocalCheckerOption {
+	return func(d *LocalChecker) {
+		if maxIdleWorkers > 0 {
+			d.workerPool = newWorkerPool(maxIdleWorkers, dispatchWorkerIdleTimeout)
+		}
+	}
+}
+
+// WithMaxConditionEvaluations limits the number of tuple conditions, of persisted and contextual tuples alike,
+// that may be evaluated while resolving a single Check. Once crossed, ResolveCheck returns
+// ErrConditionEvaluationsLimitExceeded. A limit of 0 (the default) means there is no limit.
+func WithMaxConditionEvaluations(limit uint32) LocalCheckerOption {
+	return func(d *LocalChecker) {
+		d.maxConditionEvaluations = limit
+	}
+}
+
+// WithConditionEvaluationCache makes the LocalChecker evaluate a tuple condition only once per Check for
+// the same condition and condition context, e.g. when many tuples of a relation are gated by the same
+// condition without a tuple context. The memoized evaluations do not count towards the limit set with
+// WithMaxConditionEvaluations.
+func WithConditionEvaluationCache(enabled bool) LocalCheckerOption {
+	return func(d *LocalChecker) {
+		d.conditionEvaluationCache = enabled
+	}
+}
+
+// WithUnassignableRelationError sets what the LocalChecker does when it evaluates the direct relationships
+// of a relation without directly related user types, which cannot have tuples of its own. By default the
+// direct relationships are resolved as not allowed without reading the datastore, and the rest of the rewrite
+// is resolved as usual. If enabled, ResolveCheck returns ErrUnassignableRelation instead.
+func WithUnassignableRelationError(enabled bool) LocalCheckerOption {
+	return func(d *LocalChecker) {
+		d.unassignableRelationError = enabled
+	}
+}
+
+// WithUndefinedComputedRelationError sets what the LocalChecker does when the tupleset relation of a tuple to userset
+// rewrite leads to an object whose type does not define the computed relation, which the model allows as long as some
+// of the related types define it. By default such objects are skipped. If enabled, ResolveCheck returns an
+// *UndefinedComputedRelationError naming the relations instead, to surface models that silently deny.
+func WithUndefinedComputedRelationError(enabled bool) LocalCheckerOption {
+	return func(d *LocalChecker) {
+		d.undefinedComputedRelationError = enabled
+	}
+}
+
+// WithRelationWeigher makes the LocalChecker start evaluating the operands of intersection and exclusion rewrites
+// from the cheapest one according to the weigher, so that a cheap operand denying the request spares the evaluation
+// of the expensive ones when they are not all evaluated at once (see WithResolveNodeBreadthLimit and WithMaxNodeFanout).
+// Only the order in which the operands are started changes, not the outcome. By default, and for the operands the
+// weigher has no estimate for, the operands are started in the order of the model.
+func WithRelationWeigher(weigher RelationWeigher) LocalCheckerOption {
+	return func(d *LocalChecker) {
+		d.relationWeigher = weigher
+	}
+}
+
+// operatorConcurrencyLimit returns the concurrency limit of an operator, the limit set for it if any,
+// or else the one of WithResolveNodeBreadthLimit.
+func (c *LocalChecker) operatorConcurrencyLimit(limit uint32) uint32 {
+	if limit == 0 {
+		return c.concurrencyLimit
+	}
+	return limit
+}
+
 // NewLocalChecker constructs a LocalChecker that can be used to evaluate a Check
 // request locally.
 //
@@ -242,6 +437,7 @@ type CheckFuncReducer func(ctx context.Context, concurrencyLimit uint32, handler
 // evaluations in flight at any point.
 func resolver(ctx context.Context, concurrencyLimit uint32, resultChan chan<- checkOutcome, handlers ...CheckHandlerFunc) func() {
 	limiter := make(chan struct{}, concurrencyLimit)
+	spawn := goFunc(ctx)
 
 	var wg sync.WaitGroup
 
@@ -254,14 +450,14 @@ func resolver(ctx context.Context, concurrencyLimit uint32, resultChan chan<- ch
 		resolved := make(chan checkOutcome, 1)
 
 		if ctx.Err() != nil {
-			resultChan <- checkOutcome{nil, ctx.Err()}
+			resultChan <- checkOutcome{nil, contextErr(ctx)}
 			return
 		}
 
-		go func() {
+		spawn(func() {
 			resp, err := fn(ctx)
 			resolved <- checkOutcome{resp, err}
-		}()
+		})
 
 		select {
 		case <-ctx.Done():
@@ -272,7 +468,7 @@ func resolver(ctx context.Context, concurrencyLimit uint32, resultChan chan<- ch
 	}
 
 	wg.Add(1)
-	go func() {
+	spawn(func() {
 	outer:
 		for _, handler := range handlers {
 			fn := handler // capture loop var
@@ -280,14 +476,14 @@ func resolver(ctx context.Context, concurrencyLimit uint32, resultChan chan<- ch
 			select {
 			case limiter <- struct{}{}:
 				wg.Add(1)
-				go checker(fn)
+				spawn(func() { checker(fn) })
 			case <-ctx.Done():
 				break outer
 			}
 		}
 
 		wg.Done()
-	}()
+	})
 
 	return func() {
 		wg.Wait()
@@ -331,7 +527,7 @@ func union(ctx context.Context, concurrencyLimit uint32, handlers ...CheckHandle
 				return result.resp, nil
 			}
 		case <-ctx.Done():
-			return nil, ctx.Err()
+			return nil, contextErr(ctx)
 		}
 	}
 
@@ -373,6 +569,8 @@ func intersection(ctx context.Context, concurrencyLimit uint32, handlers ...Chec
 
 	var dbReads uint32
 	var err error
+	var path []string
+	var obligations []string
 	for i := 0; i < len(handlers); i++ {
 		select {
 		case result := <-resultChan:
@@ -388,8 +586,15 @@ func intersection(ctx context.Context, concurrencyLimit uint32, handlers ...Chec
 				result.resp.GetResolutionMetadata().DatastoreQueryCount = dbReads
 				return result.resp, nil
 			}
+
+			if path == nil {
+				path = result.resp.GetResolutionPath()
+			}
+
+			// every operand grants access, so the obligations of all of them apply
+			obligations = mergeObligations(obligations, result.resp.GetObligations())
 		case <-ctx.Done():
-			return nil, ctx.Err()
+			return nil, contextErr(ctx)
 		}
 	}
 
@@ -403,13 +608,81 @@ func intersection(ctx context.Context, concurrencyLimit uint32, handlers ...Chec
 		ResolutionMetadata: &ResolveCheckResponseMetadata{
 			DatastoreQueryCount: dbReads,
 		},
+		ResolutionPath: path,
+		Obligations:    obligations,
 	}, nil
 }
 
+// batched returns a CheckFuncReducer that evaluates the provided CheckHandlerFunc with the union or
+// intersection 'reducer' in sequential batches of at most batchSize handlers. The first batch resolving
+// to the 'decisive' outcome (true for union, false for intersection) causes premature termination of the
+// reducer, so the remaining batches are never evaluated.
+func batched(reducer CheckFuncReducer, batchSize uint32, decisive bool) CheckFuncReducer {
+	return func(ctx context.Context, concurrencyLimit uint32, handlers ...CheckHandlerFunc) (*ResolveCheckResponse, error) {
+		if batchSize == 0 || len(handlers) <= int(batchSize) {
+			return reducer(ctx, concurrencyLimit, handlers...)
+		}
+
+		var dbReads uint32
+		var err error
+		var cycleDetected bool
+		var obligations []string
+		for start := 0; start < len(handlers); start += int(batchSize) {
+			if ctx.Err() != nil {
+				return nil, contextErr(ctx)
+			}
+
+			end := min(start+int(batchSize), len(handlers))
+
+			resp, batchErr := reducer(ctx, concurrencyLimit, handlers[start:end]...)
+			if batchErr != nil {
+				err = errors.Join(err, batchErr)
+				continue
+			}
+
+			dbReads += resp.GetResolutionMetadata().DatastoreQueryCount
+
+			if resp.GetAllowed() == decisive || (!decisive && resp.GetCycleDetected()) {
+				resp.GetResolutionMetadata().DatastoreQueryCount = dbReads
+				return resp, nil
+			}
+
+			if resp.GetCycleDetected() {
+				cycleDetected = true
+			}
+
+			obligations = mergeObligations(obligations, resp.GetObligations())
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &ResolveCheckResponse{
+			Allowed: !decisive,
+			ResolutionMetadata: &ResolveCheckResponseMetadata{
+				DatastoreQueryCount: dbReads,
+				CycleDetected:       cycleDetected,
+			},
+			Obligations: obligations,
+		}, nil
+	}
+}
+
 // exclusion implements a CheckFuncReducer that requires a 'base' CheckHandlerFunc to resolve to an allowed
 // outcome and a 'sub' CheckHandlerFunc to resolve to a falsey outcome. The base and sub computations are
 // handled concurrently relative to one another.
 func exclusion(ctx context.Context, concurrencyLimit uint32, handlers ...CheckHandlerFunc) (*ResolveCheckResponse, error) {
+	return evaluateExclusion(ctx, concurrencyLimit, false, handlers...)
+}
+
+// exclusionSubtractFirst is like exclusion, but starts the 'sub' computation before the 'base' one, so that
+// with a concurrency limit of 1 a cheap 'sub' resolving to an allowed outcome spares the 'base' computation.
+func exclusionSubtractFirst(ctx context.Context, concurrencyLimit uint32, handlers ...CheckHandlerFunc) (*ResolveCheckResponse, error) {
+	return evaluateExclusion(ctx, concurrencyLimit, true, handlers...)
+}
+
+func evaluateExclusion(ctx context.Context, concurrencyLimit uint32, subtractFirst bool, handlers ...CheckHandlerFunc) (*ResolveCheckResponse, error) {
 	if len(handlers) != 2 {
 		panic(fmt.Sprintf("expected two rewrite operands for exclusion operator, but got '%d'", len(handlers)))
 	}
@@ -434,23 +707,24 @@ func exclusion(ctx context.Context, concurrencyLimit uint32, handlers ...CheckHa
 	baseHandler := handlers[0]
 	subHandler := handlers[1]
 
-	limiter <- struct{}{}
-	wg.Add(1)
-	go func() {
-		resp, err := baseHandler(ctx)
-		baseChan <- checkOutcome{resp, err}
-		<-limiter
-		wg.Done()
-	}()
+	start := func(handler CheckHandlerFunc, outcomes chan<- checkOutcome) {
+		limiter <- struct{}{}
+		wg.Add(1)
+		go func() {
+			resp, err := handler(ctx)
+			outcomes <- checkOutcome{resp, err}
+			<-limiter
+			wg.Done()
+		}()
+	}
 
-	limiter <- struct{}{}
-	wg.Add(1)
-	go func() {
-		resp, err := subHandler(ctx)
-		subChan <- checkOutcome{resp, err}
-		<-limiter
-		wg.Done()
-	}()
+	if subtractFirst {
+		start(subHandler, subChan)
+		start(baseHandler, baseChan)
+	} else {
+		start(baseHandler, baseChan)
+		start(subHandler, subChan)
+	}
 
 	response := &ResolveCheckResponse{
 		Allowed: false,
@@ -461,6 +735,8 @@ func exclusion(ctx context.Context, concurrencyLimit uint32, handlers ...CheckHa
 
 	var baseErr error
 	var subErr error
+	var basePath []string
+	var baseObligations []string
 
 	var dbReads uint32
 	for i := 0; i < len(handlers); i++ {
@@ -486,9 +762,13 @@ func exclusion(ctx context.Context, concurrencyLimit uint32, handlers ...CheckHa
 
 			if !baseResult.resp.GetAllowed() {
 				response.GetResolutionMetadata().DatastoreQueryCount = dbReads
+				response.ResolutionPath = baseResult.resp.GetResolutionPath()
 				return response, nil
 			}
 
+			basePath = baseResult.resp.GetResolutionPath()
+			baseObligations = baseResult.resp.GetObligations()
+
 		case subResult := <-subChan:
 			if subResult.err != nil {
 				span.RecordError(subResult.err)
@@ -510,10 +790,14 @@ func exclusion(ctx context.Context, concurrencyLimit uint32, handlers ...CheckHa
 
 			if subResult.resp.GetAllowed() {
 				response.GetResolutionMetadata().DatastoreQueryCount = dbReads
+				if subPath := subResult.resp.GetResolutionPath(); len(subPath) > 0 {
+					response.ResolutionPath = slices.Clone(subPath)
+					response.ResolutionPath[0] = "but not " + subPath[0]
+				}
 				return response, nil
 			}
 		case <-ctx.Done():
-			return nil, ctx.Err()
+			return nil, contextErr(ctx)
 		}
 	}
 
@@ -531,17 +815,72 @@ func exclusion(ctx context.Context, concurrencyLimit uint32, handlers ...CheckHa
 		ResolutionMetadata: &ResolveCheckResponseMetadata{
 			DatastoreQueryCount: dbReads,
 		},
+		ResolutionPath: basePath,
+		Obligations:    baseObligations,
 	}, nil
 }
 
-// Close is a noop.
+// Close stops the idle dispatch workers, if any. Calls after the first one are noops.
 func (c *LocalChecker) Close() {
+	if c.workerPool != nil {
+		c.workerPool.Close()
+	}
+}
+
+// evaluateTupleCondition evaluates the condition of the tuple, if any, counting the evaluation against
+// the limit set with WithMaxConditionEvaluations.
+func (c *LocalChecker) evaluateTupleCondition(
+	ctx context.Context,
+	req *ResolveCheckRequest,
+	t *openfgav1.TupleKey,
+	typesys *typesystem.TypeSystem,
+) (*condition.EvaluationResult, error) {
+	if t.GetCondition().GetName() == "" {
+		return eval.EvaluateTupleCondition(ctx, t, typesys, req.GetContext())
+	}
+
+	cache, cached := conditionEvaluationCacheFromContext(ctx)
+
+	var key string
+	if cached {
+		var err error
+		key, err = conditionEvaluationCacheKey(t, req.GetContext())
+		if err != nil {
+			return nil, err
+		}
+
+		if res, ok := cache.get(key); ok {
+			return res, nil
+		}
+	}
+
+	if counter := req.GetRequestMetadata().ConditionEvaluationCounter; counter != nil {
+		evaluations := counter.Add(1)
+		if c.maxConditionEvaluations > 0 && evaluations > c.maxConditionEvaluations {
+			return nil, ErrConditionEvaluationsLimitExceeded
+		}
+	}
+
+	res, err := eval.EvaluateTupleCondition(ctx, t, typesys, req.GetContext())
+	if err != nil {
+		return nil, err
+	}
+
+	if cached {
+		cache.set(key, res)
+	}
+
+	return res, nil
 }
 
 // dispatch clones the parent request, modifies its metadata and tupleKey, and dispatches the new request
 // to the CheckResolver this LocalChecker was constructed with.
 func (c *LocalChecker) dispatch(_ context.Context, parentReq *ResolveCheckRequest, tk *openfgav1.TupleKey) CheckHandlerFunc {
 	return func(ctx context.Context) (*ResolveCheckResponse, error) {
+		if resp, ok := visitedCycle(parentReq, tk); ok {
+			return resp, nil
+		}
+
 		parentReq.GetRequestMetadata().DispatchCounter.Add(1)
 		childRequest := clone(parentReq)
 		childRequest.TupleKey = tk
@@ -555,6 +894,89 @@ func (c *LocalChecker) dispatch(_ context.Context, parentReq *ResolveCheckReques
 	}
 }
 
+// dispatchUserset is like dispatch, but for the tuple key of a followed userset tuple,
+// which counts towards the MaxIndirectionDepth of the request.
+func (c *LocalChecker) dispatchUserset(_ context.Context, parentReq *ResolveCheckRequest, tk *openfgav1.TupleKey) CheckHandlerFunc {
+	return func(ctx context.Context) (*ResolveCheckResponse, error) {
+		if resp, ok := visitedCycle(parentReq, tk); ok {
+			return resp, nil
+		}
+
+		parentReq.GetRequestMetadata().DispatchCounter.Add(1)
+		childRequest := clone(parentReq)
+		childRequest.TupleKey = tk
+		childRequest.GetRequestMetadata().Depth--
+		childRequest.IndirectionDepth++
+
+		return c.delegate.ResolveCheck(ctx, childRequest)
+	}
+}
+
+// contextErr returns the error of the context, wrapped in ErrRequestDeadlineExceeded if its deadline expired.
+func contextErr(ctx context.Context) error {
+	err := ctx.Err()
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", ErrRequestDeadlineExceeded, err)
+	}
+
+	return err
+}
+
+// visitedCycle returns a response with CycleDetected set if the tuple key was already visited to reach the parent
+// request, as recorded in its VisitedPaths by a CycleDetectionCheckResolver. It lets a self-referential userset (e.g.
+// 'group:1#member@group:1#member') be skipped before it is dispatched, instead of by the CycleDetectionCheckResolver
+// after going through the resolvers in front of it. Like a cycle found by the CycleDetectionCheckResolver, it is not
+// an error, the branch just doesn't allow the user.
+func visitedCycle(parentReq *ResolveCheckRequest, tk *openfgav1.TupleKey) (*ResolveCheckResponse, bool) {
+	if len(parentReq.VisitedPaths) == 0 {
+		return nil, false
+	}
+
+	if _, ok := parentReq.VisitedPaths[tuple.TupleKeyToString(tk)]; !ok {
+		return nil, false
+	}
+
+	return &ResolveCheckResponse{
+		Allowed: false,
+		ResolutionMetadata: &ResolveCheckResponseMetadata{
+			CycleDetected: true,
+		},
+	}, true
+}
+
+// withObligation returns a CheckHandlerFunc that adds the condition of the tuple it follows, if any, to the
+// obligations of the response of the handler when it allows the request.
+func withObligation(handler CheckHandlerFunc, conditionName string) CheckHandlerFunc {
+	if conditionName == "" {
+		return handler
+	}
+
+	return func(ctx context.Context) (*ResolveCheckResponse, error) {
+		resp, err := handler(ctx)
+		if err != nil || !resp.GetAllowed() {
+			return resp, err
+		}
+
+		// the response may be cached, so the obligation is added to a copy
+		obligations := mergeObligations([]string{conditionName}, resp.GetObligations())
+		resp = CloneResolveCheckResponse(resp)
+		resp.Obligations = obligations
+
+		return resp, nil
+	}
+}
+
+// mergeObligations appends to the obligations the ones of more that it doesn't hold yet.
+func mergeObligations(obligations, more []string) []string {
+	for _, name := range more {
+		if !slices.Contains(obligations, name) {
+			obligations = append(obligations, name)
+		}
+	}
+
+	return obligations
+}
+
 var _ CheckResolver = (*LocalChecker)(nil)
 
 // ResolveCheck implements [[CheckResolver.ResolveCheck]].
@@ -564,7 +986,7 @@ func (c *LocalChecker) ResolveCheck(
 	req *ResolveCheckRequest,
 ) (*ResolveCheckResponse, error) {
 	if ctx.Err() != nil {
-		return nil, ctx.Err()
+		return nil, NewResolveCheckError(req, contextErr(ctx))
 	}
 
 	ctx, span := tracer.Start(ctx, "ResolveCheck", trace.WithAttributes(
@@ -574,8 +996,21 @@ func (c *LocalChecker) ResolveCheck(
 	))
 	defer span.End()
 
+	if err := validateCheckTupleKey(req.GetTupleKey()); err != nil {
+		return nil, err
+	}
+
+	if c.workerPool != nil && ctx.Value(workerPoolCtxKey) == nil {
+		ctx = context.WithValue(ctx, workerPoolCtxKey, c.workerPool)
+	}
+
+	// the evaluations are memoized for the whole Check, so the cache is set by its first request
+	if _, ok := conditionEvaluationCacheFromContext(ctx); c.conditionEvaluationCache && !ok {
+		ctx = context.WithValue(ctx, conditionEvaluationCacheCtxKey{}, newConditionEvaluationCache())
+	}
+
 	if req.GetRequestMetadata().Depth == 0 {
-		return nil, ErrResolutionDepthExceeded
+		return nil, NewResolveCheckError(req, &ResolutionDepthExceededError{Limit: req.GetRequestMetadata().ResolveNodeLimit})
 	}
 
 	typesys, ok := typesystem.TypesystemFromContext(ctx)
@@ -591,12 +1026,17 @@ func (c *LocalChecker) ResolveCheck(
 
 	// Check(document:1#viewer@document:1#viewer) will always return true
 	if relation == userRelation && object == userObject {
-		return &ResolveCheckResponse{
+		resp := &ResolveCheckResponse{
 			Allowed: true,
 			ResolutionMetadata: &ResolveCheckResponseMetadata{
 				DatastoreQueryCount: req.GetRequestMetadata().DatastoreQueryCount,
 			},
-		}, nil
+		}
+		if req.GetResolutionTrace() {
+			resp.ResolutionPath = []string{tuple.ToObjectRelationString(object, relation)}
+		}
+
+		return resp, nil
 	}
 
 	objectType, _ := tuple.SplitObject(object)
@@ -605,15 +1045,121 @@ func (c *LocalChecker) ResolveCheck(
 		return nil, fmt.Errorf("relation '%s' undefined for object type '%s'", relation, objectType)
 	}
 
+	resultCache, sharesResults := CheckResultCacheFromContext(ctx)
+
+	var cacheKey string
+	if sharesResults {
+		cacheKey, err = CheckRequestCacheKey(req)
+		if err != nil {
+			telemetry.TraceError(span, err)
+			return nil, err
+		}
+
+		if resp, ok := resultCache.get(cacheKey); ok {
+			span.SetAttributes(attribute.Bool("is_shared_result", true))
+			return resp, nil
+		}
+	}
+
 	resp, err := c.checkRewrite(ctx, req, rel.GetRewrite())(ctx)
 	if err != nil {
 		telemetry.TraceError(span, err)
-		return nil, err
+		return nil, NewResolveCheckError(req, err)
+	}
+
+	if req.GetResolutionTrace() {
+		// the response may be shared with the reducers, so the node is prepended to a copy
+		path := append([]string{tuple.ToObjectRelationString(object, relation)}, resp.GetResolutionPath()...)
+		resp = CloneResolveCheckResponse(resp)
+		resp.ResolutionPath = path
+	}
+
+	if sharesResults {
+		resultCache.set(cacheKey, resp)
 	}
 
 	return resp, nil
 }
 
+// BatchResolveCheck implements CheckResolver. The identical requests of the batch (same tuple key, contextual tuples
+// and context) are resolved once, and the distinct ones are resolved concurrently, at most as many at a time as the
+// resolve node breadth limit.
+func (c *LocalChecker) BatchResolveCheck(ctx context.Context, reqs []*ResolveCheckRequest) ([]*ResolveCheckResponse, error) {
+	ctx, span := tracer.Start(ctx, "BatchResolveCheck", trace.WithAttributes(
+		attribute.Int("batch_size", len(reqs)),
+	))
+	defer span.End()
+
+	resps := make([]*ResolveCheckResponse, len(reqs))
+	errs := make([]error, len(reqs))
+
+	// [cache key] => index of the first request with that key
+	distinct := map[string]int{}
+	// index of a request => index of the identical request resolved in its place
+	resolvedBy := make([]int, len(reqs))
+	for i, req := range reqs {
+		resolvedBy[i] = i
+
+		key, err := CheckRequestCacheKey(req)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+
+		if first, ok := distinct[key]; ok {
+			resolvedBy[i] = first
+			continue
+		}
+		distinct[key] = i
+	}
+
+	limit := c.concurrencyLimit
+	if limit == 0 {
+		limit = 1
+	}
+	limiter := make(chan struct{}, limit)
+
+	var wg sync.WaitGroup
+	for _, i := range distinct {
+		limiter <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-limiter
+				wg.Done()
+			}()
+
+			resps[i], errs[i] = c.ResolveCheck(ctx, reqs[i])
+		}(i)
+	}
+	wg.Wait()
+
+	failed := false
+	for i := range reqs {
+		if first := resolvedBy[i]; first != i {
+			errs[i] = errs[first]
+			if resps[first] != nil {
+				resps[i] = CloneResolveCheckResponse(resps[first])
+			}
+		}
+
+		if errs[i] != nil {
+			resps[i] = nil
+			failed = true
+		}
+	}
+
+	span.SetAttributes(attribute.Int("distinct_requests", len(distinct)))
+
+	if failed {
+		err := &BatchResolveCheckError{Errors: errs}
+		telemetry.TraceError(span, err)
+		return resps, err
+	}
+
+	return resps, nil
+}
+
 // checkDirect composes two CheckHandlerFunc which evaluate direct relationships with the provided
 // 'object#relation'. The first handler looks up direct matches on the provided 'object#relation@user',
 // while the second handler looks up relationships between the target 'object#relation' and any usersets
@@ -624,7 +1170,7 @@ func (c *LocalChecker) checkDirect(parentctx context.Context, req *ResolveCheckR
 		defer span.End()
 
 		if ctx.Err() != nil {
-			return nil, ctx.Err()
+			return nil, contextErr(ctx)
 		}
 
 		typesys, ok := typesystem.TypesystemFromContext(parentctx) // note: use of 'parentctx' not 'ctx' - this is important
@@ -642,6 +1188,22 @@ func (c *LocalChecker) checkDirect(parentctx context.Context, req *ResolveCheckR
 		objectType := tuple.GetType(reqTupleKey.GetObject())
 		relation := reqTupleKey.GetRelation()
 
+		// a relation without directly related user types cannot have tuples of its own
+		if directlyRelatedTypes, _ := typesys.GetDirectlyRelatedUserTypes(objectType, relation); len(directlyRelatedTypes) == 0 {
+			if c.unassignableRelationError {
+				err := fmt.Errorf("%w: '%s'", ErrUnassignableRelation, tuple.ToObjectRelationString(objectType, relation))
+				telemetry.TraceError(span, err)
+				return nil, err
+			}
+
+			return &ResolveCheckResponse{
+				Allowed: false,
+				ResolutionMetadata: &ResolveCheckResponseMetadata{
+					DatastoreQueryCount: req.GetRequestMetadata().DatastoreQueryCount,
+				},
+			}, nil
+		}
+
 		// directlyRelatedUsersetTypes could be "user:*" or "group#member"
 		directlyRelatedUsersetTypes, _ := typesys.DirectlyRelatedUsersets(objectType, relation)
 
@@ -670,7 +1232,7 @@ func (c *LocalChecker) checkDirect(parentctx context.Context, req *ResolveCheckR
 			err = validation.ValidateTuple(typesys, tupleKey)
 
 			if t != nil && err == nil {
-				condEvalResult, err := eval.EvaluateTupleCondition(ctx, tupleKey, typesys, req.GetContext())
+				condEvalResult, err := c.evaluateTupleCondition(ctx, req, tupleKey, typesys)
 				if err != nil {
 					telemetry.TraceError(span, err)
 					return nil, err
@@ -691,6 +1253,9 @@ func (c *LocalChecker) checkDirect(parentctx context.Context, req *ResolveCheckR
 
 				span.SetAttributes(attribute.Bool("allowed", true))
 				response.Allowed = true
+				if name := tupleKey.GetCondition().GetName(); name != "" {
+					response.Obligations = []string{name}
+				}
 				return response, nil
 			}
 			return response, nil
@@ -701,7 +1266,7 @@ func (c *LocalChecker) checkDirect(parentctx context.Context, req *ResolveCheckR
 			defer span.End()
 
 			if ctx.Err() != nil {
-				return nil, ctx.Err()
+				return nil, contextErr(ctx)
 			}
 
 			response := &ResolveCheckResponse{
@@ -731,6 +1296,10 @@ func (c *LocalChecker) checkDirect(parentctx context.Context, req *ResolveCheckR
 			var errs error
 			var handlers []CheckHandlerFunc
 			for {
+				if ctx.Err() != nil {
+					return nil, contextErr(ctx)
+				}
+
 				t, err := filteredIter.Next(ctx)
 				if err != nil {
 					if errors.Is(err, storage.ErrIteratorDone) {
@@ -740,8 +1309,12 @@ func (c *LocalChecker) checkDirect(parentctx context.Context, req *ResolveCheckR
 					return nil, err
 				}
 
-				condEvalResult, err := eval.EvaluateTupleCondition(ctx, t, typesys, req.GetContext())
+				condEvalResult, err := c.evaluateTupleCondition(ctx, req, t, typesys)
 				if err != nil {
+					if errors.Is(err, ErrConditionEvaluationsLimitExceeded) {
+						return nil, err
+					}
+
 					errs = errors.Join(errs, err)
 					continue
 				}
@@ -771,6 +1344,9 @@ func (c *LocalChecker) checkDirect(parentctx context.Context, req *ResolveCheckR
 					if tuple.GetType(reqTupleKey.GetUser()) == wildcardType {
 						span.SetAttributes(attribute.Bool("allowed", true))
 						response.Allowed = true
+						if name := t.GetCondition().GetName(); name != "" {
+							response.Obligations = []string{name}
+						}
 						return response, nil
 					}
 
@@ -778,8 +1354,12 @@ func (c *LocalChecker) checkDirect(parentctx context.Context, req *ResolveCheckR
 				}
 
 				if usersetRelation != "" {
+					if req.indirectionLimitReached() {
+						continue
+					}
+
 					tupleKey := tuple.NewTupleKey(usersetObject, usersetRelation, reqTupleKey.GetUser())
-					handlers = append(handlers, c.dispatch(ctx, req, tupleKey))
+					handlers = append(handlers, withObligation(c.dispatchUserset(ctx, req, tupleKey), t.GetCondition().GetName()))
 				}
 			}
 
@@ -812,7 +1392,14 @@ func (c *LocalChecker) checkDirect(parentctx context.Context, req *ResolveCheckR
 			checkFuncs = append(checkFuncs, fn2)
 		}
 
-		resp, err := union(ctx, c.concurrencyLimit, checkFuncs...)
+		var resp *ResolveCheckResponse
+		var err error
+		if len(checkFuncs) == 1 {
+			// e.g. a relation of only directly related object types: no need to start a union for a single lookup
+			resp, err = checkFuncs[0](ctx)
+		} else {
+			resp, err = union(ctx, c.concurrencyLimit, checkFuncs...)
+		}
 		if err != nil {
 			telemetry.TraceError(span, err)
 			return nil, err
@@ -835,7 +1422,7 @@ func (c *LocalChecker) checkComputedUserset(_ context.Context, req *ResolveCheck
 		defer span.End()
 
 		if ctx.Err() != nil {
-			return nil, ctx.Err()
+			return nil, contextErr(ctx)
 		}
 
 		rewrittenTupleKey := tuple.NewTupleKey(
@@ -856,7 +1443,7 @@ func (c *LocalChecker) checkTTU(parentctx context.Context, req *ResolveCheckRequ
 		defer span.End()
 
 		if ctx.Err() != nil {
-			return nil, ctx.Err()
+			return nil, contextErr(ctx)
 		}
 
 		typesys, ok := typesystem.TypesystemFromContext(parentctx) // note: use of 'parentctx' not 'ctx' - this is important
@@ -882,6 +1469,7 @@ func (c *LocalChecker) checkTTU(parentctx context.Context, req *ResolveCheckRequ
 			attribute.String("tupleset_relation", fmt.Sprintf("%s#%s", tuple.GetType(object), tuplesetRelation)),
 			attribute.String("computed_relation", computedRelation),
 		)
+		traceSubproblem(span, req)
 
 		iter, err := ds.Read(
 			ctx,
@@ -903,6 +1491,10 @@ func (c *LocalChecker) checkTTU(parentctx context.Context, req *ResolveCheckRequ
 		var errs error
 		var handlers []CheckHandlerFunc
 		for {
+			if ctx.Err() != nil {
+				return nil, contextErr(ctx)
+			}
+
 			t, err := filteredIter.Next(ctx)
 			if err != nil {
 				if err == storage.ErrIteratorDone {
@@ -912,8 +1504,12 @@ func (c *LocalChecker) checkTTU(parentctx context.Context, req *ResolveCheckRequ
 				return nil, err
 			}
 
-			condEvalResult, err := eval.EvaluateTupleCondition(ctx, t, typesys, req.GetContext())
+			condEvalResult, err := c.evaluateTupleCondition(ctx, req, t, typesys)
 			if err != nil {
+				if errors.Is(err, ErrConditionEvaluationsLimitExceeded) {
+					return nil, err
+				}
+
 				errs = errors.Join(errs, err)
 
 				continue
@@ -944,12 +1540,23 @@ func (c *LocalChecker) checkTTU(parentctx context.Context, req *ResolveCheckRequ
 
 			if _, err := typesys.GetRelation(tuple.GetType(userObj), computedRelation); err != nil {
 				if errors.Is(err, typesystem.ErrRelationUndefined) {
+					if c.undefinedComputedRelationError {
+						err := &UndefinedComputedRelationError{
+							Relation:         tuple.ToObjectRelationString(tuple.GetType(object), tk.GetRelation()),
+							TuplesetRelation: tuple.ToObjectRelationString(tuple.GetType(object), tuplesetRelation),
+							RelatedType:      tuple.GetType(userObj),
+							ComputedRelation: computedRelation,
+						}
+						telemetry.TraceError(span, err)
+						return nil, err
+					}
+
 					continue // skip computed relations on tupleset relationships if they are undefined
 				}
 			}
 
 			// Note: we add TTU read below
-			handlers = append(handlers, c.dispatch(ctx, req, tupleKey))
+			handlers = append(handlers, withObligation(c.dispatch(ctx, req, tupleKey), t.GetCondition().GetName()))
 		}
 
 		if len(handlers) == 0 && errs != nil {
@@ -957,7 +1564,14 @@ func (c *LocalChecker) checkTTU(parentctx context.Context, req *ResolveCheckRequ
 			return nil, errs
 		}
 
-		unionResponse, err := union(ctx, c.concurrencyLimit, handlers...)
+		var resolved *atomic.Uint32
+		if span.IsRecording() {
+			handlers, resolved = countResolvedHandlers(handlers)
+		}
+
+		ObserveOperatorDispatchCount(TupleToUsersetOperator, len(handlers))
+
+		unionResponse, err := union(ctx, c.operatorConcurrencyLimit(c.ttuLimit), handlers...)
 		if err != nil {
 			telemetry.TraceError(span, err)
 			return nil, errors.Join(errs, err)
@@ -968,6 +1582,10 @@ func (c *LocalChecker) checkTTU(parentctx context.Context, req *ResolveCheckRequ
 		// if final result is "allowed = true", we want final reads to be N1 + 1
 		unionResponse.GetResolutionMetadata().DatastoreQueryCount++
 
+		if span.IsRecording() {
+			traceSubproblemOutcome(span, unionResponse, resolved.Load() < uint32(len(handlers)))
+		}
+
 		return unionResponse, nil
 	}
 }
@@ -982,18 +1600,28 @@ func (c *LocalChecker) checkSetOperation(
 	var handlers []CheckHandlerFunc
 
 	var reducerKey string
+	concurrencyLimit := c.concurrencyLimit
 	switch setOpType {
 	case unionSetOperator, intersectionSetOperator, exclusionSetOperator:
 		if setOpType == unionSetOperator {
-			reducerKey = "union"
+			reducerKey = UnionOperator
+			concurrencyLimit = c.operatorConcurrencyLimit(c.unionLimit)
+			reducer = batched(reducer, c.maxNodeFanout, true)
 		}
 
 		if setOpType == intersectionSetOperator {
-			reducerKey = "intersection"
+			reducerKey = IntersectionOperator
+			reducer = batched(reducer, c.maxNodeFanout, false)
+			if c.relationWeigher != nil {
+				children = orderOperands(c.relationWeigher, req.GetTupleKey(), children)
+			}
 		}
 
 		if setOpType == exclusionSetOperator {
-			reducerKey = "exclusion"
+			reducerKey = ExclusionOperator
+			if c.relationWeigher != nil && subtractIsCheaper(c.relationWeigher, req.GetTupleKey(), children[0], children[1]) {
+				reducer = exclusionSubtractFirst
+			}
 		}
 
 		for _, child := range children {
@@ -1014,11 +1642,66 @@ func (c *LocalChecker) checkSetOperation(
 			span.End()
 		}()
 
-		resp, err = reducer(ctx, c.concurrencyLimit, handlers...)
+		ObserveOperatorDispatchCount(reducerKey, len(handlers))
+
+		if !span.IsRecording() {
+			resp, err = reducer(ctx, concurrencyLimit, handlers...)
+			return resp, err
+		}
+
+		traceSubproblem(span, req)
+		counted, resolved := countResolvedHandlers(handlers)
+
+		resp, err = reducer(ctx, concurrencyLimit, counted...)
+		if err == nil {
+			traceSubproblemOutcome(span, resp, resolved.Load() < uint32(len(handlers)))
+		}
 		return resp, err
 	}
 }
 
+// traceSubproblem sets the attributes describing the subproblem of the request on its span.
+func traceSubproblem(span trace.Span, req *ResolveCheckRequest) {
+	if !span.IsRecording() {
+		return
+	}
+
+	tk := req.GetTupleKey()
+	span.SetAttributes(
+		attribute.String("object_type", tuple.GetType(tk.GetObject())),
+		attribute.String("relation", tk.GetRelation()),
+		attribute.String("user_type", string(tuple.GetUserTypeFromUser(tk.GetUser()))),
+	)
+}
+
+// traceSubproblemOutcome sets the attributes describing the outcome of a subproblem on its span. The subproblem
+// was short-circuited if its outcome was decided before all of its children were resolved.
+func traceSubproblemOutcome(span trace.Span, resp *ResolveCheckResponse, shortCircuited bool) {
+	span.SetAttributes(
+		attribute.Bool("allowed", resp.GetAllowed()),
+		attribute.Int64("datastore_query_count", int64(resp.GetResolutionMetadata().DatastoreQueryCount)),
+		attribute.Bool("short_circuited", shortCircuited),
+	)
+}
+
+// countResolvedHandlers wraps the handlers so that the returned counter holds the number of them that ran to
+// completion, i.e. that were not cancelled by the reducer once the outcome was decided.
+func countResolvedHandlers(handlers []CheckHandlerFunc) ([]CheckHandlerFunc, *atomic.Uint32) {
+	resolved := new(atomic.Uint32)
+	counted := make([]CheckHandlerFunc, 0, len(handlers))
+	for _, handler := range handlers {
+		counted = append(counted, func(ctx context.Context) (*ResolveCheckResponse, error) {
+			resp, err := handler(ctx)
+			if !errors.Is(err, context.Canceled) {
+				resolved.Add(1)
+			}
+			return resp, err
+		})
+	}
+
+	return counted, resolved
+}
+
 func (c *LocalChecker) checkRewrite(
 	ctx context.Context,
 	req *ResolveCheckRequest,
@@ -0,0 +1,74 @@
+package graph
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+type checkResultCacheCtxKey struct{}
+
+// CheckResultCache shares the results of the Check subproblems resolved by a group of related
+// requests, e.g. the items of a batch, for as long as the group is being resolved. Unlike
+// CachedCheckResolver, its entries never expire, so it must not outlive the group.
+// It is safe for concurrent use.
+type CheckResultCache struct {
+	mu      sync.RWMutex
+	results map[string]*ResolveCheckResponse // GUARDED_BY(mu)
+	hits    atomic.Uint64
+}
+
+// NewCheckResultCache returns an empty CheckResultCache.
+func NewCheckResultCache() *CheckResultCache {
+	return &CheckResultCache{
+		results: map[string]*ResolveCheckResponse{},
+	}
+}
+
+// ContextWithCheckResultCache returns a context that makes the LocalChecker reuse, and record, the
+// results of the subproblems it resolves in the given cache.
+func ContextWithCheckResultCache(ctx context.Context, cache *CheckResultCache) context.Context {
+	return context.WithValue(ctx, checkResultCacheCtxKey{}, cache)
+}
+
+// CheckResultCacheFromContext returns the CheckResultCache stored in the context, if any.
+func CheckResultCacheFromContext(ctx context.Context) (*CheckResultCache, bool) {
+	cache, ok := ctx.Value(checkResultCacheCtxKey{}).(*CheckResultCache)
+	return cache, ok
+}
+
+// Hits returns the number of subproblems that were answered by the cache.
+func (c *CheckResultCache) Hits() uint64 {
+	return c.hits.Load()
+}
+
+func (c *CheckResultCache) get(key string) (*ResolveCheckResponse, bool) {
+	c.mu.RLock()
+	resp, ok := c.results[key]
+	c.mu.RUnlock()
+
+	if !ok {
+		return nil, false
+	}
+
+	c.hits.Add(1)
+
+	// return a copy to avoid races across goroutines
+	return CloneResolveCheckResponse(resp), true
+}
+
+func (c *CheckResultCache) set(key string, resp *ResolveCheckResponse) {
+	// the outcome of a subproblem whose resolution ran into a cycle depends on the path it was reached from
+	if resp.GetResolutionMetadata().CycleDetected {
+		return
+	}
+
+	// as for CachedCheckResolver, the datastore reads of the request that resolved the subproblem
+	// must not be attributed to the requests reusing its result
+	cloned := CloneResolveCheckResponse(resp)
+	cloned.ResolutionMetadata.DatastoreQueryCount = 0
+
+	c.mu.Lock()
+	c.results[key] = cloned
+	c.mu.Unlock()
+}
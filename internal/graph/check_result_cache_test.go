@@ -0,0 +1,52 @@
+package graph
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckResultCache(t *testing.T) {
+	t.Run("from_context", func(t *testing.T) {
+		_, ok := CheckResultCacheFromContext(context.Background())
+		require.False(t, ok)
+
+		cache := NewCheckResultCache()
+		actual, ok := CheckResultCacheFromContext(ContextWithCheckResultCache(context.Background(), cache))
+		require.True(t, ok)
+		require.Same(t, cache, actual)
+	})
+
+	t.Run("returns_copies_without_datastore_query_count", func(t *testing.T) {
+		cache := NewCheckResultCache()
+
+		resp := &ResolveCheckResponse{
+			Allowed:            true,
+			ResolutionMetadata: &ResolveCheckResponseMetadata{DatastoreQueryCount: 3},
+		}
+		cache.set("key", resp)
+
+		cached, ok := cache.get("key")
+		require.True(t, ok)
+		require.True(t, cached.GetAllowed())
+		require.Zero(t, cached.GetResolutionMetadata().DatastoreQueryCount)
+		require.NotSame(t, resp, cached)
+		require.Equal(t, uint64(1), cache.Hits())
+
+		_, ok = cache.get("other")
+		require.False(t, ok)
+		require.Equal(t, uint64(1), cache.Hits())
+	})
+
+	t.Run("ignores_results_with_cycles", func(t *testing.T) {
+		cache := NewCheckResultCache()
+
+		cache.set("key", &ResolveCheckResponse{
+			ResolutionMetadata: &ResolveCheckResponseMetadata{CycleDetected: true},
+		})
+
+		_, ok := cache.get("key")
+		require.False(t, ok)
+	})
+}
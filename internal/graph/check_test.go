@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -12,9 +13,16 @@ import (
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	parser "github.com/openfga/language/pkg/go/transformer"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"go.uber.org/goleak"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/structpb"
 
+	"github.com/openfga/openfga/internal/mocks"
 	"github.com/openfga/openfga/pkg/storage"
 	"github.com/openfga/openfga/pkg/storage/memory"
 	"github.com/openfga/openfga/pkg/storage/storagewrappers"
@@ -1033,6 +1041,157 @@ func TestCheckWithOneConcurrentGoroutineCausesNoDeadlock(t *testing.T) {
 	require.True(t, resp.Allowed)
 }
 
+func TestCheckWithTupleOverlay(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	storeID := ulid.Make().String()
+
+	err := ds.Write(context.Background(), storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "group:eng#member"),
+		tuple.NewTupleKey("group:eng", "member", "user:jon"),
+	})
+	require.NoError(t, err)
+
+	checker := NewLocalChecker()
+	t.Cleanup(checker.Close)
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type group
+			relations
+				define member: [user]
+		type document
+			relations
+				define viewer: [group#member]`)
+
+	ctx := typesystem.ContextWithTypesystem(
+		context.Background(),
+		typesystem.New(model),
+	)
+
+	check := func(ctx context.Context, user string) bool {
+		resp, err := checker.ResolveCheck(ctx, &ResolveCheckRequest{
+			StoreID:         storeID,
+			TupleKey:        tuple.NewTupleKey("document:1", "viewer", user),
+			RequestMetadata: NewCheckRequestMetadata(25),
+		})
+		require.NoError(t, err)
+		return resp.GetAllowed()
+	}
+
+	t.Run("without_overlay", func(t *testing.T) {
+		ctx := storage.ContextWithRelationshipTupleReader(ctx, ds)
+		require.True(t, check(ctx, "user:jon"))
+		require.False(t, check(ctx, "user:maria"))
+	})
+
+	t.Run("removed_tuple_denies", func(t *testing.T) {
+		ctx := storage.ContextWithRelationshipTupleReader(ctx, storagewrappers.NewOverlayTupleReader(ds, storagewrappers.TupleOverlay{
+			RemovedTuples: []*openfgav1.TupleKeyWithoutCondition{
+				tuple.TupleKeyToTupleKeyWithoutCondition(tuple.NewTupleKey("group:eng", "member", "user:jon")),
+			},
+		}))
+		require.False(t, check(ctx, "user:jon"))
+	})
+
+	t.Run("added_tuple_allows", func(t *testing.T) {
+		ctx := storage.ContextWithRelationshipTupleReader(ctx, storagewrappers.NewOverlayTupleReader(ds, storagewrappers.TupleOverlay{
+			AddedTuples: []*openfgav1.TupleKey{
+				tuple.NewTupleKey("group:eng", "member", "user:maria"),
+			},
+		}))
+		require.True(t, check(ctx, "user:maria"))
+	})
+}
+
+func TestCheckInvalidTupleKey(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	checker := NewLocalChecker()
+	t.Cleanup(checker.Close)
+
+	ctx := typesystem.ContextWithTypesystem(context.Background(), typesystem.New(&openfgav1.AuthorizationModel{
+		SchemaVersion: typesystem.SchemaVersion1_1,
+	}))
+
+	tests := []struct {
+		name          string
+		tupleKey      *openfgav1.TupleKey
+		expectedField string
+	}{
+		{
+			name:          "nil_tuple_key",
+			tupleKey:      nil,
+			expectedField: "object",
+		},
+		{
+			name:          "missing_object",
+			tupleKey:      tuple.NewTupleKey("", "viewer", "user:jon"),
+			expectedField: "object",
+		},
+		{
+			name:          "object_without_type",
+			tupleKey:      tuple.NewTupleKey("1", "viewer", "user:jon"),
+			expectedField: "object",
+		},
+		{
+			name:          "object_without_id",
+			tupleKey:      tuple.NewTupleKey("document:", "viewer", "user:jon"),
+			expectedField: "object",
+		},
+		{
+			name:          "missing_relation",
+			tupleKey:      tuple.NewTupleKey("document:1", "", "user:jon"),
+			expectedField: "relation",
+		},
+		{
+			name:          "malformed_relation",
+			tupleKey:      tuple.NewTupleKey("document:1", "viewer#owner", "user:jon"),
+			expectedField: "relation",
+		},
+		{
+			name:          "missing_user",
+			tupleKey:      tuple.NewTupleKey("document:1", "viewer", ""),
+			expectedField: "user",
+		},
+		{
+			name:          "user_without_type",
+			tupleKey:      tuple.NewTupleKey("document:1", "viewer", "jon"),
+			expectedField: "user",
+		},
+		{
+			name:          "userset_without_relation",
+			tupleKey:      tuple.NewTupleKey("document:1", "viewer", "group:eng#"),
+			expectedField: "user",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := checker.ResolveCheck(ctx, &ResolveCheckRequest{
+				StoreID:         ulid.Make().String(),
+				TupleKey:        test.tupleKey,
+				RequestMetadata: NewCheckRequestMetadata(25),
+			})
+			require.ErrorIs(t, err, ErrInvalidTupleKey)
+
+			var invalidTupleKeyErr *InvalidTupleKeyError
+			require.ErrorAs(t, err, &invalidTupleKeyErr)
+			require.Equal(t, test.expectedField, invalidTupleKeyErr.Field)
+		})
+	}
+}
+
 func TestCheckDatastoreQueryCount(t *testing.T) {
 	t.Cleanup(func() {
 		goleak.VerifyNone(t)
@@ -1947,6 +2106,113 @@ func TestUnionCheckFuncReducer(t *testing.T) {
 	})
 }
 
+func TestMaxNodeFanout(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	t.Run("batched_reducer_bounds_in_flight_handlers", func(t *testing.T) {
+		var mu sync.Mutex
+		var inFlight, maxInFlight, evaluated int
+
+		handler := func(allowed bool) CheckHandlerFunc {
+			return func(context.Context) (*ResolveCheckResponse, error) {
+				mu.Lock()
+				inFlight++
+				evaluated++
+				maxInFlight = max(maxInFlight, inFlight)
+				mu.Unlock()
+
+				time.Sleep(time.Millisecond)
+
+				mu.Lock()
+				inFlight--
+				mu.Unlock()
+
+				return &ResolveCheckResponse{
+					Allowed:            allowed,
+					ResolutionMetadata: &ResolveCheckResponseMetadata{DatastoreQueryCount: 1},
+				}, nil
+			}
+		}
+
+		handlers := make([]CheckHandlerFunc, 0, 20)
+		for i := 0; i < 20; i++ {
+			handlers = append(handlers, handler(i == 9))
+		}
+
+		resp, err := batched(union, 4, true)(context.Background(), 100, handlers...)
+		require.NoError(t, err)
+		require.True(t, resp.GetAllowed())
+		require.LessOrEqual(t, maxInFlight, 4)
+		// the third batch (handlers 8 through 11) finds the allowed outcome, so the remaining batches are skipped
+		require.LessOrEqual(t, evaluated, 12)
+
+		resp, err = batched(intersection, 4, false)(context.Background(), 100, trueHandler, trueHandler, trueHandler, trueHandler, trueHandler, falseHandler)
+		require.NoError(t, err)
+		require.False(t, resp.GetAllowed())
+
+		resp, err = batched(intersection, 4, false)(context.Background(), 100, trueHandler, trueHandler, trueHandler, trueHandler, trueHandler)
+		require.NoError(t, err)
+		require.True(t, resp.GetAllowed())
+		require.Equal(t, uint32(5), resp.GetResolutionMetadata().DatastoreQueryCount)
+
+		_, err = batched(union, 4, true)(context.Background(), 100, falseHandler, falseHandler, falseHandler, falseHandler, generalErrorHandler)
+		require.ErrorContains(t, err, simulatedDBErrorMessage)
+	})
+
+	t.Run("wide_union_resolves_correctly", func(t *testing.T) {
+		ds := memory.New()
+		t.Cleanup(ds.Close)
+
+		storeID := ulid.Make().String()
+
+		var relations, unionOperands []string
+		for i := 0; i < 30; i++ {
+			relations = append(relations, fmt.Sprintf("\t\t\tdefine r%d: [user]", i))
+			unionOperands = append(unionOperands, fmt.Sprintf("r%d", i))
+		}
+
+		model := testutils.MustTransformDSLToProtoWithID(fmt.Sprintf(`
+		model
+			schema 1.1
+
+		type user
+		type document
+			relations
+%s
+			define viewer: %s`, strings.Join(relations, "\n"), strings.Join(unionOperands, " or ")))
+
+		err := ds.Write(context.Background(), storeID, nil, []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:1", "r27", "user:jon"),
+		})
+		require.NoError(t, err)
+
+		checker := NewLocalChecker(WithMaxNodeFanout(4))
+		t.Cleanup(checker.Close)
+
+		ctx := typesystem.ContextWithTypesystem(context.Background(), typesystem.New(model))
+		ctx = storage.ContextWithRelationshipTupleReader(ctx, ds)
+
+		resp, err := checker.ResolveCheck(ctx, &ResolveCheckRequest{
+			StoreID:         storeID,
+			TupleKey:        tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+			RequestMetadata: NewCheckRequestMetadata(25),
+		})
+		require.NoError(t, err)
+		require.True(t, resp.GetAllowed())
+
+		resp, err = checker.ResolveCheck(ctx, &ResolveCheckRequest{
+			StoreID:         storeID,
+			TupleKey:        tuple.NewTupleKey("document:1", "viewer", "user:maria"),
+			RequestMetadata: NewCheckRequestMetadata(25),
+		})
+		require.NoError(t, err)
+		require.False(t, resp.GetAllowed())
+		require.Equal(t, uint32(30), resp.GetResolutionMetadata().DatastoreQueryCount)
+	})
+}
+
 func TestCloneResolveCheckResponse(t *testing.T) {
 	resp1 := &ResolveCheckResponse{
 		Allowed: true,
@@ -1980,3 +2246,1168 @@ func TestCloneResolveCheckResponse(t *testing.T) {
 	require.Equal(t, uint32(0), clonedResp2.GetResolutionMetadata().DatastoreQueryCount)
 	require.False(t, clonedResp2.GetResolutionMetadata().CycleDetected)
 }
+
+func TestMaxConditionEvaluations(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	storeID := ulid.Make().String()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type group
+			relations
+				define member: [user]
+
+		type document
+			relations
+				define viewer: [user with x_less_than, group#member with x_less_than]
+
+		condition x_less_than(x: int) {
+			x < 100
+		}`)
+
+	tuples := []*openfgav1.TupleKey{
+		tuple.NewTupleKey("group:0", "member", "user:jon"),
+	}
+	for i := 0; i < 10; i++ {
+		tuples = append(tuples, tuple.NewTupleKeyWithCondition("document:1", "viewer", fmt.Sprintf("group:%d#member", i), "x_less_than", nil))
+	}
+
+	err := ds.Write(context.Background(), storeID, nil, tuples)
+	require.NoError(t, err)
+
+	ctx := typesystem.ContextWithTypesystem(context.Background(), typesystem.New(model))
+	ctx = storage.ContextWithRelationshipTupleReader(ctx, ds)
+
+	check := func(limit uint32, user string, x int) (*ResolveCheckResponse, error) {
+		checker := NewLocalChecker(WithMaxConditionEvaluations(limit))
+		t.Cleanup(checker.Close)
+
+		return checker.ResolveCheck(ctx, &ResolveCheckRequest{
+			StoreID:         storeID,
+			TupleKey:        tuple.NewTupleKey("document:1", "viewer", user),
+			Context:         testutils.MustNewStruct(t, map[string]interface{}{"x": x}),
+			RequestMetadata: NewCheckRequestMetadata(25),
+		})
+	}
+
+	t.Run("below_the_limit", func(t *testing.T) {
+		resp, err := check(10, "user:jon", 1)
+		require.NoError(t, err)
+		require.True(t, resp.GetAllowed())
+
+		// every condition is evaluated and none is met
+		resp, err = check(10, "user:jon", 200)
+		require.NoError(t, err)
+		require.False(t, resp.GetAllowed())
+	})
+
+	t.Run("crossing_the_limit", func(t *testing.T) {
+		_, err := check(9, "user:jon", 200)
+		require.ErrorIs(t, err, ErrConditionEvaluationsLimitExceeded)
+	})
+
+	t.Run("no_limit", func(t *testing.T) {
+		resp, err := check(0, "user:jon", 200)
+		require.NoError(t, err)
+		require.False(t, resp.GetAllowed())
+	})
+}
+
+func TestMaxIndirectionDepth(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	storeID := ulid.Make().String()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type group
+			relations
+				define member: [user, group#member]
+
+		type document
+			relations
+				define viewer: [user, group#member]`)
+
+	err := ds.Write(context.Background(), storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "user:direct"),
+		tuple.NewTupleKey("document:1", "viewer", "group:eng#member"),
+		tuple.NewTupleKey("group:eng", "member", "user:one-level"),
+		tuple.NewTupleKey("group:eng", "member", "group:backend#member"),
+		tuple.NewTupleKey("group:backend", "member", "user:two-levels"),
+	})
+	require.NoError(t, err)
+
+	ctx := typesystem.ContextWithTypesystem(context.Background(), typesystem.New(model))
+	ctx = storage.ContextWithRelationshipTupleReader(ctx, ds)
+
+	checker := NewLocalChecker()
+	t.Cleanup(checker.Close)
+
+	check := func(t *testing.T, maxDepth uint32, user string) bool {
+		resp, err := checker.ResolveCheck(ctx, &ResolveCheckRequest{
+			StoreID:             storeID,
+			TupleKey:            tuple.NewTupleKey("document:1", "viewer", user),
+			RequestMetadata:     NewCheckRequestMetadata(25),
+			MaxIndirectionDepth: maxDepth,
+		})
+		require.NoError(t, err)
+
+		return resp.GetAllowed()
+	}
+
+	tests := []struct {
+		name     string
+		maxDepth uint32
+		allowed  map[string]bool
+	}{
+		{
+			name:     "no_limit",
+			maxDepth: 0,
+			allowed:  map[string]bool{"user:direct": true, "user:one-level": true, "user:two-levels": true},
+		},
+		{
+			name:     "depth_1",
+			maxDepth: 1,
+			allowed:  map[string]bool{"user:direct": true, "user:one-level": true, "user:two-levels": false},
+		},
+		{
+			name:     "depth_2",
+			maxDepth: 2,
+			allowed:  map[string]bool{"user:direct": true, "user:one-level": true, "user:two-levels": true},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for user, allowed := range test.allowed {
+				require.Equal(t, allowed, check(t, test.maxDepth, user), user)
+			}
+		})
+	}
+
+	t.Run("cache_key_depends_on_remaining_indirections", func(t *testing.T) {
+		req := &ResolveCheckRequest{
+			StoreID:  storeID,
+			TupleKey: tuple.NewTupleKey("group:eng", "member", "user:two-levels"),
+		}
+
+		unlimited, err := CheckRequestCacheKey(req)
+		require.NoError(t, err)
+
+		req.MaxIndirectionDepth = 1
+		req.IndirectionDepth = 1
+		exhausted, err := CheckRequestCacheKey(req)
+		require.NoError(t, err)
+		require.NotEqual(t, unlimited, exhausted)
+
+		req.MaxIndirectionDepth = 2
+		req.IndirectionDepth = 2
+		sameRemaining, err := CheckRequestCacheKey(req)
+		require.NoError(t, err)
+		require.Equal(t, exhausted, sameRemaining)
+	})
+}
+
+func TestConditionEvaluationCache(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	storeID := ulid.Make().String()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type group
+			relations
+				define member: [user]
+
+		type document
+			relations
+				define viewer: [group#member with x_less_than]
+
+		condition x_less_than(x: int) {
+			x < 100
+		}`)
+
+	// every tuple is gated by the same condition, without a tuple context
+	var tuples []*openfgav1.TupleKey
+	for i := 0; i < 20; i++ {
+		tuples = append(tuples, tuple.NewTupleKeyWithCondition("document:1", "viewer", fmt.Sprintf("group:%d#member", i), "x_less_than", nil))
+	}
+	tuples = append(tuples,
+		tuple.NewTupleKeyWithCondition("document:2", "viewer", "group:0#member", "x_less_than", testutils.MustNewStruct(t, map[string]interface{}{"x": 1})),
+		tuple.NewTupleKeyWithCondition("document:2", "viewer", "group:1#member", "x_less_than", testutils.MustNewStruct(t, map[string]interface{}{"x": 2})),
+	)
+
+	err := ds.Write(context.Background(), storeID, nil, tuples)
+	require.NoError(t, err)
+
+	ctx := typesystem.ContextWithTypesystem(context.Background(), typesystem.New(model))
+	ctx = storage.ContextWithRelationshipTupleReader(ctx, ds)
+
+	check := func(t *testing.T, enabled bool, object string, requestContext map[string]interface{}) (*ResolveCheckResponse, uint32) {
+		checker := NewLocalChecker(WithConditionEvaluationCache(enabled))
+		t.Cleanup(checker.Close)
+
+		metadata := NewCheckRequestMetadata(25)
+		resp, err := checker.ResolveCheck(ctx, &ResolveCheckRequest{
+			StoreID:         storeID,
+			TupleKey:        tuple.NewTupleKey(object, "viewer", "user:jon"),
+			Context:         testutils.MustNewStruct(t, requestContext),
+			RequestMetadata: metadata,
+		})
+		require.NoError(t, err)
+
+		return resp, metadata.ConditionEvaluationCounter.Load()
+	}
+
+	t.Run("evaluated_once_with_the_cache", func(t *testing.T) {
+		resp, evaluations := check(t, true, "document:1", map[string]interface{}{"x": 200})
+		require.False(t, resp.GetAllowed())
+		require.Equal(t, uint32(1), evaluations)
+	})
+
+	t.Run("evaluated_for_every_tuple_without_the_cache", func(t *testing.T) {
+		resp, evaluations := check(t, false, "document:1", map[string]interface{}{"x": 200})
+		require.False(t, resp.GetAllowed())
+		require.Equal(t, uint32(20), evaluations)
+	})
+
+	t.Run("different_tuple_contexts_are_evaluated_separately", func(t *testing.T) {
+		resp, evaluations := check(t, true, "document:2", map[string]interface{}{})
+		require.False(t, resp.GetAllowed())
+		require.Equal(t, uint32(2), evaluations)
+	})
+}
+
+func TestCheckUnassignableRelation(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	storeID := ulid.Make().String()
+
+	// check resolves the Check with a datastore in which jon owns document:1, and returns the tuples it read
+	check := func(t *testing.T, model *openfgav1.AuthorizationModel, opts ...LocalCheckerOption) (*ResolveCheckResponse, []string, error) {
+		mockController := gomock.NewController(t)
+		defer mockController.Finish()
+
+		var mu sync.Mutex
+		var reads []string
+
+		mockDatastore := mocks.NewMockRelationshipTupleReader(mockController)
+		mockDatastore.EXPECT().
+			ReadUserTuple(gomock.Any(), storeID, gomock.Any()).
+			AnyTimes().
+			DoAndReturn(func(_ context.Context, _ string, tk *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				reads = append(reads, tuple.TupleKeyToString(tk))
+
+				if tk.GetRelation() == "owner" {
+					return &openfgav1.Tuple{Key: tuple.NewTupleKey("document:1", "owner", "user:jon")}, nil
+				}
+
+				return nil, storage.ErrNotFound
+			})
+
+		ctx := typesystem.ContextWithTypesystem(context.Background(), typesystem.New(model))
+		ctx = storage.ContextWithRelationshipTupleReader(ctx, mockDatastore)
+
+		checker := NewLocalChecker(opts...)
+		t.Cleanup(checker.Close)
+
+		resp, err := checker.ResolveCheck(ctx, &ResolveCheckRequest{
+			StoreID:         storeID,
+			TupleKey:        tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+			RequestMetadata: NewCheckRequestMetadata(25),
+		})
+
+		mu.Lock()
+		defer mu.Unlock()
+		return resp, reads, err
+	}
+
+	t.Run("computed_relation_is_not_read_directly", func(t *testing.T) {
+		model := testutils.MustTransformDSLToProtoWithID(`
+			model
+				schema 1.1
+
+			type user
+
+			type document
+				relations
+					define owner: [user]
+					define viewer: owner`)
+
+		resp, reads, err := check(t, model)
+		require.NoError(t, err)
+		require.True(t, resp.GetAllowed())
+		require.Equal(t, []string{"document:1#owner@user:jon"}, reads)
+	})
+
+	// a model written without type restrictions on a relation defined with 'this'
+	model := &openfgav1.AuthorizationModel{
+		Id:            ulid.Make().String(),
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: []*openfgav1.TypeDefinition{
+			{Type: "user"},
+			{
+				Type: "document",
+				Relations: map[string]*openfgav1.Userset{
+					"owner":  typesystem.This(),
+					"viewer": typesystem.Union(typesystem.This(), typesystem.ComputedUserset("owner")),
+				},
+				Metadata: &openfgav1.Metadata{
+					Relations: map[string]*openfgav1.RelationMetadata{
+						"owner": {
+							DirectlyRelatedUserTypes: []*openfgav1.RelationReference{
+								typesystem.DirectRelationReference("user", ""),
+							},
+						},
+						"viewer": {},
+					},
+				},
+			},
+		},
+	}
+
+	t.Run("direct_relationships_resolved_without_reading", func(t *testing.T) {
+		resp, reads, err := check(t, model)
+		require.NoError(t, err)
+		require.True(t, resp.GetAllowed())
+		require.Equal(t, []string{"document:1#owner@user:jon"}, reads)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		intersectionModel := proto.Clone(model).(*openfgav1.AuthorizationModel)
+		intersectionModel.GetTypeDefinitions()[1].GetRelations()["viewer"] = typesystem.Intersection(typesystem.This(), typesystem.ComputedUserset("owner"))
+
+		_, _, err := check(t, intersectionModel, WithUnassignableRelationError(true))
+		require.ErrorIs(t, err, ErrUnassignableRelation)
+		require.ErrorContains(t, err, "'document#viewer'")
+	})
+}
+
+func TestBatchResolveCheck(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	storeID := ulid.Make().String()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	var mu sync.Mutex
+	reads := map[string]int{}
+
+	mockDatastore := mocks.NewMockRelationshipTupleReader(mockController)
+	mockDatastore.EXPECT().
+		ReadUserTuple(gomock.Any(), storeID, gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, _ string, tk *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			reads[tuple.TupleKeyToString(tk)]++
+
+			if tk.GetUser() == "user:jon" {
+				return &openfgav1.Tuple{Key: tuple.NewTupleKey(tk.GetObject(), tk.GetRelation(), tk.GetUser())}, nil
+			}
+
+			return nil, storage.ErrNotFound
+		})
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type document
+			relations
+				define viewer: [user]`)
+
+	ctx := typesystem.ContextWithTypesystem(context.Background(), typesystem.New(model))
+	ctx = storage.ContextWithRelationshipTupleReader(ctx, mockDatastore)
+
+	checker := NewLocalCheckerWithCycleDetection()
+	t.Cleanup(checker.Close)
+
+	newRequest := func(object, user string) *ResolveCheckRequest {
+		return &ResolveCheckRequest{
+			StoreID:         storeID,
+			TupleKey:        tuple.NewTupleKey(object, "viewer", user),
+			RequestMetadata: NewCheckRequestMetadata(25),
+		}
+	}
+
+	reqs := []*ResolveCheckRequest{
+		newRequest("document:1", "user:jon"),
+		newRequest("document:1", "user:maria"),
+		newRequest("document:1", "user:jon"),
+		newRequest("document:1", "jon"),
+		newRequest("document:2", "user:jon"),
+	}
+
+	resps, err := checker.BatchResolveCheck(ctx, reqs)
+	require.Len(t, resps, len(reqs))
+
+	// the malformed request fails on its own
+	var batchErr *BatchResolveCheckError
+	require.ErrorAs(t, err, &batchErr)
+	require.ErrorIs(t, err, ErrInvalidTupleKey)
+	require.ErrorContains(t, err, "1 of 5 checks failed")
+	require.Nil(t, resps[3])
+	for i, reqErr := range batchErr.Errors {
+		if i == 3 {
+			require.ErrorIs(t, reqErr, ErrInvalidTupleKey)
+			continue
+		}
+		require.NoError(t, reqErr)
+	}
+
+	require.True(t, resps[0].GetAllowed())
+	require.False(t, resps[1].GetAllowed())
+	require.True(t, resps[2].GetAllowed())
+	require.True(t, resps[4].GetAllowed())
+
+	// the identical requests are resolved once, and do not share their response
+	require.NotSame(t, resps[0], resps[2])
+	require.Equal(t, map[string]int{
+		"document:1#viewer@user:jon":   1,
+		"document:1#viewer@user:maria": 1,
+		"document:2#viewer@user:jon":   1,
+	}, reads)
+
+	// every request keeps its own cycle state
+	for i, req := range reqs {
+		require.Nil(t, req.VisitedPaths, "request %d", i)
+	}
+
+	t.Run("no_failures", func(t *testing.T) {
+		resps, err := checker.BatchResolveCheck(ctx, []*ResolveCheckRequest{
+			newRequest("document:3", "user:jon"),
+			newRequest("document:3", "user:bob"),
+		})
+		require.NoError(t, err)
+		require.True(t, resps[0].GetAllowed())
+		require.False(t, resps[1].GetAllowed())
+	})
+}
+
+func TestCheckResolveNodeLimit(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	// group:0 is nested 10 levels above the group jon is a member of
+	var writes []*openfgav1.TupleKey
+	for i := 0; i < 10; i++ {
+		writes = append(writes, tuple.NewTupleKey(fmt.Sprintf("group:%d", i), "member", fmt.Sprintf("group:%d#member", i+1)))
+	}
+	writes = append(writes, tuple.NewTupleKey("group:10", "member", "user:jon"))
+	require.NoError(t, ds.Write(ctx, storeID, nil, writes))
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type group
+			relations
+				define member: [user, group#member]`)
+
+	ctx = typesystem.ContextWithTypesystem(ctx, typesystem.New(model))
+	ctx = storage.ContextWithRelationshipTupleReader(ctx, ds)
+
+	checker := NewLocalChecker()
+	t.Cleanup(checker.Close)
+
+	check := func(limit uint32) (*ResolveCheckResponse, error) {
+		return checker.ResolveCheck(ctx, &ResolveCheckRequest{
+			StoreID:         storeID,
+			TupleKey:        tuple.NewTupleKey("group:0", "member", "user:jon"),
+			RequestMetadata: NewCheckRequestMetadata(limit),
+		})
+	}
+
+	resp, err := check(defaultResolveNodeLimit)
+	require.NoError(t, err)
+	require.True(t, resp.GetAllowed())
+
+	_, err = check(5)
+	require.ErrorIs(t, err, ErrResolutionDepthExceeded)
+
+	var depthErr *ResolutionDepthExceededError
+	require.ErrorAs(t, err, &depthErr)
+	require.Equal(t, uint32(5), depthErr.Limit)
+	require.EqualError(t, err, "resolution depth exceeded: limit of 5")
+}
+
+func TestResolveCheckError(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	// group:0 is nested 10 levels above the group jon is a member of
+	var writes []*openfgav1.TupleKey
+	for i := 0; i < 10; i++ {
+		writes = append(writes, tuple.NewTupleKey(fmt.Sprintf("group:%d", i), "member", fmt.Sprintf("group:%d#member", i+1)))
+	}
+	writes = append(writes, tuple.NewTupleKey("group:10", "member", "user:jon"))
+	require.NoError(t, ds.Write(ctx, storeID, nil, writes))
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type group
+			relations
+				define member: [user, group#member]`)
+
+	ctx = typesystem.ContextWithTypesystem(ctx, typesystem.New(model))
+	ctx = storage.ContextWithRelationshipTupleReader(ctx, ds)
+
+	checker := NewLocalChecker()
+	t.Cleanup(checker.Close)
+
+	check := func(ctx context.Context, limit uint32) error {
+		_, err := checker.ResolveCheck(ctx, &ResolveCheckRequest{
+			StoreID:         storeID,
+			TupleKey:        tuple.NewTupleKey("group:0", "member", "user:jon"),
+			RequestMetadata: NewCheckRequestMetadata(limit),
+		})
+		return err
+	}
+
+	t.Run("resolution_depth_exceeded", func(t *testing.T) {
+		err := check(ctx, 5)
+		require.ErrorIs(t, err, ErrResolutionDepthExceeded)
+
+		// the error records the subproblem that exceeded the limit, not the Check
+		var resolveErr *ResolveCheckError
+		require.ErrorAs(t, err, &resolveErr)
+		require.Equal(t, ResolveCheckErrorResolutionDepthExceeded, resolveErr.Code)
+		require.Equal(t, "group:5#member@user:jon", resolveErr.TupleKey)
+		require.Equal(t, uint32(5), resolveErr.Depth)
+		require.Equal(t, uint32(5), resolveErr.DispatchCount)
+		require.False(t, resolveErr.Throttled)
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		var resolveErr *ResolveCheckError
+		require.ErrorAs(t, check(ctx, defaultResolveNodeLimit), &resolveErr)
+		require.Equal(t, ResolveCheckErrorCancelled, resolveErr.Code)
+		require.ErrorIs(t, resolveErr, context.Canceled)
+	})
+
+	t.Run("deadline_exceeded", func(t *testing.T) {
+		ctx, cancel := context.WithDeadline(ctx, time.Now().Add(-time.Second))
+		defer cancel()
+
+		var resolveErr *ResolveCheckError
+		require.ErrorAs(t, check(ctx, defaultResolveNodeLimit), &resolveErr)
+		require.Equal(t, ResolveCheckErrorDeadlineExceeded, resolveErr.Code)
+		require.ErrorIs(t, resolveErr, ErrRequestDeadlineExceeded)
+	})
+
+	t.Run("throttled_timeout", func(t *testing.T) {
+		ctx, cancel := context.WithDeadline(ctx, time.Now().Add(-time.Second))
+		defer cancel()
+
+		metadata := NewCheckRequestMetadata(defaultResolveNodeLimit)
+		metadata.WasThrottled.Store(true)
+
+		err := NewResolveCheckError(&ResolveCheckRequest{
+			TupleKey:        tuple.NewTupleKey("group:0", "member", "user:jon"),
+			RequestMetadata: metadata,
+		}, contextErr(ctx))
+
+		var resolveErr *ResolveCheckError
+		require.ErrorAs(t, err, &resolveErr)
+		require.Equal(t, ResolveCheckErrorThrottledTimeout, resolveErr.Code)
+		require.True(t, resolveErr.Throttled)
+	})
+
+	t.Run("unclassified_errors_are_not_wrapped", func(t *testing.T) {
+		err := errors.New("boom")
+		require.Same(t, err, NewResolveCheckError(&ResolveCheckRequest{}, err))
+	})
+}
+
+func TestCheckUndefinedComputedRelation(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	require.NoError(t, ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "parent", "team:eng"),
+		tuple.NewTupleKey("document:2", "parent", "folder:x"),
+		tuple.NewTupleKey("folder:x", "viewer", "user:jon"),
+	}))
+
+	// viewer is only defined on some of the types related to document#parent
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type team
+			relations
+				define member: [user]
+		type folder
+			relations
+				define viewer: [user]
+		type document
+			relations
+				define parent: [folder, team]
+				define viewer: viewer from parent`)
+
+	ctx = typesystem.ContextWithTypesystem(ctx, typesystem.New(model))
+	ctx = storage.ContextWithRelationshipTupleReader(ctx, ds)
+
+	check := func(t *testing.T, object string, opts ...LocalCheckerOption) (*ResolveCheckResponse, error) {
+		checker := NewLocalChecker(opts...)
+		t.Cleanup(checker.Close)
+
+		return checker.ResolveCheck(ctx, &ResolveCheckRequest{
+			StoreID:         storeID,
+			TupleKey:        tuple.NewTupleKey(object, "viewer", "user:jon"),
+			RequestMetadata: NewCheckRequestMetadata(defaultResolveNodeLimit),
+		})
+	}
+
+	t.Run("skipped_by_default", func(t *testing.T) {
+		resp, err := check(t, "document:1")
+		require.NoError(t, err)
+		require.False(t, resp.GetAllowed())
+	})
+
+	t.Run("error_if_enabled", func(t *testing.T) {
+		_, err := check(t, "document:1", WithUndefinedComputedRelationError(true))
+		require.ErrorIs(t, err, ErrUndefinedComputedRelation)
+
+		var undefinedErr *UndefinedComputedRelationError
+		require.ErrorAs(t, err, &undefinedErr)
+		require.Equal(t, &UndefinedComputedRelationError{
+			Relation:         "document#viewer",
+			TuplesetRelation: "document#parent",
+			RelatedType:      "team",
+			ComputedRelation: "viewer",
+		}, undefinedErr)
+		require.EqualError(t, err, "computed relation undefined on the related type: 'document#viewer' resolves 'viewer' through 'document#parent', but 'team#viewer' is undefined")
+	})
+
+	t.Run("defined_relations_resolve_if_enabled", func(t *testing.T) {
+		resp, err := check(t, "document:2", WithUndefinedComputedRelationError(true))
+		require.NoError(t, err)
+		require.True(t, resp.GetAllowed())
+	})
+}
+
+// checkSpanRecorder records the spans of the package tracer. The global tracer provider can only be set
+// once for the tracers created before it, so the recorder is shared by the tests, which tell their
+// spans apart by trace.
+var checkSpanRecorder = sync.OnceValue(func() *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	return recorder
+})
+
+func TestCheckSubproblemSpans(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	recorder := checkSpanRecorder()
+	storeID := ulid.Make().String()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type folder
+			relations
+				define viewer: [user]
+		type document
+			relations
+				define parent: [folder]
+				define editor: [user]
+				define viewer: [user] or editor or viewer from parent`)
+
+	// check resolves the Check under a caller's span, and returns the attributes of the spans with the
+	// name nested under it
+	check := func(t *testing.T, ds storage.RelationshipTupleReader, object, spanName string) (*ResolveCheckResponse, []map[attribute.Key]attribute.Value) {
+		ctx, root := otel.Tracer("caller").Start(context.Background(), "caller")
+		ctx = typesystem.ContextWithTypesystem(ctx, typesystem.New(model))
+		ctx = storage.ContextWithRelationshipTupleReader(ctx, ds)
+
+		checker := NewLocalChecker()
+		t.Cleanup(checker.Close)
+
+		resp, err := checker.ResolveCheck(ctx, &ResolveCheckRequest{
+			StoreID:         storeID,
+			TupleKey:        tuple.NewTupleKey(object, "viewer", "user:jon"),
+			RequestMetadata: NewCheckRequestMetadata(defaultResolveNodeLimit),
+		})
+		require.NoError(t, err)
+		root.End()
+
+		var spans []map[attribute.Key]attribute.Value
+		for _, span := range recorder.Ended() {
+			if span.Name() != spanName || span.SpanContext().TraceID() != root.SpanContext().TraceID() {
+				continue
+			}
+
+			attrs := map[attribute.Key]attribute.Value{}
+			for _, kv := range span.Attributes() {
+				attrs[kv.Key] = kv.Value
+			}
+			spans = append(spans, attrs)
+		}
+		return resp, spans
+	}
+
+	t.Run("resolved_union", func(t *testing.T) {
+		ds := memory.New()
+		t.Cleanup(ds.Close)
+
+		require.NoError(t, ds.Write(context.Background(), storeID, nil, []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:1", "parent", "folder:x"),
+		}))
+
+		resp, unions := check(t, ds, "document:1", "union")
+		require.False(t, resp.GetAllowed())
+		require.Len(t, unions, 1)
+		require.Equal(t, "document", unions[0]["object_type"].AsString())
+		require.Equal(t, "viewer", unions[0]["relation"].AsString())
+		require.Equal(t, "user", unions[0]["user_type"].AsString())
+		require.False(t, unions[0]["allowed"].AsBool())
+		require.False(t, unions[0]["short_circuited"].AsBool())
+		require.Equal(t, int64(resp.GetResolutionMetadata().DatastoreQueryCount), unions[0]["datastore_query_count"].AsInt64())
+
+		_, ttus := check(t, ds, "document:1", "checkTTU")
+		require.Len(t, ttus, 1)
+		require.Equal(t, "document", ttus[0]["object_type"].AsString())
+		require.Equal(t, "viewer", ttus[0]["relation"].AsString())
+		require.False(t, ttus[0]["short_circuited"].AsBool())
+		require.Positive(t, ttus[0]["datastore_query_count"].AsInt64())
+	})
+
+	t.Run("short_circuited_union", func(t *testing.T) {
+		mockController := gomock.NewController(t)
+		defer mockController.Finish()
+
+		// the direct relationship is found at once, while the other reads wait until the union is decided
+		mockDatastore := mocks.NewMockRelationshipTupleReader(mockController)
+		mockDatastore.EXPECT().
+			ReadUserTuple(gomock.Any(), storeID, gomock.Any()).
+			AnyTimes().
+			DoAndReturn(func(ctx context.Context, _ string, tk *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
+				if tk.GetRelation() == "viewer" {
+					return &openfgav1.Tuple{Key: tuple.NewTupleKey("document:2", "viewer", "user:jon")}, nil
+				}
+
+				<-ctx.Done()
+				return nil, ctx.Err()
+			})
+		mockDatastore.EXPECT().
+			Read(gomock.Any(), storeID, gomock.Any()).
+			AnyTimes().
+			DoAndReturn(func(ctx context.Context, _ string, _ *openfgav1.TupleKey) (storage.TupleIterator, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			})
+
+		resp, unions := check(t, mockDatastore, "document:2", "union")
+		require.True(t, resp.GetAllowed())
+		require.Len(t, unions, 1)
+		require.True(t, unions[0]["allowed"].AsBool())
+		require.True(t, unions[0]["short_circuited"].AsBool())
+	})
+}
+
+func TestCheckResolutionPath(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	require.NoError(t, ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "owner", "user:jon"),
+		tuple.NewTupleKey("document:1", "owner", "user:bob"),
+		tuple.NewTupleKey("document:1", "blocked", "user:bob"),
+		tuple.NewTupleKey("document:1", "approver", "user:jon"),
+		tuple.NewTupleKey("document:2", "parent", "folder:x"),
+		tuple.NewTupleKey("folder:x", "viewer", "user:jon"),
+	}))
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type folder
+			relations
+				define viewer: [user]
+		type document
+			relations
+				define parent: [folder]
+				define owner: [user]
+				define blocked: [user]
+				define approver: [user]
+				define editor: [user] or owner
+				define viewer: (editor or viewer from parent) but not blocked
+				define reviewer: editor and approver`)
+
+	ctx = typesystem.ContextWithTypesystem(ctx, typesystem.New(model))
+	ctx = storage.ContextWithRelationshipTupleReader(ctx, ds)
+
+	checker := NewLocalCheckerWithCycleDetection()
+	t.Cleanup(checker.Close)
+
+	check := func(t *testing.T, tk *openfgav1.TupleKey, trace bool) *ResolveCheckResponse {
+		resp, err := checker.ResolveCheck(ctx, &ResolveCheckRequest{
+			StoreID:         storeID,
+			TupleKey:        tk,
+			RequestMetadata: NewCheckRequestMetadata(defaultResolveNodeLimit),
+			ResolutionTrace: trace,
+		})
+		require.NoError(t, err)
+
+		return resp
+	}
+
+	tests := []struct {
+		name    string
+		tk      *openfgav1.TupleKey
+		allowed bool
+		path    []string
+	}{
+		{
+			name:    "computed_usersets",
+			tk:      tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+			allowed: true,
+			path:    []string{"document:1#viewer", "document:1#editor", "document:1#owner"},
+		},
+		{
+			name:    "tuple_to_userset",
+			tk:      tuple.NewTupleKey("document:2", "viewer", "user:jon"),
+			allowed: true,
+			path:    []string{"document:2#viewer", "folder:x#viewer"},
+		},
+		{
+			name: "exclusion_denied_by_subtracted_relation",
+			tk:   tuple.NewTupleKey("document:1", "viewer", "user:bob"),
+			path: []string{"document:1#viewer", "but not document:1#blocked"},
+		},
+		{
+			name: "exclusion_denied_by_base",
+			tk:   tuple.NewTupleKey("document:2", "viewer", "user:bob"),
+			path: []string{"document:2#viewer"},
+		},
+		{
+			name: "intersection_denied_by_one_operand",
+			tk:   tuple.NewTupleKey("document:1", "reviewer", "user:bob"),
+			path: []string{"document:1#reviewer", "document:1#approver"},
+		},
+		{
+			name:    "direct",
+			tk:      tuple.NewTupleKey("document:1", "approver", "user:jon"),
+			allowed: true,
+			path:    []string{"document:1#approver"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := check(t, test.tk, true)
+			require.Equal(t, test.allowed, resp.GetAllowed())
+			require.Equal(t, test.path, resp.GetResolutionPath())
+		})
+	}
+
+	t.Run("intersection_allowed", func(t *testing.T) {
+		resp := check(t, tuple.NewTupleKey("document:1", "reviewer", "user:jon"), true)
+		require.True(t, resp.GetAllowed())
+		require.Equal(t, "document:1#reviewer", resp.GetResolutionPath()[0])
+	})
+
+	t.Run("omitted_without_trace", func(t *testing.T) {
+		resp := check(t, tuple.NewTupleKey("document:1", "viewer", "user:jon"), false)
+		require.True(t, resp.GetAllowed())
+		require.Nil(t, resp.GetResolutionPath())
+	})
+}
+
+func TestCheckObligations(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	require.NoError(t, ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKeyWithCondition("document:1", "viewer", "user:jon", "step_up", nil),
+		tuple.NewTupleKeyWithCondition("document:2", "viewer", "group:eng#member", "step_up", nil),
+		tuple.NewTupleKeyWithCondition("group:eng", "member", "user:jon", "ip_allowlist", nil),
+		tuple.NewTupleKeyWithCondition("document:3", "parent", "folder:x", "ip_allowlist", nil),
+		tuple.NewTupleKey("folder:x", "viewer", "user:jon"),
+		tuple.NewTupleKey("document:3", "approver", "user:jon"),
+		tuple.NewTupleKey("document:4", "viewer", "user:jon"),
+	}))
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type group
+			relations
+				define member: [user with ip_allowlist]
+		type folder
+			relations
+				define viewer: [user]
+		type document
+			relations
+				define parent: [folder with ip_allowlist]
+				define approver: [user]
+				define viewer: [user, user with step_up, group#member with step_up] or viewer from parent
+				define reviewer: viewer and approver
+
+		condition step_up(mfa_verified: bool) {
+			mfa_verified
+		}
+
+		condition ip_allowlist(trusted: bool) {
+			trusted
+		}`)
+
+	ctx = typesystem.ContextWithTypesystem(ctx, typesystem.New(model))
+	ctx = storage.ContextWithRelationshipTupleReader(ctx, ds)
+
+	checker := NewLocalCheckerWithCycleDetection()
+	t.Cleanup(checker.Close)
+
+	conditionContext := testutils.MustNewStruct(t, map[string]interface{}{"mfa_verified": true, "trusted": true})
+
+	tests := []struct {
+		name        string
+		tk          *openfgav1.TupleKey
+		allowed     bool
+		obligations []string
+	}{
+		{
+			name:        "direct",
+			tk:          tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+			allowed:     true,
+			obligations: []string{"step_up"},
+		},
+		{
+			name:        "userset",
+			tk:          tuple.NewTupleKey("document:2", "viewer", "user:jon"),
+			allowed:     true,
+			obligations: []string{"step_up", "ip_allowlist"},
+		},
+		{
+			name:        "tuple_to_userset",
+			tk:          tuple.NewTupleKey("document:3", "viewer", "user:jon"),
+			allowed:     true,
+			obligations: []string{"ip_allowlist"},
+		},
+		{
+			name:        "intersection",
+			tk:          tuple.NewTupleKey("document:3", "reviewer", "user:jon"),
+			allowed:     true,
+			obligations: []string{"ip_allowlist"},
+		},
+		{
+			name:    "unconditional",
+			tk:      tuple.NewTupleKey("document:4", "viewer", "user:jon"),
+			allowed: true,
+		},
+		{
+			name: "denied",
+			tk:   tuple.NewTupleKey("document:1", "viewer", "user:bob"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp, err := checker.ResolveCheck(ctx, &ResolveCheckRequest{
+				StoreID:         storeID,
+				TupleKey:        test.tk,
+				Context:         conditionContext,
+				RequestMetadata: NewCheckRequestMetadata(defaultResolveNodeLimit),
+			})
+			require.NoError(t, err)
+			require.Equal(t, test.allowed, resp.GetAllowed())
+			require.Equal(t, test.obligations, resp.GetObligations())
+		})
+	}
+}
+
+// slowUsersetTupleReader delays the reads of userset tuples, regardless of the context.
+type slowUsersetTupleReader struct {
+	storage.RelationshipTupleReader
+	delay time.Duration
+}
+
+func (r *slowUsersetTupleReader) ReadUsersetTuples(
+	ctx context.Context,
+	store string,
+	filter storage.ReadUsersetTuplesFilter,
+) (storage.TupleIterator, error) {
+	time.Sleep(r.delay)
+	return r.RelationshipTupleReader.ReadUsersetTuples(ctx, store, filter)
+}
+
+func TestCheckDeadlineExceeded(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	// group:0 <- group:1#member <- ... <- group:99#member, resolving it takes 100 delayed reads
+	const depth = 100
+	for i := 1; i < depth; i++ {
+		require.NoError(t, ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+			tuple.NewTupleKey(fmt.Sprintf("group:%d", i-1), "member", fmt.Sprintf("group:%d#member", i)),
+		}))
+	}
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type group
+			relations
+				define member: [user, group#member]`)
+
+	ctx = typesystem.ContextWithTypesystem(ctx, typesystem.New(model))
+	ctx = storage.ContextWithRelationshipTupleReader(ctx, &slowUsersetTupleReader{
+		RelationshipTupleReader: ds,
+		delay:                   5 * time.Millisecond,
+	})
+
+	checker := NewLocalChecker()
+	t.Cleanup(checker.Close)
+
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := checker.ResolveCheck(ctx, &ResolveCheckRequest{
+		StoreID:         storeID,
+		TupleKey:        tuple.NewTupleKey("group:0", "member", "user:jon"),
+		RequestMetadata: NewCheckRequestMetadata(depth + 1),
+	})
+	require.ErrorIs(t, err, ErrRequestDeadlineExceeded)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), 200*time.Millisecond)
+}
+
+func BenchmarkCheckDirectAssignment(b *testing.B) {
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	ds := memory.New()
+	b.Cleanup(ds.Close)
+
+	require.NoError(b, ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+	}))
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type document
+			relations
+				define viewer: [user]`)
+
+	ctx = typesystem.ContextWithTypesystem(ctx, typesystem.New(model))
+	ctx = storage.ContextWithRelationshipTupleReader(ctx, ds)
+
+	checker := NewLocalChecker()
+	b.Cleanup(checker.Close)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		resp, err := checker.ResolveCheck(ctx, &ResolveCheckRequest{
+			StoreID:         storeID,
+			TupleKey:        tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+			RequestMetadata: NewCheckRequestMetadata(defaultResolveNodeLimit),
+		})
+		require.NoError(b, err)
+		require.True(b, resp.GetAllowed())
+	}
+}
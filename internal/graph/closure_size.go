@@ -0,0 +1,175 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+)
+
+var errClosureLimitReached = errors.New("userset closure limit reached")
+
+// UsersetClosureSize returns the number of distinct concrete users having the relation on the object, found by
+// expanding every userset the relation is granted to (e.g. the members of 'group:eng#member' and of the groups
+// nested in it). The expansion stops as soon as more than limit users are found, in which case limit and true are
+// returned. A limit of 0 counts every user. Public wildcards are not counted, since they are not a known population.
+//
+// The object and the relation are read from the tuple key of the request, whose user is ignored. Like for
+// ExplainCheck, the typesystem and the relationship tuple reader are read from the context.
+func UsersetClosureSize(ctx context.Context, req *ResolveCheckRequest, limit uint32) (uint32, bool, error) {
+	typesys, ok := typesystem.TypesystemFromContext(ctx)
+	if !ok {
+		return 0, false, fmt.Errorf("typesystem missing in context")
+	}
+
+	ds, ok := storage.RelationshipTupleReaderFromContext(ctx)
+	if !ok {
+		return 0, false, fmt.Errorf("relationship tuple reader datastore missing in context")
+	}
+
+	c := &closureCounter{
+		userExpander: &userExpander{
+			pathResolver: &pathResolver{
+				typesys: typesys,
+				ds:      ds,
+				req:     req,
+				visited: map[string]struct{}{},
+			},
+			expanding: map[string]struct{}{},
+		},
+		limit:    limit,
+		users:    map[string]struct{}{},
+		expanded: map[string]struct{}{},
+	}
+
+	err := c.countRelation(ctx, req.GetTupleKey().GetObject(), req.GetTupleKey().GetRelation(), req.GetRequestMetadata().Depth)
+	if err != nil {
+		if errors.Is(err, errClosureLimitReached) {
+			return limit, true, nil
+		}
+
+		return 0, false, err
+	}
+
+	return uint32(len(c.users)), false, nil
+}
+
+// closureCounter collects the distinct users of a relation, up to a limit.
+// It is not safe for concurrent use.
+type closureCounter struct {
+	*userExpander
+	limit uint32
+	users map[string]struct{}
+	// expanded holds the usersets whose users were already collected.
+	expanded map[string]struct{}
+}
+
+// add collects the user, and returns errClosureLimitReached once more than limit users are collected.
+func (c *closureCounter) add(user string) error {
+	c.users[user] = struct{}{}
+
+	if c.limit > 0 && uint32(len(c.users)) > c.limit {
+		return errClosureLimitReached
+	}
+
+	return nil
+}
+
+func (c *closureCounter) countRelation(ctx context.Context, object, relation string, depth uint32) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if depth == 0 {
+		return ErrResolutionDepthExceeded
+	}
+
+	key := tuple.ToObjectRelationString(object, relation)
+	if _, ok := c.expanded[key]; ok {
+		return nil
+	}
+	c.expanded[key] = struct{}{}
+
+	rel, err := c.typesys.GetRelation(tuple.GetType(object), relation)
+	if err != nil {
+		return err
+	}
+
+	return c.countRewrite(ctx, object, relation, rel.GetRewrite(), depth-1)
+}
+
+func (c *closureCounter) countRewrite(ctx context.Context, object, relation string, rewrite *openfgav1.Userset, depth uint32) error {
+	switch rw := rewrite.GetUserset().(type) {
+	case *openfgav1.Userset_This:
+		tuples, err := c.readTuples(ctx, object, relation)
+		if err != nil {
+			return err
+		}
+
+		for _, t := range tuples {
+			userObject, userRelation := tuple.SplitObjectRelation(t.GetUser())
+			if tuple.IsTypedWildcard(userObject) {
+				continue
+			}
+
+			if userRelation == "" {
+				err = c.add(userObject)
+			} else {
+				err = c.countRelation(ctx, userObject, userRelation, depth)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case *openfgav1.Userset_ComputedUserset:
+		return c.countRelation(ctx, object, rw.ComputedUserset.GetRelation(), depth)
+	case *openfgav1.Userset_TupleToUserset:
+		tuples, err := c.readTuples(ctx, object, rw.TupleToUserset.GetTupleset().GetRelation())
+		if err != nil {
+			return err
+		}
+
+		computedRelation := rw.TupleToUserset.GetComputedUserset().GetRelation()
+		for _, t := range tuples {
+			userObject, _ := tuple.SplitObjectRelation(t.GetUser())
+			if _, err := c.typesys.GetRelation(tuple.GetType(userObject), computedRelation); err != nil {
+				continue // skip computed relations on tupleset relationships if they are undefined
+			}
+
+			if err := c.countRelation(ctx, userObject, computedRelation, depth); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case *openfgav1.Userset_Union:
+		for _, child := range rw.Union.GetChild() {
+			if err := c.countRewrite(ctx, object, relation, child, depth); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	default:
+		// intersections and exclusions need the complete sets of users of their operands
+		users, err := c.usersOfRewrite(ctx, object, relation, rewrite, depth)
+		if err != nil {
+			return err
+		}
+
+		for _, user := range users {
+			if err := c.add(user); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
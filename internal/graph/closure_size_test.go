@@ -0,0 +1,106 @@
+package graph
+
+import (
+	"context"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/testutils"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+)
+
+func TestUsersetClosureSize(t *testing.T) {
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	storeID := ulid.Make().String()
+
+	// group:all contains group:eng and group:sales, group:eng contains group:backend, which contains group:eng back
+	err := ds.Write(context.Background(), storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("group:all", "member", "group:eng#member"),
+		tuple.NewTupleKey("group:all", "member", "group:sales#member"),
+		tuple.NewTupleKey("group:all", "member", "user:*"),
+		tuple.NewTupleKey("group:eng", "member", "user:anne"),
+		tuple.NewTupleKey("group:eng", "member", "user:bob"),
+		tuple.NewTupleKey("group:eng", "member", "group:backend#member"),
+		tuple.NewTupleKey("group:backend", "member", "user:bob"),
+		tuple.NewTupleKey("group:backend", "member", "user:carl"),
+		tuple.NewTupleKey("group:backend", "member", "group:eng#member"),
+		tuple.NewTupleKey("group:sales", "member", "user:dan"),
+		tuple.NewTupleKey("group:sales", "member", "user:anne"),
+
+		tuple.NewTupleKey("document:1", "viewer", "group:all#member"),
+		tuple.NewTupleKey("document:1", "blocked", "user:dan"),
+	})
+	require.NoError(t, err)
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type group
+			relations
+				define member: [user, user:*, group#member]
+		type document
+			relations
+				define viewer: [group#member]
+				define blocked: [user]
+				define can_view: viewer but not blocked`)
+
+	ctx := typesystem.ContextWithTypesystem(context.Background(), typesystem.New(model))
+	ctx = storage.ContextWithRelationshipTupleReader(ctx, ds)
+
+	closureSize := func(object, relation string, limit uint32) (uint32, bool, error) {
+		return UsersetClosureSize(ctx, &ResolveCheckRequest{
+			StoreID:         storeID,
+			TupleKey:        tuple.NewTupleKey(object, relation, ""),
+			RequestMetadata: NewCheckRequestMetadata(25),
+		}, limit)
+	}
+
+	t.Run("nested_groups_are_deduplicated", func(t *testing.T) {
+		// anne, bob, carl and dan; the wildcard is not counted
+		size, truncated, err := closureSize("group:all", "member", 0)
+		require.NoError(t, err)
+		require.False(t, truncated)
+		require.Equal(t, uint32(4), size)
+
+		size, truncated, err = closureSize("group:eng", "member", 0)
+		require.NoError(t, err)
+		require.False(t, truncated)
+		require.Equal(t, uint32(3), size)
+	})
+
+	t.Run("limit_equal_to_the_size", func(t *testing.T) {
+		size, truncated, err := closureSize("group:all", "member", 4)
+		require.NoError(t, err)
+		require.False(t, truncated)
+		require.Equal(t, uint32(4), size)
+	})
+
+	t.Run("truncated_at_the_limit", func(t *testing.T) {
+		size, truncated, err := closureSize("group:all", "member", 2)
+		require.NoError(t, err)
+		require.True(t, truncated)
+		require.Equal(t, uint32(2), size)
+	})
+
+	t.Run("through_an_exclusion", func(t *testing.T) {
+		size, truncated, err := closureSize("document:1", "can_view", 0)
+		require.NoError(t, err)
+		require.False(t, truncated)
+		require.Equal(t, uint32(3), size)
+	})
+
+	t.Run("undefined_relation", func(t *testing.T) {
+		_, _, err := closureSize("group:all", "owner", 0)
+		require.ErrorIs(t, err, typesystem.ErrRelationUndefined)
+	})
+}
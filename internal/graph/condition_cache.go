@@ -0,0 +1,78 @@
+package graph
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/cespare/xxhash/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/openfga/openfga/internal/condition"
+	"github.com/openfga/openfga/internal/keys"
+)
+
+type conditionEvaluationCacheCtxKey struct{}
+
+// conditionEvaluationCache memoizes the results of the tuple conditions evaluated while resolving a single
+// Check, keyed by the condition name and the contexts it was evaluated with.
+// It is safe for concurrent use.
+type conditionEvaluationCache struct {
+	mu      sync.RWMutex
+	results map[string]condition.EvaluationResult // GUARDED_BY(mu)
+}
+
+func newConditionEvaluationCache() *conditionEvaluationCache {
+	return &conditionEvaluationCache{
+		results: map[string]condition.EvaluationResult{},
+	}
+}
+
+func conditionEvaluationCacheFromContext(ctx context.Context) (*conditionEvaluationCache, bool) {
+	cache, ok := ctx.Value(conditionEvaluationCacheCtxKey{}).(*conditionEvaluationCache)
+	return cache, ok
+}
+
+func (c *conditionEvaluationCache) get(key string) (*condition.EvaluationResult, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	res, ok := c.results[key]
+	if !ok {
+		return nil, false
+	}
+
+	return &res, true
+}
+
+func (c *conditionEvaluationCache) set(key string, res *condition.EvaluationResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.results[key] = *res
+}
+
+// conditionEvaluationCacheKey returns a key identifying the evaluation of the condition of the tuple with
+// the request context. Two tuples with the same condition and equal condition contexts have the same key.
+func conditionEvaluationCacheKey(t *openfgav1.TupleKey, requestContext *structpb.Struct) (string, error) {
+	hasher := keys.NewCacheKeyHasher(xxhash.New())
+
+	if err := hasher.WriteString(t.GetCondition().GetName() + "/"); err != nil {
+		return "", err
+	}
+
+	if err := keys.NewContextHasher(t.GetCondition().GetContext()).Append(hasher); err != nil {
+		return "", err
+	}
+
+	if err := hasher.WriteString("/"); err != nil {
+		return "", err
+	}
+
+	if err := keys.NewContextHasher(requestContext).Append(hasher); err != nil {
+		return "", err
+	}
+
+	return strconv.FormatUint(hasher.Key().ToUInt64(), 10), nil
+}
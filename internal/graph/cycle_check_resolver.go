@@ -10,7 +10,32 @@ import (
 )
 
 type CycleDetectionCheckResolver struct {
-	delegate CheckResolver
+	delegate        CheckResolver
+	maxVisitedPaths uint32
+
+	// visitedPathsLimitAsCycle makes the paths crossing maxVisitedPaths resolve as cycles instead of failing the Check.
+	visitedPathsLimitAsCycle bool
+}
+
+type CycleDetectionCheckResolverOpt func(*CycleDetectionCheckResolver)
+
+// WithMaxVisitedPaths sets the maximum number of paths that may be visited while resolving
+// a single Check. Once crossed, ResolveCheck returns ErrVisitedPathsLimitExceeded.
+// A limit of 0 (the default) means there is no limit.
+func WithMaxVisitedPaths(limit uint32) CycleDetectionCheckResolverOpt {
+	return func(c *CycleDetectionCheckResolver) {
+		c.maxVisitedPaths = limit
+	}
+}
+
+// WithVisitedPathsLimitAsCycle makes the paths that would cross the limit set with WithMaxVisitedPaths resolve
+// conservatively, as if a cycle was detected, instead of failing the Check with ErrVisitedPathsLimitExceeded.
+// This trades completeness for bounded memory: a Check whose only path to the user is longer than the limit
+// resolves to not allowed. The visited paths are cloned for every dispatch, so each path is bounded on its own.
+func WithVisitedPathsLimitAsCycle(enabled bool) CycleDetectionCheckResolverOpt {
+	return func(c *CycleDetectionCheckResolver) {
+		c.visitedPathsLimitAsCycle = enabled
+	}
 }
 
 var _ CheckResolver = (*CycleDetectionCheckResolver)(nil)
@@ -18,10 +43,14 @@ var _ CheckResolver = (*CycleDetectionCheckResolver)(nil)
 // Close implements CheckResolver.
 func (*CycleDetectionCheckResolver) Close() {}
 
-func NewCycleDetectionCheckResolver() *CycleDetectionCheckResolver {
+func NewCycleDetectionCheckResolver(opts ...CycleDetectionCheckResolverOpt) *CycleDetectionCheckResolver {
 	c := &CycleDetectionCheckResolver{}
 	c.delegate = c
 
+	for _, opt := range opts {
+		opt(c)
+	}
+
 	return c
 }
 
@@ -49,6 +78,20 @@ func (c *CycleDetectionCheckResolver) ResolveCheck(
 		}, nil
 	}
 
+	if c.maxVisitedPaths > 0 && uint32(len(req.VisitedPaths)) >= c.maxVisitedPaths {
+		if c.visitedPathsLimitAsCycle {
+			span.SetAttributes(attribute.Bool("visited_paths_limit_exceeded", true))
+			return &ResolveCheckResponse{
+				Allowed: false,
+				ResolutionMetadata: &ResolveCheckResponseMetadata{
+					CycleDetected: true,
+				},
+			}, nil
+		}
+
+		return nil, NewResolveCheckError(req, ErrVisitedPathsLimitExceeded)
+	}
+
 	req.VisitedPaths[key] = struct{}{}
 
 	return c.delegate.ResolveCheck(ctx, &ResolveCheckRequest{
@@ -59,9 +102,45 @@ func (c *CycleDetectionCheckResolver) ResolveCheck(
 		RequestMetadata:      req.GetRequestMetadata(),
 		VisitedPaths:         req.VisitedPaths,
 		Context:              req.GetContext(),
+		MaxIndirectionDepth:  req.GetMaxIndirectionDepth(),
+		IndirectionDepth:     req.GetIndirectionDepth(),
+		ResolutionTrace:      req.GetResolutionTrace(),
+		TupleOverlay:         req.GetTupleOverlay(),
 	})
 }
 
+// BatchResolveCheck implements CheckResolver. Every request is forwarded to the delegate with its own visited
+// paths, starting from the paths it was given, so that the cycles found by one request do not affect another.
+func (c *CycleDetectionCheckResolver) BatchResolveCheck(
+	ctx context.Context,
+	reqs []*ResolveCheckRequest,
+) ([]*ResolveCheckResponse, error) {
+	forwarded := make([]*ResolveCheckRequest, 0, len(reqs))
+	for _, req := range reqs {
+		visitedPaths := make(map[string]struct{}, len(req.VisitedPaths)+1)
+		for path := range req.VisitedPaths {
+			visitedPaths[path] = struct{}{}
+		}
+		visitedPaths[tuple.TupleKeyToString(req.GetTupleKey())] = struct{}{}
+
+		forwarded = append(forwarded, &ResolveCheckRequest{
+			StoreID:              req.GetStoreID(),
+			AuthorizationModelID: req.GetAuthorizationModelID(),
+			TupleKey:             req.GetTupleKey(),
+			ContextualTuples:     req.GetContextualTuples(),
+			RequestMetadata:      req.GetRequestMetadata(),
+			VisitedPaths:         visitedPaths,
+			Context:              req.GetContext(),
+			MaxIndirectionDepth:  req.GetMaxIndirectionDepth(),
+			IndirectionDepth:     req.GetIndirectionDepth(),
+			ResolutionTrace:      req.GetResolutionTrace(),
+			TupleOverlay:         req.GetTupleOverlay(),
+		})
+	}
+
+	return c.delegate.BatchResolveCheck(ctx, forwarded)
+}
+
 func (c *CycleDetectionCheckResolver) SetDelegate(delegate CheckResolver) {
 	c.delegate = delegate
 }
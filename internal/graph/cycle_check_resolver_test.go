@@ -2,6 +2,8 @@ package graph
 
 import (
 	"context"
+	"fmt"
+	"sync/atomic"
 	"testing"
 
 	"github.com/oklog/ulid/v2"
@@ -69,6 +71,177 @@ func TestCycleDetectionCheckResolver(t *testing.T) {
 	})
 }
 
+func TestCycleDetectionCheckResolverMaxVisitedPaths(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	storeID := ulid.Make().String()
+
+	model := parser.MustTransformDSLToProto(`
+		model
+		  schema 1.1
+
+		type user
+
+		type group
+		  relations
+			define member: [user, group#member]
+`)
+
+	// group:0 has many member groups, the last of which leads through a chain of nested groups to user:jon
+	var tuples []*openfgav1.TupleKey
+	for i := 1; i <= 10; i++ {
+		tuples = append(tuples, tuple.NewTupleKey("group:0", "member", fmt.Sprintf("group:%d#member", i)))
+	}
+	for i := 10; i < 20; i++ {
+		tuples = append(tuples, tuple.NewTupleKey(fmt.Sprintf("group:%d", i), "member", fmt.Sprintf("group:%d#member", i+1)))
+	}
+	tuples = append(tuples, tuple.NewTupleKey("group:20", "member", "user:jon"))
+
+	err := ds.Write(context.Background(), storeID, nil, tuples)
+	require.NoError(t, err)
+
+	typesys, err := typesystem.NewAndValidate(
+		context.Background(),
+		model,
+	)
+	require.NoError(t, err)
+
+	ctx := storage.ContextWithRelationshipTupleReader(context.Background(), ds)
+	ctx = typesystem.ContextWithTypesystem(ctx, typesys)
+
+	check := func(opts ...CycleDetectionCheckResolverOpt) (*ResolveCheckResponse, error) {
+		cycleDetectionCheckResolver := NewCycleDetectionCheckResolver(opts...)
+		t.Cleanup(cycleDetectionCheckResolver.Close)
+		localCheckResolver := NewLocalChecker()
+		t.Cleanup(localCheckResolver.Close)
+
+		cycleDetectionCheckResolver.SetDelegate(localCheckResolver)
+		localCheckResolver.SetDelegate(cycleDetectionCheckResolver)
+
+		return cycleDetectionCheckResolver.ResolveCheck(ctx, &ResolveCheckRequest{
+			StoreID:              storeID,
+			AuthorizationModelID: model.GetId(),
+			TupleKey:             tuple.NewTupleKey("group:0", "member", "user:jon"),
+			RequestMetadata:      NewCheckRequestMetadata(25),
+		})
+	}
+
+	t.Run("unlimited_by_default", func(t *testing.T) {
+		resp, err := check()
+		require.NoError(t, err)
+		require.True(t, resp.GetAllowed())
+	})
+
+	t.Run("within_limit", func(t *testing.T) {
+		resp, err := check(WithMaxVisitedPaths(25))
+		require.NoError(t, err)
+		require.True(t, resp.GetAllowed())
+	})
+
+	t.Run("limit_exceeded_before_depth", func(t *testing.T) {
+		_, err := check(WithMaxVisitedPaths(5))
+		require.ErrorIs(t, err, ErrVisitedPathsLimitExceeded)
+	})
+
+	t.Run("limit_exceeded_as_cycle", func(t *testing.T) {
+		resp, err := check(WithMaxVisitedPaths(5), WithVisitedPathsLimitAsCycle(true))
+		require.NoError(t, err)
+		require.False(t, resp.GetAllowed())
+		require.True(t, resp.GetResolutionMetadata().CycleDetected)
+	})
+
+	t.Run("limit_as_cycle_within_limit", func(t *testing.T) {
+		resp, err := check(WithMaxVisitedPaths(25), WithVisitedPathsLimitAsCycle(true))
+		require.NoError(t, err)
+		require.True(t, resp.GetAllowed())
+	})
+}
+
+func TestCycleDetectionVisitedPathsLimitAsCycleWithLargeFanout(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	storeID := ulid.Make().String()
+
+	model := parser.MustTransformDSLToProto(`
+		model
+		  schema 1.1
+
+		type user
+
+		type group
+		  relations
+			define member: [user, group#member]
+`)
+
+	// group:0 has many member groups, each of which leads through a chain of nested groups that never reaches user:jon
+	const fanout, depth, maxVisitedPaths = 200, 20, 8
+	var tuples []*openfgav1.TupleKey
+	for i := 1; i <= fanout; i++ {
+		tuples = append(tuples, tuple.NewTupleKey("group:0", "member", fmt.Sprintf("group:%d_0#member", i)))
+		for j := 0; j < depth; j++ {
+			tuples = append(tuples, tuple.NewTupleKey(fmt.Sprintf("group:%d_%d", i, j), "member", fmt.Sprintf("group:%d_%d#member", i, j+1)))
+		}
+	}
+
+	for i := 0; i < len(tuples); i += 100 {
+		err := ds.Write(context.Background(), storeID, nil, tuples[i:min(i+100, len(tuples))])
+		require.NoError(t, err)
+	}
+
+	typesys, err := typesystem.NewAndValidate(context.Background(), model)
+	require.NoError(t, err)
+
+	ctx := storage.ContextWithRelationshipTupleReader(context.Background(), ds)
+	ctx = typesystem.ContextWithTypesystem(ctx, typesys)
+
+	cycleDetectionCheckResolver := NewCycleDetectionCheckResolver(
+		WithMaxVisitedPaths(maxVisitedPaths),
+		WithVisitedPathsLimitAsCycle(true),
+	)
+	t.Cleanup(cycleDetectionCheckResolver.Close)
+	localCheckResolver := NewLocalChecker()
+	t.Cleanup(localCheckResolver.Close)
+
+	// every dispatch goes through a mock recording the largest visited paths it was given
+	ctrl := gomock.NewController(t)
+	recorder := NewMockCheckResolver(ctrl)
+	var largestVisitedPaths atomic.Int64
+	recorder.EXPECT().ResolveCheck(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *ResolveCheckRequest) (*ResolveCheckResponse, error) {
+			size := int64(len(req.VisitedPaths))
+			for {
+				largest := largestVisitedPaths.Load()
+				if size <= largest || largestVisitedPaths.CompareAndSwap(largest, size) {
+					break
+				}
+			}
+			return cycleDetectionCheckResolver.ResolveCheck(ctx, req)
+		}).AnyTimes()
+
+	cycleDetectionCheckResolver.SetDelegate(localCheckResolver)
+	localCheckResolver.SetDelegate(recorder)
+
+	resp, err := cycleDetectionCheckResolver.ResolveCheck(ctx, &ResolveCheckRequest{
+		StoreID:              storeID,
+		AuthorizationModelID: model.GetId(),
+		TupleKey:             tuple.NewTupleKey("group:0", "member", "user:jon"),
+		RequestMetadata:      NewCheckRequestMetadata(25),
+	})
+	require.NoError(t, err)
+	require.False(t, resp.GetAllowed())
+	require.LessOrEqual(t, largestVisitedPaths.Load(), int64(maxVisitedPaths))
+}
+
 func TestIntegrationWithLocalChecker(t *testing.T) {
 	t.Cleanup(func() {
 		goleak.VerifyNone(t)
@@ -123,3 +296,142 @@ func TestIntegrationWithLocalChecker(t *testing.T) {
 	require.NotNil(t, resp)
 	require.False(t, resp.GetAllowed())
 }
+
+func TestCycleDetectionSelfReferentialUserset(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	cycleDetectionCheckResolver := NewCycleDetectionCheckResolver()
+	t.Cleanup(cycleDetectionCheckResolver.Close)
+	localCheckResolver := NewLocalChecker()
+	t.Cleanup(localCheckResolver.Close)
+
+	cycleDetectionCheckResolver.SetDelegate(localCheckResolver)
+	localCheckResolver.SetDelegate(cycleDetectionCheckResolver)
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	storeID := ulid.Make().String()
+
+	model := parser.MustTransformDSLToProto(`
+		model
+		  schema 1.1
+
+		type user
+
+		type group
+		  relations
+			define blocked: [user, group#member]
+			define member: [user, group#member] but not blocked
+`)
+
+	err := ds.Write(context.Background(), storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("group:1", "member", "user:jon"),
+		tuple.NewTupleKey("group:1", "member", "group:1#member"),
+		tuple.NewTupleKey("group:2", "member", "user:jon"),
+		tuple.NewTupleKey("group:2", "blocked", "group:1#member"),
+	})
+	require.NoError(t, err)
+
+	typesys, err := typesystem.NewAndValidate(context.Background(), model)
+	require.NoError(t, err)
+
+	ctx := storage.ContextWithRelationshipTupleReader(context.Background(), ds)
+	ctx = typesystem.ContextWithTypesystem(ctx, typesys)
+
+	tests := []struct {
+		name       string
+		tk         *openfgav1.TupleKey
+		allowed    bool
+		dispatches uint32
+	}{
+		{
+			// the self-referential userset is skipped without being dispatched, only 'blocked' is
+			name:       "self_reference_is_not_dispatched",
+			tk:         tuple.NewTupleKey("group:1", "member", "user:bob"),
+			dispatches: 1,
+		},
+		{
+			name:    "allowed_despite_self_reference",
+			tk:      tuple.NewTupleKey("group:1", "member", "user:jon"),
+			allowed: true,
+		},
+		{
+			name: "blocked_through_another_group",
+			tk:   tuple.NewTupleKey("group:2", "member", "user:jon"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			metadata := NewCheckRequestMetadata(25)
+			resp, err := cycleDetectionCheckResolver.ResolveCheck(ctx, &ResolveCheckRequest{
+				StoreID:              storeID,
+				AuthorizationModelID: model.GetId(),
+				TupleKey:             test.tk,
+				RequestMetadata:      metadata,
+			})
+			require.NoError(t, err)
+			require.Equal(t, test.allowed, resp.GetAllowed())
+
+			if test.dispatches > 0 {
+				require.Equal(t, test.dispatches, metadata.DispatchCounter.Load())
+			}
+		})
+	}
+}
+
+func TestCycleDetectionCheckResolverBatch(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	reqs := []*ResolveCheckRequest{
+		{
+			StoreID:         ulid.Make().String(),
+			TupleKey:        tuple.NewTupleKey("document:1", "viewer", "user:will"),
+			RequestMetadata: NewCheckRequestMetadata(defaultResolveNodeLimit),
+		},
+		{
+			StoreID:         ulid.Make().String(),
+			TupleKey:        tuple.NewTupleKey("document:2", "viewer", "user:will"),
+			RequestMetadata: NewCheckRequestMetadata(defaultResolveNodeLimit),
+			VisitedPaths:    map[string]struct{}{"folder:1#viewer@user:will": {}},
+		},
+	}
+
+	mockLocalChecker := NewMockCheckResolver(ctrl)
+	mockLocalChecker.EXPECT().BatchResolveCheck(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, forwarded []*ResolveCheckRequest) ([]*ResolveCheckResponse, error) {
+			require.Len(t, forwarded, 2)
+			require.Equal(t, map[string]struct{}{
+				"document:1#viewer@user:will": {},
+			}, forwarded[0].VisitedPaths)
+			require.Equal(t, map[string]struct{}{
+				"folder:1#viewer@user:will":   {},
+				"document:2#viewer@user:will": {},
+			}, forwarded[1].VisitedPaths)
+			require.Same(t, reqs[1].GetRequestMetadata(), forwarded[1].GetRequestMetadata())
+
+			return []*ResolveCheckResponse{{Allowed: true}, {Allowed: false}}, nil
+		}).
+		Times(1)
+
+	cycleDetectionCheckResolver := NewCycleDetectionCheckResolver()
+	t.Cleanup(cycleDetectionCheckResolver.Close)
+	cycleDetectionCheckResolver.SetDelegate(mockLocalChecker)
+
+	resps, err := cycleDetectionCheckResolver.BatchResolveCheck(context.Background(), reqs)
+	require.NoError(t, err)
+	require.True(t, resps[0].GetAllowed())
+	require.False(t, resps[1].GetAllowed())
+
+	// the visited paths of the requests are not modified
+	require.Nil(t, reqs[0].VisitedPaths)
+	require.Len(t, reqs[1].VisitedPaths, 1)
+}
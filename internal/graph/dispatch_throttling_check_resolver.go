@@ -2,6 +2,8 @@ package graph
 
 import (
 	"context"
+	"sync"
+	"sync/atomic"
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
@@ -25,8 +27,9 @@ type DispatchThrottlingCheckResolverConfig struct {
 // This allows a check / list objects request to be gradually throttled.
 type DispatchThrottlingCheckResolver struct {
 	delegate  CheckResolver
-	config    *DispatchThrottlingCheckResolverConfig
+	config    atomic.Pointer[DispatchThrottlingCheckResolverConfig]
 	throttler throttler.Throttler
+	closeOnce sync.Once
 }
 
 var _ CheckResolver = (*DispatchThrottlingCheckResolver)(nil)
@@ -38,7 +41,7 @@ type DispatchThrottlingCheckResolverOpt func(checkResolver *DispatchThrottlingCh
 // WithDispatchThrottlingCheckResolverConfig sets the config to be used for DispatchThrottlingCheckResolver.
 func WithDispatchThrottlingCheckResolverConfig(config DispatchThrottlingCheckResolverConfig) DispatchThrottlingCheckResolverOpt {
 	return func(r *DispatchThrottlingCheckResolver) {
-		r.config = &config
+		r.config.Store(&config)
 	}
 }
 
@@ -51,12 +54,12 @@ func WithThrottler(throttler throttler.Throttler) DispatchThrottlingCheckResolve
 
 func NewDispatchThrottlingCheckResolver(opts ...DispatchThrottlingCheckResolverOpt) *DispatchThrottlingCheckResolver {
 	dispatchThrottlingCheckResolver := &DispatchThrottlingCheckResolver{
-		config: &DispatchThrottlingCheckResolverConfig{
-			DefaultThreshold: config.DefaultCheckDispatchThrottlingDefaultThreshold,
-			MaxThreshold:     config.DefaultCheckDispatchThrottlingMaxThreshold,
-		},
 		throttler: throttler.NewNoopThrottler(),
 	}
+	dispatchThrottlingCheckResolver.config.Store(&DispatchThrottlingCheckResolverConfig{
+		DefaultThreshold: config.DefaultCheckDispatchThrottlingDefaultThreshold,
+		MaxThreshold:     config.DefaultCheckDispatchThrottlingMaxThreshold,
+	})
 	dispatchThrottlingCheckResolver.delegate = dispatchThrottlingCheckResolver
 
 	for _, opt := range opts {
@@ -65,6 +68,12 @@ func NewDispatchThrottlingCheckResolver(opts ...DispatchThrottlingCheckResolverO
 	return dispatchThrottlingCheckResolver
 }
 
+// SetConfig changes the thresholds the dispatches are throttled at, e.g. on a configuration reload. It is safe
+// to call concurrently with ResolveCheck.
+func (r *DispatchThrottlingCheckResolver) SetConfig(config DispatchThrottlingCheckResolverConfig) {
+	r.config.Store(&config)
+}
+
 func (r *DispatchThrottlingCheckResolver) SetDelegate(delegate CheckResolver) {
 	r.delegate = delegate
 }
@@ -73,8 +82,10 @@ func (r *DispatchThrottlingCheckResolver) GetDelegate() CheckResolver {
 	return r.delegate
 }
 
+// Close closes the throttler. Only the first call has an effect, so the resolver may be closed both
+// directly and through the resolver chain it is part of.
 func (r *DispatchThrottlingCheckResolver) Close() {
-	r.throttler.Close()
+	r.closeOnce.Do(r.throttler.Close)
 }
 
 func (r *DispatchThrottlingCheckResolver) ResolveCheck(ctx context.Context,
@@ -83,12 +94,13 @@ func (r *DispatchThrottlingCheckResolver) ResolveCheck(ctx context.Context,
 	span := trace.SpanFromContext(ctx)
 
 	currentNumDispatch := req.GetRequestMetadata().DispatchCounter.Load()
+	config := r.config.Load()
 
 	shouldThrottle := threshold.ShouldThrottle(
 		ctx,
 		currentNumDispatch,
-		r.config.DefaultThreshold,
-		r.config.MaxThreshold,
+		config.DefaultThreshold,
+		config.MaxThreshold,
 	)
 
 	span.SetAttributes(
@@ -101,3 +113,11 @@ func (r *DispatchThrottlingCheckResolver) ResolveCheck(ctx context.Context,
 	}
 	return r.delegate.ResolveCheck(ctx, req)
 }
+
+// BatchResolveCheck implements CheckResolver. The requests of a batch are parent problems, which have not
+// dispatched any subproblem yet, so the batch is forwarded to the delegate as is.
+func (r *DispatchThrottlingCheckResolver) BatchResolveCheck(ctx context.Context,
+	reqs []*ResolveCheckRequest,
+) ([]*ResolveCheckResponse, error) {
+	return r.delegate.BatchResolveCheck(ctx, reqs)
+}
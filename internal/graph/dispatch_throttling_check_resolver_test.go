@@ -2,9 +2,12 @@ package graph
 
 import (
 	"context"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/openfga/openfga/internal/mocks"
+	"github.com/openfga/openfga/internal/throttler"
 	"github.com/openfga/openfga/pkg/dispatch"
 
 	"github.com/stretchr/testify/require"
@@ -197,4 +200,85 @@ func TestDispatchThrottlingCheckResolver(t *testing.T) {
 
 		require.True(t, req.GetRequestMetadata().WasThrottled.Load())
 	})
+
+	t.Run("set_config_changes_the_thresholds", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockThrottler := mocks.NewMockThrottler(ctrl)
+
+		dut := NewDispatchThrottlingCheckResolver(
+			WithDispatchThrottlingCheckResolverConfig(DispatchThrottlingCheckResolverConfig{
+				DefaultThreshold: 200,
+				MaxThreshold:     200,
+			}),
+			WithThrottler(mockThrottler),
+		)
+		t.Cleanup(func() {
+			mockThrottler.EXPECT().Close().Times(1)
+			dut.Close()
+		})
+
+		mockCheckResolver := NewMockCheckResolver(ctrl)
+		dut.SetDelegate(mockCheckResolver)
+
+		mockCheckResolver.EXPECT().ResolveCheck(gomock.Any(), gomock.Any()).Times(1)
+		mockThrottler.EXPECT().Throttle(gomock.Any()).Times(1)
+
+		dut.SetConfig(DispatchThrottlingCheckResolverConfig{
+			DefaultThreshold: 100,
+			MaxThreshold:     100,
+		})
+
+		req := &ResolveCheckRequest{RequestMetadata: NewCheckRequestMetadata(10)}
+		req.GetRequestMetadata().DispatchCounter.Store(190)
+
+		_, err := dut.ResolveCheck(context.Background(), req)
+		require.NoError(t, err)
+
+		require.True(t, req.GetRequestMetadata().WasThrottled.Load())
+	})
+}
+
+func TestCheckResolverChainCloseIsIdempotent(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	newChain := func() []CheckResolver {
+		cached := NewCachedCheckResolver()
+		throttling := NewDispatchThrottlingCheckResolver(
+			WithThrottler(throttler.NewConstantRateThrottler(time.Millisecond, "test")),
+		)
+		cycleDetection := NewCycleDetectionCheckResolver()
+		local := NewLocalChecker(WithDispatchWorkerPool(10))
+
+		cached.SetDelegate(throttling)
+		throttling.SetDelegate(cycleDetection)
+		cycleDetection.SetDelegate(local)
+		local.SetDelegate(cached)
+
+		return []CheckResolver{cached, throttling, cycleDetection, local}
+	}
+
+	t.Run("closed_twice", func(t *testing.T) {
+		for _, resolver := range newChain() {
+			resolver.Close()
+			resolver.Close()
+		}
+	})
+
+	t.Run("closed_concurrently", func(t *testing.T) {
+		for _, resolver := range newChain() {
+			var wg sync.WaitGroup
+			for i := 0; i < 2; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					resolver.Close()
+				}()
+			}
+			wg.Wait()
+		}
+	})
 }
@@ -0,0 +1,280 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"golang.org/x/exp/maps"
+
+	"github.com/openfga/openfga/internal/validation"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+)
+
+// ErrRelationNotExclusion is returned by ListExcludedUsers when the relation is not defined as an exclusion.
+var ErrRelationNotExclusion = errors.New("relation is not defined as an exclusion")
+
+// ListExcludedUsers returns the users that the subtracted branch of an exclusion rewrite (e.g. 'define
+// viewer: [user:*] but not blocked') excludes from the relation on the object, although the base branch
+// grants the relation to every user of their type through a public wildcard. The users of the subtracted
+// branch are found by expanding it into concrete users; wildcards in the subtracted branch are not expanded.
+//
+// The object and the relation are read from the tuple key of the request, whose user is ignored.
+// The relation must be defined as an exclusion, otherwise ErrRelationNotExclusion is returned. Like for
+// ExplainCheck, the typesystem and the relationship tuple reader are read from the context.
+func ListExcludedUsers(ctx context.Context, req *ResolveCheckRequest) ([]string, error) {
+	typesys, ok := typesystem.TypesystemFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("typesystem missing in context")
+	}
+
+	ds, ok := storage.RelationshipTupleReaderFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("relationship tuple reader datastore missing in context")
+	}
+
+	object := req.GetTupleKey().GetObject()
+	relation := req.GetTupleKey().GetRelation()
+	depth := req.GetRequestMetadata().Depth
+
+	rel, err := typesys.GetRelation(tuple.GetType(object), relation)
+	if err != nil {
+		return nil, err
+	}
+
+	difference := rel.GetRewrite().GetDifference()
+	if difference == nil {
+		return nil, fmt.Errorf("%w: '%s#%s'", ErrRelationNotExclusion, tuple.GetType(object), relation)
+	}
+
+	r := &pathResolver{
+		typesys: typesys,
+		ds:      ds,
+		req:     req,
+		visited: map[string]struct{}{},
+	}
+
+	e := &userExpander{
+		pathResolver: r,
+		expanding:    map[string]struct{}{},
+	}
+
+	candidates, err := e.usersOfRewrite(ctx, object, relation, difference.GetSubtract(), depth)
+	if err != nil {
+		return nil, err
+	}
+
+	// [userType] => whether the base branch grants the relation to 'userType:*'
+	wildcardGranted := map[string]bool{}
+
+	excluded := make([]string, 0, len(candidates))
+	for _, user := range candidates {
+		userType := tuple.GetType(user)
+
+		granted, ok := wildcardGranted[userType]
+		if !ok {
+			paths, err := r.resolveRewrite(ctx, tuple.NewTupleKey(object, relation, tuple.TypedPublicWildcard(userType)), difference.GetBase(), depth, 1)
+			if err != nil {
+				return nil, err
+			}
+
+			granted = len(paths) > 0
+			wildcardGranted[userType] = granted
+		}
+
+		if granted {
+			excluded = append(excluded, user)
+		}
+	}
+
+	return excluded, nil
+}
+
+// userExpander expands a rewrite into the concrete users it grants the relation to.
+// It is not safe for concurrent use.
+type userExpander struct {
+	*pathResolver
+	// expanding holds the usersets being expanded, to break cycles.
+	expanding map[string]struct{}
+}
+
+// usersOfRelation returns the sorted concrete users having the relation on the object.
+func (e *userExpander) usersOfRelation(ctx context.Context, object, relation string, depth uint32) ([]string, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if depth == 0 {
+		return nil, ErrResolutionDepthExceeded
+	}
+
+	key := tuple.ToObjectRelationString(object, relation)
+	if _, ok := e.expanding[key]; ok {
+		return nil, nil
+	}
+	e.expanding[key] = struct{}{}
+	defer delete(e.expanding, key)
+
+	rel, err := e.typesys.GetRelation(tuple.GetType(object), relation)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.usersOfRewrite(ctx, object, relation, rel.GetRewrite(), depth-1)
+}
+
+// usersOfRewrite returns the sorted concrete users that the rewrite of the relation grants on the object.
+func (e *userExpander) usersOfRewrite(ctx context.Context, object, relation string, rewrite *openfgav1.Userset, depth uint32) ([]string, error) {
+	users := map[string]struct{}{}
+
+	switch rw := rewrite.GetUserset().(type) {
+	case *openfgav1.Userset_This:
+		tuples, err := e.readTuples(ctx, object, relation)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, t := range tuples {
+			userObject, userRelation := tuple.SplitObjectRelation(t.GetUser())
+			if tuple.IsTypedWildcard(userObject) {
+				continue
+			}
+
+			if userRelation == "" {
+				users[userObject] = struct{}{}
+				continue
+			}
+
+			members, err := e.usersOfRelation(ctx, userObject, userRelation, depth)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, member := range members {
+				users[member] = struct{}{}
+			}
+		}
+	case *openfgav1.Userset_ComputedUserset:
+		return e.usersOfRelation(ctx, object, rw.ComputedUserset.GetRelation(), depth)
+	case *openfgav1.Userset_TupleToUserset:
+		tuples, err := e.readTuples(ctx, object, rw.TupleToUserset.GetTupleset().GetRelation())
+		if err != nil {
+			return nil, err
+		}
+
+		computedRelation := rw.TupleToUserset.GetComputedUserset().GetRelation()
+		for _, t := range tuples {
+			userObject, _ := tuple.SplitObjectRelation(t.GetUser())
+			if _, err := e.typesys.GetRelation(tuple.GetType(userObject), computedRelation); err != nil {
+				continue // skip computed relations on tupleset relationships if they are undefined
+			}
+
+			members, err := e.usersOfRelation(ctx, userObject, computedRelation, depth)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, member := range members {
+				users[member] = struct{}{}
+			}
+		}
+	case *openfgav1.Userset_Union:
+		for _, child := range rw.Union.GetChild() {
+			childUsers, err := e.usersOfRewrite(ctx, object, relation, child, depth)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, user := range childUsers {
+				users[user] = struct{}{}
+			}
+		}
+	case *openfgav1.Userset_Intersection:
+		for i, child := range rw.Intersection.GetChild() {
+			childUsers, err := e.usersOfRewrite(ctx, object, relation, child, depth)
+			if err != nil {
+				return nil, err
+			}
+
+			if i == 0 {
+				for _, user := range childUsers {
+					users[user] = struct{}{}
+				}
+				continue
+			}
+
+			intersected := make(map[string]struct{}, len(childUsers))
+			for _, user := range childUsers {
+				if _, ok := users[user]; ok {
+					intersected[user] = struct{}{}
+				}
+			}
+			users = intersected
+		}
+	case *openfgav1.Userset_Difference:
+		baseUsers, err := e.usersOfRewrite(ctx, object, relation, rw.Difference.GetBase(), depth)
+		if err != nil {
+			return nil, err
+		}
+
+		subtractUsers, err := e.usersOfRewrite(ctx, object, relation, rw.Difference.GetSubtract(), depth)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, user := range baseUsers {
+			users[user] = struct{}{}
+		}
+
+		for _, user := range subtractUsers {
+			delete(users, user)
+		}
+	default:
+		panic("unexpected userset rewrite encountered")
+	}
+
+	res := maps.Keys(users)
+	sort.Strings(res)
+
+	return res, nil
+}
+
+// readTuples returns the valid tuples of the relation on the object whose condition, if any, is met.
+func (e *userExpander) readTuples(ctx context.Context, object, relation string) ([]*openfgav1.TupleKey, error) {
+	iter, err := e.ds.Read(ctx, e.req.GetStoreID(), tuple.NewTupleKey(object, relation, ""))
+	if err != nil {
+		return nil, err
+	}
+
+	// filter out invalid tuples yielded by the database iterator
+	filteredIter := storage.NewFilteredTupleKeyIterator(
+		storage.NewTupleKeyIteratorFromTupleIterator(iter),
+		validation.FilterInvalidTuples(e.typesys),
+	)
+	defer filteredIter.Stop()
+
+	var tuples []*openfgav1.TupleKey
+	for {
+		t, err := filteredIter.Next(ctx)
+		if err != nil {
+			if errors.Is(err, storage.ErrIteratorDone) {
+				return tuples, nil
+			}
+
+			return nil, err
+		}
+
+		conditionMet, err := e.conditionMet(ctx, t)
+		if err != nil {
+			return nil, err
+		}
+
+		if conditionMet {
+			tuples = append(tuples, t)
+		}
+	}
+}
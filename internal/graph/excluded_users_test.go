@@ -0,0 +1,81 @@
+package graph
+
+import (
+	"context"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/testutils"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+)
+
+func TestListExcludedUsers(t *testing.T) {
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	storeID := ulid.Make().String()
+
+	err := ds.Write(context.Background(), storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:public", "viewer", "user:*"),
+		tuple.NewTupleKey("document:public", "blocked", "user:jon"),
+		tuple.NewTupleKey("document:public", "blocked", "user:maria"),
+		tuple.NewTupleKey("document:public", "blocked", "group:contractors#member"),
+		tuple.NewTupleKey("document:public", "blocked", "employee:anne"),
+		tuple.NewTupleKey("group:contractors", "member", "user:bob"),
+		tuple.NewTupleKey("group:contractors", "member", "user:jon"),
+
+		tuple.NewTupleKey("document:private", "viewer", "user:jon"),
+		tuple.NewTupleKey("document:private", "blocked", "user:maria"),
+	})
+	require.NoError(t, err)
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type employee
+		type group
+			relations
+				define member: [user]
+		type document
+			relations
+				define viewer: [user, user:*, employee]
+				define blocked: [user, employee, group#member]
+				define can_view: viewer but not blocked`)
+
+	ctx := typesystem.ContextWithTypesystem(context.Background(), typesystem.New(model))
+	ctx = storage.ContextWithRelationshipTupleReader(ctx, ds)
+
+	listExcludedUsers := func(object, relation string) ([]string, error) {
+		return ListExcludedUsers(ctx, &ResolveCheckRequest{
+			StoreID:         storeID,
+			TupleKey:        tuple.NewTupleKey(object, relation, ""),
+			RequestMetadata: NewCheckRequestMetadata(25),
+		})
+	}
+
+	t.Run("blocked_users_with_wildcard_access", func(t *testing.T) {
+		users, err := listExcludedUsers("document:public", "can_view")
+		require.NoError(t, err)
+		// employee:anne is blocked but has no wildcard access (only 'user:*' is granted)
+		require.Equal(t, []string{"user:bob", "user:jon", "user:maria"}, users)
+	})
+
+	t.Run("no_wildcard_access", func(t *testing.T) {
+		users, err := listExcludedUsers("document:private", "can_view")
+		require.NoError(t, err)
+		require.Empty(t, users)
+	})
+
+	t.Run("relation_not_exclusion", func(t *testing.T) {
+		_, err := listExcludedUsers("document:public", "viewer")
+		require.ErrorIs(t, err, ErrRelationNotExclusion)
+	})
+}
@@ -0,0 +1,485 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+
+	"github.com/openfga/openfga/internal/condition"
+	"github.com/openfga/openfga/internal/condition/eval"
+	"github.com/openfga/openfga/internal/validation"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+)
+
+// CheckPath is a chain of relationships through which a user is granted a relation on an object.
+// Each step is represented as a tuple key. Steps that traverse a relationship tuple hold that tuple,
+// while steps that follow a computed userset rewrite of the model (e.g. 'define viewer: editor') hold
+// the relationship implied between the two usersets (e.g. 'document:1#viewer@document:1#editor').
+type CheckPath []*openfgav1.TupleKey
+
+// String renders the steps of the path separated by arrows.
+func (p CheckPath) String() string {
+	steps := make([]string, 0, len(p))
+	for _, step := range p {
+		steps = append(steps, tuple.TupleKeyToString(step))
+	}
+
+	return strings.Join(steps, " -> ")
+}
+
+// ExplainCheckResponse is the result of ExplainCheck.
+type ExplainCheckResponse struct {
+	Allowed bool
+	// Paths holds the distinct paths found that grant access. It is empty if Allowed is false.
+	Paths []CheckPath
+	// Text is a sentence explaining the decision through the first path, e.g. 'user:jon is a viewer of
+	// document:1 because user:jon is a member of group:eng, and every member of group:eng is a viewer of
+	// document:1'. It is only set with WithExplanationText.
+	Text string
+}
+
+type checkExplainer struct {
+	maxPaths int
+	withText bool
+}
+
+// ExplainCheckOption defines an option that can be used to change the behavior of ExplainCheck.
+type ExplainCheckOption func(*checkExplainer)
+
+// WithAllPaths makes ExplainCheck return up to maxPaths distinct paths granting access,
+// instead of only the first path found.
+func WithAllPaths(maxPaths uint32) ExplainCheckOption {
+	return func(e *checkExplainer) {
+		e.maxPaths = int(maxPaths)
+	}
+}
+
+// WithExplanationText makes ExplainCheck render the decision as a human-readable sentence in the Text
+// field of the response.
+func WithExplanationText() ExplainCheckOption {
+	return func(e *checkExplainer) {
+		e.withText = true
+	}
+}
+
+// ExplainCheck resolves the provided Check request and reports the paths through which access is granted.
+// By default only the first path found is returned, see WithAllPaths.
+//
+// Unlike the LocalChecker, ExplainCheck does not short-circuit on the first satisfying branch when more
+// paths are requested, so it is meant for debugging and analysis rather than for the request path.
+// The typesystem and the relationship tuple reader are read from the context.
+func ExplainCheck(ctx context.Context, req *ResolveCheckRequest, opts ...ExplainCheckOption) (*ExplainCheckResponse, error) {
+	e := &checkExplainer{
+		maxPaths: 1,
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	if e.maxPaths < 1 {
+		e.maxPaths = 1
+	}
+
+	typesys, ok := typesystem.TypesystemFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("typesystem missing in context")
+	}
+
+	ds, ok := storage.RelationshipTupleReaderFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("relationship tuple reader datastore missing in context")
+	}
+
+	r := &pathResolver{
+		typesys: typesys,
+		ds:      ds,
+		req:     req,
+		visited: map[string]struct{}{},
+	}
+
+	paths, err := r.resolve(ctx, req.GetTupleKey(), req.GetRequestMetadata().Depth, e.maxPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &ExplainCheckResponse{
+		Allowed: len(paths) > 0,
+		Paths:   paths,
+	}
+
+	if e.withText {
+		resp.Text = explanationText(req.GetTupleKey(), paths)
+	}
+
+	return resp, nil
+}
+
+// explanationText renders the decision for tk through the first of the paths granting it. The steps of the
+// path are described starting from the user, so that the sentence reads as a chain of memberships, e.g. for
+// the path 'document:1#viewer@group:eng#member -> group:eng#member@user:jon':
+//
+//	user:jon is a viewer of document:1 because user:jon is a member of group:eng,
+//	and every member of group:eng is a viewer of document:1
+func explanationText(tk *openfgav1.TupleKey, paths []CheckPath) string {
+	if len(paths) == 0 {
+		return fmt.Sprintf("%s is not %s of %s", tk.GetUser(), withArticle(tk.GetRelation()), tk.GetObject())
+	}
+
+	path := paths[0]
+	clauses := make([]string, 0, len(path))
+	for i := len(path) - 1; i >= 0; i-- {
+		step := path[i]
+		granted := fmt.Sprintf("%s of %s", withArticle(step.GetRelation()), step.GetObject())
+
+		userObject, userRelation := tuple.SplitObjectRelation(step.GetUser())
+		switch {
+		case tuple.IsTypedWildcard(userObject):
+			clauses = append(clauses, fmt.Sprintf("every %s is %s", tuple.GetType(userObject), granted))
+		case userRelation != "":
+			clauses = append(clauses, fmt.Sprintf("every %s of %s is %s", userRelation, userObject, granted))
+		default:
+			clauses = append(clauses, fmt.Sprintf("%s is %s", userObject, granted))
+		}
+	}
+
+	return fmt.Sprintf("%s is %s of %s because %s",
+		tk.GetUser(), withArticle(tk.GetRelation()), tk.GetObject(), strings.Join(clauses, ", and "))
+}
+
+// withArticle prefixes the relation name with the indefinite article, e.g. 'a viewer' or 'an editor'.
+func withArticle(relation string) string {
+	if relation != "" && strings.ContainsRune("aeiou", rune(relation[0])) {
+		return "an " + relation
+	}
+
+	return "a " + relation
+}
+
+// pathResolver enumerates the paths granting a relationship. It is not safe for concurrent use.
+type pathResolver struct {
+	typesys *typesystem.TypeSystem
+	ds      storage.RelationshipTupleReader
+	req     *ResolveCheckRequest
+	// visited holds the tuple keys on the path currently being explored, to break cycles.
+	visited map[string]struct{}
+}
+
+// resolve returns up to limit distinct paths granting 'tk'.
+func (r *pathResolver) resolve(ctx context.Context, tk *openfgav1.TupleKey, depth uint32, limit int) ([]CheckPath, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if depth == 0 {
+		return nil, ErrResolutionDepthExceeded
+	}
+
+	object := tk.GetObject()
+	relation := tk.GetRelation()
+
+	// document:1#viewer@document:1#viewer is always granted
+	userObject, userRelation := tuple.SplitObjectRelation(tk.GetUser())
+	if relation == userRelation && object == userObject {
+		return []CheckPath{{}}, nil
+	}
+
+	key := tuple.TupleKeyToString(tk)
+	if _, ok := r.visited[key]; ok {
+		return nil, nil
+	}
+	r.visited[key] = struct{}{}
+	defer delete(r.visited, key)
+
+	rel, err := r.typesys.GetRelation(tuple.GetType(object), relation)
+	if err != nil {
+		return nil, fmt.Errorf("relation '%s' undefined for object type '%s'", relation, tuple.GetType(object))
+	}
+
+	return r.resolveRewrite(ctx, tk, rel.GetRewrite(), depth, limit)
+}
+
+func (r *pathResolver) resolveRewrite(
+	ctx context.Context,
+	tk *openfgav1.TupleKey,
+	rewrite *openfgav1.Userset,
+	depth uint32,
+	limit int,
+) ([]CheckPath, error) {
+	switch rw := rewrite.GetUserset().(type) {
+	case *openfgav1.Userset_This:
+		return r.resolveDirect(ctx, tk, depth, limit)
+	case *openfgav1.Userset_ComputedUserset:
+		computedRelation := rw.ComputedUserset.GetRelation()
+		step := tuple.NewTupleKey(tk.GetObject(), tk.GetRelation(), tuple.ToObjectRelationString(tk.GetObject(), computedRelation))
+
+		subpaths, err := r.resolve(ctx, tuple.NewTupleKey(tk.GetObject(), computedRelation, tk.GetUser()), depth-1, limit)
+		if err != nil {
+			return nil, err
+		}
+
+		return prependStep(step, subpaths), nil
+	case *openfgav1.Userset_TupleToUserset:
+		return r.resolveTTU(ctx, tk, rw, depth, limit)
+	case *openfgav1.Userset_Union:
+		var paths []CheckPath
+		for _, child := range rw.Union.GetChild() {
+			childPaths, err := r.resolveRewrite(ctx, tk, child, depth, limit-len(paths))
+			if err != nil {
+				return nil, err
+			}
+
+			paths = appendDistinct(paths, childPaths...)
+			if len(paths) >= limit {
+				break
+			}
+		}
+
+		return paths, nil
+	case *openfgav1.Userset_Intersection:
+		children := rw.Intersection.GetChild()
+
+		paths, err := r.resolveRewrite(ctx, tk, children[0], depth, limit)
+		if err != nil || len(paths) == 0 {
+			return nil, err
+		}
+
+		// every other child must grant access as well, one path each is enough to show it
+		var rest CheckPath
+		for _, child := range children[1:] {
+			childPaths, err := r.resolveRewrite(ctx, tk, child, depth, 1)
+			if err != nil || len(childPaths) == 0 {
+				return nil, err
+			}
+
+			rest = append(rest, childPaths[0]...)
+		}
+
+		for i := range paths {
+			paths[i] = append(paths[i], rest...)
+		}
+
+		return paths, nil
+	case *openfgav1.Userset_Difference:
+		subtractPaths, err := r.resolveRewrite(ctx, tk, rw.Difference.GetSubtract(), depth, 1)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(subtractPaths) > 0 {
+			return nil, nil
+		}
+
+		return r.resolveRewrite(ctx, tk, rw.Difference.GetBase(), depth, limit)
+	default:
+		panic("unexpected userset rewrite encountered")
+	}
+}
+
+func (r *pathResolver) resolveDirect(ctx context.Context, tk *openfgav1.TupleKey, depth uint32, limit int) ([]CheckPath, error) {
+	storeID := r.req.GetStoreID()
+	objectType := tuple.GetType(tk.GetObject())
+	relation := tk.GetRelation()
+
+	var paths []CheckPath
+
+	isDirectlyRelated, _ := r.typesys.IsDirectlyRelated(
+		typesystem.DirectRelationReference(objectType, relation),
+		typesystem.DirectRelationReference(tuple.GetType(tk.GetUser()), tuple.GetRelation(tk.GetUser())),
+	)
+
+	if isDirectlyRelated {
+		t, err := r.ds.ReadUserTuple(ctx, storeID, tk)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return nil, err
+		}
+
+		if t != nil && validation.ValidateTuple(r.typesys, t.GetKey()) == nil {
+			conditionMet, err := r.conditionMet(ctx, t.GetKey())
+			if err != nil {
+				return nil, err
+			}
+
+			if conditionMet {
+				paths = append(paths, CheckPath{t.GetKey()})
+				if len(paths) >= limit {
+					return paths, nil
+				}
+			}
+		}
+	}
+
+	directlyRelatedUsersetTypes, _ := r.typesys.DirectlyRelatedUsersets(objectType, relation)
+	if len(directlyRelatedUsersetTypes) == 0 {
+		return paths, nil
+	}
+
+	iter, err := r.ds.ReadUsersetTuples(ctx, storeID, storage.ReadUsersetTuplesFilter{
+		Object:                      tk.GetObject(),
+		Relation:                    relation,
+		AllowedUserTypeRestrictions: directlyRelatedUsersetTypes,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// filter out invalid tuples yielded by the database iterator
+	filteredIter := storage.NewFilteredTupleKeyIterator(
+		storage.NewTupleKeyIteratorFromTupleIterator(iter),
+		validation.FilterInvalidTuples(r.typesys),
+	)
+	defer filteredIter.Stop()
+
+	for len(paths) < limit {
+		t, err := filteredIter.Next(ctx)
+		if err != nil {
+			if errors.Is(err, storage.ErrIteratorDone) {
+				break
+			}
+
+			return nil, err
+		}
+
+		conditionMet, err := r.conditionMet(ctx, t)
+		if err != nil {
+			return nil, err
+		}
+
+		if !conditionMet {
+			continue
+		}
+
+		usersetObject, usersetRelation := tuple.SplitObjectRelation(t.GetUser())
+
+		if tuple.IsTypedWildcard(usersetObject) {
+			if tuple.GetType(tk.GetUser()) == tuple.GetType(usersetObject) {
+				paths = appendDistinct(paths, CheckPath{t})
+			}
+
+			continue
+		}
+
+		if usersetRelation == "" {
+			continue
+		}
+
+		subpaths, err := r.resolve(ctx, tuple.NewTupleKey(usersetObject, usersetRelation, tk.GetUser()), depth-1, limit-len(paths))
+		if err != nil {
+			return nil, err
+		}
+
+		paths = appendDistinct(paths, prependStep(t, subpaths)...)
+	}
+
+	return paths, nil
+}
+
+func (r *pathResolver) resolveTTU(
+	ctx context.Context,
+	tk *openfgav1.TupleKey,
+	rewrite *openfgav1.Userset_TupleToUserset,
+	depth uint32,
+	limit int,
+) ([]CheckPath, error) {
+	tuplesetRelation := rewrite.TupleToUserset.GetTupleset().GetRelation()
+	computedRelation := rewrite.TupleToUserset.GetComputedUserset().GetRelation()
+
+	iter, err := r.ds.Read(ctx, r.req.GetStoreID(), tuple.NewTupleKey(tk.GetObject(), tuplesetRelation, ""))
+	if err != nil {
+		return nil, err
+	}
+
+	// filter out invalid tuples yielded by the database iterator
+	filteredIter := storage.NewFilteredTupleKeyIterator(
+		storage.NewTupleKeyIteratorFromTupleIterator(iter),
+		validation.FilterInvalidTuples(r.typesys),
+	)
+	defer filteredIter.Stop()
+
+	var paths []CheckPath
+	for len(paths) < limit {
+		t, err := filteredIter.Next(ctx)
+		if err != nil {
+			if errors.Is(err, storage.ErrIteratorDone) {
+				break
+			}
+
+			return nil, err
+		}
+
+		conditionMet, err := r.conditionMet(ctx, t)
+		if err != nil {
+			return nil, err
+		}
+
+		if !conditionMet {
+			continue
+		}
+
+		userObj, _ := tuple.SplitObjectRelation(t.GetUser())
+		if _, err := r.typesys.GetRelation(tuple.GetType(userObj), computedRelation); err != nil {
+			continue // skip computed relations on tupleset relationships if they are undefined
+		}
+
+		subpaths, err := r.resolve(ctx, tuple.NewTupleKey(userObj, computedRelation, tk.GetUser()), depth-1, limit-len(paths))
+		if err != nil {
+			return nil, err
+		}
+
+		paths = appendDistinct(paths, prependStep(t, subpaths)...)
+	}
+
+	return paths, nil
+}
+
+// conditionMet evaluates the condition of the tuple, if any, against the context of the request.
+func (r *pathResolver) conditionMet(ctx context.Context, t *openfgav1.TupleKey) (bool, error) {
+	condEvalResult, err := eval.EvaluateTupleCondition(ctx, t, r.typesys, r.req.GetContext())
+	if err != nil {
+		return false, err
+	}
+
+	if len(condEvalResult.MissingParameters) > 0 {
+		return false, condition.NewEvaluationError(
+			t.GetCondition().GetName(),
+			fmt.Errorf("tuple '%s' is missing context parameters '%v'",
+				tuple.TupleKeyToString(t),
+				condEvalResult.MissingParameters),
+		)
+	}
+
+	return condEvalResult.ConditionMet, nil
+}
+
+func prependStep(step *openfgav1.TupleKey, paths []CheckPath) []CheckPath {
+	res := make([]CheckPath, 0, len(paths))
+	for _, p := range paths {
+		res = append(res, append(CheckPath{step}, p...))
+	}
+
+	return res
+}
+
+// appendDistinct appends the paths that are not already present in dst.
+func appendDistinct(dst []CheckPath, paths ...CheckPath) []CheckPath {
+	for _, p := range paths {
+		duplicate := false
+		for _, existing := range dst {
+			if existing.String() == p.String() {
+				duplicate = true
+				break
+			}
+		}
+
+		if !duplicate {
+			dst = append(dst, p)
+		}
+	}
+
+	return dst
+}
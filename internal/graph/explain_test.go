@@ -0,0 +1,158 @@
+package graph
+
+import (
+	"context"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/testutils"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+)
+
+func TestExplainCheck(t *testing.T) {
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	storeID := ulid.Make().String()
+
+	err := ds.Write(context.Background(), storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+		tuple.NewTupleKey("document:1", "viewer", "group:eng#member"),
+		tuple.NewTupleKey("group:eng", "member", "user:jon"),
+		tuple.NewTupleKey("group:eng", "member", "user:maria"),
+		tuple.NewTupleKey("document:1", "parent", "folder:x"),
+		tuple.NewTupleKey("folder:x", "viewer", "user:maria"),
+		tuple.NewTupleKey("document:1", "blocked", "user:maria"),
+	})
+	require.NoError(t, err)
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type group
+			relations
+				define member: [user]
+		type folder
+			relations
+				define viewer: [user]
+		type document
+			relations
+				define parent: [folder]
+				define blocked: [user]
+				define viewer: [user, group#member]
+				define can_view: viewer or viewer from parent
+				define can_view_unless_blocked: can_view but not blocked`)
+
+	ctx := typesystem.ContextWithTypesystem(context.Background(), typesystem.New(model))
+	ctx = storage.ContextWithRelationshipTupleReader(ctx, ds)
+
+	explain := func(t *testing.T, tk *openfgav1.TupleKey, opts ...ExplainCheckOption) *ExplainCheckResponse {
+		resp, err := ExplainCheck(ctx, &ResolveCheckRequest{
+			StoreID:         storeID,
+			TupleKey:        tk,
+			RequestMetadata: NewCheckRequestMetadata(25),
+		}, opts...)
+		require.NoError(t, err)
+		return resp
+	}
+
+	paths := func(resp *ExplainCheckResponse) []string {
+		var res []string
+		for _, p := range resp.Paths {
+			res = append(res, p.String())
+		}
+		return res
+	}
+
+	t.Run("returns_first_path_by_default", func(t *testing.T) {
+		resp := explain(t, tuple.NewTupleKey("document:1", "viewer", "user:jon"))
+		require.True(t, resp.Allowed)
+		require.Len(t, resp.Paths, 1)
+	})
+
+	t.Run("all_paths_returns_direct_grant_and_group", func(t *testing.T) {
+		resp := explain(t, tuple.NewTupleKey("document:1", "viewer", "user:jon"), WithAllPaths(10))
+		require.True(t, resp.Allowed)
+		require.ElementsMatch(t, []string{
+			"document:1#viewer@user:jon",
+			"document:1#viewer@group:eng#member -> group:eng#member@user:jon",
+		}, paths(resp))
+	})
+
+	t.Run("all_paths_is_capped", func(t *testing.T) {
+		resp := explain(t, tuple.NewTupleKey("document:1", "viewer", "user:jon"), WithAllPaths(1))
+		require.True(t, resp.Allowed)
+		require.Len(t, resp.Paths, 1)
+	})
+
+	t.Run("computed_userset_and_tuple_to_userset", func(t *testing.T) {
+		resp := explain(t, tuple.NewTupleKey("document:1", "can_view", "user:maria"), WithAllPaths(10))
+		require.True(t, resp.Allowed)
+		require.ElementsMatch(t, []string{
+			"document:1#can_view@document:1#viewer -> document:1#viewer@group:eng#member -> group:eng#member@user:maria",
+			"document:1#parent@folder:x -> folder:x#viewer@user:maria",
+		}, paths(resp))
+	})
+
+	t.Run("exclusion", func(t *testing.T) {
+		resp := explain(t, tuple.NewTupleKey("document:1", "can_view_unless_blocked", "user:jon"), WithAllPaths(10))
+		require.True(t, resp.Allowed)
+		require.Len(t, resp.Paths, 2)
+
+		resp = explain(t, tuple.NewTupleKey("document:1", "can_view_unless_blocked", "user:maria"), WithAllPaths(10))
+		require.False(t, resp.Allowed)
+		require.Empty(t, resp.Paths)
+	})
+
+	t.Run("text_for_group_inherited_grant", func(t *testing.T) {
+		resp := explain(t, tuple.NewTupleKey("document:1", "viewer", "user:maria"), WithExplanationText())
+		require.True(t, resp.Allowed)
+		require.Equal(t, "user:maria is a viewer of document:1 because user:maria is a member of group:eng, "+
+			"and every member of group:eng is a viewer of document:1", resp.Text)
+	})
+
+	t.Run("text_for_computed_userset_and_tuple_to_userset", func(t *testing.T) {
+		resp := explain(t, tuple.NewTupleKey("document:1", "can_view", "user:jon"), WithExplanationText())
+		require.True(t, resp.Allowed)
+		require.Equal(t, "user:jon is a can_view of document:1 because user:jon is a viewer of document:1, "+
+			"and every viewer of document:1 is a can_view of document:1", resp.Text)
+
+		resp = explain(t, tuple.NewTupleKey("document:1", "can_view", "user:maria"), WithAllPaths(10), WithExplanationText())
+		require.True(t, resp.Allowed)
+		require.Equal(t, explanationText(tuple.NewTupleKey("document:1", "can_view", "user:maria"), resp.Paths), resp.Text)
+
+		require.Equal(t, "user:maria is a can_view of document:1 because user:maria is a viewer of folder:x, "+
+			"and folder:x is a parent of document:1", explanationText(
+			tuple.NewTupleKey("document:1", "can_view", "user:maria"),
+			[]CheckPath{{
+				tuple.NewTupleKey("document:1", "parent", "folder:x"),
+				tuple.NewTupleKey("folder:x", "viewer", "user:maria"),
+			}},
+		))
+	})
+
+	t.Run("text_is_only_rendered_on_demand", func(t *testing.T) {
+		resp := explain(t, tuple.NewTupleKey("document:1", "viewer", "user:maria"))
+		require.Empty(t, resp.Text)
+	})
+
+	t.Run("text_for_denied", func(t *testing.T) {
+		resp := explain(t, tuple.NewTupleKey("document:1", "viewer", "user:bob"), WithExplanationText())
+		require.False(t, resp.Allowed)
+		require.Equal(t, "user:bob is not a viewer of document:1", resp.Text)
+	})
+
+	t.Run("denied", func(t *testing.T) {
+		resp := explain(t, tuple.NewTupleKey("document:1", "viewer", "user:bob"), WithAllPaths(10))
+		require.False(t, resp.Allowed)
+		require.Empty(t, resp.Paths)
+	})
+}
@@ -10,6 +10,7 @@ import (
 
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 
+	"github.com/openfga/openfga/pkg/storage"
 	"github.com/openfga/openfga/pkg/tuple"
 	"github.com/openfga/openfga/pkg/typesystem"
 )
@@ -18,12 +19,259 @@ type ctxKey string
 
 const (
 	resolutionDepthCtxKey ctxKey = "resolution-depth"
+	workerPoolCtxKey      ctxKey = "dispatch-worker-pool"
 )
 
 var (
 	ErrResolutionDepthExceeded = errors.New("resolution depth exceeded")
+
+	// ErrRequestDeadlineExceeded is returned, wrapping context.DeadlineExceeded, when the deadline of the context
+	// expires while a Check is being resolved.
+	ErrRequestDeadlineExceeded = errors.New("request deadline exceeded")
+
+	// ErrVisitedPathsLimitExceeded is returned when the number of paths visited while resolving
+	// a single Check exceeds the limit configured with WithMaxVisitedPaths.
+	ErrVisitedPathsLimitExceeded = errors.New("visited paths limit exceeded")
+
+	// ErrInvalidTupleKey is returned, wrapped in an InvalidTupleKeyError, when the tuple key of a Check
+	// request is missing or malformed.
+	ErrInvalidTupleKey = errors.New("invalid tuple key")
+
+	// ErrConditionEvaluationsLimitExceeded is returned when the number of tuple conditions evaluated while
+	// resolving a single Check exceeds the limit configured with WithMaxConditionEvaluations.
+	ErrConditionEvaluationsLimitExceeded = errors.New("condition evaluations limit exceeded")
+
+	// ErrUnassignableRelation is returned when the direct relationships of a relation without directly
+	// related user types are evaluated, and the LocalChecker was configured with WithUnassignableRelationError.
+	ErrUnassignableRelation = errors.New("relation has no directly related user types")
+
+	// ErrUndefinedComputedRelation is returned, wrapped in an UndefinedComputedRelationError, when a tupleset
+	// relation leads to an object whose type does not define the computed relation, and the LocalChecker was
+	// configured with WithUndefinedComputedRelationError.
+	ErrUndefinedComputedRelation = errors.New("computed relation undefined on the related type")
 )
 
+// InvalidTupleKeyError describes which part of the tuple key of a Check request is missing or malformed.
+type InvalidTupleKeyError struct {
+	// Field is the part of the tuple key that is invalid: 'object', 'relation' or 'user'.
+	Field string
+	// Value is the invalid value of Field.
+	Value string
+}
+
+func (e *InvalidTupleKeyError) Error() string {
+	if e.Value == "" {
+		return fmt.Sprintf("%s: the '%s' field is missing", ErrInvalidTupleKey, e.Field)
+	}
+
+	return fmt.Sprintf("%s: the '%s' field '%s' is malformed", ErrInvalidTupleKey, e.Field, e.Value)
+}
+
+func (e *InvalidTupleKeyError) Unwrap() error {
+	return ErrInvalidTupleKey
+}
+
+// BatchResolveCheckError is returned by BatchResolveCheck when some of the requests of the batch failed.
+type BatchResolveCheckError struct {
+	// Errors holds the error of each request of the batch, in the order of the requests, or nil for the
+	// requests that succeeded.
+	Errors []error
+}
+
+func (e *BatchResolveCheckError) Error() string {
+	failed := 0
+	var first error
+	for _, err := range e.Errors {
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			failed++
+		}
+	}
+
+	return fmt.Sprintf("%d of %d checks failed, first error: %s", failed, len(e.Errors), first)
+}
+
+func (e *BatchResolveCheckError) Unwrap() []error {
+	var errs []error
+	for _, err := range e.Errors {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// ResolutionDepthExceededError is returned by ResolveCheck when a Check exceeds the resolve node limit
+// of its request, see ResolveCheckRequestMetadata.ResolveNodeLimit.
+type ResolutionDepthExceededError struct {
+	// Limit is the resolve node limit of the Check, or 0 if the request did not record it.
+	Limit uint32
+}
+
+func (e *ResolutionDepthExceededError) Error() string {
+	if e.Limit == 0 {
+		return ErrResolutionDepthExceeded.Error()
+	}
+
+	return fmt.Sprintf("%s: limit of %d", ErrResolutionDepthExceeded, e.Limit)
+}
+
+// Unwrap returns ErrResolutionDepthExceeded.
+func (e *ResolutionDepthExceededError) Unwrap() error {
+	return ErrResolutionDepthExceeded
+}
+
+// ResolveCheckErrorCode classifies, in a machine-readable way, why the resolution of a Check failed.
+type ResolveCheckErrorCode string
+
+const (
+	// ResolveCheckErrorResolutionDepthExceeded is the code of a Check that exceeded its resolve node limit.
+	ResolveCheckErrorResolutionDepthExceeded ResolveCheckErrorCode = "resolution_depth_exceeded"
+	// ResolveCheckErrorVisitedPathsLimitExceeded is the code of a Check that visited more paths than allowed,
+	// see WithMaxVisitedPaths.
+	ResolveCheckErrorVisitedPathsLimitExceeded ResolveCheckErrorCode = "visited_paths_limit_exceeded"
+	// ResolveCheckErrorDeadlineExceeded is the code of a Check whose deadline expired.
+	ResolveCheckErrorDeadlineExceeded ResolveCheckErrorCode = "deadline_exceeded"
+	// ResolveCheckErrorThrottledTimeout is the code of a Check whose deadline expired after some of its
+	// dispatches were throttled.
+	ResolveCheckErrorThrottledTimeout ResolveCheckErrorCode = "throttled_timeout"
+	// ResolveCheckErrorCancelled is the code of a Check whose context was cancelled, e.g. by the client.
+	ResolveCheckErrorCancelled ResolveCheckErrorCode = "cancelled"
+	// ResolveCheckErrorDatastoreTimeout is the code of a Check that failed because a datastore query
+	// exceeded the per-query timeout, see storage.ErrStorageTimeout.
+	ResolveCheckErrorDatastoreTimeout ResolveCheckErrorCode = "datastore_timeout"
+)
+
+// ResolveCheckError is returned by the CheckResolver chain when the resolution of a Check fails for one of
+// the reasons classified by a ResolveCheckErrorCode. It wraps the original error, and records the subproblem
+// that failed and the dispatch metadata of the Check at the time. It is created by the resolver the failure
+// happens in, and the resolvers it goes back through return it as is.
+type ResolveCheckError struct {
+	Code ResolveCheckErrorCode
+	// TupleKey is the tuple key of the subproblem that failed, e.g. 'group:eng#member@user:jon'.
+	TupleKey string
+	// Depth is the number of dispatches between the Check and the subproblem that failed.
+	Depth uint32
+	// DispatchCount is the number of subproblems that had been dispatched when the subproblem failed.
+	DispatchCount uint32
+	// DatastoreQueryCount is the number of datastore queries made before the subproblem was dispatched.
+	DatastoreQueryCount uint32
+	// Throttled is true if some of the dispatches of the Check were throttled.
+	Throttled bool
+
+	Err error
+}
+
+func (e *ResolveCheckError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the original error.
+func (e *ResolveCheckError) Unwrap() error {
+	return e.Err
+}
+
+// NewResolveCheckError wraps err in a ResolveCheckError for the subproblem req, if err is one of the failures
+// classified by a ResolveCheckErrorCode and it was not already wrapped by a deeper subproblem. Otherwise, err
+// is returned as is. The resolvers call it where the failures happen, and the callers of the CheckResolver
+// chain may call it to classify the failures that happened before the request reached a resolver, e.g. while
+// it was waiting to be dispatched.
+func NewResolveCheckError(req *ResolveCheckRequest, err error) error {
+	var resolveErr *ResolveCheckError
+	if err == nil || errors.As(err, &resolveErr) {
+		return err
+	}
+
+	metadata := req.GetRequestMetadata()
+	throttled := metadata != nil && metadata.WasThrottled != nil && metadata.WasThrottled.Load()
+
+	var code ResolveCheckErrorCode
+	switch {
+	case errors.Is(err, ErrResolutionDepthExceeded):
+		code = ResolveCheckErrorResolutionDepthExceeded
+	case errors.Is(err, ErrVisitedPathsLimitExceeded):
+		code = ResolveCheckErrorVisitedPathsLimitExceeded
+	case errors.Is(err, storage.ErrStorageTimeout):
+		code = ResolveCheckErrorDatastoreTimeout
+	case errors.Is(err, context.DeadlineExceeded) && throttled:
+		code = ResolveCheckErrorThrottledTimeout
+	case errors.Is(err, context.DeadlineExceeded):
+		code = ResolveCheckErrorDeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		code = ResolveCheckErrorCancelled
+	default:
+		return err
+	}
+
+	resolveErr = &ResolveCheckError{
+		Code:      code,
+		TupleKey:  tuple.TupleKeyToString(req.GetTupleKey()),
+		Throttled: throttled,
+		Err:       err,
+	}
+	if metadata != nil {
+		if metadata.ResolveNodeLimit >= metadata.Depth {
+			resolveErr.Depth = metadata.ResolveNodeLimit - metadata.Depth
+		}
+		if metadata.DispatchCounter != nil {
+			resolveErr.DispatchCount = metadata.DispatchCounter.Load()
+		}
+		resolveErr.DatastoreQueryCount = metadata.DatastoreQueryCount
+	}
+
+	return resolveErr
+}
+
+// UndefinedComputedRelationError describes a tuple to userset rewrite, e.g. 'viewer from parent' of 'document#viewer',
+// that led to an object whose type does not define the computed relation.
+type UndefinedComputedRelationError struct {
+	// Relation is the 'objectType#relation' string of the relation being resolved, e.g. 'document#viewer'.
+	Relation string
+	// TuplesetRelation is the 'objectType#relation' string of the tupleset relation, e.g. 'document#parent'.
+	TuplesetRelation string
+	// RelatedType is the type of the object the tupleset relation led to, e.g. 'team'.
+	RelatedType string
+	// ComputedRelation is the relation that RelatedType does not define, e.g. 'viewer'.
+	ComputedRelation string
+}
+
+func (e *UndefinedComputedRelationError) Error() string {
+	return fmt.Sprintf("%s: '%s' resolves '%s' through '%s', but '%s' is undefined",
+		ErrUndefinedComputedRelation, e.Relation, e.ComputedRelation, e.TuplesetRelation,
+		tuple.ToObjectRelationString(e.RelatedType, e.ComputedRelation))
+}
+
+// Unwrap returns ErrUndefinedComputedRelation.
+func (e *UndefinedComputedRelationError) Unwrap() error {
+	return ErrUndefinedComputedRelation
+}
+
+// validateCheckTupleKey ensures the tuple key of a Check request has a typed object, a relation and a typed user
+// (e.g. 'document:1#viewer@user:jon', 'document:1#viewer@user:*' or 'document:1#viewer@group:eng#member').
+func validateCheckTupleKey(tk *openfgav1.TupleKey) error {
+	object := tk.GetObject()
+	if objectType, objectID := tuple.SplitObject(object); objectType == "" || objectID == "" {
+		return &InvalidTupleKeyError{Field: "object", Value: object}
+	}
+
+	relation := tk.GetRelation()
+	if relation == "" || strings.ContainsAny(relation, ":#@") {
+		return &InvalidTupleKeyError{Field: "relation", Value: relation}
+	}
+
+	user := tk.GetUser()
+	userObject, userRelation := tuple.SplitObjectRelation(user)
+	userType, userID := tuple.SplitObject(userObject)
+	if userType == "" || userID == "" || (strings.Contains(user, "#") && userRelation == "") {
+		return &InvalidTupleKeyError{Field: "user", Value: user}
+	}
+
+	return nil
+}
+
 type findEdgeOption int
 
 const (
@@ -48,6 +296,10 @@ type ResolveCheckRequestMetadata struct {
 	// When we jump one level, we decrement 1. If it hits 0, we throw ErrResolutionDepthExceeded.
 	Depth uint32
 
+	// ResolveNodeLimit is the depth the Check started with, i.e. the resolve node limit of the request.
+	// It is reported by the ResolutionDepthExceededError returned once Depth hits 0.
+	ResolveNodeLimit uint32
+
 	// Number of calls to ReadUserTuple + ReadUsersetTuples + Read accumulated so far, before this request is solved.
 	DatastoreQueryCount uint32
 
@@ -59,14 +311,21 @@ type ResolveCheckRequestMetadata struct {
 
 	// WasThrottled indicates whether the request was throttled
 	WasThrottled *atomic.Bool
+
+	// ConditionEvaluationCounter is the address to a shared counter that keeps track of how many tuple conditions
+	// were evaluated to solve the root/parent problem, see WithMaxConditionEvaluations.
+	ConditionEvaluationCounter *atomic.Uint32
 }
 
 func NewCheckRequestMetadata(maxDepth uint32) *ResolveCheckRequestMetadata {
 	return &ResolveCheckRequestMetadata{
 		Depth:               maxDepth,
+		ResolveNodeLimit:    maxDepth,
 		DatastoreQueryCount: 0,
 		DispatchCounter:     new(atomic.Uint32),
 		WasThrottled:        new(atomic.Bool),
+
+		ConditionEvaluationCounter: new(atomic.Uint32),
 	}
 }
 
@@ -141,15 +400,51 @@ func (r RelationshipEdge) String() string {
 // RelationshipGraph represents a graph of relationships and the connectivity between
 // object and relation references within the graph through direct or indirect relationships.
 type RelationshipGraph struct {
-	typesystem *typesystem.TypeSystem
+	typesystem                 *typesystem.TypeSystem
+	intersectionOperandChooser IntersectionOperandChooser
+}
+
+// IntersectionOperandChooser returns the index of the operand of the intersection rewriting the relation
+// of the object type that GetPrunedRelationshipEdges should resolve. The other operands are left for
+// further evaluation.
+type IntersectionOperandChooser func(objectType, relation string, operands []*openfgav1.Userset) int
+
+type RelationshipGraphOption func(*RelationshipGraph)
+
+// WithIntersectionOperandChooser sets the function choosing which operand of an intersection
+// GetPrunedRelationshipEdges resolves. By default, the first operand is resolved.
+func WithIntersectionOperandChooser(chooser IntersectionOperandChooser) RelationshipGraphOption {
+	return func(g *RelationshipGraph) {
+		g.intersectionOperandChooser = chooser
+	}
 }
 
 // New returns a RelationshipGraph from an authorization model. The RelationshipGraph should be used to introspect what kind of relationships between
 // object types can exist. To visualize this graph, use https://github.com/jon-whit/openfga-graphviz-gen
-func New(typesystem *typesystem.TypeSystem) *RelationshipGraph {
-	return &RelationshipGraph{
+func New(typesystem *typesystem.TypeSystem, opts ...RelationshipGraphOption) *RelationshipGraph {
+	g := &RelationshipGraph{
 		typesystem: typesystem,
 	}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	return g
+}
+
+// chooseIntersectionOperand returns the operand of the intersection that GetPrunedRelationshipEdges resolves.
+func (g *RelationshipGraph) chooseIntersectionOperand(target *openfgav1.RelationReference, operands []*openfgav1.Userset) *openfgav1.Userset {
+	if g.intersectionOperandChooser == nil {
+		return operands[0]
+	}
+
+	i := g.intersectionOperandChooser(target.GetType(), target.GetRelation(), operands)
+	if i < 0 || i >= len(operands) {
+		return operands[0]
+	}
+
+	return operands[i]
 }
 
 // GetRelationshipEdges finds all paths from a source to a target and then returns all the edges at distance 0 or 1 of the source in those paths.
@@ -159,7 +454,8 @@ func (g *RelationshipGraph) GetRelationshipEdges(target *openfgav1.RelationRefer
 
 // GetPrunedRelationshipEdges finds all paths from a source to a target and then returns all the edges at distance 0 or 1 of the source in those paths.
 // If the edges from the source to the target pass through a relationship involving intersection or exclusion (directly or indirectly),
-// then GetPrunedRelationshipEdges will just return the first-most edge involved in that rewrite.
+// then GetPrunedRelationshipEdges will just return the first-most edge involved in that rewrite. For intersections, the operand
+// resolved can be chosen with WithIntersectionOperandChooser.
 //
 // Consider the following model:
 //
@@ -337,7 +633,7 @@ func (g *RelationshipGraph) getRelationshipEdgesWithTargetRewrite(
 	case *openfgav1.Userset_Intersection:
 
 		if findEdgeOption == resolveAnyEdge {
-			child := t.Intersection.GetChild()[0]
+			child := g.chooseIntersectionOperand(target, t.Intersection.GetChild())
 
 			childresults, err := g.getRelationshipEdgesWithTargetRewrite(target, source, child, visited, findEdgeOption)
 			if err != nil {
@@ -427,7 +723,7 @@ func (g *RelationshipGraph) getRelationshipEdgesWithTargetRewrite(
 //					CycleDetectionCheckResolver -|
 //
 // The returned CheckResolverCloser should be used to close all resolvers involved in the
-// composition after you are done with the CheckResolver.
+// composition after you are done with the CheckResolver. See NewOrderedCheckResolvers to order the layers.
 func NewLayeredCheckResolver(
 	localResolverOpts []LocalCheckerOption,
 	cacheEnabled bool,
@@ -435,41 +731,12 @@ func NewLayeredCheckResolver(
 	cachedResolverOpts []CachedCheckResolverOpt,
 	dispatchThrottlingCheckResolverOpts []DispatchThrottlingCheckResolverOpt,
 ) (CheckResolver, CheckResolverCloser) {
-	cycleDetectionCheckResolver := NewCycleDetectionCheckResolver()
-	var cachedCheckResolver *CachedCheckResolver
-	var dispatchThrottlingCheckResolver *DispatchThrottlingCheckResolver
-	localCheckResolver := NewLocalChecker(localResolverOpts...)
-
-	cycleDetectionCheckResolver.SetDelegate(localCheckResolver)
-	localCheckResolver.SetDelegate(cycleDetectionCheckResolver)
-
-	if cacheEnabled {
-		cachedCheckResolver = NewCachedCheckResolver(cachedResolverOpts...)
-		cachedCheckResolver.SetDelegate(localCheckResolver)
-		cycleDetectionCheckResolver.SetDelegate(cachedCheckResolver)
-	}
-
-	if throttlingEnabled {
-		dispatchThrottlingCheckResolver = NewDispatchThrottlingCheckResolver(dispatchThrottlingCheckResolverOpts...)
-		dispatchThrottlingCheckResolver.SetDelegate(localCheckResolver)
-		if cacheEnabled {
-			cachedCheckResolver.SetDelegate(dispatchThrottlingCheckResolver)
-		} else {
-			cycleDetectionCheckResolver.SetDelegate(dispatchThrottlingCheckResolver)
-		}
-	}
-
-	return cycleDetectionCheckResolver, func() {
-		localCheckResolver.Close()
-
-		if cachedCheckResolver != nil {
-			cachedCheckResolver.Close()
-		}
-
-		if dispatchThrottlingCheckResolver != nil {
-			dispatchThrottlingCheckResolver.Close()
-		}
-
-		cycleDetectionCheckResolver.Close()
-	}
+	// the default order is always valid
+	checkResolver, closer, _ := NewOrderedCheckResolvers(
+		WithLocalCheckerOpts(localResolverOpts...),
+		WithCachedCheckResolverOpts(cacheEnabled, cachedResolverOpts...),
+		WithDispatchThrottlingCheckResolverOpts(throttlingEnabled, dispatchThrottlingCheckResolverOpts...),
+	).Build()
+
+	return checkResolver, closer
 }
@@ -283,6 +283,72 @@ func TestPrunedRelationshipEdges(t *testing.T) {
 	}
 }
 
+func TestPrunedRelationshipEdgesWithIntersectionOperandChooser(t *testing.T) {
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type document
+			relations
+				define allowed: [user]
+				define viewer: [user] and allowed`)
+	typesys := typesystem.New(model)
+
+	target := typesystem.DirectRelationReference("document", "viewer")
+	source := typesystem.DirectRelationReference("user", "")
+
+	cmpOpts := []cmp.Option{
+		cmpopts.IgnoreUnexported(openfgav1.RelationReference{}),
+		RelationshipEdgeTransformer,
+	}
+
+	t.Run("chosen_operand_is_resolved", func(t *testing.T) {
+		var chosen []string
+		g := New(typesys, WithIntersectionOperandChooser(func(objectType, relation string, operands []*openfgav1.Userset) int {
+			chosen = append(chosen, objectType+"#"+relation)
+			require.Len(t, operands, 2)
+			return 1
+		}))
+
+		edges, err := g.GetPrunedRelationshipEdges(target, source)
+		require.NoError(t, err)
+		require.Equal(t, []string{"document#viewer"}, chosen)
+
+		expected := []*RelationshipEdge{
+			{
+				Type:            DirectEdge,
+				TargetReference: typesystem.DirectRelationReference("document", "allowed"),
+				TargetReferenceInvolvesIntersectionOrExclusion: true,
+			},
+		}
+		if diff := cmp.Diff(expected, edges, cmpOpts...); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("out_of_range_choice_resolves_the_first_operand", func(t *testing.T) {
+		g := New(typesys, WithIntersectionOperandChooser(func(string, string, []*openfgav1.Userset) int {
+			return 2
+		}))
+
+		edges, err := g.GetPrunedRelationshipEdges(target, source)
+		require.NoError(t, err)
+
+		expected := []*RelationshipEdge{
+			{
+				Type:            DirectEdge,
+				TargetReference: typesystem.DirectRelationReference("document", "viewer"),
+				TargetReferenceInvolvesIntersectionOrExclusion: true,
+			},
+		}
+		if diff := cmp.Diff(expected, edges, cmpOpts...); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
 func TestRelationshipEdges(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -26,6 +26,15 @@ type CheckResolver interface {
 	// The return values may be nil and an error, or non-nil and an error.
 	ResolveCheck(ctx context.Context, req *ResolveCheckRequest) (*ResolveCheckResponse, error)
 
+	// BatchResolveCheck resolves the parent problems of many requests against the typesystem and the
+	// [[storage.RelationshipTupleReader]] of the context, which are shared by all of them. The requests must
+	// not share their RequestMetadata or VisitedPaths.
+	//
+	// The responses are in the order of the requests. The failure of a request does not abort the others: its
+	// response is nil, and a *BatchResolveCheckError holding the error of every request is returned along with
+	// the responses of the requests that succeeded.
+	BatchResolveCheck(ctx context.Context, reqs []*ResolveCheckRequest) ([]*ResolveCheckResponse, error)
+
 	// Close releases resources. It must be called after the CheckResolver is done processing all requests.
 	Close()
 }
@@ -39,6 +39,21 @@ func (m *MockCheckResolver) EXPECT() *MockCheckResolverMockRecorder {
 	return m.recorder
 }
 
+// BatchResolveCheck mocks base method.
+func (m *MockCheckResolver) BatchResolveCheck(ctx context.Context, reqs []*ResolveCheckRequest) ([]*ResolveCheckResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BatchResolveCheck", ctx, reqs)
+	ret0, _ := ret[0].([]*ResolveCheckResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BatchResolveCheck indicates an expected call of BatchResolveCheck.
+func (mr *MockCheckResolverMockRecorder) BatchResolveCheck(ctx, reqs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchResolveCheck", reflect.TypeOf((*MockCheckResolver)(nil).BatchResolveCheck), ctx, reqs)
+}
+
 // Close mocks base method.
 func (m *MockCheckResolver) Close() {
 	m.ctrl.T.Helper()
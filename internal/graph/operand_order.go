@@ -0,0 +1,79 @@
+package graph
+
+import (
+	"cmp"
+	"slices"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+
+	"github.com/openfga/openfga/pkg/tuple"
+)
+
+// RelationWeigher returns the estimated cost of evaluating a relation of an object type, e.g. the number of
+// tuples it may read, and false if it has no estimate for it. It may be derived from the typesystem or from
+// datastore statistics. See WithRelationWeigher.
+type RelationWeigher func(objectType, relation string) (uint64, bool)
+
+// operandWeight returns the weight of an operand of the rewrite of the relation of the tuple key. The direct
+// relationships are weighed as the relation itself, and a tuple to userset as its tupleset relation. Nested
+// set operations have no weight.
+func operandWeight(weigher RelationWeigher, tk *openfgav1.TupleKey, operand *openfgav1.Userset) (uint64, bool) {
+	objectType := tuple.GetType(tk.GetObject())
+
+	switch rw := operand.GetUserset().(type) {
+	case *openfgav1.Userset_This:
+		return weigher(objectType, tk.GetRelation())
+	case *openfgav1.Userset_ComputedUserset:
+		return weigher(objectType, rw.ComputedUserset.GetRelation())
+	case *openfgav1.Userset_TupleToUserset:
+		return weigher(objectType, rw.TupleToUserset.GetTupleset().GetRelation())
+	default:
+		return 0, false
+	}
+}
+
+// orderOperands returns the operands of the rewrite of the relation of the tuple key sorted by ascending weight,
+// followed by the operands without a weight. Operands of equal weight, and those without one, keep their order.
+func orderOperands(weigher RelationWeigher, tk *openfgav1.TupleKey, operands []*openfgav1.Userset) []*openfgav1.Userset {
+	type weightedOperand struct {
+		operand  *openfgav1.Userset
+		weight   uint64
+		weighted bool
+	}
+
+	weightedOperands := make([]weightedOperand, 0, len(operands))
+	for _, operand := range operands {
+		weight, ok := operandWeight(weigher, tk, operand)
+		weightedOperands = append(weightedOperands, weightedOperand{operand: operand, weight: weight, weighted: ok})
+	}
+
+	slices.SortStableFunc(weightedOperands, func(a, b weightedOperand) int {
+		if a.weighted != b.weighted {
+			if a.weighted {
+				return -1
+			}
+			return 1
+		}
+
+		return cmp.Compare(a.weight, b.weight)
+	})
+
+	ordered := make([]*openfgav1.Userset, 0, len(operands))
+	for _, weightedOperand := range weightedOperands {
+		ordered = append(ordered, weightedOperand.operand)
+	}
+
+	return ordered
+}
+
+// subtractIsCheaper returns true if both operands of an exclusion have a weight and the subtracted one is lighter.
+func subtractIsCheaper(weigher RelationWeigher, tk *openfgav1.TupleKey, base, subtract *openfgav1.Userset) bool {
+	baseWeight, ok := operandWeight(weigher, tk, base)
+	if !ok {
+		return false
+	}
+
+	subtractWeight, ok := operandWeight(weigher, tk, subtract)
+
+	return ok && subtractWeight < baseWeight
+}
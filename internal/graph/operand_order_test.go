@@ -0,0 +1,201 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/testutils"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+)
+
+// expensiveGroups is the number of groups related to document:1#expensive, each read to resolve it.
+const expensiveGroups = 100
+
+func staticRelationWeigher(weights map[string]uint64) RelationWeigher {
+	return func(objectType, relation string) (uint64, bool) {
+		weight, ok := weights[tuple.ToObjectRelationString(objectType, relation)]
+		return weight, ok
+	}
+}
+
+func setupOperandOrderTest(t testing.TB) (context.Context, string) {
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	tuples := []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "cheap", "user:bob"),
+		tuple.NewTupleKey("document:1", "blocked", "user:bob"),
+	}
+	for i := 0; i < expensiveGroups; i++ {
+		tuples = append(tuples, tuple.NewTupleKey("document:1", "expensive", fmt.Sprintf("group:%d#member", i)))
+	}
+	tuples = append(tuples, tuple.NewTupleKey("group:0", "member", "user:bob"))
+	require.NoError(t, ds.Write(ctx, storeID, nil, tuples))
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type group
+			relations
+				define member: [user]
+		type document
+			relations
+				define expensive: [group#member]
+				define cheap: [user]
+				define blocked: [user]
+				define both: expensive and cheap
+				define unless_blocked: expensive but not blocked`)
+
+	ctx = typesystem.ContextWithTypesystem(ctx, typesystem.New(model))
+	ctx = storage.ContextWithRelationshipTupleReader(ctx, ds)
+
+	return ctx, storeID
+}
+
+func TestOrderOperands(t *testing.T) {
+	weigher := staticRelationWeigher(map[string]uint64{
+		"document#viewer": 10,
+		"document#editor": 5,
+		"document#parent": 5,
+	})
+
+	this := typesystem.This()
+	editor := typesystem.ComputedUserset("editor")
+	parent := typesystem.TupleToUserset("parent", "viewer")
+	owner := typesystem.ComputedUserset("owner")
+	nested := typesystem.Union(typesystem.This(), typesystem.ComputedUserset("owner"))
+
+	tk := tuple.NewTupleKey("document:1", "viewer", "user:jon")
+
+	t.Run("sorted_by_weight", func(t *testing.T) {
+		ordered := orderOperands(weigher, tk, []*openfgav1.Userset{this, editor, parent})
+		require.Equal(t, []*openfgav1.Userset{editor, parent, this}, ordered)
+	})
+
+	t.Run("operands_without_weight_last", func(t *testing.T) {
+		ordered := orderOperands(weigher, tk, []*openfgav1.Userset{nested, owner, this, editor})
+		require.Equal(t, []*openfgav1.Userset{editor, this, nested, owner}, ordered)
+	})
+
+	t.Run("model_order_without_weights", func(t *testing.T) {
+		noWeights := staticRelationWeigher(nil)
+		operands := []*openfgav1.Userset{this, editor, parent}
+		require.Equal(t, operands, orderOperands(noWeights, tk, operands))
+
+		require.False(t, subtractIsCheaper(noWeights, tk, this, editor))
+	})
+
+	t.Run("subtract_is_cheaper", func(t *testing.T) {
+		require.True(t, subtractIsCheaper(weigher, tk, this, editor))
+		require.False(t, subtractIsCheaper(weigher, tk, editor, this))
+		require.False(t, subtractIsCheaper(weigher, tk, editor, parent))
+		require.False(t, subtractIsCheaper(weigher, tk, nested, editor))
+	})
+}
+
+func TestCheckWithRelationWeigher(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx, storeID := setupOperandOrderTest(t)
+
+	weigher := staticRelationWeigher(map[string]uint64{
+		"document#expensive": expensiveGroups,
+		"document#cheap":     1,
+		"document#blocked":   1,
+	})
+
+	tests := []struct {
+		name    string
+		tk      *openfgav1.TupleKey
+		allowed bool
+	}{
+		{name: "intersection_allowed", tk: tuple.NewTupleKey("document:1", "both", "user:bob"), allowed: true},
+		{name: "intersection_denied", tk: tuple.NewTupleKey("document:1", "both", "user:jon")},
+		{name: "exclusion_denied_by_subtract", tk: tuple.NewTupleKey("document:1", "unless_blocked", "user:bob")},
+		{name: "exclusion_denied_by_base", tk: tuple.NewTupleKey("document:1", "unless_blocked", "user:jon")},
+	}
+
+	for _, weighted := range []bool{false, true} {
+		opts := []LocalCheckerOption{WithResolveNodeBreadthLimit(1)}
+		if weighted {
+			opts = append(opts, WithRelationWeigher(weigher))
+		}
+
+		checker := NewLocalChecker(opts...)
+		t.Cleanup(checker.Close)
+
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("%s/weighted_%t", test.name, weighted), func(t *testing.T) {
+				resp, err := checker.ResolveCheck(ctx, &ResolveCheckRequest{
+					StoreID:         storeID,
+					TupleKey:        test.tk,
+					RequestMetadata: NewCheckRequestMetadata(defaultResolveNodeLimit),
+				})
+				require.NoError(t, err)
+				require.Equal(t, test.allowed, resp.GetAllowed())
+			})
+		}
+	}
+
+	t.Run("cheap_operand_short_circuits", func(t *testing.T) {
+		checker := NewLocalChecker(WithResolveNodeBreadthLimit(1), WithRelationWeigher(weigher))
+		t.Cleanup(checker.Close)
+
+		resp, err := checker.ResolveCheck(ctx, &ResolveCheckRequest{
+			StoreID:         storeID,
+			TupleKey:        tuple.NewTupleKey("document:1", "both", "user:jon"),
+			RequestMetadata: NewCheckRequestMetadata(defaultResolveNodeLimit),
+		})
+		require.NoError(t, err)
+		require.False(t, resp.GetAllowed())
+		require.Equal(t, uint32(1), resp.GetResolutionMetadata().DatastoreQueryCount)
+	})
+}
+
+// BenchmarkCheckWithRelationWeigher measures an intersection whose first operand in the model is expensive,
+// while the second one is cheap and denies the request.
+func BenchmarkCheckWithRelationWeigher(b *testing.B) {
+	ctx, storeID := setupOperandOrderTest(b)
+
+	weigher := staticRelationWeigher(map[string]uint64{
+		"document#expensive": expensiveGroups,
+		"document#cheap":     1,
+	})
+
+	for _, weighted := range []bool{false, true} {
+		b.Run(fmt.Sprintf("weighted_%t", weighted), func(b *testing.B) {
+			opts := []LocalCheckerOption{WithResolveNodeBreadthLimit(1)}
+			if weighted {
+				opts = append(opts, WithRelationWeigher(weigher))
+			}
+
+			checker := NewLocalChecker(opts...)
+			b.Cleanup(checker.Close)
+
+			for i := 0; i < b.N; i++ {
+				_, err := checker.ResolveCheck(ctx, &ResolveCheckRequest{
+					StoreID:         storeID,
+					TupleKey:        tuple.NewTupleKey("document:1", "both", "user:jon"),
+					RequestMetadata: NewCheckRequestMetadata(defaultResolveNodeLimit),
+				})
+				require.NoError(b, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package graph
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/openfga/openfga/internal/build"
+)
+
+// The operators labeling the operator_dispatch_count metric.
+const (
+	UnionOperator                = "union"
+	IntersectionOperator         = "intersection"
+	ExclusionOperator            = "exclusion"
+	TupleToUsersetOperator       = "tuple_to_userset"
+	ListObjectsCandidateOperator = "list_objects_candidate"
+)
+
+var operatorDispatchCountHistogram = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace:                       build.ProjectName,
+	Name:                            "operator_dispatch_count",
+	Help:                            "The number of subproblems an operator fans out to each time it is evaluated, labeled by operator (union, intersection, exclusion, tuple_to_userset or list_objects_candidate), to guide the tuning of the concurrency limit of each operator.",
+	Buckets:                         []float64{1, 2, 5, 10, 25, 50, 100, 250, 500, 1000},
+	NativeHistogramBucketFactor:     1.1,
+	NativeHistogramMaxBucketNumber:  100,
+	NativeHistogramMinResetDuration: time.Hour,
+}, []string{"operator"})
+
+// ObserveOperatorDispatchCount records that the operator fanned out to count subproblems. The subproblems
+// evaluated concurrently by the operator are bounded by its concurrency limit, so a count often above the limit
+// means the operator waits on it.
+func ObserveOperatorDispatchCount(operator string, count int) {
+	operatorDispatchCountHistogram.WithLabelValues(operator).Observe(float64(count))
+}
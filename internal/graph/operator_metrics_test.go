@@ -0,0 +1,92 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/testutils"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+)
+
+func operatorDispatchSamples(t *testing.T, operator string) (uint64, float64) {
+	var m dto.Metric
+	require.NoError(t, operatorDispatchCountHistogram.WithLabelValues(operator).(prometheus.Metric).Write(&m))
+	return m.GetHistogram().GetSampleCount(), m.GetHistogram().GetSampleSum()
+}
+
+func TestOperatorConcurrencyLimits(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	t.Run("limits_default_to_the_breadth_limit", func(t *testing.T) {
+		checker := NewLocalChecker(WithResolveNodeBreadthLimit(7), WithUnionConcurrencyLimit(3))
+		require.Equal(t, uint32(3), checker.operatorConcurrencyLimit(checker.unionLimit))
+		require.Equal(t, uint32(7), checker.operatorConcurrencyLimit(checker.ttuLimit))
+	})
+
+	t.Run("operators_report_their_dispatches", func(t *testing.T) {
+		ds := memory.New()
+		t.Cleanup(ds.Close)
+
+		storeID := ulid.Make().String()
+
+		model := testutils.MustTransformDSLToProtoWithID(`
+			model
+				schema 1.1
+
+			type user
+			type folder
+				relations
+					define viewer: [user]
+			type document
+				relations
+					define parent: [folder]
+					define owner: [user]
+					define editor: [user]
+					define viewer: owner or editor or viewer from parent`)
+
+		var tuples []*openfgav1.TupleKey
+		for i := 0; i < 5; i++ {
+			tuples = append(tuples, tuple.NewTupleKey("document:1", "parent", fmt.Sprintf("folder:%d", i)))
+		}
+		tuples = append(tuples, tuple.NewTupleKey("folder:4", "viewer", "user:jon"))
+		require.NoError(t, ds.Write(context.Background(), storeID, nil, tuples))
+
+		checker := NewLocalChecker(WithUnionConcurrencyLimit(1), WithTTUConcurrencyLimit(2))
+		t.Cleanup(checker.Close)
+
+		ctx := typesystem.ContextWithTypesystem(context.Background(), typesystem.New(model))
+		ctx = storage.ContextWithRelationshipTupleReader(ctx, ds)
+
+		unionCount, unionSum := operatorDispatchSamples(t, UnionOperator)
+		ttuCount, ttuSum := operatorDispatchSamples(t, TupleToUsersetOperator)
+
+		resp, err := checker.ResolveCheck(ctx, &ResolveCheckRequest{
+			StoreID:         storeID,
+			TupleKey:        tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+			RequestMetadata: NewCheckRequestMetadata(25),
+		})
+		require.NoError(t, err)
+		require.True(t, resp.GetAllowed())
+
+		count, sum := operatorDispatchSamples(t, UnionOperator)
+		require.Equal(t, unionCount+1, count)
+		require.InDelta(t, unionSum+3, sum, 0)
+
+		count, sum = operatorDispatchSamples(t, TupleToUsersetOperator)
+		require.Equal(t, ttuCount+1, count)
+		require.InDelta(t, ttuSum+5, sum, 0)
+	})
+}
@@ -0,0 +1,293 @@
+package graph
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/openfga/openfga/internal/build"
+	"github.com/openfga/openfga/pkg/logger"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+)
+
+const defaultShadowCheckTimeout = 1 * time.Second
+
+var shadowCheckMismatchCounter = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: build.ProjectName,
+	Name:      "check_shadow_mismatch_count",
+	Help:      "The total number of Check resolutions for which the shadow CheckResolver disagreed with the primary one.",
+})
+
+// ShadowCheckResolver resolves Check requests with a primary CheckResolver, its delegate, and compares the outcomes
+// with the ones of a shadow CheckResolver, e.g. a new implementation being validated, or the same CheckResolver against
+// a candidate authorization model (see WithShadowCheckTypesystem). The responses of the primary CheckResolver are
+// returned, and the shadow CheckResolver resolves a clone of the requests in the background, within its own timeout,
+// so that it never delays nor affects the responses. Disagreements are logged and counted.
+type ShadowCheckResolver struct {
+	delegate   CheckResolver
+	shadow     CheckResolver
+	typesystem ShadowTypesystemFunc
+	timeout    time.Duration
+	logger     logger.Logger
+
+	mismatches atomic.Uint64
+	wg         sync.WaitGroup
+}
+
+var _ CheckResolver = (*ShadowCheckResolver)(nil)
+
+// ShadowCheckResolverOpt defines an option that can be used to change the behavior of ShadowCheckResolver
+// instance.
+type ShadowCheckResolverOpt func(*ShadowCheckResolver)
+
+// WithShadowCheckTimeout sets the time the shadow CheckResolver is given to resolve a request. It defaults to 1s.
+func WithShadowCheckTimeout(timeout time.Duration) ShadowCheckResolverOpt {
+	return func(r *ShadowCheckResolver) {
+		r.timeout = timeout
+	}
+}
+
+// ShadowTypesystemFunc returns the typesystem of the candidate authorization model to resolve the requests of the
+// store against, in place of the model with the given ID, or nil if the requests of the store are not shadowed. It is
+// called for every request, so it should be cheap for the stores without a candidate model.
+type ShadowTypesystemFunc func(ctx context.Context, storeID, modelID string) (*typesystem.TypeSystem, error)
+
+// WithShadowCheckTypesystem makes the shadow CheckResolver resolve the requests against the candidate authorization
+// models returned by fn, e.g. to validate a refactored model with the production requests before switching to it.
+// The requests of the stores without a candidate model are only resolved by the primary CheckResolver.
+func WithShadowCheckTypesystem(fn ShadowTypesystemFunc) ShadowCheckResolverOpt {
+	return func(r *ShadowCheckResolver) {
+		r.typesystem = fn
+	}
+}
+
+// WithShadowCheckLogger sets the logger the mismatches are logged with.
+func WithShadowCheckLogger(l logger.Logger) ShadowCheckResolverOpt {
+	return func(r *ShadowCheckResolver) {
+		r.logger = l
+	}
+}
+
+// NewShadowCheckResolver constructs a ShadowCheckResolver returning the responses of the primary CheckResolver
+// and comparing them with the ones of the shadow CheckResolver.
+func NewShadowCheckResolver(primary, shadow CheckResolver, opts ...ShadowCheckResolverOpt) *ShadowCheckResolver {
+	r := &ShadowCheckResolver{
+		delegate: primary,
+		shadow:   shadow,
+		timeout:  defaultShadowCheckTimeout,
+		logger:   logger.NewNoopLogger(),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+func (r *ShadowCheckResolver) SetDelegate(delegate CheckResolver) {
+	r.delegate = delegate
+}
+
+func (r *ShadowCheckResolver) GetDelegate() CheckResolver {
+	return r.delegate
+}
+
+// Mismatches returns the number of requests for which the shadow CheckResolver disagreed with the primary one.
+func (r *ShadowCheckResolver) Mismatches() uint64 {
+	return r.mismatches.Load()
+}
+
+// Close waits for the shadow resolutions in flight. It does not close the primary nor the shadow CheckResolver.
+func (r *ShadowCheckResolver) Close() {
+	r.wg.Wait()
+}
+
+// ResolveCheck implements CheckResolver.
+func (r *ShadowCheckResolver) ResolveCheck(ctx context.Context, req *ResolveCheckRequest) (*ResolveCheckResponse, error) {
+	// the request is cloned before the primary CheckResolver updates its visited paths and metadata
+	shadowReq := cloneShadowRequest(req)
+
+	resp, err := r.delegate.ResolveCheck(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	shadowTypesys, ok := r.shadowTypesystem(ctx, shadowReq.GetStoreID(), shadowReq.GetAuthorizationModelID())
+	if !ok {
+		return resp, nil
+	}
+	if shadowTypesys != nil {
+		shadowReq.AuthorizationModelID = shadowTypesys.GetAuthorizationModelID()
+	}
+
+	modelID := req.GetAuthorizationModelID()
+	allowed := resp.GetAllowed()
+	r.runShadow(ctx, shadowTypesys, func(ctx, shadowCtx context.Context) {
+		shadowResp, err := r.shadow.ResolveCheck(shadowCtx, shadowReq)
+		if err != nil {
+			r.logger.Debug("shadow check failed", zap.String("store_id", shadowReq.GetStoreID()), zap.Error(err))
+			return
+		}
+
+		r.compare(ctx, shadowCtx, modelID, shadowReq, allowed, shadowResp.GetAllowed())
+	})
+
+	return resp, nil
+}
+
+// BatchResolveCheck implements CheckResolver. The shadow CheckResolver resolves the batch at once, and the requests
+// that failed with either CheckResolver are not compared.
+func (r *ShadowCheckResolver) BatchResolveCheck(ctx context.Context, reqs []*ResolveCheckRequest) ([]*ResolveCheckResponse, error) {
+	shadowReqs := make([]*ResolveCheckRequest, 0, len(reqs))
+	for _, req := range reqs {
+		shadowReqs = append(shadowReqs, cloneShadowRequest(req))
+	}
+
+	resps, err := r.delegate.BatchResolveCheck(ctx, reqs)
+	if resps == nil || len(reqs) == 0 {
+		return resps, err
+	}
+
+	// the requests of a batch are all resolved against the typesystem of the context, so they share the
+	// store and the model
+	shadowTypesys, ok := r.shadowTypesystem(ctx, reqs[0].GetStoreID(), reqs[0].GetAuthorizationModelID())
+	if !ok {
+		return resps, err
+	}
+	if shadowTypesys != nil {
+		for _, shadowReq := range shadowReqs {
+			shadowReq.AuthorizationModelID = shadowTypesys.GetAuthorizationModelID()
+		}
+	}
+
+	// the responses are returned to the caller, so only their outcome is kept
+	modelID := reqs[0].GetAuthorizationModelID()
+	resolved := make([]bool, len(resps))
+	allowed := make([]bool, len(resps))
+	for i, resp := range resps {
+		resolved[i] = resp != nil
+		allowed[i] = resp.GetAllowed()
+	}
+
+	r.runShadow(ctx, shadowTypesys, func(ctx, shadowCtx context.Context) {
+		// the responses of the requests that succeeded are returned along with the error of the others
+		shadowResps, _ := r.shadow.BatchResolveCheck(shadowCtx, shadowReqs)
+		for i, shadowResp := range shadowResps {
+			if shadowResp == nil || i >= len(resolved) || !resolved[i] {
+				continue
+			}
+
+			r.compare(ctx, shadowCtx, modelID, shadowReqs[i], allowed[i], shadowResp.GetAllowed())
+		}
+	})
+
+	return resps, err
+}
+
+// shadowTypesystem returns the typesystem of the candidate model the requests of the store are shadowed against, or
+// nil if they are shadowed against the same model. It returns false if the requests of the store are not shadowed.
+func (r *ShadowCheckResolver) shadowTypesystem(ctx context.Context, storeID, modelID string) (*typesystem.TypeSystem, bool) {
+	if r.typesystem == nil {
+		return nil, true
+	}
+
+	typesys, err := r.typesystem(ctx, storeID, modelID)
+	if err != nil {
+		r.logger.Debug("failed to resolve the shadow authorization model", zap.String("store_id", storeID), zap.Error(err))
+		return nil, false
+	}
+
+	return typesys, typesys != nil
+}
+
+// runShadow runs fn in the background with a context keeping the values of ctx, such as the typesystem and the
+// tuple reader, but bounded by the timeout of the shadow resolutions instead of the deadline of ctx. The shadow
+// context passed to fn also holds the typesystem of the candidate model, if any.
+func (r *ShadowCheckResolver) runShadow(ctx context.Context, shadowTypesys *typesystem.TypeSystem, fn func(ctx, shadowCtx context.Context)) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.timeout)
+		defer cancel()
+
+		shadowCtx := ctx
+		if shadowTypesys != nil {
+			shadowCtx = typesystem.ContextWithTypesystem(ctx, shadowTypesys)
+		}
+
+		fn(ctx, shadowCtx)
+	}()
+}
+
+// compare counts and logs a disagreement between the primary and the shadow CheckResolver on the shadow request,
+// along with the path granting access in the model of the one that allowed the request, to point at the part of
+// the models they disagree on.
+func (r *ShadowCheckResolver) compare(ctx, shadowCtx context.Context, modelID string, req *ResolveCheckRequest, allowed, shadowAllowed bool) {
+	if allowed == shadowAllowed {
+		return
+	}
+
+	r.mismatches.Add(1)
+	shadowCheckMismatchCounter.Inc()
+
+	fields := []zap.Field{
+		zap.String("store_id", req.GetStoreID()),
+		zap.String("authorization_model_id", modelID),
+		zap.String("tuple_key", tuple.TupleKeyToString(req.GetTupleKey())),
+		zap.Bool("primary_allowed", allowed),
+		zap.Bool("shadow_allowed", shadowAllowed),
+	}
+
+	if req.GetAuthorizationModelID() != modelID {
+		fields = append(fields, zap.String("shadow_authorization_model_id", req.GetAuthorizationModelID()))
+	}
+
+	explainCtx := ctx
+	if shadowAllowed {
+		explainCtx = shadowCtx
+	}
+	if path := grantingPath(explainCtx, req); path != "" {
+		fields = append(fields, zap.String("granting_path", path))
+	}
+
+	r.logger.Warn("shadow check mismatch", fields...)
+}
+
+// grantingPath returns the first path granting the request in the typesystem of the context, or an empty string if
+// it cannot be found, e.g. because the tuples changed since the request was resolved.
+func grantingPath(ctx context.Context, req *ResolveCheckRequest) string {
+	explanation, err := ExplainCheck(ctx, &ResolveCheckRequest{
+		StoreID:              req.GetStoreID(),
+		AuthorizationModelID: req.GetAuthorizationModelID(),
+		TupleKey:             req.GetTupleKey(),
+		ContextualTuples:     req.GetContextualTuples(),
+		Context:              req.GetContext(),
+		RequestMetadata:      NewCheckRequestMetadata(req.GetRequestMetadata().ResolveNodeLimit),
+	})
+	if err != nil || len(explanation.Paths) == 0 {
+		return ""
+	}
+
+	return explanation.Paths[0].String()
+}
+
+// cloneShadowRequest clones the request with its own visited paths and request metadata, so that the counters of the
+// shadow resolution are not added to the ones of the primary resolution.
+func cloneShadowRequest(req *ResolveCheckRequest) *ResolveCheckRequest {
+	shadowReq := clone(req)
+
+	metadata := NewCheckRequestMetadata(req.GetRequestMetadata().ResolveNodeLimit)
+	metadata.Depth = req.GetRequestMetadata().Depth
+	metadata.DatastoreQueryCount = req.GetRequestMetadata().DatastoreQueryCount
+	shadowReq.RequestMetadata = metadata
+
+	return shadowReq
+}
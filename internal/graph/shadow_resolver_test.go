@@ -0,0 +1,240 @@
+package graph
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/openfga/openfga/pkg/logger"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/testutils"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+)
+
+func TestShadowCheckResolver(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	newRequest := func() *ResolveCheckRequest {
+		return &ResolveCheckRequest{
+			StoreID:              "store",
+			AuthorizationModelID: "model",
+			TupleKey:             tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+			RequestMetadata:      NewCheckRequestMetadata(defaultResolveNodeLimit),
+			VisitedPaths:         map[string]struct{}{},
+		}
+	}
+
+	t.Run("mismatch_is_logged_and_counted", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		primary := NewMockCheckResolver(ctrl)
+		shadow := NewMockCheckResolver(ctrl)
+
+		observerLogger, logs := observer.New(zap.WarnLevel)
+		r := NewShadowCheckResolver(primary, shadow, WithShadowCheckLogger(&logger.ZapLogger{Logger: zap.New(observerLogger)}))
+
+		req := newRequest()
+		primary.EXPECT().ResolveCheck(gomock.Any(), req).DoAndReturn(
+			func(_ context.Context, req *ResolveCheckRequest) (*ResolveCheckResponse, error) {
+				req.VisitedPaths["document:1#viewer@user:jon"] = struct{}{}
+				req.GetRequestMetadata().DispatchCounter.Add(1)
+				return &ResolveCheckResponse{Allowed: true, ResolutionMetadata: &ResolveCheckResponseMetadata{}}, nil
+			})
+		shadow.EXPECT().ResolveCheck(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, shadowReq *ResolveCheckRequest) (*ResolveCheckResponse, error) {
+				// the shadow request shares neither the visited paths nor the metadata of the primary one
+				assert.Empty(t, shadowReq.VisitedPaths)
+				assert.NotSame(t, req.GetRequestMetadata(), shadowReq.GetRequestMetadata())
+				assert.Zero(t, shadowReq.GetRequestMetadata().DispatchCounter.Load())
+				return &ResolveCheckResponse{Allowed: false, ResolutionMetadata: &ResolveCheckResponseMetadata{}}, nil
+			})
+
+		resp, err := r.ResolveCheck(context.Background(), req)
+		require.NoError(t, err)
+		require.True(t, resp.GetAllowed())
+
+		r.Close()
+		require.Equal(t, uint64(1), r.Mismatches())
+
+		entries := logs.FilterMessage("shadow check mismatch").All()
+		require.Len(t, entries, 1)
+		fields := entries[0].ContextMap()
+		require.Equal(t, "store", fields["store_id"])
+		require.Equal(t, "model", fields["authorization_model_id"])
+		require.Equal(t, "document:1#viewer@user:jon", fields["tuple_key"])
+		require.Equal(t, true, fields["primary_allowed"])
+		require.Equal(t, false, fields["shadow_allowed"])
+	})
+
+	t.Run("agreement_is_not_counted", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		primary := NewMockCheckResolver(ctrl)
+		shadow := NewMockCheckResolver(ctrl)
+
+		r := NewShadowCheckResolver(primary, shadow)
+
+		resp := &ResolveCheckResponse{Allowed: true, ResolutionMetadata: &ResolveCheckResponseMetadata{}}
+		primary.EXPECT().ResolveCheck(gomock.Any(), gomock.Any()).Return(resp, nil)
+		shadow.EXPECT().ResolveCheck(gomock.Any(), gomock.Any()).Return(CloneResolveCheckResponse(resp), nil)
+
+		_, err := r.ResolveCheck(context.Background(), newRequest())
+		require.NoError(t, err)
+
+		r.Close()
+		require.Zero(t, r.Mismatches())
+	})
+
+	t.Run("slow_shadow_does_not_delay_the_response", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		primary := NewMockCheckResolver(ctrl)
+		shadow := NewMockCheckResolver(ctrl)
+
+		r := NewShadowCheckResolver(primary, shadow, WithShadowCheckTimeout(10*time.Millisecond))
+
+		primary.EXPECT().ResolveCheck(gomock.Any(), gomock.Any()).
+			Return(&ResolveCheckResponse{Allowed: true, ResolutionMetadata: &ResolveCheckResponseMetadata{}}, nil)
+		shadow.EXPECT().ResolveCheck(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, _ *ResolveCheckRequest) (*ResolveCheckResponse, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			})
+
+		// the shadow resolution outlives the context of the request
+		ctx, cancel := context.WithCancel(context.Background())
+		resp, err := r.ResolveCheck(ctx, newRequest())
+		cancel()
+		require.NoError(t, err)
+		require.True(t, resp.GetAllowed())
+
+		r.Close()
+		require.Zero(t, r.Mismatches())
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		primary := NewMockCheckResolver(ctrl)
+		shadow := NewMockCheckResolver(ctrl)
+
+		r := NewShadowCheckResolver(primary, shadow)
+
+		allowed := &ResolveCheckResponse{Allowed: true, ResolutionMetadata: &ResolveCheckResponseMetadata{}}
+		denied := &ResolveCheckResponse{Allowed: false, ResolutionMetadata: &ResolveCheckResponseMetadata{}}
+
+		primary.EXPECT().BatchResolveCheck(gomock.Any(), gomock.Any()).
+			Return([]*ResolveCheckResponse{allowed, allowed, nil}, &BatchResolveCheckError{})
+		shadow.EXPECT().BatchResolveCheck(gomock.Any(), gomock.Any()).
+			Return([]*ResolveCheckResponse{allowed, denied, denied}, nil)
+
+		resps, err := r.BatchResolveCheck(context.Background(), []*ResolveCheckRequest{newRequest(), newRequest(), newRequest()})
+		require.Error(t, err)
+		require.Len(t, resps, 3)
+
+		r.Close()
+		require.Equal(t, uint64(1), r.Mismatches())
+	})
+
+	t.Run("candidate_model", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		primary := NewMockCheckResolver(ctrl)
+		shadow := NewMockCheckResolver(ctrl)
+
+		ds := memory.New()
+		t.Cleanup(ds.Close)
+		require.NoError(t, ds.Write(context.Background(), "store", nil, []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:1", "editor", "user:jon"),
+		}))
+
+		current := typesystem.New(testutils.MustTransformDSLToProtoWithID(`
+			model
+				schema 1.1
+			type user
+			type document
+				relations
+					define editor: [user]
+					define viewer: [user]`))
+		candidate := typesystem.New(testutils.MustTransformDSLToProtoWithID(`
+			model
+				schema 1.1
+			type user
+			type document
+				relations
+					define editor: [user]
+					define viewer: [user] or editor`))
+
+		observerLogger, logs := observer.New(zap.WarnLevel)
+		r := NewShadowCheckResolver(primary, shadow,
+			WithShadowCheckLogger(&logger.ZapLogger{Logger: zap.New(observerLogger)}),
+			WithShadowCheckTypesystem(func(_ context.Context, storeID, modelID string) (*typesystem.TypeSystem, error) {
+				if storeID != "store" {
+					return nil, nil
+				}
+				return candidate, nil
+			}),
+		)
+
+		primary.EXPECT().ResolveCheck(gomock.Any(), gomock.Any()).
+			Return(&ResolveCheckResponse{Allowed: false, ResolutionMetadata: &ResolveCheckResponseMetadata{}}, nil).Times(2)
+		shadow.EXPECT().ResolveCheck(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, shadowReq *ResolveCheckRequest) (*ResolveCheckResponse, error) {
+				// the shadow request is resolved against the candidate model
+				typesys, ok := typesystem.TypesystemFromContext(ctx)
+				assert.True(t, ok)
+				assert.Same(t, candidate, typesys)
+				assert.Equal(t, candidate.GetAuthorizationModelID(), shadowReq.GetAuthorizationModelID())
+				return &ResolveCheckResponse{Allowed: true, ResolutionMetadata: &ResolveCheckResponseMetadata{}}, nil
+			})
+
+		ctx := typesystem.ContextWithTypesystem(context.Background(), current)
+		ctx = storage.ContextWithRelationshipTupleReader(ctx, ds)
+
+		req := newRequest()
+		req.AuthorizationModelID = current.GetAuthorizationModelID()
+		resp, err := r.ResolveCheck(ctx, req)
+		require.NoError(t, err)
+		require.False(t, resp.GetAllowed())
+
+		// the requests of the stores without a candidate model are not shadowed
+		otherReq := newRequest()
+		otherReq.StoreID = "other-store"
+		_, err = r.ResolveCheck(ctx, otherReq)
+		require.NoError(t, err)
+
+		r.Close()
+		require.Equal(t, uint64(1), r.Mismatches())
+
+		entries := logs.FilterMessage("shadow check mismatch").All()
+		require.Len(t, entries, 1)
+		fields := entries[0].ContextMap()
+		require.Equal(t, current.GetAuthorizationModelID(), fields["authorization_model_id"])
+		require.Equal(t, candidate.GetAuthorizationModelID(), fields["shadow_authorization_model_id"])
+		require.Equal(t, false, fields["primary_allowed"])
+		require.Equal(t, true, fields["shadow_allowed"])
+		require.Equal(t, "document:1#viewer@document:1#editor -> document:1#editor@user:jon", fields["granting_path"])
+	})
+
+	t.Run("primary_error_is_returned_without_shadow", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		primary := NewMockCheckResolver(ctrl)
+		shadow := NewMockCheckResolver(ctrl)
+
+		r := NewShadowCheckResolver(primary, shadow)
+
+		primary.EXPECT().ResolveCheck(gomock.Any(), gomock.Any()).Return(nil, ErrResolutionDepthExceeded)
+
+		_, err := r.ResolveCheck(context.Background(), newRequest())
+		require.ErrorIs(t, err, ErrResolutionDepthExceeded)
+
+		r.Close()
+	})
+}
@@ -0,0 +1,118 @@
+package graph
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// dispatchWorkerIdleTimeout is how long an idle worker of a workerPool waits for a new function before exiting.
+const dispatchWorkerIdleTimeout = 10 * time.Second
+
+// workerPool runs functions on reusable goroutines, to avoid creating and tearing down a goroutine for every
+// subproblem evaluated concurrently. Go never blocks: if no worker is idle, a new one is started, so nested
+// evaluations waiting for each other cannot deadlock. At most maxIdle workers are kept waiting for work.
+type workerPool struct {
+	tasks       chan func()
+	maxIdle     int32
+	idle        atomic.Int32
+	idleTimeout time.Duration
+	// started is the number of workers started since the pool was created
+	started atomic.Uint64
+
+	mu     sync.Mutex
+	closed bool // GUARDED_BY(mu)
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+func newWorkerPool(maxIdle uint32, idleTimeout time.Duration) *workerPool {
+	return &workerPool{
+		tasks:       make(chan func()),
+		maxIdle:     int32(maxIdle),
+		idleTimeout: idleTimeout,
+		done:        make(chan struct{}),
+	}
+}
+
+// Go runs fn on an idle worker, or on a new worker if all of them are busy.
+func (p *workerPool) Go(fn func()) {
+	select {
+	case p.tasks <- fn:
+		return
+	default:
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		go fn()
+		return
+	}
+
+	p.wg.Add(1)
+	p.started.Add(1)
+	go p.work(fn)
+}
+
+func (p *workerPool) work(fn func()) {
+	defer p.wg.Done()
+
+	timer := time.NewTimer(p.idleTimeout)
+	defer timer.Stop()
+
+	for {
+		fn()
+
+		if p.idle.Add(1) > p.maxIdle {
+			p.idle.Add(-1)
+			return
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(p.idleTimeout)
+
+		select {
+		case fn = <-p.tasks:
+			p.idle.Add(-1)
+		case <-timer.C:
+			p.idle.Add(-1)
+			return
+		case <-p.done:
+			p.idle.Add(-1)
+			return
+		}
+	}
+}
+
+// Close stops the idle workers and waits for the busy ones to complete. Functions passed to Go
+// after Close run on new goroutines.
+func (p *workerPool) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.done)
+	p.mu.Unlock()
+
+	p.wg.Wait()
+}
+
+// goFunc returns the function starting the concurrent evaluations of a reducer: the Go method of the
+// workerPool stored in the context, if any, and the go statement otherwise.
+func goFunc(ctx context.Context) func(func()) {
+	if pool, ok := ctx.Value(workerPoolCtxKey).(*workerPool); ok {
+		return pool.Go
+	}
+
+	return func(fn func()) { go fn() }
+}
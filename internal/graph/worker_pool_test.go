@@ -0,0 +1,229 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/testutils"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+)
+
+func TestWorkerPool(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	t.Run("reuses_idle_workers", func(t *testing.T) {
+		p := newWorkerPool(1, time.Minute)
+		t.Cleanup(p.Close)
+
+		for i := 0; i < 10; i++ {
+			done := make(chan struct{})
+			p.Go(func() { close(done) })
+			<-done
+
+			// the worker goes back to the pool once the function returns
+			require.Eventually(t, func() bool { return p.idle.Load() == 1 }, time.Second, time.Millisecond)
+		}
+
+		require.Equal(t, uint64(1), p.started.Load())
+	})
+
+	t.Run("keeps_at_most_max_idle_workers", func(t *testing.T) {
+		p := newWorkerPool(2, time.Minute)
+		t.Cleanup(p.Close)
+
+		var wg sync.WaitGroup
+		release := make(chan struct{})
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			p.Go(func() {
+				defer wg.Done()
+				<-release
+			})
+		}
+		close(release)
+		wg.Wait()
+
+		require.Eventually(t, func() bool { return p.idle.Load() == 2 }, time.Second, time.Millisecond)
+		require.Never(t, func() bool { return p.idle.Load() > 2 }, 50*time.Millisecond, time.Millisecond)
+	})
+
+	t.Run("nested_functions_do_not_deadlock", func(t *testing.T) {
+		p := newWorkerPool(1, time.Minute)
+		t.Cleanup(p.Close)
+
+		var run func(depth int) int
+		run = func(depth int) int {
+			if depth == 0 {
+				return 0
+			}
+
+			res := make(chan int, 1)
+			p.Go(func() { res <- run(depth-1) + 1 })
+			return <-res
+		}
+
+		require.Equal(t, 20, run(20))
+	})
+
+	t.Run("idle_workers_exit_after_the_timeout", func(t *testing.T) {
+		p := newWorkerPool(4, 10*time.Millisecond)
+		t.Cleanup(p.Close)
+
+		done := make(chan struct{})
+		p.Go(func() { close(done) })
+		<-done
+
+		require.Eventually(t, func() bool { return p.idle.Load() == 0 }, time.Second, time.Millisecond)
+	})
+
+	t.Run("close_waits_for_busy_workers", func(t *testing.T) {
+		p := newWorkerPool(4, time.Minute)
+
+		var completed bool
+		p.Go(func() {
+			time.Sleep(20 * time.Millisecond)
+			completed = true
+		})
+		p.Close()
+		require.True(t, completed)
+
+		// functions passed after Close still run
+		done := make(chan struct{})
+		p.Go(func() { close(done) })
+		<-done
+
+		p.Close()
+	})
+}
+
+func wideUnionModel(width int) *openfgav1.AuthorizationModel {
+	var relations, unionOperands []string
+	for i := 0; i < width; i++ {
+		relations = append(relations, fmt.Sprintf("\t\t\tdefine r%d: [user]", i))
+		unionOperands = append(unionOperands, fmt.Sprintf("r%d", i))
+	}
+
+	return testutils.MustTransformDSLToProtoWithID(fmt.Sprintf(`
+		model
+			schema 1.1
+
+		type user
+		type document
+			relations
+%s
+				define blocked: [user]
+				define viewer: (%s) but not blocked`, strings.Join(relations, "\n"), strings.Join(unionOperands, " or ")))
+}
+
+func TestDispatchWorkerPool(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	storeID := ulid.Make().String()
+
+	err := ds.Write(context.Background(), storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "r17", "user:jon"),
+		tuple.NewTupleKey("document:1", "r3", "user:maria"),
+		tuple.NewTupleKey("document:1", "blocked", "user:maria"),
+	})
+	require.NoError(t, err)
+
+	ctx := typesystem.ContextWithTypesystem(context.Background(), typesystem.New(wideUnionModel(20)))
+	ctx = storage.ContextWithRelationshipTupleReader(ctx, ds)
+
+	checker := NewLocalChecker(WithDispatchWorkerPool(8))
+	t.Cleanup(checker.Close)
+
+	check := func(ctx context.Context, user string) (*ResolveCheckResponse, error) {
+		return checker.ResolveCheck(ctx, &ResolveCheckRequest{
+			StoreID:         storeID,
+			TupleKey:        tuple.NewTupleKey("document:1", "viewer", user),
+			RequestMetadata: NewCheckRequestMetadata(25),
+		})
+	}
+
+	t.Run("same_outcomes_as_without_pooling", func(t *testing.T) {
+		for user, expected := range map[string]bool{
+			"user:jon":   true,
+			"user:maria": false,
+			"user:will":  false,
+		} {
+			for i := 0; i < 10; i++ {
+				resp, err := check(ctx, user)
+				require.NoError(t, err)
+				require.Equal(t, expected, resp.GetAllowed(), user)
+			}
+		}
+	})
+
+	t.Run("cancellation_is_preserved", func(t *testing.T) {
+		cancelledCtx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		_, err := check(cancelledCtx, "user:jon")
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+// BenchmarkDispatchWorkerPool compares the allocations of a Check fanning out to many children with and
+// without reusing the goroutines evaluating them. With the pool, it also reports how many goroutines were
+// started per Check.
+func BenchmarkDispatchWorkerPool(b *testing.B) {
+	ds := memory.New()
+	b.Cleanup(ds.Close)
+
+	storeID := ulid.Make().String()
+	err := ds.Write(context.Background(), storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "r99", "user:jon"),
+	})
+	require.NoError(b, err)
+
+	ctx := typesystem.ContextWithTypesystem(context.Background(), typesystem.New(wideUnionModel(100)))
+	ctx = storage.ContextWithRelationshipTupleReader(ctx, ds)
+
+	for name, opts := range map[string][]LocalCheckerOption{
+		"without_pool": nil,
+		"with_pool":    {WithDispatchWorkerPool(256)},
+	} {
+		b.Run(name, func(b *testing.B) {
+			checker := NewLocalChecker(opts...)
+			b.Cleanup(checker.Close)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				_, err := checker.ResolveCheck(ctx, &ResolveCheckRequest{
+					StoreID:         storeID,
+					TupleKey:        tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+					RequestMetadata: NewCheckRequestMetadata(25),
+				})
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			if checker.workerPool != nil {
+				b.ReportMetric(float64(checker.workerPool.started.Load())/float64(b.N), "goroutines/op")
+			}
+		})
+	}
+}
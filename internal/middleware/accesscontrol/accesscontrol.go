@@ -0,0 +1,62 @@
+package accesscontrol
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	"github.com/openfga/openfga/internal/accesscontrol"
+	"github.com/openfga/openfga/internal/authn"
+)
+
+type hasGetStoreID interface {
+	GetStoreId() string
+}
+
+// NewUnaryInterceptor returns a grpc.UnaryServerInterceptor denying the calls the policy doesn't allow
+// to the client. It must come after the authn interceptor, which sets the auth claims of the client.
+func NewUnaryInterceptor(policy *accesscontrol.Policy) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := authorize(ctx, policy, info.FullMethod, req); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// NewStreamingInterceptor returns a grpc.StreamServerInterceptor denying the calls the policy doesn't allow
+// to the client. The store ID being in the request, the call is authorized when the request is received.
+func NewStreamingInterceptor(policy *accesscontrol.Policy) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &authorizingServerStream{ServerStream: stream, policy: policy, fullMethod: info.FullMethod})
+	}
+}
+
+type authorizingServerStream struct {
+	grpc.ServerStream
+	policy     *accesscontrol.Policy
+	fullMethod string
+}
+
+func (s *authorizingServerStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	return authorize(s.Context(), s.policy, s.fullMethod, m)
+}
+
+func authorize(ctx context.Context, policy *accesscontrol.Policy, fullMethod string, req interface{}) error {
+	var subject string
+	if claims, ok := authn.AuthClaimsFromContext(ctx); ok {
+		subject = claims.Subject
+	}
+
+	var storeID string
+	if r, ok := req.(hasGetStoreID); ok {
+		storeID = r.GetStoreId()
+	}
+
+	return policy.Authorize(subject, fullMethod, storeID)
+}
@@ -0,0 +1,60 @@
+package admission
+
+import (
+	"context"
+	"strings"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/openfga/openfga/internal/admission"
+	"github.com/openfga/openfga/internal/authn"
+)
+
+// NewUnaryInterceptor returns a grpc.UnaryServerInterceptor running the calls to the OpenFGA service once the
+// controller admits them. It must come after the authn interceptor, which sets the auth claims of the client.
+func NewUnaryInterceptor(controller *admission.Controller) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		release, err := admit(ctx, controller, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+
+		return handler(ctx, req)
+	}
+}
+
+// NewStreamingInterceptor returns a grpc.StreamServerInterceptor running the calls to the OpenFGA service once
+// the controller admits them. The slot of a call is held until the stream ends.
+func NewStreamingInterceptor(controller *admission.Controller) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		release, err := admit(stream.Context(), controller, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		return handler(srv, stream)
+	}
+}
+
+func admit(ctx context.Context, controller *admission.Controller, fullMethod string) (func(), error) {
+	// the health checks and the reflection are always admitted
+	if !strings.HasPrefix(fullMethod, "/"+openfgav1.OpenFGAService_ServiceDesc.ServiceName+"/") {
+		return func() {}, nil
+	}
+
+	var subject string
+	if claims, ok := authn.AuthClaimsFromContext(ctx); ok {
+		subject = claims.Subject
+	}
+
+	var headerClass string
+	if values := metadata.ValueFromIncomingContext(ctx, admission.PriorityClassHeader); len(values) > 0 {
+		headerClass = values[0]
+	}
+
+	return controller.Admit(ctx, controller.Classify(subject, headerClass, fullMethod))
+}
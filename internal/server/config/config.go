@@ -6,11 +6,14 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"slices"
 	"strconv"
 	"time"
 
 	"github.com/spf13/viper"
 
+	"github.com/openfga/openfga/internal/accesscontrol"
+	"github.com/openfga/openfga/internal/admission"
 	"github.com/openfga/openfga/pkg/logger"
 )
 
@@ -20,7 +23,12 @@ const (
 	DefaultMaxTypesPerAuthorizationModel    = 100
 	DefaultMaxAuthorizationModelSizeInBytes = 256 * 1_024
 	DefaultMaxAuthorizationModelCacheSize   = 100000
+	DefaultSharedTypesystemCacheSize        = 1000
 	DefaultChangelogHorizonOffset           = 0
+	DefaultWatchPollInterval                = 1 * time.Second
+	DefaultTupleExpiryReaperInterval        = 0
+	DefaultShutdownDrainTimeout             = 5 * time.Second
+	DefaultStoreStatsRefreshInterval        = 1 * time.Minute
 	DefaultResolveNodeLimit                 = 25
 	DefaultResolveNodeBreadthLimit          = 100
 	DefaultListObjectsDeadline              = 3 * time.Second
@@ -30,12 +38,28 @@ const (
 	DefaultListUsersDeadline                = 3 * time.Second
 	DefaultListUsersMaxResults              = 1000
 	DefaultMaxConcurrentReadsForListUsers   = math.MaxUint32
+	DefaultMaxConcurrentChecksPerBatchCheck = 50
+	DefaultStoreFeatureFlagsCacheTTL        = 10 * time.Second
 
 	DefaultWriteContextByteLimit = 32 * 1_024 // 32KB
 	DefaultCheckQueryCacheLimit  = 10000
 	DefaultCheckQueryCacheTTL    = 10 * time.Second
 	DefaultCheckQueryCacheEnable = false
 
+	DefaultListObjectsReadCacheLimit  = 10000
+	DefaultListObjectsReadCacheTTL    = 10 * time.Second
+	DefaultListObjectsReadCacheEnable = false
+
+	// DefaultWriteDisallowedIDCharacters are the characters that the object and user IDs of written tuples
+	// may not contain: the ASCII control characters and the space character.
+	DefaultWriteDisallowedIDCharacters = "\x00\x01\x02\x03\x04\x05\x06\x07\x08\x09\x0a\x0b\x0c\x0d\x0e\x0f" +
+		"\x10\x11\x12\x13\x14\x15\x16\x17\x18\x19\x1a\x1b\x1c\x1d\x1e\x1f\x7f "
+
+	// DefaultMaxObjectIDLength and DefaultMaxUserIDLength are the maximum lengths, in bytes, of the object
+	// and user IDs of written tuples. They are the maximum lengths the API allows for whole users.
+	DefaultMaxObjectIDLength = 512
+	DefaultMaxUserIDLength   = 512
+
 	// Care should be taken here - decreasing can cause API compatibility problems with Conditions.
 	DefaultMaxConditionEvaluationCost = 100
 	DefaultInterruptCheckFrequency    = 100
@@ -55,6 +79,9 @@ const (
 	additionalUpstreamTimeout = 3 * time.Second
 )
 
+// checkResolverLayers are the layers of the Check resolver chain, in their default order.
+var checkResolverLayers = []string{"cycle_detection", "cached", "dispatch_throttling"}
+
 type DatastoreMetricsConfig struct {
 	// Enabled enables export of the Datastore metrics.
 	Enabled bool
@@ -84,6 +111,44 @@ type DatastoreConfig struct {
 	// ConnMaxLifetime is the maximum amount of time a connection to the datastore may be reused.
 	ConnMaxLifetime time.Duration
 
+	// ReplicaURI is the URI of a read replica of the datastore, of the same engine. If set, the datastore
+	// reads of the Checks that take longer than usual are also sent to the replica, and the first answer is used.
+	ReplicaURI string `json:"-"` // private field, won't be logged
+
+	// HedgingPercentile is the percentile of the latest latencies of the datastore after which the reads of
+	// the Checks are also sent to the replica.
+	HedgingPercentile float64
+
+	// HedgingMinDelay is the minimum delay after which the reads of the Checks are also sent to the replica.
+	HedgingMinDelay time.Duration
+
+	// UsersetBatchWindow is how long the userset reads of a Check wait for each other to be coalesced into one
+	// query, if the datastore supports batched userset reads. 0 disables the batching.
+	UsersetBatchWindow time.Duration
+
+	// UsersetBatchMaxSize is the maximum number of objects whose userset tuples are read by one query.
+	UsersetBatchMaxSize int
+
+	// MemorySnapshotFile is the file the data of the 'memory' engine is persisted to, so that it survives
+	// restarts. The data is loaded from the file on start, if it exists.
+	MemorySnapshotFile string
+
+	// MemorySnapshotInterval is the interval the data of the 'memory' engine is saved to MemorySnapshotFile
+	// at, if it changed. It is also saved on shutdown.
+	MemorySnapshotInterval time.Duration
+
+	// TupleExpiryReaperInterval is the interval the tuples that expired are deleted at. It requires a datastore
+	// that supports expiring tuples. 0 disables the deletes.
+	TupleExpiryReaperInterval time.Duration
+
+	// StoreStatsRefreshInterval is the age after which the statistics of a store are recomputed in the
+	// background, by the 'mysql' and 'postgres' engines.
+	StoreStatsRefreshInterval time.Duration
+
+	// ReadBudget is the maximum number of datastore reads one Check, ListObjects or ListUsers request
+	// will make, 0 if unbounded.
+	ReadBudget uint32
+
 	// Metrics is configuration for the Datastore metrics.
 	Metrics DatastoreMetricsConfig
 }
@@ -119,10 +184,11 @@ type TLSConfig struct {
 type AuthnConfig struct {
 
 	// Method is the authentication method that should be enforced (e.g. 'none', 'preshared',
-	// 'oidc')
+	// 'oidc', 'mtls')
 	Method                   string
 	*AuthnOIDCConfig         `mapstructure:"oidc"`
 	*AuthnPresharedKeyConfig `mapstructure:"preshared"`
+	*AuthnMTLSConfig         `mapstructure:"mtls"`
 }
 
 // AuthnOIDCConfig defines configurations for the 'oidc' method of authentication.
@@ -130,6 +196,95 @@ type AuthnOIDCConfig struct {
 	Issuer        string
 	IssuerAliases []string
 	Audience      string
+
+	// Issuers are additional trusted issuers, each with its own aliases and audience. A token is
+	// verified against the issuer named by its 'iss' claim.
+	Issuers []AuthnOIDCIssuerConfig
+}
+
+// AuthnOIDCIssuerConfig defines the configuration of one of the trusted issuers of the 'oidc' method of authentication.
+type AuthnOIDCIssuerConfig struct {
+	Issuer        string
+	IssuerAliases []string
+	Audience      string
+}
+
+// AuthnMTLSConfig defines configurations for the 'mtls' method of authentication.
+type AuthnMTLSConfig struct {
+	// ClientCAPath is the file path of the certificates of the CAs the client certificates must be signed by.
+	ClientCAPath string `mapstructure:"clientCA"`
+
+	// AllowedSubjects are the common names of the client certificates that are accepted. All are if empty.
+	AllowedSubjects []string
+}
+
+// AccessControlConfig defines OpenFGA server configurations for the access control of the authenticated
+// clients to the stores.
+type AccessControlConfig struct {
+	Enabled bool
+
+	// Grants are the grants of the clients, each of the form '<subject>:<store ID>:<level>'. A client is
+	// identified by the subject of its auth claims, e.g. the 'sub' claim of its OIDC token.
+	Grants []string
+}
+
+// AdmissionControlConfig defines OpenFGA server configurations for the admission control of the requests,
+// which bounds the concurrency of each priority class of requests, see the admission package.
+type AdmissionControlConfig struct {
+	Enabled bool
+
+	// Classes are the priority classes, each of the form '<name>:<max concurrent>:<max queued>', from the
+	// highest priority to the lowest.
+	Classes []string
+
+	// Methods assign the APIs to classes, each of the form '<method>:<class>', e.g. 'ListObjects:bulk'.
+	Methods []string
+
+	// Clients assign the clients, identified by the subject of their auth claims, to classes, each of the
+	// form '<subject>:<class>'. They take precedence over the methods. The priority class header may only lower
+	// the class of a request.
+	Clients []string
+
+	// DefaultClass is the class of the requests no assignment selects a class for.
+	DefaultClass string
+
+	// QueueTimeout is the maximum time a request waits for a slot of its class before being rejected.
+	QueueTimeout time.Duration
+}
+
+// ControllerConfig returns the configuration of the admission.Controller of the admission control.
+func (c AdmissionControlConfig) ControllerConfig() admission.Config {
+	return admission.Config{
+		Classes:      c.Classes,
+		Methods:      c.Methods,
+		Clients:      c.Clients,
+		DefaultClass: c.DefaultClass,
+		QueueTimeout: c.QueueTimeout,
+	}
+}
+
+// AuditConfig defines OpenFGA server configurations for the audit events recording the changes made to the
+// stores, and optionally the Check decisions, see the audit package.
+type AuditConfig struct {
+	Enabled bool
+
+	// Sink is where the events are written to, one of 'stdout', 'file' or 'webhook'.
+	Sink string
+
+	// FilePath is the path of the file of the 'file' sink, which is rotated once it exceeds FileMaxSize
+	// bytes, keeping FileMaxBackups rotated files.
+	FilePath       string
+	FileMaxSize    int64
+	FileMaxBackups int
+
+	// WebhookURL is the URL the 'webhook' sink posts the events to.
+	WebhookURL string
+
+	// CheckSampleRate is the fraction of the Check decisions, between 0 and 1, that are audited.
+	CheckSampleRate float64
+
+	// BufferSize is the number of events buffered while the sink writes the previous ones.
+	BufferSize int
 }
 
 // AuthnPresharedKeyConfig defines configurations for the 'preshared' method of authentication.
@@ -193,6 +348,13 @@ type CheckQueryCache struct {
 	TTL     time.Duration
 }
 
+// ListObjectsReadCache defines configuration for caching the tuples read when resolving list objects.
+type ListObjectsReadCache struct {
+	Enabled bool
+	Limit   uint32 // (in items)
+	TTL     time.Duration
+}
+
 // DispatchThrottlingConfig defines configurations for dispatch throttling.
 type DispatchThrottlingConfig struct {
 	Enabled      bool
@@ -215,6 +377,10 @@ type Config struct {
 	// This is to protect the server from misuse of the ListObjects endpoints.
 	ListObjectsMaxResults uint32
 
+	// ListObjectsCandidateCheckConcurrencyLimit indicates how many candidate objects of a ListObjects
+	// query can be checked concurrently. 0 means ResolveNodeBreadthLimit.
+	ListObjectsCandidateCheckConcurrencyLimit uint32
+
 	// ListUsersDeadline defines the maximum amount of time to accumulate ListUsers results
 	// before the server will respond. This is to protect the server from misuse of the
 	// ListUsers endpoints. It cannot be larger than the configured server's request timeout (RequestTimeout or HTTPConfig.UpstreamTimeout).
@@ -266,20 +432,38 @@ type Config struct {
 	// concurrently in a query
 	ResolveNodeBreadthLimit uint32
 
+	// ResolveNodeUnionBreadthLimit and ResolveNodeTTUBreadthLimit indicate how many children of a union
+	// rewrite, and how many tuples of a tuple to userset rewrite, can be evaluated concurrently in a Check.
+	// 0 means ResolveNodeBreadthLimit.
+	ResolveNodeUnionBreadthLimit uint32
+	ResolveNodeTTUBreadthLimit   uint32
+
+	// CheckResolverOrder is the order of the layers of the Check resolver chain, among 'cycle_detection', 'cached'
+	// and 'dispatch_throttling'. The layers left out are disabled.
+	CheckResolverOrder []string
+
 	// RequestTimeout configures request timeout.  If both HTTP upstream timeout and request timeout are specified,
 	// request timeout will be prioritized
 	RequestTimeout time.Duration
 
+	// ShutdownDrainTimeout is how long the Check and ListObjects requests in flight on shutdown are given to
+	// complete before they are canceled. The new requests are rejected as soon as the shutdown starts.
+	ShutdownDrainTimeout time.Duration
+
 	Datastore                     DatastoreConfig
 	GRPC                          GRPCConfig
 	HTTP                          HTTPConfig
 	Authn                         AuthnConfig
+	AccessControl                 AccessControlConfig
+	AdmissionControl              AdmissionControlConfig
+	Audit                         AuditConfig
 	Log                           LogConfig
 	Trace                         TraceConfig
 	Playground                    PlaygroundConfig
 	Profiler                      ProfilerConfig
 	Metrics                       MetricConfig
 	CheckQueryCache               CheckQueryCache
+	ListObjectsReadCache          ListObjectsReadCache
 	DispatchThrottling            DispatchThrottlingConfig
 	CheckDispatchThrottling       DispatchThrottlingConfig
 	ListObjectsDispatchThrottling DispatchThrottlingConfig
@@ -352,6 +536,30 @@ func (cfg *Config) Verify() error {
 		}
 	}
 
+	if err := cfg.VerifyDatastoreConfig(); err != nil {
+		return err
+	}
+
+	if err := cfg.VerifyCheckResolverOrderConfig(); err != nil {
+		return err
+	}
+
+	if err := cfg.VerifyAccessControlConfig(); err != nil {
+		return err
+	}
+
+	if err := cfg.VerifyAdmissionControlConfig(); err != nil {
+		return err
+	}
+
+	if err := cfg.VerifyAuditConfig(); err != nil {
+		return err
+	}
+
+	if err := cfg.VerifyAuthnConfig(); err != nil {
+		return err
+	}
+
 	if len(cfg.RequestDurationDatastoreQueryCountBuckets) == 0 {
 		return errors.New("request duration datastore query count buckets must not be empty")
 	}
@@ -398,6 +606,10 @@ func (cfg *Config) Verify() error {
 		return errors.New("requestTimeout must be a non-negative time duration")
 	}
 
+	if cfg.ShutdownDrainTimeout < 0 {
+		return errors.New("shutdownDrainTimeout must be a non-negative time duration")
+	}
+
 	if cfg.RequestTimeout == 0 && cfg.HTTP.Enabled && cfg.HTTP.UpstreamTimeout < 0 {
 		return errors.New("http.upstreamTimeout must be a non-negative time duration")
 	}
@@ -486,6 +698,121 @@ func (cfg *Config) VerifyCheckDispatchThrottlingConfig() error {
 	return nil
 }
 
+// VerifyDatastoreConfig ensures the read replica, the userset read batching and the memory snapshot settings
+// are supported by the datastore engine.
+func (cfg *Config) VerifyDatastoreConfig() error {
+	if cfg.Datastore.ReplicaURI != "" {
+		if cfg.Datastore.Engine == "memory" {
+			return errors.New("'datastore.replicaURI' is not supported by the 'memory' datastore engine")
+		}
+
+		if cfg.Datastore.HedgingPercentile <= 0 || cfg.Datastore.HedgingPercentile > 1 {
+			return errors.New("'datastore.hedgingPercentile' must be greater than 0 and at most 1")
+		}
+	}
+
+	if cfg.Datastore.UsersetBatchWindow > 0 && cfg.Datastore.UsersetBatchMaxSize < 1 {
+		return errors.New("'datastore.usersetBatchMaxSize' must be at least 1")
+	}
+
+	if cfg.Datastore.MemorySnapshotFile != "" && cfg.Datastore.Engine != "memory" {
+		return errors.New("'datastore.memorySnapshotFile' is only supported by the 'memory' datastore engine")
+	}
+
+	return nil
+}
+
+// VerifyCheckResolverOrderConfig ensures the Check resolver layers are known and listed at most once.
+func (cfg *Config) VerifyCheckResolverOrderConfig() error {
+	for i, layer := range cfg.CheckResolverOrder {
+		if !slices.Contains(checkResolverLayers, layer) {
+			return fmt.Errorf("'checkResolverOrder' contains the unknown layer '%s'", layer)
+		}
+		if slices.Contains(cfg.CheckResolverOrder[:i], layer) {
+			return fmt.Errorf("'checkResolverOrder' contains the layer '%s' more than once", layer)
+		}
+	}
+	return nil
+}
+
+// VerifyAccessControlConfig ensures the access control grants can be parsed, if the access control is enabled.
+func (cfg *Config) VerifyAccessControlConfig() error {
+	if !cfg.AccessControl.Enabled {
+		return nil
+	}
+	_, err := accesscontrol.NewPolicyFromStrings(cfg.AccessControl.Grants)
+	return err
+}
+
+// VerifyAdmissionControlConfig ensures the priority classes are valid, if the admission control is enabled.
+func (cfg *Config) VerifyAdmissionControlConfig() error {
+	if !cfg.AdmissionControl.Enabled {
+		return nil
+	}
+	_, err := admission.NewController(cfg.AdmissionControl.ControllerConfig())
+	return err
+}
+
+// VerifyAuditConfig ensures the audit sink is known and has the settings it requires, if the audit is enabled.
+func (cfg *Config) VerifyAuditConfig() error {
+	if !cfg.Audit.Enabled {
+		return nil
+	}
+
+	switch cfg.Audit.Sink {
+	case "stdout":
+	case "file":
+		if cfg.Audit.FilePath == "" {
+			return errors.New("'audit.filePath' must be set for the 'file' audit sink")
+		}
+		if cfg.Audit.FileMaxSize <= 0 {
+			return errors.New("'audit.fileMaxSize' must be greater than 0")
+		}
+		if cfg.Audit.FileMaxBackups < 0 {
+			return errors.New("'audit.fileMaxBackups' must not be negative")
+		}
+	case "webhook":
+		if cfg.Audit.WebhookURL == "" {
+			return errors.New("'audit.webhookURL' must be set for the 'webhook' audit sink")
+		}
+	default:
+		return fmt.Errorf("'audit.sink' must be one of 'stdout', 'file' or 'webhook', got '%s'", cfg.Audit.Sink)
+	}
+
+	if cfg.Audit.CheckSampleRate < 0 || cfg.Audit.CheckSampleRate > 1 {
+		return errors.New("'audit.checkSampleRate' must be between 0 and 1")
+	}
+
+	if cfg.Audit.BufferSize <= 0 {
+		return errors.New("'audit.bufferSize' must be greater than 0")
+	}
+
+	return nil
+}
+
+// VerifyAuthnConfig ensures the 'mtls' authn method is used with gRPC TLS and a client CA, and without the HTTP
+// server.
+func (cfg *Config) VerifyAuthnConfig() error {
+	if cfg.Authn.Method != "mtls" {
+		return nil
+	}
+
+	if !cfg.GRPC.TLS.Enabled {
+		return errors.New("the 'mtls' authn method requires 'grpc.tls.enabled'")
+	}
+
+	if cfg.Authn.AuthnMTLSConfig == nil || cfg.Authn.ClientCAPath == "" {
+		return errors.New("'authn.mtls.clientCA' config must be set")
+	}
+
+	// the HTTP gateway connects to the gRPC server on behalf of its clients, it can't present their certificates
+	if cfg.HTTP.Enabled {
+		return errors.New("the 'mtls' authn method requires the HTTP server to be disabled")
+	}
+
+	return nil
+}
+
 // MaxConditionEvaluationCost ensures a safe value for CEL evaluation cost.
 func MaxConditionEvaluationCost() uint64 {
 	return max(DefaultMaxConditionEvaluationCost, viper.GetUint64("maxConditionEvaluationCost"))
@@ -504,6 +831,7 @@ func DefaultConfig() *Config {
 		ChangelogHorizonOffset:                    DefaultChangelogHorizonOffset,
 		ResolveNodeLimit:                          DefaultResolveNodeLimit,
 		ResolveNodeBreadthLimit:                   DefaultResolveNodeBreadthLimit,
+		CheckResolverOrder:                        slices.Clone(checkResolverLayers),
 		Experimentals:                             []string{},
 		ListObjectsDeadline:                       DefaultListObjectsDeadline,
 		ListObjectsMaxResults:                     DefaultListObjectsMaxResults,
@@ -516,6 +844,17 @@ func DefaultConfig() *Config {
 			MaxCacheSize: DefaultMaxAuthorizationModelCacheSize,
 			MaxIdleConns: 10,
 			MaxOpenConns: 30,
+
+			HedgingPercentile: 0.99,
+			HedgingMinDelay:   5 * time.Millisecond,
+
+			UsersetBatchMaxSize: 100,
+
+			MemorySnapshotInterval: 10 * time.Second,
+
+			TupleExpiryReaperInterval: DefaultTupleExpiryReaperInterval,
+
+			StoreStatsRefreshInterval: DefaultStoreStatsRefreshInterval,
 		},
 		GRPC: GRPCConfig{
 			Addr: "0.0.0.0:8081",
@@ -533,6 +872,7 @@ func DefaultConfig() *Config {
 			Method:                  "none",
 			AuthnPresharedKeyConfig: &AuthnPresharedKeyConfig{},
 			AuthnOIDCConfig:         &AuthnOIDCConfig{},
+			AuthnMTLSConfig:         &AuthnMTLSConfig{},
 		},
 		Log: LogConfig{
 			Format:          "text",
@@ -563,11 +903,41 @@ func DefaultConfig() *Config {
 			Addr:                "0.0.0.0:2112",
 			EnableRPCHistograms: false,
 		},
+		Audit: AuditConfig{
+			Enabled:         false,
+			Sink:            "stdout",
+			FileMaxSize:     100 * 1024 * 1024,
+			FileMaxBackups:  5,
+			CheckSampleRate: 0,
+			BufferSize:      1000,
+		},
+		AdmissionControl: AdmissionControlConfig{
+			Enabled: false,
+			Classes: []string{"interactive:100:1000", "bulk:20:100", "write:50:500"},
+			Methods: []string{
+				"ListObjects:bulk",
+				"StreamedListObjects:bulk",
+				"ListUsers:bulk",
+				"Read:bulk",
+				"ReadChanges:bulk",
+				"Write:write",
+				"WriteAssertions:write",
+				"WriteAuthorizationModel:write",
+			},
+			Clients:      []string{},
+			DefaultClass: "interactive",
+			QueueTimeout: time.Second,
+		},
 		CheckQueryCache: CheckQueryCache{
 			Enabled: DefaultCheckQueryCacheEnable,
 			Limit:   DefaultCheckQueryCacheLimit,
 			TTL:     DefaultCheckQueryCacheTTL,
 		},
+		ListObjectsReadCache: ListObjectsReadCache{
+			Enabled: DefaultListObjectsReadCacheEnable,
+			Limit:   DefaultListObjectsReadCacheLimit,
+			TTL:     DefaultListObjectsReadCacheTTL,
+		},
 		DispatchThrottling: DispatchThrottlingConfig{
 			Enabled:      DefaultCheckDispatchThrottlingEnabled,
 			Frequency:    DefaultCheckDispatchThrottlingFrequency,
@@ -586,7 +956,8 @@ func DefaultConfig() *Config {
 			Threshold:    DefaultListObjectsDispatchThrottlingDefaultThreshold,
 			MaxThreshold: DefaultListObjectsDispatchThrottlingMaxThreshold,
 		},
-		RequestTimeout: DefaultRequestTimeout,
+		RequestTimeout:       DefaultRequestTimeout,
+		ShutdownDrainTimeout: DefaultShutdownDrainTimeout,
 	}
 }
 
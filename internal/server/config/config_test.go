@@ -60,6 +60,107 @@ func TestVerifyConfig(t *testing.T) {
 		require.EqualError(t, err, "'grpc.tls.cert' and 'grpc.tls.key' configs must be set")
 	})
 
+	t.Run("mtls_authn_requirements", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Playground.Enabled = false
+		cfg.Authn.Method = "mtls"
+		require.EqualError(t, cfg.Verify(), "the 'mtls' authn method requires 'grpc.tls.enabled'")
+
+		cfg.GRPC.TLS = &TLSConfig{Enabled: true, CertPath: "some/path", KeyPath: "some/path"}
+		require.EqualError(t, cfg.Verify(), "'authn.mtls.clientCA' config must be set")
+
+		cfg.Authn.ClientCAPath = "some/path"
+		require.EqualError(t, cfg.Verify(), "the 'mtls' authn method requires the HTTP server to be disabled")
+
+		cfg.HTTP.Enabled = false
+		require.NoError(t, cfg.Verify())
+	})
+
+	t.Run("datastore_replica_requirements", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Datastore.ReplicaURI = "postgres://replica:5432/openfga"
+		require.EqualError(t, cfg.Verify(), "'datastore.replicaURI' is not supported by the 'memory' datastore engine")
+
+		cfg.Datastore.Engine = "postgres"
+		cfg.Datastore.HedgingPercentile = 1.5
+		require.EqualError(t, cfg.Verify(), "'datastore.hedgingPercentile' must be greater than 0 and at most 1")
+
+		cfg.Datastore.HedgingPercentile = 0.95
+		require.NoError(t, cfg.Verify())
+	})
+
+	t.Run("datastore_memory_snapshot_requirements", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Datastore.MemorySnapshotFile = "openfga.snapshot"
+		require.NoError(t, cfg.Verify())
+
+		cfg.Datastore.Engine = "postgres"
+		require.EqualError(t, cfg.Verify(), "'datastore.memorySnapshotFile' is only supported by the 'memory' datastore engine")
+	})
+
+	t.Run("invalid_userset_batch_max_size", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Datastore.UsersetBatchMaxSize = 0
+		require.NoError(t, cfg.Verify())
+
+		cfg.Datastore.UsersetBatchWindow = time.Millisecond
+		require.EqualError(t, cfg.Verify(), "'datastore.usersetBatchMaxSize' must be at least 1")
+	})
+
+	t.Run("invalid_check_resolver_order", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.CheckResolverOrder = []string{"cached"}
+		require.NoError(t, cfg.Verify())
+
+		cfg.CheckResolverOrder = []string{"cached", "tracker"}
+		require.EqualError(t, cfg.Verify(), "'checkResolverOrder' contains the unknown layer 'tracker'")
+
+		cfg.CheckResolverOrder = []string{"cached", "cached"}
+		require.EqualError(t, cfg.Verify(), "'checkResolverOrder' contains the layer 'cached' more than once")
+	})
+
+	t.Run("invalid_access_control_grant", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.AccessControl.Grants = []string{"client-a:read"}
+		require.NoError(t, cfg.Verify())
+
+		cfg.AccessControl.Enabled = true
+		require.EqualError(t, cfg.Verify(), "invalid grant 'client-a:read', must be of the form '<subject>:<store ID>:<level>'")
+
+		cfg.AccessControl.Grants = []string{"client-a:*:read"}
+		require.NoError(t, cfg.Verify())
+	})
+
+	t.Run("invalid_admission_control_class", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.AdmissionControl.Methods = []string{"ListObjects:batch"}
+		require.NoError(t, cfg.Verify())
+
+		cfg.AdmissionControl.Enabled = true
+		require.EqualError(t, cfg.Verify(), "invalid priority class assignment 'ListObjects:batch', the class is not defined")
+
+		cfg.AdmissionControl.Classes = append(cfg.AdmissionControl.Classes, "batch:1:0")
+		require.NoError(t, cfg.Verify())
+	})
+
+	t.Run("invalid_audit_sink", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Audit.Enabled = true
+		require.NoError(t, cfg.Verify())
+
+		cfg.Audit.Sink = "kafka"
+		require.EqualError(t, cfg.Verify(), "'audit.sink' must be one of 'stdout', 'file' or 'webhook', got 'kafka'")
+
+		cfg.Audit.Sink = "file"
+		require.EqualError(t, cfg.Verify(), "'audit.filePath' must be set for the 'file' audit sink")
+
+		cfg.Audit.FilePath = "audit.log"
+		require.NoError(t, cfg.Verify())
+
+		cfg.Audit.CheckSampleRate = 1.5
+		require.EqualError(t, cfg.Verify(), "'audit.checkSampleRate' must be between 0 and 1")
+	})
+
 	t.Run("failing_to_set_http_key_path_will_not_allow_server_to_start", func(t *testing.T) {
 		cfg := DefaultConfig()
 		cfg.HTTP.TLS = &TLSConfig{
@@ -234,6 +335,14 @@ func TestVerifyConfig(t *testing.T) {
 		require.Error(t, err)
 	})
 
+	t.Run("negative_shutdown_drain_timeout", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.ShutdownDrainTimeout = -time.Second
+
+		err := cfg.Verify()
+		require.EqualError(t, err, "shutdownDrainTimeout must be a non-negative time duration")
+	})
+
 	t.Run("negative_upstream_timeout", func(t *testing.T) {
 		cfg := DefaultConfig()
 		cfg.RequestTimeout = 0
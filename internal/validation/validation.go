@@ -31,12 +31,41 @@ func ValidateUserObjectRelation(typesys *typesystem.TypeSystem, tk *openfgav1.Tu
 	return nil
 }
 
+// ValidateTupleOption changes the validation done by ValidateTuple.
+type ValidateTupleOption func(*validateTupleOptions)
+
+type validateTupleOptions struct {
+	permissiveUsersetReferences bool
+}
+
+// WithPermissiveUsersetReferences makes ValidateTuple accept tuples whose user is a userset (e.g. 'group:eng#member')
+// although the relation of the userset is not defined, or the userset is not an allowed type restriction of the relation
+// of the tuple, e.g. to migrate tuples written for a model that is not written yet. The type of the userset must still
+// be defined. Such tuples are ignored when they are read, until the model allows them.
+func WithPermissiveUsersetReferences() ValidateTupleOption {
+	return func(o *validateTupleOptions) {
+		o.permissiveUsersetReferences = true
+	}
+}
+
 // ValidateTuple returns nil if a tuple is well formed and valid according to the provided model.
 // It is a superset of ValidateUserObjectRelation; it also validates TTU relations and type restrictions.
 //
 // Do NOT use this when validating a tuple that is an input to a Check or WriteAssertions request.
-func ValidateTuple(typesys *typesystem.TypeSystem, tk *openfgav1.TupleKey) error {
-	if err := ValidateUserObjectRelation(typesys, tk); err != nil {
+func ValidateTuple(typesys *typesystem.TypeSystem, tk *openfgav1.TupleKey, opts ...ValidateTupleOption) error {
+	var o validateTupleOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	permissiveUserset := o.permissiveUsersetReferences && tuple.IsObjectRelation(tk.GetUser())
+
+	validateUserObjectRelation := ValidateUserObjectRelation
+	if permissiveUserset {
+		validateUserObjectRelation = validatePermissiveUsersetObjectRelation
+	}
+
+	if err := validateUserObjectRelation(typesys, tk); err != nil {
 		return &tuple.InvalidTupleError{Cause: err, TupleKey: tk}
 	}
 
@@ -59,7 +88,18 @@ func ValidateTuple(typesys *typesystem.TypeSystem, tk *openfgav1.TupleKey) error
 	if hasTypeInfo {
 		err := validateTypeRestrictions(typesys, tk)
 		if err != nil {
-			return &tuple.InvalidTupleError{Cause: err, TupleKey: tk}
+			if !permissiveUserset {
+				return &tuple.InvalidTupleError{Cause: err, TupleKey: tk}
+			}
+
+			// the conditions allowed for the userset are only known once it is a type restriction
+			if _, ok := typesys.GetConditions()[tk.GetCondition().GetName()]; tk.GetCondition() != nil && !ok {
+				return &tuple.InvalidConditionalTupleError{
+					Cause: fmt.Errorf("undefined condition"), TupleKey: tk,
+				}
+			}
+
+			return nil
 		}
 
 		if err := validateCondition(typesys, tk); err != nil {
@@ -70,6 +110,48 @@ func ValidateTuple(typesys *typesystem.TypeSystem, tk *openfgav1.TupleKey) error
 	return nil
 }
 
+// ValidateContextualTuples validates the contextual tuples of a request with ValidateTuple. Rather than stopping
+// at the first invalid tuple, it returns a *tuple.InvalidContextualTuplesError listing every invalid tuple.
+func ValidateContextualTuples(typesys *typesystem.TypeSystem, tupleKeys []*openfgav1.TupleKey) error {
+	var errs []*tuple.InvalidContextualTupleError
+	for i, tk := range tupleKeys {
+		if err := ValidateTuple(typesys, tk); err != nil {
+			errs = append(errs, &tuple.InvalidContextualTupleError{Index: i, TupleKey: tk, Cause: err})
+		}
+	}
+
+	if len(errs) > 0 {
+		return &tuple.InvalidContextualTuplesError{Errors: errs}
+	}
+
+	return nil
+}
+
+// validatePermissiveUsersetObjectRelation is like ValidateUserObjectRelation for a tuple whose user is a userset,
+// but it only requires the type of the userset to be defined, see WithPermissiveUsersetReferences.
+func validatePermissiveUsersetObjectRelation(typesys *typesystem.TypeSystem, tk *openfgav1.TupleKey) error {
+	user := tk.GetUser()
+	if !tuple.IsValidUser(user) {
+		return fmt.Errorf("the 'user' field is malformed")
+	}
+
+	userObject, _ := tuple.SplitObjectRelation(user)
+	if tuple.IsTypedWildcard(userObject) {
+		return fmt.Errorf("the 'user' field cannot reference a typed wildcard in a userset value")
+	}
+
+	userObjectType := tuple.GetType(userObject)
+	if _, ok := typesys.GetTypeDefinition(userObjectType); !ok {
+		return &tuple.TypeNotFoundError{TypeName: userObjectType}
+	}
+
+	if err := ValidateObject(typesys, tk); err != nil {
+		return err
+	}
+
+	return ValidateRelation(typesys, tk)
+}
+
 // validateTuplesetRestrictions validates the provided TupleKey against tupleset restrictions.
 //
 // Given a rewrite definition such as 'viewer from parent', the 'parent' relation is known as the
@@ -7,6 +7,7 @@ import (
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	"github.com/stretchr/testify/require"
 
+	"github.com/openfga/openfga/pkg/testutils"
 	"github.com/openfga/openfga/pkg/tuple"
 	"github.com/openfga/openfga/pkg/typesystem"
 )
@@ -744,3 +745,49 @@ func BenchmarkValidateTuple(b *testing.B) {
 		require.NoError(b, err)
 	}
 }
+
+func TestValidateContextualTuples(t *testing.T) {
+	typesys := typesystem.New(testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type document
+			relations
+				define viewer: [user, user with is_weekday]
+
+		condition is_weekday(day: string) {
+			day != "saturday"
+		}`))
+
+	t.Run("valid_tuples", func(t *testing.T) {
+		require.NoError(t, ValidateContextualTuples(typesys, []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+			tuple.NewTupleKeyWithCondition("document:1", "viewer", "user:bob", "is_weekday", nil),
+		}))
+	})
+
+	t.Run("every_invalid_tuple_is_listed", func(t *testing.T) {
+		err := ValidateContextualTuples(typesys, []*openfgav1.TupleKey{
+			tuple.NewTupleKey("folder:1", "viewer", "user:jon"),
+			tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+			tuple.NewTupleKey("document:1", "editor", "user:jon"),
+			tuple.NewTupleKeyWithCondition("document:1", "viewer", "user:jon", "is_weekend", nil),
+		})
+
+		var contextualTuplesErr *tuple.InvalidContextualTuplesError
+		require.ErrorAs(t, err, &contextualTuplesErr)
+		require.Len(t, contextualTuplesErr.Errors, 3)
+
+		var indexes []int
+		for _, err := range contextualTuplesErr.Errors {
+			indexes = append(indexes, err.Index)
+		}
+		require.Equal(t, []int{0, 2, 3}, indexes)
+
+		require.ErrorIs(t, err, &tuple.InvalidConditionalTupleError{})
+		require.ErrorContains(t, err, "contextual tuple 0 'folder:1#viewer@user:jon' (type 'folder', relation 'viewer'): type 'folder' not found")
+		require.ErrorContains(t, err, "contextual tuple 2 'document:1#editor@user:jon' (type 'document', relation 'editor')")
+		require.ErrorContains(t, err, "contextual tuple 3 'document:1#viewer@user:jon' (type 'document', relation 'viewer'): undefined condition")
+	})
+}
@@ -0,0 +1,194 @@
+// Package audit emits structured events recording the changes made to the stores, and optionally the Check
+// decisions, to pluggable sinks.
+package audit
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/openfga/openfga/internal/authn"
+	"github.com/openfga/openfga/internal/build"
+	"github.com/openfga/openfga/pkg/logger"
+)
+
+const defaultBufferSize = 1000
+
+var auditEventsDroppedCounter = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: build.ProjectName,
+	Name:      "audit_events_dropped_count",
+	Help:      "The total number of audit events dropped because the sinks were slower than the requests emitting them.",
+})
+
+// EventType is the kind of operation an Event records.
+type EventType string
+
+// The types of the events, one per audited API.
+const (
+	EventWrite                   EventType = "write"
+	EventWriteAuthorizationModel EventType = "write_authorization_model"
+	EventCreateStore             EventType = "create_store"
+	EventDeleteStore             EventType = "delete_store"
+	EventCheck                   EventType = "check"
+)
+
+// Event is the record of an operation. The fields that do not apply to the operation are empty.
+type Event struct {
+	Time time.Time `json:"time"`
+	Type EventType `json:"type"`
+
+	// Principal is the subject of the authenticated client that made the request, if any.
+	Principal string `json:"principal,omitempty"`
+
+	StoreID              string `json:"store_id,omitempty"`
+	AuthorizationModelID string `json:"authorization_model_id,omitempty"`
+
+	// Writes and Deletes are the tuples written and deleted by a Write, e.g. 'document:1#viewer@user:anne'.
+	Writes  []string `json:"writes,omitempty"`
+	Deletes []string `json:"deletes,omitempty"`
+
+	// TupleKey and Allowed are the tuple checked by a Check and its decision.
+	TupleKey string `json:"tuple_key,omitempty"`
+	Allowed  bool   `json:"allowed,omitempty"`
+
+	// Error is the error the operation failed with, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// Sink receives the events of a Logger, e.g. to write them to a file or to send them to another system.
+type Sink interface {
+	// Write records the event. It is called by one goroutine at a time.
+	Write(event *Event) error
+
+	// Close flushes the recorded events and releases the resources of the sink.
+	Close() error
+}
+
+// Logger emits the events to its sinks. The events are buffered and written by a background goroutine, so
+// that a slow sink does not delay the requests; the events emitted while the buffer is full are dropped.
+type Logger struct {
+	sinks           []Sink
+	checkSampleRate float64
+	logger          logger.Logger
+
+	// mu guards events from being sent to once closed
+	mu     sync.RWMutex
+	closed bool
+	events chan *Event
+	done   chan struct{}
+}
+
+// Option configures a Logger.
+type Option func(*Logger)
+
+// WithBufferSize sets the number of events buffered while the sinks write the previous ones. It defaults to 1000.
+func WithBufferSize(size int) Option {
+	return func(l *Logger) {
+		l.events = make(chan *Event, size)
+	}
+}
+
+// WithCheckSampleRate sets the fraction of the Check decisions (between 0 and 1) that are audited, e.g. 0.01
+// audits roughly one in every hundred Checks. It defaults to 0, which audits none.
+func WithCheckSampleRate(rate float64) Option {
+	return func(l *Logger) {
+		l.checkSampleRate = rate
+	}
+}
+
+// WithLogger sets the logger the errors of the sinks are logged with.
+func WithLogger(logger logger.Logger) Option {
+	return func(l *Logger) {
+		l.logger = logger
+	}
+}
+
+// NewLogger returns a Logger emitting the events to the sinks. It must be closed to flush the buffered events
+// and close the sinks.
+func NewLogger(sinks []Sink, opts ...Option) *Logger {
+	l := &Logger{
+		sinks:  sinks,
+		logger: logger.NewNoopLogger(),
+		done:   make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	if l.events == nil {
+		l.events = make(chan *Event, defaultBufferSize)
+	}
+
+	go l.run()
+
+	return l
+}
+
+// Emit records the event of a request, setting its time and the principal of the context if they are not set.
+func (l *Logger) Emit(ctx context.Context, event *Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	if event.Principal == "" {
+		if claims, ok := authn.AuthClaimsFromContext(ctx); ok {
+			event.Principal = claims.Subject
+		}
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if l.closed {
+		return
+	}
+
+	select {
+	case l.events <- event:
+	default:
+		auditEventsDroppedCounter.Inc()
+	}
+}
+
+// SampleCheck reports whether the decision of a Check should be audited, according to the check sample rate.
+func (l *Logger) SampleCheck() bool {
+	return l.checkSampleRate > 0 && rand.Float64() < l.checkSampleRate
+}
+
+// Close writes the buffered events and closes the sinks. The events emitted after Close are lost.
+func (l *Logger) Close() {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return
+	}
+	l.closed = true
+	close(l.events)
+	l.mu.Unlock()
+
+	<-l.done
+
+	for _, sink := range l.sinks {
+		if err := sink.Close(); err != nil {
+			l.logger.Error("failed to close the audit sink", zap.Error(err))
+		}
+	}
+}
+
+func (l *Logger) run() {
+	defer close(l.done)
+
+	for event := range l.events {
+		for _, sink := range l.sinks {
+			if err := sink.Write(event); err != nil {
+				l.logger.Error("failed to write the audit event", zap.String("type", string(event.Type)), zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/openfga/openfga/internal/authn"
+)
+
+// recordingSink records the events written to it.
+type recordingSink struct {
+	mu     sync.Mutex
+	events []*Event
+	closed bool
+
+	// block, if set, delays the writes until it is closed
+	block chan struct{}
+}
+
+func (s *recordingSink) Write(event *Event) error {
+	if s.block != nil {
+		<-s.block
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *recordingSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func TestLogger(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	t.Run("emits_the_events_with_the_principal", func(t *testing.T) {
+		sink := &recordingSink{}
+		l := NewLogger([]Sink{sink})
+
+		ctx := authn.ContextWithAuthClaims(context.Background(), &authn.AuthClaims{Subject: "client-a"})
+		l.Emit(ctx, &Event{Type: EventCreateStore, StoreID: "01H"})
+		l.Emit(context.Background(), &Event{Type: EventDeleteStore, StoreID: "01H"})
+		l.Close()
+
+		require.True(t, sink.closed)
+		require.Len(t, sink.events, 2)
+		require.Equal(t, EventCreateStore, sink.events[0].Type)
+		require.Equal(t, "client-a", sink.events[0].Principal)
+		require.False(t, sink.events[0].Time.IsZero())
+		require.Equal(t, EventDeleteStore, sink.events[1].Type)
+		require.Empty(t, sink.events[1].Principal)
+	})
+
+	t.Run("drops_the_events_beyond_the_buffer", func(t *testing.T) {
+		sink := &recordingSink{block: make(chan struct{})}
+		l := NewLogger([]Sink{sink}, WithBufferSize(1))
+
+		// the first event is being written while the second one fills the buffer
+		for i := 0; i < 10; i++ {
+			l.Emit(context.Background(), &Event{Type: EventWrite})
+		}
+		close(sink.block)
+		l.Close()
+
+		require.NotEmpty(t, sink.events)
+		require.Less(t, len(sink.events), 10)
+	})
+
+	t.Run("ignores_the_events_once_closed", func(t *testing.T) {
+		sink := &recordingSink{}
+		l := NewLogger([]Sink{sink})
+		l.Close()
+		l.Close()
+
+		l.Emit(context.Background(), &Event{Type: EventWrite})
+		require.Empty(t, sink.events)
+	})
+
+	t.Run("samples_the_checks", func(t *testing.T) {
+		l := NewLogger(nil)
+		defer l.Close()
+		require.False(t, l.SampleCheck())
+
+		l = NewLogger(nil, WithCheckSampleRate(1))
+		defer l.Close()
+		require.True(t, l.SampleCheck())
+	})
+}
+
+func TestJSONSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewJSONSink(&buf)
+
+	require.NoError(t, sink.Write(&Event{Type: EventWrite, StoreID: "01H", Writes: []string{"document:1#viewer@user:anne"}}))
+	require.NoError(t, sink.Write(&Event{Type: EventCheck, TupleKey: "document:1#viewer@user:anne", Allowed: true}))
+	require.NoError(t, sink.Close())
+
+	lines := readLines(t, &buf)
+	require.Len(t, lines, 2)
+	require.Equal(t, EventWrite, lines[0].Type)
+	require.Equal(t, []string{"document:1#viewer@user:anne"}, lines[0].Writes)
+	require.True(t, lines[1].Allowed)
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	line, err := json.Marshal(&Event{Type: EventCreateStore})
+	require.NoError(t, err)
+
+	// two events per file
+	sink, err := NewFileSink(path, int64(2*(len(line)+1)), 2)
+	require.NoError(t, err)
+
+	for i := 0; i < 7; i++ {
+		require.NoError(t, sink.Write(&Event{Type: EventCreateStore}))
+	}
+	require.NoError(t, sink.Close())
+
+	require.Len(t, readFileLines(t, path), 1)
+	require.Len(t, readFileLines(t, path+".1"), 2)
+	require.Len(t, readFileLines(t, path+".2"), 2)
+	require.NoFileExists(t, path+".3")
+
+	t.Run("appends_to_the_existing_file", func(t *testing.T) {
+		sink, err := NewFileSink(path, 0, 0)
+		require.NoError(t, err)
+		require.NoError(t, sink.Write(&Event{Type: EventDeleteStore}))
+		require.NoError(t, sink.Close())
+
+		lines := readFileLines(t, path)
+		require.Len(t, lines, 2)
+		require.Equal(t, EventDeleteStore, lines[1].Type)
+	})
+}
+
+func TestWebhookSink(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received []*Event
+		status   = http.StatusOK
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		var event Event
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, &event)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	sink := NewWebhookSink(srv.URL)
+	defer sink.Close()
+
+	require.NoError(t, sink.Write(&Event{Type: EventWriteAuthorizationModel, AuthorizationModelID: "01M"}))
+	require.Len(t, received, 1)
+	require.Equal(t, "01M", received[0].AuthorizationModelID)
+
+	mu.Lock()
+	status = http.StatusInternalServerError
+	mu.Unlock()
+	require.EqualError(t, sink.Write(&Event{Type: EventWrite}), "failed to send the audit event: the webhook answered with status 500")
+}
+
+func readFileLines(t *testing.T, path string) []*Event {
+	t.Helper()
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	return readLines(t, file)
+}
+
+func readLines(t *testing.T, r io.Reader) []*Event {
+	t.Helper()
+
+	var events []*Event
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var event Event
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		events = append(events, &event)
+	}
+	require.NoError(t, scanner.Err())
+
+	return events
+}
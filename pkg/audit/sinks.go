@@ -0,0 +1,178 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+const defaultWebhookTimeout = 5 * time.Second
+
+// JSONSink writes the events to a writer, e.g. os.Stdout, as JSON objects, one per line.
+type JSONSink struct {
+	encoder *json.Encoder
+}
+
+var _ Sink = (*JSONSink)(nil)
+
+// NewJSONSink returns a JSONSink writing to w. The writer is not closed by the sink.
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{encoder: json.NewEncoder(w)}
+}
+
+// Write see [Sink].Write.
+func (s *JSONSink) Write(event *Event) error {
+	return s.encoder.Encode(event)
+}
+
+// Close see [Sink].Close.
+func (s *JSONSink) Close() error {
+	return nil
+}
+
+// FileSink writes the events to a file as JSON objects, one per line. Once the file reaches its maximum size,
+// it is rotated: it is renamed with the '.1' suffix, the previous '.1' file with the '.2' suffix, and so on, up
+// to the maximum number of backups, the oldest ones being deleted.
+type FileSink struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	file *os.File
+	size int64
+}
+
+var _ Sink = (*FileSink)(nil)
+
+// NewFileSink returns a FileSink appending to the file at the path, created if it does not exist. A maxSize of
+// 0 disables the rotation.
+func NewFileSink(path string, maxSize int64, maxBackups int) (*FileSink, error) {
+	s := &FileSink{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *FileSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open the audit file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to open the audit file: %w", err)
+	}
+
+	s.file = file
+	s.size = info.Size()
+
+	return nil
+}
+
+// Write see [Sink].Write.
+func (s *FileSink) Write(event *Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if s.maxSize > 0 && s.size > 0 && s.size+int64(len(line)) > s.maxSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+
+	return err
+}
+
+func (s *FileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("failed to rotate the audit file: %w", err)
+	}
+
+	if s.maxBackups > 0 {
+		for i := s.maxBackups - 1; i > 0; i-- {
+			if err := os.Rename(s.backupPath(i), s.backupPath(i+1)); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to rotate the audit file: %w", err)
+			}
+		}
+
+		if err := os.Rename(s.path, s.backupPath(1)); err != nil {
+			return fmt.Errorf("failed to rotate the audit file: %w", err)
+		}
+	} else if err := os.Remove(s.path); err != nil {
+		return fmt.Errorf("failed to rotate the audit file: %w", err)
+	}
+
+	return s.open()
+}
+
+func (s *FileSink) backupPath(i int) string {
+	return fmt.Sprintf("%s.%d", s.path, i)
+}
+
+// Close see [Sink].Close.
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+// WebhookSink sends the events to an HTTP endpoint, one POST request with the JSON event per event, e.g. to
+// forward them to a SIEM or to a message broker such as Kafka through its HTTP bridge.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+var _ Sink = (*WebhookSink)(nil)
+
+// NewWebhookSink returns a WebhookSink posting to the URL. The requests time out after 5s.
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{
+		url:    url,
+		client: &http.Client{Timeout: defaultWebhookTimeout},
+	}
+}
+
+// Write see [Sink].Write. It fails if the endpoint does not answer with a 2xx status.
+func (s *WebhookSink) Write(event *Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to send the audit event: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("failed to send the audit event: the webhook answered with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Close see [Sink].Close.
+func (s *WebhookSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
@@ -100,6 +100,7 @@ type OptionsLogger struct {
 	format          string
 	level           string
 	timestampFormat string
+	atomicLevel     *zap.AtomicLevel
 }
 
 type OptionLogger func(ol *OptionsLogger)
@@ -122,6 +123,14 @@ func WithTimestampFormat(timestampFormat string) OptionLogger {
 	}
 }
 
+// WithAtomicLevel makes the logger log at the given atomic level, which is set to the level of the logger,
+// so that the level can be changed while the logger is in use, e.g. on a configuration reload.
+func WithAtomicLevel(level zap.AtomicLevel) OptionLogger {
+	return func(ol *OptionsLogger) {
+		ol.atomicLevel = &level
+	}
+}
+
 func NewLogger(options ...OptionLogger) (*ZapLogger, error) {
 	logOptions := &OptionsLogger{
 		level:           "info",
@@ -142,6 +151,11 @@ func NewLogger(options ...OptionLogger) (*ZapLogger, error) {
 		return nil, fmt.Errorf("unknown log level: %s, error: %w", logOptions.level, err)
 	}
 
+	if logOptions.atomicLevel != nil {
+		logOptions.atomicLevel.SetLevel(level.Level())
+		level = *logOptions.atomicLevel
+	}
+
 	cfg := zap.NewProductionConfig()
 	cfg.Level = level
 	cfg.EncoderConfig.TimeKey = "timestamp"
@@ -124,3 +124,14 @@ func TestWithFields(t *testing.T) {
 	}
 	require.Equal(t, expectedZapFields, actualMessage.ContextMap())
 }
+
+func TestWithAtomicLevel(t *testing.T) {
+	level := zap.NewAtomicLevel()
+	logger, err := NewLogger(WithLevel("warn"), WithAtomicLevel(level))
+	require.NoError(t, err)
+	require.Equal(t, zapcore.WarnLevel, level.Level())
+	require.False(t, logger.Core().Enabled(zapcore.InfoLevel))
+
+	level.SetLevel(zapcore.DebugLevel)
+	require.True(t, logger.Core().Enabled(zapcore.DebugLevel))
+}
@@ -0,0 +1,87 @@
+// Package consistency sets the consistency preference of the requests selecting one with a header in their
+// context, see [storage.ContextWithConsistency].
+package consistency
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/openfga/openfga/pkg/storage"
+)
+
+const (
+	// ConsistencyHeader is the request header a client may select the consistency preference of its request
+	// with, e.g. to observe a Write it just made in its next Check. Over HTTP, it must be sent as
+	// 'Grpc-Metadata-Openfga-Consistency'.
+	ConsistencyHeader = "openfga-consistency"
+
+	// the values of the ConsistencyHeader, see [storage.ConsistencyHigher] and [storage.ConsistencyMinimizeLatency]
+	HigherConsistency = "HIGHER_CONSISTENCY"
+	MinimizeLatency   = "MINIMIZE_LATENCY"
+)
+
+// NewUnaryInterceptor returns a grpc.UnaryServerInterceptor setting the consistency preference of the
+// ConsistencyHeader, if any, in the context of the calls. The calls with an unknown preference fail with
+// InvalidArgument.
+func NewUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := contextWithHeaderConsistency(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// NewStreamingInterceptor returns a grpc.StreamServerInterceptor like the one of NewUnaryInterceptor.
+func NewStreamingInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := contextWithHeaderConsistency(stream.Context())
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &wrappedStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+type wrappedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *wrappedStream) Context() context.Context {
+	return s.ctx
+}
+
+func contextWithHeaderConsistency(ctx context.Context) (context.Context, error) {
+	values := metadata.ValueFromIncomingContext(ctx, ConsistencyHeader)
+	if len(values) == 0 {
+		return ctx, nil
+	}
+
+	preference, err := ParsePreference(values[0])
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return storage.ContextWithConsistency(ctx, preference), nil
+}
+
+// ParsePreference returns the consistency preference named HigherConsistency or MinimizeLatency.
+func ParsePreference(name string) (storage.ConsistencyPreference, error) {
+	switch name {
+	case HigherConsistency:
+		return storage.ConsistencyHigher, nil
+	case MinimizeLatency:
+		return storage.ConsistencyMinimizeLatency, nil
+	default:
+		return 0, fmt.Errorf("unknown consistency preference '%s', expected '%s' or '%s'", name, HigherConsistency, MinimizeLatency)
+	}
+}
@@ -0,0 +1,53 @@
+package consistency
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/openfga/openfga/pkg/storage"
+)
+
+func TestUnaryInterceptor(t *testing.T) {
+	interceptor := NewUnaryInterceptor()
+
+	call := func(ctx context.Context) (storage.ConsistencyPreference, error) {
+		var preference storage.ConsistencyPreference
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			preference = storage.ConsistencyFromContext(ctx)
+			return nil, nil
+		})
+		return preference, err
+	}
+
+	t.Run("no_header", func(t *testing.T) {
+		preference, err := call(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, storage.ConsistencyMinimizeLatency, preference)
+	})
+
+	t.Run("higher_consistency", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(ConsistencyHeader, HigherConsistency))
+		preference, err := call(ctx)
+		require.NoError(t, err)
+		require.Equal(t, storage.ConsistencyHigher, preference)
+	})
+
+	t.Run("minimize_latency", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(ConsistencyHeader, MinimizeLatency))
+		preference, err := call(ctx)
+		require.NoError(t, err)
+		require.Equal(t, storage.ConsistencyMinimizeLatency, preference)
+	})
+
+	t.Run("unknown_preference", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(ConsistencyHeader, "STRONG"))
+		_, err := call(ctx)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"sync/atomic"
 	"time"
 
 	grpcvalidator "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/validator"
@@ -12,17 +13,23 @@ import (
 
 // TimeoutInterceptor sets the timeout in each request.
 type TimeoutInterceptor struct {
-	timeout time.Duration
+	timeout atomic.Int64 // time.Duration
 	logger  logger.Logger
 }
 
 // NewTimeoutInterceptor returns new TimeoutInterceptor that timeouts request if it
 // exceeds the timeout value.
 func NewTimeoutInterceptor(timeout time.Duration, logger logger.Logger) *TimeoutInterceptor {
-	return &TimeoutInterceptor{
-		timeout: timeout,
-		logger:  logger,
+	h := &TimeoutInterceptor{
+		logger: logger,
 	}
+	h.timeout.Store(int64(timeout))
+	return h
+}
+
+// SetTimeout changes the timeout of the requests received from now on, e.g. on a configuration reload.
+func (h *TimeoutInterceptor) SetTimeout(timeout time.Duration) {
+	h.timeout.Store(int64(timeout))
 }
 
 // NewUnaryTimeoutInterceptor returns an interceptor that will timeout according to the configured timeout.
@@ -30,7 +37,7 @@ func NewTimeoutInterceptor(timeout time.Duration, logger logger.Logger) *Timeout
 // to return proper error code.
 func (h *TimeoutInterceptor) NewUnaryTimeoutInterceptor() grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		ctx, cancel := context.WithTimeout(ctx, h.timeout)
+		ctx, cancel := context.WithTimeout(ctx, time.Duration(h.timeout.Load()))
 		defer cancel()
 		return handler(ctx, req)
 	}
@@ -43,7 +50,7 @@ func (h *TimeoutInterceptor) NewStreamTimeoutInterceptor() grpc.StreamServerInte
 	validator := grpcvalidator.StreamServerInterceptor()
 	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
 		return validator(srv, stream, info, func(srv interface{}, ss grpc.ServerStream) error {
-			ctx, cancel := context.WithTimeout(stream.Context(), h.timeout)
+			ctx, cancel := context.WithTimeout(stream.Context(), time.Duration(h.timeout.Load()))
 			defer cancel()
 
 			return handler(srv, &recvWrapper{
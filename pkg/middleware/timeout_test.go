@@ -36,10 +36,7 @@ func (m mockServerGRPCStream) RecvMsg(any) error {
 }
 
 func TestNewUnaryTimeoutInterceptor(t *testing.T) {
-	timeoutInterceptor := TimeoutInterceptor{
-		timeout: 5 * time.Millisecond,
-		logger:  logger.NewNoopLogger(),
-	}
+	timeoutInterceptor := NewTimeoutInterceptor(5*time.Millisecond, logger.NewNoopLogger())
 
 	handler := func(ctx context.Context, req any) (any, error) {
 		select {
@@ -55,10 +52,7 @@ func TestNewUnaryTimeoutInterceptor(t *testing.T) {
 }
 
 func TestNewStreamTimeoutInterceptor(t *testing.T) {
-	timeoutInterceptor := TimeoutInterceptor{
-		timeout: 5 * time.Millisecond,
-		logger:  logger.NewNoopLogger(),
-	}
+	timeoutInterceptor := NewTimeoutInterceptor(5*time.Millisecond, logger.NewNoopLogger())
 
 	handler := func(srv any, stream grpc.ServerStream) error {
 		ctx := stream.Context()
@@ -73,3 +67,18 @@ func TestNewStreamTimeoutInterceptor(t *testing.T) {
 	err := interceptor(nil, mockServerGRPCStream{ctx: context.Background()}, nil, handler)
 	require.ErrorIs(t, err, context.DeadlineExceeded)
 }
+
+func TestSetTimeout(t *testing.T) {
+	timeoutInterceptor := NewTimeoutInterceptor(time.Hour, logger.NewNoopLogger())
+	timeoutInterceptor.SetTimeout(5 * time.Millisecond)
+
+	handler := func(ctx context.Context, req any) (any, error) {
+		deadline, ok := ctx.Deadline()
+		require.True(t, ok)
+		require.WithinDuration(t, time.Now().Add(5*time.Millisecond), deadline, time.Second)
+		return nil, nil
+	}
+	interceptor := timeoutInterceptor.NewUnaryTimeoutInterceptor()
+	_, err := interceptor(context.Background(), nil, nil, handler)
+	require.NoError(t, err)
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage"
+)
+
+// ArchiveAuthorizationModel archives (soft-deletes) the authorization model of the store. The model can
+// still be read, and Checks resolved against it behave as set with WithArchivedModelBehavior.
+// It returns AuthorizationModelArchiveUnsupported if the datastore cannot archive models.
+func (s *Server) ArchiveAuthorizationModel(ctx context.Context, storeID, modelID string) error {
+	ctx, span := tracer.Start(ctx, "ArchiveAuthorizationModel", trace.WithAttributes(
+		attribute.String("store_id", storeID),
+		attribute.String(authorizationModelIDKey, modelID),
+	))
+	defer span.End()
+
+	if s.modelArchiveBackend == nil {
+		return serverErrors.AuthorizationModelArchiveUnsupported
+	}
+
+	if err := s.modelArchiveBackend.ArchiveAuthorizationModel(ctx, storeID, modelID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return serverErrors.AuthorizationModelNotFound(modelID)
+		}
+
+		return serverErrors.HandleError("", err)
+	}
+
+	return nil
+}
+
+// checkModelArchived returns AuthorizationModelArchived if the model was archived and Checks against archived
+// models fail. The archived flag is not cached, so archiving a model takes effect right away.
+func (s *Server) checkModelArchived(ctx context.Context, storeID, modelID string) error {
+	if s.modelArchiveBackend == nil || s.archivedModelBehavior == ArchivedModelResolve {
+		return nil
+	}
+
+	archived, err := s.modelArchiveBackend.IsAuthorizationModelArchived(ctx, storeID, modelID)
+	if err != nil {
+		return serverErrors.HandleError("", err)
+	}
+
+	if archived {
+		return serverErrors.AuthorizationModelArchived
+	}
+
+	return nil
+}
@@ -0,0 +1,191 @@
+package server
+
+import (
+	"context"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/openfga/openfga/internal/validation"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/tuple"
+)
+
+// AssertionResult is the outcome of an assertion evaluated by RunAssertions.
+type AssertionResult struct {
+	Assertion *storage.Assertion
+
+	// Allowed is the result of the Check of the assertion.
+	Allowed bool
+	// Passed is set if the Check succeeded and its result is the expectation of the assertion.
+	Passed bool
+	// Err is the error of the Check of the assertion, if it failed.
+	Err error
+	// Trace is how the Check of a failed assertion was resolved, e.g. to report why a user was unexpectedly
+	// allowed. It is nil for the assertions that passed and for the ones whose Check failed.
+	Trace *CheckTrace
+}
+
+// RunAssertionsResponse holds the outcome of the assertions of a model, in the order they were written.
+type RunAssertionsResponse struct {
+	AuthorizationModelID string
+	Results              []*AssertionResult
+	// Passed is set if every assertion passed.
+	Passed bool
+}
+
+// WriteContextualAssertions is like WriteAssertions, with the contextual tuples and the condition context each
+// assertion is checked with. An empty modelID writes the assertions of the default model of the store.
+// It returns ContextualAssertionsUnsupported if the datastore cannot store the context of the assertions.
+func (s *Server) WriteContextualAssertions(ctx context.Context, storeID, modelID string, assertions []*storage.Assertion) error {
+	ctx, span := tracer.Start(ctx, "WriteContextualAssertions", trace.WithAttributes(
+		attribute.String("store_id", storeID),
+		attribute.String(authorizationModelIDKey, modelID),
+	))
+	defer span.End()
+
+	if s.contextualAssertionsBackend == nil {
+		return serverErrors.ContextualAssertionsUnsupported
+	}
+
+	typesys, err := s.resolveTypesystem(ctx, storeID, modelID)
+	if err != nil {
+		return err
+	}
+
+	for _, assertion := range assertions {
+		if err := validation.ValidateUserObjectRelation(typesys, tuple.ConvertAssertionTupleKeyToTupleKey(assertion.TupleKey)); err != nil {
+			return serverErrors.ValidationError(err)
+		}
+
+		if err := validation.ValidateContextualTuples(typesys, assertion.ContextualTuples); err != nil {
+			return serverErrors.HandleTupleValidateError(err)
+		}
+	}
+
+	err = s.contextualAssertionsBackend.WriteContextualAssertions(ctx, storeID, typesys.GetAuthorizationModelID(), assertions)
+	if err != nil {
+		return serverErrors.HandleError("", err)
+	}
+
+	return nil
+}
+
+// ReadContextualAssertions is like ReadAssertions, with the contextual tuples and the condition context of the
+// assertions. An empty modelID reads the assertions of the default model of the store.
+// It returns ContextualAssertionsUnsupported if the datastore cannot store the context of the assertions.
+func (s *Server) ReadContextualAssertions(ctx context.Context, storeID, modelID string) ([]*storage.Assertion, error) {
+	ctx, span := tracer.Start(ctx, "ReadContextualAssertions", trace.WithAttributes(
+		attribute.String("store_id", storeID),
+		attribute.String(authorizationModelIDKey, modelID),
+	))
+	defer span.End()
+
+	if s.contextualAssertionsBackend == nil {
+		return nil, serverErrors.ContextualAssertionsUnsupported
+	}
+
+	typesys, err := s.resolveTypesystem(ctx, storeID, modelID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.readAssertions(ctx, storeID, typesys.GetAuthorizationModelID())
+}
+
+// RunAssertions checks every assertion of the model and reports which ones passed, so that a change of the
+// model can be gated on its assertions, e.g. in CI. An empty modelID runs the assertions of the default model of
+// the store. The assertions are checked with their contextual tuples and condition context, if the datastore
+// stores them, and a failed assertion is reported along with the resolution trace of its Check. An assertion
+// whose Check fails is reported as failed, along with the error.
+func (s *Server) RunAssertions(ctx context.Context, storeID, modelID string) (*RunAssertionsResponse, error) {
+	ctx, span := tracer.Start(ctx, "RunAssertions", trace.WithAttributes(
+		attribute.String("store_id", storeID),
+		attribute.String(authorizationModelIDKey, modelID),
+	))
+	defer span.End()
+
+	typesys, err := s.resolveTypesystem(ctx, storeID, modelID)
+	if err != nil {
+		return nil, err
+	}
+	modelID = typesys.GetAuthorizationModelID() // the resolved model id
+
+	assertions, err := s.readAssertions(ctx, storeID, modelID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &RunAssertionsResponse{
+		AuthorizationModelID: modelID,
+		Results:              make([]*AssertionResult, 0, len(assertions)),
+		Passed:               true,
+	}
+	for _, assertion := range assertions {
+		result := s.runAssertion(ctx, storeID, modelID, assertion)
+		res.Results = append(res.Results, result)
+		res.Passed = res.Passed && result.Passed
+	}
+
+	span.SetAttributes(attribute.Bool("passed", res.Passed))
+
+	return res, nil
+}
+
+func (s *Server) runAssertion(ctx context.Context, storeID, modelID string, assertion *storage.Assertion) *AssertionResult {
+	result := &AssertionResult{Assertion: assertion}
+
+	var checkTrace CheckTrace
+	resp, err := s.CheckWithOptions(ctx, &openfgav1.CheckRequest{
+		StoreId:              storeID,
+		AuthorizationModelId: modelID,
+		TupleKey: &openfgav1.CheckRequestTupleKey{
+			Object:   assertion.TupleKey.GetObject(),
+			Relation: assertion.TupleKey.GetRelation(),
+			User:     assertion.TupleKey.GetUser(),
+		},
+		ContextualTuples: &openfgav1.ContextualTupleKeys{TupleKeys: assertion.ContextualTuples},
+		Context:          assertion.Context,
+	}, WithResolutionTrace(&checkTrace))
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	result.Allowed = resp.GetAllowed()
+	result.Passed = result.Allowed == assertion.Expectation
+	if !result.Passed {
+		result.Trace = &checkTrace
+	}
+
+	return result
+}
+
+// readAssertions reads the assertions of the model, with their context if the datastore stores it.
+func (s *Server) readAssertions(ctx context.Context, storeID, modelID string) ([]*storage.Assertion, error) {
+	if s.contextualAssertionsBackend != nil {
+		assertions, err := s.contextualAssertionsBackend.ReadContextualAssertions(ctx, storeID, modelID)
+		if err != nil {
+			return nil, serverErrors.HandleError("", err)
+		}
+
+		return assertions, nil
+	}
+
+	protoAssertions, err := s.datastore.ReadAssertions(ctx, storeID, modelID)
+	if err != nil {
+		return nil, serverErrors.HandleError("", err)
+	}
+
+	assertions := make([]*storage.Assertion, 0, len(protoAssertions))
+	for _, assertion := range protoAssertions {
+		assertions = append(assertions, &storage.Assertion{
+			TupleKey:    assertion.GetTupleKey(),
+			Expectation: assertion.GetExpectation(),
+		})
+	}
+
+	return assertions, nil
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"context"
+
+	"github.com/openfga/openfga/pkg/audit"
+	"github.com/openfga/openfga/pkg/tuple"
+)
+
+// WithAuditLogger emits an audit event for every Write, WriteAuthorizationModel, CreateStore and DeleteStore
+// request, successful or not, and for the Check decisions sampled by the logger, see audit.WithCheckSampleRate.
+// The principal of the events is the subject of the authenticated client. The logger is not closed by the
+// server.
+func WithAuditLogger(logger *audit.Logger) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.auditLogger = logger
+	}
+}
+
+// audit emits the event, recording the error the request failed with, if any.
+func (s *Server) audit(ctx context.Context, event *audit.Event, err error) {
+	if s.auditLogger == nil {
+		return
+	}
+
+	if err != nil {
+		event.Error = err.Error()
+	}
+
+	s.auditLogger.Emit(ctx, event)
+}
+
+// tupleStrings returns the string form of the tuples, e.g. 'document:1#viewer@user:anne'.
+func tupleStrings[T tuple.TupleWithoutCondition](tks []T) []string {
+	if len(tks) == 0 {
+		return nil
+	}
+
+	strs := make([]string, 0, len(tks))
+	for _, tk := range tks {
+		strs = append(strs, tuple.TupleKeyToString(tk))
+	}
+
+	return strs
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/storagewrappers"
+	"github.com/openfga/openfga/pkg/typesystem"
+)
+
+// CheckOption sets an option of a single Check made with CheckWithOptions.
+type CheckOption func(*checkOptions)
+
+type checkOptions struct {
+	maxIndirectionDepth uint32
+	modelFragments      []*openfgav1.AuthorizationModel
+	resolverChain       string
+	trace               *CheckTrace
+	consistency         *storage.ConsistencyPreference
+	tupleOverlay        *storagewrappers.TupleOverlay
+}
+
+// CheckTrace reports how a Check made with WithResolutionTrace was resolved.
+type CheckTrace struct {
+	// Path holds the usersets the resolution went through to reach the decision, from the requested one to
+	// the one the user was found in or, if the Check was denied, the one the resolution stopped at, e.g.
+	// ['document:1#viewer', 'document:1#editor', 'group:eng#member']. A userset the decision was reached
+	// by excluding is prefixed with 'but not '.
+	Path []string
+	// DispatchCount is the number of subproblems that were dispatched while resolving the Check.
+	DispatchCount uint32
+	// DatastoreQueryCount is the number of datastore queries made while resolving the Check.
+	DatastoreQueryCount uint32
+}
+
+// WithMaxIndirectionDepth limits how many userset tuples (e.g. 'document:1#viewer@group:eng#member') the Check
+// follows to find the user. With a depth of 1, only direct grants and grants through one level of group membership
+// are considered, and users that are only members of nested groups are denied. A depth of 0 (the default) follows
+// any number of userset tuples, up to the resolve node limit.
+func WithMaxIndirectionDepth(depth uint32) CheckOption {
+	return func(o *checkOptions) {
+		o.maxIndirectionDepth = depth
+	}
+}
+
+// WithModelFragments resolves the Check against the model composed of the fragments, e.g. the modules a model is
+// split into, instead of a model of the store. The fragments are merged with [typesystem.MergeModels], and the
+// Check fails with a validation error if they conflict or if the merged model is invalid. The authorization
+// model ID of the request is ignored.
+func WithModelFragments(fragments ...*openfgav1.AuthorizationModel) CheckOption {
+	return func(o *checkOptions) {
+		o.modelFragments = fragments
+	}
+}
+
+// WithCheckResolverChain resolves the Check with the resolver chain registered on the server under the name with
+// WithNamedCheckResolverChain, instead of the default one, e.g. to route a fraction of the traffic to an experimental
+// chain. The Check fails with a validation error if no chain is registered under the name.
+func WithCheckResolverChain(name string) CheckOption {
+	return func(o *checkOptions) {
+		o.resolverChain = name
+	}
+}
+
+// WithResolutionTrace records in trace how the Check was resolved, e.g. to debug why it was allowed or denied.
+// The trace is only set if the Check succeeds.
+func WithResolutionTrace(trace *CheckTrace) CheckOption {
+	return func(o *checkOptions) {
+		o.trace = trace
+	}
+}
+
+// WithConsistency sets the consistency preference of the Check, instead of the one of the context if any (see
+// storage.ContextWithConsistency). With storage.ConsistencyHigher, the Check is not answered from the check query
+// cache and only reads from the primary datastore, e.g. to observe a Write the caller just made.
+func WithConsistency(preference storage.ConsistencyPreference) CheckOption {
+	return func(o *checkOptions) {
+		o.consistency = &preference
+	}
+}
+
+// WithTupleOverlay resolves the Check as if the added tuples of the overlay had been written and its removed tuples
+// deleted, e.g. to preview the effect of a change of the tuples before making it. The added tuples are validated
+// like the contextual tuples, and the results of the Check are only cached for the Checks with the same overlay.
+func WithTupleOverlay(overlay storagewrappers.TupleOverlay) CheckOption {
+	return func(o *checkOptions) {
+		o.tupleOverlay = &overlay
+	}
+}
+
+// CheckWithOptions is like Check, with the options applying to this request only.
+func (s *Server) CheckWithOptions(ctx context.Context, req *openfgav1.CheckRequest, opts ...CheckOption) (*openfgav1.CheckResponse, error) {
+	var o checkOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return s.check(ctx, req, o)
+}
+
+// mergedTypesystem returns the typesystem of the model composed of the fragments. The model is identified
+// by its hash, so that the Checks resolved against the same fragments share their cached subproblems.
+func (s *Server) mergedTypesystem(ctx context.Context, storeID string, fragments []*openfgav1.AuthorizationModel) (*typesystem.TypeSystem, error) {
+	model, err := typesystem.MergeModels(fragments...)
+	if err != nil {
+		return nil, serverErrors.ValidationError(err)
+	}
+
+	model.Id, err = typesystem.ModelHash(typesystem.New(model))
+	if err != nil {
+		return nil, serverErrors.HandleError("", err)
+	}
+
+	conditionEnv, err := s.conditionEnv(storeID)
+	if err != nil {
+		return nil, err
+	}
+
+	typesys, err := typesystem.NewAndValidate(ctx, model, typesystem.WithConditionEnv(conditionEnv))
+	if err != nil {
+		return nil, serverErrors.ValidationError(err)
+	}
+
+	return typesys, nil
+}
@@ -19,6 +19,7 @@ import (
 type ExpandQuery struct {
 	logger    logger.Logger
 	datastore storage.OpenFGADatastore
+	maxDepth  uint32
 }
 
 type ExpandQueryOption func(*ExpandQuery)
@@ -29,6 +30,17 @@ func WithExpandQueryLogger(l logger.Logger) ExpandQueryOption {
 	}
 }
 
+// WithExpandQueryMaxDepth sets how many levels of usersets the tree is expanded down. With a depth of 0 (the
+// default), only the rewrite of the requested relation is expanded, and its leaves reference the computed
+// usersets, the tuple to usersets and the userset users (e.g. 'group:eng#member') without expanding them. Each
+// additional level expands the usersets referenced by the leaves of the previous one, so that a large enough
+// depth resolves the tree down to concrete users.
+func WithExpandQueryMaxDepth(depth uint32) ExpandQueryOption {
+	return func(eq *ExpandQuery) {
+		eq.maxDepth = depth
+	}
+}
+
 // NewExpandQuery creates a new ExpandQuery using the supplied backends for retrieving data.
 func NewExpandQuery(datastore storage.OpenFGADatastore, opts ...ExpandQueryOption) *ExpandQuery {
 	eq := &ExpandQuery{
@@ -103,6 +115,14 @@ func (q *ExpandQuery) Execute(ctx context.Context, req *openfgav1.ExpandRequest)
 		return nil, err
 	}
 
+	if q.maxDepth > 0 {
+		visitedPaths := map[string]struct{}{toObjectRelation(tk): {}}
+		root, err = q.expandLeaves(ctx, store, root, typesys, q.maxDepth, visitedPaths)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &openfgav1.ExpandResponse{
 		Tree: &openfgav1.UsersetTree{
 			Root: root,
@@ -419,6 +439,121 @@ func (q *ExpandQuery) resolveUsersets(
 	return out, nil
 }
 
+// expandLeaves expands the usersets referenced by the leaves of the tree, down to depth levels. A leaf
+// referencing usersets is replaced by a union node of the same name, holding the leaf followed by the trees
+// of the usersets it references. Like the CycleDetectionCheckResolver does for Check, the usersets on the path
+// from the root are tracked in visitedPaths, and a userset that would be expanded within its own tree is left
+// unexpanded instead.
+func (q *ExpandQuery) expandLeaves(
+	ctx context.Context,
+	store string,
+	node *openfgav1.UsersetTree_Node,
+	typesys *typesystem.TypeSystem,
+	depth uint32,
+	visitedPaths map[string]struct{},
+) (*openfgav1.UsersetTree_Node, error) {
+	var children []*openfgav1.UsersetTree_Node
+	switch value := node.GetValue().(type) {
+	case *openfgav1.UsersetTree_Node_Union:
+		children = value.Union.GetNodes()
+	case *openfgav1.UsersetTree_Node_Intersection:
+		children = value.Intersection.GetNodes()
+	case *openfgav1.UsersetTree_Node_Difference:
+		children = []*openfgav1.UsersetTree_Node{value.Difference.GetBase(), value.Difference.GetSubtract()}
+	case *openfgav1.UsersetTree_Node_Leaf:
+		return q.expandLeaf(ctx, store, node, typesys, depth, visitedPaths)
+	}
+
+	for i, child := range children {
+		expanded, err := q.expandLeaves(ctx, store, child, typesys, depth, visitedPaths)
+		if err != nil {
+			return nil, err
+		}
+		children[i] = expanded
+	}
+
+	if difference := node.GetDifference(); difference != nil {
+		difference.Base, difference.Subtract = children[0], children[1]
+	}
+
+	return node, nil
+}
+
+// expandLeaf expands the usersets referenced by the leaf, see expandLeaves.
+func (q *ExpandQuery) expandLeaf(
+	ctx context.Context,
+	store string,
+	leaf *openfgav1.UsersetTree_Node,
+	typesys *typesystem.TypeSystem,
+	depth uint32,
+	visitedPaths map[string]struct{},
+) (*openfgav1.UsersetTree_Node, error) {
+	var usersets []string
+	switch value := leaf.GetLeaf().GetValue().(type) {
+	case *openfgav1.UsersetTree_Leaf_Computed:
+		usersets = append(usersets, value.Computed.GetUserset())
+	case *openfgav1.UsersetTree_Leaf_TupleToUserset:
+		for _, computed := range value.TupleToUserset.GetComputed() {
+			usersets = append(usersets, computed.GetUserset())
+		}
+	case *openfgav1.UsersetTree_Leaf_Users:
+		for _, user := range value.Users.GetUsers() {
+			if tupleUtils.IsObjectRelation(user) {
+				usersets = append(usersets, user)
+			}
+		}
+	}
+
+	nodes := []*openfgav1.UsersetTree_Node{leaf}
+	for _, userset := range usersets {
+		if _, ok := visitedPaths[userset]; ok {
+			continue
+		}
+
+		object, relation := tupleUtils.SplitObjectRelation(userset)
+		rel, err := typesys.GetRelation(tupleUtils.GetType(object), relation)
+		if err != nil {
+			// e.g. a tuple to userset whose computed relation is not defined on the type of some of the objects
+			// it is related to, which resolves to no user
+			continue
+		}
+
+		tk := tupleUtils.NewTupleKey(object, relation, "")
+		node, err := q.resolveUserset(ctx, store, rel.GetRewrite(), tk, typesys)
+		if err != nil {
+			return nil, err
+		}
+
+		if depth > 1 {
+			visited := make(map[string]struct{}, len(visitedPaths)+1)
+			for path := range visitedPaths {
+				visited[path] = struct{}{}
+			}
+			visited[userset] = struct{}{}
+
+			node, err = q.expandLeaves(ctx, store, node, typesys, depth-1, visited)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		nodes = append(nodes, node)
+	}
+
+	if len(nodes) == 1 {
+		return leaf, nil
+	}
+
+	return &openfgav1.UsersetTree_Node{
+		Name: leaf.GetName(),
+		Value: &openfgav1.UsersetTree_Node_Union{
+			Union: &openfgav1.UsersetTree_Nodes{
+				Nodes: nodes,
+			},
+		},
+	}, nil
+}
+
 func toObjectRelation(tk *openfgav1.TupleKey) string {
 	return tupleUtils.ToObjectRelationString(tk.GetObject(), tk.GetRelation())
 }
@@ -56,9 +56,19 @@ type ListObjectsQuery struct {
 	resolveNodeBreadthLimit uint32
 	maxConcurrentReads      uint32
 
+	// candidateCheckConcurrencyLimit is the maximum number of candidate objects checked at once, the
+	// resolveNodeBreadthLimit if 0
+	candidateCheckConcurrencyLimit uint32
+
 	dispatchThrottlerConfig threshold.Config
 
 	checkResolver graph.CheckResolver
+
+	// readBudget is the maximum number of datastore reads of the query, 0 if unbounded
+	readBudget uint32
+
+	// tupleCounter is used to estimate which operand of an intersection is the cheapest to expand, if set
+	tupleCounter storage.TupleCounter
 }
 
 type ListObjectsResolutionMetadata struct {
@@ -70,6 +80,10 @@ type ListObjectsResolutionMetadata struct {
 
 	// WasThrottled indicates whether the request was throttled
 	WasThrottled *atomic.Bool
+
+	// WasIncomplete indicates whether the deadline was hit, or the request cancelled, before all the objects
+	// were found, in which case only the objects found until then were returned
+	WasIncomplete *atomic.Bool
 }
 
 func NewListObjectsResolutionMetadata() *ListObjectsResolutionMetadata {
@@ -77,6 +91,7 @@ func NewListObjectsResolutionMetadata() *ListObjectsResolutionMetadata {
 		DatastoreQueryCount: new(uint32),
 		DispatchCounter:     new(atomic.Uint32),
 		WasThrottled:        new(atomic.Bool),
+		WasIncomplete:       new(atomic.Bool),
 	}
 }
 
@@ -119,6 +134,13 @@ func WithResolveNodeBreadthLimit(limit uint32) ListObjectsQueryOption {
 	}
 }
 
+// WithCandidateCheckConcurrencyLimit see server.WithListObjectsCandidateCheckConcurrencyLimit.
+func WithCandidateCheckConcurrencyLimit(limit uint32) ListObjectsQueryOption {
+	return func(d *ListObjectsQuery) {
+		d.candidateCheckConcurrencyLimit = limit
+	}
+}
+
 func WithLogger(l logger.Logger) ListObjectsQueryOption {
 	return func(d *ListObjectsQuery) {
 		d.logger = l
@@ -132,6 +154,22 @@ func WithMaxConcurrentReads(limit uint32) ListObjectsQueryOption {
 	}
 }
 
+// WithTupleCounter sets the counter used to estimate the fan-out of the operands of the intersections,
+// so that the operand expected to yield the fewest candidates is expanded and the others are checked.
+// Without it, the first operand is always expanded.
+func WithTupleCounter(counter storage.TupleCounter) ListObjectsQueryOption {
+	return func(d *ListObjectsQuery) {
+		d.tupleCounter = counter
+	}
+}
+
+// WithDatastoreReadBudget see server.WithDatastoreReadBudget.
+func WithDatastoreReadBudget(budget uint32) ListObjectsQueryOption {
+	return func(d *ListObjectsQuery) {
+		d.readBudget = budget
+	}
+}
+
 func NewListObjectsQuery(
 	ds storage.RelationshipTupleReader,
 	checkResolver graph.CheckResolver,
@@ -166,6 +204,10 @@ func NewListObjectsQuery(
 		opt(query)
 	}
 
+	if query.readBudget > 0 {
+		query.datastore = storagewrappers.NewReadBudgetTupleReader(query.datastore, query.readBudget)
+	}
+
 	query.datastore = storagewrappers.NewBoundedConcurrencyTupleReader(query.datastore, query.maxConcurrentReads)
 
 	return query, nil
@@ -216,10 +258,8 @@ func (q *ListObjectsQuery) evaluate(
 		return serverErrors.ValidationError(typesystem.ErrInvalidSchemaVersion)
 	}
 
-	for _, ctxTuple := range req.GetContextualTuples().GetTupleKeys() {
-		if err := validation.ValidateTuple(typesys, ctxTuple); err != nil {
-			return serverErrors.HandleTupleValidateError(err)
-		}
+	if err := validation.ValidateContextualTuples(typesys, req.GetContextualTuples().GetTupleKeys()); err != nil {
+		return serverErrors.HandleTupleValidateError(err)
 	}
 
 	_, err := typesys.GetRelation(targetObjectType, targetRelation)
@@ -267,6 +307,16 @@ func (q *ListObjectsQuery) evaluate(
 		reverseExpandResultsChan := make(chan *reverseexpand.ReverseExpandResult, 1)
 		objectsFound := atomic.Uint32{}
 
+		// objectsSent prevents sending the same object twice, since objects are sent as soon as they are
+		// proven accessible, by any of the paths leading to them
+		objectsSent := sync.Map{}
+		sendObject := func(object string) {
+			if _, loaded := objectsSent.LoadOrStore(object, struct{}{}); loaded {
+				return
+			}
+			trySendObject(object, &objectsFound, maxResults, resultsChan)
+		}
+
 		ds := storagewrappers.NewCombinedTupleReader(
 			q.datastore,
 			req.GetContextualTuples().GetTupleKeys(),
@@ -279,6 +329,9 @@ func (q *ListObjectsQuery) evaluate(
 			reverseexpand.WithDispatchThrottlerConfig(q.dispatchThrottlerConfig),
 			reverseexpand.WithResolveNodeBreadthLimit(q.resolveNodeBreadthLimit),
 			reverseexpand.WithLogger(q.logger),
+			reverseexpand.WithIntersectionOperandChooser(
+				newIntersectionPlanner(ctx, q.tupleCounter, req.GetStoreId(), typesys).choose,
+			),
 		)
 
 		cancelCtx, cancel := context.WithCancel(ctx)
@@ -312,12 +365,18 @@ func (q *ListObjectsQuery) evaluate(
 		ctx = typesystem.ContextWithTypesystem(ctx, typesys)
 		ctx := storage.ContextWithRelationshipTupleReader(ctx, ds)
 
-		concurrencyLimiterCh := make(chan struct{}, q.resolveNodeBreadthLimit)
+		candidateCheckConcurrencyLimit := q.candidateCheckConcurrencyLimit
+		if candidateCheckConcurrencyLimit == 0 {
+			candidateCheckConcurrencyLimit = q.resolveNodeBreadthLimit
+		}
+		concurrencyLimiterCh := make(chan struct{}, candidateCheckConcurrencyLimit)
+		var candidateChecks int
 
 	ConsumerReadLoop:
 		for {
 			select {
 			case <-ctx.Done():
+				resolutionMetadata.WasIncomplete.Store(true)
 				break ConsumerReadLoop
 			case res, channelOpen := <-reverseExpandResultsChan:
 				if !channelOpen {
@@ -330,11 +389,12 @@ func (q *ListObjectsQuery) evaluate(
 
 				if res.ResultStatus == reverseexpand.NoFurtherEvalStatus {
 					noFurtherEvalRequiredCounter.Inc()
-					trySendObject(res.Object, &objectsFound, maxResults, resultsChan)
+					sendObject(res.Object)
 					continue
 				}
 
 				furtherEvalRequiredCounter.Inc()
+				candidateChecks++
 
 				wg.Add(1)
 				go func(res *reverseexpand.ReverseExpandResult) {
@@ -360,6 +420,10 @@ func (q *ListObjectsQuery) evaluate(
 							return
 						}
 
+						if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+							resolutionMetadata.WasIncomplete.Store(true)
+						}
+
 						resultsChan <- ListObjectsResult{Err: err}
 						return
 					}
@@ -368,7 +432,7 @@ func (q *ListObjectsQuery) evaluate(
 					resolutionMetadata.WasThrottled.Store(reverseExpandResolutionMetadata.WasThrottled.Load())
 
 					if resp.Allowed {
-						trySendObject(res.Object, &objectsFound, maxResults, resultsChan)
+						sendObject(res.Object)
 					}
 				}(res)
 
@@ -377,6 +441,10 @@ func (q *ListObjectsQuery) evaluate(
 					err = serverErrors.AuthorizationModelResolutionTooComplex
 				}
 
+				if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+					resolutionMetadata.WasIncomplete.Store(true)
+				}
+
 				resultsChan <- ListObjectsResult{Err: err}
 				break ConsumerReadLoop
 			}
@@ -384,6 +452,7 @@ func (q *ListObjectsQuery) evaluate(
 
 		cancel()
 		wg.Wait()
+		graph.ObserveOperatorDispatchCount(graph.ListObjectsCandidateOperator, candidateChecks)
 		close(resultsChan)
 	}
 
@@ -464,7 +533,9 @@ func (q *ListObjectsQuery) Execute(
 
 // ExecuteStreamed executes the ListObjectsQuery, returning a stream of object IDs.
 // It ignores the value of q.listObjectsMaxResults and returns all available results
-// until q.listObjectsDeadline is hit.
+// until q.listObjectsDeadline is hit. Every object is sent once, as soon as it is proven
+// accessible, so the objects found before the deadline are delivered even if the others
+// are not; ListObjectsResolutionMetadata.WasIncomplete reports whether the deadline was hit.
 func (q *ListObjectsQuery) ExecuteStreamed(ctx context.Context, req *openfgav1.StreamedListObjectsRequest, srv openfgav1.OpenFGAService_StreamedListObjectsServer) (*ListObjectsResolutionMetadata, error) {
 	maxResults := uint32(math.MaxUint32)
 	// make a buffered channel so that writer goroutines aren't blocked when attempting to send a result
@@ -494,6 +565,12 @@ func (q *ListObjectsQuery) ExecuteStreamed(ctx context.Context, req *openfgav1.S
 				return nil, serverErrors.ValidationError(result.Err)
 			}
 
+			// the objects found before the deadline were already sent, and the others are reported missing
+			// by WasIncomplete
+			if errors.Is(result.Err, context.Canceled) || errors.Is(result.Err, context.DeadlineExceeded) {
+				continue
+			}
+
 			return nil, serverErrors.HandleError("", result.Err)
 		}
 
@@ -0,0 +1,174 @@
+package commands
+
+import (
+	"context"
+	"math"
+	"sync"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/openfga/openfga/internal/build"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+)
+
+const (
+	// intersectionPlanDefault is the strategy of expanding the first operand because the fan-out can't be estimated.
+	intersectionPlanDefault = "default"
+	// intersectionPlanFirstOperand is the strategy of expanding the first operand because it has the lowest estimated fan-out.
+	intersectionPlanFirstOperand = "first_operand"
+	// intersectionPlanOtherOperand is the strategy of expanding another operand because it has the lowest estimated fan-out.
+	intersectionPlanOtherOperand = "other_operand"
+)
+
+var listObjectsIntersectionPlanCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: build.ProjectName,
+	Name:      "list_objects_intersection_plan_count",
+	Help:      "The number of intersections planned by ListObjects calls, labeled by the strategy chosen to expand them",
+}, []string{"strategy"})
+
+// intersectionPlanner chooses, for each intersection met by the reverse expansion of a ListObjects call,
+// the operand to expand. The candidates it finds are then filtered by checking them against the other operands,
+// so the operand with the lowest estimated fan-out is chosen. The fan-out of an operand is estimated from
+// the number of tuples of the relations it depends on.
+type intersectionPlanner struct {
+	ctx     context.Context
+	counter storage.TupleCounter
+	store   string
+	typesys *typesystem.TypeSystem
+
+	mu sync.Mutex
+	// choices is keyed by the first operand of the intersection
+	choices map[*openfgav1.Userset]int
+	// tupleCounts is keyed by 'objectType#relation'
+	tupleCounts map[string]int
+}
+
+func newIntersectionPlanner(ctx context.Context, counter storage.TupleCounter, store string, typesys *typesystem.TypeSystem) *intersectionPlanner {
+	return &intersectionPlanner{
+		ctx:         ctx,
+		counter:     counter,
+		store:       store,
+		typesys:     typesys,
+		choices:     map[*openfgav1.Userset]int{},
+		tupleCounts: map[string]int{},
+	}
+}
+
+// choose implements graph.IntersectionOperandChooser.
+func (p *intersectionPlanner) choose(objectType, relation string, operands []*openfgav1.Userset) int {
+	if len(operands) == 0 {
+		return 0
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if choice, ok := p.choices[operands[0]]; ok {
+		return choice
+	}
+
+	choice, strategy := p.plan(objectType, relation, operands)
+	p.choices[operands[0]] = choice
+	listObjectsIntersectionPlanCounter.WithLabelValues(strategy).Inc()
+
+	return choice
+}
+
+func (p *intersectionPlanner) plan(objectType, relation string, operands []*openfgav1.Userset) (int, string) {
+	if p.counter == nil {
+		return 0, intersectionPlanDefault
+	}
+
+	choice, lowest := 0, math.MaxInt
+	for i, operand := range operands {
+		estimate, err := p.estimate(objectType, relation, operand, map[string]struct{}{relation: {}})
+		if err != nil {
+			return 0, intersectionPlanDefault
+		}
+
+		if estimate < lowest {
+			choice, lowest = i, estimate
+		}
+	}
+
+	if choice == 0 {
+		return 0, intersectionPlanFirstOperand
+	}
+
+	return choice, intersectionPlanOtherOperand
+}
+
+// estimate returns the estimated fan-out of the rewrite of a relation of the object type.
+func (p *intersectionPlanner) estimate(objectType, relation string, rewrite *openfgav1.Userset, visited map[string]struct{}) (int, error) {
+	switch rw := rewrite.GetUserset().(type) {
+	case *openfgav1.Userset_This:
+		return p.countTuples(objectType, relation)
+	case *openfgav1.Userset_ComputedUserset:
+		return p.estimateRelation(objectType, rw.ComputedUserset.GetRelation(), visited)
+	case *openfgav1.Userset_TupleToUserset:
+		return p.countTuples(objectType, rw.TupleToUserset.GetTupleset().GetRelation())
+	case *openfgav1.Userset_Union:
+		total := 0
+		for _, child := range rw.Union.GetChild() {
+			estimate, err := p.estimate(objectType, relation, child, visited)
+			if err != nil {
+				return 0, err
+			}
+			total += estimate
+		}
+		return total, nil
+	case *openfgav1.Userset_Intersection:
+		lowest := math.MaxInt
+		for _, child := range rw.Intersection.GetChild() {
+			estimate, err := p.estimate(objectType, relation, child, visited)
+			if err != nil {
+				return 0, err
+			}
+			lowest = min(lowest, estimate)
+		}
+		if lowest == math.MaxInt {
+			return 0, nil
+		}
+		return lowest, nil
+	case *openfgav1.Userset_Difference:
+		return p.estimate(objectType, relation, rw.Difference.GetBase(), visited)
+	default:
+		return 0, nil
+	}
+}
+
+// estimateRelation returns the estimated fan-out of a relation of the object type.
+func (p *intersectionPlanner) estimateRelation(objectType, relation string, visited map[string]struct{}) (int, error) {
+	if _, ok := visited[relation]; ok {
+		// the fan-out of the recursion is already accounted for
+		return 0, nil
+	}
+	visited[relation] = struct{}{}
+
+	rel, err := p.typesys.GetRelation(objectType, relation)
+	if err != nil {
+		// an undefined relation never yields any object
+		return 0, nil
+	}
+
+	return p.estimate(objectType, relation, rel.GetRewrite(), visited)
+}
+
+func (p *intersectionPlanner) countTuples(objectType, relation string) (int, error) {
+	key := tuple.ToObjectRelationString(objectType, relation)
+	if count, ok := p.tupleCounts[key]; ok {
+		return count, nil
+	}
+
+	count, err := p.counter.CountTuples(p.ctx, p.store, objectType, relation)
+	if err != nil {
+		return 0, err
+	}
+	p.tupleCounts[key] = count
+
+	return count, nil
+}
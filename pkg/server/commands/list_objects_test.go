@@ -2,10 +2,15 @@ package commands
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
 
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 
@@ -225,3 +230,232 @@ func TestListObjectsDispatchCount(t *testing.T) {
 		})
 	}
 }
+
+func TestListObjectsIntersectionPlanner(t *testing.T) {
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	storeID, model := storagetest.BootstrapFGAStore(t, ds, `
+		model
+			schema 1.1
+
+		type user
+
+		type document
+			relations
+				define allowed: [user]
+				define viewer: [user] and allowed`,
+		[]string{
+			"document:1#viewer@user:jon",
+			"document:2#viewer@user:jon",
+			"document:3#viewer@user:jon",
+			"document:1#allowed@user:jon",
+		})
+	ts, err := typesystem.NewAndValidate(context.Background(), model)
+	require.NoError(t, err)
+
+	ctx := typesystem.ContextWithTypesystem(context.Background(), ts)
+	ctx = storage.ContextWithRelationshipTupleReader(ctx, ds)
+
+	relation, err := ts.GetRelation("document", "viewer")
+	require.NoError(t, err)
+	operands := relation.GetRewrite().GetIntersection().GetChild()
+
+	counter, ok := ds.(storage.TupleCounter)
+	require.True(t, ok)
+
+	t.Run("operand_with_the_lowest_fan_out_is_chosen", func(t *testing.T) {
+		before := testutil.ToFloat64(listObjectsIntersectionPlanCounter.WithLabelValues(intersectionPlanOtherOperand))
+
+		planner := newIntersectionPlanner(ctx, counter, storeID, ts)
+		require.Equal(t, 1, planner.choose("document", "viewer", operands))
+		// the choice is planned once per intersection
+		require.Equal(t, 1, planner.choose("document", "viewer", operands))
+
+		require.InDelta(t, before+1, testutil.ToFloat64(listObjectsIntersectionPlanCounter.WithLabelValues(intersectionPlanOtherOperand)), 0)
+	})
+
+	t.Run("first_operand_is_chosen_without_counter", func(t *testing.T) {
+		before := testutil.ToFloat64(listObjectsIntersectionPlanCounter.WithLabelValues(intersectionPlanDefault))
+
+		planner := newIntersectionPlanner(ctx, nil, storeID, ts)
+		require.Equal(t, 0, planner.choose("document", "viewer", operands))
+
+		require.InDelta(t, before+1, testutil.ToFloat64(listObjectsIntersectionPlanCounter.WithLabelValues(intersectionPlanDefault)), 0)
+	})
+
+	t.Run("results_do_not_depend_on_the_operand_chosen", func(t *testing.T) {
+		for _, opts := range [][]ListObjectsQueryOption{nil, {WithTupleCounter(counter)}} {
+			q, err := NewListObjectsQuery(ds, graph.NewLocalCheckerWithCycleDetection(), opts...)
+			require.NoError(t, err)
+
+			resp, err := q.Execute(ctx, &openfgav1.ListObjectsRequest{
+				StoreId:  storeID,
+				Type:     "document",
+				Relation: "viewer",
+				User:     "user:jon",
+			})
+			require.NoError(t, err)
+			require.Equal(t, []string{"document:1"}, resp.Objects)
+		}
+	})
+}
+
+// concurrencyRecordingCheckResolver records the maximum number of checks it resolves at once.
+type concurrencyRecordingCheckResolver struct {
+	graph.CheckResolver
+
+	mu                    sync.Mutex
+	inFlight, maxInFlight int
+}
+
+func (r *concurrencyRecordingCheckResolver) ResolveCheck(ctx context.Context, req *graph.ResolveCheckRequest) (*graph.ResolveCheckResponse, error) {
+	r.mu.Lock()
+	r.inFlight++
+	r.maxInFlight = max(r.maxInFlight, r.inFlight)
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		r.inFlight--
+		r.mu.Unlock()
+	}()
+
+	time.Sleep(time.Millisecond)
+	return r.CheckResolver.ResolveCheck(ctx, req)
+}
+
+func TestListObjectsCandidateCheckConcurrencyLimit(t *testing.T) {
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	var tuples []string
+	for i := 0; i < 20; i++ {
+		tuples = append(tuples, fmt.Sprintf("document:%d#viewer@user:jon", i), fmt.Sprintf("document:%d#allowed@user:jon", i))
+	}
+
+	storeID, model := storagetest.BootstrapFGAStore(t, ds, `
+		model
+			schema 1.1
+
+		type user
+
+		type document
+			relations
+				define allowed: [user]
+				define viewer: [user] and allowed`, tuples)
+	ts, err := typesystem.NewAndValidate(context.Background(), model)
+	require.NoError(t, err)
+
+	ctx := typesystem.ContextWithTypesystem(context.Background(), ts)
+
+	checker := graph.NewLocalCheckerWithCycleDetection()
+	t.Cleanup(checker.Close)
+	resolver := &concurrencyRecordingCheckResolver{CheckResolver: checker}
+
+	q, err := NewListObjectsQuery(ds, resolver,
+		WithResolveNodeBreadthLimit(100),
+		WithCandidateCheckConcurrencyLimit(2),
+	)
+	require.NoError(t, err)
+
+	resp, err := q.Execute(ctx, &openfgav1.ListObjectsRequest{
+		StoreId:  storeID,
+		Type:     "document",
+		Relation: "viewer",
+		User:     "user:jon",
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Objects, 20)
+	require.Positive(t, resolver.maxInFlight)
+	require.LessOrEqual(t, resolver.maxInFlight, 2)
+}
+
+// slowRelationTupleReader blocks the reads of the objects related to the user by the relation until their
+// context is done.
+type slowRelationTupleReader struct {
+	storage.RelationshipTupleReader
+	relation string
+}
+
+func (r *slowRelationTupleReader) ReadStartingWithUser(ctx context.Context, store string, filter storage.ReadStartingWithUserFilter) (storage.TupleIterator, error) {
+	if filter.Relation == r.relation {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	return r.RelationshipTupleReader.ReadStartingWithUser(ctx, store, filter)
+}
+
+type recordingStreamServer struct {
+	grpc.ServerStream
+	objects []string
+}
+
+func (s *recordingStreamServer) Context() context.Context {
+	return context.Background()
+}
+
+func (s *recordingStreamServer) Send(resp *openfgav1.StreamedListObjectsResponse) error {
+	s.objects = append(s.objects, resp.GetObject())
+	return nil
+}
+
+func TestListObjectsStreamedPartialDelivery(t *testing.T) {
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	storeID, model := storagetest.BootstrapFGAStore(t, ds, `
+		model
+			schema 1.1
+
+		type user
+
+		type document
+			relations
+				define editor: [user]
+				define owner: [user]
+				define viewer: [user] or editor or owner`,
+		[]string{
+			"document:1#viewer@user:jon",
+			"document:2#viewer@user:jon",
+			"document:2#owner@user:jon",
+			"document:3#editor@user:jon",
+		})
+	ts, err := typesystem.NewAndValidate(context.Background(), model)
+	require.NoError(t, err)
+
+	ctx := typesystem.ContextWithTypesystem(context.Background(), ts)
+	req := &openfgav1.StreamedListObjectsRequest{
+		StoreId:  storeID,
+		Type:     "document",
+		Relation: "viewer",
+		User:     "user:jon",
+	}
+
+	t.Run("objects_found_before_the_deadline_are_delivered_once", func(t *testing.T) {
+		q, err := NewListObjectsQuery(
+			&slowRelationTupleReader{RelationshipTupleReader: ds, relation: "editor"},
+			graph.NewLocalCheckerWithCycleDetection(),
+			WithListObjectsDeadline(100*time.Millisecond),
+		)
+		require.NoError(t, err)
+
+		srv := &recordingStreamServer{}
+		resolutionMetadata, err := q.ExecuteStreamed(ctx, req, srv)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"document:1", "document:2"}, srv.objects)
+		require.True(t, resolutionMetadata.WasIncomplete.Load())
+	})
+
+	t.Run("complete_results", func(t *testing.T) {
+		q, err := NewListObjectsQuery(ds, graph.NewLocalCheckerWithCycleDetection())
+		require.NoError(t, err)
+
+		srv := &recordingStreamServer{}
+		resolutionMetadata, err := q.ExecuteStreamed(ctx, req, srv)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"document:1", "document:2", "document:3"}, srv.objects)
+		require.False(t, resolutionMetadata.WasIncomplete.Load())
+	})
+}
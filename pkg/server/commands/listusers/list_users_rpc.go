@@ -40,7 +40,9 @@ type listUsersQuery struct {
 	resolveNodeLimit        uint32
 	maxResults              uint32
 	maxConcurrentReads      uint32
+	readBudget              uint32
 	deadline                time.Duration
+	conflictPolicy          storagewrappers.ConflictPolicy
 }
 
 type expandResponse struct {
@@ -118,6 +120,20 @@ func WithListUsersMaxConcurrentReads(limit uint32) ListUsersQueryOption {
 	}
 }
 
+// WithListUsersContextualTuplesConflictPolicy see server.WithContextualTuplesConflictPolicy.
+func WithListUsersContextualTuplesConflictPolicy(policy storagewrappers.ConflictPolicy) ListUsersQueryOption {
+	return func(d *listUsersQuery) {
+		d.conflictPolicy = policy
+	}
+}
+
+// WithListUsersDatastoreReadBudget see server.WithDatastoreReadBudget.
+func WithListUsersDatastoreReadBudget(budget uint32) ListUsersQueryOption {
+	return func(d *listUsersQuery) {
+		d.readBudget = budget
+	}
+}
+
 // NewListUsersQuery is not meant to be shared.
 func NewListUsersQuery(ds storage.RelationshipTupleReader, opts ...ListUsersQueryOption) *listUsersQuery {
 	l := &listUsersQuery{
@@ -160,9 +176,14 @@ func (l *listUsersQuery) ListUsers(
 	}
 	defer cancelCtx()
 
+	if l.readBudget > 0 {
+		l.ds = storagewrappers.NewReadBudgetTupleReader(l.ds, l.readBudget)
+	}
+
 	l.ds = storagewrappers.NewCombinedTupleReader(
 		storagewrappers.NewBoundedConcurrencyTupleReader(l.ds, l.maxConcurrentReads),
 		req.GetContextualTuples(),
+		storagewrappers.WithConflictPolicy(l.conflictPolicy),
 	)
 	typesys, ok := typesystem.TypesystemFromContext(cancellableCtx)
 	if !ok {
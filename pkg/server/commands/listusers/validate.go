@@ -27,10 +27,8 @@ func ValidateListUsersRequest(ctx context.Context, req *openfgav1.ListUsersReque
 }
 
 func validateContextualTuples(request *openfgav1.ListUsersRequest, typeSystem *typesystem.TypeSystem) error {
-	for _, contextualTuple := range request.GetContextualTuples() {
-		if err := validation.ValidateTuple(typeSystem, contextualTuple); err != nil {
-			return serverErrors.HandleTupleValidateError(err)
-		}
+	if err := validation.ValidateContextualTuples(typeSystem, request.GetContextualTuples()); err != nil {
+		return serverErrors.HandleTupleValidateError(err)
 	}
 
 	return nil
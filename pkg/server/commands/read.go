@@ -17,7 +17,8 @@ import (
 // Each tupleset specifies keys of a set of relation tuples.
 // The set can include a single tuple key, or all tuples with
 // a given object ID or userset in a type, optionally
-// constrained by a relation name.
+// constrained by a relation name, or all tuples of a relation
+// in a type, e.g. every 'document' tuple with relation 'viewer'.
 type ReadQuery struct {
 	datastore storage.OpenFGADatastore
 	logger    logger.Logger
@@ -59,11 +60,13 @@ func (q *ReadQuery) Execute(ctx context.Context, req *openfgav1.ReadRequest) (*o
 	tk := req.GetTupleKey()
 
 	// Restrict our reads due to some compatibility issues in one of our storage implementations.
+	// Reading by object type requires a user or a relation, so that the datastores can serve the
+	// read from an index on (object type, relation, user) rather than scanning the whole type.
 	if tk != nil {
 		objectType, objectID := tupleUtils.SplitObject(tk.GetObject())
-		if objectType == "" || (objectID == "" && tk.GetUser() == "") {
+		if objectType == "" || (objectID == "" && tk.GetUser() == "" && tk.GetRelation() == "") {
 			return nil, serverErrors.ValidationError(
-				fmt.Errorf("the 'tuple_key' field was provided but the object type field is required and both the object id and user cannot be empty"),
+				fmt.Errorf("the 'tuple_key' field was provided but the object type field is required and the object id, relation and user cannot all be empty"),
 			)
 		}
 	}
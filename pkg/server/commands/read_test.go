@@ -46,11 +46,12 @@ func TestReadCommand(t *testing.T) {
 					},
 				},
 			},
-			`missing_object_id`: {
+			// an object type with a relation, e.g. 'repo:' and 'writer', is a valid read of every tuple of the
+			// relation in the type, see reads_by_object_type_and_relation
+			`missing_object_id_relation_and_user`: {
 				request: &openfgav1.ReadRequest{
 					TupleKey: &openfgav1.ReadRequestTupleKey{
-						Object:   "repo:",
-						Relation: "writer",
+						Object: "repo:",
 					},
 				},
 			},
@@ -71,7 +72,7 @@ func TestReadCommand(t *testing.T) {
 				resp, err := cmd.Execute(context.Background(), test.request)
 				require.Nil(t, resp)
 				require.ErrorIs(t, err, serverErrors.ValidationError(
-					fmt.Errorf("the 'tuple_key' field was provided but the object type field is required and both the object id and user cannot be empty"),
+					fmt.Errorf("the 'tuple_key' field was provided but the object type field is required and the object id, relation and user cannot all be empty"),
 				))
 			})
 		}
@@ -100,6 +101,26 @@ func TestReadCommand(t *testing.T) {
 		require.NoError(t, err)
 	})
 
+	t.Run("reads_by_object_type_and_relation", func(t *testing.T) {
+		mockController := gomock.NewController(t)
+		defer mockController.Finish()
+
+		storeID := ulid.Make().String()
+
+		mockDatastore := mocks.NewMockOpenFGADatastore(mockController)
+		mockDatastore.EXPECT().ReadPage(gomock.Any(), storeID, tuple.NewTupleKey("repo:", "writer", ""), storage.PaginationOptions{
+			PageSize: storage.DefaultPageSize,
+			From:     "",
+		}).Times(1)
+
+		cmd := NewReadQuery(mockDatastore)
+		_, err := cmd.Execute(context.Background(), &openfgav1.ReadRequest{
+			StoreId:  storeID,
+			TupleKey: &openfgav1.ReadRequestTupleKey{Object: "repo:", Relation: "writer"},
+		})
+		require.NoError(t, err)
+	})
+
 	t.Run("calls_token_decoder", func(t *testing.T) {
 		mockController := gomock.NewController(t)
 		defer mockController.Finish()
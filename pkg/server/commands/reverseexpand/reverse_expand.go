@@ -117,6 +117,8 @@ type ReverseExpandQuery struct {
 
 	dispatchThrottlerConfig threshold.Config
 
+	intersectionOperandChooser graph.IntersectionOperandChooser
+
 	// visitedUsersetsMap map prevents visiting the same userset through the same edge twice
 	visitedUsersetsMap *sync.Map
 	// candidateObjectsMap map prevents returning the same object twice
@@ -143,6 +145,14 @@ func WithResolveNodeBreadthLimit(limit uint32) ReverseExpandQueryOption {
 	}
 }
 
+// WithIntersectionOperandChooser sets the function choosing which operand of an intersection is expanded,
+// the candidates it finds are then checked against the other operands.
+func WithIntersectionOperandChooser(chooser graph.IntersectionOperandChooser) ReverseExpandQueryOption {
+	return func(d *ReverseExpandQuery) {
+		d.intersectionOperandChooser = chooser
+	}
+}
+
 func NewReverseExpandQuery(ds storage.RelationshipTupleReader, ts *typesystem.TypeSystem, opts ...ReverseExpandQueryOption) *ReverseExpandQuery {
 	query := &ReverseExpandQuery{
 		logger:                  logger.NewNoopLogger(),
@@ -319,7 +329,12 @@ func (c *ReverseExpandQuery) execute(
 
 	targetObjRef := typesystem.DirectRelationReference(req.ObjectType, req.Relation)
 
-	g := graph.New(c.typesystem)
+	var graphOpts []graph.RelationshipGraphOption
+	if c.intersectionOperandChooser != nil {
+		graphOpts = append(graphOpts, graph.WithIntersectionOperandChooser(c.intersectionOperandChooser))
+	}
+
+	g := graph.New(c.typesystem, graphOpts...)
 
 	edges, err := g.GetPrunedRelationshipEdges(targetObjRef, sourceUserRef)
 	if err != nil {
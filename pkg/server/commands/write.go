@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"unicode/utf8"
 
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	"google.golang.org/protobuf/proto"
@@ -22,6 +24,14 @@ type WriteCommand struct {
 	logger                    logger.Logger
 	datastore                 storage.OpenFGADatastore
 	conditionContextByteLimit int
+	disallowedIDCharacters    string
+	maxObjectIDLength         int
+	maxUserIDLength           int
+	validateTupleOptions      []validation.ValidateTupleOption
+
+	// conditionalWriter is only set for the writes with preconditions
+	conditionalWriter storage.ConditionalTupleWriter
+	preconditions     storage.WritePreconditions
 }
 
 type WriteCommandOption func(*WriteCommand)
@@ -38,12 +48,53 @@ func WithConditionContextByteLimit(limit int) WriteCommandOption {
 	}
 }
 
+// WithDisallowedIDCharacters sets the characters that the object and user IDs of written tuples may not contain.
+// An empty string disables the validation, e.g. for stores holding legacy data.
+func WithDisallowedIDCharacters(chars string) WriteCommandOption {
+	return func(wc *WriteCommand) {
+		wc.disallowedIDCharacters = chars
+	}
+}
+
+// WithPermissiveUsersetReferences makes the command accept written tuples whose userset user references an
+// undefined relation, or is not an allowed type restriction, e.g. during migrations. See
+// [validation.WithPermissiveUsersetReferences].
+func WithPermissiveUsersetReferences(permissive bool) WriteCommandOption {
+	return func(wc *WriteCommand) {
+		wc.validateTupleOptions = nil
+		if permissive {
+			wc.validateTupleOptions = []validation.ValidateTupleOption{validation.WithPermissiveUsersetReferences()}
+		}
+	}
+}
+
+// WithMaxIDLength sets the maximum lengths, in bytes, of the object and user IDs of written tuples.
+// A maximum length of 0 disables the validation of the corresponding ID.
+func WithMaxIDLength(maxObjectIDLength, maxUserIDLength int) WriteCommandOption {
+	return func(wc *WriteCommand) {
+		wc.maxObjectIDLength = maxObjectIDLength
+		wc.maxUserIDLength = maxUserIDLength
+	}
+}
+
+// WithWritePreconditions makes the command apply the writes with the conditional writer, only if the preconditions
+// hold. See [storage.ConditionalTupleWriter].
+func WithWritePreconditions(writer storage.ConditionalTupleWriter, preconditions storage.WritePreconditions) WriteCommandOption {
+	return func(wc *WriteCommand) {
+		wc.conditionalWriter = writer
+		wc.preconditions = preconditions
+	}
+}
+
 // NewWriteCommand creates a WriteCommand with specified storage.OpenFGADatastore to use for storage.
 func NewWriteCommand(datastore storage.OpenFGADatastore, opts ...WriteCommandOption) *WriteCommand {
 	cmd := &WriteCommand{
 		datastore:                 datastore,
 		logger:                    logger.NewNoopLogger(),
 		conditionContextByteLimit: config.DefaultWriteContextByteLimit,
+		disallowedIDCharacters:    config.DefaultWriteDisallowedIDCharacters,
+		maxObjectIDLength:         config.DefaultMaxObjectIDLength,
+		maxUserIDLength:           config.DefaultMaxUserIDLength,
 	}
 
 	for _, opt := range opts {
@@ -58,12 +109,23 @@ func (c *WriteCommand) Execute(ctx context.Context, req *openfgav1.WriteRequest)
 		return nil, err
 	}
 
-	err := c.datastore.Write(
-		ctx,
-		req.GetStoreId(),
-		req.GetDeletes().GetTupleKeys(),
-		req.GetWrites().GetTupleKeys(),
-	)
+	var err error
+	if c.conditionalWriter != nil {
+		err = c.conditionalWriter.WriteWithPreconditions(
+			ctx,
+			req.GetStoreId(),
+			c.preconditions,
+			req.GetDeletes().GetTupleKeys(),
+			req.GetWrites().GetTupleKeys(),
+		)
+	} else {
+		err = c.datastore.Write(
+			ctx,
+			req.GetStoreId(),
+			req.GetDeletes().GetTupleKeys(),
+			req.GetWrites().GetTupleKeys(),
+		)
+	}
 	if err != nil {
 		return nil, serverErrors.HandleError("", err)
 	}
@@ -100,7 +162,7 @@ func (c *WriteCommand) validateWriteRequest(ctx context.Context, req *openfgav1.
 		typesys := typesystem.New(authModel)
 
 		for _, tk := range writes {
-			err := validation.ValidateTuple(typesys, tk)
+			err := validation.ValidateTuple(typesys, tk, c.validateTupleOptions...)
 			if err != nil {
 				return serverErrors.ValidationError(err)
 			}
@@ -110,6 +172,16 @@ func (c *WriteCommand) validateWriteRequest(ctx context.Context, req *openfgav1.
 				return err
 			}
 
+			err = c.validateAllowedIDCharacters(tk)
+			if err != nil {
+				return err
+			}
+
+			err = tupleUtils.ValidateIDLength(tk, c.maxObjectIDLength, c.maxUserIDLength)
+			if err != nil {
+				return serverErrors.ValidationError(err)
+			}
+
 			contextSize := proto.Size(tk.GetCondition().GetContext())
 			if contextSize > c.conditionContextByteLimit {
 				return serverErrors.ValidationError(&tupleUtils.InvalidTupleError{
@@ -180,3 +252,34 @@ func (c *WriteCommand) validateNotImplicit(
 	}
 	return nil
 }
+
+// validateAllowedIDCharacters ensures the object and user IDs of the tuple to be written (not deleted)
+// do not contain any of the disallowed characters.
+func (c *WriteCommand) validateAllowedIDCharacters(
+	tk *openfgav1.TupleKey,
+) error {
+	if c.disallowedIDCharacters == "" {
+		return nil
+	}
+
+	userObject, _ := tupleUtils.SplitObjectRelation(tk.GetUser())
+
+	for _, field := range []struct {
+		name   string
+		object string
+	}{
+		{name: "object", object: tk.GetObject()},
+		{name: "user", object: userObject},
+	} {
+		_, id := tupleUtils.SplitObject(field.object)
+		if i := strings.IndexAny(id, c.disallowedIDCharacters); i >= 0 {
+			r, _ := utf8.DecodeRuneInString(id[i:])
+			return serverErrors.ValidationError(&tupleUtils.InvalidTupleError{
+				Cause:    fmt.Errorf("the '%s' field contains the disallowed character %q", field.name, r),
+				TupleKey: tk,
+			})
+		}
+	}
+
+	return nil
+}
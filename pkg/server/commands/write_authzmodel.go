@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/google/cel-go/cel"
 	"github.com/oklog/ulid/v2"
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	"google.golang.org/grpc/codes"
@@ -22,6 +23,8 @@ type WriteAuthorizationModelCommand struct {
 	backend                          storage.TypeDefinitionWriteBackend
 	logger                           logger.Logger
 	maxAuthorizationModelSizeInBytes int
+	maxTypesPerAuthorizationModel    int
+	conditionEnv                     *cel.Env
 }
 
 type WriteAuthModelOption func(*WriteAuthorizationModelCommand)
@@ -38,6 +41,22 @@ func WithWriteAuthModelMaxSizeInBytes(size int) WriteAuthModelOption {
 	}
 }
 
+// WithWriteAuthModelMaxTypes sets the maximum number of type definitions of a written model, in place of
+// the one of the datastore. A value of 0 keeps the limit of the datastore.
+func WithWriteAuthModelMaxTypes(maxTypes int) WriteAuthModelOption {
+	return func(m *WriteAuthorizationModelCommand) {
+		m.maxTypesPerAuthorizationModel = maxTypes
+	}
+}
+
+// WithWriteAuthModelConditionEnv sets the CEL environment the conditions of the written model are
+// validated in, e.g. to allow the custom functions of the store. See [typesystem.WithConditionEnv].
+func WithWriteAuthModelConditionEnv(env *cel.Env) WriteAuthModelOption {
+	return func(m *WriteAuthorizationModelCommand) {
+		m.conditionEnv = env
+	}
+}
+
 func NewWriteAuthorizationModelCommand(backend storage.TypeDefinitionWriteBackend, opts ...WriteAuthModelOption) *WriteAuthorizationModelCommand {
 	model := &WriteAuthorizationModelCommand{
 		backend:                          backend,
@@ -53,9 +72,40 @@ func NewWriteAuthorizationModelCommand(backend storage.TypeDefinitionWriteBacken
 
 // Execute the command using the supplied request.
 func (w *WriteAuthorizationModelCommand) Execute(ctx context.Context, req *openfgav1.WriteAuthorizationModelRequest) (*openfgav1.WriteAuthorizationModelResponse, error) {
+	model, _, err := w.validate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	err = w.backend.WriteAuthorizationModel(ctx, req.GetStoreId(), model)
+	if err != nil {
+		return nil, serverErrors.
+			HandleError("Error writing authorization model configuration", err)
+	}
+
+	return &openfgav1.WriteAuthorizationModelResponse{
+		AuthorizationModelId: model.GetId(),
+	}, nil
+}
+
+// Validate validates the authorization model of the request like Execute does, without writing it, and
+// returns its typesystem.
+func (w *WriteAuthorizationModelCommand) Validate(ctx context.Context, req *openfgav1.WriteAuthorizationModelRequest) (*typesystem.TypeSystem, error) {
+	_, typesys, err := w.validate(ctx, req)
+	return typesys, err
+}
+
+func (w *WriteAuthorizationModelCommand) validate(
+	ctx context.Context,
+	req *openfgav1.WriteAuthorizationModelRequest,
+) (*openfgav1.AuthorizationModel, *typesystem.TypeSystem, error) {
 	// Until this is solved: https://github.com/envoyproxy/protoc-gen-validate/issues/74
-	if len(req.GetTypeDefinitions()) > w.backend.MaxTypesPerAuthorizationModel() {
-		return nil, serverErrors.ExceededEntityLimit("type definitions in an authorization model", w.backend.MaxTypesPerAuthorizationModel())
+	maxTypes := w.maxTypesPerAuthorizationModel
+	if maxTypes == 0 {
+		maxTypes = w.backend.MaxTypesPerAuthorizationModel()
+	}
+	if len(req.GetTypeDefinitions()) > maxTypes {
+		return nil, nil, serverErrors.ExceededEntityLimit("type definitions in an authorization model", maxTypes)
 	}
 
 	// Fill in the schema version for old requests, which don't contain it, while we migrate to the new schema version.
@@ -73,24 +123,16 @@ func (w *WriteAuthorizationModelCommand) Execute(ctx context.Context, req *openf
 	// Validate the size in bytes of the wire-format encoding of the authorization model.
 	modelSize := proto.Size(model)
 	if modelSize > w.maxAuthorizationModelSizeInBytes {
-		return nil, status.Error(
+		return nil, nil, status.Error(
 			codes.Code(openfgav1.ErrorCode_exceeded_entity_limit),
 			fmt.Sprintf("model exceeds size limit: %d bytes vs %d bytes", modelSize, w.maxAuthorizationModelSizeInBytes),
 		)
 	}
 
-	_, err := typesystem.NewAndValidate(ctx, model)
+	typesys, err := typesystem.NewAndValidate(ctx, model, typesystem.WithConditionEnv(w.conditionEnv))
 	if err != nil {
-		return nil, serverErrors.InvalidAuthorizationModelInput(err)
+		return nil, nil, serverErrors.InvalidAuthorizationModelInput(err)
 	}
 
-	err = w.backend.WriteAuthorizationModel(ctx, req.GetStoreId(), model)
-	if err != nil {
-		return nil, serverErrors.
-			HandleError("Error writing authorization model configuration", err)
-	}
-
-	return &openfgav1.WriteAuthorizationModelResponse{
-		AuthorizationModelId: model.GetId(),
-	}, nil
+	return model, typesys, nil
 }
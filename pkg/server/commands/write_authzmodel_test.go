@@ -2,6 +2,7 @@ package commands
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/oklog/ulid/v2"
@@ -11,6 +12,7 @@ import (
 	"go.uber.org/mock/gomock"
 
 	mockstorage "github.com/openfga/openfga/internal/mocks"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
 	"github.com/openfga/openfga/pkg/typesystem"
 )
 
@@ -57,3 +59,69 @@ func TestWriteAuthorizationModel(t *testing.T) {
 		})
 	}
 }
+
+func TestWriteAuthorizationModelMaxTypes(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	typeDefinitions := func(count int) []*openfgav1.TypeDefinition {
+		typeDefs := make([]*openfgav1.TypeDefinition, 0, count)
+		for i := 0; i < count; i++ {
+			typeDefs = append(typeDefs, &openfgav1.TypeDefinition{Type: fmt.Sprintf("type%d", i)})
+		}
+		return typeDefs
+	}
+
+	testCases := map[string]struct {
+		maxTypes      int
+		typeCount     int
+		expectedError error
+	}{
+		`at_the_configured_limit`: {
+			maxTypes:  3,
+			typeCount: 3,
+		},
+		`above_the_configured_limit`: {
+			maxTypes:      3,
+			typeCount:     4,
+			expectedError: serverErrors.ExceededEntityLimit("type definitions in an authorization model", 3),
+		},
+		`configured_limit_above_the_datastore_limit`: {
+			maxTypes:  10,
+			typeCount: 6,
+		},
+		`datastore_limit_by_default`: {
+			typeCount:     6,
+			expectedError: serverErrors.ExceededEntityLimit("type definitions in an authorization model", 5),
+		},
+	}
+
+	for testName, test := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			mockController := gomock.NewController(t)
+			defer mockController.Finish()
+
+			mockDatastore := mockstorage.NewMockOpenFGADatastore(mockController)
+			mockDatastore.EXPECT().MaxTypesPerAuthorizationModel().AnyTimes().Return(5)
+			if test.expectedError == nil {
+				mockDatastore.EXPECT().WriteAuthorizationModel(gomock.Any(), storeID, gomock.Any()).Return(nil)
+			}
+
+			cmd := NewWriteAuthorizationModelCommand(mockDatastore, WithWriteAuthModelMaxTypes(test.maxTypes))
+			_, err := cmd.Execute(ctx, &openfgav1.WriteAuthorizationModelRequest{
+				StoreId:         storeID,
+				TypeDefinitions: typeDefinitions(test.typeCount),
+				SchemaVersion:   typesystem.SchemaVersion1_1,
+			})
+			if test.expectedError != nil {
+				require.ErrorIs(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
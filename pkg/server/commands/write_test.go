@@ -228,6 +228,183 @@ func TestValidateWriteRequest(t *testing.T) {
 	}
 }
 
+func TestValidateUsersetReferences(t *testing.T) {
+	model := parser.MustTransformDSLToProto(`
+		model
+			schema 1.1
+
+		type user
+
+		type team
+			relations
+				define member: [user]
+
+		type group
+			relations
+				define member: [user]
+				define admin: [user]
+
+		type document
+			relations
+				define viewer: [user, group#member]`)
+
+	validUserset := tuple.NewTupleKey("document:1", "viewer", "group:eng#member")
+	undefinedRelation := tuple.NewTupleKey("document:1", "viewer", "group:eng#owner")
+	disallowedSubject := tuple.NewTupleKey("document:1", "viewer", "team:eng#member")
+	disallowedRelation := tuple.NewTupleKey("document:1", "viewer", "group:eng#admin")
+	undefinedType := tuple.NewTupleKey("document:1", "viewer", "org:acme#member")
+
+	tests := []struct {
+		name          string
+		tupleKey      *openfgav1.TupleKey
+		permissive    bool
+		expectedCause error
+	}{
+		{
+			name:     "valid_userset_reference",
+			tupleKey: validUserset,
+		},
+		{
+			name:          "undefined_referenced_relation",
+			tupleKey:      undefinedRelation,
+			expectedCause: &tuple.RelationNotFoundError{Relation: "owner", TypeName: "group"},
+		},
+		{
+			name:          "disallowed_subject_type",
+			tupleKey:      disallowedSubject,
+			expectedCause: fmt.Errorf("'team#member' is not an allowed type restriction for 'document#viewer'"),
+		},
+		{
+			name:          "disallowed_subject_relation",
+			tupleKey:      disallowedRelation,
+			expectedCause: fmt.Errorf("'group#admin' is not an allowed type restriction for 'document#viewer'"),
+		},
+		{
+			name:       "permissive_valid_userset_reference",
+			tupleKey:   validUserset,
+			permissive: true,
+		},
+		{
+			name:       "permissive_undefined_referenced_relation",
+			tupleKey:   undefinedRelation,
+			permissive: true,
+		},
+		{
+			name:       "permissive_disallowed_subject_type",
+			tupleKey:   disallowedSubject,
+			permissive: true,
+		},
+		{
+			name:          "permissive_undefined_referenced_type",
+			tupleKey:      undefinedType,
+			permissive:    true,
+			expectedCause: &tuple.TypeNotFoundError{TypeName: "org"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockController := gomock.NewController(t)
+			defer mockController.Finish()
+
+			mockDatastore := mockstorage.NewMockOpenFGADatastore(mockController)
+			mockDatastore.EXPECT().MaxTuplesPerWrite().AnyTimes().Return(10)
+			mockDatastore.EXPECT().
+				ReadAuthorizationModel(gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(&openfgav1.AuthorizationModel{
+					SchemaVersion:   typesystem.SchemaVersion1_1,
+					TypeDefinitions: model.GetTypeDefinitions(),
+				}, nil)
+
+			cmd := NewWriteCommand(mockDatastore, WithPermissiveUsersetReferences(test.permissive))
+
+			err := cmd.validateWriteRequest(context.Background(), &openfgav1.WriteRequest{
+				StoreId: "abcd123",
+				Writes:  &openfgav1.WriteRequestWrites{TupleKeys: []*openfgav1.TupleKey{test.tupleKey}},
+			})
+			if test.expectedCause == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			require.ErrorIs(t, err, serverErrors.ValidationError(&tuple.InvalidTupleError{
+				Cause:    test.expectedCause,
+				TupleKey: test.tupleKey,
+			}))
+		})
+	}
+}
+
+func TestValidateAllowedIDCharacters(t *testing.T) {
+	tests := []struct {
+		name          string
+		tupleKey      *openfgav1.TupleKey
+		opts          []WriteCommandOption
+		expectedCause error
+	}{
+		{
+			name:     "allowed_ids",
+			tupleKey: tuple.NewTupleKey("document:budget-2024_v1.final", "viewer", "user:jon@example.com"),
+		},
+		{
+			name:     "allowed_userset",
+			tupleKey: tuple.NewTupleKey("document:1", "viewer", "group:eng|backend#member"),
+		},
+		{
+			name:     "allowed_wildcard",
+			tupleKey: tuple.NewTupleKey("document:1", "viewer", "user:*"),
+		},
+		{
+			name:          "object_id_with_space",
+			tupleKey:      tuple.NewTupleKey("document:my doc", "viewer", "user:jon"),
+			expectedCause: fmt.Errorf("the 'object' field contains the disallowed character ' '"),
+		},
+		{
+			name:          "object_id_with_control_character",
+			tupleKey:      tuple.NewTupleKey("document:1\x00", "viewer", "user:jon"),
+			expectedCause: fmt.Errorf("the 'object' field contains the disallowed character '\\x00'"),
+		},
+		{
+			name:          "user_id_with_newline",
+			tupleKey:      tuple.NewTupleKey("document:1", "viewer", "user:jon\n"),
+			expectedCause: fmt.Errorf("the 'user' field contains the disallowed character '\\n'"),
+		},
+		{
+			name:          "userset_object_id_with_tab",
+			tupleKey:      tuple.NewTupleKey("document:1", "viewer", "group:e\tng#member"),
+			expectedCause: fmt.Errorf("the 'user' field contains the disallowed character '\\t'"),
+		},
+		{
+			name:          "custom_denylist",
+			tupleKey:      tuple.NewTupleKey("document:1", "viewer", "user:jon@example.com"),
+			opts:          []WriteCommandOption{WithDisallowedIDCharacters("@")},
+			expectedCause: fmt.Errorf("the 'user' field contains the disallowed character '@'"),
+		},
+		{
+			name:     "validation_disabled",
+			tupleKey: tuple.NewTupleKey("document:my doc", "viewer", "user:jon"),
+			opts:     []WriteCommandOption{WithDisallowedIDCharacters("")},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd := NewWriteCommand(nil, test.opts...)
+
+			err := cmd.validateAllowedIDCharacters(test.tupleKey)
+			if test.expectedCause == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			require.ErrorIs(t, err, serverErrors.ValidationError(&tuple.InvalidTupleError{
+				Cause:    test.expectedCause,
+				TupleKey: test.tupleKey,
+			}))
+		})
+	}
+}
+
 func TestTransactionalWriteFailedError(t *testing.T) {
 	mockController := gomock.NewController(t)
 	defer mockController.Finish()
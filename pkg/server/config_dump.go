@@ -0,0 +1,130 @@
+package server
+
+import (
+	"time"
+
+	"golang.org/x/exp/maps"
+)
+
+// ResolverConfig is a snapshot of the resolver options a [Server] is running with.
+type ResolverConfig struct {
+	ResolveNodeLimit        uint32            `json:"resolveNodeLimit"`
+	ResolveNodeBreadthLimit uint32            `json:"resolveNodeBreadthLimit"`
+	StoreResolveNodeLimits  map[string]uint32 `json:"storeResolveNodeLimits,omitempty"`
+	MaxVisitedPathsForCheck uint32            `json:"maxVisitedPathsForCheck"`
+	MaxNodeFanoutForCheck   uint32            `json:"maxNodeFanoutForCheck"`
+	CheckDispatchWorkerPool uint32            `json:"checkDispatchWorkerPool"`
+	MaxConcurrentChecks     uint32            `json:"maxConcurrentChecks"`
+	CheckQueueSize          uint32            `json:"checkQueueSize"`
+
+	ResolveNodeUnionBreadthLimit              uint32 `json:"resolveNodeUnionBreadthLimit"`
+	ResolveNodeTTUBreadthLimit                uint32 `json:"resolveNodeTTUBreadthLimit"`
+	ListObjectsCandidateCheckConcurrencyLimit uint32 `json:"listObjectsCandidateCheckConcurrencyLimit"`
+
+	MaxConditionEvaluationsForCheck uint32 `json:"maxConditionEvaluationsForCheck"`
+	CheckConditionEvaluationCache   bool   `json:"checkConditionEvaluationCache"`
+
+	MaxConcurrentReadsForCheck       uint32 `json:"maxConcurrentReadsForCheck"`
+	MaxConcurrentReadsForListObjects uint32 `json:"maxConcurrentReadsForListObjects"`
+	MaxConcurrentReadsForListUsers   uint32 `json:"maxConcurrentReadsForListUsers"`
+	MaxConcurrentChecksPerBatchCheck uint32 `json:"maxConcurrentChecksPerBatchCheck"`
+	DatastoreReadBudget              uint32 `json:"datastoreReadBudget"`
+
+	ListObjectsDeadline   time.Duration `json:"listObjectsDeadline"`
+	ListObjectsMaxResults uint32        `json:"listObjectsMaxResults"`
+	ListUsersDeadline     time.Duration `json:"listUsersDeadline"`
+	ListUsersMaxResults   uint32        `json:"listUsersMaxResults"`
+
+	CheckQueryCache      CheckQueryCacheConfig `json:"checkQueryCache"`
+	ListObjectsReadCache CheckQueryCacheConfig `json:"listObjectsReadCache"`
+
+	CheckDispatchThrottling       DispatchThrottlingConfig `json:"checkDispatchThrottling"`
+	ListObjectsDispatchThrottling DispatchThrottlingConfig `json:"listObjectsDispatchThrottling"`
+
+	CheckOutcomeLogSampleRate float64 `json:"checkOutcomeLogSampleRate"`
+
+	StoreFeatureFlagsCacheTTL      time.Duration `json:"storeFeatureFlagsCacheTTL"`
+	StorageQueryTimeout            time.Duration `json:"storageQueryTimeout"`
+	ListObjectsEmptyResultCacheTTL time.Duration `json:"listObjectsEmptyResultCacheTTL"`
+}
+
+// CheckQueryCacheConfig describes the settings of a cache of a [ResolverConfig], e.g. the Check query cache.
+type CheckQueryCacheConfig struct {
+	Enabled bool          `json:"enabled"`
+	Limit   uint32        `json:"limit"`
+	TTL     time.Duration `json:"ttl"`
+}
+
+// DispatchThrottlingConfig describes the dispatch throttling settings of a [ResolverConfig].
+type DispatchThrottlingConfig struct {
+	Enabled          bool          `json:"enabled"`
+	Frequency        time.Duration `json:"frequency"`
+	DefaultThreshold uint32        `json:"defaultThreshold"`
+	MaxThreshold     uint32        `json:"maxThreshold"`
+}
+
+// DumpConfig returns a snapshot of the resolver options currently in effect, after defaults
+// have been applied. It can be used to verify that a deployment runs with the intended settings.
+func (s *Server) DumpConfig() ResolverConfig {
+	s.runtimeConfigMu.RLock()
+	defer s.runtimeConfigMu.RUnlock()
+
+	return ResolverConfig{
+		ResolveNodeLimit:        s.resolveNodeLimit,
+		ResolveNodeBreadthLimit: s.resolveNodeBreadthLimit,
+		StoreResolveNodeLimits:  maps.Clone(s.storeResolveNodeLimits),
+		MaxVisitedPathsForCheck: s.maxVisitedPathsForCheck,
+		MaxNodeFanoutForCheck:   s.maxNodeFanoutForCheck,
+		CheckDispatchWorkerPool: s.checkDispatchWorkerPoolSize,
+		MaxConcurrentChecks:     s.maxConcurrentChecks,
+		CheckQueueSize:          s.checkQueueSize,
+
+		ResolveNodeUnionBreadthLimit:              s.resolveNodeUnionBreadthLimit,
+		ResolveNodeTTUBreadthLimit:                s.resolveNodeTTUBreadthLimit,
+		ListObjectsCandidateCheckConcurrencyLimit: s.listObjectsCandidateCheckConcurrencyLimit,
+
+		MaxConditionEvaluationsForCheck: s.maxConditionEvaluationsForCheck,
+		CheckConditionEvaluationCache:   s.checkConditionEvaluationCache,
+
+		MaxConcurrentReadsForCheck:       s.maxConcurrentReadsForCheck,
+		MaxConcurrentReadsForListObjects: s.maxConcurrentReadsForListObjects,
+		MaxConcurrentReadsForListUsers:   s.maxConcurrentReadsForListUsers,
+		MaxConcurrentChecksPerBatchCheck: s.maxConcurrentChecksPerBatchCheck,
+		DatastoreReadBudget:              s.datastoreReadBudget,
+
+		ListObjectsDeadline:   s.listObjectsDeadline,
+		ListObjectsMaxResults: s.listObjectsMaxResults,
+		ListUsersDeadline:     s.listUsersDeadline,
+		ListUsersMaxResults:   s.listUsersMaxResults,
+
+		CheckQueryCache: CheckQueryCacheConfig{
+			Enabled: s.checkQueryCacheEnabled,
+			Limit:   s.checkQueryCacheLimit,
+			TTL:     s.checkQueryCacheTTL,
+		},
+		ListObjectsReadCache: CheckQueryCacheConfig{
+			Enabled: s.listObjectsReadCacheEnabled,
+			Limit:   s.listObjectsReadCacheLimit,
+			TTL:     s.listObjectsReadCacheTTL,
+		},
+
+		CheckDispatchThrottling: DispatchThrottlingConfig{
+			Enabled:          s.checkDispatchThrottlingEnabled,
+			Frequency:        s.checkDispatchThrottlingFrequency,
+			DefaultThreshold: s.checkDispatchThrottlingDefaultThreshold,
+			MaxThreshold:     s.checkDispatchThrottlingMaxThreshold,
+		},
+		ListObjectsDispatchThrottling: DispatchThrottlingConfig{
+			Enabled:          s.listObjectsDispatchThrottlingEnabled,
+			Frequency:        s.listObjectsDispatchThrottlingFrequency,
+			DefaultThreshold: s.listObjectsDispatchDefaultThreshold,
+			MaxThreshold:     s.listObjectsDispatchThrottlingMaxThreshold,
+		},
+
+		CheckOutcomeLogSampleRate: s.checkOutcomeLogSampleRate,
+
+		StoreFeatureFlagsCacheTTL:      s.storeFeatureFlagsCacheTTL,
+		StorageQueryTimeout:            s.storageQueryTimeout,
+		ListObjectsEmptyResultCacheTTL: s.listObjectsEmptyResultCacheTTL,
+	}
+}
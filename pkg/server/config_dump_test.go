@@ -0,0 +1,60 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	serverconfig "github.com/openfga/openfga/internal/server/config"
+	"github.com/openfga/openfga/pkg/storage/memory"
+)
+
+func TestDumpConfig(t *testing.T) {
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+		WithResolveNodeLimit(10),
+		WithStoreResolveNodeLimit("01HVMMBCMGZNT3SED4Z17ECXCA", 5),
+		WithMaxNodeFanoutForCheck(4),
+		WithResolveNodeUnionBreadthLimit(8),
+		WithListObjectsCandidateCheckConcurrencyLimit(16),
+		WithListObjectsDeadline(2*time.Second),
+		WithCheckQueryCacheEnabled(true),
+		WithCheckQueryCacheLimit(500),
+		WithCheckQueryCacheTTL(time.Minute),
+		WithDispatchThrottlingCheckResolverEnabled(true),
+		WithDispatchThrottlingCheckResolverFrequency(time.Millisecond),
+		WithDispatchThrottlingCheckResolverThreshold(50),
+		WithDispatchThrottlingCheckResolverMaxThreshold(100),
+	)
+	t.Cleanup(s.Close)
+
+	cfg := s.DumpConfig()
+
+	require.Equal(t, uint32(10), cfg.ResolveNodeLimit)
+	require.Equal(t, map[string]uint32{"01HVMMBCMGZNT3SED4Z17ECXCA": 5}, cfg.StoreResolveNodeLimits)
+	require.Equal(t, uint32(4), cfg.MaxNodeFanoutForCheck)
+	require.Equal(t, uint32(8), cfg.ResolveNodeUnionBreadthLimit)
+	require.Equal(t, uint32(16), cfg.ListObjectsCandidateCheckConcurrencyLimit)
+	require.Equal(t, 2*time.Second, cfg.ListObjectsDeadline)
+	require.Equal(t, CheckQueryCacheConfig{Enabled: true, Limit: 500, TTL: time.Minute}, cfg.CheckQueryCache)
+	require.Equal(t, DispatchThrottlingConfig{
+		Enabled:          true,
+		Frequency:        time.Millisecond,
+		DefaultThreshold: 50,
+		MaxThreshold:     100,
+	}, cfg.CheckDispatchThrottling)
+
+	// options that were not provided report their defaults
+	require.Equal(t, uint32(serverconfig.DefaultResolveNodeBreadthLimit), cfg.ResolveNodeBreadthLimit)
+	require.Zero(t, cfg.ResolveNodeTTUBreadthLimit)
+	require.Equal(t, uint32(serverconfig.DefaultMaxConcurrentReadsForCheck), cfg.MaxConcurrentReadsForCheck)
+	require.Equal(t, serverconfig.DefaultListObjectsDispatchThrottlingEnabled, cfg.ListObjectsDispatchThrottling.Enabled)
+
+	// the snapshot does not alias the server's state
+	cfg.StoreResolveNodeLimits["01HVMMBCMGZNT3SED4Z17ECXCA"] = 1
+	require.Equal(t, uint32(5), s.DumpConfig().StoreResolveNodeLimits["01HVMMBCMGZNT3SED4Z17ECXCA"])
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+
+	"github.com/openfga/openfga/internal/keys"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/tuple"
+)
+
+// dedupContextualTuples returns the contextual tuples without the ones that repeat an earlier tuple, keeping
+// the order of the first occurrences. If reject is true, a duplicate is reported with a
+// duplicate_contextual_tuple error instead.
+func dedupContextualTuples(tupleKeys []*openfgav1.TupleKey, reject bool) ([]*openfgav1.TupleKey, error) {
+	if len(tupleKeys) < 2 {
+		return tupleKeys, nil
+	}
+
+	seen := make(map[string]struct{}, len(tupleKeys))
+	deduped := make([]*openfgav1.TupleKey, 0, len(tupleKeys))
+	for _, tk := range tupleKeys {
+		key, err := canonicalTupleKey(tk)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := seen[key]; ok {
+			if reject {
+				return nil, serverErrors.DuplicateContextualTuple(tk)
+			}
+			continue
+		}
+
+		seen[key] = struct{}{}
+		deduped = append(deduped, tk)
+	}
+
+	return deduped, nil
+}
+
+// canonicalTupleKey returns a key identifying the tuple including its condition. The fields of the
+// condition context are ordered, so two tuples with equal contexts have the same key.
+func canonicalTupleKey(tk *openfgav1.TupleKey) (string, error) {
+	condition := tk.GetCondition()
+	if condition == nil {
+		return tuple.TupleKeyToString(tk), nil
+	}
+
+	var conditionContext stringBuilderHasher
+	if err := keys.NewContextHasher(condition.GetContext()).Append(&conditionContext); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s (condition %s %s)", tuple.TupleKeyToString(tk), condition.GetName(), conditionContext.String()), nil
+}
+
+// stringBuilderHasher adapts a strings.Builder to the string writer used by the keys hashers.
+type stringBuilderHasher struct {
+	strings.Builder
+}
+
+func (s *stringBuilderHasher) WriteString(value string) error {
+	_, err := s.Builder.WriteString(value)
+	return err
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/openfga/openfga/internal/build"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+)
+
+var shutdownRequestsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: build.ProjectName,
+	Name:      "shutdown_requests_count",
+	Help:      "The total number of Check and ListObjects requests in flight when the server started draining, labeled by whether they completed within the drain timeout or were aborted.",
+}, []string{"outcome"})
+
+// WithShutdownDrainTimeout sets how long Drain waits for the Check and ListObjects requests in flight to complete
+// before canceling them. It defaults to 5s.
+func WithShutdownDrainTimeout(timeout time.Duration) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.shutdownDrainTimeout = timeout
+	}
+}
+
+// Drain rejects the new Check and ListObjects requests with ServerShuttingDown, and waits for the ones in
+// flight to complete, for up to the timeout set with WithShutdownDrainTimeout. The requests still in flight then
+// are canceled, along with their dispatches, and Drain returns once they returned. Close drains the server, so
+// Drain only needs to be called to drain it before the transport stops, e.g. before a gRPC GracefulStop, which
+// would otherwise wait for the requests in flight for as long as they take.
+func (s *Server) Drain() {
+	s.drainMu.Lock()
+	if s.draining {
+		s.drainMu.Unlock()
+		<-s.drained
+		return
+	}
+	s.draining = true
+	if s.inFlightRequests == 0 {
+		close(s.drained)
+	}
+	s.drainMu.Unlock()
+
+	timer := time.NewTimer(s.shutdownDrainTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-s.drained:
+	case <-timer.C:
+		s.abortRequests(serverErrors.ServerShuttingDown)
+		<-s.drained
+	}
+
+	s.abortRequests(serverErrors.ServerShuttingDown)
+}
+
+// trackRequest registers a Check or ListObjects request as in flight, and returns its context, which is canceled
+// if the request is still in flight once the drain timeout has elapsed. It returns ServerShuttingDown if the
+// server is draining. The returned function must be called once the request completes.
+func (s *Server) trackRequest(ctx context.Context) (context.Context, func(), error) {
+	s.drainMu.Lock()
+	defer s.drainMu.Unlock()
+
+	if s.draining {
+		return nil, nil, serverErrors.ServerShuttingDown
+	}
+	s.inFlightRequests++
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	stop := context.AfterFunc(s.abortCtx, func() {
+		cancel(context.Cause(s.abortCtx))
+	})
+
+	return ctx, func() {
+		stop()
+		cancel(nil)
+
+		s.drainMu.Lock()
+		defer s.drainMu.Unlock()
+
+		s.inFlightRequests--
+		if !s.draining {
+			return
+		}
+
+		outcome := "completed"
+		if s.abortCtx.Err() != nil {
+			outcome = "aborted"
+		}
+		shutdownRequestsCounter.WithLabelValues(outcome).Inc()
+
+		if s.inFlightRequests == 0 {
+			close(s.drained)
+		}
+	}, nil
+}
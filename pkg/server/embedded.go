@@ -0,0 +1,301 @@
+package server
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/memory"
+)
+
+// ServerShuttingDown is returned by the calls made through the client of an [EmbeddedServer] once it started
+// shutting down.
+var ServerShuttingDown = status.Error(codes.Unavailable, "the server is shutting down")
+
+// EmbeddedServer runs OpenFGA in-process, as a library: its client calls the methods of the [Server] directly,
+// without a network listener nor the gRPC and HTTP layers, and it can be shut down gracefully, waiting for the
+// calls in flight. Since it bypasses the gRPC layer, the response headers set by the server, e.g. the
+// AuthorizationModelIDHeader, are not reported, and no authentication is applied.
+type EmbeddedServer struct {
+	server *Server
+
+	mu       sync.RWMutex
+	closing  bool
+	inflight sync.WaitGroup
+
+	closeOnce sync.Once
+}
+
+// NewEmbeddedServer returns an [EmbeddedServer] storing its data in the given datastore, or in a new memory
+// datastore if ds is nil, e.g. one returned by memory.NewPersistent to keep the data across restarts. The
+// datastore is closed along with the server. The options configure the server as for NewServerWithOpts, and
+// WithDatastore must not be among them.
+func NewEmbeddedServer(ds storage.OpenFGADatastore, opts ...OpenFGAServiceV1Option) (*EmbeddedServer, error) {
+	if ds == nil {
+		ds = memory.New()
+	}
+
+	s, err := NewServerWithOpts(append([]OpenFGAServiceV1Option{WithDatastore(ds)}, opts...)...)
+	if err != nil {
+		ds.Close()
+		return nil, err
+	}
+
+	return &EmbeddedServer{server: s}, nil
+}
+
+// Client returns a client calling the server in-process. It is safe for concurrent use, and it can be used in
+// place of a gRPC client of a remote server. The grpc.CallOption arguments of its methods are ignored.
+func (e *EmbeddedServer) Client() openfgav1.OpenFGAServiceClient {
+	return &embeddedClient{embedded: e}
+}
+
+// Server returns the underlying [Server], for its methods that have no RPC, e.g. ImportTuples. Unlike the calls
+// made through the client, the calls made on the server directly are not waited for by Shutdown.
+func (e *EmbeddedServer) Server() *Server {
+	return e.server
+}
+
+// Shutdown stops accepting calls through the client, which then fail with ServerShuttingDown, waits for the
+// calls in flight to complete and closes the server and its datastore. If ctx is done before the calls in flight
+// complete, Shutdown returns its error and leaves the server open; Close then closes it regardless.
+func (e *EmbeddedServer) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	e.closing = true
+	e.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		e.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		e.Close()
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting calls through the client and closes the server and its datastore right away, failing the
+// calls in flight. It is safe to call more than once, and after Shutdown.
+func (e *EmbeddedServer) Close() {
+	e.mu.Lock()
+	e.closing = true
+	e.mu.Unlock()
+
+	e.closeOnce.Do(e.server.Close)
+}
+
+// begin registers a call in flight, unless the server is shutting down. The call must be ended with
+// e.inflight.Done.
+func (e *EmbeddedServer) begin() error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if e.closing {
+		return ServerShuttingDown
+	}
+
+	e.inflight.Add(1)
+	return nil
+}
+
+// call makes a unary call to the server as a call in flight.
+func call[Req, Resp any](e *EmbeddedServer, ctx context.Context, req Req, method func(context.Context, Req) (Resp, error)) (Resp, error) {
+	if err := e.begin(); err != nil {
+		var zero Resp
+		return zero, err
+	}
+	defer e.inflight.Done()
+
+	return method(ctx, req)
+}
+
+var _ openfgav1.OpenFGAServiceClient = (*embeddedClient)(nil)
+
+type embeddedClient struct {
+	embedded *EmbeddedServer
+}
+
+func (c *embeddedClient) Read(ctx context.Context, in *openfgav1.ReadRequest, _ ...grpc.CallOption) (*openfgav1.ReadResponse, error) {
+	return call(c.embedded, ctx, in, c.embedded.server.Read)
+}
+
+func (c *embeddedClient) Write(ctx context.Context, in *openfgav1.WriteRequest, _ ...grpc.CallOption) (*openfgav1.WriteResponse, error) {
+	return call(c.embedded, ctx, in, c.embedded.server.Write)
+}
+
+func (c *embeddedClient) Check(ctx context.Context, in *openfgav1.CheckRequest, _ ...grpc.CallOption) (*openfgav1.CheckResponse, error) {
+	return call(c.embedded, ctx, in, c.embedded.server.Check)
+}
+
+func (c *embeddedClient) Expand(ctx context.Context, in *openfgav1.ExpandRequest, _ ...grpc.CallOption) (*openfgav1.ExpandResponse, error) {
+	return call(c.embedded, ctx, in, c.embedded.server.Expand)
+}
+
+func (c *embeddedClient) ReadAuthorizationModels(ctx context.Context, in *openfgav1.ReadAuthorizationModelsRequest, _ ...grpc.CallOption) (*openfgav1.ReadAuthorizationModelsResponse, error) {
+	return call(c.embedded, ctx, in, c.embedded.server.ReadAuthorizationModels)
+}
+
+func (c *embeddedClient) ReadAuthorizationModel(ctx context.Context, in *openfgav1.ReadAuthorizationModelRequest, _ ...grpc.CallOption) (*openfgav1.ReadAuthorizationModelResponse, error) {
+	return call(c.embedded, ctx, in, c.embedded.server.ReadAuthorizationModel)
+}
+
+func (c *embeddedClient) WriteAuthorizationModel(ctx context.Context, in *openfgav1.WriteAuthorizationModelRequest, _ ...grpc.CallOption) (*openfgav1.WriteAuthorizationModelResponse, error) {
+	return call(c.embedded, ctx, in, c.embedded.server.WriteAuthorizationModel)
+}
+
+func (c *embeddedClient) WriteAssertions(ctx context.Context, in *openfgav1.WriteAssertionsRequest, _ ...grpc.CallOption) (*openfgav1.WriteAssertionsResponse, error) {
+	return call(c.embedded, ctx, in, c.embedded.server.WriteAssertions)
+}
+
+func (c *embeddedClient) ReadAssertions(ctx context.Context, in *openfgav1.ReadAssertionsRequest, _ ...grpc.CallOption) (*openfgav1.ReadAssertionsResponse, error) {
+	return call(c.embedded, ctx, in, c.embedded.server.ReadAssertions)
+}
+
+func (c *embeddedClient) ReadChanges(ctx context.Context, in *openfgav1.ReadChangesRequest, _ ...grpc.CallOption) (*openfgav1.ReadChangesResponse, error) {
+	return call(c.embedded, ctx, in, c.embedded.server.ReadChanges)
+}
+
+func (c *embeddedClient) CreateStore(ctx context.Context, in *openfgav1.CreateStoreRequest, _ ...grpc.CallOption) (*openfgav1.CreateStoreResponse, error) {
+	return call(c.embedded, ctx, in, c.embedded.server.CreateStore)
+}
+
+func (c *embeddedClient) UpdateStore(ctx context.Context, in *openfgav1.UpdateStoreRequest, _ ...grpc.CallOption) (*openfgav1.UpdateStoreResponse, error) {
+	return call(c.embedded, ctx, in, c.embedded.server.UpdateStore)
+}
+
+func (c *embeddedClient) DeleteStore(ctx context.Context, in *openfgav1.DeleteStoreRequest, _ ...grpc.CallOption) (*openfgav1.DeleteStoreResponse, error) {
+	return call(c.embedded, ctx, in, c.embedded.server.DeleteStore)
+}
+
+func (c *embeddedClient) GetStore(ctx context.Context, in *openfgav1.GetStoreRequest, _ ...grpc.CallOption) (*openfgav1.GetStoreResponse, error) {
+	return call(c.embedded, ctx, in, c.embedded.server.GetStore)
+}
+
+func (c *embeddedClient) ListStores(ctx context.Context, in *openfgav1.ListStoresRequest, _ ...grpc.CallOption) (*openfgav1.ListStoresResponse, error) {
+	return call(c.embedded, ctx, in, c.embedded.server.ListStores)
+}
+
+func (c *embeddedClient) ListObjects(ctx context.Context, in *openfgav1.ListObjectsRequest, _ ...grpc.CallOption) (*openfgav1.ListObjectsResponse, error) {
+	return call(c.embedded, ctx, in, c.embedded.server.ListObjects)
+}
+
+func (c *embeddedClient) ListUsers(ctx context.Context, in *openfgav1.ListUsersRequest, _ ...grpc.CallOption) (*openfgav1.ListUsersResponse, error) {
+	return call(c.embedded, ctx, in, c.embedded.server.ListUsers)
+}
+
+// StreamedListObjects runs the StreamedListObjects of the server in a goroutine, which hands the objects to the
+// returned stream as they are received. As with a gRPC stream, the stream must be read until Recv returns an
+// error, or ctx canceled, for the call to complete.
+func (c *embeddedClient) StreamedListObjects(ctx context.Context, in *openfgav1.StreamedListObjectsRequest, _ ...grpc.CallOption) (openfgav1.OpenFGAService_StreamedListObjectsClient, error) {
+	if err := c.embedded.begin(); err != nil {
+		return nil, err
+	}
+
+	stream := &embeddedListObjectsStream{
+		ctx:       ctx,
+		responses: make(chan *openfgav1.StreamedListObjectsResponse),
+		done:      make(chan struct{}),
+	}
+
+	go func() {
+		defer c.embedded.inflight.Done()
+		defer close(stream.done)
+
+		stream.err = c.embedded.server.StreamedListObjects(in, &embeddedListObjectsServerStream{stream: stream})
+	}()
+
+	return stream, nil
+}
+
+// embeddedListObjectsStream is the client side of an in-process StreamedListObjects.
+type embeddedListObjectsStream struct {
+	grpc.ClientStream
+
+	ctx       context.Context
+	responses chan *openfgav1.StreamedListObjectsResponse
+
+	// done is closed once the server returned, after err and trailer are set
+	done    chan struct{}
+	err     error
+	trailer metadata.MD
+}
+
+func (s *embeddedListObjectsStream) Recv() (*openfgav1.StreamedListObjectsResponse, error) {
+	select {
+	case resp := <-s.responses:
+		return resp, nil
+	case <-s.done:
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, io.EOF
+	case <-s.ctx.Done():
+		return nil, status.FromContextError(s.ctx.Err()).Err()
+	}
+}
+
+func (s *embeddedListObjectsStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *embeddedListObjectsStream) Header() (metadata.MD, error) {
+	return metadata.MD{}, nil
+}
+
+// Trailer returns the trailer set by the server, e.g. the ListObjectsCompleteTrailer, once Recv returned an error.
+func (s *embeddedListObjectsStream) Trailer() metadata.MD {
+	select {
+	case <-s.done:
+		return s.trailer
+	default:
+		return nil
+	}
+}
+
+func (s *embeddedListObjectsStream) CloseSend() error {
+	return nil
+}
+
+// embeddedListObjectsServerStream is the server side of an in-process StreamedListObjects.
+type embeddedListObjectsServerStream struct {
+	grpc.ServerStream
+
+	stream *embeddedListObjectsStream
+}
+
+func (s *embeddedListObjectsServerStream) Send(resp *openfgav1.StreamedListObjectsResponse) error {
+	select {
+	case s.stream.responses <- resp:
+		return nil
+	case <-s.stream.ctx.Done():
+		return s.stream.ctx.Err()
+	}
+}
+
+func (s *embeddedListObjectsServerStream) Context() context.Context {
+	return s.stream.ctx
+}
+
+func (s *embeddedListObjectsServerStream) SetHeader(metadata.MD) error {
+	return nil
+}
+
+func (s *embeddedListObjectsServerStream) SendHeader(metadata.MD) error {
+	return nil
+}
+
+func (s *embeddedListObjectsServerStream) SetTrailer(md metadata.MD) {
+	s.stream.trailer = metadata.Join(s.stream.trailer, md)
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/openfga/openfga/pkg/testutils"
+	"github.com/openfga/openfga/pkg/tuple"
+)
+
+func TestEmbeddedServer(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	embedded, err := NewEmbeddedServer(nil)
+	require.NoError(t, err)
+	t.Cleanup(embedded.Close)
+
+	client := embedded.Client()
+
+	createStoreResp, err := client.CreateStore(ctx, &openfgav1.CreateStoreRequest{Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type document
+			relations
+				define viewer: [user]`)
+
+	writeModelResp, err := client.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:         storeID,
+		SchemaVersion:   model.GetSchemaVersion(),
+		TypeDefinitions: model.GetTypeDefinitions(),
+	})
+	require.NoError(t, err)
+
+	_, err = client.Write(ctx, &openfgav1.WriteRequest{
+		StoreId: storeID,
+		Writes: &openfgav1.WriteRequestWrites{
+			TupleKeys: []*openfgav1.TupleKey{
+				tuple.NewTupleKey("document:1", "viewer", "user:anne"),
+				tuple.NewTupleKey("document:2", "viewer", "user:anne"),
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	checkResp, err := client.Check(ctx, &openfgav1.CheckRequest{
+		StoreId:  storeID,
+		TupleKey: tuple.NewCheckRequestTupleKey("document:1", "viewer", "user:anne"),
+	})
+	require.NoError(t, err)
+	require.True(t, checkResp.GetAllowed())
+
+	streamedListObjects := func(t *testing.T) openfgav1.OpenFGAService_StreamedListObjectsClient {
+		stream, err := client.StreamedListObjects(ctx, &openfgav1.StreamedListObjectsRequest{
+			StoreId:              storeID,
+			AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+			Type:                 "document",
+			Relation:             "viewer",
+			User:                 "user:anne",
+		})
+		require.NoError(t, err)
+		return stream
+	}
+
+	t.Run("streamed_list_objects", func(t *testing.T) {
+		stream := streamedListObjects(t)
+
+		var objects []string
+		for {
+			resp, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			require.NoError(t, err)
+			objects = append(objects, resp.GetObject())
+		}
+
+		require.ElementsMatch(t, []string{"document:1", "document:2"}, objects)
+		require.Equal(t, []string{"true"}, stream.Trailer().Get(ListObjectsCompleteTrailer))
+	})
+
+	t.Run("shutdown_waits_for_the_calls_in_flight", func(t *testing.T) {
+		// the stream is not read, so the call stays in flight
+		stream := streamedListObjects(t)
+
+		shutdownCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, embedded.Shutdown(shutdownCtx), context.DeadlineExceeded)
+
+		_, err := client.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:  storeID,
+			TupleKey: tuple.NewCheckRequestTupleKey("document:1", "viewer", "user:anne"),
+		})
+		require.ErrorIs(t, err, ServerShuttingDown)
+
+		// the call in flight completes once the stream is read
+		for {
+			_, err := stream.Recv()
+			if err != nil {
+				require.ErrorIs(t, err, io.EOF)
+				break
+			}
+		}
+
+		require.NoError(t, embedded.Shutdown(ctx))
+	})
+}
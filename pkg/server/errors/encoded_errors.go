@@ -80,6 +80,17 @@ func NewEncodedError(errorCode int32, message string) *EncodedError {
 				},
 			}
 		}
+		if errorCode == int32(codes.PermissionDenied) {
+			return &EncodedError{
+				HTTPStatusCode: http.StatusForbidden,
+				GRPCStatusCode: codes.PermissionDenied,
+				ActualError: ErrorResponse{
+					Code:    codes.PermissionDenied.String(),
+					Message: sanitizedMessage(message),
+					codeInt: errorCode,
+				},
+			}
+		}
 		return &EncodedError{
 			HTTPStatusCode: http.StatusInternalServerError,
 			GRPCStatusCode: codes.Internal,
@@ -245,6 +256,8 @@ func ConvertToEncodedErrorCode(statusError *status.Status) int32 {
 		return int32(openfgav1.InternalErrorCode_failed_precondition)
 	case codes.Aborted:
 		return int32(codes.Aborted)
+	case codes.PermissionDenied:
+		return int32(codes.PermissionDenied)
 	case codes.OutOfRange:
 		return int32(openfgav1.InternalErrorCode_out_of_range)
 	case codes.Unimplemented:
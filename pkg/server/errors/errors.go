@@ -27,6 +27,21 @@ var (
 	RequestCancelled                       = status.Error(codes.Code(openfgav1.InternalErrorCode_cancelled), "Request Cancelled")
 	RequestDeadlineExceeded                = status.Error(codes.Code(openfgav1.InternalErrorCode_deadline_exceeded), "Request Deadline Exceeded")
 	ThrottledTimeout                       = status.Error(codes.Code(openfgav1.UnprocessableContentErrorCode_throttled_timeout_error), "timeout due to throttling on complex request")
+	DatastoreTimeout                       = status.Error(codes.Code(openfgav1.InternalErrorCode_unavailable), "a datastore query timed out")
+	ServerBusy                             = status.Error(codes.Code(openfgav1.InternalErrorCode_resource_exhausted), "server is busy, too many concurrent Check requests")
+	ServerShuttingDown                     = status.Error(codes.Code(openfgav1.InternalErrorCode_unavailable), "the server is shutting down")
+	StoreFeatureFlagsUnsupported           = status.Error(codes.Code(openfgav1.NotFoundErrorCode_unimplemented), "the datastore does not support per-store feature flags")
+	AuthorizationModelArchiveUnsupported   = status.Error(codes.Code(openfgav1.NotFoundErrorCode_unimplemented), "the datastore does not support archiving authorization models")
+	AuthorizationModelArchived             = status.Error(codes.Code(openfgav1.InternalErrorCode_failed_precondition), "the authorization model is archived")
+	ChangeCountUnsupported                 = status.Error(codes.Code(openfgav1.NotFoundErrorCode_unimplemented), "the datastore does not support counting the changes of a store")
+	ReadByActorUnsupported                 = status.Error(codes.Code(openfgav1.NotFoundErrorCode_unimplemented), "the datastore does not record the actor of the writes")
+	ConditionalWriteUnsupported            = status.Error(codes.Code(openfgav1.NotFoundErrorCode_unimplemented), "the datastore does not support conditional writes")
+	TupleExpiryUnsupported                 = status.Error(codes.Code(openfgav1.NotFoundErrorCode_unimplemented), "the datastore does not support expiring tuples")
+	ChangelogFilterUnsupported             = status.Error(codes.Code(openfgav1.NotFoundErrorCode_unimplemented), "the datastore does not support filtering the changes by relation, user or time")
+	StoreDefaultModelUnsupported           = status.Error(codes.Code(openfgav1.NotFoundErrorCode_unimplemented), "the datastore does not support pinning the default authorization model of a store")
+	ContextualAssertionsUnsupported        = status.Error(codes.Code(openfgav1.NotFoundErrorCode_unimplemented), "the datastore does not support the contextual tuples and the condition context of assertions")
+	StoreStatsUnsupported                  = status.Error(codes.Code(openfgav1.NotFoundErrorCode_unimplemented), "the datastore does not support computing the statistics of a store")
+	StoreTimeRangeUnsupported              = status.Error(codes.Code(openfgav1.NotFoundErrorCode_unimplemented), "the datastore does not support reading the time range of a store")
 )
 
 type InternalError struct {
@@ -83,6 +98,11 @@ func LatestAuthorizationModelNotFound(store string) error {
 	return status.Error(codes.Code(openfgav1.ErrorCode_latest_authorization_model_not_found), fmt.Sprintf("No authorization models found for store '%s'", store))
 }
 
+// CheckResolverChainNotFound is returned when a Check selects a resolver chain that is not registered on the server.
+func CheckResolverChainNotFound(name string) error {
+	return status.Error(codes.Code(openfgav1.ErrorCode_validation_error), fmt.Sprintf("check resolver chain '%s' is not registered", name))
+}
+
 func TypeNotFound(objectType string) error {
 	return status.Error(codes.Code(openfgav1.ErrorCode_type_not_found), fmt.Sprintf("type '%s' not found", objectType))
 }
@@ -105,6 +125,15 @@ func DuplicateTupleInWrite(tk tuple.TupleWithoutCondition) error {
 	return status.Error(codes.Code(openfgav1.ErrorCode_cannot_allow_duplicate_tuples_in_one_request), fmt.Sprintf("duplicate tuple in write: user: '%s', relation: '%s', object: '%s'", tk.GetUser(), tk.GetRelation(), tk.GetObject()))
 }
 
+func DuplicateContextualTuple(tk tuple.TupleWithoutCondition) error {
+	return status.Error(codes.Code(openfgav1.ErrorCode_duplicate_contextual_tuple), fmt.Sprintf("duplicate contextual tuple in request: user: '%s', relation: '%s', object: '%s'", tk.GetUser(), tk.GetRelation(), tk.GetObject()))
+}
+
+// ReadBudgetExceeded is returned when a request is aborted because it exceeded its datastore read budget.
+func ReadBudgetExceeded(err *storage.ReadBudgetExceededError) error {
+	return status.Error(codes.ResourceExhausted, fmt.Sprintf("the request exceeded its budget of %d datastore reads (%d reads attempted)", err.Budget, err.Reads))
+}
+
 func WriteFailedDueToInvalidInput(err error) error {
 	if err != nil {
 		return status.Error(codes.Code(openfgav1.ErrorCode_write_failed_due_to_invalid_input), err.Error())
@@ -119,19 +148,25 @@ func InvalidAuthorizationModelInput(err error) error {
 // HandleError is used to surface some errors, and hide others.
 // Use `public` if you want to return a useful error message to the user.
 func HandleError(public string, err error) error {
+	var readBudgetErr *storage.ReadBudgetExceededError
+
 	switch {
 	case errors.Is(err, storage.ErrTransactionalWriteFailed):
 		return status.Error(codes.Aborted, err.Error())
 	case errors.Is(err, storage.ErrInvalidWriteInput):
 		return WriteFailedDueToInvalidInput(err)
+	case errors.Is(err, storage.ErrWritePreconditionFailed):
+		return status.Error(codes.FailedPrecondition, err.Error())
 	case errors.Is(err, storage.ErrInvalidContinuationToken):
 		return InvalidContinuationToken
 	case errors.Is(err, storage.ErrMismatchObjectType):
 		return MismatchObjectType
 	case errors.Is(err, storage.ErrCancelled):
 		return RequestCancelled
-	case errors.Is(err, storage.ErrDeadlineExceeded):
+	case errors.Is(err, storage.ErrDeadlineExceeded), errors.Is(err, storage.ErrStorageTimeout):
 		return RequestDeadlineExceeded
+	case errors.As(err, &readBudgetErr):
+		return ReadBudgetExceeded(readBudgetErr)
 	default:
 		return NewInternalError(public, err)
 	}
@@ -150,6 +185,15 @@ func HandleTupleValidateError(err error) error {
 	case *tuple.RelationNotFoundError:
 		return RelationNotFound(t.Relation, t.TypeName, t.TupleKey)
 	case *tuple.InvalidConditionalTupleError:
+		return status.Error(
+			codes.Code(openfgav1.ErrorCode_validation_error),
+			err.Error(),
+		)
+	case *tuple.InvalidContextualTuplesError:
+		if len(t.Errors) == 1 {
+			return HandleTupleValidateError(t.Errors[0].Cause)
+		}
+
 		return status.Error(
 			codes.Code(openfgav1.ErrorCode_validation_error),
 			err.Error(),
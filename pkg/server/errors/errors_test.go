@@ -56,6 +56,13 @@ func TestHandleStorageErrors(t *testing.T) {
 			storageErr:              storage.ErrTransactionalWriteFailed,
 			expectedTranslatedError: status.Error(codes.Aborted, storage.ErrTransactionalWriteFailed.Error()),
 		},
+		`read_budget_exceeded`: {
+			storageErr: fmt.Errorf("failed to read: %w", &storage.ReadBudgetExceededError{Budget: 10, Reads: 11}),
+			expectedTranslatedError: status.Error(
+				codes.ResourceExhausted,
+				"the request exceeded its budget of 10 datastore reads (11 reads attempted)",
+			),
+		},
 	}
 	for testName, test := range tests {
 		t.Run(testName, func(t *testing.T) {
@@ -111,6 +118,28 @@ func TestHandleTupleValidateError(t *testing.T) {
 				invalidConditionTupleError.Error(),
 			),
 		},
+		"single_invalid_contextual_tuple": {
+			validateError: &tuple.InvalidContextualTuplesError{
+				Errors: []*tuple.InvalidContextualTupleError{
+					{Index: 1, TupleKey: tuple.NewTupleKey("doc:x", "viewer", "user:z"), Cause: &tuple.TypeNotFoundError{TypeName: "doc"}},
+				},
+			},
+			expectedTranslatedError: TypeNotFound("doc"),
+		},
+		"invalid_contextual_tuples": {
+			validateError: &tuple.InvalidContextualTuplesError{
+				Errors: []*tuple.InvalidContextualTupleError{
+					{Index: 0, TupleKey: tuple.NewTupleKey("doc:x", "viewer", "user:z"), Cause: &tuple.TypeNotFoundError{TypeName: "doc"}},
+					{Index: 2, TupleKey: tuple.NewTupleKey("doc:x", "viewer", "user:z"), Cause: &invalidConditionTupleError},
+				},
+			},
+			expectedTranslatedError: status.Error(
+				codes.Code(openfgav1.ErrorCode_validation_error),
+				"2 invalid contextual tuples: "+
+					"contextual tuple 0 'doc:x#viewer@user:z' (type 'doc', relation 'viewer'): type 'doc' not found; "+
+					"contextual tuple 2 'doc:x#viewer@user:z' (type 'doc', relation 'viewer'): foo",
+			),
+		},
 		"undefined error": {
 			validateError:           fmt.Errorf("unknown"),
 			expectedTranslatedError: HandleError("", fmt.Errorf("unknown")),
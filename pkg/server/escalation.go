@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"sort"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/openfga/openfga/internal/graph"
+	"github.com/openfga/openfga/internal/validation"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/telemetry"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+)
+
+// AnalyzeEscalation returns, as sorted 'object#relation' strings, the high-privilege relations that the user of
+// the proposed tuple would be granted if the tuple was written, and that it is not granted yet. The high-privilege
+// relations are set with WithHighPrivilegeRelations. The tuple is not written.
+//
+// The model is first analyzed to only consider the relations that the relation of the proposed tuple can lead to.
+// The objects on which those relations are unlocked are then found by comparing the results of ListObjects with
+// and without the proposed tuple, so they are subject to the ListObjects deadline and maximum number of results.
+func (s *Server) AnalyzeEscalation(ctx context.Context, storeID, modelID string, proposedTuple *openfgav1.TupleKey) ([]string, error) {
+	ctx, span := tracer.Start(ctx, "AnalyzeEscalation", trace.WithAttributes(
+		attribute.String("store_id", storeID),
+		attribute.String("tuple_key", proposedTuple.String()),
+	))
+	defer span.End()
+
+	typesys, err := s.resolveTypesystem(ctx, storeID, modelID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validation.ValidateTuple(typesys, proposedTuple); err != nil {
+		return nil, serverErrors.HandleTupleValidateError(err)
+	}
+
+	candidates, err := s.escalationCandidates(typesys, proposedTuple)
+	if err != nil {
+		telemetry.TraceError(span, err)
+		return nil, serverErrors.HandleError("", err)
+	}
+
+	var unlocked []string
+	for _, candidate := range candidates {
+		req := &openfgav1.ListObjectsRequest{
+			StoreId:              storeID,
+			AuthorizationModelId: typesys.GetAuthorizationModelID(),
+			Type:                 candidate.GetType(),
+			Relation:             candidate.GetRelation(),
+			User:                 proposedTuple.GetUser(),
+		}
+
+		before, err := s.ListObjects(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		req.ContextualTuples = &openfgav1.ContextualTupleKeys{
+			TupleKeys: []*openfgav1.TupleKey{proposedTuple},
+		}
+
+		after, err := s.ListObjects(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		alreadyGranted := make(map[string]struct{}, len(before.GetObjects()))
+		for _, object := range before.GetObjects() {
+			alreadyGranted[object] = struct{}{}
+		}
+
+		for _, object := range after.GetObjects() {
+			if _, ok := alreadyGranted[object]; !ok {
+				unlocked = append(unlocked, tuple.ToObjectRelationString(object, candidate.GetRelation()))
+			}
+		}
+	}
+
+	sort.Strings(unlocked)
+	span.SetAttributes(attribute.Int("unlocked_count", len(unlocked)))
+
+	return unlocked, nil
+}
+
+// escalationCandidates returns the high-privilege relations of the model that the relation of the proposed
+// tuple is connected to, and that the tuple may therefore unlock.
+func (s *Server) escalationCandidates(typesys *typesystem.TypeSystem, proposedTuple *openfgav1.TupleKey) ([]*openfgav1.RelationReference, error) {
+	var relations []*openfgav1.RelationReference
+	if len(s.highPrivilegeRelations) == 0 {
+		for objectType, rels := range typesys.GetAllRelations() {
+			for relation := range rels {
+				relations = append(relations, typesystem.DirectRelationReference(objectType, relation))
+			}
+		}
+	}
+
+	for _, objectRelation := range s.highPrivilegeRelations {
+		objectType, relation := tuple.SplitObjectRelation(objectRelation)
+		if _, err := typesys.GetRelation(objectType, relation); err != nil {
+			continue // the high-privilege relation is not defined in this model
+		}
+
+		relations = append(relations, typesystem.DirectRelationReference(objectType, relation))
+	}
+
+	source := typesystem.DirectRelationReference(tuple.GetType(proposedTuple.GetObject()), proposedTuple.GetRelation())
+	g := graph.New(typesys)
+
+	var candidates []*openfgav1.RelationReference
+	for _, target := range relations {
+		if target.GetType() == source.GetType() && target.GetRelation() == source.GetRelation() {
+			candidates = append(candidates, target)
+			continue
+		}
+
+		edges, err := g.GetRelationshipEdges(target, source)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(edges) > 0 {
+			candidates = append(candidates, target)
+		}
+	}
+
+	return candidates, nil
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"context"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+)
+
+// ExpandOption sets an option of a single Expand made with ExpandWithOptions.
+type ExpandOption func(*expandOptions)
+
+type expandOptions struct {
+	maxDepth uint32
+}
+
+// WithExpandMaxDepth expands the usersets referenced by the leaves of the tree (computed usersets, tuple to
+// usersets and userset users like 'group:eng#member') recursively, down to depth levels, e.g. for an admin UI
+// to render the full effective access of a relation. A leaf referencing usersets is replaced by a union node of
+// the same name holding the leaf and the trees of the usersets it references. The usersets already expanded on
+// the path from the root are not expanded again, so cyclic models terminate. A depth of 0 (the default) expands
+// one level, like Expand. The Expand fails with a validation error if the depth exceeds the resolve node limit.
+func WithExpandMaxDepth(depth uint32) ExpandOption {
+	return func(o *expandOptions) {
+		o.maxDepth = depth
+	}
+}
+
+// ExpandWithOptions is like Expand, with the options applying to this request only.
+func (s *Server) ExpandWithOptions(ctx context.Context, req *openfgav1.ExpandRequest, opts ...ExpandOption) (*openfgav1.ExpandResponse, error) {
+	var o expandOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return s.expand(ctx, req, o)
+}
@@ -3,6 +3,7 @@ package health
 
 import (
 	"context"
+	"strings"
 
 	grpcauth "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
 	"google.golang.org/grpc/codes"
@@ -15,6 +16,33 @@ type TargetService interface {
 	IsReady(ctx context.Context) (bool, error)
 }
 
+// DatastoreServiceSuffix is appended to the name of the target service, followed by the name of a datastore, to
+// check the health of that datastore alone, e.g. 'openfga.v1.OpenFGAService/datastore/replica'.
+const DatastoreServiceSuffix = "/datastore/"
+
+// DatastoresServiceSuffix is appended to the name of the target service to check the health of all its datastores,
+// e.g. 'openfga.v1.OpenFGAService/datastores'. It reports NOT_SERVING if any of them is not ready, even though the
+// target service may still be serving, e.g. while a read replica it only hedges reads with is down.
+const DatastoresServiceSuffix = "/datastores"
+
+// DatastoreHealth is the health of one of the datastores of a service.
+type DatastoreHealth struct {
+	// Name identifies the datastore, e.g. 'primary'.
+	Name string
+
+	// Ready tells whether the datastore is reachable and its schema is at a supported revision.
+	Ready bool
+
+	// Message explains why the datastore is not ready.
+	Message string
+}
+
+// DatastoreReporter may be implemented by a TargetService to report the health of each of its datastores, which
+// the Checker serves under the DatastoreServiceSuffix and DatastoresServiceSuffix service names.
+type DatastoreReporter interface {
+	DatastoreHealth(ctx context.Context) []DatastoreHealth
+}
+
 type Checker struct {
 	healthv1pb.UnimplementedHealthServer
 	TargetService
@@ -43,6 +71,32 @@ func (o *Checker) Check(ctx context.Context, req *healthv1pb.HealthCheckRequest)
 		return &healthv1pb.HealthCheckResponse{Status: healthv1pb.HealthCheckResponse_SERVING}, nil
 	}
 
+	if reporter, ok := o.TargetService.(DatastoreReporter); ok {
+		if requestedService == o.TargetServiceName+DatastoresServiceSuffix {
+			for _, datastore := range reporter.DatastoreHealth(ctx) {
+				if !datastore.Ready {
+					return &healthv1pb.HealthCheckResponse{Status: healthv1pb.HealthCheckResponse_NOT_SERVING}, nil
+				}
+			}
+
+			return &healthv1pb.HealthCheckResponse{Status: healthv1pb.HealthCheckResponse_SERVING}, nil
+		}
+
+		if name, found := strings.CutPrefix(requestedService, o.TargetServiceName+DatastoreServiceSuffix); found {
+			for _, datastore := range reporter.DatastoreHealth(ctx) {
+				if datastore.Name != name {
+					continue
+				}
+
+				if !datastore.Ready {
+					return &healthv1pb.HealthCheckResponse{Status: healthv1pb.HealthCheckResponse_NOT_SERVING}, nil
+				}
+
+				return &healthv1pb.HealthCheckResponse{Status: healthv1pb.HealthCheckResponse_SERVING}, nil
+			}
+		}
+	}
+
 	return nil, status.Errorf(codes.NotFound, "service '%s' is not registered with the Health server", requestedService)
 }
 
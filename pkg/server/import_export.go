@@ -0,0 +1,210 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/openfga/openfga/pkg/server/commands"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/telemetry"
+)
+
+const defaultExportTuplesPageSize = 1000
+
+// ImportTuplesProgress reports the tuples written by ImportTuples so far.
+type ImportTuplesProgress struct {
+	// Imported is the number of tuples of the input written so far, including the ones skipped
+	// because they were imported before the checkpoint the import resumed from.
+	Imported int64
+
+	// Checkpoint resumes the import after the tuples written so far.
+	Checkpoint string
+}
+
+// ExportTuplesBatch is a batch of tuples sent by ExportTuples.
+type ExportTuplesBatch struct {
+	Tuples []*openfgav1.Tuple
+
+	// Checkpoint resumes the export after the tuples of the batch. It is also a valid continuation
+	// token for Read. It is empty for the last batch.
+	Checkpoint string
+}
+
+// importCheckpoint is the decoded checkpoint of ImportTuples.
+type importCheckpoint struct {
+	StoreID  string `json:"store_id"`
+	ModelID  string `json:"model_id"`
+	Imported int64  `json:"imported"`
+}
+
+// ImportTuples writes the tuples received from recv to the store, to bootstrap a store with many tuples
+// without splitting them into Write requests. It calls recv for the next batch of tuples, of any size, until
+// recv returns io.EOF, and writes every batch in transactions of at most MaxTuplesPerWrite tuples, validated
+// against the authorization model with the given ID, or against the latest model of the store if modelID is
+// empty. recv is only called again once the previous batch is written, which throttles the sender.
+//
+// After every transaction, send is called with the progress of the import. Its checkpoint resumes an import
+// that failed or was interrupted: the input must be sent again from its start, and the tuples written before
+// the checkpoint are skipped rather than written twice. A resumed import uses the model of the checkpoint.
+//
+// ImportTuples returns nil once recv returns io.EOF, or the first error of recv, send or of a write.
+func (s *Server) ImportTuples(
+	ctx context.Context,
+	storeID, modelID, checkpoint string,
+	recv func() ([]*openfgav1.TupleKey, error),
+	send func(ImportTuplesProgress) error,
+) error {
+	ctx, span := tracer.Start(ctx, "ImportTuples", trace.WithAttributes(
+		attribute.String("store_id", storeID),
+	))
+	defer span.End()
+
+	ctx = telemetry.ContextWithRPCInfo(ctx, telemetry.RPCInfo{
+		Service: s.serviceName,
+		Method:  "ImportTuples",
+	})
+
+	progress := importCheckpoint{StoreID: storeID}
+	if checkpoint != "" {
+		decoded, err := s.encoder.Decode(checkpoint)
+		if err != nil {
+			return serverErrors.InvalidContinuationToken
+		}
+
+		if err := json.Unmarshal(decoded, &progress); err != nil || progress.StoreID != storeID {
+			return serverErrors.InvalidContinuationToken
+		}
+
+		if modelID != "" && modelID != progress.ModelID {
+			return serverErrors.InvalidContinuationToken
+		}
+	} else {
+		// the model is resolved once, so that all the tuples are validated against the same model
+		typesys, err := s.resolveTypesystem(ctx, storeID, modelID)
+		if err != nil {
+			return err
+		}
+		progress.ModelID = typesys.GetAuthorizationModelID()
+	}
+
+	skip := progress.Imported
+	maxTuplesPerWrite := s.datastore.MaxTuplesPerWrite()
+
+	for {
+		tupleKeys, err := recv()
+		if errors.Is(err, io.EOF) {
+			span.SetAttributes(attribute.Int64("imported", progress.Imported))
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if skip > 0 {
+			skipped := min(skip, int64(len(tupleKeys)))
+			tupleKeys = tupleKeys[skipped:]
+			skip -= skipped
+		}
+
+		for len(tupleKeys) > 0 {
+			chunk := tupleKeys[:min(maxTuplesPerWrite, len(tupleKeys))]
+			tupleKeys = tupleKeys[len(chunk):]
+
+			_, err := s.Write(ctx, &openfgav1.WriteRequest{
+				StoreId:              storeID,
+				AuthorizationModelId: progress.ModelID,
+				Writes: &openfgav1.WriteRequestWrites{
+					TupleKeys: chunk,
+				},
+			})
+			if err != nil {
+				return err
+			}
+
+			progress.Imported += int64(len(chunk))
+
+			encoded, err := json.Marshal(progress)
+			if err != nil {
+				return serverErrors.HandleError("", err)
+			}
+
+			token, err := s.encoder.Encode(encoded)
+			if err != nil {
+				return serverErrors.HandleError("", err)
+			}
+
+			if err := send(ImportTuplesProgress{Imported: progress.Imported, Checkpoint: token}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// ExportTuples sends all the tuples of the store, in batches of pageSize tuples, or of 1000 tuples if pageSize
+// is not positive. It starts after the checkpoint of a batch sent by a previous export, or a continuation token
+// returned by Read, or from the first tuple of the store if checkpoint is empty. The next batch is only read
+// once send returns, which throttles the export to the pace of the receiver.
+//
+// ExportTuples returns nil once all the tuples are sent, or the first error of send or of a read.
+func (s *Server) ExportTuples(
+	ctx context.Context,
+	storeID string,
+	pageSize int32,
+	checkpoint string,
+	send func(ExportTuplesBatch) error,
+) error {
+	ctx, span := tracer.Start(ctx, "ExportTuples", trace.WithAttributes(
+		attribute.String("store_id", storeID),
+	))
+	defer span.End()
+
+	ctx = telemetry.ContextWithRPCInfo(ctx, telemetry.RPCInfo{
+		Service: s.serviceName,
+		Method:  "ExportTuples",
+	})
+
+	if pageSize <= 0 {
+		pageSize = defaultExportTuplesPageSize
+	}
+
+	q := commands.NewReadQuery(s.datastore,
+		commands.WithReadQueryLogger(s.logger),
+		commands.WithReadQueryEncoder(s.encoder),
+	)
+
+	req := &openfgav1.ReadRequest{
+		StoreId:           storeID,
+		PageSize:          wrapperspb.Int32(pageSize),
+		ContinuationToken: checkpoint,
+	}
+
+	for {
+		resp, err := q.Execute(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		if len(resp.GetTuples()) > 0 {
+			err := send(ExportTuplesBatch{
+				Tuples:     resp.GetTuples(),
+				Checkpoint: resp.GetContinuationToken(),
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if resp.GetContinuationToken() == "" {
+			return nil
+		}
+
+		req.ContinuationToken = resp.GetContinuationToken()
+	}
+}
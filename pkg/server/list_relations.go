@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"sort"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/tuple"
+)
+
+// ListRelationsRequest is a request to ListRelations.
+type ListRelationsRequest struct {
+	StoreID              string
+	AuthorizationModelID string
+
+	Object string
+	User   string
+
+	// Relations are the relations of the type of the object to check, all of them if empty.
+	Relations []string
+
+	ContextualTuples []*openfgav1.TupleKey
+	Context          *structpb.Struct
+}
+
+// ListRelationsResponse is the response of ListRelations.
+type ListRelationsResponse struct {
+	// Relations are the relations the user has on the object, sorted by name.
+	Relations []string
+}
+
+// ListRelations returns the relations of the type of the object that the user has on the object, e.g. to
+// render the actions a user may take on a resource at once. Every relation is checked against the same
+// authorization model, and the checks share the results of their subproblems like the ones of a BatchCheck,
+// so relations defined in terms of each other (e.g. 'viewer: editor or ...') are resolved once instead of once
+// per relation as with separate Check calls. It fails if the check of any relation fails.
+func (s *Server) ListRelations(ctx context.Context, req *ListRelationsRequest) (*ListRelationsResponse, error) {
+	ctx, span := tracer.Start(ctx, "ListRelations", trace.WithAttributes(
+		attribute.String("store_id", req.StoreID),
+		attribute.String("object", req.Object),
+		attribute.String("user", req.User),
+	))
+	defer span.End()
+
+	typesys, err := s.resolveTypesystem(ctx, req.StoreID, req.AuthorizationModelID)
+	if err != nil {
+		return nil, err
+	}
+
+	relations := req.Relations
+	if len(relations) == 0 {
+		typeRelations, err := typesys.GetRelations(tuple.GetType(req.Object))
+		if err != nil {
+			return nil, serverErrors.ValidationError(err)
+		}
+
+		for relation := range typeRelations {
+			relations = append(relations, relation)
+		}
+	}
+
+	checkReqs := make([]*openfgav1.CheckRequest, 0, len(relations))
+	for _, relation := range relations {
+		checkReqs = append(checkReqs, &openfgav1.CheckRequest{
+			StoreId:              req.StoreID,
+			AuthorizationModelId: typesys.GetAuthorizationModelID(),
+			TupleKey:             tuple.NewCheckRequestTupleKey(req.Object, relation, req.User),
+			ContextualTuples:     &openfgav1.ContextualTupleKeys{TupleKeys: req.ContextualTuples},
+			Context:              req.Context,
+		})
+	}
+
+	results := s.BatchCheck(ctx, checkReqs)
+
+	res := &ListRelationsResponse{}
+	for i, result := range results {
+		if result.Err != nil {
+			return nil, result.Err
+		}
+
+		if result.Response.GetAllowed() {
+			res.Relations = append(res.Relations, relations[i])
+		}
+	}
+	sort.Strings(res.Relations)
+
+	span.SetAttributes(attribute.Int("relation_count", len(res.Relations)))
+
+	return res, nil
+}
@@ -63,15 +63,22 @@ func (s *Server) ListUsers(
 		return nil, err
 	}
 
+	conflictPolicy, err := s.contextualTuplesConflictPolicyFor(ctx, req.GetStoreId())
+	if err != nil {
+		return nil, err
+	}
+
 	ctx = typesystem.ContextWithTypesystem(ctx, typesys)
 
 	listUsersQuery := listusers.NewListUsersQuery(s.datastore,
-		listusers.WithResolveNodeLimit(s.resolveNodeLimit),
+		listusers.WithResolveNodeLimit(s.getResolveNodeLimit(ctx, req.GetStoreId())),
 		listusers.WithResolveNodeBreadthLimit(s.resolveNodeBreadthLimit),
 		listusers.WithListUsersQueryLogger(s.logger),
 		listusers.WithListUsersMaxResults(s.listUsersMaxResults),
 		listusers.WithListUsersDeadline(s.listUsersDeadline),
 		listusers.WithListUsersMaxConcurrentReads(s.maxConcurrentReadsForListUsers),
+		listusers.WithListUsersContextualTuplesConflictPolicy(conflictPolicy),
+		listusers.WithListUsersDatastoreReadBudget(s.datastoreReadBudget),
 	)
 
 	resp, err := listUsersQuery.ListUsers(ctx, req)
@@ -18,6 +18,7 @@ import (
 	serverErrors "github.com/openfga/openfga/pkg/server/errors"
 	"github.com/openfga/openfga/pkg/storage"
 	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/storage/storagewrappers"
 	"github.com/openfga/openfga/pkg/storage/test"
 	"github.com/openfga/openfga/pkg/testutils"
 	"github.com/openfga/openfga/pkg/tuple"
@@ -529,6 +530,105 @@ func TestListUsers_Deadline(t *testing.T) {
 	})
 }
 
+func TestListUsersContextualTuplesConflictPolicy(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type document
+			relations
+				define viewer: [user, user with under_limit]
+
+		condition under_limit(x: int) {
+			x < 100
+		}`)
+
+	// listUsers lists the viewers of document:1 with a contextual tuple conflicting with the persisted
+	// one of user:jon, and whose condition is not met
+	listUsers := func(t *testing.T, opts ...OpenFGAServiceV1Option) (*Server, string, func() []string) {
+		s := MustNewServerWithOpts(append([]OpenFGAServiceV1Option{
+			WithDatastore(memory.New()),
+			WithExperimentals(ExperimentalEnableListUsers),
+		}, opts...)...)
+		t.Cleanup(s.Close)
+
+		createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+			Name: "openfga-test",
+		})
+		require.NoError(t, err)
+		storeID := createStoreResp.GetId()
+
+		writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+			StoreId:         storeID,
+			SchemaVersion:   model.GetSchemaVersion(),
+			TypeDefinitions: model.GetTypeDefinitions(),
+			Conditions:      model.GetConditions(),
+		})
+		require.NoError(t, err)
+
+		_, err = s.Write(ctx, &openfgav1.WriteRequest{
+			StoreId: storeID,
+			Writes: &openfgav1.WriteRequestWrites{
+				TupleKeys: []*openfgav1.TupleKey{
+					tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+					tuple.NewTupleKey("document:1", "viewer", "user:anne"),
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		return s, storeID, func() []string {
+			resp, err := s.ListUsers(ctx, &openfgav1.ListUsersRequest{
+				StoreId:              storeID,
+				AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+				Object:               &openfgav1.Object{Type: "document", Id: "1"},
+				Relation:             "viewer",
+				UserFilters:          []*openfgav1.UserTypeFilter{{Type: "user"}},
+				ContextualTuples: []*openfgav1.TupleKey{
+					tuple.NewTupleKeyWithCondition("document:1", "viewer", "user:jon", "under_limit", nil),
+				},
+				Context: testutils.MustNewStruct(t, map[string]interface{}{"x": 200}),
+			})
+			require.NoError(t, err)
+
+			var users []string
+			for _, user := range resp.GetUsers() {
+				users = append(users, string(tuple.UserProtoToString(user)))
+			}
+			return users
+		}
+	}
+
+	t.Run("contextual_tuples_win_by_default", func(t *testing.T) {
+		_, _, list := listUsers(t)
+		require.ElementsMatch(t, []string{"user:anne"}, list())
+	})
+
+	t.Run("persisted_tuples_win", func(t *testing.T) {
+		_, _, list := listUsers(t, WithContextualTuplesConflictPolicy(storagewrappers.PersistedTuplesWin))
+		require.ElementsMatch(t, []string{"user:anne", "user:jon"}, list())
+	})
+
+	t.Run("persisted_tuples_win_for_the_store", func(t *testing.T) {
+		s, storeID, list := listUsers(t)
+
+		err := s.WriteStoreFeatureFlags(ctx, storeID, map[StoreFeatureFlag]bool{
+			StoreFeaturePersistedTuplesWin: true,
+		})
+		require.NoError(t, err)
+
+		require.ElementsMatch(t, []string{"user:anne", "user:jon"}, list())
+	})
+}
+
 func TestUserFiltersToString(t *testing.T) {
 	require.Equal(t, "user", userFiltersToString([]*openfgav1.UserTypeFilter{{
 		Type: "user",
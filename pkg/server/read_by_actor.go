@@ -0,0 +1,35 @@
+package server
+
+import (
+	"context"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+)
+
+// ReadByActor returns the tuples of the store that were written by the actor, for audit. The actor of a
+// Write is the subject of the authenticated client, or the one set with storage.ContextWithWriteActor.
+// It returns ReadByActorUnsupported if the datastore does not record the actor of the writes. Only the memory
+// datastore records them: the SQL datastores have no column for the actor, so the tuples they persist have no
+// provenance, and the Writes to them still succeed without recording it.
+func (s *Server) ReadByActor(ctx context.Context, storeID, actor string) ([]*openfgav1.Tuple, error) {
+	ctx, span := tracer.Start(ctx, "ReadByActor", trace.WithAttributes(
+		attribute.String("store_id", storeID),
+		attribute.String("actor", actor),
+	))
+	defer span.End()
+
+	if s.actorTupleReader == nil {
+		return nil, serverErrors.ReadByActorUnsupported
+	}
+
+	tuples, err := s.actorTupleReader.ReadByActor(ctx, storeID, actor)
+	if err != nil {
+		return nil, serverErrors.HandleError("", err)
+	}
+
+	return tuples, nil
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage"
+)
+
+// ReadFilteredChanges returns a page of the changes of the store, as ReadChanges does, keeping only the ones
+// matching the filter: by object type, relation and user, and written in the [StartTime, EndTime) range if set.
+// The filters are pushed down to the datastore, so that a consumer syncing one relation does not read the
+// full changelog. The continuation token must be used with the same filter. It returns
+// ChangelogFilterUnsupported if the datastore cannot filter the changes.
+func (s *Server) ReadFilteredChanges(
+	ctx context.Context,
+	storeID string,
+	filter storage.ReadChangesFilter,
+	pageSize int32,
+	continuationToken string,
+) (*openfgav1.ReadChangesResponse, error) {
+	ctx, span := tracer.Start(ctx, "ReadFilteredChanges", trace.WithAttributes(
+		attribute.String("store_id", storeID),
+		attribute.String("type", filter.ObjectType),
+		attribute.String("relation", filter.Relation),
+	))
+	defer span.End()
+
+	if s.changelogFilterReader == nil {
+		return nil, serverErrors.ChangelogFilterUnsupported
+	}
+
+	if !filter.StartTime.IsZero() && !filter.EndTime.IsZero() && !filter.StartTime.Before(filter.EndTime) {
+		return nil, status.Error(codes.InvalidArgument, "the start time must be before the end time")
+	}
+
+	decodedContToken, err := s.encoder.Decode(continuationToken)
+	if err != nil {
+		return nil, serverErrors.InvalidContinuationToken
+	}
+	paginationOptions := storage.NewPaginationOptions(pageSize, string(decodedContToken))
+
+	changes, contToken, err := s.changelogFilterReader.ReadFilteredChanges(
+		ctx, storeID, filter, paginationOptions, time.Duration(s.changelogHorizonOffset)*time.Minute,
+	)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return &openfgav1.ReadChangesResponse{
+				ContinuationToken: continuationToken,
+			}, nil
+		}
+		return nil, serverErrors.HandleError("", err)
+	}
+
+	encodedContToken, err := s.encoder.Encode(contToken)
+	if err != nil {
+		return nil, serverErrors.HandleError("", err)
+	}
+
+	return &openfgav1.ReadChangesResponse{
+		Changes:           changes,
+		ContinuationToken: encodedContToken,
+	}, nil
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/openfga/openfga/internal/graph"
+)
+
+// RuntimeConfig holds the settings of a [Server] that can be changed with UpdateRuntimeConfig while it is
+// running, e.g. on a configuration reload, without dropping its in-memory caches.
+type RuntimeConfig struct {
+	// CheckQueryCacheTTL is the TTL of the Check sub-problems cached from now on, see WithCheckQueryCacheTTL.
+	// It has no effect if the Check query cache is disabled.
+	CheckQueryCacheTTL time.Duration
+	// CheckDispatchThrottlingDefaultThreshold and CheckDispatchThrottlingMaxThreshold are the thresholds the
+	// Check dispatches are throttled at, see WithDispatchThrottlingCheckResolverThreshold and
+	// WithDispatchThrottlingCheckResolverMaxThreshold. They have no effect if Check dispatch throttling is
+	// disabled.
+	CheckDispatchThrottlingDefaultThreshold uint32
+	CheckDispatchThrottlingMaxThreshold     uint32
+}
+
+// RuntimeConfig returns the settings the server is currently running with that can be changed with
+// UpdateRuntimeConfig.
+func (s *Server) RuntimeConfig() RuntimeConfig {
+	s.runtimeConfigMu.RLock()
+	defer s.runtimeConfigMu.RUnlock()
+
+	return RuntimeConfig{
+		CheckQueryCacheTTL:                      s.checkQueryCacheTTL,
+		CheckDispatchThrottlingDefaultThreshold: s.checkDispatchThrottlingDefaultThreshold,
+		CheckDispatchThrottlingMaxThreshold:     s.checkDispatchThrottlingMaxThreshold,
+	}
+}
+
+// UpdateRuntimeConfig applies the settings to the running server. The requests in flight may complete with
+// either the previous or the new settings. The cached Check sub-problems are kept, with the TTL they were
+// cached with. It returns an error, without changing any setting, if the settings are invalid.
+func (s *Server) UpdateRuntimeConfig(config RuntimeConfig) error {
+	if config.CheckQueryCacheTTL <= 0 {
+		return fmt.Errorf("check query cache TTL must be greater than zero")
+	}
+
+	if config.CheckDispatchThrottlingMaxThreshold != 0 && config.CheckDispatchThrottlingDefaultThreshold > config.CheckDispatchThrottlingMaxThreshold {
+		return fmt.Errorf("check default dispatch throttling threshold must be equal or smaller than max dispatch threshold for Check")
+	}
+
+	s.runtimeConfigMu.Lock()
+	defer s.runtimeConfigMu.Unlock()
+
+	s.checkQueryCacheTTL = config.CheckQueryCacheTTL
+	if s.cachedCheckResolver != nil {
+		s.cachedCheckResolver.SetCacheTTL(config.CheckQueryCacheTTL)
+	}
+
+	s.checkDispatchThrottlingDefaultThreshold = config.CheckDispatchThrottlingDefaultThreshold
+	s.checkDispatchThrottlingMaxThreshold = config.CheckDispatchThrottlingMaxThreshold
+	if s.dispatchThrottlingCheckResolver != nil {
+		s.dispatchThrottlingCheckResolver.SetConfig(graph.DispatchThrottlingCheckResolverConfig{
+			DefaultThreshold: config.CheckDispatchThrottlingDefaultThreshold,
+			MaxThreshold:     config.CheckDispatchThrottlingMaxThreshold,
+		})
+	}
+
+	return nil
+}
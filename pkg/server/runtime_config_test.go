@@ -0,0 +1,61 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/openfga/openfga/pkg/storage/memory"
+)
+
+func TestUpdateRuntimeConfig(t *testing.T) {
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+		WithCheckQueryCacheEnabled(true),
+		WithCheckQueryCacheTTL(time.Minute),
+		WithDispatchThrottlingCheckResolverEnabled(true),
+		WithDispatchThrottlingCheckResolverFrequency(time.Millisecond),
+		WithDispatchThrottlingCheckResolverThreshold(50),
+		WithDispatchThrottlingCheckResolverMaxThreshold(100),
+	)
+	t.Cleanup(s.Close)
+
+	require.Equal(t, RuntimeConfig{
+		CheckQueryCacheTTL:                      time.Minute,
+		CheckDispatchThrottlingDefaultThreshold: 50,
+		CheckDispatchThrottlingMaxThreshold:     100,
+	}, s.RuntimeConfig())
+
+	updated := RuntimeConfig{
+		CheckQueryCacheTTL:                      10 * time.Second,
+		CheckDispatchThrottlingDefaultThreshold: 20,
+		CheckDispatchThrottlingMaxThreshold:     40,
+	}
+	require.NoError(t, s.UpdateRuntimeConfig(updated))
+	require.Equal(t, updated, s.RuntimeConfig())
+
+	cfg := s.DumpConfig()
+	require.Equal(t, 10*time.Second, cfg.CheckQueryCache.TTL)
+	require.Equal(t, uint32(20), cfg.CheckDispatchThrottling.DefaultThreshold)
+	require.Equal(t, uint32(40), cfg.CheckDispatchThrottling.MaxThreshold)
+
+	t.Run("invalid_config_changes_nothing", func(t *testing.T) {
+		err := s.UpdateRuntimeConfig(RuntimeConfig{
+			CheckQueryCacheTTL:                      time.Second,
+			CheckDispatchThrottlingDefaultThreshold: 50,
+			CheckDispatchThrottlingMaxThreshold:     10,
+		})
+		require.Error(t, err)
+
+		err = s.UpdateRuntimeConfig(RuntimeConfig{
+			CheckDispatchThrottlingDefaultThreshold: 50,
+		})
+		require.Error(t, err)
+
+		require.Equal(t, updated, s.RuntimeConfig())
+	})
+}
@@ -5,17 +5,23 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math/rand"
 	"net/http"
 	"slices"
 	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/openfga/openfga/internal/throttler/threshold"
 
 	"github.com/openfga/openfga/internal/throttler"
 
+	"github.com/google/cel-go/cel"
 	grpc_ctxtags "github.com/grpc-ecosystem/go-grpc-middleware/tags"
+	"github.com/karlseguin/ccache/v3"
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -24,14 +30,18 @@ import (
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
+	"github.com/openfga/openfga/internal/authn"
 	"github.com/openfga/openfga/internal/build"
 	"github.com/openfga/openfga/internal/condition"
 	"github.com/openfga/openfga/internal/graph"
 	serverconfig "github.com/openfga/openfga/internal/server/config"
 	"github.com/openfga/openfga/internal/utils"
 	"github.com/openfga/openfga/internal/validation"
+	"github.com/openfga/openfga/pkg/audit"
+	"github.com/openfga/openfga/pkg/dispatch"
 	"github.com/openfga/openfga/pkg/encoder"
 	"github.com/openfga/openfga/pkg/gateway"
 	"github.com/openfga/openfga/pkg/logger"
@@ -39,6 +49,7 @@ import (
 	"github.com/openfga/openfga/pkg/middleware/validator"
 	"github.com/openfga/openfga/pkg/server/commands"
 	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/server/health"
 	"github.com/openfga/openfga/pkg/storage"
 	"github.com/openfga/openfga/pkg/storage/storagewrappers"
 	"github.com/openfga/openfga/pkg/telemetry"
@@ -50,8 +61,16 @@ type ExperimentalFeatureFlag string
 
 const (
 	AuthorizationModelIDHeader                          = "Openfga-Authorization-Model-Id"
+	DataStalenessHeader                                 = "Openfga-Data-Staleness-Ms"
+	IncompleteDecisionHeader                            = "Openfga-Incomplete-Decision"
+	ObligationsHeader                                   = "Openfga-Obligations"
+	ListObjectsCompleteTrailer                          = "Openfga-List-Objects-Complete"
 	authorizationModelIDKey                             = "authorization_model_id"
 	ExperimentalEnableListUsers ExperimentalFeatureFlag = "enable-list-users"
+
+	// ExperimentalListObjectsIntersectionPlanning makes ListObjects expand the operand of the intersections
+	// with the lowest estimated fan-out instead of the first one, if the datastore can count tuples.
+	ExperimentalListObjectsIntersectionPlanning ExperimentalFeatureFlag = "enable-list-objects-intersection-planning"
 )
 
 var tracer = otel.Tracer("openfga/pkg/server")
@@ -105,7 +124,10 @@ type Server struct {
 	transport                        gateway.Transport
 	resolveNodeLimit                 uint32
 	resolveNodeBreadthLimit          uint32
+	resolveNodeUnionBreadthLimit     uint32
+	resolveNodeTTUBreadthLimit       uint32
 	changelogHorizonOffset           int
+	watchPollInterval                time.Duration
 	listObjectsDeadline              time.Duration
 	listObjectsMaxResults            uint32
 	listUsersDeadline                time.Duration
@@ -114,10 +136,15 @@ type Server struct {
 	maxConcurrentReadsForCheck       uint32
 	maxConcurrentReadsForListUsers   uint32
 	maxAuthorizationModelCacheSize   int
+	sharedTypesystemCacheSize        int
 	maxAuthorizationModelSizeInBytes int
+	maxTypesPerAuthorizationModel    int
 	experimentals                    []ExperimentalFeatureFlag
 	serviceName                      string
 
+	// set if the conditions of each store are compiled in their own CEL environment
+	conditionEnvs *condition.StoreEnvs
+
 	// NOTE don't use this directly, use function resolveTypesystem. See https://github.com/openfga/openfga/issues/1527
 	typesystemResolver     typesystem.TypesystemResolverFunc
 	typesystemResolverStop func()
@@ -127,7 +154,20 @@ type Server struct {
 	checkQueryCacheTTL     time.Duration
 	cachedCheckResolver    *graph.CachedCheckResolver
 
+	listObjectsReadCacheEnabled bool
+	listObjectsReadCacheLimit   uint32
+	listObjectsReadCacheTTL     time.Duration
+	// set if listObjectsReadCacheEnabled is true
+	listObjectsReadCache *storagewrappers.ReadStartingWithUserCache
+
 	checkResolver graph.CheckResolver
+	// closes the layers of checkResolver
+	checkResolverCloser graph.CheckResolverCloser
+	// set with WithCheckResolverOrder and WithCheckResolverMiddleware
+	checkResolverBuilderOpts []graph.CheckResolverOrderedBuilderOpt
+
+	// [name] => alternate resolver chain selected with WithCheckResolverChain
+	namedCheckResolvers map[string]graph.CheckResolver
 
 	requestDurationByQueryHistogramBuckets         []uint
 	requestDurationByDispatchCountHistogramBuckets []uint
@@ -144,7 +184,176 @@ type Server struct {
 
 	dispatchThrottlingCheckResolver *graph.DispatchThrottlingCheckResolver
 
+	// runtimeConfigMu guards the settings changed with UpdateRuntimeConfig once the server is running
+	runtimeConfigMu sync.RWMutex
+
 	listObjectsDispatchThrottler throttler.Throttler
+
+	checkOutcomeLogSampleRate float64
+
+	// set with WithAuditLogger
+	auditLogger *audit.Logger
+
+	maxVisitedPathsForCheck          uint32
+	visitedPathsLimitAsCycleForCheck bool
+
+	maxNodeFanoutForCheck uint32
+
+	listObjectsCandidateCheckConcurrencyLimit uint32
+
+	maxConditionEvaluationsForCheck uint32
+	checkConditionEvaluationCache   bool
+
+	checkUndefinedComputedRelationError bool
+
+	checkDispatchWorkerPoolSize uint32
+
+	maxConcurrentChecksPerBatchCheck uint32
+
+	// [storeID] => resolve node limit used for the requests of that store
+	storeResolveNodeLimits            map[string]uint32
+	storeDispatchThrottlingThresholds map[string]uint32
+
+	writeDisallowedIDCharacters      string
+	writePermissiveUsersetReferences bool
+
+	maxObjectIDLength        int
+	maxUserIDLength          int
+	checkIDLengthEnforcement bool
+
+	checkSubjectTypeValidation bool
+
+	// [storeID] => ['objectType#relation'] => relation resolved in its place
+	storeRelationAliases map[string]map[string]string
+
+	// set if the datastore can report how stale the data backing a decision may be
+	freshnessReporter storage.FreshnessReporter
+
+	// set with WithCheckTupleSnapshots, tupleSnapshotter takes the snapshots of the tuples a Check reads
+	checkTupleSnapshotsEnabled bool
+	tupleSnapshotter           storage.TupleSnapshotter
+
+	// set if the datastore can count the recent changes of a store
+	changeCounter storage.ChangeCounter
+
+	// set if the datastore can count the tuples of a relation and ListObjects intersection planning is enabled
+	tupleCounter storage.TupleCounter
+
+	// set if the datastore can compute the cardinality statistics of a store
+	storeStatsReader storage.StoreStatsReader
+
+	// set if the datastore can read the earliest and the latest tuple writes of a store
+	storeTimeRangeReader storage.StoreTimeRangeReader
+
+	// set if the datastore records the actor of the writes
+	actorTupleReader storage.ActorTupleReader
+
+	// set if the datastore can write expiring tuples
+	tupleExpirer storage.TupleExpirer
+
+	// the expired tuples are deleted every tupleExpiryReaperInterval by a goroutine, stopped by closing
+	// stopTupleExpiryReaper, that closes tupleExpiryReaperDone once it returns
+	tupleExpiryReaperInterval time.Duration
+	stopTupleExpiryReaper     chan struct{}
+	tupleExpiryReaperDone     chan struct{}
+
+	// once draining is set by Drain, the new Check and ListObjects requests are rejected, and drained is closed
+	// when the last of the inFlightRequests completes. The requests still in flight after shutdownDrainTimeout
+	// are canceled with abortRequests.
+	shutdownDrainTimeout time.Duration
+	drainMu              sync.Mutex
+	draining             bool
+	inFlightRequests     int
+	drained              chan struct{}
+	abortCtx             context.Context
+	abortRequests        context.CancelCauseFunc
+
+	// set if the datastore can filter the changes of a store by relation, user and time
+	changelogFilterReader storage.ChangelogFilterReader
+
+	// set if the datastore can apply writes conditionally
+	conditionalTupleWriter storage.ConditionalTupleWriter
+
+	maxConcurrentChecks uint32
+	checkQueueSize      uint32
+	// checkSlots and checkQueue are only set if maxConcurrentChecks is not 0
+	checkSlots chan struct{}
+	checkQueue chan struct{}
+
+	contextualTuplesConflictPolicy  storagewrappers.ConflictPolicy
+	rejectDuplicateContextualTuples bool
+	depthLimitBehavior              DepthLimitBehavior
+	checkObligations                bool
+
+	// set if the datastore persists per-store feature flags
+	storeFeatureFlagsBackend  storage.StoreFeatureFlagsBackend
+	storeFeatureFlagsCacheTTL time.Duration
+	storeFeatureFlagsCache    *ccache.Cache[map[StoreFeatureFlag]bool]
+
+	// set if the datastore can pin the default model of the stores, cached for storeFeatureFlagsCacheTTL
+	storeDefaultModelBackend storage.StoreDefaultModelBackend
+	storeDefaultModelCache   *ccache.Cache[string]
+
+	// set if the datastore stores the contextual tuples and the condition context of the assertions
+	contextualAssertionsBackend storage.ContextualAssertionsBackend
+
+	storageQueryTimeout time.Duration
+
+	datastoreReadBudget uint32
+
+	// set with WithCheckReadHedging, checkReadHedger hedges the reads of the Checks with checkReadReplica
+	checkReadReplica     storage.RelationshipTupleReader
+	checkReadHedgingOpts []storagewrappers.HedgingOption
+	checkReadHedger      *storagewrappers.HedgingTupleReader
+
+	checkReadDeduplicationEnabled bool
+
+	// set with WithCheckUsersetReadBatching, usersetBatchReader is the datastore if it supports batched
+	// userset reads. It is the unwrapped datastore: the BatchingUsersetTupleReader sends the lone reads through
+	// the reader of the Check, e.g. the hedged one, and traces the batched ones itself.
+	checkUsersetReadBatchingEnabled bool
+	checkUsersetReadBatchingOpts    []storagewrappers.UsersetBatchingOption
+	usersetBatchReader              storage.BatchUsersetTupleReader
+
+	// set with WithStoreShadowModel, checkShadowResolver resolves the Checks of the stores of shadowModels
+	// against their candidate models as well
+	shadowModels        map[string]string
+	checkShadowResolver *graph.ShadowCheckResolver
+
+	listObjectsEmptyResultCacheTTL time.Duration
+	// set if listObjectsEmptyResultCacheTTL is not 0
+	listObjectsEmptyResultCache *ccache.Cache[struct{}]
+
+	// set to record the datastore reads made by each Check
+	checkReadPatternSink CheckReadPatternSink
+
+	// set if the datastore can archive authorization models
+	modelArchiveBackend   storage.AuthorizationModelArchiveBackend
+	archivedModelBehavior ArchivedModelBehavior
+
+	// 'objectType#relation' strings of the relations reported by AnalyzeEscalation
+	highPrivilegeRelations []string
+}
+
+type ctxKey string
+
+const (
+	resolveNodeLimitCtxKey ctxKey = "resolve-node-limit"
+	relationAliasesCtxKey  ctxKey = "relation-aliases"
+)
+
+// ContextWithResolveNodeLimit returns a context carrying the resolve node limit to use for a single
+// request. It overrides the store default set with WithStoreResolveNodeLimit, but cannot exceed the
+// server-wide limit set with WithResolveNodeLimit: a higher limit is clamped to it.
+func ContextWithResolveNodeLimit(ctx context.Context, limit uint32) context.Context {
+	return context.WithValue(ctx, resolveNodeLimitCtxKey, limit)
+}
+
+// ContextWithRelationAliases returns a context carrying relation aliases to apply to a single Check request,
+// in addition to (and taking precedence over) the aliases of the store set with WithStoreRelationAliases.
+// See WithStoreRelationAliases for the format of aliases.
+func ContextWithRelationAliases(ctx context.Context, aliases map[string]string) context.Context {
+	return context.WithValue(ctx, relationAliasesCtxKey, aliases)
 }
 
 type OpenFGAServiceV1Option func(s *Server)
@@ -164,6 +373,15 @@ func WithAuthorizationModelCacheSize(maxAuthorizationModelCacheSize int) OpenFGA
 	}
 }
 
+// WithSharedTypesystemCacheSize sets the maximum number of typesystems shared by semantically identical models,
+// across stores and model IDs, so that such models are validated and their conditions compiled only once.
+// The least recently used typesystems are evicted first. A size of 0 disables the sharing.
+func WithSharedTypesystemCacheSize(size int) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.sharedTypesystemCacheSize = size
+	}
+}
+
 func WithLogger(l logger.Logger) OpenFGAServiceV1Option {
 	return func(s *Server) {
 		s.logger = l
@@ -192,6 +410,60 @@ func WithResolveNodeLimit(limit uint32) OpenFGAServiceV1Option {
 	}
 }
 
+// WithStoreResolveNodeLimit sets the default resolve node limit for the requests of one store,
+// overriding the server-wide limit set with WithResolveNodeLimit. It may be provided multiple times.
+// See also ContextWithResolveNodeLimit.
+func WithStoreResolveNodeLimit(storeID string, limit uint32) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		if s.storeResolveNodeLimits == nil {
+			s.storeResolveNodeLimits = map[string]uint32{}
+		}
+		s.storeResolveNodeLimits[storeID] = limit
+	}
+}
+
+// WithStoreDispatchThrottlingThreshold sets the dispatch throttling threshold of the Check and ListObjects requests
+// of one store, e.g. to keep a store with a pathological model from starving the dispatch capacity of the other
+// stores. It only applies when dispatch throttling is enabled for the API, and is bounded by the max threshold set
+// for it. A lower threshold set in the context of a request with [dispatch.ContextWithThrottlingThreshold] takes
+// precedence. It may be provided multiple times.
+func WithStoreDispatchThrottlingThreshold(storeID string, threshold uint32) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		if s.storeDispatchThrottlingThresholds == nil {
+			s.storeDispatchThrottlingThresholds = map[string]uint32{}
+		}
+		s.storeDispatchThrottlingThresholds[storeID] = threshold
+	}
+}
+
+// WithStoreRelationAliases sets relation aliases for the Check requests of one store, so that Checks of an
+// old relation resolve using the definition of a new one without rewriting the model (e.g. during a migration).
+// The keys of aliases are the 'objectType#relation' strings of the aliased relations, and the values are
+// the names of the relations of the same type that are resolved in their place, e.g. {"document#reader": "viewer"}.
+// See also ContextWithRelationAliases.
+func WithStoreRelationAliases(storeID string, aliases map[string]string) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		if s.storeRelationAliases == nil {
+			s.storeRelationAliases = map[string]map[string]string{}
+		}
+		s.storeRelationAliases[storeID] = aliases
+	}
+}
+
+// WithStoreShadowModel resolves the Checks of one store against a candidate authorization model as well, in the
+// background, to validate a change of the model with the production requests before writing it as the latest model
+// of the store. The Checks are answered with the model they request, and the ones for which the candidate model
+// disagrees are logged, along with the path granting access in the model that allowed them, and counted by the
+// check_shadow_mismatch_count metric. See graph.WithShadowCheckTypesystem.
+func WithStoreShadowModel(storeID, modelID string) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		if s.shadowModels == nil {
+			s.shadowModels = map[string]string{}
+		}
+		s.shadowModels[storeID] = modelID
+	}
+}
+
 // WithResolveNodeBreadthLimit sets a limit on the number of goroutines that can be created
 // when evaluating a subtree of a Check, ListObjects or ListUsers call.
 // Thinking of a Check request as a tree of evaluations, this option controls,
@@ -205,6 +477,35 @@ func WithResolveNodeBreadthLimit(limit uint32) OpenFGAServiceV1Option {
 	}
 }
 
+// WithResolveNodeUnionBreadthLimit sets the maximum number of children of a union rewrite that are evaluated
+// concurrently in a Check, instead of the WithResolveNodeBreadthLimit, e.g. to evaluate wide unions of cheap
+// relations at once without raising the limit of the other rewrites. 0 (the default) means the limit of
+// WithResolveNodeBreadthLimit. The openfga_operator_dispatch_count metric reports how many children the
+// unions have.
+func WithResolveNodeUnionBreadthLimit(limit uint32) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.resolveNodeUnionBreadthLimit = limit
+	}
+}
+
+// WithResolveNodeTTUBreadthLimit sets the maximum number of tuples of a tuple to userset rewrite (e.g. the
+// parents of an object) whose computed relation is evaluated concurrently in a Check, instead of the
+// WithResolveNodeBreadthLimit. 0 (the default) means the limit of WithResolveNodeBreadthLimit.
+func WithResolveNodeTTUBreadthLimit(limit uint32) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.resolveNodeTTUBreadthLimit = limit
+	}
+}
+
+// WithListObjectsCandidateCheckConcurrencyLimit sets the maximum number of candidate objects of a ListObjects
+// call that are checked concurrently, instead of the WithResolveNodeBreadthLimit. 0 (the default) means the
+// limit of WithResolveNodeBreadthLimit.
+func WithListObjectsCandidateCheckConcurrencyLimit(limit uint32) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.listObjectsCandidateCheckConcurrencyLimit = limit
+	}
+}
+
 // WithChangelogHorizonOffset sets an offset (in minutes) from the current time.
 // Changes that occur after this offset will not be included in the response of ReadChanges API.
 // If your datastore is eventually consistent or if you have a database with replication delay, we recommend setting this (e.g. 1 minute).
@@ -214,6 +515,14 @@ func WithChangelogHorizonOffset(offset int) OpenFGAServiceV1Option {
 	}
 }
 
+// WithWatchPollInterval sets how often Watch reads the changelog of the store once it has caught up with it.
+// It defaults to 1s.
+func WithWatchPollInterval(interval time.Duration) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.watchPollInterval = interval
+	}
+}
+
 // WithListObjectsDeadline affect the ListObjects API and Streamed ListObjects API only.
 // It sets the maximum amount of time that the server will spend gathering results.
 func WithListObjectsDeadline(deadline time.Duration) OpenFGAServiceV1Option {
@@ -317,268 +626,837 @@ func WithCheckQueryCacheTTL(ttl time.Duration) OpenFGAServiceV1Option {
 	}
 }
 
-// WithRequestDurationByQueryHistogramBuckets sets the buckets used in labelling the requestDurationByQueryAndDispatchHistogram.
-func WithRequestDurationByQueryHistogramBuckets(buckets []uint) OpenFGAServiceV1Option {
+// WithListObjectsReadCacheEnabled enables caching of the tuples read by the reverse expansion of the List objects
+// API, so that the reads repeated across requests for the same user, relation and type are served from memory.
+// This cache is shared for all requests. A Write to the store invalidates the cached reads of the store on this
+// server; the writes made through other servers are only observed once the TTL expires.
+// See also WithListObjectsReadCacheLimit and WithListObjectsReadCacheTTL.
+func WithListObjectsReadCacheEnabled(enabled bool) OpenFGAServiceV1Option {
 	return func(s *Server) {
-		sort.Slice(buckets, func(i, j int) bool { return buckets[i] < buckets[j] })
-		s.requestDurationByQueryHistogramBuckets = buckets
+		s.listObjectsReadCacheEnabled = enabled
 	}
 }
 
-// WithRequestDurationByDispatchCountHistogramBuckets sets the buckets used in labelling the requestDurationByQueryAndDispatchHistogram.
-func WithRequestDurationByDispatchCountHistogramBuckets(buckets []uint) OpenFGAServiceV1Option {
+// WithListObjectsReadCacheLimit sets the cache size limit (in items)
+// Needs WithListObjectsReadCacheEnabled set to true.
+func WithListObjectsReadCacheLimit(limit uint32) OpenFGAServiceV1Option {
 	return func(s *Server) {
-		sort.Slice(buckets, func(i, j int) bool { return buckets[i] < buckets[j] })
-		s.requestDurationByDispatchCountHistogramBuckets = buckets
+		s.listObjectsReadCacheLimit = limit
 	}
 }
 
-func WithMaxAuthorizationModelSizeInBytes(size int) OpenFGAServiceV1Option {
+// WithListObjectsReadCacheTTL sets the TTL of the cached list objects reads
+// Needs WithListObjectsReadCacheEnabled set to true.
+func WithListObjectsReadCacheTTL(ttl time.Duration) OpenFGAServiceV1Option {
 	return func(s *Server) {
-		s.maxAuthorizationModelSizeInBytes = size
+		s.listObjectsReadCacheTTL = ttl
 	}
 }
 
-// WithDispatchThrottlingCheckResolverEnabled sets whether dispatch throttling is enabled for Check requests.
-// Enabling this feature will prioritize dispatched requests requiring less than the configured dispatch
-// threshold over requests whose dispatch count exceeds the configured threshold.
-func WithDispatchThrottlingCheckResolverEnabled(enabled bool) OpenFGAServiceV1Option {
+// WithMaxVisitedPathsForCheck sets the maximum number of paths that may be visited while resolving
+// a single Check request. A limit of 0 (the default) means there is no limit.
+func WithMaxVisitedPathsForCheck(limit uint32) OpenFGAServiceV1Option {
 	return func(s *Server) {
-		s.checkDispatchThrottlingEnabled = enabled
+		s.maxVisitedPathsForCheck = limit
 	}
 }
 
-// WithDispatchThrottlingCheckResolverFrequency defines how frequent dispatch throttling
-// will be evaluated for Check requests.
-// Frequency controls how frequently throttled dispatch requests are evaluated to determine whether
-// it can be processed.
-// This value should not be too small (i.e., in the ns ranges) as i) there are limitation in timer resolution
-// and ii) very small value will result in a higher frequency of processing dispatches,
-// which diminishes the value of the throttling.
-func WithDispatchThrottlingCheckResolverFrequency(frequency time.Duration) OpenFGAServiceV1Option {
+// WithVisitedPathsLimitAsCycleForCheck makes the paths crossing the limit set with WithMaxVisitedPathsForCheck
+// resolve as cycles, i.e. as not allowed, instead of failing the Check. This bounds the memory used by Check at the
+// expense of completeness.
+func WithVisitedPathsLimitAsCycleForCheck(enabled bool) OpenFGAServiceV1Option {
 	return func(s *Server) {
-		s.checkDispatchThrottlingFrequency = frequency
+		s.visitedPathsLimitAsCycleForCheck = enabled
 	}
 }
 
-// WithDispatchThrottlingCheckResolverThreshold define the number of dispatches to be throttled.
-// In addition, it will update checkDispatchThrottlingMaxThreshold if required.
-func WithDispatchThrottlingCheckResolverThreshold(defaultThreshold uint32) OpenFGAServiceV1Option {
+// WithMaxNodeFanoutForCheck sets the maximum number of children of a union or intersection rewrite that are
+// evaluated at the same time while resolving a Check. Wider rewrites are evaluated in sequential batches.
+// A limit of 0 (the default) means there is no limit.
+func WithMaxNodeFanoutForCheck(limit uint32) OpenFGAServiceV1Option {
 	return func(s *Server) {
-		s.checkDispatchThrottlingDefaultThreshold = defaultThreshold
+		s.maxNodeFanoutForCheck = limit
 	}
 }
 
-// WithDispatchThrottlingCheckResolverMaxThreshold define the maximum threshold values allowed
-// It will ensure checkDispatchThrottlingMaxThreshold will never be smaller than threshold.
-func WithDispatchThrottlingCheckResolverMaxThreshold(maxThreshold uint32) OpenFGAServiceV1Option {
+// WithMaxConditionEvaluationsForCheck sets the maximum number of tuple conditions, of persisted and contextual
+// tuples alike, that may be evaluated while resolving a single Check. This bounds the CPU spent on requests
+// attaching conditions to many contextual tuples. A limit of 0 (the default) means there is no limit.
+func WithMaxConditionEvaluationsForCheck(limit uint32) OpenFGAServiceV1Option {
 	return func(s *Server) {
-		s.checkDispatchThrottlingMaxThreshold = maxThreshold
+		s.maxConditionEvaluationsForCheck = limit
 	}
 }
 
-// MustNewServerWithOpts see NewServerWithOpts.
-func MustNewServerWithOpts(opts ...OpenFGAServiceV1Option) *Server {
-	s, err := NewServerWithOpts(opts...)
-	if err != nil {
-		panic(fmt.Errorf("failed to construct the OpenFGA server: %w", err))
+// WithCheckConditionEvaluationCache makes Check evaluate a tuple condition only once for the same condition
+// and condition context, which avoids running the same CEL program many times when many tuples of a relation
+// are gated by the same condition. The evaluations are memoized for the duration of a single Check.
+func WithCheckConditionEvaluationCache(enabled bool) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.checkConditionEvaluationCache = enabled
 	}
+}
 
-	return s
+// WithCheckUndefinedComputedRelationError makes Check fail with a validation error naming the relations when a
+// tuple to userset rewrite (e.g. 'viewer from parent') leads to an object whose type does not define the computed
+// relation, instead of skipping the object. See graph.WithUndefinedComputedRelationError.
+func WithCheckUndefinedComputedRelationError(enabled bool) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.checkUndefinedComputedRelationError = enabled
+	}
 }
 
-func (s *Server) IsExperimentallyEnabled(flag ExperimentalFeatureFlag) bool {
-	return slices.Contains(s.experimentals, flag)
+// WithCheckDispatchWorkerPool makes Check evaluate the children of rewrites on reusable goroutines instead of
+// starting new goroutines for each of them, which reduces scheduler overhead at high request rates. Up to
+// maxIdleWorkers goroutines are kept idle between evaluations. A value of 0 (the default) disables the pool.
+func WithCheckDispatchWorkerPool(maxIdleWorkers uint32) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.checkDispatchWorkerPoolSize = maxIdleWorkers
+	}
 }
 
-// WithListObjectsDispatchThrottlingEnabled sets whether dispatch throttling is enabled for List Objects requests.
-// Enabling this feature will prioritize dispatched requests requiring less than the configured dispatch
-// threshold over requests whose dispatch count exceeds the configured threshold.
-func WithListObjectsDispatchThrottlingEnabled(enabled bool) OpenFGAServiceV1Option {
+// WithMaxConcurrentChecksPerBatchCheck sets the maximum number of checks of a single BatchCheck call
+// that are evaluated concurrently.
+func WithMaxConcurrentChecksPerBatchCheck(limit uint32) OpenFGAServiceV1Option {
 	return func(s *Server) {
-		s.listObjectsDispatchThrottlingEnabled = enabled
+		s.maxConcurrentChecksPerBatchCheck = limit
 	}
 }
 
-// WithListObjectsDispatchThrottlingFrequency defines how frequent dispatch throttling
-// will be evaluated for List Objects requests.
-// Frequency controls how frequently throttled dispatch requests are evaluated to determine whether
-// it can be processed.
-// This value should not be too small (i.e., in the ns ranges) as i) there are limitation in timer resolution
-// and ii) very small value will result in a higher frequency of processing dispatches,
-// which diminishes the value of the throttling.
-func WithListObjectsDispatchThrottlingFrequency(frequency time.Duration) OpenFGAServiceV1Option {
+// WithMaxConcurrentChecks sets the maximum number of Check requests that the server evaluates at the
+// same time, across all stores. Requests beyond the limit wait for a free slot in the queue sized with
+// WithCheckQueueSize, or are rejected with ServerBusy if the queue is full.
+// A limit of 0 (the default) means there is no limit.
+func WithMaxConcurrentChecks(limit uint32) OpenFGAServiceV1Option {
 	return func(s *Server) {
-		s.listObjectsDispatchThrottlingFrequency = frequency
+		s.maxConcurrentChecks = limit
 	}
 }
 
-// WithListObjectsDispatchThrottlingThreshold define the number of dispatches to be throttled
-// for List Objects requests.
-func WithListObjectsDispatchThrottlingThreshold(threshold uint32) OpenFGAServiceV1Option {
+// WithCheckQueueSize sets the maximum number of Check requests that may wait for a slot when the limit
+// set with WithMaxConcurrentChecks is reached. A size of 0 (the default) rejects those requests right away.
+func WithCheckQueueSize(size uint32) OpenFGAServiceV1Option {
 	return func(s *Server) {
-		s.listObjectsDispatchDefaultThreshold = threshold
+		s.checkQueueSize = size
 	}
 }
 
-// WithListObjectsDispatchThrottlingMaxThreshold define the maximum threshold values allowed
-// It will ensure listObjectsDispatchThrottlingMaxThreshold will never be smaller than threshold.
-func WithListObjectsDispatchThrottlingMaxThreshold(maxThreshold uint32) OpenFGAServiceV1Option {
+// WithContextualTuplesConflictPolicy sets which tuple a Check or ListUsers reads when a contextual tuple has the same
+// object, relation and user as a persisted tuple. See [storagewrappers.ConflictPolicy].
+// It defaults to [storagewrappers.ContextualTuplesWin].
+func WithContextualTuplesConflictPolicy(policy storagewrappers.ConflictPolicy) OpenFGAServiceV1Option {
 	return func(s *Server) {
-		s.listObjectsDispatchThrottlingMaxThreshold = maxThreshold
+		s.contextualTuplesConflictPolicy = policy
 	}
 }
 
-// NewServerWithOpts returns a new server.
-// You must call Close on it after you are done using it.
-func NewServerWithOpts(opts ...OpenFGAServiceV1Option) (*Server, error) {
-	s := &Server{
-		logger:                           logger.NewNoopLogger(),
-		encoder:                          encoder.NewBase64Encoder(),
-		transport:                        gateway.NewNoopTransport(),
-		changelogHorizonOffset:           serverconfig.DefaultChangelogHorizonOffset,
-		resolveNodeLimit:                 serverconfig.DefaultResolveNodeLimit,
-		resolveNodeBreadthLimit:          serverconfig.DefaultResolveNodeBreadthLimit,
-		listObjectsDeadline:              serverconfig.DefaultListObjectsDeadline,
-		listObjectsMaxResults:            serverconfig.DefaultListObjectsMaxResults,
-		listUsersDeadline:                serverconfig.DefaultListUsersDeadline,
-		listUsersMaxResults:              serverconfig.DefaultListUsersMaxResults,
-		maxConcurrentReadsForCheck:       serverconfig.DefaultMaxConcurrentReadsForCheck,
-		maxConcurrentReadsForListObjects: serverconfig.DefaultMaxConcurrentReadsForListObjects,
-		maxConcurrentReadsForListUsers:   serverconfig.DefaultMaxConcurrentReadsForListUsers,
-		maxAuthorizationModelSizeInBytes: serverconfig.DefaultMaxAuthorizationModelSizeInBytes,
-		maxAuthorizationModelCacheSize:   serverconfig.DefaultMaxAuthorizationModelCacheSize,
-		experimentals:                    make([]ExperimentalFeatureFlag, 0, 10),
+// WithRejectDuplicateContextualTuples makes Check reject requests that send the same contextual tuple
+// more than once with a duplicate_contextual_tuple error. Two contextual tuples are the same if they have
+// the same object, relation, user, condition name and condition context. By default (false) the duplicates
+// are silently dropped.
+func WithRejectDuplicateContextualTuples(reject bool) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.rejectDuplicateContextualTuples = reject
+	}
+}
 
-		checkQueryCacheEnabled: serverconfig.DefaultCheckQueryCacheEnable,
-		checkQueryCacheLimit:   serverconfig.DefaultCheckQueryCacheLimit,
-		checkQueryCacheTTL:     serverconfig.DefaultCheckQueryCacheTTL,
-		checkResolver:          nil,
+// DepthLimitBehavior is what a Check returns when its resolution exceeds the resolve node limit.
+type DepthLimitBehavior int
 
-		requestDurationByQueryHistogramBuckets:         []uint{50, 200},
-		requestDurationByDispatchCountHistogramBuckets: []uint{50, 200},
-		serviceName: openfgav1.OpenFGAService_ServiceDesc.ServiceName,
+const (
+	// DepthLimitError fails the Check with an authorization_model_resolution_too_complex error.
+	// This is the default behavior.
+	DepthLimitError DepthLimitBehavior = iota
+
+	// DepthLimitDenyWithIndicator returns allowed=false and sets the IncompleteDecisionHeader response
+	// header to "true", to note that the user may have been allowed by the part of the graph that was
+	// not resolved.
+	DepthLimitDenyWithIndicator
+)
 
-		checkDispatchThrottlingEnabled:          serverconfig.DefaultCheckDispatchThrottlingEnabled,
-		checkDispatchThrottlingFrequency:        serverconfig.DefaultCheckDispatchThrottlingFrequency,
-		checkDispatchThrottlingDefaultThreshold: serverconfig.DefaultCheckDispatchThrottlingDefaultThreshold,
+// WithDepthLimitBehavior sets what a Check returns when its resolution exceeds the resolve node limit.
+// It defaults to DepthLimitError.
+func WithDepthLimitBehavior(behavior DepthLimitBehavior) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.depthLimitBehavior = behavior
+	}
+}
 
-		listObjectsDispatchThrottlingEnabled:      serverconfig.DefaultListObjectsDispatchThrottlingEnabled,
-		listObjectsDispatchThrottlingFrequency:    serverconfig.DefaultListObjectsDispatchThrottlingFrequency,
-		listObjectsDispatchDefaultThreshold:       serverconfig.DefaultListObjectsDispatchThrottlingDefaultThreshold,
-		listObjectsDispatchThrottlingMaxThreshold: serverconfig.DefaultListObjectsDispatchThrottlingMaxThreshold,
+// WithCheckObligations makes an allowed Check report its obligations in the ObligationsHeader response header:
+// the comma-separated names of the conditions of the conditional tuples granting access, e.g. a 'step_up'
+// condition telling the caller to enforce step-up authentication. The header is not set if access is granted
+// without conditional tuples.
+func WithCheckObligations(enabled bool) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.checkObligations = enabled
 	}
+}
 
-	for _, opt := range opts {
-		opt(s)
+// ArchivedModelBehavior is what a Check returns when it is resolved against an archived authorization model.
+type ArchivedModelBehavior int
+
+const (
+	// ArchivedModelError fails the Check with AuthorizationModelArchived. This is the default behavior.
+	ArchivedModelError ArchivedModelBehavior = iota
+
+	// ArchivedModelResolve resolves the Check against the archived model, e.g. to audit past decisions.
+	ArchivedModelResolve
+)
+
+// WithArchivedModelBehavior sets what a Check returns when it is resolved against a model archived with
+// ArchiveAuthorizationModel. It defaults to ArchivedModelError.
+func WithArchivedModelBehavior(behavior ArchivedModelBehavior) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.archivedModelBehavior = behavior
 	}
+}
 
-	if s.datastore == nil {
-		return nil, fmt.Errorf("a datastore option must be provided")
+// WithListObjectsEmptyResultCacheTTL makes ListObjects cache the requests that return no objects for the
+// given duration, so that identical requests, e.g. of UIs denying access by default, are not recomputed.
+// Requests with contextual tuples or context are never cached. A Write to the store invalidates the cached
+// requests of the store on this server; the writes made through other servers are only observed once the
+// TTL expires. A value of 0 (the default) disables the cache.
+func WithListObjectsEmptyResultCacheTTL(ttl time.Duration) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.listObjectsEmptyResultCacheTTL = ttl
 	}
+}
 
-	if len(s.requestDurationByQueryHistogramBuckets) == 0 {
-		return nil, fmt.Errorf("request duration datastore count buckets must not be empty")
+// WithStoreFeatureFlagsCacheTTL sets how long the per-store feature flags read from the datastore are cached.
+// A flag written with WriteStoreFeatureFlags takes effect on the Checks of the store after at most this duration.
+// It defaults to 10 seconds.
+func WithStoreFeatureFlagsCacheTTL(ttl time.Duration) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.storeFeatureFlagsCacheTTL = ttl
 	}
+}
 
-	if len(s.requestDurationByDispatchCountHistogramBuckets) == 0 {
-		return nil, fmt.Errorf("request duration by dispatch count buckets must not be empty")
+// WithStorageQueryTimeout bounds each datastore query made while resolving a Check, independently of the
+// deadline of the Check itself. A query that times out fails its branch of the resolution with an error
+// wrapping [storage.ErrStorageTimeout]; the Check still succeeds if another branch decides the outcome, and
+// otherwise fails with serverErrors.DatastoreTimeout. A timeout of 0 (the default) disables it.
+func WithStorageQueryTimeout(timeout time.Duration) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.storageQueryTimeout = timeout
 	}
-	if s.checkDispatchThrottlingEnabled && s.checkDispatchThrottlingMaxThreshold != 0 && s.checkDispatchThrottlingDefaultThreshold > s.checkDispatchThrottlingMaxThreshold {
-		return nil, fmt.Errorf("check default dispatch throttling threshold must be equal or smaller than max dispatch threshold for Check")
+}
+
+// WithCheckReadHedging sends the datastore reads of a Check that the datastore is slow to answer to a read
+// replica of it as well, and uses the first answer, see storagewrappers.NewHedgingTupleReader. A read is sent
+// to the replica once it has been waiting for longer than a percentile (0.99 by default) of the latest
+// latencies of the datastore. It cuts the tail latency of the Checks, e.g. when the datastore is in another
+// availability zone, at the cost of the Checks possibly reading stale tuples from a lagging replica. It cannot be
+// combined with WithCheckTupleSnapshots.
+//
+// The server does not close the replica.
+func WithCheckReadHedging(replica storage.RelationshipTupleReader, opts ...storagewrappers.HedgingOption) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.checkReadReplica = replica
+		s.checkReadHedgingOpts = opts
 	}
+}
 
-	if s.listObjectsDispatchThrottlingMaxThreshold != 0 && s.listObjectsDispatchDefaultThreshold > s.listObjectsDispatchThrottlingMaxThreshold {
-		return nil, fmt.Errorf("ListObjects default dispatch throttling threshold must be equal or smaller than max dispatch threshold for ListObjects")
+// WithDatastoreReadBudget sets a limit on the number of datastore reads one Check, ListObjects or ListUsers call
+// will make. Unlike WithResolveNodeLimit, which bounds the depth of the resolution, it bounds its total cost, e.g.
+// for a ListObjects on a model with wide and deeply nested relations. A request exceeding it fails with
+// serverErrors.ReadBudgetExceeded, which reports the number of reads attempted. A budget of 0 (the default)
+// disables it.
+func WithDatastoreReadBudget(budget uint32) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.datastoreReadBudget = budget
 	}
+}
 
-	// below this point, don't throw errors or we may leak resources in tests
+// WithCheckReadDeduplicationEnabled coalesces the identical datastore reads made while resolving a single Check,
+// e.g. by parallel branches of a union, so that each distinct read reaches the datastore once. The results of the
+// reads are held in memory until the Check is resolved. See storagewrappers.DedupingTupleReader.
+func WithCheckReadDeduplicationEnabled(enabled bool) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.checkReadDeduplicationEnabled = enabled
+	}
+}
 
-	cycleDetectionCheckResolver := graph.NewCycleDetectionCheckResolver()
-	s.checkResolver = cycleDetectionCheckResolver
+// WithCheckUsersetReadBatching coalesces the userset reads made within a short window while resolving a single
+// Check, e.g. for the many groups of a wide group expansion, into one query per relation, if the datastore
+// supports batched userset reads (storage.BatchUsersetTupleReader). With WithCheckTupleSnapshots, the batched
+// reads are served by the snapshots, if they support them too. With WithCheckReadHedging, the batched reads
+// are not hedged. See storagewrappers.BatchingUsersetTupleReader.
+func WithCheckUsersetReadBatching(opts ...storagewrappers.UsersetBatchingOption) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.checkUsersetReadBatchingEnabled = true
+		s.checkUsersetReadBatchingOpts = opts
+	}
+}
 
-	localChecker := graph.NewLocalChecker(
-		graph.WithResolveNodeBreadthLimit(s.resolveNodeBreadthLimit),
-	)
+// CheckReadPatternSink receives the sequence of datastore reads made while resolving a Check, for offline
+// analysis of the access patterns of a model, e.g. to choose which indexes a datastore needs.
+// RecordCheckReads is called synchronously once the Check is resolved, so it should return quickly.
+type CheckReadPatternSink interface {
+	RecordCheckReads(ctx context.Context, storeID string, tupleKey *openfgav1.CheckRequestTupleKey, reads []storagewrappers.ReadCall)
+}
 
-	cycleDetectionCheckResolver.SetDelegate(localChecker)
-	localChecker.SetDelegate(cycleDetectionCheckResolver)
+// WithCheckReadPatternSink records the datastore reads made by each Check, and hands them to the sink.
+// Reads of contextual tuples are not recorded. It is disabled by default (nil).
+func WithCheckReadPatternSink(sink CheckReadPatternSink) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.checkReadPatternSink = sink
+	}
+}
 
-	if s.checkQueryCacheEnabled {
-		s.logger.Info("Check query cache is enabled and may lead to stale query results up to the configured query cache TTL",
-			zap.Duration("CheckQueryCacheTTL", s.checkQueryCacheTTL),
-			zap.Uint32("CheckQueryCacheLimit", s.checkQueryCacheLimit))
+// WithHighPrivilegeRelations sets the relations that AnalyzeEscalation reports, as 'objectType#relation'
+// strings (e.g. 'organization#admin'). If none are set, every relation of the model is reported.
+func WithHighPrivilegeRelations(relations ...string) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.highPrivilegeRelations = relations
+	}
+}
 
-		cachedCheckResolver := graph.NewCachedCheckResolver(
-			graph.WithMaxCacheSize(int64(s.checkQueryCacheLimit)),
-			graph.WithLogger(s.logger),
+// WithWriteDisallowedIDCharacters sets the characters that the object and user IDs of written tuples may not contain.
+// An empty string disables the validation, e.g. for stores holding legacy data.
+func WithWriteDisallowedIDCharacters(chars string) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.writeDisallowedIDCharacters = chars
+	}
+}
+
+// WithNamedCheckResolverChain registers an alternate Check resolver chain under the name, which Checks can select
+// with WithCheckResolverChain, e.g. to compare a chain with a Check cache to one without. The chain must be fully
+// wired, with its last resolver delegating back to its first one. The server closes it on Close.
+func WithNamedCheckResolverChain(name string, resolver graph.CheckResolver) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		if s.namedCheckResolvers == nil {
+			s.namedCheckResolvers = map[string]graph.CheckResolver{}
+		}
+		s.namedCheckResolvers[name] = resolver
+	}
+}
+
+// WithCheckResolverOrder sets the order of the layers of the Check resolver chain, by name, among the built-in
+// layers (see graph.DefaultCheckResolverOrder) and the ones registered with WithCheckResolverMiddleware. The layers
+// that are not named are left out of the chain, e.g. to disable the cycle detection, and so are the Check cache and
+// dispatch throttling if they are not enabled. The LocalChecker is always the last layer. It defaults to
+// graph.DefaultCheckResolverOrder followed by the registered middlewares.
+func WithCheckResolverOrder(layers ...string) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.checkResolverBuilderOpts = append(s.checkResolverBuilderOpts, graph.WithCheckResolverOrder(layers...))
+	}
+}
+
+// WithCheckResolverMiddleware registers a custom layer of the Check resolver chain under the name, which can be
+// placed with WithCheckResolverOrder. The server sets its delegate and closes it on Close.
+func WithCheckResolverMiddleware(name string, middleware graph.CheckResolverMiddleware) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.checkResolverBuilderOpts = append(s.checkResolverBuilderOpts, graph.WithCheckResolverMiddleware(name, middleware))
+	}
+}
+
+// WithMaxIDLength sets the maximum lengths, in bytes, of the object and user IDs of written tuples. Above
+// them, Write fails with a validation error. A maximum length of 0 disables the validation of the corresponding
+// ID. Both default to 512 bytes. See also WithCheckIDLengthEnforcement.
+func WithMaxIDLength(maxObjectIDLength, maxUserIDLength int) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.maxObjectIDLength = maxObjectIDLength
+		s.maxUserIDLength = maxUserIDLength
+	}
+}
+
+// WithCheckIDLengthEnforcement makes Check also fail with a validation error if the object or user ID of the
+// request is longer than the maximum lengths set with WithMaxIDLength. By default (false), Check does not
+// validate them: a Check about an ID too long to be written is denied, unless granted through contextual tuples.
+func WithCheckIDLengthEnforcement(enabled bool) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.checkIDLengthEnforcement = enabled
+	}
+}
+
+// WithCheckSubjectTypeValidation makes Check fail with a validation error naming the expected types if the user
+// of the request is of a type that can never have the relation, e.g. 'widget:1' for a relation only granted to
+// users and groups. See [typesystem.TypeSystem.ValidateSubjectType]. By default (false), such Checks are denied.
+func WithCheckSubjectTypeValidation(enabled bool) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.checkSubjectTypeValidation = enabled
+	}
+}
+
+// WithWritePermissiveUsersetReferences makes Write accept tuples whose userset user (e.g. 'group:eng#member')
+// references a relation that is not defined, or is not an allowed type restriction of the relation of the tuple.
+// This is meant for migrations writing tuples ahead of the model allowing them. By default (false) both are enforced.
+func WithWritePermissiveUsersetReferences(permissive bool) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.writePermissiveUsersetReferences = permissive
+	}
+}
+
+// WithOutcomeLogging enables structured logging of the outcome of completed Check requests.
+// sampleRate is the fraction of requests (between 0 and 1) whose outcome is logged, e.g.
+// 0.01 logs roughly one in every hundred Checks. A sampleRate of 0 disables outcome logging.
+func WithOutcomeLogging(sampleRate float64) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.checkOutcomeLogSampleRate = sampleRate
+	}
+}
+
+// WithRequestDurationByQueryHistogramBuckets sets the buckets used in labelling the requestDurationByQueryAndDispatchHistogram.
+func WithRequestDurationByQueryHistogramBuckets(buckets []uint) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		sort.Slice(buckets, func(i, j int) bool { return buckets[i] < buckets[j] })
+		s.requestDurationByQueryHistogramBuckets = buckets
+	}
+}
+
+// WithRequestDurationByDispatchCountHistogramBuckets sets the buckets used in labelling the requestDurationByQueryAndDispatchHistogram.
+func WithRequestDurationByDispatchCountHistogramBuckets(buckets []uint) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		sort.Slice(buckets, func(i, j int) bool { return buckets[i] < buckets[j] })
+		s.requestDurationByDispatchCountHistogramBuckets = buckets
+	}
+}
+
+func WithMaxAuthorizationModelSizeInBytes(size int) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.maxAuthorizationModelSizeInBytes = size
+	}
+}
+
+// WithMaxTypesPerAuthorizationModel sets the maximum number of type definitions of a model written with
+// WriteAuthorizationModel, in place of the limit of the datastore. Above it, the write fails with an
+// exceeded_entity_limit error. A value of 0 (the default) keeps the limit of the datastore.
+func WithMaxTypesPerAuthorizationModel(maxTypes int) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.maxTypesPerAuthorizationModel = maxTypes
+	}
+}
+
+// WithStoreConditionFunctions makes the conditions of the models of each store compile in a CEL environment
+// of their own, extended with the options returned for the store, e.g. the declarations of the custom functions
+// of the tenant owning the store. The functions of a store are not available to the conditions of other stores.
+// The environment of a store is constructed once, on the first model of the store resolved or written.
+func WithStoreConditionFunctions(functions func(storeID string) []cel.EnvOption) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.conditionEnvs = condition.NewStoreEnvs(functions)
+	}
+}
+
+// WithDispatchThrottlingCheckResolverEnabled sets whether dispatch throttling is enabled for Check requests.
+// Enabling this feature will prioritize dispatched requests requiring less than the configured dispatch
+// threshold over requests whose dispatch count exceeds the configured threshold.
+func WithDispatchThrottlingCheckResolverEnabled(enabled bool) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.checkDispatchThrottlingEnabled = enabled
+	}
+}
+
+// WithDispatchThrottlingCheckResolverFrequency defines how frequent dispatch throttling
+// will be evaluated for Check requests.
+// Frequency controls how frequently throttled dispatch requests are evaluated to determine whether
+// it can be processed.
+// This value should not be too small (i.e., in the ns ranges) as i) there are limitation in timer resolution
+// and ii) very small value will result in a higher frequency of processing dispatches,
+// which diminishes the value of the throttling.
+func WithDispatchThrottlingCheckResolverFrequency(frequency time.Duration) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.checkDispatchThrottlingFrequency = frequency
+	}
+}
+
+// WithDispatchThrottlingCheckResolverThreshold define the number of dispatches to be throttled.
+// In addition, it will update checkDispatchThrottlingMaxThreshold if required.
+func WithDispatchThrottlingCheckResolverThreshold(defaultThreshold uint32) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.checkDispatchThrottlingDefaultThreshold = defaultThreshold
+	}
+}
+
+// WithDispatchThrottlingCheckResolverMaxThreshold define the maximum threshold values allowed
+// It will ensure checkDispatchThrottlingMaxThreshold will never be smaller than threshold.
+func WithDispatchThrottlingCheckResolverMaxThreshold(maxThreshold uint32) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.checkDispatchThrottlingMaxThreshold = maxThreshold
+	}
+}
+
+// MustNewServerWithOpts see NewServerWithOpts.
+func MustNewServerWithOpts(opts ...OpenFGAServiceV1Option) *Server {
+	s, err := NewServerWithOpts(opts...)
+	if err != nil {
+		panic(fmt.Errorf("failed to construct the OpenFGA server: %w", err))
+	}
+
+	return s
+}
+
+func (s *Server) IsExperimentallyEnabled(flag ExperimentalFeatureFlag) bool {
+	return slices.Contains(s.experimentals, flag)
+}
+
+// WithListObjectsDispatchThrottlingEnabled sets whether dispatch throttling is enabled for List Objects requests.
+// Enabling this feature will prioritize dispatched requests requiring less than the configured dispatch
+// threshold over requests whose dispatch count exceeds the configured threshold.
+func WithListObjectsDispatchThrottlingEnabled(enabled bool) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.listObjectsDispatchThrottlingEnabled = enabled
+	}
+}
+
+// WithListObjectsDispatchThrottlingFrequency defines how frequent dispatch throttling
+// will be evaluated for List Objects requests.
+// Frequency controls how frequently throttled dispatch requests are evaluated to determine whether
+// it can be processed.
+// This value should not be too small (i.e., in the ns ranges) as i) there are limitation in timer resolution
+// and ii) very small value will result in a higher frequency of processing dispatches,
+// which diminishes the value of the throttling.
+func WithListObjectsDispatchThrottlingFrequency(frequency time.Duration) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.listObjectsDispatchThrottlingFrequency = frequency
+	}
+}
+
+// WithListObjectsDispatchThrottlingThreshold define the number of dispatches to be throttled
+// for List Objects requests.
+func WithListObjectsDispatchThrottlingThreshold(threshold uint32) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.listObjectsDispatchDefaultThreshold = threshold
+	}
+}
+
+// WithListObjectsDispatchThrottlingMaxThreshold define the maximum threshold values allowed
+// It will ensure listObjectsDispatchThrottlingMaxThreshold will never be smaller than threshold.
+func WithListObjectsDispatchThrottlingMaxThreshold(maxThreshold uint32) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.listObjectsDispatchThrottlingMaxThreshold = maxThreshold
+	}
+}
+
+// NewServerWithOpts returns a new server.
+// You must call Close on it after you are done using it.
+func NewServerWithOpts(opts ...OpenFGAServiceV1Option) (*Server, error) {
+	s := &Server{
+		logger:                           logger.NewNoopLogger(),
+		encoder:                          encoder.NewBase64Encoder(),
+		transport:                        gateway.NewNoopTransport(),
+		changelogHorizonOffset:           serverconfig.DefaultChangelogHorizonOffset,
+		watchPollInterval:                serverconfig.DefaultWatchPollInterval,
+		tupleExpiryReaperInterval:        serverconfig.DefaultTupleExpiryReaperInterval,
+		shutdownDrainTimeout:             serverconfig.DefaultShutdownDrainTimeout,
+		drained:                          make(chan struct{}),
+		resolveNodeLimit:                 serverconfig.DefaultResolveNodeLimit,
+		resolveNodeBreadthLimit:          serverconfig.DefaultResolveNodeBreadthLimit,
+		listObjectsDeadline:              serverconfig.DefaultListObjectsDeadline,
+		listObjectsMaxResults:            serverconfig.DefaultListObjectsMaxResults,
+		listUsersDeadline:                serverconfig.DefaultListUsersDeadline,
+		listUsersMaxResults:              serverconfig.DefaultListUsersMaxResults,
+		maxConcurrentReadsForCheck:       serverconfig.DefaultMaxConcurrentReadsForCheck,
+		maxConcurrentReadsForListObjects: serverconfig.DefaultMaxConcurrentReadsForListObjects,
+		maxConcurrentReadsForListUsers:   serverconfig.DefaultMaxConcurrentReadsForListUsers,
+		maxAuthorizationModelSizeInBytes: serverconfig.DefaultMaxAuthorizationModelSizeInBytes,
+		maxAuthorizationModelCacheSize:   serverconfig.DefaultMaxAuthorizationModelCacheSize,
+		sharedTypesystemCacheSize:        serverconfig.DefaultSharedTypesystemCacheSize,
+		experimentals:                    make([]ExperimentalFeatureFlag, 0, 10),
+
+		checkQueryCacheEnabled: serverconfig.DefaultCheckQueryCacheEnable,
+		checkQueryCacheLimit:   serverconfig.DefaultCheckQueryCacheLimit,
+		checkQueryCacheTTL:     serverconfig.DefaultCheckQueryCacheTTL,
+		checkResolver:          nil,
+
+		listObjectsReadCacheEnabled: serverconfig.DefaultListObjectsReadCacheEnable,
+		listObjectsReadCacheLimit:   serverconfig.DefaultListObjectsReadCacheLimit,
+		listObjectsReadCacheTTL:     serverconfig.DefaultListObjectsReadCacheTTL,
+
+		requestDurationByQueryHistogramBuckets:         []uint{50, 200},
+		requestDurationByDispatchCountHistogramBuckets: []uint{50, 200},
+		serviceName: openfgav1.OpenFGAService_ServiceDesc.ServiceName,
+
+		checkDispatchThrottlingEnabled:          serverconfig.DefaultCheckDispatchThrottlingEnabled,
+		checkDispatchThrottlingFrequency:        serverconfig.DefaultCheckDispatchThrottlingFrequency,
+		checkDispatchThrottlingDefaultThreshold: serverconfig.DefaultCheckDispatchThrottlingDefaultThreshold,
+
+		listObjectsDispatchThrottlingEnabled:      serverconfig.DefaultListObjectsDispatchThrottlingEnabled,
+		listObjectsDispatchThrottlingFrequency:    serverconfig.DefaultListObjectsDispatchThrottlingFrequency,
+		listObjectsDispatchDefaultThreshold:       serverconfig.DefaultListObjectsDispatchThrottlingDefaultThreshold,
+		listObjectsDispatchThrottlingMaxThreshold: serverconfig.DefaultListObjectsDispatchThrottlingMaxThreshold,
+
+		writeDisallowedIDCharacters: serverconfig.DefaultWriteDisallowedIDCharacters,
+		maxObjectIDLength:           serverconfig.DefaultMaxObjectIDLength,
+		maxUserIDLength:             serverconfig.DefaultMaxUserIDLength,
+
+		maxConcurrentChecksPerBatchCheck: serverconfig.DefaultMaxConcurrentChecksPerBatchCheck,
+
+		contextualTuplesConflictPolicy: storagewrappers.ContextualTuplesWin,
+
+		storeFeatureFlagsCacheTTL: serverconfig.DefaultStoreFeatureFlagsCacheTTL,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.datastore == nil {
+		return nil, fmt.Errorf("a datastore option must be provided")
+	}
+
+	if len(s.requestDurationByQueryHistogramBuckets) == 0 {
+		return nil, fmt.Errorf("request duration datastore count buckets must not be empty")
+	}
+
+	if len(s.requestDurationByDispatchCountHistogramBuckets) == 0 {
+		return nil, fmt.Errorf("request duration by dispatch count buckets must not be empty")
+	}
+	if s.checkDispatchThrottlingEnabled && s.checkDispatchThrottlingMaxThreshold != 0 && s.checkDispatchThrottlingDefaultThreshold > s.checkDispatchThrottlingMaxThreshold {
+		return nil, fmt.Errorf("check default dispatch throttling threshold must be equal or smaller than max dispatch threshold for Check")
+	}
+
+	if s.listObjectsDispatchThrottlingMaxThreshold != 0 && s.listObjectsDispatchDefaultThreshold > s.listObjectsDispatchThrottlingMaxThreshold {
+		return nil, fmt.Errorf("ListObjects default dispatch throttling threshold must be equal or smaller than max dispatch threshold for ListObjects")
+	}
+
+	if s.checkOutcomeLogSampleRate < 0 || s.checkOutcomeLogSampleRate > 1 {
+		return nil, fmt.Errorf("check outcome log sample rate must be between 0 and 1")
+	}
+
+	if s.maxConcurrentChecksPerBatchCheck == 0 {
+		return nil, fmt.Errorf("max concurrent checks per BatchCheck must be greater than 0")
+	}
+
+	if s.checkTupleSnapshotsEnabled {
+		snapshotter, ok := s.datastore.(storage.TupleSnapshotter)
+		if !ok {
+			return nil, fmt.Errorf("check tuple snapshots require a datastore that can take snapshots of the tuples")
+		}
+		if s.checkReadReplica != nil {
+			return nil, fmt.Errorf("check tuple snapshots cannot be combined with check read hedging, whose replica does not read from the snapshots")
+		}
+		s.tupleSnapshotter = snapshotter
+	}
+
+	if _, ok := s.datastore.(storage.TupleExpirer); !ok && s.tupleExpiryReaperInterval > 0 {
+		return nil, fmt.Errorf("the tuple expiry reaper requires a datastore that supports expiring tuples")
+	}
+
+	if s.maxConcurrentChecks > 0 {
+		s.checkSlots = make(chan struct{}, s.maxConcurrentChecks)
+		s.checkQueue = make(chan struct{}, s.checkQueueSize)
+	}
+
+	checkResolverOrder := graph.NewOrderedCheckResolvers(s.checkResolverBuilderOpts...)
+	if err := checkResolverOrder.Validate(); err != nil {
+		return nil, err
+	}
+
+	// below this point, don't throw errors or we may leak resources in tests
+
+	checkResolverBuilderOpts := []graph.CheckResolverOrderedBuilderOpt{
+		graph.WithCycleDetectionCheckResolverOpts(
+			graph.WithMaxVisitedPaths(s.maxVisitedPathsForCheck),
+			graph.WithVisitedPathsLimitAsCycle(s.visitedPathsLimitAsCycleForCheck),
+		),
+		graph.WithLocalCheckerOpts(
+			graph.WithResolveNodeBreadthLimit(s.resolveNodeBreadthLimit),
+			graph.WithUnionConcurrencyLimit(s.resolveNodeUnionBreadthLimit),
+			graph.WithTTUConcurrencyLimit(s.resolveNodeTTUBreadthLimit),
+			graph.WithMaxNodeFanout(s.maxNodeFanoutForCheck),
+			graph.WithDispatchWorkerPool(s.checkDispatchWorkerPoolSize),
+			graph.WithMaxConditionEvaluations(s.maxConditionEvaluationsForCheck),
+			graph.WithConditionEvaluationCache(s.checkConditionEvaluationCache),
+			graph.WithUndefinedComputedRelationError(s.checkUndefinedComputedRelationError),
+		),
+	}
+
+	// the layers left out of the order are not constructed
+	if s.checkQueryCacheEnabled && slices.Contains(checkResolverOrder.Order(), graph.CachedCheckResolverLayer) {
+		s.logger.Info("Check query cache is enabled and may lead to stale query results up to the configured query cache TTL",
+			zap.Duration("CheckQueryCacheTTL", s.checkQueryCacheTTL),
+			zap.Uint32("CheckQueryCacheLimit", s.checkQueryCacheLimit))
+
+		checkResolverBuilderOpts = append(checkResolverBuilderOpts, graph.WithCachedCheckResolverOpts(true,
+			graph.WithMaxCacheSize(int64(s.checkQueryCacheLimit)),
+			graph.WithLogger(s.logger),
 			graph.WithCacheTTL(s.checkQueryCacheTTL),
+		))
+	}
+
+	if s.checkDispatchThrottlingEnabled && slices.Contains(checkResolverOrder.Order(), graph.DispatchThrottlingCheckResolverLayer) {
+		s.logger.Info("Enabling Check dispatch throttling",
+			zap.Duration("Frequency", s.checkDispatchThrottlingFrequency),
+			zap.Uint32("DefaultThreshold", s.checkDispatchThrottlingDefaultThreshold),
+			zap.Uint32("MaxThreshold", s.checkDispatchThrottlingMaxThreshold),
+		)
+
+		dispatchThrottlingConfig := graph.DispatchThrottlingCheckResolverConfig{
+			DefaultThreshold: s.checkDispatchThrottlingDefaultThreshold,
+			MaxThreshold:     s.checkDispatchThrottlingMaxThreshold,
+		}
+
+		checkResolverBuilderOpts = append(checkResolverBuilderOpts, graph.WithDispatchThrottlingCheckResolverOpts(true,
+			graph.WithDispatchThrottlingCheckResolverConfig(dispatchThrottlingConfig),
+			graph.WithThrottler(throttler.NewConstantRateThrottler(s.checkDispatchThrottlingFrequency, "check_dispatch_throttle")),
+		))
+	}
+
+	// the order and the middlewares, validated above, apply last
+	checkResolverBuilder := graph.NewOrderedCheckResolvers(append(checkResolverBuilderOpts, s.checkResolverBuilderOpts...)...)
+	s.checkResolver, s.checkResolverCloser, _ = checkResolverBuilder.Build()
+	s.cachedCheckResolver = checkResolverBuilder.CachedCheckResolver()
+	s.dispatchThrottlingCheckResolver = checkResolverBuilder.DispatchThrottlingCheckResolver()
+
+	if len(s.shadowModels) > 0 {
+		// the Checks against the candidate models are resolved by the same chain
+		s.checkShadowResolver = graph.NewShadowCheckResolver(s.checkResolver, s.checkResolver,
+			graph.WithShadowCheckLogger(s.logger),
+			graph.WithShadowCheckTypesystem(s.shadowTypesystem),
 		)
-		s.cachedCheckResolver = cachedCheckResolver
+	}
+
+	if s.listObjectsDispatchThrottlingEnabled {
+		s.logger.Info("Enabling ListObjects dispatch throttling",
+			zap.Duration("Frequency", s.listObjectsDispatchThrottlingFrequency),
+			zap.Uint32("DefaultThreshold", s.listObjectsDispatchDefaultThreshold),
+			zap.Uint32("MaxThreshold", s.listObjectsDispatchThrottlingMaxThreshold),
+		)
+
+		s.listObjectsDispatchThrottler = throttler.NewConstantRateThrottler(s.listObjectsDispatchThrottlingFrequency, "list_objects_dispatch_throttle")
+	}
+
+	if reporter, ok := s.datastore.(storage.FreshnessReporter); ok {
+		s.freshnessReporter = reporter
+	}
+
+	if backend, ok := s.datastore.(storage.StoreFeatureFlagsBackend); ok {
+		s.storeFeatureFlagsBackend = backend
+		s.storeFeatureFlagsCache = ccache.New(ccache.Configure[map[StoreFeatureFlag]bool]())
+	}
+
+	if backend, ok := s.datastore.(storage.StoreDefaultModelBackend); ok {
+		s.storeDefaultModelBackend = backend
+		s.storeDefaultModelCache = ccache.New(ccache.Configure[string]())
+	}
+
+	if backend, ok := s.datastore.(storage.ContextualAssertionsBackend); ok {
+		s.contextualAssertionsBackend = backend
+	}
+
+	if s.listObjectsEmptyResultCacheTTL > 0 {
+		s.listObjectsEmptyResultCache = ccache.New(ccache.Configure[struct{}]())
+	}
+
+	if backend, ok := s.datastore.(storage.AuthorizationModelArchiveBackend); ok {
+		s.modelArchiveBackend = backend
+	}
+
+	if counter, ok := s.datastore.(storage.ChangeCounter); ok {
+		s.changeCounter = counter
+	}
+
+	if counter, ok := s.datastore.(storage.TupleCounter); ok && s.IsExperimentallyEnabled(ExperimentalListObjectsIntersectionPlanning) {
+		s.tupleCounter = counter
+	}
+
+	if reader, ok := s.datastore.(storage.StoreStatsReader); ok {
+		s.storeStatsReader = reader
+	}
+
+	if reader, ok := s.datastore.(storage.StoreTimeRangeReader); ok {
+		s.storeTimeRangeReader = reader
+	}
+
+	if reader, ok := s.datastore.(storage.ActorTupleReader); ok {
+		s.actorTupleReader = reader
+	}
+
+	if reader, ok := s.datastore.(storage.ChangelogFilterReader); ok {
+		s.changelogFilterReader = reader
+	}
 
-		cachedCheckResolver.SetDelegate(localChecker)
-		cycleDetectionCheckResolver.SetDelegate(cachedCheckResolver)
+	if writer, ok := s.datastore.(storage.ConditionalTupleWriter); ok {
+		s.conditionalTupleWriter = writer
 	}
 
-	if s.checkDispatchThrottlingEnabled {
-		s.logger.Info("Enabling Check dispatch throttling",
-			zap.Duration("Frequency", s.checkDispatchThrottlingFrequency),
-			zap.Uint32("DefaultThreshold", s.checkDispatchThrottlingDefaultThreshold),
-			zap.Uint32("MaxThreshold", s.checkDispatchThrottlingMaxThreshold),
-		)
+	if expirer, ok := s.datastore.(storage.TupleExpirer); ok {
+		s.tupleExpirer = expirer
+	}
 
-		dispatchThrottlingConfig := graph.DispatchThrottlingCheckResolverConfig{
-			DefaultThreshold: s.checkDispatchThrottlingDefaultThreshold,
-			MaxThreshold:     s.checkDispatchThrottlingMaxThreshold,
-		}
+	if batcher, ok := s.datastore.(storage.BatchUsersetTupleReader); ok && s.checkUsersetReadBatchingEnabled {
+		s.usersetBatchReader = batcher
+	}
 
-		dispatchThrottlingCheckResolver := graph.NewDispatchThrottlingCheckResolver(
-			graph.WithDispatchThrottlingCheckResolverConfig(dispatchThrottlingConfig),
-			graph.WithThrottler(throttler.NewConstantRateThrottler(s.checkDispatchThrottlingFrequency, "check_dispatch_throttle")),
-		)
-		dispatchThrottlingCheckResolver.SetDelegate(localChecker)
-		s.dispatchThrottlingCheckResolver = dispatchThrottlingCheckResolver
+	s.datastore = storagewrappers.NewCachedOpenFGADatastore(
+		storagewrappers.NewTracingDatastore(storagewrappers.NewContextWrapper(s.datastore)),
+		s.maxAuthorizationModelCacheSize,
+	)
 
-		if s.cachedCheckResolver != nil {
-			s.cachedCheckResolver.SetDelegate(dispatchThrottlingCheckResolver)
-		} else {
-			cycleDetectionCheckResolver.SetDelegate(dispatchThrottlingCheckResolver)
-		}
-	}
+	if s.listObjectsReadCacheEnabled {
+		s.logger.Info("List objects read cache is enabled and may lead to stale query results up to the configured cache TTL",
+			zap.Duration("ListObjectsReadCacheTTL", s.listObjectsReadCacheTTL),
+			zap.Uint32("ListObjectsReadCacheLimit", s.listObjectsReadCacheLimit))
 
-	if s.listObjectsDispatchThrottlingEnabled {
-		s.logger.Info("Enabling ListObjects dispatch throttling",
-			zap.Duration("Frequency", s.listObjectsDispatchThrottlingFrequency),
-			zap.Uint32("DefaultThreshold", s.listObjectsDispatchDefaultThreshold),
-			zap.Uint32("MaxThreshold", s.listObjectsDispatchThrottlingMaxThreshold),
+		s.listObjectsReadCache = storagewrappers.NewReadStartingWithUserCache(s.datastore,
+			storagewrappers.WithReadStartingWithUserCacheMaxSize(int64(s.listObjectsReadCacheLimit)),
+			storagewrappers.WithReadStartingWithUserCacheTTL(s.listObjectsReadCacheTTL),
 		)
+	}
 
-		s.listObjectsDispatchThrottler = throttler.NewConstantRateThrottler(s.listObjectsDispatchThrottlingFrequency, "list_objects_dispatch_throttle")
+	if s.checkReadReplica != nil {
+		s.checkReadHedger = storagewrappers.NewHedgingTupleReader(s.datastore, s.checkReadReplica, s.checkReadHedgingOpts...)
+	}
+
+	resolverOpts := []typesystem.ResolverOption{
+		typesystem.WithModelHashCache(int64(s.sharedTypesystemCacheSize)),
+	}
+	if s.conditionEnvs != nil {
+		resolverOpts = append(resolverOpts, typesystem.WithStoreConditionEnv(s.conditionEnvs.Get))
 	}
 
-	s.datastore = storagewrappers.NewCachedOpenFGADatastore(storagewrappers.NewContextWrapper(s.datastore), s.maxAuthorizationModelCacheSize)
+	s.typesystemResolver, s.typesystemResolverStop = typesystem.MemoizedTypesystemResolverFunc(s.datastore, resolverOpts...)
 
-	s.typesystemResolver, s.typesystemResolverStop = typesystem.MemoizedTypesystemResolverFunc(s.datastore)
+	s.abortCtx, s.abortRequests = context.WithCancelCause(context.Background())
+
+	if s.tupleExpirer != nil && s.tupleExpiryReaperInterval > 0 {
+		s.stopTupleExpiryReaper = make(chan struct{})
+		s.tupleExpiryReaperDone = make(chan struct{})
+		go s.reapExpiredTuples(s.tupleExpiryReaperInterval)
+	}
 
 	return s, nil
 }
 
-// Close releases the server resources.
+// Close drains the server, see Drain, then stops its background goroutines and releases its resources.
 func (s *Server) Close() {
-	if s.dispatchThrottlingCheckResolver != nil {
-		s.dispatchThrottlingCheckResolver.Close()
+	s.Drain()
+
+	if s.stopTupleExpiryReaper != nil {
+		close(s.stopTupleExpiryReaper)
+		<-s.tupleExpiryReaperDone
+	}
+
+	if s.checkShadowResolver != nil {
+		s.checkShadowResolver.Close()
 	}
 
 	if s.listObjectsDispatchThrottler != nil {
 		s.listObjectsDispatchThrottler.Close()
 	}
 
-	if s.cachedCheckResolver != nil {
-		s.cachedCheckResolver.Close()
+	if s.checkResolverCloser != nil {
+		s.checkResolverCloser()
+	}
+
+	for _, resolver := range s.namedCheckResolvers {
+		resolver.Close()
+	}
+
+	if s.storeFeatureFlagsCache != nil {
+		s.storeFeatureFlagsCache.Stop()
+	}
+
+	if s.storeDefaultModelCache != nil {
+		s.storeDefaultModelCache.Stop()
 	}
 
-	if s.checkResolver != nil {
-		s.checkResolver.Close()
+	if s.listObjectsEmptyResultCache != nil {
+		s.listObjectsEmptyResultCache.Stop()
+	}
+
+	if s.listObjectsReadCache != nil {
+		s.listObjectsReadCache.Close()
 	}
 	s.datastore.Close()
 	s.typesystemResolverStop()
@@ -602,6 +1480,12 @@ func (s *Server) ListObjects(ctx context.Context, req *openfgav1.ListObjectsRequ
 		}
 	}
 
+	ctx, done, err := s.trackRequest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
 	const methodName = "listobjects"
 
 	ctx = telemetry.ContextWithRPCInfo(ctx, telemetry.RPCInfo{
@@ -610,14 +1494,26 @@ func (s *Server) ListObjects(ctx context.Context, req *openfgav1.ListObjectsRequ
 	})
 
 	storeID := req.GetStoreId()
+	ctx = s.contextWithStoreDispatchThrottlingThreshold(ctx, storeID)
 
 	typesys, err := s.resolveTypesystem(ctx, storeID, req.GetAuthorizationModelId())
 	if err != nil {
 		return nil, err
 	}
 
+	// the result of a request with contextual tuples or context depends on more than the tuples of the store
+	var emptyResultCacheKey string
+	if s.listObjectsEmptyResultCache != nil && len(req.GetContextualTuples().GetTupleKeys()) == 0 && len(req.GetContext().GetFields()) == 0 {
+		emptyResultCacheKey = fmt.Sprintf("%s/%s/%s#%s@%s", storeID, typesys.GetAuthorizationModelID(), targetObjectType, req.GetRelation(), req.GetUser())
+		item := s.listObjectsEmptyResultCache.Get(emptyResultCacheKey)
+		if item != nil && !item.Expired() && storage.ConsistencyFromContext(ctx) != storage.ConsistencyHigher {
+			span.SetAttributes(attribute.Bool("empty_result_cached", true))
+			return &openfgav1.ListObjectsResponse{Objects: []string{}}, nil
+		}
+	}
+
 	q, err := commands.NewListObjectsQuery(
-		s.datastore,
+		s.listObjectsReader(),
 		s.checkResolver,
 		commands.WithLogger(s.logger),
 		commands.WithListObjectsDeadline(s.listObjectsDeadline),
@@ -628,9 +1524,12 @@ func (s *Server) ListObjects(ctx context.Context, req *openfgav1.ListObjectsRequ
 			Threshold:    s.listObjectsDispatchDefaultThreshold,
 			MaxThreshold: s.listObjectsDispatchThrottlingMaxThreshold,
 		}),
-		commands.WithResolveNodeLimit(s.resolveNodeLimit),
+		commands.WithResolveNodeLimit(s.getResolveNodeLimit(ctx, storeID)),
 		commands.WithResolveNodeBreadthLimit(s.resolveNodeBreadthLimit),
+		commands.WithCandidateCheckConcurrencyLimit(s.listObjectsCandidateCheckConcurrencyLimit),
 		commands.WithMaxConcurrentReads(s.maxConcurrentReadsForListObjects),
+		commands.WithTupleCounter(s.tupleCounter),
+		commands.WithDatastoreReadBudget(s.datastoreReadBudget),
 	)
 	if err != nil {
 		return nil, serverErrors.NewInternalError("", err)
@@ -681,6 +1580,10 @@ func (s *Server) ListObjects(ctx context.Context, req *openfgav1.ListObjectsRequ
 		utils.Bucketize(uint(result.ResolutionMetadata.DispatchCounter.Load()), s.requestDurationByDispatchCountHistogramBuckets),
 	).Observe(float64(time.Since(start).Milliseconds()))
 
+	if emptyResultCacheKey != "" && len(result.Objects) == 0 {
+		s.listObjectsEmptyResultCache.Set(emptyResultCacheKey, struct{}{}, s.listObjectsEmptyResultCacheTTL)
+	}
+
 	return &openfgav1.ListObjectsResponse{
 		Objects: result.Objects,
 	}, nil
@@ -703,6 +1606,12 @@ func (s *Server) StreamedListObjects(req *openfgav1.StreamedListObjectsRequest,
 		}
 	}
 
+	ctx, done, err := s.trackRequest(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
 	const methodName = "streamedlistobjects"
 
 	ctx = telemetry.ContextWithRPCInfo(ctx, telemetry.RPCInfo{
@@ -711,6 +1620,7 @@ func (s *Server) StreamedListObjects(req *openfgav1.StreamedListObjectsRequest,
 	})
 
 	storeID := req.GetStoreId()
+	ctx = s.contextWithStoreDispatchThrottlingThreshold(ctx, storeID)
 
 	typesys, err := s.resolveTypesystem(ctx, storeID, req.GetAuthorizationModelId())
 	if err != nil {
@@ -718,7 +1628,7 @@ func (s *Server) StreamedListObjects(req *openfgav1.StreamedListObjectsRequest,
 	}
 
 	q, err := commands.NewListObjectsQuery(
-		s.datastore,
+		s.listObjectsReader(),
 		s.checkResolver,
 		commands.WithLogger(s.logger),
 		commands.WithListObjectsDeadline(s.listObjectsDeadline),
@@ -729,9 +1639,12 @@ func (s *Server) StreamedListObjects(req *openfgav1.StreamedListObjectsRequest,
 			MaxThreshold: s.listObjectsDispatchThrottlingMaxThreshold,
 		}),
 		commands.WithListObjectsMaxResults(s.listObjectsMaxResults),
-		commands.WithResolveNodeLimit(s.resolveNodeLimit),
+		commands.WithResolveNodeLimit(s.getResolveNodeLimit(ctx, storeID)),
 		commands.WithResolveNodeBreadthLimit(s.resolveNodeBreadthLimit),
+		commands.WithCandidateCheckConcurrencyLimit(s.listObjectsCandidateCheckConcurrencyLimit),
 		commands.WithMaxConcurrentReads(s.maxConcurrentReadsForListObjects),
+		commands.WithTupleCounter(s.tupleCounter),
+		commands.WithDatastoreReadBudget(s.datastoreReadBudget),
 	)
 	if err != nil {
 		return serverErrors.NewInternalError("", err)
@@ -748,6 +1661,12 @@ func (s *Server) StreamedListObjects(req *openfgav1.StreamedListObjectsRequest,
 		telemetry.TraceError(span, err)
 		return err
 	}
+
+	// the objects were streamed as they were found, so whether they are all of them is only known at the end
+	complete := !resolutionMetadata.WasIncomplete.Load()
+	span.SetAttributes(attribute.Bool("complete", complete))
+	srv.SetTrailer(metadata.Pairs(ListObjectsCompleteTrailer, strconv.FormatBool(complete)))
+
 	datastoreQueryCount := float64(*resolutionMetadata.DatastoreQueryCount)
 
 	grpc_ctxtags.Extract(ctx).Set(datastoreQueryCountHistogramName, datastoreQueryCount)
@@ -757,31 +1676,399 @@ func (s *Server) StreamedListObjects(req *openfgav1.StreamedListObjectsRequest,
 		methodName,
 	).Observe(datastoreQueryCount)
 
-	dispatchCount := float64(resolutionMetadata.DispatchCounter.Load())
+	dispatchCount := float64(resolutionMetadata.DispatchCounter.Load())
+
+	grpc_ctxtags.Extract(ctx).Set(dispatchCountHistogramName, dispatchCount)
+	span.SetAttributes(attribute.Float64(dispatchCountHistogramName, dispatchCount))
+	dispatchCountHistogram.WithLabelValues(
+		s.serviceName,
+		methodName,
+	).Observe(dispatchCount)
+
+	requestDurationHistogram.WithLabelValues(
+		s.serviceName,
+		methodName,
+		utils.Bucketize(uint(*resolutionMetadata.DatastoreQueryCount), s.requestDurationByQueryHistogramBuckets),
+		utils.Bucketize(uint(resolutionMetadata.DispatchCounter.Load()), s.requestDurationByDispatchCountHistogramBuckets),
+	).Observe(float64(time.Since(start).Milliseconds()))
+
+	return nil
+}
+
+func (s *Server) Read(ctx context.Context, req *openfgav1.ReadRequest) (*openfgav1.ReadResponse, error) {
+	tk := req.GetTupleKey()
+	ctx, span := tracer.Start(ctx, "Read", trace.WithAttributes(
+		attribute.KeyValue{Key: "object", Value: attribute.StringValue(tk.GetObject())},
+		attribute.KeyValue{Key: "relation", Value: attribute.StringValue(tk.GetRelation())},
+		attribute.KeyValue{Key: "user", Value: attribute.StringValue(tk.GetUser())},
+	))
+	defer span.End()
+
+	if !validator.RequestIsValidatedFromContext(ctx) {
+		if err := req.Validate(); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	ctx = telemetry.ContextWithRPCInfo(ctx, telemetry.RPCInfo{
+		Service: s.serviceName,
+		Method:  "Read",
+	})
+
+	q := commands.NewReadQuery(s.datastore,
+		commands.WithReadQueryLogger(s.logger),
+		commands.WithReadQueryEncoder(s.encoder),
+	)
+	return q.Execute(ctx, &openfgav1.ReadRequest{
+		StoreId:           req.GetStoreId(),
+		TupleKey:          tk,
+		PageSize:          req.GetPageSize(),
+		ContinuationToken: req.GetContinuationToken(),
+	})
+}
+
+func (s *Server) Write(ctx context.Context, req *openfgav1.WriteRequest) (*openfgav1.WriteResponse, error) {
+	return s.write(ctx, req, writeOptions{})
+}
+
+func (s *Server) write(ctx context.Context, req *openfgav1.WriteRequest, opts writeOptions) (*openfgav1.WriteResponse, error) {
+	ctx, span := tracer.Start(ctx, "Write")
+	defer span.End()
+
+	if !validator.RequestIsValidatedFromContext(ctx) {
+		if err := req.Validate(); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	ctx = telemetry.ContextWithRPCInfo(ctx, telemetry.RPCInfo{
+		Service: s.serviceName,
+		Method:  "Write",
+	})
+
+	storeID := req.GetStoreId()
+
+	// record the authenticated client as the actor of the writes, unless the caller set one
+	if _, ok := storage.WriteActorFromContext(ctx); !ok {
+		if claims, ok := authn.AuthClaimsFromContext(ctx); ok && claims.Subject != "" {
+			ctx = storage.ContextWithWriteActor(ctx, claims.Subject)
+		}
+	}
+
+	cmdOpts := []commands.WriteCommandOption{
+		commands.WithWriteCmdLogger(s.logger),
+		commands.WithDisallowedIDCharacters(s.writeDisallowedIDCharacters),
+		commands.WithPermissiveUsersetReferences(s.writePermissiveUsersetReferences),
+		commands.WithMaxIDLength(s.maxObjectIDLength, s.maxUserIDLength),
+	}
+	if !opts.expiresAt.IsZero() {
+		if s.tupleExpirer == nil {
+			return nil, serverErrors.TupleExpiryUnsupported
+		}
+
+		if !opts.expiresAt.After(time.Now()) {
+			return nil, status.Error(codes.InvalidArgument, "the tuples must expire in the future")
+		}
+
+		ctx = storage.ContextWithTupleExpiry(ctx, opts.expiresAt)
+	}
+
+	if opts.preconditions != nil {
+		if s.conditionalTupleWriter == nil {
+			return nil, serverErrors.ConditionalWriteUnsupported
+		}
+
+		if err := validateWritePreconditions(opts.preconditions); err != nil {
+			return nil, err
+		}
+
+		cmdOpts = append(cmdOpts, commands.WithWritePreconditions(s.conditionalTupleWriter, *opts.preconditions))
+	}
+
+	typesys, err := s.resolveTypesystem(ctx, storeID, req.GetAuthorizationModelId())
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := commands.NewWriteCommand(s.datastore, cmdOpts...)
+	resp, err := cmd.Execute(ctx, &openfgav1.WriteRequest{
+		StoreId:              storeID,
+		AuthorizationModelId: typesys.GetAuthorizationModelID(), // the resolved model id
+		Writes:               req.GetWrites(),
+		Deletes:              req.GetDeletes(),
+	})
+	s.audit(ctx, &audit.Event{
+		Type:                 audit.EventWrite,
+		StoreID:              storeID,
+		AuthorizationModelID: typesys.GetAuthorizationModelID(),
+		Writes:               tupleStrings(req.GetWrites().GetTupleKeys()),
+		Deletes:              tupleStrings(req.GetDeletes().GetTupleKeys()),
+	}, err)
+	if err != nil {
+		return nil, err
+	}
+
+	// any tuple of the store may grant or revoke a relation through a rewrite, so every cached
+	// empty result of the store may now be stale
+	if s.listObjectsEmptyResultCache != nil {
+		s.listObjectsEmptyResultCache.DeletePrefix(storeID + "/")
+	}
+
+	// likewise for the cached Check results of the store
+	if s.cachedCheckResolver != nil {
+		s.cachedCheckResolver.InvalidateStore(storeID)
+	}
+
+	// and for the tuples cached for the reverse expansion of ListObjects
+	if s.listObjectsReadCache != nil {
+		s.listObjectsReadCache.InvalidateStore(storeID)
+	}
+
+	return resp, nil
+}
+
+// listObjectsReader returns the reader of the tuples of the ListObjects queries, which caches their reads if
+// WithListObjectsReadCacheEnabled is set.
+func (s *Server) listObjectsReader() storage.RelationshipTupleReader {
+	if s.listObjectsReadCache != nil {
+		return s.listObjectsReadCache
+	}
+
+	return s.datastore
+}
+
+// getResolveNodeLimit returns the resolve node limit to use for a request to the given store.
+// A limit carried by the context takes precedence over the store default, which in turn takes
+// precedence over the server-wide limit. The limit of the context is clamped to the server-wide
+// limit, so that a client can only lower it.
+func (s *Server) getResolveNodeLimit(ctx context.Context, storeID string) uint32 {
+	if limit, ok := ctx.Value(resolveNodeLimitCtxKey).(uint32); ok {
+		return min(limit, s.resolveNodeLimit)
+	}
+
+	if limit, ok := s.storeResolveNodeLimits[storeID]; ok {
+		return limit
+	}
+
+	return s.resolveNodeLimit
+}
+
+// contextWithStoreDispatchThrottlingThreshold returns a context holding the dispatch throttling threshold of the
+// store, if any, unless the context already holds a lower threshold.
+func (s *Server) contextWithStoreDispatchThrottlingThreshold(ctx context.Context, storeID string) context.Context {
+	threshold, ok := s.storeDispatchThrottlingThresholds[storeID]
+	if !ok {
+		return ctx
+	}
+
+	if ctxThreshold := dispatch.ThrottlingThresholdFromContext(ctx); ctxThreshold > 0 && ctxThreshold <= threshold {
+		return ctx
+	}
+
+	return dispatch.ContextWithThrottlingThreshold(ctx, threshold)
+}
+
+// resolveRelationAlias returns the relation to resolve in place of the relation of the tuple key
+// according to the relation aliases of the request and of the store. If the relation is not aliased,
+// it is returned unchanged.
+func (s *Server) resolveRelationAlias(ctx context.Context, storeID string, tk *openfgav1.CheckRequestTupleKey) string {
+	aliasKey := tuple.ToObjectRelationString(tuple.GetType(tk.GetObject()), tk.GetRelation())
+
+	if aliases, ok := ctx.Value(relationAliasesCtxKey).(map[string]string); ok {
+		if relation, ok := aliases[aliasKey]; ok {
+			return relation
+		}
+	}
+
+	if relation, ok := s.storeRelationAliases[storeID][aliasKey]; ok {
+		return relation
+	}
+
+	return tk.GetRelation()
+}
+
+// MultiStoreWrite writes tuples to several stores in one call. The tuples of every store are
+// written in their own transaction against the latest authorization model of that store, so a
+// failure to write to one store does not affect the writes to the others. The returned map has
+// an entry for every store in writes, holding the error of its write or nil on success.
+func (s *Server) MultiStoreWrite(ctx context.Context, writes map[string][]*openfgav1.TupleKey) map[string]error {
+	ctx, span := tracer.Start(ctx, "MultiStoreWrite")
+	defer span.End()
+
+	results := make(map[string]error, len(writes))
+	for storeID, tupleKeys := range writes {
+		_, err := s.Write(ctx, &openfgav1.WriteRequest{
+			StoreId: storeID,
+			Writes: &openfgav1.WriteRequestWrites{
+				TupleKeys: tupleKeys,
+			},
+		})
+		results[storeID] = err
+	}
+
+	return results
+}
+
+// GetRelationRewrite returns the normalized rewrite of a relation of the authorization model with the given ID,
+// or of the latest authorization model of the store if modelID is empty. See [typesystem.TypeSystem.GetRelationRewrite].
+func (s *Server) GetRelationRewrite(ctx context.Context, storeID, modelID, objectType, relation string) (*typesystem.RewriteExpression, error) {
+	ctx, span := tracer.Start(ctx, "GetRelationRewrite", trace.WithAttributes(
+		attribute.String("store_id", storeID),
+		attribute.String("object_type", objectType),
+		attribute.String("relation", relation),
+	))
+	defer span.End()
+
+	typesys, err := s.resolveTypesystem(ctx, storeID, modelID)
+	if err != nil {
+		return nil, err
+	}
+
+	rewrite, err := typesys.GetRelationRewrite(objectType, relation)
+	if err != nil {
+		if errors.Is(err, typesystem.ErrObjectTypeUndefined) {
+			return nil, serverErrors.TypeNotFound(objectType)
+		}
+
+		if errors.Is(err, typesystem.ErrRelationUndefined) {
+			return nil, serverErrors.RelationNotFound(relation, objectType, nil)
+		}
+
+		return nil, serverErrors.HandleError("", err)
+	}
+
+	return rewrite, nil
+}
+
+// ListExcludedUsers returns the users that the 'but not' branch of the relation excludes from the object although
+// they would otherwise be granted the relation through a public wildcard. See [graph.ListExcludedUsers].
+func (s *Server) ListExcludedUsers(ctx context.Context, storeID, modelID, object, relation string) ([]string, error) {
+	ctx, span := tracer.Start(ctx, "ListExcludedUsers", trace.WithAttributes(
+		attribute.String("store_id", storeID),
+		attribute.String("object", object),
+		attribute.String("relation", relation),
+	))
+	defer span.End()
+
+	typesys, err := s.resolveTypesystem(ctx, storeID, modelID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = typesystem.ContextWithTypesystem(ctx, typesys)
+	ctx = storage.ContextWithRelationshipTupleReader(ctx, s.datastore)
+
+	users, err := graph.ListExcludedUsers(ctx, &graph.ResolveCheckRequest{
+		StoreID:              storeID,
+		AuthorizationModelID: typesys.GetAuthorizationModelID(),
+		TupleKey:             tuple.NewTupleKey(object, relation, ""),
+		RequestMetadata:      graph.NewCheckRequestMetadata(s.getResolveNodeLimit(ctx, storeID)),
+	})
+	if err != nil {
+		telemetry.TraceError(span, err)
+		switch {
+		case errors.Is(err, typesystem.ErrObjectTypeUndefined):
+			return nil, serverErrors.TypeNotFound(tuple.GetType(object))
+		case errors.Is(err, typesystem.ErrRelationUndefined):
+			return nil, serverErrors.RelationNotFound(relation, tuple.GetType(object), nil)
+		case errors.Is(err, graph.ErrRelationNotExclusion), errors.Is(err, condition.ErrEvaluationFailed):
+			return nil, serverErrors.ValidationError(err)
+		case errors.Is(err, graph.ErrResolutionDepthExceeded):
+			return nil, serverErrors.AuthorizationModelResolutionTooComplex
+		}
+
+		return nil, serverErrors.HandleError("", err)
+	}
+
+	return users, nil
+}
+
+// UsersetClosureSize returns the number of distinct users having the relation on the object once every
+// nested userset is expanded, up to limit. The returned bool is true if the relation has more than limit
+// users, in which case the returned count is limit. See [graph.UsersetClosureSize].
+func (s *Server) UsersetClosureSize(ctx context.Context, storeID, modelID, object, relation string, limit uint32) (uint32, bool, error) {
+	ctx, span := tracer.Start(ctx, "UsersetClosureSize", trace.WithAttributes(
+		attribute.String("store_id", storeID),
+		attribute.String("object", object),
+		attribute.String("relation", relation),
+	))
+	defer span.End()
+
+	typesys, err := s.resolveTypesystem(ctx, storeID, modelID)
+	if err != nil {
+		return 0, false, err
+	}
+
+	ctx = typesystem.ContextWithTypesystem(ctx, typesys)
+	ctx = storage.ContextWithRelationshipTupleReader(ctx, s.datastore)
+
+	size, truncated, err := graph.UsersetClosureSize(ctx, &graph.ResolveCheckRequest{
+		StoreID:              storeID,
+		AuthorizationModelID: typesys.GetAuthorizationModelID(),
+		TupleKey:             tuple.NewTupleKey(object, relation, ""),
+		RequestMetadata:      graph.NewCheckRequestMetadata(s.getResolveNodeLimit(ctx, storeID)),
+	}, limit)
+	if err != nil {
+		telemetry.TraceError(span, err)
+		switch {
+		case errors.Is(err, typesystem.ErrObjectTypeUndefined):
+			return 0, false, serverErrors.TypeNotFound(tuple.GetType(object))
+		case errors.Is(err, typesystem.ErrRelationUndefined):
+			return 0, false, serverErrors.RelationNotFound(relation, tuple.GetType(object), nil)
+		case errors.Is(err, condition.ErrEvaluationFailed):
+			return 0, false, serverErrors.ValidationError(err)
+		case errors.Is(err, graph.ErrResolutionDepthExceeded):
+			return 0, false, serverErrors.AuthorizationModelResolutionTooComplex
+		}
+
+		return 0, false, serverErrors.HandleError("", err)
+	}
+
+	span.SetAttributes(attribute.Int("closure_size", int(size)), attribute.Bool("truncated", truncated))
+
+	return size, truncated, nil
+}
+
+// ExportRelationGraph returns the relation dependency graph of the authorization model with the given ID, or of
+// the latest authorization model of the store if modelID is empty, as a GraphViz DOT string.
+// See [typesystem.TypeSystem.RelationGraphDOT].
+func (s *Server) ExportRelationGraph(ctx context.Context, storeID, modelID string) (string, error) {
+	ctx, span := tracer.Start(ctx, "ExportRelationGraph", trace.WithAttributes(
+		attribute.String("store_id", storeID),
+	))
+	defer span.End()
+
+	typesys, err := s.resolveTypesystem(ctx, storeID, modelID)
+	if err != nil {
+		return "", err
+	}
+
+	return typesys.RelationGraphDOT(), nil
+}
 
-	grpc_ctxtags.Extract(ctx).Set(dispatchCountHistogramName, dispatchCount)
-	span.SetAttributes(attribute.Float64(dispatchCountHistogramName, dispatchCount))
-	dispatchCountHistogram.WithLabelValues(
-		s.serviceName,
-		methodName,
-	).Observe(dispatchCount)
+// ExportModelGraph returns the graph of the authorization model with the given ID, or of the latest
+// authorization model of the store if modelID is empty, annotated with its rewrite operators and type
+// restrictions. See [typesystem.TypeSystem.ModelGraph].
+func (s *Server) ExportModelGraph(ctx context.Context, storeID, modelID string) (*typesystem.ModelGraph, error) {
+	ctx, span := tracer.Start(ctx, "ExportModelGraph", trace.WithAttributes(
+		attribute.String("store_id", storeID),
+	))
+	defer span.End()
 
-	requestDurationHistogram.WithLabelValues(
-		s.serviceName,
-		methodName,
-		utils.Bucketize(uint(*resolutionMetadata.DatastoreQueryCount), s.requestDurationByQueryHistogramBuckets),
-		utils.Bucketize(uint(resolutionMetadata.DispatchCounter.Load()), s.requestDurationByDispatchCountHistogramBuckets),
-	).Observe(float64(time.Since(start).Milliseconds()))
+	typesys, err := s.resolveTypesystem(ctx, storeID, modelID)
+	if err != nil {
+		return nil, err
+	}
 
-	return nil
+	return typesys.ModelGraph(), nil
 }
 
-func (s *Server) Read(ctx context.Context, req *openfgav1.ReadRequest) (*openfgav1.ReadResponse, error) {
-	tk := req.GetTupleKey()
-	ctx, span := tracer.Start(ctx, "Read", trace.WithAttributes(
-		attribute.KeyValue{Key: "object", Value: attribute.StringValue(tk.GetObject())},
-		attribute.KeyValue{Key: "relation", Value: attribute.StringValue(tk.GetRelation())},
-		attribute.KeyValue{Key: "user", Value: attribute.StringValue(tk.GetUser())},
+// ValidateAuthorizationModel validates the authorization model of the request like WriteAuthorizationModel
+// does, without writing it, and returns the diagnostics of its analysis, e.g. the relations that can never be
+// satisfied or the ones whose resolution depth is unbounded. See [typesystem.TypeSystem.Lint].
+func (s *Server) ValidateAuthorizationModel(ctx context.Context, req *openfgav1.WriteAuthorizationModelRequest) ([]typesystem.Diagnostic, error) {
+	ctx, span := tracer.Start(ctx, "ValidateAuthorizationModel", trace.WithAttributes(
+		attribute.String("store_id", req.GetStoreId()),
 	))
 	defer span.End()
 
@@ -791,25 +2078,39 @@ func (s *Server) Read(ctx context.Context, req *openfgav1.ReadRequest) (*openfga
 		}
 	}
 
-	ctx = telemetry.ContextWithRPCInfo(ctx, telemetry.RPCInfo{
-		Service: s.serviceName,
-		Method:  "Read",
-	})
+	c, err := s.writeAuthorizationModelCommand(req.GetStoreId())
+	if err != nil {
+		return nil, err
+	}
 
-	q := commands.NewReadQuery(s.datastore,
-		commands.WithReadQueryLogger(s.logger),
-		commands.WithReadQueryEncoder(s.encoder),
-	)
-	return q.Execute(ctx, &openfgav1.ReadRequest{
-		StoreId:           req.GetStoreId(),
-		TupleKey:          tk,
-		PageSize:          req.GetPageSize(),
-		ContinuationToken: req.GetContinuationToken(),
-	})
+	typesys, err := c.Validate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	diagnostics := typesys.Lint()
+	span.SetAttributes(attribute.Int("diagnostic_count", len(diagnostics)))
+
+	return diagnostics, nil
 }
 
-func (s *Server) Write(ctx context.Context, req *openfgav1.WriteRequest) (*openfgav1.WriteResponse, error) {
-	ctx, span := tracer.Start(ctx, "Write")
+// WriteAuthorizationModelDryRunResult is the outcome of WriteAuthorizationModelDryRun.
+type WriteAuthorizationModelDryRunResult struct {
+	// LatestAuthorizationModelID is the ID of the model the written model is compared with, empty if the store
+	// has no model.
+	LatestAuthorizationModelID string
+
+	// Changes are the changes from the latest model of the store to the written model. See [typesystem.Diff].
+	Changes []typesystem.ModelChange
+}
+
+// WriteAuthorizationModelDryRun validates the authorization model of the request like WriteAuthorizationModel
+// does, without writing it, and returns its changes from the latest model of the store, e.g. for a pipeline to
+// require a review of the changes that are [typesystem.ModelChange].Breaking before writing the model.
+func (s *Server) WriteAuthorizationModelDryRun(ctx context.Context, req *openfgav1.WriteAuthorizationModelRequest) (*WriteAuthorizationModelDryRunResult, error) {
+	ctx, span := tracer.Start(ctx, "WriteAuthorizationModelDryRun", trace.WithAttributes(
+		attribute.String("store_id", req.GetStoreId()),
+	))
 	defer span.End()
 
 	if !validator.RequestIsValidatedFromContext(ctx) {
@@ -818,31 +2119,206 @@ func (s *Server) Write(ctx context.Context, req *openfgav1.WriteRequest) (*openf
 		}
 	}
 
-	ctx = telemetry.ContextWithRPCInfo(ctx, telemetry.RPCInfo{
-		Service: s.serviceName,
-		Method:  "Write",
-	})
+	c, err := s.writeAuthorizationModelCommand(req.GetStoreId())
+	if err != nil {
+		return nil, err
+	}
 
-	storeID := req.GetStoreId()
+	typesys, err := c.Validate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
 
-	typesys, err := s.resolveTypesystem(ctx, storeID, req.GetAuthorizationModelId())
+	// the first model of a store is compared with an empty model
+	latest, err := s.typesystemResolver(ctx, req.GetStoreId(), "")
+	if err != nil && !errors.Is(err, typesystem.ErrModelNotFound) {
+		return nil, serverErrors.HandleError("", err)
+	}
+
+	res := &WriteAuthorizationModelDryRunResult{
+		Changes: typesystem.Diff(latest, typesys),
+	}
+	if latest != nil {
+		res.LatestAuthorizationModelID = latest.GetAuthorizationModelID()
+	}
+	span.SetAttributes(attribute.Int("change_count", len(res.Changes)))
+
+	return res, nil
+}
+
+// LintAuthorizationModel returns the diagnostics of the analysis of the authorization model with the given ID,
+// or of the latest authorization model of the store if modelID is empty. See [typesystem.TypeSystem.Lint].
+func (s *Server) LintAuthorizationModel(ctx context.Context, storeID, modelID string) ([]typesystem.Diagnostic, error) {
+	ctx, span := tracer.Start(ctx, "LintAuthorizationModel", trace.WithAttributes(
+		attribute.String("store_id", storeID),
+	))
+	defer span.End()
+
+	typesys, err := s.resolveTypesystem(ctx, storeID, modelID)
 	if err != nil {
 		return nil, err
 	}
 
-	cmd := commands.NewWriteCommand(
-		s.datastore,
-		commands.WithWriteCmdLogger(s.logger),
-	)
-	return cmd.Execute(ctx, &openfgav1.WriteRequest{
-		StoreId:              storeID,
-		AuthorizationModelId: typesys.GetAuthorizationModelID(), // the resolved model id
-		Writes:               req.GetWrites(),
-		Deletes:              req.GetDeletes(),
+	return typesys.Lint(), nil
+}
+
+// BatchCheckResult holds the outcome of one of the checks of a BatchCheck call.
+type BatchCheckResult struct {
+	Response *openfgav1.CheckResponse
+	Err      error
+}
+
+// BatchCheck evaluates several Check requests, up to the limit set with WithMaxConcurrentChecksPerBatchCheck
+// at a time. The returned slice has one result per request and results[i] always holds the outcome of reqs[i],
+// regardless of the order in which the checks complete. The checks of the batch share the results of the
+// subproblems they resolve, so items overlapping with each other, e.g. many checks for the same user going
+// through the same group memberships, are cheaper to evaluate than with separate Check calls.
+func (s *Server) BatchCheck(ctx context.Context, reqs []*openfgav1.CheckRequest) []BatchCheckResult {
+	ctx, span := tracer.Start(ctx, "BatchCheck", trace.WithAttributes(
+		attribute.Int("batch_size", len(reqs)),
+	))
+	defer span.End()
+
+	results := make([]BatchCheckResult, len(reqs))
+	s.batchCheck(ctx, reqs, func(i int, resp *openfgav1.CheckResponse, err error) {
+		results[i] = BatchCheckResult{Response: resp, Err: err}
+	})
+
+	return results
+}
+
+// BatchCheckCounts holds the aggregate outcome of the checks of a BatchCheckSummary call.
+type BatchCheckCounts struct {
+	Allowed uint32
+	Denied  uint32
+	Errors  uint32
+}
+
+// BatchCheckSummary evaluates several Check requests like BatchCheck, but only returns how many were allowed,
+// denied or failed.
+func (s *Server) BatchCheckSummary(ctx context.Context, reqs []*openfgav1.CheckRequest) BatchCheckCounts {
+	ctx, span := tracer.Start(ctx, "BatchCheckSummary", trace.WithAttributes(
+		attribute.Int("batch_size", len(reqs)),
+	))
+	defer span.End()
+
+	var allowed, denied, errored atomic.Uint32
+	s.batchCheck(ctx, reqs, func(_ int, resp *openfgav1.CheckResponse, err error) {
+		switch {
+		case err != nil:
+			errored.Add(1)
+		case resp.GetAllowed():
+			allowed.Add(1)
+		default:
+			denied.Add(1)
+		}
 	})
+
+	counts := BatchCheckCounts{
+		Allowed: allowed.Load(),
+		Denied:  denied.Load(),
+		Errors:  errored.Load(),
+	}
+
+	span.SetAttributes(
+		attribute.Int("allowed_count", int(counts.Allowed)),
+		attribute.Int("denied_count", int(counts.Denied)),
+		attribute.Int("error_count", int(counts.Errors)),
+	)
+
+	return counts
+}
+
+// batchCheck calls Check for every request, up to maxConcurrentChecksPerBatchCheck at a time, and reports
+// the outcome of reqs[i] to onResult with index i. onResult may be called concurrently. The checks share
+// the results of their subproblems for as long as the batch is being evaluated.
+func (s *Server) batchCheck(
+	ctx context.Context,
+	reqs []*openfgav1.CheckRequest,
+	onResult func(i int, resp *openfgav1.CheckResponse, err error),
+) {
+	resultCache := graph.NewCheckResultCache()
+	ctx = graph.ContextWithCheckResultCache(ctx, resultCache)
+	defer func() {
+		trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("shared_result_count", int64(resultCache.Hits())))
+	}()
+
+	limiter := make(chan struct{}, s.maxConcurrentChecksPerBatchCheck)
+
+	var wg sync.WaitGroup
+	for i, req := range reqs {
+		limiter <- struct{}{}
+		wg.Add(1)
+		go func(i int, req *openfgav1.CheckRequest) {
+			defer func() {
+				<-limiter
+				wg.Done()
+			}()
+
+			resp, err := s.Check(ctx, req)
+			onResult(i, resp, err)
+		}(i, req)
+	}
+	wg.Wait()
+}
+
+// admitCheck reserves one of the slots bounding the number of concurrent Check requests, waiting in the
+// queue if there is no free slot. The returned function must be called to release the slot.
+func (s *Server) admitCheck(ctx context.Context) (func(), error) {
+	if s.checkSlots == nil {
+		return func() {}, nil
+	}
+
+	release := func() { <-s.checkSlots }
+
+	select {
+	case s.checkSlots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	select {
+	case s.checkQueue <- struct{}{}:
+		defer func() { <-s.checkQueue }()
+	default:
+		return nil, serverErrors.ServerBusy
+	}
+
+	select {
+	case s.checkSlots <- struct{}{}:
+		return release, nil
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, serverErrors.RequestDeadlineExceeded
+		}
+		return nil, serverErrors.RequestCancelled
+	}
+}
+
+// resolveCheckErrorStatus returns the status error a Check that failed with the ResolveCheckError is answered with.
+// Note for ListObjects: it returns partial results instead when its Checks time out, so it does not use it.
+func resolveCheckErrorStatus(err *graph.ResolveCheckError) error {
+	switch err.Code {
+	case graph.ResolveCheckErrorResolutionDepthExceeded, graph.ResolveCheckErrorVisitedPathsLimitExceeded:
+		return serverErrors.AuthorizationModelResolutionTooComplex
+	case graph.ResolveCheckErrorThrottledTimeout:
+		return serverErrors.ThrottledTimeout
+	case graph.ResolveCheckErrorDeadlineExceeded:
+		return serverErrors.RequestDeadlineExceeded
+	case graph.ResolveCheckErrorCancelled:
+		return serverErrors.RequestCancelled
+	case graph.ResolveCheckErrorDatastoreTimeout:
+		return serverErrors.DatastoreTimeout
+	default:
+		return serverErrors.HandleError("", err)
+	}
 }
 
 func (s *Server) Check(ctx context.Context, req *openfgav1.CheckRequest) (*openfgav1.CheckResponse, error) {
+	return s.check(ctx, req, checkOptions{})
+}
+
+func (s *Server) check(ctx context.Context, req *openfgav1.CheckRequest, opts checkOptions) (*openfgav1.CheckResponse, error) {
 	start := time.Now()
 
 	tk := req.GetTupleKey()
@@ -860,65 +2336,199 @@ func (s *Server) Check(ctx context.Context, req *openfgav1.CheckRequest) (*openf
 		}
 	}
 
+	ctx, done, err := s.trackRequest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
 	ctx = telemetry.ContextWithRPCInfo(ctx, telemetry.RPCInfo{
 		Service: s.serviceName,
 		Method:  "Check",
 	})
 
-	storeID := req.GetStoreId()
+	if opts.consistency != nil {
+		ctx = storage.ContextWithConsistency(ctx, *opts.consistency)
+	}
+	if storage.ConsistencyFromContext(ctx) == storage.ConsistencyHigher {
+		span.SetAttributes(attribute.Bool("higher_consistency", true))
+	}
 
-	typesys, err := s.resolveTypesystem(ctx, storeID, req.GetAuthorizationModelId())
+	if s.checkIDLengthEnforcement {
+		if err := tuple.ValidateIDLength(tk, s.maxObjectIDLength, s.maxUserIDLength); err != nil {
+			return nil, serverErrors.ValidationError(err)
+		}
+	}
+
+	checkResolver := s.checkResolver
+	if s.checkShadowResolver != nil {
+		checkResolver = s.checkShadowResolver
+	}
+	if opts.resolverChain != "" {
+		resolver, ok := s.namedCheckResolvers[opts.resolverChain]
+		if !ok {
+			return nil, serverErrors.CheckResolverChainNotFound(opts.resolverChain)
+		}
+
+		span.SetAttributes(attribute.String("resolver_chain", opts.resolverChain))
+		checkResolver = resolver
+	}
+
+	release, err := s.admitCheck(ctx)
 	if err != nil {
 		return nil, err
 	}
+	defer release()
+
+	storeID := req.GetStoreId()
+	ctx = s.contextWithStoreDispatchThrottlingThreshold(ctx, storeID)
+
+	if relation := s.resolveRelationAlias(ctx, storeID, tk); relation != tk.GetRelation() {
+		span.SetAttributes(attribute.String("aliased_relation", relation))
+		tk = tuple.NewCheckRequestTupleKey(tk.GetObject(), relation, tk.GetUser())
+	}
+
+	var typesys *typesystem.TypeSystem
+	if len(opts.modelFragments) > 0 {
+		typesys, err = s.mergedTypesystem(ctx, storeID, opts.modelFragments)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		typesys, err = s.resolveTypesystem(ctx, storeID, req.GetAuthorizationModelId())
+		if err != nil {
+			return nil, err
+		}
+
+		if err := s.checkModelArchived(ctx, storeID, typesys.GetAuthorizationModelID()); err != nil {
+			return nil, err
+		}
+	}
 
 	if err := validation.ValidateUserObjectRelation(typesys, tuple.ConvertCheckRequestTupleKeyToTupleKey(tk)); err != nil {
 		return nil, serverErrors.ValidationError(err)
 	}
 
-	for _, ctxTuple := range req.GetContextualTuples().GetTupleKeys() {
-		if err := validation.ValidateTuple(typesys, ctxTuple); err != nil {
-			return nil, serverErrors.HandleTupleValidateError(err)
+	if s.checkSubjectTypeValidation {
+		if err := typesys.ValidateSubjectType(tuple.GetType(tk.GetObject()), tk.GetRelation(), tk.GetUser()); err != nil {
+			return nil, serverErrors.ValidationError(err)
+		}
+	}
+
+	if err := validation.ValidateContextualTuples(typesys, req.GetContextualTuples().GetTupleKeys()); err != nil {
+		return nil, serverErrors.HandleTupleValidateError(err)
+	}
+
+	if opts.tupleOverlay != nil {
+		for _, tk := range opts.tupleOverlay.AddedTuples {
+			if err := validation.ValidateTuple(typesys, tk); err != nil {
+				return nil, serverErrors.HandleTupleValidateError(err)
+			}
+		}
+		span.SetAttributes(attribute.Bool("tuple_overlay", true))
+	}
+
+	contextualTuples, err := dedupContextualTuples(req.GetContextualTuples().GetTupleKeys(), s.rejectDuplicateContextualTuples)
+	if err != nil {
+		return nil, err
+	}
+
+	conflictPolicy, err := s.contextualTuplesConflictPolicyFor(ctx, storeID)
+	if err != nil {
+		return nil, err
+	}
+
+	var ds storage.RelationshipTupleReader = s.datastore
+	usersetBatchReader := s.usersetBatchReader
+	if s.tupleSnapshotter != nil {
+		ds, usersetBatchReader, err = s.snapshotTuples(ctx, storeID)
+		if err != nil {
+			return nil, err
 		}
+	} else if s.checkReadHedger != nil {
+		ds = s.checkReadHedger
+	}
+	if usersetBatchReader != nil {
+		ds = storagewrappers.NewBatchingUsersetTupleReader(ds, usersetBatchReader, s.checkUsersetReadBatchingOpts...)
+	}
+	if s.storageQueryTimeout > 0 {
+		ds = storagewrappers.NewTimeoutTupleReader(ds, s.storageQueryTimeout)
+	}
+	if s.datastoreReadBudget > 0 {
+		ds = storagewrappers.NewReadBudgetTupleReader(ds, s.datastoreReadBudget)
+	}
+
+	var readPatternRecorder *storagewrappers.ReadPatternRecorder
+	if s.checkReadPatternSink != nil {
+		readPatternRecorder = storagewrappers.NewReadPatternRecorder(ds)
+		ds = readPatternRecorder
+	}
+
+	if opts.tupleOverlay != nil {
+		ds = storagewrappers.NewOverlayTupleReader(ds, *opts.tupleOverlay)
 	}
 
 	ctx = typesystem.ContextWithTypesystem(ctx, typesys)
-	ctx = storage.ContextWithRelationshipTupleReader(ctx,
-		storagewrappers.NewBoundedConcurrencyTupleReader(
-			storagewrappers.NewCombinedTupleReader(
-				s.datastore,
-				req.GetContextualTuples().GetTupleKeys(),
-			),
-			s.maxConcurrentReadsForCheck,
+	var tupleReader storage.RelationshipTupleReader = storagewrappers.NewBoundedConcurrencyTupleReader(
+		storagewrappers.NewCombinedTupleReader(
+			ds,
+			contextualTuples,
+			storagewrappers.WithConflictPolicy(conflictPolicy),
 		),
+		s.maxConcurrentReadsForCheck,
 	)
 
-	checkRequestMetadata := graph.NewCheckRequestMetadata(s.resolveNodeLimit)
+	// the identical reads are coalesced before they wait for a slot of the bounded concurrency
+	if s.checkReadDeduplicationEnabled {
+		dedupingReader := storagewrappers.NewDedupingTupleReader(tupleReader)
+		defer func() {
+			span.SetAttributes(attribute.Int64("deduplicated_read_count", int64(dedupingReader.Hits())))
+		}()
+		tupleReader = dedupingReader
+	}
+
+	ctx = storage.ContextWithRelationshipTupleReader(ctx, tupleReader)
+
+	checkRequestMetadata := graph.NewCheckRequestMetadata(s.getResolveNodeLimit(ctx, storeID))
 
 	resolveCheckRequest := graph.ResolveCheckRequest{
 		StoreID:              req.GetStoreId(),
 		AuthorizationModelID: typesys.GetAuthorizationModelID(), // the resolved model id
-		TupleKey:             tuple.ConvertCheckRequestTupleKeyToTupleKey(req.GetTupleKey()),
-		ContextualTuples:     req.GetContextualTuples().GetTupleKeys(),
+		TupleKey:             tuple.ConvertCheckRequestTupleKeyToTupleKey(tk),
+		ContextualTuples:     contextualTuples,
 		Context:              req.GetContext(),
 		RequestMetadata:      checkRequestMetadata,
+		MaxIndirectionDepth:  opts.maxIndirectionDepth,
+		ResolutionTrace:      opts.trace != nil,
+		TupleOverlay:         opts.tupleOverlay,
 	}
 
-	resp, err := s.checkResolver.ResolveCheck(ctx, &resolveCheckRequest)
+	resp, err := checkResolver.ResolveCheck(ctx, &resolveCheckRequest)
+	if readPatternRecorder != nil {
+		s.checkReadPatternSink.RecordCheckReads(ctx, storeID, tk, readPatternRecorder.Reads())
+	}
 	if err != nil {
 		telemetry.TraceError(span, err)
-		if errors.Is(err, graph.ErrResolutionDepthExceeded) {
-			return nil, serverErrors.AuthorizationModelResolutionTooComplex
+		if errors.Is(err, graph.ErrResolutionDepthExceeded) && s.depthLimitBehavior == DepthLimitDenyWithIndicator {
+			span.SetAttributes(attribute.Bool("incomplete_decision", true))
+			s.transport.SetHeader(ctx, IncompleteDecisionHeader, "true")
+			return &openfgav1.CheckResponse{Allowed: false}, nil
 		}
 
-		if errors.Is(err, condition.ErrEvaluationFailed) {
-			return nil, serverErrors.ValidationError(err)
+		var resolveErr *graph.ResolveCheckError
+		if errors.As(graph.NewResolveCheckError(&resolveCheckRequest, err), &resolveErr) {
+			span.SetAttributes(
+				attribute.String("resolve_check_error_code", string(resolveErr.Code)),
+				attribute.String("resolve_check_error_tuple_key", resolveErr.TupleKey),
+				attribute.Int64("resolve_check_error_depth", int64(resolveErr.Depth)),
+			)
+
+			return nil, resolveCheckErrorStatus(resolveErr)
 		}
 
-		// Note for ListObjects:
-		// Currently this is not feasible in ListObjects as we return partial results.
-		if errors.Is(err, context.DeadlineExceeded) && resolveCheckRequest.GetRequestMetadata().WasThrottled.Load() {
-			return nil, serverErrors.ThrottledTimeout
+		if errors.Is(err, condition.ErrEvaluationFailed) || errors.Is(err, graph.ErrInvalidTupleKey) ||
+			errors.Is(err, graph.ErrConditionEvaluationsLimitExceeded) || errors.Is(err, graph.ErrUndefinedComputedRelation) {
+			return nil, serverErrors.ValidationError(err)
 		}
 
 		return nil, serverErrors.HandleError("", err)
@@ -948,23 +2558,93 @@ func (s *Server) Check(ctx context.Context, req *openfgav1.CheckRequest) (*openf
 		Allowed: resp.Allowed,
 	}
 
+	if opts.trace != nil {
+		*opts.trace = CheckTrace{
+			Path:                resp.GetResolutionPath(),
+			DispatchCount:       rawDispatchCount,
+			DatastoreQueryCount: resp.GetResolutionMetadata().DatastoreQueryCount,
+		}
+	}
+
 	span.SetAttributes(attribute.KeyValue{Key: "allowed", Value: attribute.BoolValue(res.GetAllowed())})
 
+	if s.checkObligations && res.GetAllowed() {
+		if obligations := resp.GetObligations(); len(obligations) > 0 {
+			s.transport.SetHeader(ctx, ObligationsHeader, strings.Join(obligations, ","))
+		}
+	}
+
+	s.setDataStalenessHeader(ctx, storeID)
+
+	duration := time.Since(start)
+
 	requestDurationHistogram.WithLabelValues(
 		s.serviceName,
 		methodName,
 		utils.Bucketize(uint(resp.GetResolutionMetadata().DatastoreQueryCount), s.requestDurationByQueryHistogramBuckets),
 		utils.Bucketize(uint(rawDispatchCount), s.requestDurationByDispatchCountHistogramBuckets),
-	).Observe(float64(time.Since(start).Milliseconds()))
+	).Observe(float64(duration.Milliseconds()))
+
+	if s.auditLogger != nil && s.auditLogger.SampleCheck() {
+		s.audit(ctx, &audit.Event{
+			Type:                 audit.EventCheck,
+			StoreID:              storeID,
+			AuthorizationModelID: typesys.GetAuthorizationModelID(),
+			TupleKey:             tuple.TupleKeyToString(tk),
+			Allowed:              res.GetAllowed(),
+		}, nil)
+	}
+
+	if s.checkOutcomeLogSampleRate > 0 && rand.Float64() < s.checkOutcomeLogSampleRate {
+		s.logger.InfoWithContext(ctx, "check outcome",
+			zap.String("store_id", storeID),
+			zap.String("relation", tk.GetRelation()),
+			zap.Bool("allowed", res.GetAllowed()),
+			zap.Duration("duration", duration),
+			zap.Uint32(dispatchCountHistogramName, rawDispatchCount),
+		)
+	}
 
 	return res, nil
 }
 
+// shadowTypesystem returns the typesystem of the candidate model of the store set with WithStoreShadowModel,
+// or nil if the store has none or the Check already requests it.
+func (s *Server) shadowTypesystem(ctx context.Context, storeID, modelID string) (*typesystem.TypeSystem, error) {
+	shadowModelID, ok := s.shadowModels[storeID]
+	if !ok || shadowModelID == modelID {
+		return nil, nil
+	}
+
+	return s.resolveTypesystem(ctx, storeID, shadowModelID)
+}
+
+// setDataStalenessHeader reports, if the datastore supports it, the maximum age (in milliseconds) of the
+// data read from the store in the DataStalenessHeader response header.
+func (s *Server) setDataStalenessHeader(ctx context.Context, storeID string) {
+	if s.freshnessReporter == nil {
+		return
+	}
+
+	staleness, err := s.freshnessReporter.Staleness(ctx, storeID)
+	if err != nil {
+		s.logger.WarnWithContext(ctx, "failed to read the datastore staleness", zap.String("store_id", storeID), zap.Error(err))
+		return
+	}
+
+	s.transport.SetHeader(ctx, DataStalenessHeader, strconv.FormatInt(staleness.Milliseconds(), 10))
+}
+
 func (s *Server) Expand(ctx context.Context, req *openfgav1.ExpandRequest) (*openfgav1.ExpandResponse, error) {
+	return s.expand(ctx, req, expandOptions{})
+}
+
+func (s *Server) expand(ctx context.Context, req *openfgav1.ExpandRequest, opts expandOptions) (*openfgav1.ExpandResponse, error) {
 	tk := req.GetTupleKey()
 	ctx, span := tracer.Start(ctx, "Expand", trace.WithAttributes(
 		attribute.KeyValue{Key: "object", Value: attribute.StringValue(tk.GetObject())},
 		attribute.KeyValue{Key: "relation", Value: attribute.StringValue(tk.GetRelation())},
+		attribute.Int("max_depth", int(opts.maxDepth)),
 	))
 	defer span.End()
 
@@ -981,12 +2661,21 @@ func (s *Server) Expand(ctx context.Context, req *openfgav1.ExpandRequest) (*ope
 
 	storeID := req.GetStoreId()
 
+	if limit := s.getResolveNodeLimit(ctx, storeID); opts.maxDepth > limit {
+		return nil, serverErrors.ValidationError(
+			fmt.Errorf("the expand depth %d exceeds the resolve node limit %d", opts.maxDepth, limit),
+		)
+	}
+
 	typesys, err := s.resolveTypesystem(ctx, storeID, req.GetAuthorizationModelId())
 	if err != nil {
 		return nil, err
 	}
 
-	q := commands.NewExpandQuery(s.datastore, commands.WithExpandQueryLogger(s.logger))
+	q := commands.NewExpandQuery(s.datastore,
+		commands.WithExpandQueryLogger(s.logger),
+		commands.WithExpandQueryMaxDepth(opts.maxDepth),
+	)
 	return q.Execute(ctx, &openfgav1.ExpandRequest{
 		StoreId:              storeID,
 		AuthorizationModelId: typesys.GetAuthorizationModelID(), // the resolved model id
@@ -1030,11 +2719,17 @@ func (s *Server) WriteAuthorizationModel(ctx context.Context, req *openfgav1.Wri
 		Method:  "WriteAuthorizationModel",
 	})
 
-	c := commands.NewWriteAuthorizationModelCommand(s.datastore,
-		commands.WithWriteAuthModelLogger(s.logger),
-		commands.WithWriteAuthModelMaxSizeInBytes(s.maxAuthorizationModelSizeInBytes),
-	)
+	c, err := s.writeAuthorizationModelCommand(req.GetStoreId())
+	if err != nil {
+		return nil, err
+	}
+
 	res, err := c.Execute(ctx, req)
+	s.audit(ctx, &audit.Event{
+		Type:                 audit.EventWriteAuthorizationModel,
+		StoreID:              req.GetStoreId(),
+		AuthorizationModelID: res.GetAuthorizationModelId(),
+	}, err)
 	if err != nil {
 		return nil, err
 	}
@@ -1044,6 +2739,21 @@ func (s *Server) WriteAuthorizationModel(ctx context.Context, req *openfgav1.Wri
 	return res, nil
 }
 
+// writeAuthorizationModelCommand returns the command validating and writing the models of the store.
+func (s *Server) writeAuthorizationModelCommand(storeID string) (*commands.WriteAuthorizationModelCommand, error) {
+	conditionEnv, err := s.conditionEnv(storeID)
+	if err != nil {
+		return nil, err
+	}
+
+	return commands.NewWriteAuthorizationModelCommand(s.datastore,
+		commands.WithWriteAuthModelLogger(s.logger),
+		commands.WithWriteAuthModelMaxSizeInBytes(s.maxAuthorizationModelSizeInBytes),
+		commands.WithWriteAuthModelMaxTypes(s.maxTypesPerAuthorizationModel),
+		commands.WithWriteAuthModelConditionEnv(conditionEnv),
+	), nil
+}
+
 func (s *Server) ReadAuthorizationModels(ctx context.Context, req *openfgav1.ReadAuthorizationModelsRequest) (*openfgav1.ReadAuthorizationModelsResponse, error) {
 	ctx, span := tracer.Start(ctx, "ReadAuthorizationModels")
 	defer span.End()
@@ -1169,6 +2879,10 @@ func (s *Server) CreateStore(ctx context.Context, req *openfgav1.CreateStoreRequ
 
 	c := commands.NewCreateStoreCommand(s.datastore, commands.WithCreateStoreCmdLogger(s.logger))
 	res, err := c.Execute(ctx, req)
+	s.audit(ctx, &audit.Event{
+		Type:    audit.EventCreateStore,
+		StoreID: res.GetId(),
+	}, err)
 	if err != nil {
 		return nil, err
 	}
@@ -1195,6 +2909,10 @@ func (s *Server) DeleteStore(ctx context.Context, req *openfgav1.DeleteStoreRequ
 
 	cmd := commands.NewDeleteStoreCommand(s.datastore, commands.WithDeleteStoreCmdLogger(s.logger))
 	res, err := cmd.Execute(ctx, req)
+	s.audit(ctx, &audit.Event{
+		Type:    audit.EventDeleteStore,
+		StoreID: req.GetStoreId(),
+	}, err)
 	if err != nil {
 		return nil, err
 	}
@@ -1265,12 +2983,71 @@ func (s *Server) IsReady(ctx context.Context) (bool, error) {
 	return false, nil
 }
 
+const (
+	// PrimaryDatastoreName is the name DatastoreHealth reports the datastore given to WithDatastore under.
+	PrimaryDatastoreName = "primary"
+	// ReplicaDatastoreName is the name DatastoreHealth reports the replica given to WithCheckReadHedging under.
+	ReplicaDatastoreName = "replica"
+)
+
+// DatastoreHealth reports the health of each datastore of the server: the 'primary' datastore, which IsReady
+// depends on, and the 'replica' given to WithCheckReadHedging, if it reports its readiness. A replica that is not
+// ready, e.g. because it is down or its schema is behind the one of the primary, degrades the latency of the Checks
+// but does not make the server unready.
+func (s *Server) DatastoreHealth(ctx context.Context) []health.DatastoreHealth {
+	datastores := []health.DatastoreHealth{datastoreHealth(ctx, PrimaryDatastoreName, s.datastore)}
+
+	if replica, ok := s.checkReadReplica.(readinessReporter); ok {
+		datastores = append(datastores, datastoreHealth(ctx, ReplicaDatastoreName, replica))
+	}
+
+	return datastores
+}
+
+// readinessReporter is implemented by the datastores reporting their readiness, see [storage.OpenFGADatastore].
+type readinessReporter interface {
+	IsReady(ctx context.Context) (storage.ReadinessStatus, error)
+}
+
+func datastoreHealth(ctx context.Context, name string, datastore readinessReporter) health.DatastoreHealth {
+	status, err := datastore.IsReady(ctx)
+	if err != nil {
+		return health.DatastoreHealth{Name: name, Message: err.Error()}
+	}
+
+	return health.DatastoreHealth{Name: name, Ready: status.IsReady, Message: status.Message}
+}
+
+// conditionEnv returns the CEL environment the conditions of the store are compiled in, or nil for the
+// default one if WithStoreConditionFunctions is not set.
+func (s *Server) conditionEnv(storeID string) (*cel.Env, error) {
+	if s.conditionEnvs == nil {
+		return nil, nil
+	}
+
+	env, err := s.conditionEnvs.Get(storeID)
+	if err != nil {
+		return nil, serverErrors.HandleError("", err)
+	}
+
+	return env, nil
+}
+
 // resolveTypesystem resolves the underlying TypeSystem given the storeID and modelID and
-// it sets some response metadata based on the model resolution.
+// it sets some response metadata based on the model resolution. If modelID is empty, the model
+// pinned as the default of the store is resolved, or the latest model if none is pinned.
 func (s *Server) resolveTypesystem(ctx context.Context, storeID, modelID string) (*typesystem.TypeSystem, error) {
 	ctx, span := tracer.Start(ctx, "resolveTypesystem")
 	defer span.End()
 
+	if modelID == "" {
+		pinnedModelID, err := s.storeDefaultModel(ctx, storeID)
+		if err != nil {
+			return nil, err
+		}
+		modelID = pinnedModelID
+	}
+
 	typesys, err := s.typesystemResolver(ctx, storeID, modelID)
 	if err != nil {
 		if errors.Is(err, typesystem.ErrModelNotFound) {
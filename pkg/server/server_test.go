@@ -4,33 +4,51 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"math"
 	"os"
 	"path"
 	"runtime"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/google/cel-go/cel"
+	celtypes "github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
 	"github.com/oklog/ulid/v2"
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	language "github.com/openfga/language/pkg/go/transformer"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/goleak"
 	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	healthv1pb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
 	"github.com/openfga/openfga/cmd/migrate"
 	"github.com/openfga/openfga/cmd/util"
+	"github.com/openfga/openfga/internal/authn"
 	"github.com/openfga/openfga/internal/build"
 	"github.com/openfga/openfga/internal/graph"
 	mockstorage "github.com/openfga/openfga/internal/mocks"
 	serverconfig "github.com/openfga/openfga/internal/server/config"
+	"github.com/openfga/openfga/pkg/audit"
+	"github.com/openfga/openfga/pkg/dispatch"
+	"github.com/openfga/openfga/pkg/logger"
 	"github.com/openfga/openfga/pkg/server/commands"
 	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/server/health"
 	"github.com/openfga/openfga/pkg/server/test"
 	"github.com/openfga/openfga/pkg/storage"
 	"github.com/openfga/openfga/pkg/storage/memory"
@@ -256,6 +274,18 @@ func TestServerPanicIfDefaultListObjectThresholdGreaterThanMaxDispatchThreshold(
 	})
 }
 
+func TestServerPanicIfInvalidOutcomeLogSampleRate(t *testing.T) {
+	require.PanicsWithError(t, "failed to construct the OpenFGA server: check outcome log sample rate must be between 0 and 1", func() {
+		mockController := gomock.NewController(t)
+		defer mockController.Finish()
+		mockDatastore := mockstorage.NewMockOpenFGADatastore(mockController)
+		_ = MustNewServerWithOpts(
+			WithDatastore(mockDatastore),
+			WithOutcomeLogging(1.5),
+		)
+	})
+}
+
 func TestServerWithPostgresDatastore(t *testing.T) {
 	t.Cleanup(func() {
 		goleak.VerifyNone(t)
@@ -1155,861 +1185,6392 @@ func TestCheckWithCachedResolution(t *testing.T) {
 	require.True(t, checkResponse.GetAllowed())
 }
 
-func TestWriteAssertionModelDSError(t *testing.T) {
+func TestCheckOutcomeLogging(t *testing.T) {
 	t.Cleanup(func() {
 		goleak.VerifyNone(t)
 	})
 
 	ctx := context.Background()
 
-	storeID := ulid.Make().String()
-	modelID := ulid.Make().String()
-
-	typedefs := language.MustTransformDSLToProto(`
-		model
-			schema 1.1
+	setup := func(t *testing.T, sampleRate float64) (*Server, string, *observer.ObservedLogs) {
+		_, ds, _ := util.MustBootstrapDatastore(t, "memory")
 
-		type user
+		observerLogger, logs := observer.New(zap.InfoLevel)
+		s := MustNewServerWithOpts(
+			WithDatastore(ds),
+			WithLogger(&logger.ZapLogger{Logger: zap.New(observerLogger)}),
+			WithOutcomeLogging(sampleRate),
+		)
+		t.Cleanup(s.Close)
 
-		type repo
-			relations
-				define reader: [user]`).GetTypeDefinitions()
+		createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+			Name: "openfga-test",
+		})
+		require.NoError(t, err)
 
-	mockController := gomock.NewController(t)
-	defer mockController.Finish()
+		storeID := createStoreResp.GetId()
 
-	mockDSOldSchema := mockstorage.NewMockOpenFGADatastore(mockController)
+		model := testutils.MustTransformDSLToProtoWithID(`
+			model
+				schema 1.1
 
-	mockDSOldSchema.EXPECT().
-		ReadAuthorizationModel(gomock.Any(), storeID, modelID).
-		AnyTimes().
-		Return(&openfgav1.AuthorizationModel{
-			SchemaVersion:   typesystem.SchemaVersion1_0,
-			TypeDefinitions: typedefs,
-		}, nil)
+			type user
 
-	mockDSBadReadAuthModel := mockstorage.NewMockOpenFGADatastore(mockController)
+			type document
+				relations
+					define viewer: [user]`)
 
-	mockDSBadReadAuthModel.EXPECT().
-		ReadAuthorizationModel(gomock.Any(), storeID, modelID).
-		AnyTimes().
-		Return(nil, fmt.Errorf("unable to read"))
+		_, err = s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+			StoreId:         storeID,
+			SchemaVersion:   model.GetSchemaVersion(),
+			TypeDefinitions: model.GetTypeDefinitions(),
+		})
+		require.NoError(t, err)
 
-	mockDSBadWriteAssertions := mockstorage.NewMockOpenFGADatastore(mockController)
-	mockDSBadWriteAssertions.EXPECT().
-		ReadAuthorizationModel(gomock.Any(), storeID, modelID).
-		AnyTimes().
-		Return(&openfgav1.AuthorizationModel{
-			SchemaVersion:   typesystem.SchemaVersion1_1,
-			TypeDefinitions: typedefs,
-		}, nil)
-	mockDSBadWriteAssertions.EXPECT().
-		WriteAssertions(gomock.Any(), storeID, modelID, gomock.Any()).
-		AnyTimes().
-		Return(fmt.Errorf("unable to write"))
+		_, err = s.Write(ctx, &openfgav1.WriteRequest{
+			StoreId: storeID,
+			Writes: &openfgav1.WriteRequestWrites{
+				TupleKeys: []*openfgav1.TupleKey{
+					tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+				},
+			},
+		})
+		require.NoError(t, err)
 
-	tests := []struct {
-		name          string
-		assertions    []*openfgav1.Assertion
-		mockDatastore *mockstorage.MockOpenFGADatastore
-		expectedError error
-	}{
-		{
-			name:          "unsupported_schema",
-			assertions:    []*openfgav1.Assertion{},
-			mockDatastore: mockDSOldSchema,
-			expectedError: serverErrors.ValidationError(
-				fmt.Errorf("invalid schema version"),
-			),
-		},
-		{
-			name:          "failed_to_read",
-			assertions:    []*openfgav1.Assertion{},
-			mockDatastore: mockDSBadReadAuthModel,
-			expectedError: serverErrors.NewInternalError(
-				"", fmt.Errorf("unable to read"),
-			),
-		},
-		{
-			name:          "failed_to_write",
-			assertions:    []*openfgav1.Assertion{},
-			mockDatastore: mockDSBadWriteAssertions,
-			expectedError: serverErrors.NewInternalError(
-				"", fmt.Errorf("unable to write"),
-			),
-		},
+		return s, storeID, logs
 	}
 
-	for _, curTest := range tests {
-		t.Run(curTest.name, func(t *testing.T) {
-			request := &openfgav1.WriteAssertionsRequest{
-				StoreId:              storeID,
-				Assertions:           curTest.assertions,
-				AuthorizationModelId: modelID,
-			}
+	t.Run("sampled_outcomes_are_logged", func(t *testing.T) {
+		s, storeID, logs := setup(t, 1)
 
-			writeAssertionCmd := commands.NewWriteAssertionsCommand(curTest.mockDatastore)
-			_, err := writeAssertionCmd.Execute(ctx, request)
-			require.ErrorIs(t, curTest.expectedError, err)
+		_, err := s.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:  storeID,
+			TupleKey: tuple.NewCheckRequestTupleKey("document:1", "viewer", "user:jon"),
 		})
-	}
-}
-
-func TestReadAssertionModelDSError(t *testing.T) {
-	t.Cleanup(func() {
-		goleak.VerifyNone(t)
-	})
+		require.NoError(t, err)
 
-	ctx := context.Background()
+		outcomes := logs.FilterMessage("check outcome").All()
+		require.Len(t, outcomes, 1)
 
-	storeID := ulid.Make().String()
-	modelID := ulid.Make().String()
+		fields := outcomes[0].ContextMap()
+		require.Equal(t, storeID, fields["store_id"])
+		require.Equal(t, "viewer", fields["relation"])
+		require.Equal(t, true, fields["allowed"])
+		require.Contains(t, fields, "duration")
+		require.Contains(t, fields, "dispatch_count")
+	})
 
-	mockController := gomock.NewController(t)
-	defer mockController.Finish()
+	t.Run("outcomes_are_not_logged_when_disabled", func(t *testing.T) {
+		s, storeID, logs := setup(t, 0)
 
-	mockDSBadReadAssertions := mockstorage.NewMockOpenFGADatastore(mockController)
-	mockDSBadReadAssertions.EXPECT().
-		ReadAssertions(gomock.Any(), storeID, modelID).
-		AnyTimes().
-		Return(nil, fmt.Errorf("unable to read"))
+		_, err := s.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:  storeID,
+			TupleKey: tuple.NewCheckRequestTupleKey("document:1", "viewer", "user:jon"),
+		})
+		require.NoError(t, err)
 
-	readAssertionQuery := commands.NewReadAssertionsQuery(mockDSBadReadAssertions)
-	_, err := readAssertionQuery.Execute(ctx, storeID, modelID)
-	expectedError := serverErrors.NewInternalError(
-		"", fmt.Errorf("unable to read"),
-	)
-	require.ErrorIs(t, expectedError, err)
+		require.Empty(t, logs.FilterMessage("check outcome").All())
+	})
 }
 
-func TestResolveAuthorizationModel(t *testing.T) {
+func TestMultiStoreWrite(t *testing.T) {
 	t.Cleanup(func() {
 		goleak.VerifyNone(t)
 	})
 
 	ctx := context.Background()
 
-	t.Run("no_latest_authorization_model_id_found", func(t *testing.T) {
-		store := ulid.Make().String()
-
-		mockController := gomock.NewController(t)
-		defer mockController.Finish()
-
-		mockDatastore := mockstorage.NewMockOpenFGADatastore(mockController)
-		mockDatastore.EXPECT().FindLatestAuthorizationModel(gomock.Any(), store).Return(nil, storage.ErrNotFound)
-
-		s := MustNewServerWithOpts(
-			WithDatastore(mockDatastore),
-		)
-		t.Cleanup(func() {
-			mockDatastore.EXPECT().Close().Times(1)
-			s.Close()
-		})
-
-		expectedError := serverErrors.LatestAuthorizationModelNotFound(store)
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
 
-		_, err := s.resolveTypesystem(ctx, store, "")
-		require.ErrorIs(t, err, expectedError)
-	})
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+	)
+	t.Cleanup(s.Close)
 
-	t.Run("read_existing_authorization_model", func(t *testing.T) {
-		store := ulid.Make().String()
-		modelID := ulid.Make().String()
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
 
-		mockController := gomock.NewController(t)
-		defer mockController.Finish()
+		type user
 
-		mockDatastore := mockstorage.NewMockOpenFGADatastore(mockController)
-		mockDatastore.EXPECT().FindLatestAuthorizationModel(gomock.Any(), store).Return(
-			&openfgav1.AuthorizationModel{
-				Id:            modelID,
-				SchemaVersion: typesystem.SchemaVersion1_1,
-			},
-			nil,
-		)
+		type document
+			relations
+				define viewer: [user]`)
 
-		s := MustNewServerWithOpts(
-			WithDatastore(mockDatastore),
-		)
-		t.Cleanup(func() {
-			mockDatastore.EXPECT().Close().Times(1)
-			s.Close()
+	createStore := func(withModel bool) string {
+		createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+			Name: "openfga-test",
 		})
-
-		typesys, err := s.resolveTypesystem(ctx, store, "")
 		require.NoError(t, err)
-		require.Equal(t, modelID, typesys.GetAuthorizationModelID())
-	})
 
-	t.Run("non-valid_modelID_returns_error", func(t *testing.T) {
-		store := ulid.Make().String()
-		modelID := "foo"
-		want := serverErrors.AuthorizationModelNotFound(modelID)
+		if withModel {
+			_, err = s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+				StoreId:         createStoreResp.GetId(),
+				SchemaVersion:   model.GetSchemaVersion(),
+				TypeDefinitions: model.GetTypeDefinitions(),
+			})
+			require.NoError(t, err)
+		}
 
-		mockController := gomock.NewController(t)
-		defer mockController.Finish()
+		return createStoreResp.GetId()
+	}
 
-		mockDatastore := mockstorage.NewMockOpenFGADatastore(mockController)
+	store1 := createStore(true)
+	store2 := createStore(true)
+	storeWithoutModel := createStore(false)
+	storeWithInvalidTuple := createStore(true)
 
-		s := MustNewServerWithOpts(
-			WithDatastore(mockDatastore),
-		)
-		t.Cleanup(func() {
-			mockDatastore.EXPECT().Close().Times(1)
-			s.Close()
-		})
+	baseTuples := []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+		tuple.NewTupleKey("document:2", "viewer", "user:jon"),
+	}
 
-		_, err := s.resolveTypesystem(ctx, store, modelID)
-		require.Equal(t, want, err)
+	results := s.MultiStoreWrite(ctx, map[string][]*openfgav1.TupleKey{
+		store1:            baseTuples,
+		store2:            baseTuples,
+		storeWithoutModel: baseTuples,
+		storeWithInvalidTuple: {
+			tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+			tuple.NewTupleKey("document:1", "undefined", "user:jon"),
+		},
 	})
+	require.Len(t, results, 4)
+
+	require.NoError(t, results[store1])
+	require.NoError(t, results[store2])
+	require.ErrorIs(t, results[storeWithoutModel], serverErrors.LatestAuthorizationModelNotFound(storeWithoutModel))
+	require.Error(t, results[storeWithInvalidTuple])
+	require.Equal(t, codes.Code(openfgav1.ErrorCode_validation_error), status.Code(results[storeWithInvalidTuple]))
+
+	for storeID, expected := range map[string]int{
+		store1:                len(baseTuples),
+		store2:                len(baseTuples),
+		storeWithoutModel:     0,
+		storeWithInvalidTuple: 0,
+	} {
+		readResp, err := s.Read(ctx, &openfgav1.ReadRequest{
+			StoreId: storeID,
+		})
+		require.NoError(t, err)
+		require.Len(t, readResp.GetTuples(), expected)
+	}
 }
 
-type mockStreamServer struct {
-	grpc.ServerStream
+// delayedTupleReaderDatastore delays the direct tuple reads of some objects to make the checks
+// of a BatchCheck complete in a different order than the one they were requested in.
+type delayedTupleReaderDatastore struct {
+	storage.OpenFGADatastore
+	delays map[string]time.Duration
 }
 
-func NewMockStreamServer() *mockStreamServer {
-	return &mockStreamServer{}
+func (d *delayedTupleReaderDatastore) ReadUserTuple(ctx context.Context, store string, tk *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
+	time.Sleep(d.delays[tk.GetObject()])
+	return d.OpenFGADatastore.ReadUserTuple(ctx, store, tk)
 }
 
-func (m *mockStreamServer) Context() context.Context {
-	return context.Background()
+// blockingTupleReaderDatastore blocks the reads of user tuples until release is closed or their context is done.
+// Note that the server detaches the reads from the cancellation of the requests, see
+// storagewrappers.ContextTracerWrapper.
+type blockingTupleReaderDatastore struct {
+	storage.OpenFGADatastore
+	started chan struct{}
+	release chan struct{}
 }
 
-func (m *mockStreamServer) Send(*openfgav1.StreamedListObjectsResponse) error {
-	return nil
+func (d *blockingTupleReaderDatastore) ReadUserTuple(ctx context.Context, store string, tk *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
+	select {
+	case d.started <- struct{}{}:
+	default: // a read already reported the start
+	}
+
+	select {
+	case <-d.release:
+		return d.OpenFGADatastore.ReadUserTuple(ctx, store, tk)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 }
 
-// This runs ListObjects and StreamedListObjects many times over to ensure no race conditions (see https://github.com/openfga/openfga/pull/762)
-func BenchmarkListObjectsNoRaceCondition(b *testing.B) {
-	b.Cleanup(func() {
-		goleak.VerifyNone(b,
-			// https://github.com/uber-go/goleak/discussions/89
-			goleak.IgnoreTopFunction("testing.(*B).run1"),
-			goleak.IgnoreTopFunction("testing.(*B).doBench"),
-		)
+func TestDrain(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
 	})
+
 	ctx := context.Background()
-	store := ulid.Make().String()
-	modelID := ulid.Make().String()
 
-	mockController := gomock.NewController(b)
-	defer mockController.Finish()
+	newServer := func(t *testing.T, drainTimeout time.Duration) (*Server, *blockingTupleReaderDatastore, string) {
+		_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+		blocking := &blockingTupleReaderDatastore{
+			OpenFGADatastore: ds,
+			started:          make(chan struct{}, 1),
+			release:          make(chan struct{}),
+		}
 
-	typedefs := language.MustTransformDSLToProto(`
-		model
-			schema 1.1
+		s := MustNewServerWithOpts(
+			WithDatastore(blocking),
+			WithShutdownDrainTimeout(drainTimeout),
+		)
+		t.Cleanup(s.Close)
 
-		type user
+		createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+			Name: "openfga-test",
+		})
+		require.NoError(t, err)
 
-		type repo
-			relations
+		model := testutils.MustTransformDSLToProtoWithID(`
+			model
+				schema 1.1
+
+			type user
+
+			type document
+				relations
+					define viewer: [user]`)
+		_, err = s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+			StoreId:         createStoreResp.GetId(),
+			SchemaVersion:   model.GetSchemaVersion(),
+			TypeDefinitions: model.GetTypeDefinitions(),
+		})
+		require.NoError(t, err)
+
+		return s, blocking, createStoreResp.GetId()
+	}
+
+	// startCheck starts a Check and waits for it to block on its read
+	startCheck := func(s *Server, ds *blockingTupleReaderDatastore, storeID string) chan error {
+		errCh := make(chan error, 1)
+		go func() {
+			_, err := s.Check(ctx, &openfgav1.CheckRequest{
+				StoreId:  storeID,
+				TupleKey: tuple.NewCheckRequestTupleKey("document:1", "viewer", "user:jon"),
+			})
+			errCh <- err
+		}()
+		<-ds.started
+
+		return errCh
+	}
+
+	t.Run("completes_the_requests_in_flight", func(t *testing.T) {
+		s, ds, storeID := newServer(t, time.Minute)
+		completed := testutil.ToFloat64(shutdownRequestsCounter.WithLabelValues("completed"))
+
+		errCh := startCheck(s, ds, storeID)
+
+		drained := make(chan struct{})
+		go func() {
+			s.Drain()
+			close(drained)
+		}()
+
+		require.Eventually(t, func() bool {
+			s.drainMu.Lock()
+			defer s.drainMu.Unlock()
+			return s.draining
+		}, time.Second, time.Millisecond)
+
+		// the new requests are rejected while the ones in flight complete
+		_, err := s.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:  storeID,
+			TupleKey: tuple.NewCheckRequestTupleKey("document:1", "viewer", "user:jon"),
+		})
+		require.ErrorIs(t, err, serverErrors.ServerShuttingDown)
+
+		_, err = s.ListObjects(ctx, &openfgav1.ListObjectsRequest{
+			StoreId:  storeID,
+			Type:     "document",
+			Relation: "viewer",
+			User:     "user:jon",
+		})
+		require.ErrorIs(t, err, serverErrors.ServerShuttingDown)
+
+		close(ds.release)
+		require.NoError(t, <-errCh)
+		<-drained
+
+		require.InDelta(t, completed+1, testutil.ToFloat64(shutdownRequestsCounter.WithLabelValues("completed")), 0)
+	})
+
+	t.Run("aborts_the_requests_after_the_timeout", func(t *testing.T) {
+		s, ds, storeID := newServer(t, 10*time.Millisecond)
+		aborted := testutil.ToFloat64(shutdownRequestsCounter.WithLabelValues("aborted"))
+
+		errCh := startCheck(s, ds, storeID)
+
+		// the read completes once the Check is canceled
+		go func() {
+			<-s.abortCtx.Done()
+			close(ds.release)
+		}()
+
+		s.Drain()
+		<-errCh
+
+		require.InDelta(t, aborted+1, testutil.ToFloat64(shutdownRequestsCounter.WithLabelValues("aborted")), 0)
+	})
+}
+
+func TestBatchCheck(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	s := MustNewServerWithOpts(
+		WithDatastore(&delayedTupleReaderDatastore{
+			OpenFGADatastore: ds,
+			delays: map[string]time.Duration{
+				"document:0": 40 * time.Millisecond,
+				"document:1": 30 * time.Millisecond,
+				"document:2": 20 * time.Millisecond,
+				"document:3": 10 * time.Millisecond,
+			},
+		}),
+		WithMaxConcurrentChecksPerBatchCheck(5),
+	)
+	t.Cleanup(s.Close)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+		Name: "openfga-test",
+	})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type document
+			relations
+				define viewer: [user]`)
+
+	writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:         storeID,
+		SchemaVersion:   model.GetSchemaVersion(),
+		TypeDefinitions: model.GetTypeDefinitions(),
+	})
+	require.NoError(t, err)
+
+	_, err = s.Write(ctx, &openfgav1.WriteRequest{
+		StoreId: storeID,
+		Writes: &openfgav1.WriteRequestWrites{
+			TupleKeys: []*openfgav1.TupleKey{
+				tuple.NewTupleKey("document:0", "viewer", "user:jon"),
+				tuple.NewTupleKey("document:2", "viewer", "user:jon"),
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	checkRequest := func(object, relation string) *openfgav1.CheckRequest {
+		return &openfgav1.CheckRequest{
+			StoreId:              storeID,
+			AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+			TupleKey:             tuple.NewCheckRequestTupleKey(object, relation, "user:jon"),
+		}
+	}
+
+	// the checks of the earliest documents take the longest, so they complete last
+	results := s.BatchCheck(ctx, []*openfgav1.CheckRequest{
+		checkRequest("document:0", "viewer"),
+		checkRequest("document:1", "viewer"),
+		checkRequest("document:2", "viewer"),
+		checkRequest("document:3", "viewer"),
+		checkRequest("document:4", "undefined"),
+	})
+	require.Len(t, results, 5)
+
+	for i, expected := range []bool{true, false, true, false} {
+		require.NoError(t, results[i].Err)
+		require.Equal(t, expected, results[i].Response.GetAllowed(), "result %d", i)
+	}
+
+	require.Nil(t, results[4].Response)
+	require.ErrorContains(t, results[4].Err, "relation 'document#undefined' not found")
+
+	require.Empty(t, s.BatchCheck(ctx, nil))
+}
+
+// readCountingDatastore counts the direct tuple reads per object.
+type readCountingDatastore struct {
+	storage.OpenFGADatastore
+	mu    sync.Mutex
+	reads map[string]int
+}
+
+func (r *readCountingDatastore) ReadUserTuple(ctx context.Context, store string, tk *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
+	r.mu.Lock()
+	r.reads[tk.GetObject()]++
+	r.mu.Unlock()
+
+	return r.OpenFGADatastore.ReadUserTuple(ctx, store, tk)
+}
+
+func (r *readCountingDatastore) readsOf(object string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reads[object]
+}
+
+func (r *readCountingDatastore) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reads = map[string]int{}
+}
+
+func TestBatchCheckSummary(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+	countingDatastore := &readCountingDatastore{OpenFGADatastore: ds, reads: map[string]int{}}
+
+	s := MustNewServerWithOpts(
+		WithDatastore(countingDatastore),
+		// one check at a time, so that every shared subproblem is resolved before it is reused
+		WithMaxConcurrentChecksPerBatchCheck(1),
+	)
+	t.Cleanup(s.Close)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+		Name: "openfga-test",
+	})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type group
+			relations
+				define member: [user]
+
+		type document
+			relations
+				define parent: [group]
+				define viewer: [user] or member from parent`)
+
+	writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:         storeID,
+		SchemaVersion:   model.GetSchemaVersion(),
+		TypeDefinitions: model.GetTypeDefinitions(),
+	})
+	require.NoError(t, err)
+
+	_, err = s.Write(ctx, &openfgav1.WriteRequest{
+		StoreId: storeID,
+		Writes: &openfgav1.WriteRequestWrites{
+			TupleKeys: []*openfgav1.TupleKey{
+				tuple.NewTupleKey("group:eng", "member", "user:jon"),
+				tuple.NewTupleKey("document:0", "parent", "group:eng"),
+				tuple.NewTupleKey("document:1", "parent", "group:eng"),
+				tuple.NewTupleKey("document:2", "parent", "group:eng"),
+				tuple.NewTupleKey("document:3", "viewer", "user:anne"),
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	checkRequest := func(object, relation, user string) *openfgav1.CheckRequest {
+		return &openfgav1.CheckRequest{
+			StoreId:              storeID,
+			AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+			TupleKey:             tuple.NewCheckRequestTupleKey(object, relation, user),
+		}
+	}
+
+	reqs := []*openfgav1.CheckRequest{
+		checkRequest("document:0", "viewer", "user:jon"),
+		checkRequest("document:1", "viewer", "user:jon"),
+		checkRequest("document:2", "viewer", "user:jon"),
+		checkRequest("document:2", "viewer", "user:jon"),
+		checkRequest("document:3", "viewer", "user:jon"),
+		checkRequest("document:3", "viewer", "user:anne"),
+		checkRequest("document:4", "viewer", "user:jon"),
+		checkRequest("document:5", "undefined", "user:jon"),
+	}
+
+	var expected BatchCheckCounts
+	for _, result := range s.BatchCheck(ctx, reqs) {
+		switch {
+		case result.Err != nil:
+			expected.Errors++
+		case result.Response.GetAllowed():
+			expected.Allowed++
+		default:
+			expected.Denied++
+		}
+	}
+	require.Equal(t, BatchCheckCounts{Allowed: 5, Denied: 2, Errors: 1}, expected)
+
+	// the membership of user:jon in group:eng is read once and shared by the checks of the batch
+	require.Equal(t, 1, countingDatastore.readsOf("group:eng"))
+
+	countingDatastore.reset()
+
+	require.Equal(t, expected, s.BatchCheckSummary(ctx, reqs))
+	require.Equal(t, 1, countingDatastore.readsOf("group:eng"))
+
+	// separate checks do not share their subproblems
+	countingDatastore.reset()
+	for _, req := range reqs[:3] {
+		_, err := s.Check(ctx, req)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 3, countingDatastore.readsOf("group:eng"))
+
+	require.Equal(t, BatchCheckCounts{}, s.BatchCheckSummary(ctx, nil))
+}
+
+// headerRecordingTransport records the response headers set by the server.
+type headerRecordingTransport struct {
+	mu      sync.Mutex
+	headers map[string]string
+}
+
+func (h *headerRecordingTransport) SetHeader(_ context.Context, key, value string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.headers[key] = value
+}
+
+// laggingDatastore reports a fixed replication lag.
+type laggingDatastore struct {
+	storage.OpenFGADatastore
+	lag time.Duration
+}
+
+func (l *laggingDatastore) Staleness(context.Context, string) (time.Duration, error) {
+	return l.lag, nil
+}
+
+func TestCheckDataStalenessHeader(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type document
+			relations
+				define viewer: [user]`)
+
+	check := func(t *testing.T, ds storage.OpenFGADatastore) map[string]string {
+		transport := &headerRecordingTransport{headers: map[string]string{}}
+
+		s := MustNewServerWithOpts(
+			WithDatastore(ds),
+			WithTransport(transport),
+		)
+		t.Cleanup(s.Close)
+
+		createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+			Name: "openfga-test",
+		})
+		require.NoError(t, err)
+
+		writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+			StoreId:         createStoreResp.GetId(),
+			SchemaVersion:   model.GetSchemaVersion(),
+			TypeDefinitions: model.GetTypeDefinitions(),
+		})
+		require.NoError(t, err)
+
+		_, err = s.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:              createStoreResp.GetId(),
+			AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+			TupleKey:             tuple.NewCheckRequestTupleKey("document:1", "viewer", "user:jon"),
+		})
+		require.NoError(t, err)
+
+		return transport.headers
+	}
+
+	t.Run("memory_datastore_is_always_fresh", func(t *testing.T) {
+		headers := check(t, memory.New())
+		require.Equal(t, "0", headers[DataStalenessHeader])
+	})
+
+	t.Run("datastore_reporting_lag", func(t *testing.T) {
+		headers := check(t, &laggingDatastore{OpenFGADatastore: memory.New(), lag: 1500 * time.Millisecond})
+		require.Equal(t, "1500", headers[DataStalenessHeader])
+	})
+
+	t.Run("datastore_without_freshness_support", func(t *testing.T) {
+		headers := check(t, &delayedTupleReaderDatastore{OpenFGADatastore: memory.New()})
+		require.NotContains(t, headers, DataStalenessHeader)
+	})
+}
+
+// blockingDatastore blocks the direct tuple reads until unblock is closed, signaling on entered
+// every time a read starts.
+type blockingDatastore struct {
+	storage.OpenFGADatastore
+	entered chan struct{}
+	unblock chan struct{}
+}
+
+func (b *blockingDatastore) ReadUserTuple(ctx context.Context, store string, tk *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
+	b.entered <- struct{}{}
+	<-b.unblock
+	return b.OpenFGADatastore.ReadUserTuple(ctx, store, tk)
+}
+
+func TestMaxConcurrentChecks(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type document
+			relations
+				define viewer: [user]`)
+
+	setup := func(t *testing.T, queueSize uint32) (*Server, *blockingDatastore, func(ctx context.Context) error) {
+		ds := &blockingDatastore{
+			OpenFGADatastore: memory.New(),
+			entered:          make(chan struct{}, 10),
+			unblock:          make(chan struct{}),
+		}
+
+		s := MustNewServerWithOpts(
+			WithDatastore(ds),
+			WithMaxConcurrentChecks(2),
+			WithCheckQueueSize(queueSize),
+		)
+		t.Cleanup(s.Close)
+
+		createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+			Name: "openfga-test",
+		})
+		require.NoError(t, err)
+
+		writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+			StoreId:         createStoreResp.GetId(),
+			SchemaVersion:   model.GetSchemaVersion(),
+			TypeDefinitions: model.GetTypeDefinitions(),
+		})
+		require.NoError(t, err)
+
+		return s, ds, func(ctx context.Context) error {
+			_, err := s.Check(ctx, &openfgav1.CheckRequest{
+				StoreId:              createStoreResp.GetId(),
+				AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+				TupleKey:             tuple.NewCheckRequestTupleKey("document:1", "viewer", "user:jon"),
+			})
+			return err
+		}
+	}
+
+	// saturate starts two checks and waits until both of them are being evaluated
+	saturate := func(ds *blockingDatastore, check func(ctx context.Context) error) chan error {
+		errs := make(chan error, 10)
+		for i := 0; i < 2; i++ {
+			go func() {
+				errs <- check(ctx)
+			}()
+		}
+		<-ds.entered
+		<-ds.entered
+		return errs
+	}
+
+	t.Run("rejects_when_there_is_no_queue", func(t *testing.T) {
+		_, ds, check := setup(t, 0)
+		errs := saturate(ds, check)
+
+		require.ErrorIs(t, check(ctx), serverErrors.ServerBusy)
+
+		close(ds.unblock)
+		require.NoError(t, <-errs)
+		require.NoError(t, <-errs)
+
+		// slots are released once the checks complete
+		require.NoError(t, check(ctx))
+	})
+
+	t.Run("queues_up_to_the_queue_size", func(t *testing.T) {
+		s, ds, check := setup(t, 1)
+		errs := saturate(ds, check)
+
+		go func() {
+			errs <- check(ctx)
+		}()
+
+		require.Eventually(t, func() bool {
+			return len(s.checkQueue) == 1
+		}, time.Second, time.Millisecond)
+
+		require.ErrorIs(t, check(ctx), serverErrors.ServerBusy)
+
+		close(ds.unblock)
+		for i := 0; i < 3; i++ {
+			require.NoError(t, <-errs)
+		}
+	})
+
+	t.Run("queued_check_honors_the_deadline", func(t *testing.T) {
+		_, ds, check := setup(t, 1)
+		errs := saturate(ds, check)
+
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+
+		require.ErrorIs(t, check(ctx), serverErrors.RequestDeadlineExceeded)
+
+		close(ds.unblock)
+		require.NoError(t, <-errs)
+		require.NoError(t, <-errs)
+	})
+}
+
+func TestGetRelationRewrite(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+	)
+	t.Cleanup(s.Close)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+		Name: "openfga-test",
+	})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type folder
+			relations
+				define viewer: [user]
+		type document
+			relations
+				define parent: [folder]
+				define blocked: [user]
+				define viewer: [user] or viewer from parent
+				define can_view: viewer but not blocked`)
+
+	writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:         storeID,
+		SchemaVersion:   model.GetSchemaVersion(),
+		TypeDefinitions: model.GetTypeDefinitions(),
+	})
+	require.NoError(t, err)
+
+	rewrite, err := s.GetRelationRewrite(ctx, storeID, writeModelResp.GetAuthorizationModelId(), "document", "viewer")
+	require.NoError(t, err)
+	require.Equal(t, "([user] or viewer from parent)", rewrite.String())
+
+	// the latest model is used if no model ID is provided
+	rewrite, err = s.GetRelationRewrite(ctx, storeID, "", "document", "can_view")
+	require.NoError(t, err)
+	require.Equal(t, typesystem.RewriteDifference, rewrite.Type)
+
+	_, err = s.GetRelationRewrite(ctx, storeID, "", "document", "undefined")
+	require.ErrorContains(t, err, "relation 'document#undefined' not found")
+
+	_, err = s.GetRelationRewrite(ctx, storeID, "", "undefined", "viewer")
+	require.ErrorContains(t, err, "type 'undefined' not found")
+}
+
+func TestExportRelationGraph(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+	)
+	t.Cleanup(s.Close)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+		Name: "openfga-test",
+	})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type group
+			relations
+				define blocked: [user]
+				define member: [user] but not blocked`)
+
+	writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:         storeID,
+		SchemaVersion:   model.GetSchemaVersion(),
+		TypeDefinitions: model.GetTypeDefinitions(),
+	})
+	require.NoError(t, err)
+
+	expected := `digraph {
+	"group#blocked";
+	"group#member";
+	"group#member" -> "group#blocked" [label="computed"];
+}
+`
+
+	graph, err := s.ExportRelationGraph(ctx, storeID, writeModelResp.GetAuthorizationModelId())
+	require.NoError(t, err)
+	require.Equal(t, expected, graph)
+
+	// the latest model is used if no model ID is provided
+	graph, err = s.ExportRelationGraph(ctx, storeID, "")
+	require.NoError(t, err)
+	require.Equal(t, expected, graph)
+
+	_, err = s.ExportRelationGraph(ctx, storeID, ulid.Make().String())
+	require.Error(t, err)
+}
+
+func TestExportModelGraph(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+	)
+	t.Cleanup(s.Close)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+		Name: "openfga-test",
+	})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type group
+			relations
+				define blocked: [user]
+				define member: [user] but not blocked`)
+
+	_, err = s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:         storeID,
+		SchemaVersion:   model.GetSchemaVersion(),
+		TypeDefinitions: model.GetTypeDefinitions(),
+	})
+	require.NoError(t, err)
+
+	graph, err := s.ExportModelGraph(ctx, storeID, "")
+	require.NoError(t, err)
+	require.Equal(t, []typesystem.ModelGraphEdge{
+		{From: "group#blocked", To: "user", Kind: typesystem.ModelGraphEdgeDirect},
+		{From: "group#member", To: "group#member/0", Kind: typesystem.ModelGraphEdgeRewrite},
+		{From: "group#member/0", To: "group#blocked", Kind: typesystem.ModelGraphEdgeComputed, Subtracted: true},
+		{From: "group#member/0", To: "user", Kind: typesystem.ModelGraphEdgeDirect},
+	}, graph.Edges)
+
+	_, err = s.ExportModelGraph(ctx, storeID, ulid.Make().String())
+	require.Error(t, err)
+}
+
+func TestValidateAuthorizationModel(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+	)
+	t.Cleanup(s.Close)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+		Name: "openfga-test",
+	})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type group
+			relations
+				define member: [user, group#member]
+				define blocked: [user]
+				define blocked_member: blocked but not blocked`)
+
+	req := &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:         storeID,
+		SchemaVersion:   model.GetSchemaVersion(),
+		TypeDefinitions: model.GetTypeDefinitions(),
+	}
+
+	t.Run("returns_the_diagnostics_without_writing_the_model", func(t *testing.T) {
+		diagnostics, err := s.ValidateAuthorizationModel(ctx, req)
+		require.NoError(t, err)
+		require.Len(t, diagnostics, 2)
+		require.Equal(t, typesystem.DiagnosticUnsatisfiableRelation, diagnostics[0].Code)
+		require.Equal(t, "blocked_member", diagnostics[0].Relation)
+		require.Equal(t, typesystem.DiagnosticUnboundedRecursion, diagnostics[1].Code)
+		require.Equal(t, "member", diagnostics[1].Relation)
+
+		_, err = s.LintAuthorizationModel(ctx, storeID, "")
+		require.ErrorIs(t, err, serverErrors.LatestAuthorizationModelNotFound(storeID))
+	})
+
+	t.Run("invalid_model", func(t *testing.T) {
+		_, err := s.ValidateAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+			StoreId:         storeID,
+			SchemaVersion:   typesystem.SchemaVersion1_1,
+			TypeDefinitions: []*openfgav1.TypeDefinition{{Type: "group", Relations: map[string]*openfgav1.Userset{"member": typesystem.ComputedUserset("undefined")}}},
+		})
+		require.Error(t, err)
+		require.Equal(t, codes.Code(openfgav1.ErrorCode_invalid_authorization_model), status.Code(err))
+	})
+
+	t.Run("lints_a_written_model", func(t *testing.T) {
+		writeModelResp, err := s.WriteAuthorizationModel(ctx, req)
+		require.NoError(t, err)
+
+		diagnostics, err := s.LintAuthorizationModel(ctx, storeID, writeModelResp.GetAuthorizationModelId())
+		require.NoError(t, err)
+		require.Len(t, diagnostics, 2)
+	})
+}
+
+func TestExpandWithMaxDepth(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	storeID := ulid.Make().String()
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type group
+			relations
+				define member: [user, group#member]
+		type folder
+			relations
+				define viewer: [user]
+		type document
+			relations
+				define parent: [folder]
+				define editor: [user, group#member]
+				define viewer: editor or viewer from parent`)
+	require.NoError(t, ds.WriteAuthorizationModel(ctx, storeID, model))
+
+	err := ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "parent", "folder:x"),
+		tuple.NewTupleKey("folder:x", "viewer", "user:carl"),
+		tuple.NewTupleKey("document:1", "editor", "group:eng#member"),
+		tuple.NewTupleKey("group:eng", "member", "user:anne"),
+		tuple.NewTupleKey("group:eng", "member", "group:fga#member"),
+		tuple.NewTupleKey("group:fga", "member", "user:bob"),
+		tuple.NewTupleKey("group:fga", "member", "group:eng#member"),
+	})
+	require.NoError(t, err)
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+	)
+	t.Cleanup(s.Close)
+
+	// concreteUsers returns the users of the leaves of the tree that are not usersets
+	var concreteUsers func(node *openfgav1.UsersetTree_Node) []string
+	concreteUsers = func(node *openfgav1.UsersetTree_Node) []string {
+		var children []*openfgav1.UsersetTree_Node
+		switch {
+		case node.GetUnion() != nil:
+			children = node.GetUnion().GetNodes()
+		case node.GetIntersection() != nil:
+			children = node.GetIntersection().GetNodes()
+		case node.GetDifference() != nil:
+			children = []*openfgav1.UsersetTree_Node{node.GetDifference().GetBase(), node.GetDifference().GetSubtract()}
+		}
+
+		var users []string
+		for _, user := range node.GetLeaf().GetUsers().GetUsers() {
+			if !tuple.IsObjectRelation(user) {
+				users = append(users, user)
+			}
+		}
+		for _, child := range children {
+			users = append(users, concreteUsers(child)...)
+		}
+		return users
+	}
+
+	req := &openfgav1.ExpandRequest{
+		StoreId:  storeID,
+		TupleKey: tuple.NewExpandRequestTupleKey("document:1", "viewer"),
+	}
+
+	t.Run("default_depth_expands_one_level", func(t *testing.T) {
+		expected, err := s.Expand(ctx, req)
+		require.NoError(t, err)
+
+		resp, err := s.ExpandWithOptions(ctx, req, WithExpandMaxDepth(0))
+		require.NoError(t, err)
+		require.Empty(t, concreteUsers(resp.GetTree().GetRoot()))
+		require.Equal(t, expected.GetTree().String(), resp.GetTree().String())
+	})
+
+	t.Run("expands_computed_usersets_and_tuple_to_usersets", func(t *testing.T) {
+		resp, err := s.ExpandWithOptions(ctx, req, WithExpandMaxDepth(1))
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"user:carl"}, concreteUsers(resp.GetTree().GetRoot()))
+
+		root := resp.GetTree().GetRoot()
+		require.Equal(t, "document:1#viewer", root.GetName())
+		editor := root.GetUnion().GetNodes()[0]
+		require.Equal(t, "document:1#viewer", editor.GetName())
+		require.Len(t, editor.GetUnion().GetNodes(), 2)
+		require.Equal(t, "document:1#editor", editor.GetUnion().GetNodes()[0].GetLeaf().GetComputed().GetUserset())
+		require.Equal(t, "document:1#editor", editor.GetUnion().GetNodes()[1].GetName())
+	})
+
+	t.Run("expands_userset_users_down_to_concrete_users", func(t *testing.T) {
+		for _, depth := range []uint32{3, 10} {
+			resp, err := s.ExpandWithOptions(ctx, req, WithExpandMaxDepth(depth))
+			require.NoError(t, err)
+			require.ElementsMatch(t, []string{"user:anne", "user:bob", "user:carl"}, concreteUsers(resp.GetTree().GetRoot()))
+		}
+	})
+
+	t.Run("depth_exceeding_the_resolve_node_limit", func(t *testing.T) {
+		_, err := s.ExpandWithOptions(ctx, req, WithExpandMaxDepth(serverconfig.DefaultResolveNodeLimit+1))
+		require.Error(t, err)
+		require.Equal(t, codes.Code(openfgav1.ErrorCode_validation_error), status.Code(err))
+	})
+}
+
+func TestStoreConditionFunctions(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	createStoreResp, err := ds.CreateStore(ctx, &openfgav1.Store{Id: ulid.Make().String(), Name: "tenant1"})
+	require.NoError(t, err)
+	tenant1 := createStoreResp.GetId()
+
+	createStoreResp, err = ds.CreateStore(ctx, &openfgav1.Store{Id: ulid.Make().String(), Name: "tenant2"})
+	require.NoError(t, err)
+	tenant2 := createStoreResp.GetId()
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+		WithStoreConditionFunctions(func(storeID string) []cel.EnvOption {
+			if storeID != tenant1 {
+				return nil
+			}
+
+			return []cel.EnvOption{
+				cel.Function("is_internal", cel.Overload("is_internal_string", []*cel.Type{cel.StringType}, cel.BoolType,
+					cel.UnaryBinding(func(arg ref.Val) ref.Val {
+						return celtypes.Bool(strings.HasSuffix(arg.Value().(string), ".internal"))
+					}),
+				)),
+			}
+		}),
+	)
+	t.Cleanup(s.Close)
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type document
+			relations
+				define viewer: [user with internal_host]
+
+		condition internal_host(host: string) {
+			is_internal(host)
+		}`)
+
+	writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:         tenant1,
+		SchemaVersion:   model.GetSchemaVersion(),
+		TypeDefinitions: model.GetTypeDefinitions(),
+		Conditions:      model.GetConditions(),
+	})
+	require.NoError(t, err)
+
+	_, err = s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:         tenant2,
+		SchemaVersion:   model.GetSchemaVersion(),
+		TypeDefinitions: model.GetTypeDefinitions(),
+		Conditions:      model.GetConditions(),
+	})
+	require.ErrorContains(t, err, "undeclared reference to 'is_internal'")
+
+	_, err = s.Write(ctx, &openfgav1.WriteRequest{
+		StoreId: tenant1,
+		Writes: &openfgav1.WriteRequestWrites{
+			TupleKeys: []*openfgav1.TupleKey{
+				tuple.NewTupleKeyWithCondition("document:1", "viewer", "user:anne", "internal_host", nil),
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	for host, allowed := range map[string]bool{"db.internal": true, "example.com": false} {
+		checkResp, err := s.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:              tenant1,
+			AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+			TupleKey:             tuple.NewCheckRequestTupleKey("document:1", "viewer", "user:anne"),
+			Context:              testutils.MustNewStruct(t, map[string]interface{}{"host": host}),
+		})
+		require.NoError(t, err)
+		require.Equal(t, allowed, checkResp.GetAllowed(), host)
+	}
+
+	// the model is resolved against the environment of the store it is read from
+	require.NoError(t, ds.WriteAuthorizationModel(ctx, tenant2, model))
+
+	_, err = s.Check(ctx, &openfgav1.CheckRequest{
+		StoreId:  tenant2,
+		TupleKey: tuple.NewCheckRequestTupleKey("document:1", "viewer", "user:anne"),
+	})
+	require.ErrorContains(t, err, "undeclared reference to 'is_internal'")
+}
+
+func TestMaxIDLength(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+		WithMaxIDLength(10, 8),
+	)
+	t.Cleanup(s.Close)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+		Name: "openfga-test",
+	})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type document
+			relations
+				define viewer: [user]`)
+
+	writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:         storeID,
+		SchemaVersion:   model.GetSchemaVersion(),
+		TypeDefinitions: model.GetTypeDefinitions(),
+	})
+	require.NoError(t, err)
+
+	write := func(tk *openfgav1.TupleKey) error {
+		_, err := s.Write(ctx, &openfgav1.WriteRequest{
+			StoreId: storeID,
+			Writes: &openfgav1.WriteRequestWrites{
+				TupleKeys: []*openfgav1.TupleKey{tk},
+			},
+		})
+		return err
+	}
+
+	objectAtMax := "document:" + strings.Repeat("a", 10)
+	userAtMax := "user:" + strings.Repeat("b", 8)
+
+	t.Run("write_ids_at_the_maximum_length", func(t *testing.T) {
+		require.NoError(t, write(tuple.NewTupleKey(objectAtMax, "viewer", userAtMax)))
+	})
+
+	t.Run("write_ids_above_the_maximum_length", func(t *testing.T) {
+		err := write(tuple.NewTupleKey(objectAtMax+"a", "viewer", userAtMax))
+		require.ErrorContains(t, err, "the 'object' field ID is 11 bytes long, which exceeds the maximum of 10 bytes")
+
+		e, ok := status.FromError(err)
+		require.True(t, ok)
+		require.Equal(t, codes.Code(openfgav1.ErrorCode_validation_error), e.Code())
+
+		err = write(tuple.NewTupleKey(objectAtMax, "viewer", userAtMax+"b"))
+		require.ErrorContains(t, err, "the 'user' field ID is 9 bytes long, which exceeds the maximum of 8 bytes")
+	})
+
+	t.Run("check_not_enforced_by_default", func(t *testing.T) {
+		checkResp, err := s.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:              storeID,
+			AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+			TupleKey:             tuple.NewCheckRequestTupleKey(objectAtMax+"a", "viewer", userAtMax),
+		})
+		require.NoError(t, err)
+		require.False(t, checkResp.GetAllowed())
+	})
+
+	t.Run("check_enforced", func(t *testing.T) {
+		s := MustNewServerWithOpts(
+			WithDatastore(ds),
+			WithMaxIDLength(10, 8),
+			WithCheckIDLengthEnforcement(true),
+		)
+		t.Cleanup(s.Close)
+
+		checkResp, err := s.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:              storeID,
+			AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+			TupleKey:             tuple.NewCheckRequestTupleKey(objectAtMax, "viewer", userAtMax),
+		})
+		require.NoError(t, err)
+		require.True(t, checkResp.GetAllowed())
+
+		_, err = s.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:              storeID,
+			AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+			TupleKey:             tuple.NewCheckRequestTupleKey(objectAtMax, "viewer", userAtMax+"b"),
+		})
+		require.ErrorContains(t, err, "the 'user' field ID is 9 bytes long, which exceeds the maximum of 8 bytes")
+	})
+}
+
+func TestCheckSubjectTypeValidation(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	createStoreResp, err := ds.CreateStore(ctx, &openfgav1.Store{Id: ulid.Make().String(), Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type widget
+		type group
+			relations
+				define member: [user]
+		type document
+			relations
+				define viewer: [user, group#member]`)
+	require.NoError(t, ds.WriteAuthorizationModel(ctx, storeID, model))
+	require.NoError(t, ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+	}))
+
+	check := func(s *Server, user string) (*openfgav1.CheckResponse, error) {
+		return s.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:              storeID,
+			AuthorizationModelId: model.GetId(),
+			TupleKey:             tuple.NewCheckRequestTupleKey("document:1", "viewer", user),
+		})
+	}
+
+	t.Run("disabled_by_default", func(t *testing.T) {
+		s := MustNewServerWithOpts(
+			WithDatastore(ds),
+		)
+		t.Cleanup(s.Close)
+
+		checkResp, err := check(s, "widget:1")
+		require.NoError(t, err)
+		require.False(t, checkResp.GetAllowed())
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		s := MustNewServerWithOpts(
+			WithDatastore(ds),
+			WithCheckSubjectTypeValidation(true),
+		)
+		t.Cleanup(s.Close)
+
+		checkResp, err := check(s, "user:jon")
+		require.NoError(t, err)
+		require.True(t, checkResp.GetAllowed())
+
+		checkResp, err = check(s, "group:eng#member")
+		require.NoError(t, err)
+		require.False(t, checkResp.GetAllowed())
+
+		_, err = check(s, "widget:1")
+		require.ErrorContains(t, err, "invalid subject type: 'widget' cannot have the relation 'document#viewer', "+
+			"expected one of: document#viewer, group#member, user")
+
+		e, ok := status.FromError(err)
+		require.True(t, ok)
+		require.Equal(t, codes.Code(openfgav1.ErrorCode_validation_error), e.Code())
+	})
+}
+
+func TestListExcludedUsers(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+	)
+	t.Cleanup(s.Close)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+		Name: "openfga-test",
+	})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type document
+			relations
+				define viewer: [user:*]
+				define blocked: [user]
+				define can_view: viewer but not blocked`)
+
+	_, err = s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:         storeID,
+		SchemaVersion:   model.GetSchemaVersion(),
+		TypeDefinitions: model.GetTypeDefinitions(),
+	})
+	require.NoError(t, err)
+
+	_, err = s.Write(ctx, &openfgav1.WriteRequest{
+		StoreId: storeID,
+		Writes: &openfgav1.WriteRequestWrites{
+			TupleKeys: []*openfgav1.TupleKey{
+				tuple.NewTupleKey("document:1", "viewer", "user:*"),
+				tuple.NewTupleKey("document:1", "blocked", "user:jon"),
+				tuple.NewTupleKey("document:1", "blocked", "user:maria"),
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	users, err := s.ListExcludedUsers(ctx, storeID, "", "document:1", "can_view")
+	require.NoError(t, err)
+	require.Equal(t, []string{"user:jon", "user:maria"}, users)
+
+	_, err = s.ListExcludedUsers(ctx, storeID, "", "document:1", "viewer")
+	require.ErrorContains(t, err, "relation is not defined as an exclusion")
+
+	_, err = s.ListExcludedUsers(ctx, storeID, "", "document:1", "undefined")
+	require.ErrorContains(t, err, "relation 'document#undefined' not found")
+}
+
+func TestStoreFeatureFlags(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type document
+			relations
+				define viewer: [user, user with under_limit]
+
+		condition under_limit(x: int) {
+			x < 100
+		}`)
+
+	t.Run("flag_takes_effect_after_cache_ttl", func(t *testing.T) {
+		cacheTTL := 200 * time.Millisecond
+
+		s := MustNewServerWithOpts(
+			WithDatastore(memory.New()),
+			WithStoreFeatureFlagsCacheTTL(cacheTTL),
+		)
+		t.Cleanup(s.Close)
+
+		createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+			Name: "openfga-test",
+		})
+		require.NoError(t, err)
+		storeID := createStoreResp.GetId()
+
+		writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+			StoreId:         storeID,
+			SchemaVersion:   model.GetSchemaVersion(),
+			TypeDefinitions: model.GetTypeDefinitions(),
+			Conditions:      model.GetConditions(),
+		})
+		require.NoError(t, err)
+
+		_, err = s.Write(ctx, &openfgav1.WriteRequest{
+			StoreId: storeID,
+			Writes: &openfgav1.WriteRequestWrites{
+				TupleKeys: []*openfgav1.TupleKey{
+					tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		// the contextual tuple conflicts with the persisted one, and its condition is not met
+		check := func() bool {
+			resp, err := s.Check(ctx, &openfgav1.CheckRequest{
+				StoreId:              storeID,
+				AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+				TupleKey:             tuple.NewCheckRequestTupleKey("document:1", "viewer", "user:jon"),
+				ContextualTuples: &openfgav1.ContextualTupleKeys{
+					TupleKeys: []*openfgav1.TupleKey{
+						tuple.NewTupleKeyWithCondition("document:1", "viewer", "user:jon", "under_limit", nil),
+					},
+				},
+				Context: testutils.MustNewStruct(t, map[string]interface{}{"x": 200}),
+			})
+			require.NoError(t, err)
+
+			return resp.GetAllowed()
+		}
+
+		require.False(t, check())
+
+		flags, err := s.ReadStoreFeatureFlags(ctx, storeID)
+		require.NoError(t, err)
+		require.Empty(t, flags)
+
+		err = s.WriteStoreFeatureFlags(ctx, storeID, map[StoreFeatureFlag]bool{
+			StoreFeaturePersistedTuplesWin: true,
+		})
+		require.NoError(t, err)
+		flipped := time.Now()
+
+		// the read is not served by the cache of the Checks
+		flags, err = s.ReadStoreFeatureFlags(ctx, storeID)
+		require.NoError(t, err)
+		require.Equal(t, map[StoreFeatureFlag]bool{StoreFeaturePersistedTuplesWin: true}, flags)
+
+		// the flags read by the previous Check are still cached
+		require.False(t, check())
+
+		require.Eventually(t, check, 5*time.Second, 10*time.Millisecond)
+		require.GreaterOrEqual(t, time.Since(flipped), cacheTTL/2)
+
+		err = s.WriteStoreFeatureFlags(ctx, storeID, map[StoreFeatureFlag]bool{
+			StoreFeaturePersistedTuplesWin: false,
+		})
+		require.NoError(t, err)
+
+		require.Eventually(t, func() bool { return !check() }, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("datastore_without_feature_flags_support", func(t *testing.T) {
+		s := MustNewServerWithOpts(
+			WithDatastore(&delayedTupleReaderDatastore{OpenFGADatastore: memory.New()}),
+		)
+		t.Cleanup(s.Close)
+
+		err := s.WriteStoreFeatureFlags(ctx, "01HVMMBCMGZNT3SED4Z17ECXCA", map[StoreFeatureFlag]bool{
+			StoreFeaturePersistedTuplesWin: true,
+		})
+		require.ErrorIs(t, err, serverErrors.StoreFeatureFlagsUnsupported)
+
+		_, err = s.ReadStoreFeatureFlags(ctx, "01HVMMBCMGZNT3SED4Z17ECXCA")
+		require.ErrorIs(t, err, serverErrors.StoreFeatureFlagsUnsupported)
+		require.Equal(t, codes.Code(openfgav1.NotFoundErrorCode_unimplemented), status.Code(err))
+	})
+}
+
+func TestStorageQueryTimeout(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	delay := 200 * time.Millisecond
+	s := MustNewServerWithOpts(
+		WithDatastore(&delayedTupleReaderDatastore{
+			OpenFGADatastore: ds,
+			delays: map[string]time.Duration{
+				"document:1": delay,
+				"document:2": delay,
+			},
+		}),
+		WithStorageQueryTimeout(20*time.Millisecond),
+	)
+	t.Cleanup(s.Close)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+		Name: "openfga-test",
+	})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type group
+			relations
+				define member: [user]
+
+		type document
+			relations
+				define parent: [group]
+				define viewer: [user] or member from parent`)
+
+	writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:         storeID,
+		SchemaVersion:   model.GetSchemaVersion(),
+		TypeDefinitions: model.GetTypeDefinitions(),
+	})
+	require.NoError(t, err)
+
+	_, err = s.Write(ctx, &openfgav1.WriteRequest{
+		StoreId: storeID,
+		Writes: &openfgav1.WriteRequestWrites{
+			TupleKeys: []*openfgav1.TupleKey{
+				tuple.NewTupleKey("group:eng", "member", "user:jon"),
+				tuple.NewTupleKey("document:1", "parent", "group:eng"),
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	check := func(object string) (*openfgav1.CheckResponse, error) {
+		return s.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:              storeID,
+			AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+			TupleKey:             tuple.NewCheckRequestTupleKey(object, "viewer", "user:jon"),
+		})
+	}
+
+	t.Run("other_branch_decides", func(t *testing.T) {
+		start := time.Now()
+
+		// the direct lookup of document:1 times out, but user:jon is a member of its parent
+		resp, err := check("document:1")
+		require.NoError(t, err)
+		require.True(t, resp.GetAllowed())
+		require.Less(t, time.Since(start), delay)
+	})
+
+	t.Run("timed_out_branch_fails_the_check", func(t *testing.T) {
+		start := time.Now()
+
+		_, err := check("document:2")
+		require.ErrorIs(t, err, serverErrors.DatastoreTimeout)
+		require.Less(t, time.Since(start), delay)
+	})
+
+	// let the abandoned queries complete
+	time.Sleep(2 * delay)
+}
+
+func TestCheckCancelled(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	createStoreResp, err := ds.CreateStore(ctx, &openfgav1.Store{Id: ulid.Make().String(), Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type document
+			relations
+				define viewer: [user]`)
+	require.NoError(t, ds.WriteAuthorizationModel(ctx, storeID, model))
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+	)
+	t.Cleanup(s.Close)
+
+	cancelledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+
+	// a Check cancelled by the client is reported as such, rather than as an internal error
+	_, err = s.Check(cancelledCtx, &openfgav1.CheckRequest{
+		StoreId:              storeID,
+		AuthorizationModelId: model.GetId(),
+		TupleKey:             tuple.NewCheckRequestTupleKey("document:1", "viewer", "user:jon"),
+	})
+	require.ErrorIs(t, err, serverErrors.RequestCancelled)
+}
+
+func TestAnalyzeEscalation(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type group
+			relations
+				define member: [user]
+
+		type organization
+			relations
+				define admin: [user, group#member]
+				define viewer: [user] or admin
+
+		type document
+			relations
+				define parent: [organization]
+				define admin: admin from parent
+				define viewer: [user] or viewer from parent`)
+
+	setup := func(t *testing.T, opts ...OpenFGAServiceV1Option) (*Server, string, string) {
+		_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+		s := MustNewServerWithOpts(append([]OpenFGAServiceV1Option{WithDatastore(ds)}, opts...)...)
+		t.Cleanup(s.Close)
+
+		createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+			Name: "openfga-test",
+		})
+		require.NoError(t, err)
+
+		writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+			StoreId:         createStoreResp.GetId(),
+			SchemaVersion:   model.GetSchemaVersion(),
+			TypeDefinitions: model.GetTypeDefinitions(),
+		})
+		require.NoError(t, err)
+
+		_, err = s.Write(ctx, &openfgav1.WriteRequest{
+			StoreId: createStoreResp.GetId(),
+			Writes: &openfgav1.WriteRequestWrites{
+				TupleKeys: []*openfgav1.TupleKey{
+					tuple.NewTupleKey("organization:acme", "admin", "group:admins#member"),
+					tuple.NewTupleKey("organization:acme", "admin", "user:anne"),
+					tuple.NewTupleKey("document:1", "parent", "organization:acme"),
+					tuple.NewTupleKey("document:2", "parent", "organization:acme"),
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		return s, createStoreResp.GetId(), writeModelResp.GetAuthorizationModelId()
+	}
+
+	t.Run("membership_unlocking_admin", func(t *testing.T) {
+		s, storeID, modelID := setup(t, WithHighPrivilegeRelations("organization#admin", "document#admin"))
+
+		unlocked, err := s.AnalyzeEscalation(ctx, storeID, modelID, tuple.NewTupleKey("group:admins", "member", "user:mallory"))
+		require.NoError(t, err)
+		require.Equal(t, []string{"document:1#admin", "document:2#admin", "organization:acme#admin"}, unlocked)
+	})
+
+	t.Run("membership_of_unrelated_group", func(t *testing.T) {
+		s, storeID, modelID := setup(t, WithHighPrivilegeRelations("organization#admin", "document#admin"))
+
+		unlocked, err := s.AnalyzeEscalation(ctx, storeID, modelID, tuple.NewTupleKey("group:readers", "member", "user:mallory"))
+		require.NoError(t, err)
+		require.Empty(t, unlocked)
+	})
+
+	t.Run("already_granted_relations_are_not_reported", func(t *testing.T) {
+		s, storeID, modelID := setup(t, WithHighPrivilegeRelations("organization#admin", "document#admin"))
+
+		unlocked, err := s.AnalyzeEscalation(ctx, storeID, modelID, tuple.NewTupleKey("group:admins", "member", "user:anne"))
+		require.NoError(t, err)
+		require.Empty(t, unlocked)
+	})
+
+	t.Run("without_high_privilege_relations_every_relation_is_reported", func(t *testing.T) {
+		s, storeID, modelID := setup(t)
+
+		unlocked, err := s.AnalyzeEscalation(ctx, storeID, modelID, tuple.NewTupleKey("group:admins", "member", "user:mallory"))
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"document:1#admin",
+			"document:1#viewer",
+			"document:2#admin",
+			"document:2#viewer",
+			"group:admins#member",
+			"organization:acme#admin",
+			"organization:acme#viewer",
+		}, unlocked)
+	})
+
+	t.Run("invalid_tuple", func(t *testing.T) {
+		s, storeID, modelID := setup(t)
+
+		_, err := s.AnalyzeEscalation(ctx, storeID, modelID, tuple.NewTupleKey("group:admins", "owner", "user:mallory"))
+		require.Error(t, err)
+		require.Equal(t, codes.Code(openfgav1.ErrorCode_invalid_tuple), status.Code(err))
+	})
+}
+
+func TestMaxConditionEvaluationsForCheck(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+		WithMaxConditionEvaluationsForCheck(5),
+	)
+	t.Cleanup(s.Close)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+		Name: "openfga-test",
+	})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type group
+			relations
+				define member: [user]
+
+		type document
+			relations
+				define viewer: [user, group#member with x_less_than]
+
+		condition x_less_than(x: int) {
+			x < 100
+		}`)
+
+	writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:         storeID,
+		SchemaVersion:   model.GetSchemaVersion(),
+		TypeDefinitions: model.GetTypeDefinitions(),
+		Conditions:      model.GetConditions(),
+	})
+	require.NoError(t, err)
+
+	check := func(groups int) (*openfgav1.CheckResponse, error) {
+		var contextualTuples []*openfgav1.TupleKey
+		for i := 0; i < groups; i++ {
+			contextualTuples = append(contextualTuples,
+				tuple.NewTupleKeyWithCondition("document:1", "viewer", fmt.Sprintf("group:%d#member", i), "x_less_than", nil))
+		}
+
+		return s.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:              storeID,
+			AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+			TupleKey:             tuple.NewCheckRequestTupleKey("document:1", "viewer", "user:jon"),
+			ContextualTuples:     &openfgav1.ContextualTupleKeys{TupleKeys: contextualTuples},
+			Context:              testutils.MustNewStruct(t, map[string]interface{}{"x": 200}),
+		})
+	}
+
+	resp, err := check(5)
+	require.NoError(t, err)
+	require.False(t, resp.GetAllowed())
+
+	_, err = check(20)
+	require.Error(t, err)
+	require.Equal(t, codes.Code(openfgav1.ErrorCode_validation_error), status.Code(err))
+	require.ErrorContains(t, err, graph.ErrConditionEvaluationsLimitExceeded.Error())
+}
+
+func TestDuplicateContextualTuples(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type document
+			relations
+				define viewer: [user, user with x_less_than]
+
+		condition x_less_than(x: int) {
+			x < 100
+		}`)
+
+	duplicates := []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+		tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+	}
+
+	check := func(t *testing.T, s *Server, contextualTuples []*openfgav1.TupleKey) (*openfgav1.CheckResponse, error) {
+		createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+			Name: "openfga-test",
+		})
+		require.NoError(t, err)
+
+		writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+			StoreId:         createStoreResp.GetId(),
+			SchemaVersion:   model.GetSchemaVersion(),
+			TypeDefinitions: model.GetTypeDefinitions(),
+			Conditions:      model.GetConditions(),
+		})
+		require.NoError(t, err)
+
+		return s.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:              createStoreResp.GetId(),
+			AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+			TupleKey:             tuple.NewCheckRequestTupleKey("document:1", "viewer", "user:jon"),
+			ContextualTuples:     &openfgav1.ContextualTupleKeys{TupleKeys: contextualTuples},
+		})
+	}
+
+	t.Run("dedup_by_default", func(t *testing.T) {
+		s := MustNewServerWithOpts(WithDatastore(ds))
+		t.Cleanup(s.Close)
+
+		resp, err := check(t, s, duplicates)
+		require.NoError(t, err)
+		require.True(t, resp.GetAllowed())
+	})
+
+	t.Run("reject_in_strict_mode", func(t *testing.T) {
+		s := MustNewServerWithOpts(
+			WithDatastore(ds),
+			WithRejectDuplicateContextualTuples(true),
+		)
+		t.Cleanup(s.Close)
+
+		_, err := check(t, s, duplicates)
+		require.Error(t, err)
+		require.Equal(t, codes.Code(openfgav1.ErrorCode_duplicate_contextual_tuple), status.Code(err))
+	})
+
+	t.Run("tuples_with_different_condition_contexts_are_not_duplicates", func(t *testing.T) {
+		s := MustNewServerWithOpts(
+			WithDatastore(ds),
+			WithRejectDuplicateContextualTuples(true),
+		)
+		t.Cleanup(s.Close)
+
+		_, err := check(t, s, []*openfgav1.TupleKey{
+			tuple.NewTupleKeyWithCondition("document:1", "viewer", "user:jon", "x_less_than",
+				testutils.MustNewStruct(t, map[string]interface{}{"x": 200})),
+			tuple.NewTupleKeyWithCondition("document:1", "viewer", "user:jon", "x_less_than",
+				testutils.MustNewStruct(t, map[string]interface{}{"x": 10})),
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("canonical_key_includes_condition", func(t *testing.T) {
+		deduped, err := dedupContextualTuples([]*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+			tuple.NewTupleKeyWithCondition("document:1", "viewer", "user:jon", "x_less_than",
+				testutils.MustNewStruct(t, map[string]interface{}{"x": 10, "y": "a"})),
+			tuple.NewTupleKeyWithCondition("document:1", "viewer", "user:jon", "x_less_than",
+				testutils.MustNewStruct(t, map[string]interface{}{"y": "a", "x": 10})),
+			tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+		}, false)
+		require.NoError(t, err)
+		require.Len(t, deduped, 2)
+		require.Nil(t, deduped[0].GetCondition())
+		require.Equal(t, "x_less_than", deduped[1].GetCondition().GetName())
+	})
+}
+
+func TestCheckWithMaxIndirectionDepth(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	s := MustNewServerWithOpts(WithDatastore(ds))
+	t.Cleanup(s.Close)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+		Name: "openfga-test",
+	})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type group
+			relations
+				define member: [user, group#member]
+
+		type document
+			relations
+				define viewer: [user, group#member]`)
+
+	writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:         storeID,
+		SchemaVersion:   model.GetSchemaVersion(),
+		TypeDefinitions: model.GetTypeDefinitions(),
+	})
+	require.NoError(t, err)
+
+	_, err = s.Write(ctx, &openfgav1.WriteRequest{
+		StoreId: storeID,
+		Writes: &openfgav1.WriteRequestWrites{TupleKeys: []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:1", "viewer", "user:direct"),
+			tuple.NewTupleKey("document:1", "viewer", "group:eng#member"),
+			tuple.NewTupleKey("group:eng", "member", "user:one-level"),
+			tuple.NewTupleKey("group:eng", "member", "group:backend#member"),
+			tuple.NewTupleKey("group:backend", "member", "user:two-levels"),
+		}},
+	})
+	require.NoError(t, err)
+
+	check := func(user string, opts ...CheckOption) bool {
+		resp, err := s.CheckWithOptions(ctx, &openfgav1.CheckRequest{
+			StoreId:              storeID,
+			AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+			TupleKey:             tuple.NewCheckRequestTupleKey("document:1", "viewer", user),
+		}, opts...)
+		require.NoError(t, err)
+
+		return resp.GetAllowed()
+	}
+
+	require.True(t, check("user:direct", WithMaxIndirectionDepth(1)))
+	require.True(t, check("user:one-level", WithMaxIndirectionDepth(1)))
+	require.False(t, check("user:two-levels", WithMaxIndirectionDepth(1)))
+
+	// the limit only applies to the request it was set on
+	require.True(t, check("user:two-levels"))
+}
+
+func TestCheckWithResolutionTrace(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	s := MustNewServerWithOpts(WithDatastore(ds))
+	t.Cleanup(s.Close)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+		Name: "openfga-test",
+	})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type group
+			relations
+				define member: [user, group#member]
+
+		type document
+			relations
+				define blocked: [user]
+				define editor: [user, group#member]
+				define viewer: editor but not blocked`)
+
+	writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:         storeID,
+		SchemaVersion:   model.GetSchemaVersion(),
+		TypeDefinitions: model.GetTypeDefinitions(),
+	})
+	require.NoError(t, err)
+
+	_, err = s.Write(ctx, &openfgav1.WriteRequest{
+		StoreId: storeID,
+		Writes: &openfgav1.WriteRequestWrites{TupleKeys: []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:1", "editor", "group:eng#member"),
+			tuple.NewTupleKey("group:eng", "member", "user:jon"),
+			tuple.NewTupleKey("group:eng", "member", "user:bob"),
+			tuple.NewTupleKey("document:1", "blocked", "user:bob"),
+		}},
+	})
+	require.NoError(t, err)
+
+	check := func(user string, opts ...CheckOption) bool {
+		resp, err := s.CheckWithOptions(ctx, &openfgav1.CheckRequest{
+			StoreId:              storeID,
+			AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+			TupleKey:             tuple.NewCheckRequestTupleKey("document:1", "viewer", user),
+		}, opts...)
+		require.NoError(t, err)
+
+		return resp.GetAllowed()
+	}
+
+	t.Run("allowed", func(t *testing.T) {
+		var trace CheckTrace
+		require.True(t, check("user:jon", WithResolutionTrace(&trace)))
+		require.Equal(t, []string{"document:1#viewer", "document:1#editor", "group:eng#member"}, trace.Path)
+		require.NotZero(t, trace.DispatchCount)
+		require.NotZero(t, trace.DatastoreQueryCount)
+	})
+
+	t.Run("denied_by_exclusion", func(t *testing.T) {
+		var trace CheckTrace
+		require.False(t, check("user:bob", WithResolutionTrace(&trace)))
+		require.Equal(t, []string{"document:1#viewer", "but not document:1#blocked"}, trace.Path)
+	})
+
+	t.Run("untraced", func(t *testing.T) {
+		require.True(t, check("user:jon"))
+	})
+}
+
+func TestCheckWithTupleOverlay(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+		WithCheckQueryCacheEnabled(true),
+	)
+	t.Cleanup(s.Close)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+		Name: "openfga-test",
+	})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type document
+			relations
+				define viewer: [user]`)
+
+	writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:         storeID,
+		SchemaVersion:   model.GetSchemaVersion(),
+		TypeDefinitions: model.GetTypeDefinitions(),
+	})
+	require.NoError(t, err)
+
+	_, err = s.Write(ctx, &openfgav1.WriteRequest{
+		StoreId: storeID,
+		Writes: &openfgav1.WriteRequestWrites{TupleKeys: []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+		}},
+	})
+	require.NoError(t, err)
+
+	check := func(user string, opts ...CheckOption) bool {
+		resp, err := s.CheckWithOptions(ctx, &openfgav1.CheckRequest{
+			StoreId:              storeID,
+			AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+			TupleKey:             tuple.NewCheckRequestTupleKey("document:1", "viewer", user),
+		}, opts...)
+		require.NoError(t, err)
+
+		return resp.GetAllowed()
+	}
+
+	t.Run("added_tuples", func(t *testing.T) {
+		overlay := storagewrappers.TupleOverlay{
+			AddedTuples: []*openfgav1.TupleKey{tuple.NewTupleKey("document:1", "viewer", "user:bob")},
+		}
+		require.True(t, check("user:bob", WithTupleOverlay(overlay)))
+
+		// the result of the what-if Check is not cached for the Checks without the overlay
+		require.False(t, check("user:bob"))
+	})
+
+	t.Run("removed_tuples", func(t *testing.T) {
+		require.True(t, check("user:jon"))
+
+		overlay := storagewrappers.TupleOverlay{
+			RemovedTuples: []*openfgav1.TupleKeyWithoutCondition{
+				tuple.TupleKeyToTupleKeyWithoutCondition(tuple.NewTupleKey("document:1", "viewer", "user:jon")),
+			},
+		}
+		require.False(t, check("user:jon", WithTupleOverlay(overlay)))
+	})
+
+	t.Run("invalid_added_tuple", func(t *testing.T) {
+		_, err := s.CheckWithOptions(ctx, &openfgav1.CheckRequest{
+			StoreId:              storeID,
+			AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+			TupleKey:             tuple.NewCheckRequestTupleKey("document:1", "viewer", "user:bob"),
+		}, WithTupleOverlay(storagewrappers.TupleOverlay{
+			AddedTuples: []*openfgav1.TupleKey{tuple.NewTupleKey("document:1", "undefined", "user:bob")},
+		}))
+		require.ErrorContains(t, err, "relation 'document#undefined' not found")
+	})
+}
+
+func TestDepthLimitBehavior(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type group
+			relations
+				define member: [user, group#member]`)
+
+	check := func(t *testing.T, opts ...OpenFGAServiceV1Option) (*openfgav1.CheckResponse, map[string]string, error) {
+		transport := &headerRecordingTransport{headers: map[string]string{}}
+
+		s := MustNewServerWithOpts(append([]OpenFGAServiceV1Option{
+			WithDatastore(ds),
+			WithTransport(transport),
+			WithResolveNodeLimit(2),
+		}, opts...)...)
+		t.Cleanup(s.Close)
+
+		createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+			Name: "openfga-test",
+		})
+		require.NoError(t, err)
+		storeID := createStoreResp.GetId()
+
+		writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+			StoreId:         storeID,
+			SchemaVersion:   model.GetSchemaVersion(),
+			TypeDefinitions: model.GetTypeDefinitions(),
+		})
+		require.NoError(t, err)
+
+		// user:jon is a member of group:1 through three levels of nested groups
+		_, err = s.Write(ctx, &openfgav1.WriteRequest{
+			StoreId: storeID,
+			Writes: &openfgav1.WriteRequestWrites{TupleKeys: []*openfgav1.TupleKey{
+				tuple.NewTupleKey("group:1", "member", "group:2#member"),
+				tuple.NewTupleKey("group:2", "member", "group:3#member"),
+				tuple.NewTupleKey("group:3", "member", "group:4#member"),
+				tuple.NewTupleKey("group:4", "member", "user:jon"),
+			}},
+		})
+		require.NoError(t, err)
+
+		resp, err := s.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:              storeID,
+			AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+			TupleKey:             tuple.NewCheckRequestTupleKey("group:1", "member", "user:jon"),
+		})
+
+		return resp, transport.headers, err
+	}
+
+	t.Run("error_by_default", func(t *testing.T) {
+		_, headers, err := check(t)
+		require.ErrorIs(t, err, serverErrors.AuthorizationModelResolutionTooComplex)
+		require.NotContains(t, headers, IncompleteDecisionHeader)
+	})
+
+	t.Run("deny_with_indicator", func(t *testing.T) {
+		resp, headers, err := check(t, WithDepthLimitBehavior(DepthLimitDenyWithIndicator))
+		require.NoError(t, err)
+		require.False(t, resp.GetAllowed())
+		require.Equal(t, "true", headers[IncompleteDecisionHeader])
+	})
+}
+
+func TestCheckArchivedModel(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type document
+			relations
+				define viewer: [user]`)
+
+	setup := func(t *testing.T, s *Server) (string, string, string) {
+		createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+			Name: "openfga-test",
+		})
+		require.NoError(t, err)
+		storeID := createStoreResp.GetId()
+
+		var modelIDs []string
+		for i := 0; i < 2; i++ {
+			writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+				StoreId:         storeID,
+				SchemaVersion:   model.GetSchemaVersion(),
+				TypeDefinitions: model.GetTypeDefinitions(),
+			})
+			require.NoError(t, err)
+			modelIDs = append(modelIDs, writeModelResp.GetAuthorizationModelId())
+		}
+
+		_, err = s.Write(ctx, &openfgav1.WriteRequest{
+			StoreId: storeID,
+			Writes: &openfgav1.WriteRequestWrites{TupleKeys: []*openfgav1.TupleKey{
+				tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+			}},
+		})
+		require.NoError(t, err)
+
+		err = s.ArchiveAuthorizationModel(ctx, storeID, modelIDs[0])
+		require.NoError(t, err)
+
+		return storeID, modelIDs[0], modelIDs[1]
+	}
+
+	check := func(s *Server, storeID, modelID string) (*openfgav1.CheckResponse, error) {
+		return s.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:              storeID,
+			AuthorizationModelId: modelID,
+			TupleKey:             tuple.NewCheckRequestTupleKey("document:1", "viewer", "user:jon"),
+		})
+	}
+
+	t.Run("error_by_default", func(t *testing.T) {
+		s := MustNewServerWithOpts(WithDatastore(ds))
+		t.Cleanup(s.Close)
+
+		storeID, archivedModelID, modelID := setup(t, s)
+
+		_, err := check(s, storeID, archivedModelID)
+		require.ErrorIs(t, err, serverErrors.AuthorizationModelArchived)
+
+		resp, err := check(s, storeID, modelID)
+		require.NoError(t, err)
+		require.True(t, resp.GetAllowed())
+	})
+
+	t.Run("resolve_against_archived_model", func(t *testing.T) {
+		s := MustNewServerWithOpts(
+			WithDatastore(ds),
+			WithArchivedModelBehavior(ArchivedModelResolve),
+		)
+		t.Cleanup(s.Close)
+
+		storeID, archivedModelID, _ := setup(t, s)
+
+		resp, err := check(s, storeID, archivedModelID)
+		require.NoError(t, err)
+		require.True(t, resp.GetAllowed())
+	})
+
+	t.Run("archive_unknown_model", func(t *testing.T) {
+		s := MustNewServerWithOpts(WithDatastore(ds))
+		t.Cleanup(s.Close)
+
+		storeID, _, _ := setup(t, s)
+
+		err := s.ArchiveAuthorizationModel(ctx, storeID, ulid.Make().String())
+		require.Equal(t, codes.Code(openfgav1.ErrorCode_authorization_model_not_found), status.Code(err))
+	})
+}
+
+func TestUsersetClosureSize(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+	)
+	t.Cleanup(s.Close)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+		Name: "openfga-test",
+	})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type group
+			relations
+				define member: [user, group#member]`)
+
+	_, err = s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:         storeID,
+		SchemaVersion:   model.GetSchemaVersion(),
+		TypeDefinitions: model.GetTypeDefinitions(),
+	})
+	require.NoError(t, err)
+
+	_, err = s.Write(ctx, &openfgav1.WriteRequest{
+		StoreId: storeID,
+		Writes: &openfgav1.WriteRequestWrites{
+			TupleKeys: []*openfgav1.TupleKey{
+				tuple.NewTupleKey("group:all", "member", "group:eng#member"),
+				tuple.NewTupleKey("group:all", "member", "user:jon"),
+				tuple.NewTupleKey("group:eng", "member", "user:jon"),
+				tuple.NewTupleKey("group:eng", "member", "user:maria"),
+				tuple.NewTupleKey("group:eng", "member", "user:anne"),
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	size, truncated, err := s.UsersetClosureSize(ctx, storeID, "", "group:all", "member", 10)
+	require.NoError(t, err)
+	require.False(t, truncated)
+	require.Equal(t, uint32(3), size)
+
+	size, truncated, err = s.UsersetClosureSize(ctx, storeID, "", "group:all", "member", 2)
+	require.NoError(t, err)
+	require.True(t, truncated)
+	require.Equal(t, uint32(2), size)
+
+	_, _, err = s.UsersetClosureSize(ctx, storeID, "", "group:all", "undefined", 10)
+	require.ErrorContains(t, err, "relation 'group#undefined' not found")
+}
+
+type recordingReadPatternSink struct {
+	mu    sync.Mutex
+	reads map[string][]storagewrappers.ReadCall
+}
+
+func (r *recordingReadPatternSink) RecordCheckReads(_ context.Context, _ string, tupleKey *openfgav1.CheckRequestTupleKey, reads []storagewrappers.ReadCall) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reads[tuple.TupleKeyToString(tupleKey)] = reads
+}
+
+func TestCheckReadPatternSink(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	sink := &recordingReadPatternSink{reads: map[string][]storagewrappers.ReadCall{}}
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+		WithCheckReadPatternSink(sink),
+	)
+	t.Cleanup(s.Close)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+		Name: "openfga-test",
+	})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type group
+			relations
+				define member: [user]
+
+		type document
+			relations
+				define viewer: [user, group#member]`)
+
+	writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:         storeID,
+		SchemaVersion:   model.GetSchemaVersion(),
+		TypeDefinitions: model.GetTypeDefinitions(),
+	})
+	require.NoError(t, err)
+
+	_, err = s.Write(ctx, &openfgav1.WriteRequest{
+		StoreId: storeID,
+		Writes: &openfgav1.WriteRequestWrites{TupleKeys: []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:1", "viewer", "group:eng#member"),
+			tuple.NewTupleKey("group:eng", "member", "user:jon"),
+		}},
+	})
+	require.NoError(t, err)
+
+	// a denied Check resolves every branch, so all of its reads are made before it returns
+	tk := tuple.NewCheckRequestTupleKey("document:1", "viewer", "user:bob")
+	resp, err := s.Check(ctx, &openfgav1.CheckRequest{
+		StoreId:              storeID,
+		AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+		TupleKey:             tk,
+	})
+	require.NoError(t, err)
+	require.False(t, resp.GetAllowed())
+
+	// the direct and the userset reads are made concurrently, so their order is not asserted
+	require.ElementsMatch(t, []storagewrappers.ReadCall{
+		{Method: "ReadUserTuple", Filter: "document:1#viewer@user:bob"},
+		{Method: "ReadUsersetTuples", Filter: "document:1#viewer@group#member"},
+		{Method: "ReadUserTuple", Filter: "group:eng#member@user:bob"},
+	}, sink.reads[tuple.TupleKeyToString(tk)])
+}
+
+func TestCheckReadDeduplication(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	createStoreResp, err := ds.CreateStore(ctx, &openfgav1.Store{Id: ulid.Make().String(), Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	// both the viewer and the editor relations resolve document:1#owner
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type document
+			relations
+				define owner: [user]
+				define editor: [user] or owner
+				define viewer: [user] or owner or editor`)
+	require.NoError(t, ds.WriteAuthorizationModel(ctx, storeID, model))
+
+	reads := func(enabled bool) []storagewrappers.ReadCall {
+		sink := &recordingReadPatternSink{reads: map[string][]storagewrappers.ReadCall{}}
+		s := MustNewServerWithOpts(
+			WithDatastore(ds),
+			WithCheckReadPatternSink(sink),
+			WithCheckReadDeduplicationEnabled(enabled),
+		)
+		t.Cleanup(s.Close)
+
+		// a denied Check resolves every branch
+		tk := tuple.NewCheckRequestTupleKey("document:1", "viewer", "user:bob")
+		resp, err := s.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:              storeID,
+			AuthorizationModelId: model.GetId(),
+			TupleKey:             tk,
+		})
+		require.NoError(t, err)
+		require.False(t, resp.GetAllowed())
+
+		return sink.reads[tuple.TupleKeyToString(tk)]
+	}
+
+	require.ElementsMatch(t, []storagewrappers.ReadCall{
+		{Method: "ReadUserTuple", Filter: "document:1#viewer@user:bob"},
+		{Method: "ReadUserTuple", Filter: "document:1#owner@user:bob"},
+		{Method: "ReadUserTuple", Filter: "document:1#editor@user:bob"},
+		{Method: "ReadUserTuple", Filter: "document:1#owner@user:bob"},
+	}, reads(false))
+
+	require.ElementsMatch(t, []storagewrappers.ReadCall{
+		{Method: "ReadUserTuple", Filter: "document:1#viewer@user:bob"},
+		{Method: "ReadUserTuple", Filter: "document:1#owner@user:bob"},
+		{Method: "ReadUserTuple", Filter: "document:1#editor@user:bob"},
+	}, reads(true))
+}
+
+func TestCheckWithModelFragments(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+	)
+	t.Cleanup(s.Close)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+		Name: "openfga-test",
+	})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	coreModule := language.MustTransformDSLToProto(`
+		model
+			schema 1.1
+
+		type user
+
+		type group
+			relations
+				define member: [user]
+
+		type document
+			relations
+				define owner: [user]`)
+
+	sharingModule := language.MustTransformDSLToProto(`
+		model
+			schema 1.1
+
+		type document
+			relations
+				define viewer: [group#member] or owner`)
+
+	writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:       storeID,
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: language.MustTransformDSLToProto(`
+			model
+				schema 1.1
+
+			type user
+
+			type group
+				relations
+					define member: [user]
+
+			type document
+				relations
+					define owner: [user]
+					define viewer: [group#member]`).GetTypeDefinitions(),
+	})
+	require.NoError(t, err)
+
+	_, err = s.Write(ctx, &openfgav1.WriteRequest{
+		StoreId:              storeID,
+		AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+		Writes: &openfgav1.WriteRequestWrites{TupleKeys: []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:1", "owner", "user:anne"),
+			tuple.NewTupleKey("document:1", "viewer", "group:eng#member"),
+			tuple.NewTupleKey("group:eng", "member", "user:jon"),
+		}},
+	})
+	require.NoError(t, err)
+
+	t.Run("resolves_against_the_merged_modules", func(t *testing.T) {
+		for _, user := range []string{"user:anne", "user:jon"} {
+			resp, err := s.CheckWithOptions(ctx, &openfgav1.CheckRequest{
+				StoreId:  storeID,
+				TupleKey: tuple.NewCheckRequestTupleKey("document:1", "viewer", user),
+			}, WithModelFragments(coreModule, sharingModule))
+			require.NoError(t, err)
+			require.True(t, resp.GetAllowed(), user)
+		}
+
+		// the owner is not a viewer in the model of the store
+		resp, err := s.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:  storeID,
+			TupleKey: tuple.NewCheckRequestTupleKey("document:1", "viewer", "user:anne"),
+		})
+		require.NoError(t, err)
+		require.False(t, resp.GetAllowed())
+	})
+
+	t.Run("conflicting_modules", func(t *testing.T) {
+		conflictingModule := language.MustTransformDSLToProto(`
+			model
+				schema 1.1
+
+			type document
+				relations
+					define owner: [user, group#member]`)
+
+		_, err := s.CheckWithOptions(ctx, &openfgav1.CheckRequest{
+			StoreId:  storeID,
+			TupleKey: tuple.NewCheckRequestTupleKey("document:1", "owner", "user:anne"),
+		}, WithModelFragments(coreModule, conflictingModule))
+		require.Equal(t, codes.Code(openfgav1.ErrorCode_validation_error), status.Code(err))
+		require.ErrorContains(t, err, typesystem.ErrModelMergeConflict.Error())
+	})
+}
+
+func TestCheckWithResolverChain(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	// the controller verifies the chains are closed, after the server is
+	mockController := gomock.NewController(t)
+
+	// each chain resolves every Check with a different outcome, to tell which one handled it
+	chains := map[string]bool{"experimental": true, "control": false}
+	opts := []OpenFGAServiceV1Option{WithDatastore(ds)}
+	for name, allowed := range chains {
+		chain := graph.NewMockCheckResolver(mockController)
+		chain.EXPECT().ResolveCheck(gomock.Any(), gomock.Any()).Times(2).Return(&graph.ResolveCheckResponse{
+			Allowed:            allowed,
+			ResolutionMetadata: &graph.ResolveCheckResponseMetadata{},
+		}, nil)
+		chain.EXPECT().Close().Times(1)
+
+		opts = append(opts, WithNamedCheckResolverChain(name, chain))
+	}
+
+	s := MustNewServerWithOpts(opts...)
+	t.Cleanup(s.Close)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+		Name: "openfga-test",
+	})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type document
+			relations
+				define viewer: [user]`)
+
+	writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:         storeID,
+		SchemaVersion:   model.GetSchemaVersion(),
+		TypeDefinitions: model.GetTypeDefinitions(),
+	})
+	require.NoError(t, err)
+
+	req := &openfgav1.CheckRequest{
+		StoreId:              storeID,
+		AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+		TupleKey:             tuple.NewCheckRequestTupleKey("document:1", "viewer", "user:jon"),
+	}
+
+	for i := 0; i < 2; i++ {
+		for name, allowed := range chains {
+			checkResp, err := s.CheckWithOptions(ctx, req, WithCheckResolverChain(name))
+			require.NoError(t, err)
+			require.Equal(t, allowed, checkResp.GetAllowed(), name)
+		}
+	}
+
+	// the default chain resolves the Checks that select none
+	checkResp, err := s.Check(ctx, req)
+	require.NoError(t, err)
+	require.False(t, checkResp.GetAllowed())
+
+	_, err = s.CheckWithOptions(ctx, req, WithCheckResolverChain("undefined"))
+	require.ErrorContains(t, err, "check resolver chain 'undefined' is not registered")
+
+	e, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.Code(openfgav1.ErrorCode_validation_error), e.Code())
+}
+
+func TestCheckDuringAtomicBulkDelete(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	ds := memory.New(memory.WithMaxTuplesPerWrite(10), memory.WithAtomicBulkDeletes(true))
+	t.Cleanup(ds.Close)
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+		WithCheckTupleSnapshots(true),
+	)
+	t.Cleanup(s.Close)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+		Name: "openfga-test",
+	})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type document
+			relations
+				define blocked: [user]
+				define viewer: [user] but not blocked`)
+
+	writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:         storeID,
+		SchemaVersion:   model.GetSchemaVersion(),
+		TypeDefinitions: model.GetTypeDefinitions(),
+	})
+	require.NoError(t, err)
+
+	// user:jon is a blocked viewer of every document, and is denied before and after deleting his tuples.
+	// The blocked tuples are written first, so that a partially applied delete would remove them before
+	// the viewer ones, and allow user:jon.
+	const documents = 1000
+	var writes []*openfgav1.TupleKey
+	for i := 0; i < documents; i++ {
+		writes = append(writes, tuple.NewTupleKey(fmt.Sprintf("document:%d", i), "blocked", "user:jon"))
+	}
+	for i := 0; i < documents; i++ {
+		writes = append(writes, tuple.NewTupleKey(fmt.Sprintf("document:%d", i), "viewer", "user:jon"))
+	}
+	require.NoError(t, ds.Write(ctx, storeID, nil, writes))
+
+	done := make(chan struct{})
+	var started sync.WaitGroup
+	var checks errgroup.Group
+	for w := 0; w < 4; w++ {
+		started.Add(1)
+		checks.Go(func() error {
+			var once sync.Once
+			defer once.Do(started.Done)
+			for i := 0; ; i++ {
+				select {
+				case <-done:
+					return nil
+				default:
+				}
+
+				// the blocked tuples of the first documents are the first ones deleted
+				object := fmt.Sprintf("document:%d", i%10)
+				resp, err := s.Check(ctx, &openfgav1.CheckRequest{
+					StoreId:              storeID,
+					AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+					TupleKey:             tuple.NewCheckRequestTupleKey(object, "viewer", "user:jon"),
+				})
+				if err != nil {
+					return err
+				}
+				if resp.GetAllowed() {
+					return fmt.Errorf("%s allowed during the delete", object)
+				}
+				once.Do(started.Done)
+			}
+		})
+	}
+	started.Wait()
+
+	deleted, err := ds.(storage.BulkTupleDeleter).DeleteTuples(ctx, storeID, tuple.NewTupleKey("document:", "", "user:jon"))
+	close(done)
+	require.NoError(t, err)
+	require.Equal(t, 2*documents, deleted)
+	require.NoError(t, checks.Wait())
+}
+
+func TestCheckTupleSnapshots(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	t.Run("requires_a_datastore_taking_snapshots", func(t *testing.T) {
+		mockController := gomock.NewController(t)
+		defer mockController.Finish()
+
+		_, err := NewServerWithOpts(
+			WithDatastore(mockstorage.NewMockOpenFGADatastore(mockController)),
+			WithCheckTupleSnapshots(true),
+		)
+		require.ErrorContains(t, err, "check tuple snapshots require a datastore that can take snapshots of the tuples")
+	})
+
+	t.Run("requires_atomic_bulk_deletes", func(t *testing.T) {
+		ctx := context.Background()
+
+		ds := memory.New()
+		t.Cleanup(ds.Close)
+
+		s := MustNewServerWithOpts(
+			WithDatastore(ds),
+			WithCheckTupleSnapshots(true),
+		)
+		t.Cleanup(s.Close)
+
+		createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+			Name: "openfga-test",
+		})
+		require.NoError(t, err)
+
+		model := testutils.MustTransformDSLToProtoWithID(`
+			model
+				schema 1.1
+
+			type user
+
+			type document
+				relations
+					define viewer: [user]`)
+
+		writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+			StoreId:         createStoreResp.GetId(),
+			SchemaVersion:   model.GetSchemaVersion(),
+			TypeDefinitions: model.GetTypeDefinitions(),
+		})
+		require.NoError(t, err)
+
+		_, err = s.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:              createStoreResp.GetId(),
+			AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+			TupleKey:             tuple.NewCheckRequestTupleKey("document:1", "viewer", "user:jon"),
+		})
+		require.ErrorIs(t, err, serverErrors.NewInternalError("", nil))
+	})
+}
+
+func TestStoreResolveNodeLimit(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type group
+			relations
+				define member: [user, group#member]`)
+
+	storeWithDefault := ulid.Make().String()
+	storeWithoutDefault := ulid.Make().String()
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+		WithStoreResolveNodeLimit(storeWithDefault, 2),
+	)
+	t.Cleanup(s.Close)
+
+	for _, storeID := range []string{storeWithDefault, storeWithoutDefault} {
+		_, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+			StoreId:         storeID,
+			SchemaVersion:   model.GetSchemaVersion(),
+			TypeDefinitions: model.GetTypeDefinitions(),
+		})
+		require.NoError(t, err)
+
+		_, err = s.Write(ctx, &openfgav1.WriteRequest{
+			StoreId: storeID,
+			Writes: &openfgav1.WriteRequestWrites{
+				TupleKeys: []*openfgav1.TupleKey{
+					tuple.NewTupleKey("group:1", "member", "group:2#member"),
+					tuple.NewTupleKey("group:2", "member", "group:3#member"),
+					tuple.NewTupleKey("group:3", "member", "group:4#member"),
+					tuple.NewTupleKey("group:4", "member", "user:jon"),
+				},
+			},
+		})
+		require.NoError(t, err)
+	}
+
+	check := func(ctx context.Context, storeID string) (*openfgav1.CheckResponse, error) {
+		return s.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:  storeID,
+			TupleKey: tuple.NewCheckRequestTupleKey("group:1", "member", "user:jon"),
+		})
+	}
+
+	t.Run("store_default_applies_when_request_omits_limit", func(t *testing.T) {
+		_, err := check(ctx, storeWithDefault)
+		require.ErrorIs(t, err, serverErrors.AuthorizationModelResolutionTooComplex)
+	})
+
+	t.Run("server_default_applies_to_other_stores", func(t *testing.T) {
+		resp, err := check(ctx, storeWithoutDefault)
+		require.NoError(t, err)
+		require.True(t, resp.GetAllowed())
+	})
+
+	t.Run("request_limit_overrides_store_default", func(t *testing.T) {
+		resp, err := check(ContextWithResolveNodeLimit(ctx, 25), storeWithDefault)
+		require.NoError(t, err)
+		require.True(t, resp.GetAllowed())
+
+		_, err = check(ContextWithResolveNodeLimit(ctx, 2), storeWithoutDefault)
+		require.ErrorIs(t, err, serverErrors.AuthorizationModelResolutionTooComplex)
+	})
+
+	t.Run("request_limit_is_clamped_to_server_limit", func(t *testing.T) {
+		s := MustNewServerWithOpts(
+			WithDatastore(ds),
+			WithResolveNodeLimit(3),
+		)
+		t.Cleanup(s.Close)
+
+		_, err := s.Check(ContextWithResolveNodeLimit(ctx, 25), &openfgav1.CheckRequest{
+			StoreId:  storeWithoutDefault,
+			TupleKey: tuple.NewCheckRequestTupleKey("group:1", "member", "user:jon"),
+		})
+		require.ErrorIs(t, err, serverErrors.AuthorizationModelResolutionTooComplex)
+	})
+}
+
+func TestStoreDispatchThrottlingThreshold(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type group
+			relations
+				define member: [user, group#member]`)
+
+	throttledStore := ulid.Make().String()
+	otherStore := ulid.Make().String()
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+		WithDispatchThrottlingCheckResolverEnabled(true),
+		WithDispatchThrottlingCheckResolverFrequency(20*time.Millisecond),
+		WithDispatchThrottlingCheckResolverThreshold(100),
+		WithStoreDispatchThrottlingThreshold(throttledStore, 2),
+	)
+	t.Cleanup(s.Close)
+
+	for _, storeID := range []string{throttledStore, otherStore} {
+		_, err := s.WriteAuthorizationModel(context.Background(), &openfgav1.WriteAuthorizationModelRequest{
+			StoreId:         storeID,
+			SchemaVersion:   model.GetSchemaVersion(),
+			TypeDefinitions: model.GetTypeDefinitions(),
+		})
+		require.NoError(t, err)
+
+		var tuples []*openfgav1.TupleKey
+		for i := 1; i <= 10; i++ {
+			tuples = append(tuples, tuple.NewTupleKey("group:x", "member", fmt.Sprintf("group:%d#member", i)))
+		}
+
+		_, err = s.Write(context.Background(), &openfgav1.WriteRequest{
+			StoreId: storeID,
+			Writes:  &openfgav1.WriteRequestWrites{TupleKeys: tuples},
+		})
+		require.NoError(t, err)
+	}
+
+	// the check dispatches one subproblem per member group, and every dispatch past the threshold
+	// waits for the next tick of the throttler
+	check := func(ctx context.Context, storeID string) error {
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+
+		_, err := s.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:  storeID,
+			TupleKey: tuple.NewCheckRequestTupleKey("group:x", "member", "user:anne"),
+		})
+		return err
+	}
+
+	require.ErrorIs(t, check(context.Background(), throttledStore), serverErrors.ThrottledTimeout)
+	require.NoError(t, check(context.Background(), otherStore))
+
+	// a lower threshold of the request still applies to the other stores
+	require.ErrorIs(t, check(dispatch.ContextWithThrottlingThreshold(context.Background(), 2), otherStore), serverErrors.ThrottledTimeout)
+}
+
+func TestCheckWithRelationAliases(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type document
+			relations
+				define owner: [user]
+				define viewer: [user] or owner`)
+
+	storeWithAliases := ulid.Make().String()
+	storeWithoutAliases := ulid.Make().String()
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+		WithStoreRelationAliases(storeWithAliases, map[string]string{
+			"document#reader": "viewer",
+		}),
+	)
+	t.Cleanup(s.Close)
+
+	for _, storeID := range []string{storeWithAliases, storeWithoutAliases} {
+		_, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+			StoreId:         storeID,
+			SchemaVersion:   model.GetSchemaVersion(),
+			TypeDefinitions: model.GetTypeDefinitions(),
+		})
+		require.NoError(t, err)
+
+		_, err = s.Write(ctx, &openfgav1.WriteRequest{
+			StoreId: storeID,
+			Writes: &openfgav1.WriteRequestWrites{
+				TupleKeys: []*openfgav1.TupleKey{
+					tuple.NewTupleKey("document:1", "owner", "user:jon"),
+				},
+			},
+		})
+		require.NoError(t, err)
+	}
+
+	check := func(ctx context.Context, storeID, relation string) (*openfgav1.CheckResponse, error) {
+		return s.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:  storeID,
+			TupleKey: tuple.NewCheckRequestTupleKey("document:1", relation, "user:jon"),
+		})
+	}
+
+	t.Run("store_alias_resolves_using_new_relation", func(t *testing.T) {
+		resp, err := check(ctx, storeWithAliases, "reader")
+		require.NoError(t, err)
+		require.True(t, resp.GetAllowed())
+	})
+
+	t.Run("unaliased_relation_is_undefined", func(t *testing.T) {
+		_, err := check(ctx, storeWithoutAliases, "reader")
+		require.Error(t, err)
+		require.Equal(t, codes.Code(openfgav1.ErrorCode_validation_error), status.Code(err))
+	})
+
+	t.Run("request_alias_takes_precedence", func(t *testing.T) {
+		resp, err := check(ContextWithRelationAliases(ctx, map[string]string{"document#reader": "owner"}), storeWithoutAliases, "reader")
+		require.NoError(t, err)
+		require.True(t, resp.GetAllowed())
+
+		_, err = check(ContextWithRelationAliases(ctx, map[string]string{"document#reader": "editor"}), storeWithAliases, "reader")
+		require.Error(t, err)
+		require.Equal(t, codes.Code(openfgav1.ErrorCode_validation_error), status.Code(err))
+	})
+}
+
+func TestWriteAssertionModelDSError(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	storeID := ulid.Make().String()
+	modelID := ulid.Make().String()
+
+	typedefs := language.MustTransformDSLToProto(`
+		model
+			schema 1.1
+
+		type user
+
+		type repo
+			relations
+				define reader: [user]`).GetTypeDefinitions()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mockDSOldSchema := mockstorage.NewMockOpenFGADatastore(mockController)
+
+	mockDSOldSchema.EXPECT().
+		ReadAuthorizationModel(gomock.Any(), storeID, modelID).
+		AnyTimes().
+		Return(&openfgav1.AuthorizationModel{
+			SchemaVersion:   typesystem.SchemaVersion1_0,
+			TypeDefinitions: typedefs,
+		}, nil)
+
+	mockDSBadReadAuthModel := mockstorage.NewMockOpenFGADatastore(mockController)
+
+	mockDSBadReadAuthModel.EXPECT().
+		ReadAuthorizationModel(gomock.Any(), storeID, modelID).
+		AnyTimes().
+		Return(nil, fmt.Errorf("unable to read"))
+
+	mockDSBadWriteAssertions := mockstorage.NewMockOpenFGADatastore(mockController)
+	mockDSBadWriteAssertions.EXPECT().
+		ReadAuthorizationModel(gomock.Any(), storeID, modelID).
+		AnyTimes().
+		Return(&openfgav1.AuthorizationModel{
+			SchemaVersion:   typesystem.SchemaVersion1_1,
+			TypeDefinitions: typedefs,
+		}, nil)
+	mockDSBadWriteAssertions.EXPECT().
+		WriteAssertions(gomock.Any(), storeID, modelID, gomock.Any()).
+		AnyTimes().
+		Return(fmt.Errorf("unable to write"))
+
+	tests := []struct {
+		name          string
+		assertions    []*openfgav1.Assertion
+		mockDatastore *mockstorage.MockOpenFGADatastore
+		expectedError error
+	}{
+		{
+			name:          "unsupported_schema",
+			assertions:    []*openfgav1.Assertion{},
+			mockDatastore: mockDSOldSchema,
+			expectedError: serverErrors.ValidationError(
+				fmt.Errorf("invalid schema version"),
+			),
+		},
+		{
+			name:          "failed_to_read",
+			assertions:    []*openfgav1.Assertion{},
+			mockDatastore: mockDSBadReadAuthModel,
+			expectedError: serverErrors.NewInternalError(
+				"", fmt.Errorf("unable to read"),
+			),
+		},
+		{
+			name:          "failed_to_write",
+			assertions:    []*openfgav1.Assertion{},
+			mockDatastore: mockDSBadWriteAssertions,
+			expectedError: serverErrors.NewInternalError(
+				"", fmt.Errorf("unable to write"),
+			),
+		},
+	}
+
+	for _, curTest := range tests {
+		t.Run(curTest.name, func(t *testing.T) {
+			request := &openfgav1.WriteAssertionsRequest{
+				StoreId:              storeID,
+				Assertions:           curTest.assertions,
+				AuthorizationModelId: modelID,
+			}
+
+			writeAssertionCmd := commands.NewWriteAssertionsCommand(curTest.mockDatastore)
+			_, err := writeAssertionCmd.Execute(ctx, request)
+			require.ErrorIs(t, curTest.expectedError, err)
+		})
+	}
+}
+
+func TestReadAssertionModelDSError(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	storeID := ulid.Make().String()
+	modelID := ulid.Make().String()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mockDSBadReadAssertions := mockstorage.NewMockOpenFGADatastore(mockController)
+	mockDSBadReadAssertions.EXPECT().
+		ReadAssertions(gomock.Any(), storeID, modelID).
+		AnyTimes().
+		Return(nil, fmt.Errorf("unable to read"))
+
+	readAssertionQuery := commands.NewReadAssertionsQuery(mockDSBadReadAssertions)
+	_, err := readAssertionQuery.Execute(ctx, storeID, modelID)
+	expectedError := serverErrors.NewInternalError(
+		"", fmt.Errorf("unable to read"),
+	)
+	require.ErrorIs(t, expectedError, err)
+}
+
+func TestResolveAuthorizationModel(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	t.Run("no_latest_authorization_model_id_found", func(t *testing.T) {
+		store := ulid.Make().String()
+
+		mockController := gomock.NewController(t)
+		defer mockController.Finish()
+
+		mockDatastore := mockstorage.NewMockOpenFGADatastore(mockController)
+		mockDatastore.EXPECT().FindLatestAuthorizationModel(gomock.Any(), store).Return(nil, storage.ErrNotFound)
+
+		s := MustNewServerWithOpts(
+			WithDatastore(mockDatastore),
+		)
+		t.Cleanup(func() {
+			mockDatastore.EXPECT().Close().Times(1)
+			s.Close()
+		})
+
+		expectedError := serverErrors.LatestAuthorizationModelNotFound(store)
+
+		_, err := s.resolveTypesystem(ctx, store, "")
+		require.ErrorIs(t, err, expectedError)
+	})
+
+	t.Run("read_existing_authorization_model", func(t *testing.T) {
+		store := ulid.Make().String()
+		modelID := ulid.Make().String()
+
+		mockController := gomock.NewController(t)
+		defer mockController.Finish()
+
+		mockDatastore := mockstorage.NewMockOpenFGADatastore(mockController)
+		mockDatastore.EXPECT().FindLatestAuthorizationModel(gomock.Any(), store).Return(
+			&openfgav1.AuthorizationModel{
+				Id:            modelID,
+				SchemaVersion: typesystem.SchemaVersion1_1,
+			},
+			nil,
+		)
+
+		s := MustNewServerWithOpts(
+			WithDatastore(mockDatastore),
+		)
+		t.Cleanup(func() {
+			mockDatastore.EXPECT().Close().Times(1)
+			s.Close()
+		})
+
+		typesys, err := s.resolveTypesystem(ctx, store, "")
+		require.NoError(t, err)
+		require.Equal(t, modelID, typesys.GetAuthorizationModelID())
+	})
+
+	t.Run("non-valid_modelID_returns_error", func(t *testing.T) {
+		store := ulid.Make().String()
+		modelID := "foo"
+		want := serverErrors.AuthorizationModelNotFound(modelID)
+
+		mockController := gomock.NewController(t)
+		defer mockController.Finish()
+
+		mockDatastore := mockstorage.NewMockOpenFGADatastore(mockController)
+
+		s := MustNewServerWithOpts(
+			WithDatastore(mockDatastore),
+		)
+		t.Cleanup(func() {
+			mockDatastore.EXPECT().Close().Times(1)
+			s.Close()
+		})
+
+		_, err := s.resolveTypesystem(ctx, store, modelID)
+		require.Equal(t, want, err)
+	})
+}
+
+type mockStreamServer struct {
+	grpc.ServerStream
+	trailer metadata.MD
+}
+
+func NewMockStreamServer() *mockStreamServer {
+	return &mockStreamServer{}
+}
+
+func (m *mockStreamServer) Context() context.Context {
+	return context.Background()
+}
+
+func (m *mockStreamServer) Send(*openfgav1.StreamedListObjectsResponse) error {
+	return nil
+}
+
+func (m *mockStreamServer) SetTrailer(md metadata.MD) {
+	m.trailer = metadata.Join(m.trailer, md)
+}
+
+// This runs ListObjects and StreamedListObjects many times over to ensure no race conditions (see https://github.com/openfga/openfga/pull/762)
+func BenchmarkListObjectsNoRaceCondition(b *testing.B) {
+	b.Cleanup(func() {
+		goleak.VerifyNone(b,
+			// https://github.com/uber-go/goleak/discussions/89
+			goleak.IgnoreTopFunction("testing.(*B).run1"),
+			goleak.IgnoreTopFunction("testing.(*B).doBench"),
+		)
+	})
+	ctx := context.Background()
+	store := ulid.Make().String()
+	modelID := ulid.Make().String()
+
+	mockController := gomock.NewController(b)
+	defer mockController.Finish()
+
+	typedefs := language.MustTransformDSLToProto(`
+		model
+			schema 1.1
+
+		type user
+
+		type repo
+			relations
+				define allowed: [user]
+				define viewer: [user] and allowed`).GetTypeDefinitions()
+
+	mockDatastore := mockstorage.NewMockOpenFGADatastore(mockController)
+
+	mockDatastore.EXPECT().ReadAuthorizationModel(gomock.Any(), store, modelID).AnyTimes().Return(&openfgav1.AuthorizationModel{
+		SchemaVersion:   typesystem.SchemaVersion1_1,
+		TypeDefinitions: typedefs,
+	}, nil)
+	mockDatastore.EXPECT().ReadStartingWithUser(gomock.Any(), store, gomock.Any()).AnyTimes().Return(nil, errors.New("error reading from storage"))
+
+	s := MustNewServerWithOpts(
+		WithDatastore(mockDatastore),
+	)
+	b.Cleanup(func() {
+		mockDatastore.EXPECT().Close().Times(1)
+		s.Close()
+	})
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := s.ListObjects(ctx, &openfgav1.ListObjectsRequest{
+			StoreId:              store,
+			AuthorizationModelId: modelID,
+			Type:                 "repo",
+			Relation:             "viewer",
+			User:                 "user:bob",
+		})
+
+		require.ErrorIs(b, err, serverErrors.NewInternalError("", errors.New("error reading from storage")))
+
+		err = s.StreamedListObjects(&openfgav1.StreamedListObjectsRequest{
+			StoreId:              store,
+			AuthorizationModelId: modelID,
+			Type:                 "repo",
+			Relation:             "viewer",
+			User:                 "user:bob",
+		}, NewMockStreamServer())
+
+		require.ErrorIs(b, err, serverErrors.NewInternalError("", errors.New("error reading from storage")))
+	}
+}
+
+func TestListObjectsEmptyResultCache(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+		WithListObjectsEmptyResultCacheTTL(time.Hour),
+	)
+	t.Cleanup(s.Close)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+		Name: "openfga-test",
+	})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type document
+			relations
+				define viewer: [user]`)
+
+	writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:         storeID,
+		SchemaVersion:   model.GetSchemaVersion(),
+		TypeDefinitions: model.GetTypeDefinitions(),
+	})
+	require.NoError(t, err)
+
+	listObjects := func(t *testing.T, user string) []string {
+		resp, err := s.ListObjects(ctx, &openfgav1.ListObjectsRequest{
+			StoreId:              storeID,
+			AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+			Type:                 "document",
+			Relation:             "viewer",
+			User:                 user,
+		})
+		require.NoError(t, err)
+		return resp.GetObjects()
+	}
+
+	require.Empty(t, listObjects(t, "user:anne"))
+	require.Empty(t, listObjects(t, "user:bob"))
+
+	// tuples written without going through the server are not observed until the cached empty result expires
+	require.NoError(t, ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "user:anne"),
+	}))
+	require.Empty(t, listObjects(t, "user:anne"))
+
+	t.Run("a_write_to_the_store_invalidates_the_cached_results", func(t *testing.T) {
+		_, err := s.Write(ctx, &openfgav1.WriteRequest{
+			StoreId: storeID,
+			Writes: &openfgav1.WriteRequestWrites{
+				TupleKeys: []*openfgav1.TupleKey{
+					tuple.NewTupleKey("document:2", "viewer", "user:bob"),
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, []string{"document:1"}, listObjects(t, "user:anne"))
+		require.Equal(t, []string{"document:2"}, listObjects(t, "user:bob"))
+	})
+
+	t.Run("requests_with_contextual_tuples_are_not_cached", func(t *testing.T) {
+		req := &openfgav1.ListObjectsRequest{
+			StoreId:              storeID,
+			AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+			Type:                 "document",
+			Relation:             "viewer",
+			User:                 "user:charlie",
+			ContextualTuples: &openfgav1.ContextualTupleKeys{
+				TupleKeys: []*openfgav1.TupleKey{
+					tuple.NewTupleKey("document:3", "viewer", "user:anne"),
+				},
+			},
+		}
+
+		resp, err := s.ListObjects(ctx, req)
+		require.NoError(t, err)
+		require.Empty(t, resp.GetObjects())
+
+		req.ContextualTuples.TupleKeys[0].User = "user:charlie"
+		resp, err = s.ListObjects(ctx, req)
+		require.NoError(t, err)
+		require.Equal(t, []string{"document:3"}, resp.GetObjects())
+	})
+
+	t.Run("results_expire_after_the_ttl", func(t *testing.T) {
+		s := MustNewServerWithOpts(
+			WithDatastore(ds),
+			WithListObjectsEmptyResultCacheTTL(time.Millisecond),
+		)
+		t.Cleanup(s.Close)
+
+		req := &openfgav1.ListObjectsRequest{
+			StoreId:              storeID,
+			AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+			Type:                 "document",
+			Relation:             "viewer",
+			User:                 "user:dave",
+		}
+
+		resp, err := s.ListObjects(ctx, req)
+		require.NoError(t, err)
+		require.Empty(t, resp.GetObjects())
+
+		require.NoError(t, ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:4", "viewer", "user:dave"),
+		}))
+		time.Sleep(10 * time.Millisecond)
+
+		resp, err = s.ListObjects(ctx, req)
+		require.NoError(t, err)
+		require.Equal(t, []string{"document:4"}, resp.GetObjects())
+	})
+}
+
+func TestListObjects_ErrorCases(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+	store := ulid.Make().String()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	t.Run("database_errors", func(t *testing.T) {
+		mockDatastore := mockstorage.NewMockOpenFGADatastore(mockController)
+
+		s := MustNewServerWithOpts(
+			WithDatastore(mockDatastore),
+		)
+		t.Cleanup(func() {
+			mockDatastore.EXPECT().Close().Times(1)
+			s.Close()
+		})
+
+		modelID := ulid.Make().String()
+
+		mockDatastore.EXPECT().ReadAuthorizationModel(gomock.Any(), store, modelID).AnyTimes().Return(&openfgav1.AuthorizationModel{
+			SchemaVersion: typesystem.SchemaVersion1_1,
+			TypeDefinitions: language.MustTransformDSLToProto(`
+				model
+					schema 1.1
+
+				type user
+
+				type document
+					relations
+						define viewer: [user, user:*]`).GetTypeDefinitions(),
+		}, nil)
+
+		mockDatastore.EXPECT().ReadStartingWithUser(gomock.Any(), store, storage.ReadStartingWithUserFilter{
+			ObjectType: "document",
+			Relation:   "viewer",
+			UserFilter: []*openfgav1.ObjectRelation{
+				{Object: "user:*"},
+				{Object: "user:bob"},
+			}}).AnyTimes().Return(nil, errors.New("error reading from storage"))
+
+		t.Run("error_listing_objects_from_storage_in_non-streaming_version", func(t *testing.T) {
+			res, err := s.ListObjects(ctx, &openfgav1.ListObjectsRequest{
+				StoreId:              store,
+				AuthorizationModelId: modelID,
+				Type:                 "document",
+				Relation:             "viewer",
+				User:                 "user:bob",
+			})
+
+			require.Nil(t, res)
+			require.ErrorIs(t, err, serverErrors.NewInternalError("", errors.New("error reading from storage")))
+		})
+
+		t.Run("error_listing_objects_from_storage_in_streaming_version", func(t *testing.T) {
+			err := s.StreamedListObjects(&openfgav1.StreamedListObjectsRequest{
+				StoreId:              store,
+				AuthorizationModelId: modelID,
+				Type:                 "document",
+				Relation:             "viewer",
+				User:                 "user:bob",
+			}, NewMockStreamServer())
+
+			require.ErrorIs(t, err, serverErrors.NewInternalError("", errors.New("error reading from storage")))
+		})
+	})
+
+	t.Run("graph_resolution_errors", func(t *testing.T) {
+		s := MustNewServerWithOpts(
+			WithDatastore(memory.New()),
+			WithResolveNodeLimit(2),
+		)
+		t.Cleanup(s.Close)
+
+		writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+			StoreId:       store,
+			SchemaVersion: typesystem.SchemaVersion1_1,
+			TypeDefinitions: language.MustTransformDSLToProto(`
+				model
+					schema 1.1
+
+				type user
+
+				type group
+					relations
+						define member: [user, group#member]
+
+				type document
+					relations
+						define viewer: [group#member]`).GetTypeDefinitions(),
+		})
+		require.NoError(t, err)
+
+		_, err = s.Write(ctx, &openfgav1.WriteRequest{
+			StoreId: store,
+			Writes: &openfgav1.WriteRequestWrites{
+				TupleKeys: []*openfgav1.TupleKey{
+					tuple.NewTupleKey("document:1", "viewer", "group:1#member"),
+					tuple.NewTupleKey("group:1", "member", "group:2#member"),
+					tuple.NewTupleKey("group:2", "member", "group:3#member"),
+					tuple.NewTupleKey("group:3", "member", "user:jon"),
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		t.Run("resolution_depth_exceeded_error_unary", func(t *testing.T) {
+			res, err := s.ListObjects(ctx, &openfgav1.ListObjectsRequest{
+				StoreId:              store,
+				AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+				Type:                 "document",
+				Relation:             "viewer",
+				User:                 "user:jon",
+			})
+
+			require.Nil(t, res)
+			require.ErrorIs(t, err, serverErrors.AuthorizationModelResolutionTooComplex)
+		})
+
+		t.Run("resolution_depth_exceeded_error_streaming", func(t *testing.T) {
+			err := s.StreamedListObjects(&openfgav1.StreamedListObjectsRequest{
+				StoreId:              store,
+				AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+				Type:                 "document",
+				Relation:             "viewer",
+				User:                 "user:jon",
+			}, NewMockStreamServer())
+
+			require.ErrorIs(t, err, serverErrors.AuthorizationModelResolutionTooComplex)
+		})
+	})
+}
+
+func TestAuthorizationModelInvalidSchemaVersion(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+	store := ulid.Make().String()
+	modelID := ulid.Make().String()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mockDatastore := mockstorage.NewMockOpenFGADatastore(mockController)
+
+	mockDatastore.EXPECT().ReadAuthorizationModel(gomock.Any(), store, modelID).AnyTimes().Return(&openfgav1.AuthorizationModel{
+		SchemaVersion: typesystem.SchemaVersion1_0,
+		TypeDefinitions: []*openfgav1.TypeDefinition{
+			{
+				Type: "user",
+			},
+			{
+				Type: "team",
+				Relations: map[string]*openfgav1.Userset{
+					"member": typesystem.This(),
+				},
+			},
+		},
+	}, nil)
+
+	s := MustNewServerWithOpts(
+		WithDatastore(mockDatastore),
+	)
+	t.Cleanup(func() {
+		mockDatastore.EXPECT().Close().Times(1)
+		s.Close()
+	})
+
+	t.Run("invalid_schema_error_in_check", func(t *testing.T) {
+		_, err := s.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:              store,
+			AuthorizationModelId: modelID,
+			TupleKey: tuple.NewCheckRequestTupleKey(
+				"team:abc",
+				"member",
+				"user:anne"),
+		})
+		require.Error(t, err)
+		e, ok := status.FromError(err)
+		require.True(t, ok)
+		require.Equal(t, codes.Code(openfgav1.ErrorCode_validation_error), e.Code())
+	})
+
+	t.Run("invalid_schema_error_in_list_objects", func(t *testing.T) {
+		_, err := s.ListObjects(ctx, &openfgav1.ListObjectsRequest{
+			StoreId:              store,
+			AuthorizationModelId: modelID,
+			Type:                 "team",
+			Relation:             "member",
+			User:                 "user:anne",
+		})
+		require.Error(t, err)
+		e, ok := status.FromError(err)
+		require.True(t, ok)
+		require.Equal(t, codes.Code(openfgav1.ErrorCode_validation_error), e.Code())
+	})
+
+	t.Run("invalid_schema_error_in_streamed_list_objects", func(t *testing.T) {
+		err := s.StreamedListObjects(&openfgav1.StreamedListObjectsRequest{
+			StoreId:              store,
+			AuthorizationModelId: modelID,
+			Type:                 "team",
+			Relation:             "member",
+			User:                 "user:anne",
+		}, NewMockStreamServer())
+		require.Error(t, err)
+		e, ok := status.FromError(err)
+		require.True(t, ok)
+		require.Equal(t, codes.Code(openfgav1.ErrorCode_validation_error), e.Code())
+	})
+
+	t.Run("invalid_schema_error_in_write", func(t *testing.T) {
+		_, err := s.Write(ctx, &openfgav1.WriteRequest{
+			StoreId:              store,
+			AuthorizationModelId: modelID,
+			Writes: &openfgav1.WriteRequestWrites{
+				TupleKeys: []*openfgav1.TupleKey{
+					{
+						Object:   "repo:openfga/openfga",
+						Relation: "reader",
+						User:     "user:anne",
+					},
+				},
+			},
+		})
+		require.Error(t, err)
+		e, ok := status.FromError(err)
+		require.True(t, ok)
+		require.Equal(t, codes.Code(openfgav1.ErrorCode_validation_error), e.Code())
+	})
+
+	t.Run("invalid_schema_error_in_write_model", func(t *testing.T) {
+		mockDatastore.EXPECT().MaxTypesPerAuthorizationModel().Return(100)
+
+		_, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+			StoreId:       store,
+			SchemaVersion: typesystem.SchemaVersion1_0,
+			TypeDefinitions: language.MustTransformDSLToProto(`
+				model
+					schema 1.1
+
+				type repo
+				`).GetTypeDefinitions(),
+		})
+		require.Error(t, err)
+		e, ok := status.FromError(err)
+		require.True(t, ok)
+		require.Equal(t, codes.Code(openfgav1.ErrorCode_invalid_authorization_model), e.Code(), err)
+	})
+
+	t.Run("invalid_schema_error_in_write_assertion", func(t *testing.T) {
+		_, err := s.WriteAssertions(ctx, &openfgav1.WriteAssertionsRequest{
+			StoreId:              store,
+			AuthorizationModelId: modelID,
+			Assertions: []*openfgav1.Assertion{{
+				TupleKey:    tuple.NewAssertionTupleKey("repo:test", "reader", "user:elbuo"),
+				Expectation: false,
+			}},
+		})
+		require.Error(t, err)
+		e, ok := status.FromError(err)
+		require.True(t, ok)
+		require.Equal(t, codes.Code(openfgav1.ErrorCode_validation_error), e.Code())
+	})
+}
+
+func TestDefaultMaxConcurrentReadSettings(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	cfg := serverconfig.DefaultConfig()
+	require.EqualValues(t, math.MaxUint32, cfg.MaxConcurrentReadsForCheck)
+	require.EqualValues(t, math.MaxUint32, cfg.MaxConcurrentReadsForListObjects)
+	require.EqualValues(t, math.MaxUint32, cfg.MaxConcurrentReadsForListUsers)
+
+	s := MustNewServerWithOpts(
+		WithDatastore(memory.New()),
+	)
+	t.Cleanup(s.Close)
+	require.EqualValues(t, math.MaxUint32, s.maxConcurrentReadsForCheck)
+	require.EqualValues(t, math.MaxUint32, s.maxConcurrentReadsForListObjects)
+	require.EqualValues(t, math.MaxUint32, s.maxConcurrentReadsForListUsers)
+}
+
+func TestDelegateCheckResolver(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+	t.Run("default_check_resolver_alone", func(t *testing.T) {
+		cfg := serverconfig.DefaultConfig()
+		require.False(t, cfg.CheckDispatchThrottling.Enabled)
+		require.False(t, cfg.DispatchThrottling.Enabled)
+		require.False(t, cfg.ListObjectsDispatchThrottling.Enabled)
+		require.False(t, cfg.CheckQueryCache.Enabled)
+
+		ds := memory.New()
+		t.Cleanup(ds.Close)
+		s := MustNewServerWithOpts(
+			WithDatastore(ds),
+		)
+		t.Cleanup(s.Close)
+		require.Nil(t, s.dispatchThrottlingCheckResolver)
+		require.False(t, s.checkDispatchThrottlingEnabled)
+
+		require.False(t, s.checkQueryCacheEnabled)
+		require.Nil(t, s.cachedCheckResolver)
+
+		require.NotNil(t, s.checkResolver)
+		cycleDetectionCheckResolver, ok := s.checkResolver.(*graph.CycleDetectionCheckResolver)
+		require.True(t, ok)
+
+		localCheckResolver, ok := cycleDetectionCheckResolver.GetDelegate().(*graph.LocalChecker)
+		require.True(t, ok)
+
+		_, ok = localCheckResolver.GetDelegate().(*graph.CycleDetectionCheckResolver)
+		require.True(t, ok)
+	})
+
+	t.Run("dispatch_throttling_check_resolver_enabled", func(t *testing.T) {
+		ds := memory.New()
+		t.Cleanup(ds.Close)
+		const dispatchThreshold = 50
+		s := MustNewServerWithOpts(
+			WithDatastore(ds),
+			WithDispatchThrottlingCheckResolverEnabled(true),
+			WithDispatchThrottlingCheckResolverThreshold(dispatchThreshold),
+		)
+		t.Cleanup(s.Close)
+
+		require.False(t, s.checkQueryCacheEnabled)
+		require.Nil(t, s.cachedCheckResolver)
+
+		require.True(t, s.checkDispatchThrottlingEnabled)
+		require.EqualValues(t, dispatchThreshold, s.checkDispatchThrottlingDefaultThreshold)
+		require.EqualValues(t, 0, s.checkDispatchThrottlingMaxThreshold)
+		require.NotNil(t, s.dispatchThrottlingCheckResolver)
+		require.NotNil(t, s.checkResolver)
+		cycleDetectionCheckResolver, ok := s.checkResolver.(*graph.CycleDetectionCheckResolver)
+		require.True(t, ok)
+
+		dispatchThrottlingResolver, ok := cycleDetectionCheckResolver.GetDelegate().(*graph.DispatchThrottlingCheckResolver)
+		require.True(t, ok)
+
+		localChecker, ok := dispatchThrottlingResolver.GetDelegate().(*graph.LocalChecker)
+		require.True(t, ok)
+
+		_, ok = localChecker.GetDelegate().(*graph.CycleDetectionCheckResolver)
+		require.True(t, ok)
+	})
+
+	t.Run("dispatch_throttling_check_resolver_enabled_zero_max_threshold", func(t *testing.T) {
+		ds := memory.New()
+		t.Cleanup(ds.Close)
+		const dispatchThreshold = 50
+		s := MustNewServerWithOpts(
+			WithDatastore(ds),
+			WithDispatchThrottlingCheckResolverEnabled(true),
+			WithDispatchThrottlingCheckResolverThreshold(dispatchThreshold),
+			WithDispatchThrottlingCheckResolverMaxThreshold(0),
+		)
+		t.Cleanup(s.Close)
+
+		require.False(t, s.checkQueryCacheEnabled)
+		require.Nil(t, s.cachedCheckResolver)
+
+		require.True(t, s.checkDispatchThrottlingEnabled)
+		require.EqualValues(t, dispatchThreshold, s.checkDispatchThrottlingDefaultThreshold)
+		require.EqualValues(t, 0, s.checkDispatchThrottlingMaxThreshold)
+		require.NotNil(t, s.dispatchThrottlingCheckResolver)
+		require.NotNil(t, s.checkResolver)
+		cycleDetectionCheckResolver, ok := s.checkResolver.(*graph.CycleDetectionCheckResolver)
+		require.True(t, ok)
+
+		dispatchThrottlingResolver, ok := cycleDetectionCheckResolver.GetDelegate().(*graph.DispatchThrottlingCheckResolver)
+		require.True(t, ok)
+
+		localChecker, ok := dispatchThrottlingResolver.GetDelegate().(*graph.LocalChecker)
+		require.True(t, ok)
+
+		_, ok = localChecker.GetDelegate().(*graph.CycleDetectionCheckResolver)
+		require.True(t, ok)
+	})
+
+	t.Run("dispatch_throttling_check_resolver_enabled_non_zero_max_threshold", func(t *testing.T) {
+		ds := memory.New()
+		t.Cleanup(ds.Close)
+		const dispatchThreshold = 50
+		const maxDispatchThreshold = 60
+
+		s := MustNewServerWithOpts(
+			WithDatastore(ds),
+			WithDispatchThrottlingCheckResolverEnabled(true),
+			WithDispatchThrottlingCheckResolverThreshold(dispatchThreshold),
+			WithDispatchThrottlingCheckResolverMaxThreshold(maxDispatchThreshold),
+		)
+		t.Cleanup(s.Close)
+
+		require.False(t, s.checkQueryCacheEnabled)
+		require.Nil(t, s.cachedCheckResolver)
+
+		require.True(t, s.checkDispatchThrottlingEnabled)
+		require.EqualValues(t, dispatchThreshold, s.checkDispatchThrottlingDefaultThreshold)
+		require.EqualValues(t, maxDispatchThreshold, s.checkDispatchThrottlingMaxThreshold)
+		require.NotNil(t, s.dispatchThrottlingCheckResolver)
+		require.NotNil(t, s.checkResolver)
+		cycleDetectionCheckResolver, ok := s.checkResolver.(*graph.CycleDetectionCheckResolver)
+		require.True(t, ok)
+
+		dispatchThrottlingResolver, ok := cycleDetectionCheckResolver.GetDelegate().(*graph.DispatchThrottlingCheckResolver)
+		require.True(t, ok)
+
+		localChecker, ok := dispatchThrottlingResolver.GetDelegate().(*graph.LocalChecker)
+		require.True(t, ok)
+
+		_, ok = localChecker.GetDelegate().(*graph.CycleDetectionCheckResolver)
+		require.True(t, ok)
+	})
+
+	t.Run("cache_check_resolver_enabled", func(t *testing.T) {
+		ds := memory.New()
+		t.Cleanup(ds.Close)
+		s := MustNewServerWithOpts(
+			WithDatastore(ds),
+			WithCheckQueryCacheEnabled(true),
+		)
+		t.Cleanup(s.Close)
+
+		require.False(t, s.checkDispatchThrottlingEnabled)
+		require.Nil(t, s.dispatchThrottlingCheckResolver)
+
+		require.True(t, s.checkQueryCacheEnabled)
+		require.NotNil(t, s.cachedCheckResolver)
+		require.NotNil(t, s.checkResolver)
+		cycleDetectionCheckResolver, ok := s.checkResolver.(*graph.CycleDetectionCheckResolver)
+		require.True(t, ok)
+
+		cachedCheckResolver, ok := cycleDetectionCheckResolver.GetDelegate().(*graph.CachedCheckResolver)
+		require.True(t, ok)
+
+		localChecker, ok := cachedCheckResolver.GetDelegate().(*graph.LocalChecker)
+		require.True(t, ok)
+
+		_, ok = localChecker.GetDelegate().(*graph.CycleDetectionCheckResolver)
+		require.True(t, ok)
+	})
+
+	t.Run("both_dispatch_throttling_and_cache_check_resolver_enabled", func(t *testing.T) {
+		ds := memory.New()
+		t.Cleanup(ds.Close)
+		s := MustNewServerWithOpts(
+			WithDatastore(ds),
+			WithCheckQueryCacheEnabled(true),
+			WithDispatchThrottlingCheckResolverEnabled(true),
+			WithDispatchThrottlingCheckResolverThreshold(50),
+			WithDispatchThrottlingCheckResolverMaxThreshold(100),
+		)
+		t.Cleanup(s.Close)
+
+		require.True(t, s.checkDispatchThrottlingEnabled)
+		require.EqualValues(t, 50, s.checkDispatchThrottlingDefaultThreshold)
+		require.EqualValues(t, 100, s.checkDispatchThrottlingMaxThreshold)
+		require.NotNil(t, s.dispatchThrottlingCheckResolver)
+		require.NotNil(t, s.checkResolver)
+		cycleDetectionCheckResolver, ok := s.checkResolver.(*graph.CycleDetectionCheckResolver)
+		require.True(t, ok)
+
+		cachedCheckResolver, ok := cycleDetectionCheckResolver.GetDelegate().(*graph.CachedCheckResolver)
+		require.True(t, ok)
+
+		require.True(t, s.checkQueryCacheEnabled)
+		require.NotNil(t, s.cachedCheckResolver)
+
+		dispatchThrottlingResolver, ok := cachedCheckResolver.GetDelegate().(*graph.DispatchThrottlingCheckResolver)
+		require.True(t, ok)
+
+		localChecker, ok := dispatchThrottlingResolver.GetDelegate().(*graph.LocalChecker)
+		require.True(t, ok)
+
+		_, ok = localChecker.GetDelegate().(*graph.CycleDetectionCheckResolver)
+		require.True(t, ok)
+	})
+
+	t.Run("reordered_check_resolvers", func(t *testing.T) {
+		ds := memory.New()
+		t.Cleanup(ds.Close)
+		s := MustNewServerWithOpts(
+			WithDatastore(ds),
+			WithCheckQueryCacheEnabled(true),
+			WithDispatchThrottlingCheckResolverEnabled(true),
+			WithCheckResolverOrder(graph.CachedCheckResolverLayer, graph.CycleDetectionCheckResolverLayer),
+		)
+		t.Cleanup(s.Close)
+
+		// the dispatch throttling is enabled but left out of the chain
+		require.Nil(t, s.dispatchThrottlingCheckResolver)
+		require.NotNil(t, s.cachedCheckResolver)
+
+		cachedCheckResolver, ok := s.checkResolver.(*graph.CachedCheckResolver)
+		require.True(t, ok)
+
+		cycleDetectionCheckResolver, ok := cachedCheckResolver.GetDelegate().(*graph.CycleDetectionCheckResolver)
+		require.True(t, ok)
+
+		localChecker, ok := cycleDetectionCheckResolver.GetDelegate().(*graph.LocalChecker)
+		require.True(t, ok)
+
+		_, ok = localChecker.GetDelegate().(*graph.CachedCheckResolver)
+		require.True(t, ok)
+	})
+
+	t.Run("unknown_check_resolver", func(t *testing.T) {
+		ds := memory.New()
+		t.Cleanup(ds.Close)
+		_, err := NewServerWithOpts(
+			WithDatastore(ds),
+			WithCheckResolverOrder(graph.CycleDetectionCheckResolverLayer, "tracker"),
+		)
+		require.EqualError(t, err, "unknown Check resolver layer 'tracker'")
+	})
+}
+
+func TestWriteAuthorizationModelWithSchema12(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mockDatastore := mockstorage.NewMockOpenFGADatastore(mockController)
+
+	t.Run("accepts_request_with_schema_version_1.2", func(t *testing.T) {
+		s := MustNewServerWithOpts(
+			WithDatastore(mockDatastore),
+		)
+		t.Cleanup(func() {
+			mockDatastore.EXPECT().Close().Times(1)
+			s.Close()
+		})
+
+		mockDatastore.EXPECT().MaxTypesPerAuthorizationModel().Return(100)
+		mockDatastore.EXPECT().WriteAuthorizationModel(gomock.Any(), storeID, gomock.Any()).Return(nil)
+
+		_, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+			StoreId:       storeID,
+			SchemaVersion: typesystem.SchemaVersion1_2,
+			TypeDefinitions: []*openfgav1.TypeDefinition{
+				{
+					Type: "user",
+					Metadata: &openfgav1.Metadata{
+						Relations:  nil,
+						Module:     "usermanagement",
+						SourceInfo: nil,
+					},
+				},
+			},
+		})
+
+		require.NoError(t, err)
+	})
+}
+
+func TestIsExperimentallyEnabled(t *testing.T) {
+	ds := memory.New() // Datastore required for server instantiation
+	someExperimentalFlag := ExperimentalFeatureFlag("some-experimental-feature-to-enable")
+
+	t.Run("returns_false_if_experimentals_is_empty", func(t *testing.T) {
+		s := MustNewServerWithOpts(WithDatastore(ds))
+		t.Cleanup(s.Close)
+		require.False(t, s.IsExperimentallyEnabled(someExperimentalFlag))
+	})
+
+	t.Run("returns_true_if_experimentals_has_matching_element", func(t *testing.T) {
+		s := MustNewServerWithOpts(
+			WithDatastore(ds),
+			WithExperimentals(someExperimentalFlag),
+		)
+		t.Cleanup(s.Close)
+		require.True(t, s.IsExperimentallyEnabled(someExperimentalFlag))
+	})
+
+	t.Run("returns_true_if_experimentals_has_matching_element_and_other_matching_element", func(t *testing.T) {
+		s := MustNewServerWithOpts(
+			WithDatastore(ds),
+			WithExperimentals(someExperimentalFlag, ExperimentalFeatureFlag("some-other-feature")),
+		)
+		t.Cleanup(s.Close)
+		require.True(t, s.IsExperimentallyEnabled(someExperimentalFlag))
+	})
+
+	t.Run("returns_false_if_experimentals_has_no_matching_element", func(t *testing.T) {
+		s := MustNewServerWithOpts(
+			WithDatastore(ds),
+			WithExperimentals(ExperimentalFeatureFlag("some-other-feature")),
+		)
+		t.Cleanup(s.Close)
+		require.False(t, s.IsExperimentallyEnabled(someExperimentalFlag))
+	})
+}
+
+func TestWriteRate(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	createStoreResp, err := ds.CreateStore(ctx, &openfgav1.Store{Id: ulid.Make().String(), Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+	)
+	t.Cleanup(s.Close)
+
+	require.NoError(t, ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+		tuple.NewTupleKey("document:2", "viewer", "user:jon"),
+	}))
+
+	time.Sleep(200 * time.Millisecond)
+
+	require.NoError(t, ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:3", "viewer", "user:jon"),
+	}))
+
+	rate, err := s.WriteRate(ctx, storeID, 100*time.Millisecond)
+	require.NoError(t, err)
+	require.Equal(t, 1, rate)
+
+	rate, err = s.WriteRate(ctx, storeID, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, 3, rate)
+
+	t.Run("invalid_window", func(t *testing.T) {
+		_, err := s.WriteRate(ctx, storeID, 0)
+		require.ErrorContains(t, err, "the window must be positive")
+	})
+
+	t.Run("datastore_without_change_count_support", func(t *testing.T) {
+		s := MustNewServerWithOpts(
+			WithDatastore(&delayedTupleReaderDatastore{OpenFGADatastore: memory.New()}),
+		)
+		t.Cleanup(s.Close)
+
+		_, err := s.WriteRate(ctx, storeID, time.Hour)
+		require.ErrorIs(t, err, serverErrors.ChangeCountUnsupported)
+	})
+}
+
+func TestStoreStats(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	createStoreResp, err := ds.CreateStore(ctx, &openfgav1.Store{Id: ulid.Make().String(), Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+	)
+	t.Cleanup(s.Close)
+
+	require.NoError(t, ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+		tuple.NewTupleKey("document:2", "viewer", "user:jon"),
+		tuple.NewTupleKey("folder:1", "owner", "user:jon"),
+	}))
+
+	stats, err := s.StoreStats(ctx, storeID)
+	require.NoError(t, err)
+	require.Equal(t, 3, stats.TupleCount)
+	require.Equal(t, map[string]int{"document#viewer": 2, "folder#owner": 1}, stats.RelationTupleCounts)
+	require.Equal(t, 3, stats.HourlyChangeCount)
+
+	t.Run("datastore_without_store_stats_support", func(t *testing.T) {
+		s := MustNewServerWithOpts(
+			WithDatastore(&delayedTupleReaderDatastore{OpenFGADatastore: memory.New()}),
+		)
+		t.Cleanup(s.Close)
+
+		_, err := s.StoreStats(ctx, storeID)
+		require.ErrorIs(t, err, serverErrors.StoreStatsUnsupported)
+	})
+}
+
+func TestStoreTimeRange(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	createStoreResp, err := ds.CreateStore(ctx, &openfgav1.Store{Id: ulid.Make().String(), Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+	)
+	t.Cleanup(s.Close)
+
+	timeRange, err := s.StoreTimeRange(ctx, storeID)
+	require.NoError(t, err)
+	require.Nil(t, timeRange)
+
+	require.NoError(t, ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+	}))
+
+	timeRange, err = s.StoreTimeRange(ctx, storeID)
+	require.NoError(t, err)
+	require.NotEmpty(t, timeRange.EarliestWriteUlid)
+	require.Equal(t, timeRange.EarliestWriteUlid, timeRange.LatestWriteUlid)
+
+	t.Run("datastore_without_store_time_range_support", func(t *testing.T) {
+		s := MustNewServerWithOpts(
+			WithDatastore(&delayedTupleReaderDatastore{OpenFGADatastore: memory.New()}),
+		)
+		t.Cleanup(s.Close)
+
+		_, err := s.StoreTimeRange(ctx, storeID)
+		require.ErrorIs(t, err, serverErrors.StoreTimeRangeUnsupported)
+	})
+}
+
+func TestReadByActor(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	createStoreResp, err := ds.CreateStore(ctx, &openfgav1.Store{Id: ulid.Make().String(), Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type document
+			relations
+				define viewer: [user]`)
+	require.NoError(t, ds.WriteAuthorizationModel(ctx, storeID, model))
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+	)
+	t.Cleanup(s.Close)
+
+	write := func(ctx context.Context, object string) {
+		_, err := s.Write(ctx, &openfgav1.WriteRequest{
+			StoreId: storeID,
+			Writes: &openfgav1.WriteRequestWrites{
+				TupleKeys: []*openfgav1.TupleKey{tuple.NewTupleKey(object, "viewer", "user:jon")},
+			},
+		})
+		require.NoError(t, err)
+	}
+
+	write(authn.ContextWithAuthClaims(ctx, &authn.AuthClaims{Subject: "client-a"}), "document:1")
+	write(authn.ContextWithAuthClaims(ctx, &authn.AuthClaims{Subject: "client-b"}), "document:2")
+	write(storage.ContextWithWriteActor(ctx, "client-a"), "document:3")
+	write(ctx, "document:4")
+
+	tuples, err := s.ReadByActor(ctx, storeID, "client-a")
+	require.NoError(t, err)
+	require.Len(t, tuples, 2)
+	require.Equal(t, "document:1#viewer@user:jon", tuple.TupleKeyToString(tuples[0].GetKey()))
+	require.Equal(t, "document:3#viewer@user:jon", tuple.TupleKeyToString(tuples[1].GetKey()))
+
+	tuples, err = s.ReadByActor(ctx, storeID, "client-b")
+	require.NoError(t, err)
+	require.Len(t, tuples, 1)
+	require.Equal(t, "document:2#viewer@user:jon", tuple.TupleKeyToString(tuples[0].GetKey()))
+
+	t.Run("datastore_without_actor_support", func(t *testing.T) {
+		s := MustNewServerWithOpts(
+			WithDatastore(&delayedTupleReaderDatastore{OpenFGADatastore: memory.New()}),
+		)
+		t.Cleanup(s.Close)
+
+		_, err := s.ReadByActor(ctx, storeID, "client-a")
+		require.ErrorIs(t, err, serverErrors.ReadByActorUnsupported)
+		require.Equal(t, codes.Code(openfgav1.NotFoundErrorCode_unimplemented), status.Code(err))
+	})
+}
+
+func TestWriteWithPreconditions(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	createStoreResp, err := ds.CreateStore(ctx, &openfgav1.Store{Id: ulid.Make().String(), Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type document
+			relations
+				define owner: [user]
+				define viewer: [user]`)
+	require.NoError(t, ds.WriteAuthorizationModel(ctx, storeID, model))
+	require.NoError(t, ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "owner", "user:jon"),
+	}))
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+	)
+	t.Cleanup(s.Close)
+
+	grant := func(opts ...WriteOption) error {
+		_, err := s.WriteWithOptions(ctx, &openfgav1.WriteRequest{
+			StoreId: storeID,
+			Writes: &openfgav1.WriteRequestWrites{
+				TupleKeys: []*openfgav1.TupleKey{tuple.NewTupleKey("document:1", "viewer", "user:maria")},
+			},
+		}, opts...)
+		return err
+	}
+
+	t.Run("precondition_does_not_hold", func(t *testing.T) {
+		err := grant(WithWritePreconditions(
+			[]*openfgav1.TupleKeyWithoutCondition{tuple.TupleKeyToTupleKeyWithoutCondition(tuple.NewTupleKey("document:1", "owner", "user:anne"))},
+			nil,
+		))
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+		_, err = ds.ReadUserTuple(ctx, storeID, tuple.NewTupleKey("document:1", "viewer", "user:maria"))
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("malformed_precondition", func(t *testing.T) {
+		err := grant(WithWritePreconditions(
+			nil,
+			[]*openfgav1.TupleKeyWithoutCondition{tuple.TupleKeyToTupleKeyWithoutCondition(tuple.NewTupleKey("1", "viewer", "user:maria"))},
+		))
+		require.Equal(t, codes.Code(openfgav1.ErrorCode_validation_error), status.Code(err))
+	})
+
+	t.Run("preconditions_hold", func(t *testing.T) {
+		err := grant(WithWritePreconditions(
+			[]*openfgav1.TupleKeyWithoutCondition{tuple.TupleKeyToTupleKeyWithoutCondition(tuple.NewTupleKey("document:1", "owner", "user:jon"))},
+			[]*openfgav1.TupleKeyWithoutCondition{tuple.TupleKeyToTupleKeyWithoutCondition(tuple.NewTupleKey("document:1", "viewer", "user:maria"))},
+		))
+		require.NoError(t, err)
+
+		_, err = ds.ReadUserTuple(ctx, storeID, tuple.NewTupleKey("document:1", "viewer", "user:maria"))
+		require.NoError(t, err)
+	})
+
+	t.Run("datastore_without_conditional_write_support", func(t *testing.T) {
+		s := MustNewServerWithOpts(
+			WithDatastore(&delayedTupleReaderDatastore{OpenFGADatastore: memory.New()}),
+		)
+		t.Cleanup(s.Close)
+
+		_, err := s.WriteWithOptions(ctx, &openfgav1.WriteRequest{
+			StoreId: storeID,
+			Writes: &openfgav1.WriteRequestWrites{
+				TupleKeys: []*openfgav1.TupleKey{tuple.NewTupleKey("document:1", "viewer", "user:maria")},
+			},
+		}, WithWritePreconditions(nil, nil))
+		require.ErrorIs(t, err, serverErrors.ConditionalWriteUnsupported)
+	})
+}
+
+func TestWatch(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	createStoreResp, err := ds.CreateStore(ctx, &openfgav1.Store{Id: ulid.Make().String(), Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type document
+			relations
+				define viewer: [user]`)
+	require.NoError(t, ds.WriteAuthorizationModel(ctx, storeID, model))
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+		WithWatchPollInterval(10*time.Millisecond),
+	)
+	t.Cleanup(s.Close)
+
+	write := func(object string) {
+		_, err := s.Write(ctx, &openfgav1.WriteRequest{
+			StoreId: storeID,
+			Writes: &openfgav1.WriteRequestWrites{
+				TupleKeys: []*openfgav1.TupleKey{tuple.NewTupleKey(object, "viewer", "user:jon")},
+			},
+		})
+		require.NoError(t, err)
+	}
+
+	write("document:1")
+
+	// the changes written before the Watch are read with ReadChanges
+	readResp, err := s.ReadChanges(ctx, &openfgav1.ReadChangesRequest{StoreId: storeID})
+	require.NoError(t, err)
+	require.Len(t, readResp.GetChanges(), 1)
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	responses := make(chan *openfgav1.ReadChangesResponse)
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Watch(watchCtx, &openfgav1.ReadChangesRequest{
+			StoreId:           storeID,
+			ContinuationToken: readResp.GetContinuationToken(),
+		}, func(resp *openfgav1.ReadChangesResponse) error {
+			responses <- resp
+			return nil
+		})
+	}()
+
+	write("document:2")
+
+	var watchResp *openfgav1.ReadChangesResponse
+	select {
+	case watchResp = <-responses:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "the change was not watched")
+	}
+	require.Len(t, watchResp.GetChanges(), 1)
+	require.Equal(t, "document:2", watchResp.GetChanges()[0].GetTupleKey().GetObject())
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+
+	t.Run("token_resumes_with_read_changes", func(t *testing.T) {
+		write("document:3")
+
+		readResp, err := s.ReadChanges(ctx, &openfgav1.ReadChangesRequest{
+			StoreId:           storeID,
+			ContinuationToken: watchResp.GetContinuationToken(),
+		})
+		require.NoError(t, err)
+		require.Len(t, readResp.GetChanges(), 1)
+		require.Equal(t, "document:3", readResp.GetChanges()[0].GetTupleKey().GetObject())
+	})
+
+	t.Run("send_error_stops_the_watch", func(t *testing.T) {
+		errSend := errors.New("stream closed")
+		err := s.Watch(ctx, &openfgav1.ReadChangesRequest{StoreId: storeID}, func(*openfgav1.ReadChangesResponse) error {
+			return errSend
+		})
+		require.ErrorIs(t, err, errSend)
+	})
+}
+
+func TestCheckObligations(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	createStoreResp, err := ds.CreateStore(ctx, &openfgav1.Store{Id: ulid.Make().String(), Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type group
+			relations
+				define member: [user, user with step_up]
+		type document
+			relations
+				define viewer: [user, group#member]
+
+		condition step_up(mfa_verified: bool) {
+			mfa_verified
+		}`)
+	require.NoError(t, ds.WriteAuthorizationModel(ctx, storeID, model))
+	require.NoError(t, ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+		tuple.NewTupleKey("document:1", "viewer", "group:admins#member"),
+		tuple.NewTupleKeyWithCondition("group:admins", "member", "user:maria", "step_up", nil),
+	}))
+
+	check := func(t *testing.T, user string, opts ...OpenFGAServiceV1Option) (*openfgav1.CheckResponse, map[string]string) {
+		transport := &headerRecordingTransport{headers: map[string]string{}}
+
+		s := MustNewServerWithOpts(append([]OpenFGAServiceV1Option{
+			WithDatastore(ds),
+			WithTransport(transport),
+		}, opts...)...)
+		t.Cleanup(s.Close)
+
+		checkResp, err := s.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:              storeID,
+			AuthorizationModelId: model.GetId(),
+			TupleKey:             tuple.NewCheckRequestTupleKey("document:1", "viewer", user),
+			Context:              testutils.MustNewStruct(t, map[string]interface{}{"mfa_verified": true}),
+		})
+		require.NoError(t, err)
+
+		return checkResp, transport.headers
+	}
+
+	t.Run("disabled_by_default", func(t *testing.T) {
+		checkResp, headers := check(t, "user:maria")
+		require.True(t, checkResp.GetAllowed())
+		require.NotContains(t, headers, ObligationsHeader)
+	})
+
+	t.Run("conditional_grant", func(t *testing.T) {
+		checkResp, headers := check(t, "user:maria", WithCheckObligations(true))
+		require.True(t, checkResp.GetAllowed())
+		require.Equal(t, "step_up", headers[ObligationsHeader])
+	})
+
+	t.Run("unconditional_grant", func(t *testing.T) {
+		checkResp, headers := check(t, "user:jon", WithCheckObligations(true))
+		require.True(t, checkResp.GetAllowed())
+		require.NotContains(t, headers, ObligationsHeader)
+	})
+
+	t.Run("denied", func(t *testing.T) {
+		checkResp, headers := check(t, "user:bob", WithCheckObligations(true))
+		require.False(t, checkResp.GetAllowed())
+		require.NotContains(t, headers, ObligationsHeader)
+	})
+}
+
+func TestCheckUndefinedComputedRelationError(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	createStoreResp, err := ds.CreateStore(ctx, &openfgav1.Store{Id: ulid.Make().String(), Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type team
+			relations
+				define member: [user]
+		type folder
+			relations
+				define viewer: [user]
+		type document
+			relations
+				define parent: [folder, team]
+				define viewer: viewer from parent`)
+	require.NoError(t, ds.WriteAuthorizationModel(ctx, storeID, model))
+	require.NoError(t, ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "parent", "team:eng"),
+	}))
+
+	check := func(s *Server) (*openfgav1.CheckResponse, error) {
+		return s.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:  storeID,
+			TupleKey: tuple.NewCheckRequestTupleKey("document:1", "viewer", "user:jon"),
+		})
+	}
+
+	t.Run("denied_by_default", func(t *testing.T) {
+		s := MustNewServerWithOpts(
+			WithDatastore(ds),
+		)
+		t.Cleanup(s.Close)
+
+		resp, err := check(s)
+		require.NoError(t, err)
+		require.False(t, resp.GetAllowed())
+	})
+
+	t.Run("validation_error_if_enabled", func(t *testing.T) {
+		s := MustNewServerWithOpts(
+			WithDatastore(ds),
+			WithCheckUndefinedComputedRelationError(true),
+		)
+		t.Cleanup(s.Close)
+
+		_, err := check(s)
+		require.Equal(t, codes.Code(openfgav1.ErrorCode_validation_error), status.Code(err))
+		require.ErrorContains(t, err, "'team#viewer' is undefined")
+	})
+}
+
+func TestInvalidContextualTuples(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	createStoreResp, err := ds.CreateStore(ctx, &openfgav1.Store{Id: ulid.Make().String(), Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type document
+			relations
+				define viewer: [user]`)
+	require.NoError(t, ds.WriteAuthorizationModel(ctx, storeID, model))
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+	)
+	t.Cleanup(s.Close)
+
+	contextualTuples := &openfgav1.ContextualTupleKeys{
+		TupleKeys: []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+			tuple.NewTupleKey("document:1", "editor", "user:jon"),
+			tuple.NewTupleKey("document:1", "viewer", "folder:x"),
+		},
+	}
+
+	t.Run("check", func(t *testing.T) {
+		_, err := s.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:          storeID,
+			TupleKey:         tuple.NewCheckRequestTupleKey("document:1", "viewer", "user:jon"),
+			ContextualTuples: contextualTuples,
+		})
+		require.Equal(t, codes.Code(openfgav1.ErrorCode_validation_error), status.Code(err))
+		require.ErrorContains(t, err, "2 invalid contextual tuples: contextual tuple 1 'document:1#editor@user:jon' (type 'document', relation 'editor')")
+		require.ErrorContains(t, err, "contextual tuple 2 'document:1#viewer@folder:x' (type 'document', relation 'viewer')")
+	})
+
+	t.Run("list_objects", func(t *testing.T) {
+		_, err := s.ListObjects(ctx, &openfgav1.ListObjectsRequest{
+			StoreId:          storeID,
+			Type:             "document",
+			Relation:         "viewer",
+			User:             "user:jon",
+			ContextualTuples: contextualTuples,
+		})
+		require.Equal(t, codes.Code(openfgav1.ErrorCode_validation_error), status.Code(err))
+		require.ErrorContains(t, err, "2 invalid contextual tuples")
+	})
+}
+
+func TestWriteInvalidatesCachedChecks(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	createStoreResp, err := ds.CreateStore(ctx, &openfgav1.Store{Id: ulid.Make().String(), Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type document
+			relations
+				define viewer: [user]`)
+	require.NoError(t, ds.WriteAuthorizationModel(ctx, storeID, model))
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+		WithCheckQueryCacheEnabled(true),
+		WithCheckQueryCacheTTL(time.Hour),
+	)
+	t.Cleanup(s.Close)
+
+	check := func() bool {
+		resp, err := s.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:  storeID,
+			TupleKey: tuple.NewCheckRequestTupleKey("document:1", "viewer", "user:jon"),
+		})
+		require.NoError(t, err)
+		return resp.GetAllowed()
+	}
+
+	require.False(t, check())
+
+	_, err = s.Write(ctx, &openfgav1.WriteRequest{
+		StoreId: storeID,
+		Writes: &openfgav1.WriteRequestWrites{
+			TupleKeys: []*openfgav1.TupleKey{tuple.NewTupleKey("document:1", "viewer", "user:jon")},
+		},
+	})
+	require.NoError(t, err)
+
+	require.True(t, check())
+}
+
+func TestListObjectsIntersectionPlanning(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	createStoreResp, err := ds.CreateStore(ctx, &openfgav1.Store{Id: ulid.Make().String(), Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type document
+			relations
 				define allowed: [user]
-				define viewer: [user] and allowed`).GetTypeDefinitions()
+				define viewer: [user] and allowed`)
+	require.NoError(t, ds.WriteAuthorizationModel(ctx, storeID, model))
+
+	require.NoError(t, ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+		tuple.NewTupleKey("document:2", "viewer", "user:jon"),
+		tuple.NewTupleKey("document:3", "viewer", "user:jon"),
+		tuple.NewTupleKey("document:1", "allowed", "user:jon"),
+	}))
+
+	t.Run("disabled_by_default", func(t *testing.T) {
+		s := MustNewServerWithOpts(WithDatastore(ds))
+		t.Cleanup(s.Close)
+
+		require.Nil(t, s.tupleCounter)
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		s := MustNewServerWithOpts(
+			WithDatastore(ds),
+			WithExperimentals(ExperimentalListObjectsIntersectionPlanning),
+		)
+		t.Cleanup(s.Close)
+
+		require.NotNil(t, s.tupleCounter)
+
+		resp, err := s.ListObjects(ctx, &openfgav1.ListObjectsRequest{
+			StoreId:  storeID,
+			Type:     "document",
+			Relation: "viewer",
+			User:     "user:jon",
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"document:1"}, resp.GetObjects())
+	})
+}
+
+func TestDatastoreReadBudget(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	createStoreResp, err := ds.CreateStore(ctx, &openfgav1.Store{Id: ulid.Make().String(), Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type group
+			relations
+				define member: [user, group#member]
+
+		type document
+			relations
+				define viewer: [group#member]`)
+	require.NoError(t, ds.WriteAuthorizationModel(ctx, storeID, model))
+
+	require.NoError(t, ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "group:1#member"),
+		tuple.NewTupleKey("group:1", "member", "group:2#member"),
+		tuple.NewTupleKey("group:2", "member", "group:3#member"),
+		tuple.NewTupleKey("group:3", "member", "group:4#member"),
+		tuple.NewTupleKey("group:4", "member", "user:jon"),
+	}))
+
+	t.Run("within_budget", func(t *testing.T) {
+		s := MustNewServerWithOpts(
+			WithDatastore(ds),
+			WithExperimentals(ExperimentalEnableListUsers),
+			WithDatastoreReadBudget(100),
+		)
+		t.Cleanup(s.Close)
+
+		checkResp, err := s.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:  storeID,
+			TupleKey: tuple.NewCheckRequestTupleKey("document:1", "viewer", "user:jon"),
+		})
+		require.NoError(t, err)
+		require.True(t, checkResp.GetAllowed())
+
+		listObjectsResp, err := s.ListObjects(ctx, &openfgav1.ListObjectsRequest{
+			StoreId:  storeID,
+			Type:     "document",
+			Relation: "viewer",
+			User:     "user:jon",
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"document:1"}, listObjectsResp.GetObjects())
+
+		listUsersResp, err := s.ListUsers(ctx, &openfgav1.ListUsersRequest{
+			StoreId:     storeID,
+			Object:      &openfgav1.Object{Type: "document", Id: "1"},
+			Relation:    "viewer",
+			UserFilters: []*openfgav1.UserTypeFilter{{Type: "user"}},
+		})
+		require.NoError(t, err)
+		require.Len(t, listUsersResp.GetUsers(), 1)
+	})
+
+	t.Run("exceeded", func(t *testing.T) {
+		s := MustNewServerWithOpts(
+			WithDatastore(ds),
+			WithExperimentals(ExperimentalEnableListUsers),
+			WithDatastoreReadBudget(2),
+		)
+		t.Cleanup(s.Close)
+
+		_, err := s.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:  storeID,
+			TupleKey: tuple.NewCheckRequestTupleKey("document:1", "viewer", "user:jon"),
+		})
+		require.Equal(t, codes.ResourceExhausted, status.Code(err))
+		require.ErrorContains(t, err, "the request exceeded its budget of 2 datastore reads")
+
+		_, err = s.ListObjects(ctx, &openfgav1.ListObjectsRequest{
+			StoreId:  storeID,
+			Type:     "document",
+			Relation: "viewer",
+			User:     "user:jon",
+		})
+		require.Equal(t, codes.ResourceExhausted, status.Code(err))
+		require.ErrorContains(t, err, "the request exceeded its budget of 2 datastore reads")
+
+		_, err = s.ListUsers(ctx, &openfgav1.ListUsersRequest{
+			StoreId:     storeID,
+			Object:      &openfgav1.Object{Type: "document", Id: "1"},
+			Relation:    "viewer",
+			UserFilters: []*openfgav1.UserTypeFilter{{Type: "user"}},
+		})
+		require.Equal(t, codes.ResourceExhausted, status.Code(err))
+		require.ErrorContains(t, err, "the request exceeded its budget of 2 datastore reads")
+	})
+}
+
+func TestCheckReadHedging(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type document
+			relations
+				define viewer: [user]`)
+
+	// setup writes the model to the slow datastore, and returns a replica holding the only tuple, so that the
+	// Check is only allowed if the replica answers
+	setup := func(t *testing.T, ds storage.OpenFGADatastore) (string, *readCountingDatastore) {
+		createStoreResp, err := ds.CreateStore(ctx, &openfgav1.Store{Id: ulid.Make().String(), Name: "openfga-test"})
+		require.NoError(t, err)
+		storeID := createStoreResp.GetId()
+		require.NoError(t, ds.WriteAuthorizationModel(ctx, storeID, model))
+
+		replica := &readCountingDatastore{OpenFGADatastore: memory.New(), reads: map[string]int{}}
+		t.Cleanup(replica.Close)
+		require.NoError(t, replica.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+		}))
+
+		return storeID, replica
+	}
+
+	t.Run("reads_from_the_replica", func(t *testing.T) {
+		_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+		storeID, replica := setup(t, ds)
+
+		s := MustNewServerWithOpts(
+			WithDatastore(mockstorage.NewMockSlowDataStorage(ds, 100*time.Millisecond)),
+			WithCheckReadHedging(replica, storagewrappers.WithHedgingMinDelay(time.Millisecond)),
+		)
+		t.Cleanup(s.Close)
+
+		checkResp, err := s.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:  storeID,
+			TupleKey: tuple.NewCheckRequestTupleKey("document:1", "viewer", "user:jon"),
+		})
+		require.NoError(t, err)
+		require.True(t, checkResp.GetAllowed())
+		require.Equal(t, 1, replica.readsOf("document:1"))
+	})
+
+	t.Run("reads_from_the_replica_of_a_datastore_taking_snapshots", func(t *testing.T) {
+		ds := memory.New(memory.WithArtificialReadLatency(100*time.Millisecond), memory.WithAtomicBulkDeletes(true))
+		t.Cleanup(ds.Close)
+		storeID, replica := setup(t, ds)
+
+		s := MustNewServerWithOpts(
+			WithDatastore(ds),
+			WithCheckReadHedging(replica, storagewrappers.WithHedgingMinDelay(time.Millisecond)),
+		)
+		t.Cleanup(s.Close)
 
-	mockDatastore := mockstorage.NewMockOpenFGADatastore(mockController)
+		checkResp, err := s.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:  storeID,
+			TupleKey: tuple.NewCheckRequestTupleKey("document:1", "viewer", "user:jon"),
+		})
+		require.NoError(t, err)
+		require.True(t, checkResp.GetAllowed())
+		require.Equal(t, 1, replica.readsOf("document:1"))
+	})
+
+	t.Run("cannot_be_combined_with_tuple_snapshots", func(t *testing.T) {
+		ds := memory.New(memory.WithAtomicBulkDeletes(true))
+		t.Cleanup(ds.Close)
+
+		replica := memory.New()
+		t.Cleanup(replica.Close)
+
+		_, err := NewServerWithOpts(
+			WithDatastore(ds),
+			WithCheckReadHedging(replica),
+			WithCheckTupleSnapshots(true),
+		)
+		require.ErrorContains(t, err, "check tuple snapshots cannot be combined with check read hedging")
+	})
+}
+
+// batchCountingDatastore counts the batched userset reads of the datastore and of its snapshots.
+type batchCountingDatastore struct {
+	storage.OpenFGADatastore
+	batches *atomic.Int32
+}
+
+func (b *batchCountingDatastore) ReadUsersetTuplesBatch(ctx context.Context, store string, filter storage.ReadUsersetTuplesBatchFilter) (storage.TupleIterator, error) {
+	b.batches.Add(1)
+	return b.OpenFGADatastore.(storage.BatchUsersetTupleReader).ReadUsersetTuplesBatch(ctx, store, filter)
+}
+
+func (b *batchCountingDatastore) SnapshotTuples(ctx context.Context, store string) (storage.RelationshipTupleReader, error) {
+	snapshot, err := b.OpenFGADatastore.(storage.TupleSnapshotter).SnapshotTuples(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	return &batchCountingDatastore{OpenFGADatastore: snapshot.(storage.OpenFGADatastore), batches: b.batches}, nil
+}
+
+func TestCheckUsersetReadBatching(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type group
+			relations
+				define member: [user]
+
+		type team
+			relations
+				define member: [user, group#member]
+
+		type document
+			relations
+				define viewer: [team#member]`)
+
+	// user:jon is a member of the group of the last of the teams of document:1, whose usersets are read
+	// concurrently, and batched
+	const teams = 20
+	writes := []*openfgav1.TupleKey{tuple.NewTupleKey("group:1", "member", "user:jon")}
+	for i := 0; i < teams; i++ {
+		writes = append(writes, tuple.NewTupleKey("document:1", "viewer", fmt.Sprintf("team:%d#member", i)))
+	}
+	writes = append(writes, tuple.NewTupleKey(fmt.Sprintf("team:%d", teams-1), "member", "group:1#member"))
+
+	storeID := ulid.Make().String()
+
+	// check returns the number of batched userset reads of a Check of a server with the options, whose
+	// datastore reads are slow enough for the reads of the teams to be in flight together
+	check := func(t *testing.T, opts ...OpenFGAServiceV1Option) int32 {
+		var batches atomic.Int32
+		ds := &batchCountingDatastore{
+			OpenFGADatastore: memory.New(memory.WithArtificialReadLatency(10*time.Millisecond), memory.WithAtomicBulkDeletes(true)),
+			batches:          &batches,
+		}
+		t.Cleanup(ds.Close)
+
+		_, err := ds.CreateStore(ctx, &openfgav1.Store{Id: storeID, Name: "openfga-test"})
+		require.NoError(t, err)
+		require.NoError(t, ds.WriteAuthorizationModel(ctx, storeID, model))
+		require.NoError(t, ds.Write(ctx, storeID, nil, writes))
+
+		s := MustNewServerWithOpts(append([]OpenFGAServiceV1Option{
+			WithDatastore(ds),
+			WithCheckUsersetReadBatching(storagewrappers.WithUsersetBatchWindow(5 * time.Millisecond)),
+		}, opts...)...)
+		t.Cleanup(s.Close)
+
+		checkResp, err := s.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:  storeID,
+			TupleKey: tuple.NewCheckRequestTupleKey("document:1", "viewer", "user:jon"),
+		})
+		require.NoError(t, err)
+		require.True(t, checkResp.GetAllowed())
+
+		return batches.Load()
+	}
+
+	t.Run("batches_the_userset_reads", func(t *testing.T) {
+		require.Positive(t, check(t))
+	})
+
+	t.Run("batches_the_userset_reads_of_the_snapshots", func(t *testing.T) {
+		require.Positive(t, check(t, WithCheckTupleSnapshots(true)))
+	})
+
+	t.Run("batches_the_userset_reads_of_the_hedged_checks", func(t *testing.T) {
+		replica := memory.New()
+		t.Cleanup(replica.Close)
+		require.NoError(t, replica.Write(ctx, storeID, nil, writes))
+
+		require.Positive(t, check(t, WithCheckReadHedging(replica)))
+	})
+}
+
+func TestImportExportTuples(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	createStoreResp, err := ds.CreateStore(ctx, &openfgav1.Store{Id: ulid.Make().String(), Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type document
+			relations
+				define viewer: [user]`)
+	require.NoError(t, ds.WriteAuthorizationModel(ctx, storeID, model))
+
+	s := MustNewServerWithOpts(WithDatastore(ds))
+	t.Cleanup(s.Close)
+
+	tupleKeys := make([]*openfgav1.TupleKey, 0, 250)
+	for i := 0; i < 250; i++ {
+		tupleKeys = append(tupleKeys, tuple.NewTupleKey(fmt.Sprintf("document:%d", i), "viewer", "user:jon"))
+	}
+
+	// recvBatches returns a recv function sending the tuple keys in batches of the given size
+	recvBatches := func(tupleKeys []*openfgav1.TupleKey, size int) func() ([]*openfgav1.TupleKey, error) {
+		return func() ([]*openfgav1.TupleKey, error) {
+			if len(tupleKeys) == 0 {
+				return nil, io.EOF
+			}
+
+			batch := tupleKeys[:min(size, len(tupleKeys))]
+			tupleKeys = tupleKeys[len(batch):]
+			return batch, nil
+		}
+	}
+
+	// the import fails on the first invalid tuple, after writing the transactions before it
+	invalid := slices.Clone(tupleKeys[:150])
+	invalid[120] = tuple.NewTupleKey("document:120", "editor", "user:jon")
+
+	var progress []ImportTuplesProgress
+	err = s.ImportTuples(ctx, storeID, "", "", recvBatches(invalid, 70), func(p ImportTuplesProgress) error {
+		progress = append(progress, p)
+		return nil
+	})
+	require.Error(t, err)
+	require.Equal(t, codes.Code(openfgav1.ErrorCode_validation_error), status.Code(err))
+	require.Len(t, progress, 1)
+	require.Equal(t, int64(70), progress[0].Imported)
+
+	t.Run("resumes_from_the_checkpoint", func(t *testing.T) {
+		var resumed []ImportTuplesProgress
+		err := s.ImportTuples(ctx, storeID, "", progress[0].Checkpoint, recvBatches(tupleKeys, 250), func(p ImportTuplesProgress) error {
+			resumed = append(resumed, p)
+			return nil
+		})
+		require.NoError(t, err)
+		// the 70 tuples imported before the checkpoint are skipped, and the rest is written in
+		// transactions of at most 100 tuples
+		require.Len(t, resumed, 2)
+		require.Equal(t, int64(170), resumed[0].Imported)
+		require.Equal(t, int64(250), resumed[1].Imported)
+	})
+
+	t.Run("rejects_the_checkpoint_of_another_store", func(t *testing.T) {
+		err := s.ImportTuples(ctx, ulid.Make().String(), "", progress[0].Checkpoint, recvBatches(tupleKeys, 70), func(ImportTuplesProgress) error {
+			return nil
+		})
+		require.ErrorIs(t, err, serverErrors.InvalidContinuationToken)
+	})
+
+	t.Run("exports_all_the_tuples", func(t *testing.T) {
+		var batches []ExportTuplesBatch
+		err := s.ExportTuples(ctx, storeID, 100, "", func(batch ExportTuplesBatch) error {
+			batches = append(batches, batch)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Len(t, batches, 3)
+		require.Len(t, batches[2].Tuples, 50)
+		require.Empty(t, batches[2].Checkpoint)
+
+		// the export resumes after the first batch
+		var exported int
+		err = s.ExportTuples(ctx, storeID, 1000, batches[0].Checkpoint, func(batch ExportTuplesBatch) error {
+			exported += len(batch.Tuples)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 150, exported)
+	})
+
+	t.Run("send_error_stops_the_export", func(t *testing.T) {
+		errSend := errors.New("stream closed")
+		err := s.ExportTuples(ctx, storeID, 10, "", func(ExportTuplesBatch) error {
+			return errSend
+		})
+		require.ErrorIs(t, err, errSend)
+	})
+}
+
+func TestStoreShadowModel(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	createStoreResp, err := ds.CreateStore(ctx, &openfgav1.Store{Id: ulid.Make().String(), Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	// the candidate model makes the editors viewers, but is not the latest model of the store
+	candidate := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type document
+			relations
+				define editor: [user]
+				define viewer: [user] or editor`)
+	require.NoError(t, ds.WriteAuthorizationModel(ctx, storeID, candidate))
+
+	current := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type document
+			relations
+				define editor: [user]
+				define viewer: [user]`)
+	require.NoError(t, ds.WriteAuthorizationModel(ctx, storeID, current))
+
+	require.NoError(t, ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "editor", "user:jon"),
+		tuple.NewTupleKey("document:2", "viewer", "user:jon"),
+	}))
+
+	observerLogger, logs := observer.New(zap.WarnLevel)
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+		WithLogger(&logger.ZapLogger{Logger: zap.New(observerLogger)}),
+		WithStoreShadowModel(storeID, candidate.GetId()),
+	)
+
+	for _, object := range []string{"document:1", "document:2"} {
+		resp, err := s.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:  storeID,
+			TupleKey: tuple.NewCheckRequestTupleKey(object, "viewer", "user:jon"),
+		})
+		require.NoError(t, err)
+
+		// the Checks are answered with the latest model
+		require.Equal(t, object == "document:2", resp.GetAllowed())
+	}
+
+	// waits for the shadow resolutions
+	s.Close()
+
+	entries := logs.FilterMessage("shadow check mismatch").All()
+	require.Len(t, entries, 1)
+	fields := entries[0].ContextMap()
+	require.Equal(t, "document:1#viewer@user:jon", fields["tuple_key"])
+	require.Equal(t, current.GetId(), fields["authorization_model_id"])
+	require.Equal(t, candidate.GetId(), fields["shadow_authorization_model_id"])
+	require.Equal(t, "document:1#viewer@document:1#editor -> document:1#editor@user:jon", fields["granting_path"])
+}
+
+func TestStreamedListObjectsCompleteTrailer(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	createStoreResp, err := ds.CreateStore(ctx, &openfgav1.Store{Id: ulid.Make().String(), Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type document
+			relations
+				define viewer: [user]`)
+	require.NoError(t, ds.WriteAuthorizationModel(ctx, storeID, model))
+	require.NoError(t, ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+	}))
+
+	s := MustNewServerWithOpts(WithDatastore(ds))
+	t.Cleanup(s.Close)
+
+	srv := NewMockStreamServer()
+	err = s.StreamedListObjects(&openfgav1.StreamedListObjectsRequest{
+		StoreId:  storeID,
+		Type:     "document",
+		Relation: "viewer",
+		User:     "user:jon",
+	}, srv)
+	require.NoError(t, err)
+	require.Equal(t, []string{"true"}, srv.trailer.Get(ListObjectsCompleteTrailer))
+}
+
+func TestWriteAuthorizationModelDryRun(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	createStoreResp, err := ds.CreateStore(ctx, &openfgav1.Store{Id: ulid.Make().String(), Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	s := MustNewServerWithOpts(WithDatastore(ds))
+	t.Cleanup(s.Close)
+
+	req := &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:       storeID,
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: language.MustTransformDSLToProto(`
+			model
+				schema 1.1
+
+			type user
+			type document
+				relations
+					define viewer: [user]`).GetTypeDefinitions(),
+	}
+
+	t.Run("first_model", func(t *testing.T) {
+		res, err := s.WriteAuthorizationModelDryRun(ctx, req)
+		require.NoError(t, err)
+		require.Empty(t, res.LatestAuthorizationModelID)
+		require.Len(t, res.Changes, 2)
+
+		// the model is not written
+		_, err = ds.FindLatestAuthorizationModel(ctx, storeID)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	writeResp, err := s.WriteAuthorizationModel(ctx, req)
+	require.NoError(t, err)
+
+	t.Run("changes_from_the_latest_model", func(t *testing.T) {
+		res, err := s.WriteAuthorizationModelDryRun(ctx, &openfgav1.WriteAuthorizationModelRequest{
+			StoreId:       storeID,
+			SchemaVersion: typesystem.SchemaVersion1_1,
+			TypeDefinitions: language.MustTransformDSLToProto(`
+				model
+					schema 1.1
+
+				type user
+				type document
+					relations
+						define editor: [user]
+						define viewer: editor`).GetTypeDefinitions(),
+		})
+		require.NoError(t, err)
+		require.Equal(t, writeResp.GetAuthorizationModelId(), res.LatestAuthorizationModelID)
+		require.Equal(t, []typesystem.ModelChange{
+			{Kind: typesystem.ModelChangeRelationAdded, ObjectType: "document", Relation: "editor"},
+			{Kind: typesystem.ModelChangeRewriteChanged, ObjectType: "document", Relation: "viewer", Breaking: true},
+			{Kind: typesystem.ModelChangeTypeRestrictionsNarrowed, ObjectType: "document", Relation: "viewer", TypeRestrictions: []string{"user"}, Breaking: true},
+		}, res.Changes)
+	})
+
+	t.Run("invalid_model", func(t *testing.T) {
+		_, err := s.WriteAuthorizationModelDryRun(ctx, &openfgav1.WriteAuthorizationModelRequest{
+			StoreId:       storeID,
+			SchemaVersion: typesystem.SchemaVersion1_1,
+			TypeDefinitions: []*openfgav1.TypeDefinition{
+				{Type: "document", Relations: map[string]*openfgav1.Userset{"viewer": typesystem.ComputedUserset("editor")}},
+			},
+		})
+		e, ok := status.FromError(err)
+		require.True(t, ok)
+		require.Equal(t, codes.Code(openfgav1.ErrorCode_invalid_authorization_model), e.Code())
+	})
+}
+
+func TestListObjectsReadCache(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+		WithListObjectsReadCacheEnabled(true),
+		WithListObjectsReadCacheTTL(time.Hour),
+	)
+	t.Cleanup(s.Close)
+
+	require.Equal(t, CheckQueryCacheConfig{Enabled: true, Limit: serverconfig.DefaultListObjectsReadCacheLimit, TTL: time.Hour}, s.DumpConfig().ListObjectsReadCache)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+		Name: "openfga-test",
+	})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type document
+			relations
+				define viewer: [user]`)
 
-	mockDatastore.EXPECT().ReadAuthorizationModel(gomock.Any(), store, modelID).AnyTimes().Return(&openfgav1.AuthorizationModel{
-		SchemaVersion:   typesystem.SchemaVersion1_1,
-		TypeDefinitions: typedefs,
-	}, nil)
-	mockDatastore.EXPECT().ReadStartingWithUser(gomock.Any(), store, gomock.Any()).AnyTimes().Return(nil, errors.New("error reading from storage"))
+	writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:         storeID,
+		SchemaVersion:   model.GetSchemaVersion(),
+		TypeDefinitions: model.GetTypeDefinitions(),
+	})
+	require.NoError(t, err)
 
-	s := MustNewServerWithOpts(
-		WithDatastore(mockDatastore),
-	)
-	b.Cleanup(func() {
-		mockDatastore.EXPECT().Close().Times(1)
-		s.Close()
+	_, err = s.Write(ctx, &openfgav1.WriteRequest{
+		StoreId: storeID,
+		Writes: &openfgav1.WriteRequestWrites{
+			TupleKeys: []*openfgav1.TupleKey{
+				tuple.NewTupleKey("document:1", "viewer", "user:anne"),
+			},
+		},
 	})
+	require.NoError(t, err)
 
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		_, err := s.ListObjects(ctx, &openfgav1.ListObjectsRequest{
-			StoreId:              store,
-			AuthorizationModelId: modelID,
-			Type:                 "repo",
+	listObjects := func(t *testing.T) []string {
+		resp, err := s.ListObjects(ctx, &openfgav1.ListObjectsRequest{
+			StoreId:              storeID,
+			AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+			Type:                 "document",
 			Relation:             "viewer",
-			User:                 "user:bob",
+			User:                 "user:anne",
 		})
+		require.NoError(t, err)
+		return resp.GetObjects()
+	}
 
-		require.ErrorIs(b, err, serverErrors.NewInternalError("", errors.New("error reading from storage")))
+	require.Equal(t, []string{"document:1"}, listObjects(t))
 
-		err = s.StreamedListObjects(&openfgav1.StreamedListObjectsRequest{
-			StoreId:              store,
-			AuthorizationModelId: modelID,
-			Type:                 "repo",
-			Relation:             "viewer",
-			User:                 "user:bob",
-		}, NewMockStreamServer())
+	// tuples written without going through the server are not observed until the cached reads expire
+	require.NoError(t, ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:2", "viewer", "user:anne"),
+	}))
+	require.Equal(t, []string{"document:1"}, listObjects(t))
 
-		require.ErrorIs(b, err, serverErrors.NewInternalError("", errors.New("error reading from storage")))
-	}
+	// a write to the store invalidates the cached reads
+	_, err = s.Write(ctx, &openfgav1.WriteRequest{
+		StoreId: storeID,
+		Writes: &openfgav1.WriteRequestWrites{
+			TupleKeys: []*openfgav1.TupleKey{
+				tuple.NewTupleKey("document:3", "viewer", "user:bob"),
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"document:1", "document:2"}, listObjects(t))
 }
 
-func TestListObjects_ErrorCases(t *testing.T) {
+func TestDatastoreHealth(t *testing.T) {
 	t.Cleanup(func() {
 		goleak.VerifyNone(t)
 	})
 
 	ctx := context.Background()
-	store := ulid.Make().String()
+	serviceName := openfgav1.OpenFGAService_ServiceDesc.ServiceName
 
 	mockController := gomock.NewController(t)
 	defer mockController.Finish()
 
-	t.Run("database_errors", func(t *testing.T) {
-		mockDatastore := mockstorage.NewMockOpenFGADatastore(mockController)
+	replica := mockstorage.NewMockOpenFGADatastore(mockController)
+	replica.EXPECT().IsReady(gomock.Any()).Return(storage.ReadinessStatus{
+		Message: "datastore requires migrations",
+	}, nil).AnyTimes()
 
-		s := MustNewServerWithOpts(
-			WithDatastore(mockDatastore),
-		)
-		t.Cleanup(func() {
-			mockDatastore.EXPECT().Close().Times(1)
-			s.Close()
-		})
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
 
-		modelID := ulid.Make().String()
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+		WithCheckReadHedging(replica),
+	)
+	t.Cleanup(s.Close)
 
-		mockDatastore.EXPECT().ReadAuthorizationModel(gomock.Any(), store, modelID).AnyTimes().Return(&openfgav1.AuthorizationModel{
-			SchemaVersion: typesystem.SchemaVersion1_1,
-			TypeDefinitions: language.MustTransformDSLToProto(`
-				model
-					schema 1.1
+	require.Equal(t, []health.DatastoreHealth{
+		{Name: PrimaryDatastoreName, Ready: true},
+		{Name: ReplicaDatastoreName, Message: "datastore requires migrations"},
+	}, s.DatastoreHealth(ctx))
 
-				type user
+	checker := &health.Checker{TargetService: s, TargetServiceName: serviceName}
 
-				type document
-					relations
-						define viewer: [user, user:*]`).GetTypeDefinitions(),
-		}, nil)
+	check := func(t *testing.T, service string) healthv1pb.HealthCheckResponse_ServingStatus {
+		resp, err := checker.Check(ctx, &healthv1pb.HealthCheckRequest{Service: service})
+		require.NoError(t, err)
+		return resp.GetStatus()
+	}
 
-		mockDatastore.EXPECT().ReadStartingWithUser(gomock.Any(), store, storage.ReadStartingWithUserFilter{
-			ObjectType: "document",
-			Relation:   "viewer",
-			UserFilter: []*openfgav1.ObjectRelation{
-				{Object: "user:*"},
-				{Object: "user:bob"},
-			}}).AnyTimes().Return(nil, errors.New("error reading from storage"))
+	// the server serves with a degraded replica, which the health of the datastores reports
+	require.Equal(t, healthv1pb.HealthCheckResponse_SERVING, check(t, ""))
+	require.Equal(t, healthv1pb.HealthCheckResponse_SERVING, check(t, serviceName))
+	require.Equal(t, healthv1pb.HealthCheckResponse_NOT_SERVING, check(t, serviceName+health.DatastoresServiceSuffix))
+	require.Equal(t, healthv1pb.HealthCheckResponse_SERVING, check(t, serviceName+health.DatastoreServiceSuffix+PrimaryDatastoreName))
+	require.Equal(t, healthv1pb.HealthCheckResponse_NOT_SERVING, check(t, serviceName+health.DatastoreServiceSuffix+ReplicaDatastoreName))
 
-		t.Run("error_listing_objects_from_storage_in_non-streaming_version", func(t *testing.T) {
-			res, err := s.ListObjects(ctx, &openfgav1.ListObjectsRequest{
-				StoreId:              store,
-				AuthorizationModelId: modelID,
-				Type:                 "document",
-				Relation:             "viewer",
-				User:                 "user:bob",
-			})
+	_, err := checker.Check(ctx, &healthv1pb.HealthCheckRequest{Service: serviceName + health.DatastoreServiceSuffix + "unknown"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
 
-			require.Nil(t, res)
-			require.ErrorIs(t, err, serverErrors.NewInternalError("", errors.New("error reading from storage")))
-		})
+func TestReadFilteredChanges(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
 
-		t.Run("error_listing_objects_from_storage_in_streaming_version", func(t *testing.T) {
-			err := s.StreamedListObjects(&openfgav1.StreamedListObjectsRequest{
-				StoreId:              store,
-				AuthorizationModelId: modelID,
-				Type:                 "document",
-				Relation:             "viewer",
-				User:                 "user:bob",
-			}, NewMockStreamServer())
+	ctx := context.Background()
 
-			require.ErrorIs(t, err, serverErrors.NewInternalError("", errors.New("error reading from storage")))
-		})
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	createStoreResp, err := ds.CreateStore(ctx, &openfgav1.Store{Id: ulid.Make().String(), Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type document
+			relations
+				define viewer: [user]
+				define editor: [user]`)
+	require.NoError(t, ds.WriteAuthorizationModel(ctx, storeID, model))
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+		WithChangelogHorizonOffset(0),
+	)
+	t.Cleanup(s.Close)
+
+	_, err = s.Write(ctx, &openfgav1.WriteRequest{
+		StoreId: storeID,
+		Writes: &openfgav1.WriteRequestWrites{
+			TupleKeys: []*openfgav1.TupleKey{
+				tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+				tuple.NewTupleKey("document:1", "editor", "user:jon"),
+				tuple.NewTupleKey("document:2", "viewer", "user:maria"),
+			},
+		},
 	})
+	require.NoError(t, err)
 
-	t.Run("graph_resolution_errors", func(t *testing.T) {
-		s := MustNewServerWithOpts(
-			WithDatastore(memory.New()),
-			WithResolveNodeLimit(2),
-		)
-		t.Cleanup(s.Close)
+	filter := storage.ReadChangesFilter{ObjectType: "document", Relation: "viewer"}
 
-		writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
-			StoreId:       store,
-			SchemaVersion: typesystem.SchemaVersion1_1,
-			TypeDefinitions: language.MustTransformDSLToProto(`
-				model
-					schema 1.1
+	resp, err := s.ReadFilteredChanges(ctx, storeID, filter, 1, "")
+	require.NoError(t, err)
+	require.Len(t, resp.GetChanges(), 1)
+	require.Equal(t, "document:1#viewer@user:jon", tuple.TupleKeyToString(resp.GetChanges()[0].GetTupleKey()))
 
-				type user
+	resp, err = s.ReadFilteredChanges(ctx, storeID, filter, 1, resp.GetContinuationToken())
+	require.NoError(t, err)
+	require.Len(t, resp.GetChanges(), 1)
+	require.Equal(t, "document:2#viewer@user:maria", tuple.TupleKeyToString(resp.GetChanges()[0].GetTupleKey()))
 
-				type group
-					relations
-						define member: [user, group#member]
+	// the last page is empty, and keeps the continuation token
+	token := resp.GetContinuationToken()
+	resp, err = s.ReadFilteredChanges(ctx, storeID, filter, 1, token)
+	require.NoError(t, err)
+	require.Empty(t, resp.GetChanges())
+	require.Equal(t, token, resp.GetContinuationToken())
 
-				type document
-					relations
-						define viewer: [group#member]`).GetTypeDefinitions(),
-		})
-		require.NoError(t, err)
+	t.Run("invalid_time_range", func(t *testing.T) {
+		now := time.Now()
+		_, err := s.ReadFilteredChanges(ctx, storeID, storage.ReadChangesFilter{StartTime: now, EndTime: now}, 0, "")
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
 
-		_, err = s.Write(ctx, &openfgav1.WriteRequest{
-			StoreId: store,
+	t.Run("mismatched_continuation_token", func(t *testing.T) {
+		_, err := s.ReadFilteredChanges(ctx, storeID, storage.ReadChangesFilter{ObjectType: "user"}, 1, token)
+		require.ErrorIs(t, err, serverErrors.MismatchObjectType)
+	})
+}
+
+func TestWriteExpiry(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	createStoreResp, err := ds.CreateStore(ctx, &openfgav1.Store{Id: ulid.Make().String(), Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type document
+			relations
+				define viewer: [user]`)
+	require.NoError(t, ds.WriteAuthorizationModel(ctx, storeID, model))
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+		WithTupleExpiryReaperInterval(10*time.Millisecond),
+	)
+	t.Cleanup(s.Close)
+
+	write := func(expiresAt time.Time) error {
+		_, err := s.WriteWithOptions(ctx, &openfgav1.WriteRequest{
+			StoreId: storeID,
 			Writes: &openfgav1.WriteRequestWrites{
-				TupleKeys: []*openfgav1.TupleKey{
-					tuple.NewTupleKey("document:1", "viewer", "group:1#member"),
-					tuple.NewTupleKey("group:1", "member", "group:2#member"),
-					tuple.NewTupleKey("group:2", "member", "group:3#member"),
-					tuple.NewTupleKey("group:3", "member", "user:jon"),
-				},
+				TupleKeys: []*openfgav1.TupleKey{tuple.NewTupleKey("document:1", "viewer", "user:jon")},
 			},
+		}, WithWriteExpiry(expiresAt))
+		return err
+	}
+
+	check := func() bool {
+		resp, err := s.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:  storeID,
+			TupleKey: tuple.NewCheckRequestTupleKey("document:1", "viewer", "user:jon"),
 		})
 		require.NoError(t, err)
+		return resp.GetAllowed()
+	}
 
-		t.Run("resolution_depth_exceeded_error_unary", func(t *testing.T) {
-			res, err := s.ListObjects(ctx, &openfgav1.ListObjectsRequest{
-				StoreId:              store,
-				AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
-				Type:                 "document",
-				Relation:             "viewer",
-				User:                 "user:jon",
-			})
+	t.Run("the_expiry_must_be_in_the_future", func(t *testing.T) {
+		err := write(time.Now().Add(-time.Second))
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
 
-			require.Nil(t, res)
-			require.ErrorIs(t, err, serverErrors.AuthorizationModelResolutionTooComplex)
-		})
+	require.NoError(t, write(time.Now().Add(200*time.Millisecond)))
+	require.True(t, check())
 
-		t.Run("resolution_depth_exceeded_error_streaming", func(t *testing.T) {
-			err := s.StreamedListObjects(&openfgav1.StreamedListObjectsRequest{
-				StoreId:              store,
-				AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
-				Type:                 "document",
-				Relation:             "viewer",
-				User:                 "user:jon",
-			}, NewMockStreamServer())
+	// the reaper deletes the tuple once it expired, and records the delete in the changelog
+	require.Eventually(t, func() bool {
+		resp, err := s.ReadChanges(ctx, &openfgav1.ReadChangesRequest{StoreId: storeID})
+		require.NoError(t, err)
+		changes := resp.GetChanges()
+		return len(changes) == 2 && changes[1].GetOperation() == openfgav1.TupleOperation_TUPLE_OPERATION_DELETE
+	}, 5*time.Second, 10*time.Millisecond)
+	require.False(t, check())
 
-			require.ErrorIs(t, err, serverErrors.AuthorizationModelResolutionTooComplex)
-		})
+	t.Run("datastore_without_tuple_expiry_support", func(t *testing.T) {
+		s := MustNewServerWithOpts(
+			WithDatastore(&delayedTupleReaderDatastore{OpenFGADatastore: memory.New()}),
+		)
+		t.Cleanup(s.Close)
+
+		_, err := s.WriteWithOptions(ctx, &openfgav1.WriteRequest{StoreId: storeID}, WithWriteExpiry(time.Now().Add(time.Hour)))
+		require.ErrorIs(t, err, serverErrors.TupleExpiryUnsupported)
+	})
+
+	t.Run("reaper_requires_tuple_expiry_support", func(t *testing.T) {
+		ds := memory.New()
+		t.Cleanup(ds.Close)
+
+		_, err := NewServerWithOpts(
+			WithDatastore(&delayedTupleReaderDatastore{OpenFGADatastore: ds}),
+			WithTupleExpiryReaperInterval(time.Minute),
+		)
+		require.ErrorContains(t, err, "the tuple expiry reaper requires a datastore that supports expiring tuples")
 	})
 }
 
-func TestAuthorizationModelInvalidSchemaVersion(t *testing.T) {
+func TestListRelations(t *testing.T) {
 	t.Cleanup(func() {
 		goleak.VerifyNone(t)
 	})
 
 	ctx := context.Background()
-	store := ulid.Make().String()
-	modelID := ulid.Make().String()
 
-	mockController := gomock.NewController(t)
-	defer mockController.Finish()
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
+
+	s := MustNewServerWithOpts(WithDatastore(ds))
+	t.Cleanup(s.Close)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+		Name: "openfga-test",
+	})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type folder
+			relations
+				define viewer: [user]
 
-	mockDatastore := mockstorage.NewMockOpenFGADatastore(mockController)
+		type document
+			relations
+				define parent: [folder]
+				define owner: [user]
+				define editor: [user] or owner
+				define viewer: [user] or editor or viewer from parent
+				define deleter: [user] and owner`)
 
-	mockDatastore.EXPECT().ReadAuthorizationModel(gomock.Any(), store, modelID).AnyTimes().Return(&openfgav1.AuthorizationModel{
-		SchemaVersion: typesystem.SchemaVersion1_0,
-		TypeDefinitions: []*openfgav1.TypeDefinition{
-			{
-				Type: "user",
-			},
-			{
-				Type: "team",
-				Relations: map[string]*openfgav1.Userset{
-					"member": typesystem.This(),
-				},
+	writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:         storeID,
+		SchemaVersion:   model.GetSchemaVersion(),
+		TypeDefinitions: model.GetTypeDefinitions(),
+	})
+	require.NoError(t, err)
+
+	_, err = s.Write(ctx, &openfgav1.WriteRequest{
+		StoreId: storeID,
+		Writes: &openfgav1.WriteRequestWrites{
+			TupleKeys: []*openfgav1.TupleKey{
+				tuple.NewTupleKey("document:1", "owner", "user:jon"),
+				tuple.NewTupleKey("document:1", "parent", "folder:1"),
+				tuple.NewTupleKey("folder:1", "viewer", "user:maria"),
 			},
 		},
-	}, nil)
-
-	s := MustNewServerWithOpts(
-		WithDatastore(mockDatastore),
-	)
-	t.Cleanup(func() {
-		mockDatastore.EXPECT().Close().Times(1)
-		s.Close()
 	})
+	require.NoError(t, err)
 
-	t.Run("invalid_schema_error_in_check", func(t *testing.T) {
-		_, err := s.Check(ctx, &openfgav1.CheckRequest{
-			StoreId:              store,
-			AuthorizationModelId: modelID,
-			TupleKey: tuple.NewCheckRequestTupleKey(
-				"team:abc",
-				"member",
-				"user:anne"),
+	t.Run("all_relations", func(t *testing.T) {
+		resp, err := s.ListRelations(ctx, &ListRelationsRequest{
+			StoreID: storeID,
+			Object:  "document:1",
+			User:    "user:jon",
 		})
-		require.Error(t, err)
-		e, ok := status.FromError(err)
-		require.True(t, ok)
-		require.Equal(t, codes.Code(openfgav1.ErrorCode_validation_error), e.Code())
-	})
+		require.NoError(t, err)
+		require.Equal(t, []string{"editor", "owner", "viewer"}, resp.Relations)
 
-	t.Run("invalid_schema_error_in_list_objects", func(t *testing.T) {
-		_, err := s.ListObjects(ctx, &openfgav1.ListObjectsRequest{
-			StoreId:              store,
-			AuthorizationModelId: modelID,
-			Type:                 "team",
-			Relation:             "member",
-			User:                 "user:anne",
+		resp, err = s.ListRelations(ctx, &ListRelationsRequest{
+			StoreID:              storeID,
+			AuthorizationModelID: writeModelResp.GetAuthorizationModelId(),
+			Object:               "document:1",
+			User:                 "user:maria",
 		})
-		require.Error(t, err)
-		e, ok := status.FromError(err)
-		require.True(t, ok)
-		require.Equal(t, codes.Code(openfgav1.ErrorCode_validation_error), e.Code())
+		require.NoError(t, err)
+		require.Equal(t, []string{"viewer"}, resp.Relations)
 	})
 
-	t.Run("invalid_schema_error_in_streamed_list_objects", func(t *testing.T) {
-		err := s.StreamedListObjects(&openfgav1.StreamedListObjectsRequest{
-			StoreId:              store,
-			AuthorizationModelId: modelID,
-			Type:                 "team",
-			Relation:             "member",
-			User:                 "user:anne",
-		}, NewMockStreamServer())
-		require.Error(t, err)
-		e, ok := status.FromError(err)
-		require.True(t, ok)
-		require.Equal(t, codes.Code(openfgav1.ErrorCode_validation_error), e.Code())
+	t.Run("requested_relations", func(t *testing.T) {
+		resp, err := s.ListRelations(ctx, &ListRelationsRequest{
+			StoreID:   storeID,
+			Object:    "document:1",
+			User:      "user:jon",
+			Relations: []string{"viewer", "deleter"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"viewer"}, resp.Relations)
 	})
 
-	t.Run("invalid_schema_error_in_write", func(t *testing.T) {
-		_, err := s.Write(ctx, &openfgav1.WriteRequest{
-			StoreId:              store,
-			AuthorizationModelId: modelID,
-			Writes: &openfgav1.WriteRequestWrites{
-				TupleKeys: []*openfgav1.TupleKey{
-					{
-						Object:   "repo:openfga/openfga",
-						Relation: "reader",
-						User:     "user:anne",
-					},
-				},
+	t.Run("contextual_tuples", func(t *testing.T) {
+		resp, err := s.ListRelations(ctx, &ListRelationsRequest{
+			StoreID: storeID,
+			Object:  "document:1",
+			User:    "user:jon",
+			ContextualTuples: []*openfgav1.TupleKey{
+				tuple.NewTupleKey("document:1", "deleter", "user:jon"),
 			},
 		})
-		require.Error(t, err)
-		e, ok := status.FromError(err)
-		require.True(t, ok)
-		require.Equal(t, codes.Code(openfgav1.ErrorCode_validation_error), e.Code())
+		require.NoError(t, err)
+		require.Equal(t, []string{"deleter", "editor", "owner", "viewer"}, resp.Relations)
 	})
 
-	t.Run("invalid_schema_error_in_write_model", func(t *testing.T) {
-		mockDatastore.EXPECT().MaxTypesPerAuthorizationModel().Return(100)
-
-		_, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
-			StoreId:       store,
-			SchemaVersion: typesystem.SchemaVersion1_0,
-			TypeDefinitions: language.MustTransformDSLToProto(`
-				model
-					schema 1.1
+	t.Run("no_relations", func(t *testing.T) {
+		resp, err := s.ListRelations(ctx, &ListRelationsRequest{
+			StoreID: storeID,
+			Object:  "document:2",
+			User:    "user:jon",
+		})
+		require.NoError(t, err)
+		require.Empty(t, resp.Relations)
+	})
 
-				type repo
-				`).GetTypeDefinitions(),
+	t.Run("undefined_type", func(t *testing.T) {
+		_, err := s.ListRelations(ctx, &ListRelationsRequest{
+			StoreID: storeID,
+			Object:  "unknown:1",
+			User:    "user:jon",
 		})
-		require.Error(t, err)
-		e, ok := status.FromError(err)
-		require.True(t, ok)
-		require.Equal(t, codes.Code(openfgav1.ErrorCode_invalid_authorization_model), e.Code(), err)
+		require.Equal(t, codes.Code(openfgav1.ErrorCode_validation_error), status.Code(err))
 	})
 
-	t.Run("invalid_schema_error_in_write_assertion", func(t *testing.T) {
-		_, err := s.WriteAssertions(ctx, &openfgav1.WriteAssertionsRequest{
-			StoreId:              store,
-			AuthorizationModelId: modelID,
-			Assertions: []*openfgav1.Assertion{{
-				TupleKey:    tuple.NewAssertionTupleKey("repo:test", "reader", "user:elbuo"),
-				Expectation: false,
-			}},
+	t.Run("undefined_relation", func(t *testing.T) {
+		_, err := s.ListRelations(ctx, &ListRelationsRequest{
+			StoreID:   storeID,
+			Object:    "document:1",
+			User:      "user:jon",
+			Relations: []string{"unknown"},
 		})
-		require.Error(t, err)
-		e, ok := status.FromError(err)
-		require.True(t, ok)
-		require.Equal(t, codes.Code(openfgav1.ErrorCode_validation_error), e.Code())
+		require.Equal(t, codes.Code(openfgav1.ErrorCode_validation_error), status.Code(err))
 	})
 }
 
-func TestDefaultMaxConcurrentReadSettings(t *testing.T) {
+func TestCheckWithHigherConsistency(t *testing.T) {
 	t.Cleanup(func() {
 		goleak.VerifyNone(t)
 	})
 
-	cfg := serverconfig.DefaultConfig()
-	require.EqualValues(t, math.MaxUint32, cfg.MaxConcurrentReadsForCheck)
-	require.EqualValues(t, math.MaxUint32, cfg.MaxConcurrentReadsForListObjects)
-	require.EqualValues(t, math.MaxUint32, cfg.MaxConcurrentReadsForListUsers)
+	ctx := context.Background()
+
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
 
 	s := MustNewServerWithOpts(
-		WithDatastore(memory.New()),
+		WithDatastore(ds),
+		WithCheckQueryCacheEnabled(true),
+		WithCheckQueryCacheTTL(time.Minute),
 	)
 	t.Cleanup(s.Close)
-	require.EqualValues(t, math.MaxUint32, s.maxConcurrentReadsForCheck)
-	require.EqualValues(t, math.MaxUint32, s.maxConcurrentReadsForListObjects)
-	require.EqualValues(t, math.MaxUint32, s.maxConcurrentReadsForListUsers)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+		Name: "openfga-test",
+	})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+
+		type document
+			relations
+				define viewer: [user]`)
+
+	_, err = s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:         storeID,
+		SchemaVersion:   model.GetSchemaVersion(),
+		TypeDefinitions: model.GetTypeDefinitions(),
+	})
+	require.NoError(t, err)
+
+	checkReq := &openfgav1.CheckRequest{
+		StoreId:  storeID,
+		TupleKey: tuple.NewCheckRequestTupleKey("document:1", "viewer", "user:jon"),
+	}
+
+	resp, err := s.Check(ctx, checkReq)
+	require.NoError(t, err)
+	require.False(t, resp.GetAllowed())
+
+	// written behind the server's back, so that the cached result is not invalidated
+	err = ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tuple.NewTupleKey("document:1", "viewer", "user:jon")})
+	require.NoError(t, err)
+
+	resp, err = s.Check(ctx, checkReq)
+	require.NoError(t, err)
+	require.False(t, resp.GetAllowed())
+
+	resp, err = s.CheckWithOptions(ctx, checkReq, WithConsistency(storage.ConsistencyHigher))
+	require.NoError(t, err)
+	require.True(t, resp.GetAllowed())
+
+	// the preference may also be set in the context
+	resp, err = s.Check(storage.ContextWithConsistency(ctx, storage.ConsistencyHigher), checkReq)
+	require.NoError(t, err)
+	require.True(t, resp.GetAllowed())
 }
 
-func TestDelegateCheckResolver(t *testing.T) {
+// auditRecordingSink records the audit events written to it.
+type auditRecordingSink struct {
+	events []*audit.Event
+}
+
+func (s *auditRecordingSink) Write(event *audit.Event) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *auditRecordingSink) Close() error {
+	return nil
+}
+
+func TestAuditLogger(t *testing.T) {
 	t.Cleanup(func() {
 		goleak.VerifyNone(t)
 	})
-	t.Run("default_check_resolver_alone", func(t *testing.T) {
-		cfg := serverconfig.DefaultConfig()
-		require.False(t, cfg.CheckDispatchThrottling.Enabled)
-		require.False(t, cfg.DispatchThrottling.Enabled)
-		require.False(t, cfg.ListObjectsDispatchThrottling.Enabled)
-		require.False(t, cfg.CheckQueryCache.Enabled)
 
-		ds := memory.New()
-		t.Cleanup(ds.Close)
-		s := MustNewServerWithOpts(
-			WithDatastore(ds),
-		)
-		t.Cleanup(s.Close)
-		require.Nil(t, s.dispatchThrottlingCheckResolver)
-		require.False(t, s.checkDispatchThrottlingEnabled)
+	ctx := authn.ContextWithAuthClaims(context.Background(), &authn.AuthClaims{Subject: "client-a"})
 
-		require.False(t, s.checkQueryCacheEnabled)
-		require.Nil(t, s.cachedCheckResolver)
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
 
-		require.NotNil(t, s.checkResolver)
-		cycleDetectionCheckResolver, ok := s.checkResolver.(*graph.CycleDetectionCheckResolver)
-		require.True(t, ok)
+	sink := &auditRecordingSink{}
+	auditLogger := audit.NewLogger([]audit.Sink{sink}, audit.WithCheckSampleRate(1))
 
-		localCheckResolver, ok := cycleDetectionCheckResolver.GetDelegate().(*graph.LocalChecker)
-		require.True(t, ok)
+	s := MustNewServerWithOpts(WithDatastore(ds), WithAuditLogger(auditLogger))
 
-		_, ok = localCheckResolver.GetDelegate().(*graph.CycleDetectionCheckResolver)
-		require.True(t, ok)
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+		Name: "openfga-test",
 	})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
 
-	t.Run("dispatch_throttling_check_resolver_enabled", func(t *testing.T) {
-		ds := memory.New()
-		t.Cleanup(ds.Close)
-		const dispatchThreshold = 50
-		s := MustNewServerWithOpts(
-			WithDatastore(ds),
-			WithDispatchThrottlingCheckResolverEnabled(true),
-			WithDispatchThrottlingCheckResolverThreshold(dispatchThreshold),
-		)
-		t.Cleanup(s.Close)
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
 
-		require.False(t, s.checkQueryCacheEnabled)
-		require.Nil(t, s.cachedCheckResolver)
+		type user
 
-		require.True(t, s.checkDispatchThrottlingEnabled)
-		require.EqualValues(t, dispatchThreshold, s.checkDispatchThrottlingDefaultThreshold)
-		require.EqualValues(t, 0, s.checkDispatchThrottlingMaxThreshold)
-		require.NotNil(t, s.dispatchThrottlingCheckResolver)
-		require.NotNil(t, s.checkResolver)
-		cycleDetectionCheckResolver, ok := s.checkResolver.(*graph.CycleDetectionCheckResolver)
-		require.True(t, ok)
+		type document
+			relations
+				define viewer: [user]`)
 
-		dispatchThrottlingResolver, ok := cycleDetectionCheckResolver.GetDelegate().(*graph.DispatchThrottlingCheckResolver)
-		require.True(t, ok)
+	writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:         storeID,
+		SchemaVersion:   model.GetSchemaVersion(),
+		TypeDefinitions: model.GetTypeDefinitions(),
+	})
+	require.NoError(t, err)
+	modelID := writeModelResp.GetAuthorizationModelId()
+
+	_, err = s.Write(ctx, &openfgav1.WriteRequest{
+		StoreId: storeID,
+		Writes: &openfgav1.WriteRequestWrites{
+			TupleKeys: []*openfgav1.TupleKey{tuple.NewTupleKey("document:1", "viewer", "user:jon")},
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = s.Write(ctx, &openfgav1.WriteRequest{
+		StoreId: storeID,
+		Deletes: &openfgav1.WriteRequestDeletes{
+			TupleKeys: []*openfgav1.TupleKeyWithoutCondition{tuple.TupleKeyToTupleKeyWithoutCondition(tuple.NewTupleKey("document:2", "viewer", "user:jon"))},
+		},
+	})
+	require.Error(t, err)
+
+	checkResp, err := s.Check(ctx, &openfgav1.CheckRequest{
+		StoreId:  storeID,
+		TupleKey: tuple.NewCheckRequestTupleKey("document:1", "viewer", "user:jon"),
+	})
+	require.NoError(t, err)
+	require.True(t, checkResp.GetAllowed())
+
+	_, err = s.DeleteStore(ctx, &openfgav1.DeleteStoreRequest{StoreId: storeID})
+	require.NoError(t, err)
+
+	// the events are flushed to the sink once the logger is closed
+	s.Close()
+	auditLogger.Close()
+
+	require.Len(t, sink.events, 6)
+	for _, event := range sink.events {
+		require.Equal(t, "client-a", event.Principal)
+		require.Equal(t, storeID, event.StoreID)
+	}
+
+	require.Equal(t, audit.EventCreateStore, sink.events[0].Type)
+
+	require.Equal(t, audit.EventWriteAuthorizationModel, sink.events[1].Type)
+	require.Equal(t, modelID, sink.events[1].AuthorizationModelID)
 
-		localChecker, ok := dispatchThrottlingResolver.GetDelegate().(*graph.LocalChecker)
-		require.True(t, ok)
+	require.Equal(t, audit.EventWrite, sink.events[2].Type)
+	require.Equal(t, modelID, sink.events[2].AuthorizationModelID)
+	require.Equal(t, []string{"document:1#viewer@user:jon"}, sink.events[2].Writes)
+	require.Empty(t, sink.events[2].Error)
 
-		_, ok = localChecker.GetDelegate().(*graph.CycleDetectionCheckResolver)
-		require.True(t, ok)
-	})
+	require.Equal(t, audit.EventWrite, sink.events[3].Type)
+	require.Equal(t, []string{"document:2#viewer@user:jon"}, sink.events[3].Deletes)
+	require.NotEmpty(t, sink.events[3].Error)
 
-	t.Run("dispatch_throttling_check_resolver_enabled_zero_max_threshold", func(t *testing.T) {
-		ds := memory.New()
-		t.Cleanup(ds.Close)
-		const dispatchThreshold = 50
-		s := MustNewServerWithOpts(
-			WithDatastore(ds),
-			WithDispatchThrottlingCheckResolverEnabled(true),
-			WithDispatchThrottlingCheckResolverThreshold(dispatchThreshold),
-			WithDispatchThrottlingCheckResolverMaxThreshold(0),
-		)
-		t.Cleanup(s.Close)
+	require.Equal(t, audit.EventCheck, sink.events[4].Type)
+	require.Equal(t, "document:1#viewer@user:jon", sink.events[4].TupleKey)
+	require.True(t, sink.events[4].Allowed)
 
-		require.False(t, s.checkQueryCacheEnabled)
-		require.Nil(t, s.cachedCheckResolver)
+	require.Equal(t, audit.EventDeleteStore, sink.events[5].Type)
+}
 
-		require.True(t, s.checkDispatchThrottlingEnabled)
-		require.EqualValues(t, dispatchThreshold, s.checkDispatchThrottlingDefaultThreshold)
-		require.EqualValues(t, 0, s.checkDispatchThrottlingMaxThreshold)
-		require.NotNil(t, s.dispatchThrottlingCheckResolver)
-		require.NotNil(t, s.checkResolver)
-		cycleDetectionCheckResolver, ok := s.checkResolver.(*graph.CycleDetectionCheckResolver)
-		require.True(t, ok)
+func TestStoreDefaultModel(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
 
-		dispatchThrottlingResolver, ok := cycleDetectionCheckResolver.GetDelegate().(*graph.DispatchThrottlingCheckResolver)
-		require.True(t, ok)
+	ctx := context.Background()
 
-		localChecker, ok := dispatchThrottlingResolver.GetDelegate().(*graph.LocalChecker)
-		require.True(t, ok)
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
 
-		_, ok = localChecker.GetDelegate().(*graph.CycleDetectionCheckResolver)
-		require.True(t, ok)
+	s := MustNewServerWithOpts(WithDatastore(ds))
+	t.Cleanup(s.Close)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+		Name: "openfga-test",
 	})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
 
-	t.Run("dispatch_throttling_check_resolver_enabled_non_zero_max_threshold", func(t *testing.T) {
-		ds := memory.New()
-		t.Cleanup(ds.Close)
-		const dispatchThreshold = 50
-		const maxDispatchThreshold = 60
+	writeModel := func(dsl string) string {
+		model := testutils.MustTransformDSLToProtoWithID(dsl)
+		resp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+			StoreId:         storeID,
+			SchemaVersion:   model.GetSchemaVersion(),
+			TypeDefinitions: model.GetTypeDefinitions(),
+		})
+		require.NoError(t, err)
+		return resp.GetAuthorizationModelId()
+	}
 
-		s := MustNewServerWithOpts(
-			WithDatastore(ds),
-			WithDispatchThrottlingCheckResolverEnabled(true),
-			WithDispatchThrottlingCheckResolverThreshold(dispatchThreshold),
-			WithDispatchThrottlingCheckResolverMaxThreshold(maxDispatchThreshold),
-		)
-		t.Cleanup(s.Close)
+	pinnedModelID := writeModel(`
+		model
+			schema 1.1
 
-		require.False(t, s.checkQueryCacheEnabled)
-		require.Nil(t, s.cachedCheckResolver)
+		type user
 
-		require.True(t, s.checkDispatchThrottlingEnabled)
-		require.EqualValues(t, dispatchThreshold, s.checkDispatchThrottlingDefaultThreshold)
-		require.EqualValues(t, maxDispatchThreshold, s.checkDispatchThrottlingMaxThreshold)
-		require.NotNil(t, s.dispatchThrottlingCheckResolver)
-		require.NotNil(t, s.checkResolver)
-		cycleDetectionCheckResolver, ok := s.checkResolver.(*graph.CycleDetectionCheckResolver)
-		require.True(t, ok)
+		type document
+			relations
+				define viewer: [user]`)
 
-		dispatchThrottlingResolver, ok := cycleDetectionCheckResolver.GetDelegate().(*graph.DispatchThrottlingCheckResolver)
-		require.True(t, ok)
+	latestModelID := writeModel(`
+		model
+			schema 1.1
 
-		localChecker, ok := dispatchThrottlingResolver.GetDelegate().(*graph.LocalChecker)
-		require.True(t, ok)
+		type user
 
-		_, ok = localChecker.GetDelegate().(*graph.CycleDetectionCheckResolver)
-		require.True(t, ok)
-	})
+		type document
+			relations
+				define viewer: [user]
+				define editor: [user]`)
 
-	t.Run("cache_check_resolver_enabled", func(t *testing.T) {
-		ds := memory.New()
-		t.Cleanup(ds.Close)
-		s := MustNewServerWithOpts(
-			WithDatastore(ds),
-			WithCheckQueryCacheEnabled(true),
-		)
-		t.Cleanup(s.Close)
+	resolvedModelID := func(t *testing.T) string {
+		typesys, err := s.resolveTypesystem(ctx, storeID, "")
+		require.NoError(t, err)
+		return typesys.GetAuthorizationModelID()
+	}
 
-		require.False(t, s.checkDispatchThrottlingEnabled)
-		require.Nil(t, s.dispatchThrottlingCheckResolver)
+	modelID, err := s.GetStoreDefaultModel(ctx, storeID)
+	require.NoError(t, err)
+	require.Empty(t, modelID)
+	require.Equal(t, latestModelID, resolvedModelID(t))
 
-		require.True(t, s.checkQueryCacheEnabled)
-		require.NotNil(t, s.cachedCheckResolver)
-		require.NotNil(t, s.checkResolver)
-		cycleDetectionCheckResolver, ok := s.checkResolver.(*graph.CycleDetectionCheckResolver)
-		require.True(t, ok)
+	require.NoError(t, s.SetStoreDefaultModel(ctx, storeID, pinnedModelID))
 
-		cachedCheckResolver, ok := cycleDetectionCheckResolver.GetDelegate().(*graph.CachedCheckResolver)
-		require.True(t, ok)
+	modelID, err = s.GetStoreDefaultModel(ctx, storeID)
+	require.NoError(t, err)
+	require.Equal(t, pinnedModelID, modelID)
+	require.Equal(t, pinnedModelID, resolvedModelID(t))
 
-		localChecker, ok := cachedCheckResolver.GetDelegate().(*graph.LocalChecker)
-		require.True(t, ok)
+	// the requests that omit the model ID use the pinned one
+	_, err = s.Check(ctx, &openfgav1.CheckRequest{
+		StoreId:  storeID,
+		TupleKey: tuple.NewCheckRequestTupleKey("document:1", "editor", "user:jon"),
+	})
+	require.Error(t, err)
 
-		_, ok = localChecker.GetDelegate().(*graph.CycleDetectionCheckResolver)
-		require.True(t, ok)
+	_, err = s.Check(ctx, &openfgav1.CheckRequest{
+		StoreId:              storeID,
+		AuthorizationModelId: latestModelID,
+		TupleKey:             tuple.NewCheckRequestTupleKey("document:1", "editor", "user:jon"),
 	})
+	require.NoError(t, err)
 
-	t.Run("both_dispatch_throttling_and_cache_check_resolver_enabled", func(t *testing.T) {
-		ds := memory.New()
-		t.Cleanup(ds.Close)
-		s := MustNewServerWithOpts(
-			WithDatastore(ds),
-			WithCheckQueryCacheEnabled(true),
-			WithDispatchThrottlingCheckResolverEnabled(true),
-			WithDispatchThrottlingCheckResolverThreshold(50),
-			WithDispatchThrottlingCheckResolverMaxThreshold(100),
-		)
-		t.Cleanup(s.Close)
+	t.Run("unknown_model", func(t *testing.T) {
+		err := s.SetStoreDefaultModel(ctx, storeID, ulid.Make().String())
+		require.ErrorContains(t, err, "not found")
 
-		require.True(t, s.checkDispatchThrottlingEnabled)
-		require.EqualValues(t, 50, s.checkDispatchThrottlingDefaultThreshold)
-		require.EqualValues(t, 100, s.checkDispatchThrottlingMaxThreshold)
-		require.NotNil(t, s.dispatchThrottlingCheckResolver)
-		require.NotNil(t, s.checkResolver)
-		cycleDetectionCheckResolver, ok := s.checkResolver.(*graph.CycleDetectionCheckResolver)
-		require.True(t, ok)
+		err = s.SetStoreDefaultModel(ctx, storeID, "invalid")
+		require.ErrorContains(t, err, "not found")
 
-		cachedCheckResolver, ok := cycleDetectionCheckResolver.GetDelegate().(*graph.CachedCheckResolver)
-		require.True(t, ok)
+		require.Equal(t, pinnedModelID, resolvedModelID(t))
+	})
 
-		require.True(t, s.checkQueryCacheEnabled)
-		require.NotNil(t, s.cachedCheckResolver)
+	t.Run("unpin", func(t *testing.T) {
+		require.NoError(t, s.SetStoreDefaultModel(ctx, storeID, ""))
+		require.Equal(t, latestModelID, resolvedModelID(t))
+	})
 
-		dispatchThrottlingResolver, ok := cachedCheckResolver.GetDelegate().(*graph.DispatchThrottlingCheckResolver)
-		require.True(t, ok)
+	t.Run("datastore_without_default_model_support", func(t *testing.T) {
+		s := MustNewServerWithOpts(
+			WithDatastore(&delayedTupleReaderDatastore{OpenFGADatastore: ds}),
+		)
+		t.Cleanup(s.Close)
 
-		localChecker, ok := dispatchThrottlingResolver.GetDelegate().(*graph.LocalChecker)
-		require.True(t, ok)
+		err := s.SetStoreDefaultModel(ctx, storeID, pinnedModelID)
+		require.ErrorIs(t, err, serverErrors.StoreDefaultModelUnsupported)
+		require.Equal(t, codes.Code(openfgav1.NotFoundErrorCode_unimplemented), status.Code(err))
 
-		_, ok = localChecker.GetDelegate().(*graph.CycleDetectionCheckResolver)
-		require.True(t, ok)
+		_, err = s.GetStoreDefaultModel(ctx, storeID)
+		require.ErrorIs(t, err, serverErrors.StoreDefaultModelUnsupported)
 	})
 }
 
-func TestWriteAuthorizationModelWithSchema12(t *testing.T) {
+func TestRunAssertions(t *testing.T) {
 	t.Cleanup(func() {
 		goleak.VerifyNone(t)
 	})
+
 	ctx := context.Background()
-	storeID := ulid.Make().String()
 
-	mockController := gomock.NewController(t)
-	defer mockController.Finish()
+	_, ds, _ := util.MustBootstrapDatastore(t, "memory")
 
-	mockDatastore := mockstorage.NewMockOpenFGADatastore(mockController)
+	s := MustNewServerWithOpts(WithDatastore(ds))
+	t.Cleanup(s.Close)
 
-	t.Run("accepts_request_with_schema_version_1.2", func(t *testing.T) {
-		s := MustNewServerWithOpts(
-			WithDatastore(mockDatastore),
-		)
-		t.Cleanup(func() {
-			mockDatastore.EXPECT().Close().Times(1)
-			s.Close()
-		})
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{
+		Name: "openfga-test",
+	})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
 
-		mockDatastore.EXPECT().MaxTypesPerAuthorizationModel().Return(100)
-		mockDatastore.EXPECT().WriteAuthorizationModel(gomock.Any(), storeID, gomock.Any()).Return(nil)
+	model := testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
 
-		_, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
-			StoreId:       storeID,
-			SchemaVersion: typesystem.SchemaVersion1_2,
-			TypeDefinitions: []*openfgav1.TypeDefinition{
-				{
-					Type: "user",
-					Metadata: &openfgav1.Metadata{
-						Relations:  nil,
-						Module:     "usermanagement",
-						SourceInfo: nil,
-					},
+		type user
+
+		type group
+			relations
+				define member: [user]
+
+		type document
+			relations
+				define owner: [user with in_office]
+				define viewer: [user, group#member] or owner
+
+		condition in_office(office_hours: bool) {
+			office_hours
+		}`)
+	writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:         storeID,
+		SchemaVersion:   model.GetSchemaVersion(),
+		TypeDefinitions: model.GetTypeDefinitions(),
+		Conditions:      model.GetConditions(),
+	})
+	require.NoError(t, err)
+	modelID := writeModelResp.GetAuthorizationModelId()
+
+	_, err = s.Write(ctx, &openfgav1.WriteRequest{
+		StoreId: storeID,
+		Writes: &openfgav1.WriteRequestWrites{
+			TupleKeys: []*openfgav1.TupleKey{
+				tuple.NewTupleKey("group:eng", "member", "user:anne"),
+				tuple.NewTupleKey("document:1", "viewer", "group:eng#member"),
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	t.Run("passing", func(t *testing.T) {
+		require.NoError(t, s.WriteContextualAssertions(ctx, storeID, modelID, []*storage.Assertion{
+			{TupleKey: tuple.NewAssertionTupleKey("document:1", "viewer", "user:anne"), Expectation: true},
+			{TupleKey: tuple.NewAssertionTupleKey("document:1", "viewer", "user:bob"), Expectation: false},
+			{
+				TupleKey:    tuple.NewAssertionTupleKey("document:2", "viewer", "user:bob"),
+				Expectation: true,
+				ContextualTuples: []*openfgav1.TupleKey{
+					tuple.NewTupleKeyWithCondition("document:2", "owner", "user:bob", "in_office", nil),
 				},
+				Context: testutils.MustNewStruct(t, map[string]interface{}{"office_hours": true}),
 			},
-		})
+		}))
 
+		// an empty model ID runs the assertions of the latest model
+		res, err := s.RunAssertions(ctx, storeID, "")
 		require.NoError(t, err)
+		require.True(t, res.Passed)
+		require.Equal(t, modelID, res.AuthorizationModelID)
+		require.Len(t, res.Results, 3)
+		for _, result := range res.Results {
+			require.True(t, result.Passed)
+			require.NoError(t, result.Err)
+			require.Nil(t, result.Trace)
+		}
 	})
-}
 
-func TestIsExperimentallyEnabled(t *testing.T) {
-	ds := memory.New() // Datastore required for server instantiation
-	someExperimentalFlag := ExperimentalFeatureFlag("some-experimental-feature-to-enable")
+	t.Run("failing", func(t *testing.T) {
+		require.NoError(t, s.WriteContextualAssertions(ctx, storeID, modelID, []*storage.Assertion{
+			{TupleKey: tuple.NewAssertionTupleKey("document:1", "viewer", "user:anne"), Expectation: false},
+			{
+				TupleKey:    tuple.NewAssertionTupleKey("document:2", "viewer", "user:bob"),
+				Expectation: true,
+				ContextualTuples: []*openfgav1.TupleKey{
+					tuple.NewTupleKeyWithCondition("document:2", "owner", "user:bob", "in_office", nil),
+				},
+				Context: testutils.MustNewStruct(t, map[string]interface{}{"office_hours": false}),
+			},
+			{
+				// the condition context is missing
+				TupleKey:    tuple.NewAssertionTupleKey("document:3", "owner", "user:bob"),
+				Expectation: true,
+				ContextualTuples: []*openfgav1.TupleKey{
+					tuple.NewTupleKeyWithCondition("document:3", "owner", "user:bob", "in_office", nil),
+				},
+			},
+		}))
 
-	t.Run("returns_false_if_experimentals_is_empty", func(t *testing.T) {
-		s := MustNewServerWithOpts(WithDatastore(ds))
-		require.False(t, s.IsExperimentallyEnabled(someExperimentalFlag))
+		res, err := s.RunAssertions(ctx, storeID, modelID)
+		require.NoError(t, err)
+		require.False(t, res.Passed)
+		require.Len(t, res.Results, 3)
+
+		// the negative assertion fails along with the path that allowed the user
+		require.False(t, res.Results[0].Passed)
+		require.True(t, res.Results[0].Allowed)
+		require.NotNil(t, res.Results[0].Trace)
+		require.Equal(t, []string{"document:1#viewer", "group:eng#member"}, res.Results[0].Trace.Path)
+
+		require.False(t, res.Results[1].Passed)
+		require.False(t, res.Results[1].Allowed)
+		require.NoError(t, res.Results[1].Err)
+		require.NotNil(t, res.Results[1].Trace)
+
+		require.False(t, res.Results[2].Passed)
+		require.Error(t, res.Results[2].Err)
+		require.Nil(t, res.Results[2].Trace)
 	})
 
-	t.Run("returns_true_if_experimentals_has_matching_element", func(t *testing.T) {
-		s := MustNewServerWithOpts(
-			WithDatastore(ds),
-			WithExperimentals(someExperimentalFlag),
-		)
-		require.True(t, s.IsExperimentallyEnabled(someExperimentalFlag))
+	t.Run("assertions_without_context", func(t *testing.T) {
+		_, err := s.WriteAssertions(ctx, &openfgav1.WriteAssertionsRequest{
+			StoreId:              storeID,
+			AuthorizationModelId: modelID,
+			Assertions: []*openfgav1.Assertion{
+				{TupleKey: tuple.NewAssertionTupleKey("document:1", "viewer", "user:anne"), Expectation: true},
+			},
+		})
+		require.NoError(t, err)
+
+		assertions, err := s.ReadContextualAssertions(ctx, storeID, modelID)
+		require.NoError(t, err)
+		require.Len(t, assertions, 1)
+		require.Empty(t, assertions[0].ContextualTuples)
+
+		res, err := s.RunAssertions(ctx, storeID, modelID)
+		require.NoError(t, err)
+		require.True(t, res.Passed)
 	})
 
-	t.Run("returns_true_if_experimentals_has_matching_element_and_other_matching_element", func(t *testing.T) {
-		s := MustNewServerWithOpts(
-			WithDatastore(ds),
-			WithExperimentals(someExperimentalFlag, ExperimentalFeatureFlag("some-other-feature")),
-		)
-		require.True(t, s.IsExperimentallyEnabled(someExperimentalFlag))
+	t.Run("invalid_assertion", func(t *testing.T) {
+		err := s.WriteContextualAssertions(ctx, storeID, modelID, []*storage.Assertion{
+			{
+				TupleKey:    tuple.NewAssertionTupleKey("document:1", "viewer", "user:anne"),
+				Expectation: true,
+				ContextualTuples: []*openfgav1.TupleKey{
+					tuple.NewTupleKey("document:1", "undefined", "user:anne"),
+				},
+			},
+		})
+		require.Error(t, err)
 	})
 
-	t.Run("returns_false_if_experimentals_has_no_matching_element", func(t *testing.T) {
-		s := MustNewServerWithOpts(
-			WithDatastore(ds),
-			WithExperimentals(ExperimentalFeatureFlag("some-other-feature")),
-		)
-		require.False(t, s.IsExperimentallyEnabled(someExperimentalFlag))
+	t.Run("unknown_model", func(t *testing.T) {
+		_, err := s.RunAssertions(ctx, storeID, ulid.Make().String())
+		require.ErrorContains(t, err, "not found")
 	})
 }
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/oklog/ulid/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage"
+)
+
+// SetStoreDefaultModel pins the authorization model used by the requests of the store that do not specify an
+// authorization model ID, instead of the latest model of the store. The models written afterwards do not
+// replace it, so a new model can be written ahead of time, tested with requests specifying its ID, and made
+// the default in one step. An empty modelID unpins the model, so that the latest model is the default again.
+// Because the pinned model is cached by every server for the duration set with WithStoreFeatureFlagsCacheTTL,
+// the change may take up to that duration to take effect on the other servers.
+// It returns StoreDefaultModelUnsupported if the datastore cannot pin models, e.g. the SQL datastores, whose
+// stores always default to their latest model.
+func (s *Server) SetStoreDefaultModel(ctx context.Context, storeID, modelID string) error {
+	ctx, span := tracer.Start(ctx, "SetStoreDefaultModel", trace.WithAttributes(
+		attribute.String("store_id", storeID),
+		attribute.String(authorizationModelIDKey, modelID),
+	))
+	defer span.End()
+
+	if s.storeDefaultModelBackend == nil {
+		return serverErrors.StoreDefaultModelUnsupported
+	}
+
+	if modelID != "" {
+		if _, err := ulid.Parse(modelID); err != nil {
+			return serverErrors.AuthorizationModelNotFound(modelID)
+		}
+	}
+
+	if err := s.storeDefaultModelBackend.WriteStoreDefaultModel(ctx, storeID, modelID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return serverErrors.AuthorizationModelNotFound(modelID)
+		}
+
+		return serverErrors.HandleError("", err)
+	}
+
+	s.storeDefaultModelCache.Delete(storeID)
+
+	return nil
+}
+
+// GetStoreDefaultModel returns the ID of the model pinned as the default of the store with
+// SetStoreDefaultModel, or an empty string if none is pinned.
+// It returns StoreDefaultModelUnsupported if the datastore cannot pin models.
+func (s *Server) GetStoreDefaultModel(ctx context.Context, storeID string) (string, error) {
+	ctx, span := tracer.Start(ctx, "GetStoreDefaultModel", trace.WithAttributes(
+		attribute.String("store_id", storeID),
+	))
+	defer span.End()
+
+	if s.storeDefaultModelBackend == nil {
+		return "", serverErrors.StoreDefaultModelUnsupported
+	}
+
+	modelID, err := s.storeDefaultModelBackend.ReadStoreDefaultModel(ctx, storeID)
+	if err != nil {
+		return "", serverErrors.HandleError("", err)
+	}
+
+	return modelID, nil
+}
+
+// storeDefaultModel returns the ID of the model pinned as the default of the store, read from the datastore
+// at most once per WithStoreFeatureFlagsCacheTTL. It returns an empty string if none is pinned or if the
+// datastore cannot pin models, since none can have been pinned then.
+func (s *Server) storeDefaultModel(ctx context.Context, storeID string) (string, error) {
+	if s.storeDefaultModelBackend == nil {
+		return "", nil
+	}
+
+	if item := s.storeDefaultModelCache.Get(storeID); item != nil && !item.Expired() {
+		return item.Value(), nil
+	}
+
+	modelID, err := s.storeDefaultModelBackend.ReadStoreDefaultModel(ctx, storeID)
+	if err != nil {
+		s.logger.ErrorWithContext(ctx, "failed to read the store default model", zap.String("store_id", storeID), zap.Error(err))
+		return "", serverErrors.HandleError("", err)
+	}
+
+	s.storeDefaultModelCache.Set(storeID, modelID, s.storeFeatureFlagsCacheTTL)
+
+	return modelID, nil
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage/storagewrappers"
+)
+
+// StoreFeatureFlag is the name of a resolver feature that can be enabled for a single store.
+type StoreFeatureFlag string
+
+const (
+	// StoreFeaturePersistedTuplesWin makes the Check and ListUsers requests of the store read the persisted
+	// tuple when a contextual tuple has the same object, relation and user, regardless of the conflict policy
+	// set with WithContextualTuplesConflictPolicy.
+	StoreFeaturePersistedTuplesWin StoreFeatureFlag = "persisted-tuples-win"
+)
+
+// WriteStoreFeatureFlags persists the given feature flags of the store. Flags that are not in the
+// map keep their current value. Because the flags are cached by every server for the duration set
+// with WithStoreFeatureFlagsCacheTTL, the change may take up to that duration to take effect.
+// It returns StoreFeatureFlagsUnsupported if the datastore cannot persist feature flags.
+func (s *Server) WriteStoreFeatureFlags(ctx context.Context, storeID string, flags map[StoreFeatureFlag]bool) error {
+	ctx, span := tracer.Start(ctx, "WriteStoreFeatureFlags")
+	defer span.End()
+
+	if s.storeFeatureFlagsBackend == nil {
+		return serverErrors.StoreFeatureFlagsUnsupported
+	}
+
+	raw := make(map[string]bool, len(flags))
+	for flag, enabled := range flags {
+		raw[string(flag)] = enabled
+	}
+
+	if err := s.storeFeatureFlagsBackend.WriteStoreFeatureFlags(ctx, storeID, raw); err != nil {
+		return serverErrors.HandleError("", err)
+	}
+
+	return nil
+}
+
+// ReadStoreFeatureFlags returns the feature flags persisted for the store, read from the datastore rather
+// than from the cache of the requests of the store. Flags that were never written are not in the map.
+// It returns StoreFeatureFlagsUnsupported if the datastore cannot persist feature flags, e.g. the SQL
+// datastores, whose stores are then always resolved without any flag.
+func (s *Server) ReadStoreFeatureFlags(ctx context.Context, storeID string) (map[StoreFeatureFlag]bool, error) {
+	ctx, span := tracer.Start(ctx, "ReadStoreFeatureFlags")
+	defer span.End()
+
+	if s.storeFeatureFlagsBackend == nil {
+		return nil, serverErrors.StoreFeatureFlagsUnsupported
+	}
+
+	raw, err := s.storeFeatureFlagsBackend.ReadStoreFeatureFlags(ctx, storeID)
+	if err != nil {
+		return nil, serverErrors.HandleError("", err)
+	}
+
+	return toStoreFeatureFlags(raw), nil
+}
+
+// storeFeatureFlags returns the feature flags of the store, read from the datastore at most once
+// per WithStoreFeatureFlagsCacheTTL. It returns no flags if the datastore cannot persist them,
+// since none can have been written then.
+func (s *Server) storeFeatureFlags(ctx context.Context, storeID string) (map[StoreFeatureFlag]bool, error) {
+	if s.storeFeatureFlagsBackend == nil {
+		return nil, nil
+	}
+
+	if item := s.storeFeatureFlagsCache.Get(storeID); item != nil && !item.Expired() {
+		return item.Value(), nil
+	}
+
+	raw, err := s.storeFeatureFlagsBackend.ReadStoreFeatureFlags(ctx, storeID)
+	if err != nil {
+		s.logger.ErrorWithContext(ctx, "failed to read the store feature flags", zap.String("store_id", storeID), zap.Error(err))
+		return nil, serverErrors.HandleError("", err)
+	}
+
+	flags := toStoreFeatureFlags(raw)
+	s.storeFeatureFlagsCache.Set(storeID, flags, s.storeFeatureFlagsCacheTTL)
+
+	return flags, nil
+}
+
+// toStoreFeatureFlags keys the feature flags read from the datastore by StoreFeatureFlag.
+func toStoreFeatureFlags(raw map[string]bool) map[StoreFeatureFlag]bool {
+	flags := make(map[StoreFeatureFlag]bool, len(raw))
+	for flag, enabled := range raw {
+		flags[StoreFeatureFlag(flag)] = enabled
+	}
+
+	return flags
+}
+
+// contextualTuplesConflictPolicyFor returns the conflict policy between the contextual and the persisted
+// tuples of the requests of the store, taking StoreFeaturePersistedTuplesWin into account.
+func (s *Server) contextualTuplesConflictPolicyFor(ctx context.Context, storeID string) (storagewrappers.ConflictPolicy, error) {
+	storeFlags, err := s.storeFeatureFlags(ctx, storeID)
+	if err != nil {
+		return 0, err
+	}
+
+	if storeFlags[StoreFeaturePersistedTuplesWin] {
+		return storagewrappers.PersistedTuplesWin, nil
+	}
+
+	return s.contextualTuplesConflictPolicy, nil
+}
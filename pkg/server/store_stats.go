@@ -0,0 +1,33 @@
+package server
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage"
+)
+
+// StoreStats returns the number of tuples of the store by object type and relation, and the number of its
+// changes during the last hour and day, e.g. for capacity planning. The SQL datastores compute the statistics
+// in the background, so they may be as old as the refresh interval they are configured with.
+// It returns StoreStatsUnsupported if the datastore cannot compute the statistics of a store.
+func (s *Server) StoreStats(ctx context.Context, storeID string) (*storage.StoreStats, error) {
+	ctx, span := tracer.Start(ctx, "StoreStats", trace.WithAttributes(
+		attribute.String("store_id", storeID),
+	))
+	defer span.End()
+
+	if s.storeStatsReader == nil {
+		return nil, serverErrors.StoreStatsUnsupported
+	}
+
+	stats, err := s.storeStatsReader.ReadStoreStats(ctx, storeID)
+	if err != nil {
+		return nil, serverErrors.HandleError("", err)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage"
+)
+
+// StoreTimeRange returns the earliest and the latest tuple writes of the store, or nil if no tuple was ever
+// written to it.
+// It returns StoreTimeRangeUnsupported if the datastore cannot read the time range of a store.
+func (s *Server) StoreTimeRange(ctx context.Context, storeID string) (*storage.StoreTimeRange, error) {
+	ctx, span := tracer.Start(ctx, "StoreTimeRange", trace.WithAttributes(
+		attribute.String("store_id", storeID),
+	))
+	defer span.End()
+
+	if s.storeTimeRangeReader == nil {
+		return nil, serverErrors.StoreTimeRangeUnsupported
+	}
+
+	timeRange, err := s.storeTimeRangeReader.StoreTimeRange(ctx, storeID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, serverErrors.HandleError("", err)
+	}
+
+	return timeRange, nil
+}
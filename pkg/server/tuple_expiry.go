@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/openfga/openfga/internal/build"
+)
+
+var expiredTuplesDeletedCounter = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: build.ProjectName,
+	Name:      "expired_tuples_deleted_count",
+	Help:      "The total number of expired tuples deleted by the tuple expiry reaper.",
+})
+
+// WithTupleExpiryReaperInterval sets the interval the tuples that expired are deleted at, see WithWriteExpiry.
+// Since the expired tuples are not read anymore, the interval only bounds how long they are kept, and how late
+// their deletes are recorded in the changelog. The datastore must support expiring tuples (storage.TupleExpirer).
+// 0, the default, disables the reaper: the expired tuples are then only deleted by the next Write to their store.
+func WithTupleExpiryReaperInterval(interval time.Duration) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.tupleExpiryReaperInterval = interval
+	}
+}
+
+// reapExpiredTuples deletes the expired tuples every interval, until stopTupleExpiryReaper is closed.
+func (s *Server) reapExpiredTuples(interval time.Duration) {
+	defer close(s.tupleExpiryReaperDone)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopTupleExpiryReaper:
+			return
+		case <-ticker.C:
+			deleted, err := s.tupleExpirer.DeleteExpiredTuples(context.Background(), time.Now())
+			if err != nil {
+				s.logger.Error("failed to delete the expired tuples", zap.Error(err))
+			}
+			expiredTuplesDeletedCounter.Add(float64(deleted))
+		}
+	}
+}
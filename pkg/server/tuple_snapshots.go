@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/storagewrappers"
+)
+
+// WithCheckTupleSnapshots reads every tuple of a Check from one snapshot of its store, so that the decision is
+// consistent with a single state of the store, e.g. rather than with a large delete applied in part. The datastore
+// must be able to take snapshots of the tuples (storage.TupleSnapshotter) and to apply the bulk deletes at once,
+// e.g. the memory datastore with memory.WithAtomicBulkDeletes. It cannot be combined with WithCheckReadHedging,
+// whose replica does not read from the snapshots.
+func WithCheckTupleSnapshots(enabled bool) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.checkTupleSnapshotsEnabled = enabled
+	}
+}
+
+// snapshotTuples returns a reader of the tuples of the store taken by the tupleSnapshotter, traced like the reads
+// of the datastore. With WithCheckUsersetReadBatching, it also returns the snapshot as the reader of the batched
+// userset reads, if it can batch them.
+func (s *Server) snapshotTuples(ctx context.Context, storeID string) (storage.RelationshipTupleReader, storage.BatchUsersetTupleReader, error) {
+	snapshot, err := s.tupleSnapshotter.SnapshotTuples(ctx, storeID)
+	if err != nil {
+		return nil, nil, serverErrors.HandleError("", err)
+	}
+
+	var usersetBatchReader storage.BatchUsersetTupleReader
+	if s.usersetBatchReader != nil {
+		usersetBatchReader, _ = snapshot.(storage.BatchUsersetTupleReader)
+	}
+
+	return storagewrappers.NewTracingDatastore(storagewrappers.NewContextWrapper(&snapshotDatastore{
+		OpenFGADatastore: s.datastore,
+		snapshot:         snapshot,
+	})), usersetBatchReader, nil
+}
+
+// snapshotDatastore is a datastore whose tuple reads are served by a snapshot, so that the snapshot can be wrapped
+// like the datastore.
+type snapshotDatastore struct {
+	storage.OpenFGADatastore
+
+	snapshot storage.RelationshipTupleReader
+}
+
+// Read see [storage.RelationshipTupleReader].Read.
+func (d *snapshotDatastore) Read(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (storage.TupleIterator, error) {
+	return d.snapshot.Read(ctx, store, tupleKey)
+}
+
+// ReadPage see [storage.RelationshipTupleReader].ReadPage.
+func (d *snapshotDatastore) ReadPage(ctx context.Context, store string, tupleKey *openfgav1.TupleKey, opts storage.PaginationOptions) ([]*openfgav1.Tuple, []byte, error) {
+	return d.snapshot.ReadPage(ctx, store, tupleKey, opts)
+}
+
+// ReadUserTuple see [storage.RelationshipTupleReader].ReadUserTuple.
+func (d *snapshotDatastore) ReadUserTuple(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
+	return d.snapshot.ReadUserTuple(ctx, store, tupleKey)
+}
+
+// ReadUsersetTuples see [storage.RelationshipTupleReader].ReadUsersetTuples.
+func (d *snapshotDatastore) ReadUsersetTuples(ctx context.Context, store string, filter storage.ReadUsersetTuplesFilter) (storage.TupleIterator, error) {
+	return d.snapshot.ReadUsersetTuples(ctx, store, filter)
+}
+
+// ReadStartingWithUser see [storage.RelationshipTupleReader].ReadStartingWithUser.
+func (d *snapshotDatastore) ReadStartingWithUser(ctx context.Context, store string, filter storage.ReadStartingWithUserFilter) (storage.TupleIterator, error) {
+	return d.snapshot.ReadStartingWithUser(ctx, store, filter)
+}
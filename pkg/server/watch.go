@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/openfga/openfga/pkg/middleware/validator"
+	"github.com/openfga/openfga/pkg/server/commands"
+	"github.com/openfga/openfga/pkg/telemetry"
+)
+
+// Watch tails the changelog of the store, sending the changes as they are written. It starts from the
+// continuation token of the request, e.g. one returned by ReadChanges, so that a consumer can backfill from
+// ReadChanges and then switch to Watch, or resume a Watch that was interrupted. Without a token, it starts
+// from the first change of the store. The type and the page size of the request apply like they do for
+// ReadChanges.
+//
+// Each response holds one page of changes and the continuation token to resume after them, which is also
+// valid for ReadChanges. Once Watch has caught up with the changelog, it reads it again every poll interval
+// (see WithWatchPollInterval), and it only sends responses with changes. Changes are subject to the
+// changelog horizon offset, like for ReadChanges.
+//
+// Watch returns when ctx is done, with the error of ctx, or as soon as send or reading the changelog fails.
+func (s *Server) Watch(ctx context.Context, req *openfgav1.ReadChangesRequest, send func(*openfgav1.ReadChangesResponse) error) error {
+	ctx, span := tracer.Start(ctx, "Watch", trace.WithAttributes(
+		attribute.String("store_id", req.GetStoreId()),
+		attribute.String("type", req.GetType()),
+	))
+	defer span.End()
+
+	if !validator.RequestIsValidatedFromContext(ctx) {
+		if err := req.Validate(); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	ctx = telemetry.ContextWithRPCInfo(ctx, telemetry.RPCInfo{
+		Service: s.serviceName,
+		Method:  "Watch",
+	})
+
+	q := commands.NewReadChangesQuery(s.datastore,
+		commands.WithReadChangesQueryLogger(s.logger),
+		commands.WithReadChangesQueryEncoder(s.encoder),
+		commands.WithReadChangeQueryHorizonOffset(s.changelogHorizonOffset),
+	)
+
+	pageReq := &openfgav1.ReadChangesRequest{
+		StoreId:           req.GetStoreId(),
+		Type:              req.GetType(),
+		PageSize:          req.GetPageSize(),
+		ContinuationToken: req.GetContinuationToken(),
+	}
+
+	ticker := time.NewTicker(s.watchPollInterval)
+	defer ticker.Stop()
+
+	for {
+		resp, err := q.Execute(ctx, pageReq)
+		if err != nil {
+			return err
+		}
+
+		if len(resp.GetChanges()) > 0 {
+			if err := send(resp); err != nil {
+				return err
+			}
+
+			// there may be more changes to catch up with, so the next page is read right away
+			pageReq.ContinuationToken = resp.GetContinuationToken()
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/tuple"
+)
+
+// WriteOption sets an option of a single Write made with WriteWithOptions.
+type WriteOption func(*writeOptions)
+
+type writeOptions struct {
+	preconditions *storage.WritePreconditions
+	expiresAt     time.Time
+}
+
+// WithWritePreconditions applies the Write only if the tuples of mustExist exist in the store and the ones of
+// mustNotExist do not, e.g. to revoke a grant only while the user still owns the object. The preconditions are
+// checked in the same transaction as the writes and deletes, and the Write fails with a FailedPrecondition error,
+// without changing the store, if one of them does not hold. The Write fails with an Unimplemented error if the
+// datastore does not support conditional writes.
+func WithWritePreconditions(mustExist, mustNotExist []*openfgav1.TupleKeyWithoutCondition) WriteOption {
+	return func(o *writeOptions) {
+		o.preconditions = &storage.WritePreconditions{
+			MustExist:    mustExist,
+			MustNotExist: mustNotExist,
+		}
+	}
+}
+
+// WithWriteExpiry makes the tuples written by the Write expire at expiresAt, e.g. for temporary access grants.
+// From then on they are not read anymore, and they are deleted, with their deletes recorded in the changelog, by
+// the reaper, see WithTupleExpiryReaperInterval, or by the next Write to the store. The cached results of the
+// Checks and ListObjects may still grant them for up to the TTL of their cache. The Write fails with an
+// Unimplemented error if the datastore does not support expiring tuples.
+func WithWriteExpiry(expiresAt time.Time) WriteOption {
+	return func(o *writeOptions) {
+		o.expiresAt = expiresAt
+	}
+}
+
+// WriteWithOptions is like Write, with the options applying to this request only.
+func (s *Server) WriteWithOptions(ctx context.Context, req *openfgav1.WriteRequest, opts ...WriteOption) (*openfgav1.WriteResponse, error) {
+	var o writeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return s.write(ctx, req, o)
+}
+
+// validateWritePreconditions ensures the precondition tuples are well formed. They are only looked up, so unlike
+// the tuples to write they are not validated against the model.
+func validateWritePreconditions(preconditions *storage.WritePreconditions) error {
+	for _, tks := range [][]*openfgav1.TupleKeyWithoutCondition{preconditions.MustExist, preconditions.MustNotExist} {
+		for _, tk := range tks {
+			var cause error
+			switch {
+			case !tuple.IsValidObject(tk.GetObject()):
+				cause = fmt.Errorf("the 'object' field is malformed")
+			case !tuple.IsValidRelation(tk.GetRelation()):
+				cause = fmt.Errorf("the 'relation' field is malformed")
+			case !tuple.IsValidUser(tk.GetUser()):
+				cause = fmt.Errorf("the 'user' field is malformed")
+			default:
+				continue
+			}
+
+			return serverErrors.ValidationError(&tuple.InvalidTupleError{Cause: cause, TupleKey: tk})
+		}
+	}
+
+	return nil
+}
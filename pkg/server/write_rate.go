@@ -0,0 +1,38 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+)
+
+// WriteRate returns the number of tuples written or deleted in the store during the last window, as
+// recorded in its changelog. It can be used to flag stores receiving an unusual amount of writes.
+// It returns ChangeCountUnsupported if the datastore cannot count the changes of a store.
+func (s *Server) WriteRate(ctx context.Context, storeID string, window time.Duration) (int, error) {
+	ctx, span := tracer.Start(ctx, "WriteRate", trace.WithAttributes(
+		attribute.String("store_id", storeID),
+		attribute.String("window", window.String()),
+	))
+	defer span.End()
+
+	if s.changeCounter == nil {
+		return 0, serverErrors.ChangeCountUnsupported
+	}
+
+	if window <= 0 {
+		return 0, serverErrors.ValidationError(errors.New("the window must be positive"))
+	}
+
+	count, err := s.changeCounter.CountChanges(ctx, storeID, time.Now().Add(-window))
+	if err != nil {
+		return 0, serverErrors.HandleError("", err)
+	}
+
+	return count, nil
+}
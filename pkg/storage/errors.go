@@ -38,8 +38,35 @@ var (
 
 	// ErrNotFound is returned when the object does not exist.
 	ErrNotFound = errors.New("not found")
+
+	// ErrStorageTimeout is returned when a single datastore query takes longer than the per-query timeout.
+	ErrStorageTimeout = errors.New("storage query timed out")
+
+	// ErrWritePreconditionFailed is returned when a precondition of a conditional write does not hold.
+	ErrWritePreconditionFailed = errors.New("write precondition failed")
+
+	// ErrReadBudgetExceeded is returned when a request makes more datastore reads than its read budget allows.
+	ErrReadBudgetExceeded = errors.New("datastore read budget exceeded")
 )
 
+// ReadBudgetExceededError is returned by the datastore reads a request attempts after it consumed
+// its read budget. It wraps ErrReadBudgetExceeded.
+type ReadBudgetExceededError struct {
+	// Budget is the maximum number of datastore reads of the request.
+	Budget uint32
+	// Reads is the number of datastore reads the request attempted, including the rejected ones.
+	Reads uint32
+}
+
+func (e *ReadBudgetExceededError) Error() string {
+	return fmt.Sprintf("%s: %d reads attempted, budget of %d", ErrReadBudgetExceeded, e.Reads, e.Budget)
+}
+
+// Unwrap returns ErrReadBudgetExceeded.
+func (e *ReadBudgetExceededError) Unwrap() error {
+	return ErrReadBudgetExceeded
+}
+
 // ExceededMaxTypeDefinitionsLimitError constructs an error indicating that
 // the maximum allowed limit for type definitions has been exceeded.
 func ExceededMaxTypeDefinitionsLimitError(limit int) error {
@@ -73,3 +100,25 @@ func InvalidWriteInputError(tk tuple.TupleWithoutCondition, operation openfgav1.
 		return nil
 	}
 }
+
+// WritePreconditionFailedError generates an error for a conditional write whose precondition on the tuple
+// does not hold, i.e. the tuple must exist but does not, or must not exist but does.
+func WritePreconditionFailedError(tk tuple.TupleWithoutCondition, mustExist bool) error {
+	if mustExist {
+		return fmt.Errorf(
+			"tuple which must exist does not exist: user: '%s', relation: '%s', object: '%s': %w",
+			tk.GetUser(),
+			tk.GetRelation(),
+			tk.GetObject(),
+			ErrWritePreconditionFailed,
+		)
+	}
+
+	return fmt.Errorf(
+		"tuple which must not exist exists: user: '%s', relation: '%s', object: '%s': %w",
+		tk.GetUser(),
+		tk.GetRelation(),
+		tk.GetObject(),
+		ErrWritePreconditionFailed,
+	)
+}
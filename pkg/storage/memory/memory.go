@@ -12,9 +12,12 @@ import (
 	"github.com/oklog/ulid/v2"
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+	"golang.org/x/exp/maps"
 	"google.golang.org/protobuf/types/known/structpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"github.com/openfga/openfga/pkg/logger"
 	"github.com/openfga/openfga/pkg/storage"
 	"github.com/openfga/openfga/pkg/telemetry"
 	tupleUtils "github.com/openfga/openfga/pkg/tuple"
@@ -103,38 +106,145 @@ const (
 type MemoryBackend struct {
 	maxTuplesPerWrite             int
 	maxTypesPerAuthorizationModel int
+	atomicBulkDeletes             bool
+
+	// readLatency and readErrorInjector simulate slow and failing tuple reads, see WithArtificialReadLatency
+	// and WithReadErrorInjection.
+	readLatency       time.Duration
+	readErrorInjector func(tupleKey *openfgav1.TupleKey) error
 
 	// TupleBackend
 	// map: store => set of tuples
 	tuples      map[string][]*storage.TupleRecord // GUARDED_BY(mutexTuples).
 	mutexTuples sync.RWMutex
 
+	// map: store => actor => tuples written by the actor
+	actorTuples map[string]map[string][]*storage.TupleRecord // GUARDED_BY(mutexTuples).
+
 	// ChangelogBackend
 	// map: store => set of changes
 	changes map[string][]*openfgav1.TupleChange // GUARDED_BY(mutexTuples).
 
+	// map: store => earliest and latest tuple writes
+	timeRanges map[string]*storage.StoreTimeRange // GUARDED_BY(mutexTuples).
+
 	// AuthorizationModelBackend
 	// map: store = > map: type definition id => type definition
 	authorizationModels map[string]map[string]*AuthorizationModelEntry // GUARDED_BY(mutexModels).
-	mutexModels         sync.RWMutex
+	// map: store => id of the model pinned as the default
+	defaultModels map[string]string // GUARDED_BY(mutexModels).
+	mutexModels   sync.RWMutex
 
 	// map: store id => store data
 	stores      map[string]*openfgav1.Store // GUARDED_BY(mutexStores).
 	mutexStores sync.RWMutex
 
 	// map: store id | authz model id => assertions
-	assertions      map[string][]*openfgav1.Assertion // GUARDED_BY(mutexAssertions).
+	assertions      map[string][]*storage.Assertion // GUARDED_BY(mutexAssertions).
 	mutexAssertions sync.RWMutex
+
+	// map: store id => feature flag => enabled
+	featureFlags      map[string]map[string]bool // GUARDED_BY(mutexFeatureFlags).
+	mutexFeatureFlags sync.RWMutex
+
+	// map: store id => watchers of the changes
+	changeWatchers      map[string]map[*changeWatcher]struct{} // GUARDED_BY(mutexChangeWatchers).
+	mutexChangeWatchers sync.Mutex
+
+	// snapshotPath is the file the data is persisted to, empty unless created with NewPersistent.
+	snapshotPath  string
+	lastSnapshot  []byte // GUARDED_BY(mutexSnapshot).
+	mutexSnapshot sync.Mutex
+	stopSnapshots chan struct{}
+	snapshotsDone chan struct{}
+	closeOnce     sync.Once
+	logger        logger.Logger
+}
+
+// changeWatcher is a subscription created with WatchChanges.
+type changeWatcher struct {
+	// notify receives a value when changes are appended to the store. It is buffered, so that
+	// writes never wait for a slow watcher.
+	notify chan struct{}
 }
 
 // Ensures that [MemoryBackend] implements the [storage.OpenFGADatastore] interface.
 var _ storage.OpenFGADatastore = (*MemoryBackend)(nil)
 
+// Ensures that [MemoryBackend] implements the [storage.FreshnessReporter] interface.
+var _ storage.FreshnessReporter = (*MemoryBackend)(nil)
+
+// Ensures that [MemoryBackend] implements the [storage.StoreFeatureFlagsBackend] interface.
+var _ storage.StoreFeatureFlagsBackend = (*MemoryBackend)(nil)
+
+// Ensures that [MemoryBackend] implements the [storage.ChangeWatcher] interface.
+var _ storage.ChangeWatcher = (*MemoryBackend)(nil)
+
+// Ensures that [MemoryBackend] implements the [storage.AuthorizationModelArchiveBackend] interface.
+var _ storage.AuthorizationModelArchiveBackend = (*MemoryBackend)(nil)
+
+// Ensures that [MemoryBackend] implements the [storage.AuthorizationModelMetadataBackend] interface.
+var _ storage.AuthorizationModelMetadataBackend = (*MemoryBackend)(nil)
+
+// Ensures that [MemoryBackend] implements the [storage.StoreDefaultModelBackend] interface.
+var _ storage.StoreDefaultModelBackend = (*MemoryBackend)(nil)
+
+// Ensures that [MemoryBackend] implements the [storage.BulkTupleDeleter] interface.
+var _ storage.BulkTupleDeleter = (*MemoryBackend)(nil)
+
+// Ensures that [MemoryBackend] implements the [storage.TupleSnapshotter] interface.
+var _ storage.TupleSnapshotter = (*MemoryBackend)(nil)
+
+// Ensures that [MemoryBackend] implements the [storage.ConditionalTupleWriter] interface.
+var _ storage.ConditionalTupleWriter = (*MemoryBackend)(nil)
+
+// Ensures that [MemoryBackend] implements the [storage.TupleCounter] interface.
+var _ storage.TupleCounter = (*MemoryBackend)(nil)
+
+// Ensures that [MemoryBackend] implements the [storage.ChangelogFilterReader] interface.
+var _ storage.ChangelogFilterReader = (*MemoryBackend)(nil)
+
+// Ensures that [MemoryBackend] implements the [storage.TupleExpirer] interface.
+var _ storage.TupleExpirer = (*MemoryBackend)(nil)
+
+// Ensures that [MemoryBackend] implements the [storage.BatchUsersetTupleReader] interface.
+var _ storage.BatchUsersetTupleReader = (*MemoryBackend)(nil)
+
+// Ensures that [MemoryBackend] implements the [storage.ContextualAssertionsBackend] interface.
+var _ storage.ContextualAssertionsBackend = (*MemoryBackend)(nil)
+
+// Ensures that [MemoryBackend] implements the [storage.StoreStatsReader] interface.
+var _ storage.StoreStatsReader = (*MemoryBackend)(nil)
+
+// Ensures that [MemoryBackend] implements the [storage.StoreTimeRangeReader] interface.
+var _ storage.StoreTimeRangeReader = (*MemoryBackend)(nil)
+
+func init() {
+	storage.Register("memory", func(_ string, cfg *storage.DatastoreConfig) (storage.OpenFGADatastore, error) {
+		opts := []StorageOption{
+			WithMaxTypesPerAuthorizationModel(cfg.MaxTypesPerModelField),
+			WithMaxTuplesPerWrite(cfg.MaxTuplesPerWriteField),
+		}
+
+		if cfg.MemorySnapshotFile != "" {
+			if cfg.Logger != nil {
+				opts = append(opts, WithLogger(cfg.Logger))
+			}
+			return NewPersistent(cfg.MemorySnapshotFile, cfg.MemorySnapshotInterval, opts...)
+		}
+
+		return New(opts...), nil
+	})
+}
+
 // AuthorizationModelEntry represents an entry in a storage system
 // that holds information about an authorization model.
 type AuthorizationModelEntry struct {
-	model  *openfgav1.AuthorizationModel
-	latest bool
+	model     *openfgav1.AuthorizationModel
+	latest    bool
+	archived  bool
+	createdAt time.Time
+	labels    map[string]string
 }
 
 // New creates a new [MemoryBackend] given the options.
@@ -143,10 +253,16 @@ func New(opts ...StorageOption) storage.OpenFGADatastore {
 		maxTuplesPerWrite:             defaultMaxTuplesPerWrite,
 		maxTypesPerAuthorizationModel: defaultMaxTypesPerAuthorizationModel,
 		tuples:                        make(map[string][]*storage.TupleRecord, 0),
+		actorTuples:                   make(map[string]map[string][]*storage.TupleRecord, 0),
 		changes:                       make(map[string][]*openfgav1.TupleChange, 0),
+		timeRanges:                    make(map[string]*storage.StoreTimeRange, 0),
 		authorizationModels:           make(map[string]map[string]*AuthorizationModelEntry),
+		defaultModels:                 make(map[string]string),
 		stores:                        make(map[string]*openfgav1.Store, 0),
-		assertions:                    make(map[string][]*openfgav1.Assertion, 0),
+		assertions:                    make(map[string][]*storage.Assertion, 0),
+		featureFlags:                  make(map[string]map[string]bool, 0),
+		changeWatchers:                make(map[string]map[*changeWatcher]struct{}, 0),
+		logger:                        logger.NewNoopLogger(),
 	}
 
 	for _, opt := range opts {
@@ -170,8 +286,62 @@ func WithMaxTypesPerAuthorizationModel(n int) StorageOption {
 	return func(ds *MemoryBackend) { ds.maxTypesPerAuthorizationModel = n }
 }
 
-// Close does not do anything for [MemoryBackend].
-func (s *MemoryBackend) Close() {}
+// WithAtomicBulkDeletes returns a [StorageOption] that makes DeleteTuples apply the whole delete at once, so that
+// concurrent reads observe the tuples either from before or from after the delete. Together with SnapshotTuples,
+// it ensures that a Check never resolves against a partially applied delete. By default, a large delete is
+// applied in batches of the maximum number of tuples per write, and reads may be served between the batches.
+func WithAtomicBulkDeletes(enabled bool) StorageOption {
+	return func(ds *MemoryBackend) { ds.atomicBulkDeletes = enabled }
+}
+
+// WithArtificialReadLatency returns a [StorageOption] that delays every tuple read by d, to test how slow reads are
+// handled. The delay is cut short, and the read fails with the error of the context, if the context is done first.
+func WithArtificialReadLatency(d time.Duration) StorageOption {
+	return func(ds *MemoryBackend) { ds.readLatency = d }
+}
+
+// WithReadErrorInjection returns a [StorageOption] that makes the tuple reads for which fn returns an error fail with
+// that error, to test how failing reads are handled. fn is given the filter of the read: the tuple key of Read,
+// ReadPage and ReadUserTuple, the object and relation of ReadUsersetTuples, and the object type (e.g. 'document:')
+// and relation of ReadStartingWithUser.
+func WithReadErrorInjection(fn func(tupleKey *openfgav1.TupleKey) error) StorageOption {
+	return func(ds *MemoryBackend) { ds.readErrorInjector = fn }
+}
+
+// simulateRead applies the options simulating slow and failing reads to a read with the given filter.
+func (s *MemoryBackend) simulateRead(ctx context.Context, filter *openfgav1.TupleKey) error {
+	if s.readLatency > 0 {
+		timer := time.NewTimer(s.readLatency)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if s.readErrorInjector != nil {
+		return s.readErrorInjector(filter)
+	}
+
+	return nil
+}
+
+// Close does not do anything for [MemoryBackend], unless it was created with NewPersistent, in which case it
+// stops the periodic snapshots and saves a last one.
+func (s *MemoryBackend) Close() {
+	s.closeOnce.Do(func() {
+		if s.stopSnapshots != nil {
+			close(s.stopSnapshots)
+			<-s.snapshotsDone
+		}
+
+		if err := s.SaveSnapshot(); err != nil {
+			s.logger.Error("failed to save the snapshot of the memory datastore", zap.Error(err))
+		}
+	})
+}
 
 // Read see [storage.RelationshipTupleReader].Read.
 func (s *MemoryBackend) Read(ctx context.Context, store string, key *openfgav1.TupleKey) (storage.TupleIterator, error) {
@@ -210,9 +380,34 @@ func (s *MemoryBackend) ReadChanges(
 	_, span := tracer.Start(ctx, "memory.ReadChanges")
 	defer span.End()
 
+	return s.readChanges(store, storage.ReadChangesFilter{ObjectType: objectType}, paginationOptions, horizonOffset)
+}
+
+// ReadFilteredChanges see [storage.ChangelogFilterReader].ReadFilteredChanges.
+func (s *MemoryBackend) ReadFilteredChanges(
+	ctx context.Context,
+	store string,
+	filter storage.ReadChangesFilter,
+	paginationOptions storage.PaginationOptions,
+	horizonOffset time.Duration,
+) ([]*openfgav1.TupleChange, []byte, error) {
+	_, span := tracer.Start(ctx, "memory.ReadFilteredChanges")
+	defer span.End()
+
+	return s.readChanges(store, filter, paginationOptions, horizonOffset)
+}
+
+func (s *MemoryBackend) readChanges(
+	store string,
+	filter storage.ReadChangesFilter,
+	paginationOptions storage.PaginationOptions,
+	horizonOffset time.Duration,
+) ([]*openfgav1.TupleChange, []byte, error) {
 	s.mutexTuples.RLock()
 	defer s.mutexTuples.RUnlock()
 
+	objectType := filter.ObjectType
+
 	var err error
 	var from int64
 	var typeInToken string
@@ -240,6 +435,9 @@ func (s *MemoryBackend) ReadChanges(
 			if change.GetTimestamp().AsTime().After(now.Add(-horizonOffset)) {
 				break
 			}
+			if !matchesChangesFilter(change, filter) {
+				continue
+			}
 			allChanges = append(allChanges, change)
 		}
 	}
@@ -269,24 +467,194 @@ func (s *MemoryBackend) ReadChanges(
 	return res, []byte(continuationToken), nil
 }
 
+// matchesChangesFilter reports whether the change matches the relation, user and time range of the filter.
+func matchesChangesFilter(change *openfgav1.TupleChange, filter storage.ReadChangesFilter) bool {
+	if filter.Relation != "" && change.GetTupleKey().GetRelation() != filter.Relation {
+		return false
+	}
+
+	if filter.User != "" && change.GetTupleKey().GetUser() != filter.User {
+		return false
+	}
+
+	timestamp := change.GetTimestamp().AsTime()
+	if !filter.StartTime.IsZero() && timestamp.Before(filter.StartTime) {
+		return false
+	}
+
+	return filter.EndTime.IsZero() || timestamp.Before(filter.EndTime)
+}
+
+// WatchChanges see [storage.ChangeWatcher].WatchChanges.
+func (s *MemoryBackend) WatchChanges(ctx context.Context, store, objectType string) (<-chan *openfgav1.TupleChange, error) {
+	_, span := tracer.Start(ctx, "memory.WatchChanges")
+	defer span.End()
+
+	w := &changeWatcher{notify: make(chan struct{}, 1)}
+
+	// register the watcher while holding the tuples lock, so that no write happens between
+	// reading the position of the next change and the registration
+	s.mutexTuples.RLock()
+	next := len(s.changes[store])
+	s.mutexChangeWatchers.Lock()
+	if s.changeWatchers[store] == nil {
+		s.changeWatchers[store] = map[*changeWatcher]struct{}{}
+	}
+	s.changeWatchers[store][w] = struct{}{}
+	s.mutexChangeWatchers.Unlock()
+	s.mutexTuples.RUnlock()
+
+	changes := make(chan *openfgav1.TupleChange)
+	go func() {
+		defer close(changes)
+		defer s.removeChangeWatcher(store, w)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-w.notify:
+			}
+
+			// the changelog is append-only, so the changes read here are never modified
+			s.mutexTuples.RLock()
+			pending := s.changes[store][next:]
+			next = len(s.changes[store])
+			s.mutexTuples.RUnlock()
+
+			for _, change := range pending {
+				if objectType != "" && tupleUtils.GetType(change.GetTupleKey().GetObject()) != objectType {
+					continue
+				}
+
+				select {
+				case changes <- change:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return changes, nil
+}
+
+// notifyChangeWatchers wakes up the watchers of the store after changes were appended to it.
+func (s *MemoryBackend) notifyChangeWatchers(store string) {
+	s.mutexChangeWatchers.Lock()
+	defer s.mutexChangeWatchers.Unlock()
+
+	for w := range s.changeWatchers[store] {
+		select {
+		case w.notify <- struct{}{}:
+		default: // the watcher has not read the previous changes yet, it will read these too
+		}
+	}
+}
+
+func (s *MemoryBackend) removeChangeWatcher(store string, w *changeWatcher) {
+	s.mutexChangeWatchers.Lock()
+	defer s.mutexChangeWatchers.Unlock()
+
+	delete(s.changeWatchers[store], w)
+	if len(s.changeWatchers[store]) == 0 {
+		delete(s.changeWatchers, store)
+	}
+}
+
+// CountChanges see [storage.ChangeCounter].CountChanges.
+func (s *MemoryBackend) CountChanges(ctx context.Context, store string, since time.Time) (int, error) {
+	_, span := tracer.Start(ctx, "memory.CountChanges")
+	defer span.End()
+
+	s.mutexTuples.RLock()
+	defer s.mutexTuples.RUnlock()
+
+	// the changelog is ordered by timestamp, so the changes in the window are at its end
+	changes := s.changes[store]
+	count := 0
+	for i := len(changes) - 1; i >= 0; i-- {
+		if changes[i].GetTimestamp().AsTime().Before(since) {
+			break
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// CountTuples see [storage.TupleCounter].CountTuples.
+func (s *MemoryBackend) CountTuples(ctx context.Context, store, objectType, relation string) (int, error) {
+	_, span := tracer.Start(ctx, "memory.CountTuples")
+	defer span.End()
+
+	s.mutexTuples.RLock()
+	defer s.mutexTuples.RUnlock()
+
+	now := time.Now()
+	count := 0
+	for _, t := range s.tuples[store] {
+		if t.ObjectType == objectType && t.Relation == relation && !t.IsExpired(now) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// ReadStoreStats see [storage.StoreStatsReader].ReadStoreStats. The statistics are computed on every call.
+func (s *MemoryBackend) ReadStoreStats(ctx context.Context, store string) (*storage.StoreStats, error) {
+	_, span := tracer.Start(ctx, "memory.ReadStoreStats")
+	defer span.End()
+
+	s.mutexTuples.RLock()
+	defer s.mutexTuples.RUnlock()
+
+	stats := &storage.StoreStats{ComputedAt: time.Now()}
+	for _, t := range s.tuples[store] {
+		if !t.IsExpired(stats.ComputedAt) {
+			stats.AddTuples(t.ObjectType, t.Relation, 1)
+		}
+	}
+
+	hourAgo := stats.ComputedAt.Add(-time.Hour)
+	dayAgo := stats.ComputedAt.Add(-24 * time.Hour)
+
+	// the changelog is ordered by timestamp, so the changes of the last day are at its end
+	changes := s.changes[store]
+	for i := len(changes) - 1; i >= 0; i-- {
+		timestamp := changes[i].GetTimestamp().AsTime()
+		if timestamp.Before(dayAgo) {
+			break
+		}
+		stats.DailyChangeCount++
+		if !timestamp.Before(hourAgo) {
+			stats.HourlyChangeCount++
+		}
+	}
+
+	return stats, nil
+}
+
 // read returns an iterator of a store's tuples with a given tuple as filter.
 // A nil paginationOptions input means the returned iterator will iterate through all values.
 func (s *MemoryBackend) read(ctx context.Context, store string, tk *openfgav1.TupleKey, paginationOptions *storage.PaginationOptions) (*staticIterator, error) {
 	_, span := tracer.Start(ctx, "memory.read")
 	defer span.End()
 
+	if err := s.simulateRead(ctx, tk); err != nil {
+		telemetry.TraceError(span, err)
+		return nil, err
+	}
+
 	s.mutexTuples.RLock()
 	defer s.mutexTuples.RUnlock()
 
+	now := time.Now()
 	var matches []*storage.TupleRecord
-	if tk.GetObject() == "" && tk.GetRelation() == "" && tk.GetUser() == "" {
-		matches = make([]*storage.TupleRecord, len(s.tuples[store]))
-		copy(matches, s.tuples[store])
-	} else {
-		for _, t := range s.tuples[store] {
-			if match(t, tk) {
-				matches = append(matches, t)
-			}
+	for _, t := range s.tuples[store] {
+		if !t.IsExpired(now) && match(t, tk) {
+			matches = append(matches, t)
 		}
 	}
 
@@ -315,6 +683,79 @@ func (s *MemoryBackend) read(ctx context.Context, store string, tk *openfgav1.Tu
 	return &staticIterator{records: matches}, nil
 }
 
+// ReadObjectRelations returns all the tuples of the given object (e.g. 'document:1') grouped by
+// relation, reading the store's tuples in a single pass.
+func (s *MemoryBackend) ReadObjectRelations(ctx context.Context, store string, object string) (map[string][]*openfgav1.Tuple, error) {
+	_, span := tracer.Start(ctx, "memory.ReadObjectRelations")
+	defer span.End()
+
+	objectType, objectID := tupleUtils.SplitObject(object)
+
+	s.mutexTuples.RLock()
+	defer s.mutexTuples.RUnlock()
+
+	now := time.Now()
+	res := make(map[string][]*openfgav1.Tuple)
+	for _, t := range s.tuples[store] {
+		if t.ObjectType == objectType && t.ObjectID == objectID && !t.IsExpired(now) {
+			res[t.Relation] = append(res[t.Relation], t.AsTuple())
+		}
+	}
+
+	return res, nil
+}
+
+// ListObjectsWithTuples returns the sorted distinct IDs of the objects of the given type that are the object of at
+// least one tuple of the store, regardless of the relation of those tuples. It does not evaluate any access.
+func (s *MemoryBackend) ListObjectsWithTuples(ctx context.Context, store string, objectType string) ([]string, error) {
+	_, span := tracer.Start(ctx, "memory.ListObjectsWithTuples")
+	defer span.End()
+
+	s.mutexTuples.RLock()
+	defer s.mutexTuples.RUnlock()
+
+	now := time.Now()
+	seen := make(map[string]struct{})
+	res := make([]string, 0)
+	for _, t := range s.tuples[store] {
+		if t.ObjectType != objectType || t.IsExpired(now) {
+			continue
+		}
+
+		if _, ok := seen[t.ObjectID]; !ok {
+			seen[t.ObjectID] = struct{}{}
+			res = append(res, t.ObjectID)
+		}
+	}
+
+	sort.Strings(res)
+
+	return res, nil
+}
+
+// Staleness see [storage.FreshnessReporter].Staleness. The reads of a [MemoryBackend] always observe
+// the latest writes, so it always returns 0.
+func (s *MemoryBackend) Staleness(context.Context, string) (time.Duration, error) {
+	return 0, nil
+}
+
+// StoreTimeRange see [storage.StoreTimeRangeReader].StoreTimeRange.
+func (s *MemoryBackend) StoreTimeRange(ctx context.Context, store string) (*storage.StoreTimeRange, error) {
+	_, span := tracer.Start(ctx, "memory.StoreTimeRange")
+	defer span.End()
+
+	s.mutexTuples.RLock()
+	defer s.mutexTuples.RUnlock()
+
+	timeRange, ok := s.timeRanges[store]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+
+	res := *timeRange
+	return &res, nil
+}
+
 // Write see [storage.RelationshipTupleWriter].Write.
 func (s *MemoryBackend) Write(ctx context.Context, store string, deletes storage.Deletes, writes storage.Writes) error {
 	_, span := tracer.Start(ctx, "memory.Write")
@@ -323,7 +764,47 @@ func (s *MemoryBackend) Write(ctx context.Context, store string, deletes storage
 	s.mutexTuples.Lock()
 	defer s.mutexTuples.Unlock()
 
+	s.deleteExpired(store, time.Now())
+
+	return s.write(ctx, store, deletes, writes)
+}
+
+// WriteWithPreconditions see [storage.ConditionalTupleWriter].WriteWithPreconditions.
+func (s *MemoryBackend) WriteWithPreconditions(
+	ctx context.Context,
+	store string,
+	preconditions storage.WritePreconditions,
+	deletes storage.Deletes,
+	writes storage.Writes,
+) error {
+	_, span := tracer.Start(ctx, "memory.WriteWithPreconditions")
+	defer span.End()
+
+	s.mutexTuples.Lock()
+	defer s.mutexTuples.Unlock()
+
+	s.deleteExpired(store, time.Now())
+
+	for _, tk := range preconditions.MustExist {
+		if !find(s.tuples[store], tupleUtils.TupleKeyWithoutConditionToTupleKey(tk)) {
+			return storage.WritePreconditionFailedError(tk, true)
+		}
+	}
+	for _, tk := range preconditions.MustNotExist {
+		if find(s.tuples[store], tupleUtils.TupleKeyWithoutConditionToTupleKey(tk)) {
+			return storage.WritePreconditionFailedError(tk, false)
+		}
+	}
+
+	return s.write(ctx, store, deletes, writes)
+}
+
+// write applies the deletes and the writes to the store. The caller must hold the write lock of mutexTuples, and
+// delete the expired tuples of the store first.
+func (s *MemoryBackend) write(ctx context.Context, store string, deletes storage.Deletes, writes storage.Writes) error {
 	now := timestamppb.Now()
+	actor, _ := storage.WriteActorFromContext(ctx)
+	expiresAt, _ := storage.TupleExpiryFromContext(ctx)
 
 	if err := validateTuples(s.tuples[store], deletes, writes); err != nil {
 		return err
@@ -367,7 +848,7 @@ Write:
 
 		objectType, objectID := tupleUtils.SplitObject(t.GetObject())
 
-		records = append(records, &storage.TupleRecord{
+		record := &storage.TupleRecord{
 			Store:            store,
 			ObjectType:       objectType,
 			ObjectID:         objectID,
@@ -377,7 +858,21 @@ Write:
 			ConditionContext: conditionContext,
 			Ulid:             ulid.MustNew(ulid.Timestamp(now.AsTime()), ulid.DefaultEntropy()).String(),
 			InsertedAt:       now.AsTime(),
-		})
+			WrittenBy:        actor,
+			ExpiresAt:        expiresAt,
+		}
+		records = append(records, record)
+
+		timeRange, ok := s.timeRanges[store]
+		if !ok {
+			timeRange = &storage.StoreTimeRange{
+				EarliestWriteUlid: record.Ulid,
+				EarliestWriteTime: record.InsertedAt,
+			}
+			s.timeRanges[store] = timeRange
+		}
+		timeRange.LatestWriteUlid = record.Ulid
+		timeRange.LatestWriteTime = record.InsertedAt
 
 		tk := tupleUtils.NewTupleKeyWithCondition(
 			tupleUtils.BuildObject(objectType, objectID),
@@ -393,10 +888,191 @@ Write:
 			Timestamp: now,
 		})
 	}
-	s.tuples[store] = records
+	s.setTuples(store, records)
+	s.notifyChangeWatchers(store)
 	return nil
 }
 
+// setTuples replaces the tuples of the store and rebuilds their index by actor.
+// The caller must hold the write lock of mutexTuples.
+func (s *MemoryBackend) setTuples(store string, records []*storage.TupleRecord) {
+	s.tuples[store] = records
+
+	byActor := make(map[string][]*storage.TupleRecord)
+	for _, tr := range records {
+		if tr.WrittenBy != "" {
+			byActor[tr.WrittenBy] = append(byActor[tr.WrittenBy], tr)
+		}
+	}
+	s.actorTuples[store] = byActor
+}
+
+// ReadByActor see [storage.ActorTupleReader].ReadByActor.
+func (s *MemoryBackend) ReadByActor(ctx context.Context, store, actor string) ([]*openfgav1.Tuple, error) {
+	_, span := tracer.Start(ctx, "memory.ReadByActor")
+	defer span.End()
+
+	s.mutexTuples.RLock()
+	defer s.mutexTuples.RUnlock()
+
+	if actor == "" {
+		return []*openfgav1.Tuple{}, nil
+	}
+
+	now := time.Now()
+	records := s.actorTuples[store][actor]
+	res := make([]*openfgav1.Tuple, 0, len(records))
+	for _, tr := range records {
+		if !tr.IsExpired(now) {
+			res = append(res, tr.AsTuple())
+		}
+	}
+
+	return res, nil
+}
+
+// DeleteTuples see [storage.BulkTupleDeleter].DeleteTuples.
+func (s *MemoryBackend) DeleteTuples(ctx context.Context, store string, filter *openfgav1.TupleKey) (int, error) {
+	_, span := tracer.Start(ctx, "memory.DeleteTuples")
+	defer span.End()
+
+	if s.atomicBulkDeletes || s.maxTuplesPerWrite <= 0 {
+		return s.deleteMatching(store, filter, 0), nil
+	}
+
+	var deleted int
+	for {
+		if err := ctx.Err(); err != nil {
+			return deleted, err
+		}
+
+		n := s.deleteMatching(store, filter, s.maxTuplesPerWrite)
+		deleted += n
+		if n < s.maxTuplesPerWrite {
+			return deleted, nil
+		}
+	}
+}
+
+// PreviewDeleteTuples see [storage.BulkTupleDeleter].PreviewDeleteTuples.
+func (s *MemoryBackend) PreviewDeleteTuples(ctx context.Context, store string, filter *openfgav1.TupleKey) ([]*openfgav1.Tuple, error) {
+	_, span := tracer.Start(ctx, "memory.PreviewDeleteTuples")
+	defer span.End()
+
+	s.mutexTuples.RLock()
+	defer s.mutexTuples.RUnlock()
+
+	now := time.Now()
+	res := []*openfgav1.Tuple{}
+	for _, tr := range s.tuples[store] {
+		if !tr.IsExpired(now) && match(tr, filter) {
+			res = append(res, tr.AsTuple())
+		}
+	}
+
+	return res, nil
+}
+
+// deleteMatching deletes up to limit tuples of the store matching the filter, or all of them if limit is 0,
+// and returns the number of deleted tuples.
+func (s *MemoryBackend) deleteMatching(store string, filter *openfgav1.TupleKey, limit int) int {
+	s.mutexTuples.Lock()
+	defer s.mutexTuples.Unlock()
+
+	s.deleteExpired(store, time.Now())
+
+	now := timestamppb.Now()
+
+	var deleted int
+	records := make([]*storage.TupleRecord, 0, len(s.tuples[store]))
+	for _, tr := range s.tuples[store] {
+		if (limit == 0 || deleted < limit) && match(tr, filter) {
+			tk := tr.AsTuple().GetKey()
+			s.changes[store] = append(s.changes[store], &openfgav1.TupleChange{
+				TupleKey:  tupleUtils.NewTupleKey(tk.GetObject(), tk.GetRelation(), tk.GetUser()), // Redact the condition info.
+				Operation: openfgav1.TupleOperation_TUPLE_OPERATION_DELETE,
+				Timestamp: now,
+			})
+			deleted++
+			continue
+		}
+		records = append(records, tr)
+	}
+
+	if deleted > 0 {
+		s.setTuples(store, records)
+		s.notifyChangeWatchers(store)
+	}
+
+	return deleted
+}
+
+// DeleteExpiredTuples see [storage.TupleExpirer].DeleteExpiredTuples.
+func (s *MemoryBackend) DeleteExpiredTuples(ctx context.Context, now time.Time) (int, error) {
+	_, span := tracer.Start(ctx, "memory.DeleteExpiredTuples")
+	defer span.End()
+
+	s.mutexTuples.Lock()
+	defer s.mutexTuples.Unlock()
+
+	var deleted int
+	for store := range s.tuples {
+		deleted += s.deleteExpired(store, now)
+	}
+
+	return deleted, nil
+}
+
+// deleteExpired deletes the tuples of the store that expired at or before now, recording their deletes in the
+// changelog, and returns the number of deleted tuples. The caller must hold the write lock of mutexTuples.
+func (s *MemoryBackend) deleteExpired(store string, now time.Time) int {
+	timestamp := timestamppb.Now()
+
+	var deleted int
+	records := make([]*storage.TupleRecord, 0, len(s.tuples[store]))
+	for _, tr := range s.tuples[store] {
+		if tr.IsExpired(now) {
+			tk := tr.AsTuple().GetKey()
+			s.changes[store] = append(s.changes[store], &openfgav1.TupleChange{
+				TupleKey:  tupleUtils.NewTupleKey(tk.GetObject(), tk.GetRelation(), tk.GetUser()), // Redact the condition info.
+				Operation: openfgav1.TupleOperation_TUPLE_OPERATION_DELETE,
+				Timestamp: timestamp,
+			})
+			deleted++
+			continue
+		}
+		records = append(records, tr)
+	}
+
+	if deleted > 0 {
+		s.setTuples(store, records)
+		s.notifyChangeWatchers(store)
+	}
+
+	return deleted
+}
+
+// SnapshotTuples see [storage.TupleSnapshotter].SnapshotTuples. It fails unless the bulk deletes are atomic, see
+// WithAtomicBulkDeletes, since a snapshot could otherwise hold a partially applied delete.
+func (s *MemoryBackend) SnapshotTuples(ctx context.Context, store string) (storage.RelationshipTupleReader, error) {
+	_, span := tracer.Start(ctx, "memory.SnapshotTuples")
+	defer span.End()
+
+	if !s.atomicBulkDeletes {
+		return nil, fmt.Errorf("the snapshots of the tuples require atomic bulk deletes")
+	}
+
+	s.mutexTuples.RLock()
+	defer s.mutexTuples.RUnlock()
+
+	// Writes replace the tuples of a store rather than modifying them, so the snapshot can share them.
+	return &MemoryBackend{
+		tuples:            map[string][]*storage.TupleRecord{store: s.tuples[store]},
+		readLatency:       s.readLatency,
+		readErrorInjector: s.readErrorInjector,
+	}, nil
+}
+
 func validateTuples(
 	records []*storage.TupleRecord,
 	deletes []*openfgav1.TupleKeyWithoutCondition,
@@ -430,11 +1106,17 @@ func (s *MemoryBackend) ReadUserTuple(ctx context.Context, store string, key *op
 	_, span := tracer.Start(ctx, "memory.ReadUserTuple")
 	defer span.End()
 
+	if err := s.simulateRead(ctx, key); err != nil {
+		telemetry.TraceError(span, err)
+		return nil, err
+	}
+
 	s.mutexTuples.RLock()
 	defer s.mutexTuples.RUnlock()
 
+	now := time.Now()
 	for _, t := range s.tuples[store] {
-		if match(t, key) {
+		if !t.IsExpired(now) && match(t, key) {
 			return t.AsTuple(), nil
 		}
 	}
@@ -452,35 +1134,92 @@ func (s *MemoryBackend) ReadUsersetTuples(
 	_, span := tracer.Start(ctx, "memory.ReadUsersetTuples")
 	defer span.End()
 
+	if err := s.simulateRead(ctx, &openfgav1.TupleKey{Object: filter.Object, Relation: filter.Relation}); err != nil {
+		telemetry.TraceError(span, err)
+		return nil, err
+	}
+
 	s.mutexTuples.RLock()
 	defer s.mutexTuples.RUnlock()
 
+	now := time.Now()
 	var matches []*storage.TupleRecord
 	for _, t := range s.tuples[store] {
+		if t.IsExpired(now) {
+			continue
+		}
+
 		if match(t, &openfgav1.TupleKey{
 			Object:   filter.Object,
 			Relation: filter.Relation,
-		}) && tupleUtils.GetUserTypeFromUser(t.User) == tupleUtils.UserSet {
-			if len(filter.AllowedUserTypeRestrictions) == 0 { // 1.0 model.
-				matches = append(matches, t)
-				continue
-			}
+		}) && isAllowedUserset(t.User, filter.AllowedUserTypeRestrictions) {
+			matches = append(matches, t)
+		}
+	}
 
-			// 1.1 model: see if the tuple found is of an allowed type.
-			userType := tupleUtils.GetType(t.User)
-			_, userRelation := tupleUtils.SplitObjectRelation(t.User)
-			for _, allowedType := range filter.AllowedUserTypeRestrictions {
-				if allowedType.GetType() == userType && allowedType.GetRelation() == userRelation {
-					matches = append(matches, t)
-					continue
-				}
-			}
+	return &staticIterator{records: matches}, nil
+}
+
+// ReadUsersetTuplesBatch see [storage.BatchUsersetTupleReader].ReadUsersetTuplesBatch.
+func (s *MemoryBackend) ReadUsersetTuplesBatch(
+	ctx context.Context,
+	store string,
+	filter storage.ReadUsersetTuplesBatchFilter,
+) (storage.TupleIterator, error) {
+	_, span := tracer.Start(ctx, "memory.ReadUsersetTuplesBatch")
+	defer span.End()
+
+	if err := s.simulateRead(ctx, &openfgav1.TupleKey{Relation: filter.Relation}); err != nil {
+		telemetry.TraceError(span, err)
+		return nil, err
+	}
+
+	objects := make(map[string]struct{}, len(filter.Objects))
+	for _, object := range filter.Objects {
+		objects[object] = struct{}{}
+	}
+
+	s.mutexTuples.RLock()
+	defer s.mutexTuples.RUnlock()
+
+	now := time.Now()
+	var matches []*storage.TupleRecord
+	for _, t := range s.tuples[store] {
+		if t.IsExpired(now) || t.Relation != filter.Relation {
+			continue
+		}
+
+		if _, ok := objects[tupleUtils.BuildObject(t.ObjectType, t.ObjectID)]; ok && isAllowedUserset(t.User, filter.AllowedUserTypeRestrictions) {
+			matches = append(matches, t)
 		}
 	}
 
 	return &staticIterator{records: matches}, nil
 }
 
+// isAllowedUserset reports whether the user is a userset of one of the allowed types, or of any type if there
+// are no restrictions.
+func isAllowedUserset(user string, restrictions []*openfgav1.RelationReference) bool {
+	if tupleUtils.GetUserTypeFromUser(user) != tupleUtils.UserSet {
+		return false
+	}
+
+	if len(restrictions) == 0 { // 1.0 model.
+		return true
+	}
+
+	// 1.1 model: see if the tuple found is of an allowed type.
+	userType := tupleUtils.GetType(user)
+	_, userRelation := tupleUtils.SplitObjectRelation(user)
+	for _, allowedType := range restrictions {
+		if allowedType.GetType() == userType && allowedType.GetRelation() == userRelation {
+			return true
+		}
+	}
+
+	return false
+}
+
 // ReadStartingWithUser see [storage.RelationshipTupleReader].ReadStartingWithUser.
 func (s *MemoryBackend) ReadStartingWithUser(
 	ctx context.Context,
@@ -490,12 +1229,18 @@ func (s *MemoryBackend) ReadStartingWithUser(
 	_, span := tracer.Start(ctx, "memory.ReadStartingWithUser")
 	defer span.End()
 
+	if err := s.simulateRead(ctx, &openfgav1.TupleKey{Object: filter.ObjectType + ":", Relation: filter.Relation}); err != nil {
+		telemetry.TraceError(span, err)
+		return nil, err
+	}
+
 	s.mutexTuples.RLock()
 	defer s.mutexTuples.RUnlock()
 
+	now := time.Now()
 	var matches []*storage.TupleRecord
 	for _, t := range s.tuples[store] {
-		if t.ObjectType != filter.ObjectType {
+		if t.ObjectType != filter.ObjectType || t.IsExpired(now) {
 			continue
 		}
 
@@ -658,10 +1403,162 @@ func (s *MemoryBackend) WriteAuthorizationModel(ctx context.Context, store strin
 	}
 
 	s.authorizationModels[store][model.GetId()] = &AuthorizationModelEntry{
-		model:  model,
-		latest: true,
+		model:     model,
+		latest:    true,
+		createdAt: time.Now().UTC(),
+	}
+
+	return nil
+}
+
+// ArchiveAuthorizationModel see [storage.AuthorizationModelArchiveBackend].ArchiveAuthorizationModel.
+func (s *MemoryBackend) ArchiveAuthorizationModel(ctx context.Context, store, id string) error {
+	_, span := tracer.Start(ctx, "memory.ArchiveAuthorizationModel")
+	defer span.End()
+
+	s.mutexModels.Lock()
+	defer s.mutexModels.Unlock()
+
+	entry, ok := s.authorizationModels[store][id]
+	if !ok {
+		telemetry.TraceError(span, storage.ErrNotFound)
+		return storage.ErrNotFound
+	}
+
+	entry.archived = true
+	return nil
+}
+
+// IsAuthorizationModelArchived see [storage.AuthorizationModelArchiveBackend].IsAuthorizationModelArchived.
+func (s *MemoryBackend) IsAuthorizationModelArchived(ctx context.Context, store, id string) (bool, error) {
+	_, span := tracer.Start(ctx, "memory.IsAuthorizationModelArchived")
+	defer span.End()
+
+	s.mutexModels.RLock()
+	defer s.mutexModels.RUnlock()
+
+	entry, ok := s.authorizationModels[store][id]
+	if !ok {
+		telemetry.TraceError(span, storage.ErrNotFound)
+		return false, storage.ErrNotFound
+	}
+
+	return entry.archived, nil
+}
+
+// ReadStoreDefaultModel see [storage.StoreDefaultModelBackend].ReadStoreDefaultModel.
+func (s *MemoryBackend) ReadStoreDefaultModel(ctx context.Context, store string) (string, error) {
+	_, span := tracer.Start(ctx, "memory.ReadStoreDefaultModel")
+	defer span.End()
+
+	s.mutexModels.RLock()
+	defer s.mutexModels.RUnlock()
+
+	return s.defaultModels[store], nil
+}
+
+// WriteStoreDefaultModel see [storage.StoreDefaultModelBackend].WriteStoreDefaultModel.
+func (s *MemoryBackend) WriteStoreDefaultModel(ctx context.Context, store, id string) error {
+	_, span := tracer.Start(ctx, "memory.WriteStoreDefaultModel")
+	defer span.End()
+
+	s.mutexModels.Lock()
+	defer s.mutexModels.Unlock()
+
+	if id == "" {
+		delete(s.defaultModels, store)
+		return nil
+	}
+
+	if _, ok := s.authorizationModels[store][id]; !ok {
+		telemetry.TraceError(span, storage.ErrNotFound)
+		return storage.ErrNotFound
+	}
+
+	s.defaultModels[store] = id
+	return nil
+}
+
+// ListModels see [storage.AuthorizationModelMetadataBackend].ListModels.
+func (s *MemoryBackend) ListModels(
+	ctx context.Context,
+	store string,
+	options storage.PaginationOptions,
+) ([]*storage.AuthorizationModelMetadata, []byte, error) {
+	_, span := tracer.Start(ctx, "memory.ListModels")
+	defer span.End()
+
+	s.mutexModels.RLock()
+	defer s.mutexModels.RUnlock()
+
+	entries := make([]*AuthorizationModelEntry, 0, len(s.authorizationModels[store]))
+	for _, entry := range s.authorizationModels[store] {
+		entries = append(entries, entry)
+	}
+
+	// From oldest to newest, the IDs break the ties of models written at the same time.
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].createdAt.Equal(entries[j].createdAt) {
+			return entries[i].createdAt.Before(entries[j].createdAt)
+		}
+
+		return entries[i].model.GetId() < entries[j].model.GetId()
+	})
+
+	var from int64
+	var err error
+	if options.From != "" {
+		from, err = strconv.ParseInt(options.From, 10, 32)
+		if err != nil {
+			return nil, nil, storage.ErrInvalidContinuationToken
+		}
+	}
+
+	pageSize := storage.DefaultPageSize
+	if options.PageSize > 0 {
+		pageSize = options.PageSize
+	}
+
+	to := int(from) + pageSize
+	if len(entries) < to {
+		to = len(entries)
+	}
+	if int(from) > to {
+		from = int64(to)
+	}
+
+	res := make([]*storage.AuthorizationModelMetadata, 0, to-int(from))
+	for _, entry := range entries[from:to] {
+		res = append(res, &storage.AuthorizationModelMetadata{
+			ID:        entry.model.GetId(),
+			CreatedAt: entry.createdAt,
+			Labels:    maps.Clone(entry.labels),
+		})
 	}
 
+	continuationToken := ""
+	if to != len(entries) {
+		continuationToken = strconv.Itoa(to)
+	}
+
+	return res, []byte(continuationToken), nil
+}
+
+// WriteAuthorizationModelLabels see [storage.AuthorizationModelMetadataBackend].WriteAuthorizationModelLabels.
+func (s *MemoryBackend) WriteAuthorizationModelLabels(ctx context.Context, store, id string, labels map[string]string) error {
+	_, span := tracer.Start(ctx, "memory.WriteAuthorizationModelLabels")
+	defer span.End()
+
+	s.mutexModels.Lock()
+	defer s.mutexModels.Unlock()
+
+	entry, ok := s.authorizationModels[store][id]
+	if !ok {
+		telemetry.TraceError(span, storage.ErrNotFound)
+		return storage.ErrNotFound
+	}
+
+	entry.labels = maps.Clone(labels)
 	return nil
 }
 
@@ -705,11 +1602,19 @@ func (s *MemoryBackend) WriteAssertions(ctx context.Context, store, modelID stri
 	_, span := tracer.Start(ctx, "memory.WriteAssertions")
 	defer span.End()
 
+	contextual := make([]*storage.Assertion, 0, len(assertions))
+	for _, assertion := range assertions {
+		contextual = append(contextual, &storage.Assertion{
+			TupleKey:    assertion.GetTupleKey(),
+			Expectation: assertion.GetExpectation(),
+		})
+	}
+
 	s.mutexAssertions.Lock()
 	defer s.mutexAssertions.Unlock()
 
 	assertionsID := fmt.Sprintf("%s|%s", store, modelID)
-	s.assertions[assertionsID] = assertions
+	s.assertions[assertionsID] = contextual
 
 	return nil
 }
@@ -722,14 +1627,85 @@ func (s *MemoryBackend) ReadAssertions(ctx context.Context, store, modelID strin
 	s.mutexAssertions.RLock()
 	defer s.mutexAssertions.RUnlock()
 
+	assertionsID := fmt.Sprintf("%s|%s", store, modelID)
+	contextual := s.assertions[assertionsID]
+
+	assertions := make([]*openfgav1.Assertion, 0, len(contextual))
+	for _, assertion := range contextual {
+		assertions = append(assertions, &openfgav1.Assertion{
+			TupleKey:    assertion.TupleKey,
+			Expectation: assertion.Expectation,
+		})
+	}
+
+	return assertions, nil
+}
+
+// WriteContextualAssertions see [storage.ContextualAssertionsBackend].WriteContextualAssertions.
+func (s *MemoryBackend) WriteContextualAssertions(ctx context.Context, store, modelID string, assertions []*storage.Assertion) error {
+	_, span := tracer.Start(ctx, "memory.WriteContextualAssertions")
+	defer span.End()
+
+	s.mutexAssertions.Lock()
+	defer s.mutexAssertions.Unlock()
+
+	assertionsID := fmt.Sprintf("%s|%s", store, modelID)
+	s.assertions[assertionsID] = assertions
+
+	return nil
+}
+
+// ReadContextualAssertions see [storage.ContextualAssertionsBackend].ReadContextualAssertions.
+func (s *MemoryBackend) ReadContextualAssertions(ctx context.Context, store, modelID string) ([]*storage.Assertion, error) {
+	_, span := tracer.Start(ctx, "memory.ReadContextualAssertions")
+	defer span.End()
+
+	s.mutexAssertions.RLock()
+	defer s.mutexAssertions.RUnlock()
+
 	assertionsID := fmt.Sprintf("%s|%s", store, modelID)
 	assertions, ok := s.assertions[assertionsID]
 	if !ok {
-		return []*openfgav1.Assertion{}, nil
+		return []*storage.Assertion{}, nil
 	}
 	return assertions, nil
 }
 
+// ReadStoreFeatureFlags see [storage.StoreFeatureFlagsBackend].ReadStoreFeatureFlags.
+func (s *MemoryBackend) ReadStoreFeatureFlags(ctx context.Context, store string) (map[string]bool, error) {
+	_, span := tracer.Start(ctx, "memory.ReadStoreFeatureFlags")
+	defer span.End()
+
+	s.mutexFeatureFlags.RLock()
+	defer s.mutexFeatureFlags.RUnlock()
+
+	flags := make(map[string]bool, len(s.featureFlags[store]))
+	for flag, enabled := range s.featureFlags[store] {
+		flags[flag] = enabled
+	}
+
+	return flags, nil
+}
+
+// WriteStoreFeatureFlags see [storage.StoreFeatureFlagsBackend].WriteStoreFeatureFlags.
+func (s *MemoryBackend) WriteStoreFeatureFlags(ctx context.Context, store string, flags map[string]bool) error {
+	_, span := tracer.Start(ctx, "memory.WriteStoreFeatureFlags")
+	defer span.End()
+
+	s.mutexFeatureFlags.Lock()
+	defer s.mutexFeatureFlags.Unlock()
+
+	if _, ok := s.featureFlags[store]; !ok {
+		s.featureFlags[store] = make(map[string]bool, len(flags))
+	}
+
+	for flag, enabled := range flags {
+		s.featureFlags[store][flag] = enabled
+	}
+
+	return nil
+}
+
 // MaxTuplesPerWrite see [storage.RelationshipTupleWriter].MaxTuplesPerWrite.
 func (s *MemoryBackend) MaxTuplesPerWrite() int {
 	return s.maxTuplesPerWrite
@@ -2,6 +2,11 @@ package memory
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
 	"testing"
 	"time"
 
@@ -213,3 +218,1040 @@ func TestFindTupleKey(t *testing.T) {
 		})
 	}
 }
+
+func TestReadObjectRelations(t *testing.T) {
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	ds := New().(*MemoryBackend)
+	t.Cleanup(ds.Close)
+
+	err := ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "owner", "user:anne"),
+		tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+		tuple.NewTupleKey("document:1", "viewer", "group:eng#member"),
+		tuple.NewTupleKey("document:1", "parent", "folder:x"),
+		tuple.NewTupleKey("document:2", "viewer", "user:jon"),
+		tuple.NewTupleKey("document:11", "viewer", "user:jon"),
+	})
+	require.NoError(t, err)
+
+	t.Run("groups_tuples_by_relation", func(t *testing.T) {
+		relations, err := ds.ReadObjectRelations(ctx, storeID, "document:1")
+		require.NoError(t, err)
+		require.Len(t, relations, 3)
+
+		users := func(tuples []*openfgav1.Tuple) []string {
+			var res []string
+			for _, t := range tuples {
+				res = append(res, t.GetKey().GetUser())
+			}
+			return res
+		}
+
+		require.ElementsMatch(t, []string{"user:anne"}, users(relations["owner"]))
+		require.ElementsMatch(t, []string{"user:jon", "group:eng#member"}, users(relations["viewer"]))
+		require.ElementsMatch(t, []string{"folder:x"}, users(relations["parent"]))
+	})
+
+	t.Run("object_without_tuples", func(t *testing.T) {
+		relations, err := ds.ReadObjectRelations(ctx, storeID, "document:3")
+		require.NoError(t, err)
+		require.Empty(t, relations)
+	})
+
+	t.Run("other_store", func(t *testing.T) {
+		relations, err := ds.ReadObjectRelations(ctx, ulid.Make().String(), "document:1")
+		require.NoError(t, err)
+		require.Empty(t, relations)
+	})
+}
+
+func TestListObjectsWithTuples(t *testing.T) {
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	ds := New().(*MemoryBackend)
+	t.Cleanup(ds.Close)
+
+	err := ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "owner", "user:anne"),
+		tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+		tuple.NewTupleKey("document:3", "parent", "folder:x"),
+		tuple.NewTupleKey("document:2", "viewer", "group:eng#member"),
+		tuple.NewTupleKey("folder:x", "viewer", "document:4"),
+	})
+	require.NoError(t, err)
+
+	err = ds.Write(ctx, storeID, []*openfgav1.TupleKeyWithoutCondition{
+		tuple.TupleKeyToTupleKeyWithoutCondition(tuple.NewTupleKey("document:2", "viewer", "group:eng#member")),
+	}, nil)
+	require.NoError(t, err)
+
+	t.Run("only_objects_with_tuples", func(t *testing.T) {
+		// document:2 has no tuples left and document:4 only appears as a user
+		objectIDs, err := ds.ListObjectsWithTuples(ctx, storeID, "document")
+		require.NoError(t, err)
+		require.Equal(t, []string{"1", "3"}, objectIDs)
+
+		objectIDs, err = ds.ListObjectsWithTuples(ctx, storeID, "folder")
+		require.NoError(t, err)
+		require.Equal(t, []string{"x"}, objectIDs)
+	})
+
+	t.Run("type_without_tuples", func(t *testing.T) {
+		objectIDs, err := ds.ListObjectsWithTuples(ctx, storeID, "user")
+		require.NoError(t, err)
+		require.Empty(t, objectIDs)
+	})
+
+	t.Run("other_store", func(t *testing.T) {
+		objectIDs, err := ds.ListObjectsWithTuples(ctx, ulid.Make().String(), "document")
+		require.NoError(t, err)
+		require.Empty(t, objectIDs)
+	})
+}
+
+func TestStoreFeatureFlags(t *testing.T) {
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	ds := New().(*MemoryBackend)
+	t.Cleanup(ds.Close)
+
+	flags, err := ds.ReadStoreFeatureFlags(ctx, storeID)
+	require.NoError(t, err)
+	require.Empty(t, flags)
+
+	err = ds.WriteStoreFeatureFlags(ctx, storeID, map[string]bool{"a": true, "b": true})
+	require.NoError(t, err)
+
+	err = ds.WriteStoreFeatureFlags(ctx, storeID, map[string]bool{"b": false})
+	require.NoError(t, err)
+
+	flags, err = ds.ReadStoreFeatureFlags(ctx, storeID)
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{"a": true, "b": false}, flags)
+
+	// the returned map is a copy
+	flags["a"] = false
+	flags, err = ds.ReadStoreFeatureFlags(ctx, storeID)
+	require.NoError(t, err)
+	require.True(t, flags["a"])
+
+	flags, err = ds.ReadStoreFeatureFlags(ctx, ulid.Make().String())
+	require.NoError(t, err)
+	require.Empty(t, flags)
+}
+
+func TestWatchChanges(t *testing.T) {
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	ds := New().(*MemoryBackend)
+	t.Cleanup(ds.Close)
+
+	// changes written before the watch starts are not sent
+	err := ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:0", "viewer", "user:jon"),
+	})
+	require.NoError(t, err)
+
+	receive := func(t *testing.T, changes <-chan *openfgav1.TupleChange, n int) []string {
+		var received []string
+		for i := 0; i < n; i++ {
+			select {
+			case change := <-changes:
+				received = append(received, change.GetOperation().String()+" "+tuple.TupleKeyToString(change.GetTupleKey()))
+			case <-time.After(time.Second):
+				require.FailNow(t, "timed out waiting for a change")
+			}
+		}
+
+		return received
+	}
+
+	t.Run("filtered_by_object_type", func(t *testing.T) {
+		watchCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		documentChanges, err := ds.WatchChanges(watchCtx, storeID, "document")
+		require.NoError(t, err)
+
+		allChanges, err := ds.WatchChanges(watchCtx, storeID, "")
+		require.NoError(t, err)
+
+		err = ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+			tuple.NewTupleKey("folder:x", "viewer", "user:jon"),
+			tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+		})
+		require.NoError(t, err)
+
+		err = ds.Write(ctx, storeID, []*openfgav1.TupleKeyWithoutCondition{
+			tuple.TupleKeyToTupleKeyWithoutCondition(tuple.NewTupleKey("folder:x", "viewer", "user:jon")),
+			tuple.TupleKeyToTupleKeyWithoutCondition(tuple.NewTupleKey("document:0", "viewer", "user:jon")),
+		}, []*openfgav1.TupleKey{
+			tuple.NewTupleKey("group:eng", "member", "user:jon"),
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, []string{
+			"TUPLE_OPERATION_WRITE document:1#viewer@user:jon",
+			"TUPLE_OPERATION_DELETE document:0#viewer@user:jon",
+		}, receive(t, documentChanges, 2))
+
+		require.Len(t, receive(t, allChanges, 5), 5)
+
+		select {
+		case change := <-documentChanges:
+			require.FailNow(t, "unexpected change", change.String())
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+
+	t.Run("closed_when_context_is_done", func(t *testing.T) {
+		watchCtx, cancel := context.WithCancel(ctx)
+
+		changes, err := ds.WatchChanges(watchCtx, storeID, "document")
+		require.NoError(t, err)
+
+		cancel()
+
+		require.Eventually(t, func() bool {
+			select {
+			case _, ok := <-changes:
+				return !ok
+			default:
+				return false
+			}
+		}, time.Second, 10*time.Millisecond)
+
+		// the watchers of the previous subtest stop too, once their context is canceled
+		require.Eventually(t, func() bool {
+			ds.mutexChangeWatchers.Lock()
+			defer ds.mutexChangeWatchers.Unlock()
+			_, ok := ds.changeWatchers[storeID]
+			return !ok
+		}, time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestArchiveAuthorizationModel(t *testing.T) {
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	ds := New().(*MemoryBackend)
+	t.Cleanup(ds.Close)
+
+	model := &openfgav1.AuthorizationModel{
+		Id:              ulid.Make().String(),
+		SchemaVersion:   "1.1",
+		TypeDefinitions: []*openfgav1.TypeDefinition{{Type: "user"}},
+	}
+	err := ds.WriteAuthorizationModel(ctx, storeID, model)
+	require.NoError(t, err)
+
+	archived, err := ds.IsAuthorizationModelArchived(ctx, storeID, model.GetId())
+	require.NoError(t, err)
+	require.False(t, archived)
+
+	err = ds.ArchiveAuthorizationModel(ctx, storeID, model.GetId())
+	require.NoError(t, err)
+
+	archived, err = ds.IsAuthorizationModelArchived(ctx, storeID, model.GetId())
+	require.NoError(t, err)
+	require.True(t, archived)
+
+	// an archived model can still be read, and is still the latest model
+	_, err = ds.ReadAuthorizationModel(ctx, storeID, model.GetId())
+	require.NoError(t, err)
+
+	latest, err := ds.FindLatestAuthorizationModel(ctx, storeID)
+	require.NoError(t, err)
+	require.Equal(t, model.GetId(), latest.GetId())
+
+	err = ds.ArchiveAuthorizationModel(ctx, storeID, ulid.Make().String())
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	_, err = ds.IsAuthorizationModelArchived(ctx, ulid.Make().String(), model.GetId())
+	require.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func TestStoreDefaultModel(t *testing.T) {
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	ds := New().(*MemoryBackend)
+	t.Cleanup(ds.Close)
+
+	id, err := ds.ReadStoreDefaultModel(ctx, storeID)
+	require.NoError(t, err)
+	require.Empty(t, id)
+
+	model := &openfgav1.AuthorizationModel{
+		Id:              ulid.Make().String(),
+		SchemaVersion:   "1.1",
+		TypeDefinitions: []*openfgav1.TypeDefinition{{Type: "user"}},
+	}
+	require.NoError(t, ds.WriteAuthorizationModel(ctx, storeID, model))
+
+	require.NoError(t, ds.WriteStoreDefaultModel(ctx, storeID, model.GetId()))
+
+	id, err = ds.ReadStoreDefaultModel(ctx, storeID)
+	require.NoError(t, err)
+	require.Equal(t, model.GetId(), id)
+
+	err = ds.WriteStoreDefaultModel(ctx, storeID, ulid.Make().String())
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	// unpinning
+	require.NoError(t, ds.WriteStoreDefaultModel(ctx, storeID, ""))
+
+	id, err = ds.ReadStoreDefaultModel(ctx, storeID)
+	require.NoError(t, err)
+	require.Empty(t, id)
+}
+
+func TestContextualAssertions(t *testing.T) {
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+	modelID := ulid.Make().String()
+
+	ds := New().(*MemoryBackend)
+	t.Cleanup(ds.Close)
+
+	assertions, err := ds.ReadContextualAssertions(ctx, storeID, modelID)
+	require.NoError(t, err)
+	require.Empty(t, assertions)
+
+	conditionContext, err := structpb.NewStruct(map[string]any{"ip": "10.0.0.1"})
+	require.NoError(t, err)
+
+	contextual := []*storage.Assertion{
+		{
+			TupleKey:         tuple.NewAssertionTupleKey("document:1", "viewer", "user:jon"),
+			Expectation:      true,
+			ContextualTuples: []*openfgav1.TupleKey{tuple.NewTupleKey("document:1", "viewer", "user:jon")},
+			Context:          conditionContext,
+		},
+	}
+	require.NoError(t, ds.WriteContextualAssertions(ctx, storeID, modelID, contextual))
+
+	assertions, err = ds.ReadContextualAssertions(ctx, storeID, modelID)
+	require.NoError(t, err)
+	require.Equal(t, contextual, assertions)
+
+	// the assertions are read without their context by ReadAssertions
+	plain, err := ds.ReadAssertions(ctx, storeID, modelID)
+	require.NoError(t, err)
+	require.Len(t, plain, 1)
+	require.True(t, plain[0].GetExpectation())
+
+	// and WriteAssertions overwrites them
+	require.NoError(t, ds.WriteAssertions(ctx, storeID, modelID, []*openfgav1.Assertion{
+		{TupleKey: tuple.NewAssertionTupleKey("document:2", "viewer", "user:jon")},
+	}))
+
+	assertions, err = ds.ReadContextualAssertions(ctx, storeID, modelID)
+	require.NoError(t, err)
+	require.Len(t, assertions, 1)
+	require.Equal(t, "document:2", assertions[0].TupleKey.GetObject())
+	require.Empty(t, assertions[0].ContextualTuples)
+	require.Nil(t, assertions[0].Context)
+}
+
+func TestListModels(t *testing.T) {
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	ds := New().(*MemoryBackend)
+	t.Cleanup(ds.Close)
+
+	var modelIDs []string
+	for i := 0; i < 3; i++ {
+		model := &openfgav1.AuthorizationModel{
+			Id:              ulid.Make().String(),
+			SchemaVersion:   "1.1",
+			TypeDefinitions: []*openfgav1.TypeDefinition{{Type: "user"}},
+		}
+		err := ds.WriteAuthorizationModel(ctx, storeID, model)
+		require.NoError(t, err)
+
+		modelIDs = append(modelIDs, model.GetId())
+	}
+
+	err := ds.WriteAuthorizationModelLabels(ctx, storeID, modelIDs[1], map[string]string{"env": "prod", "version": "v2"})
+	require.NoError(t, err)
+
+	t.Run("ordered_by_creation_with_metadata", func(t *testing.T) {
+		models, continuationToken, err := ds.ListModels(ctx, storeID, storage.PaginationOptions{})
+		require.NoError(t, err)
+		require.Empty(t, continuationToken)
+		require.Len(t, models, 3)
+
+		for i, model := range models {
+			require.Equal(t, modelIDs[i], model.ID)
+			require.False(t, model.CreatedAt.IsZero())
+			if i > 0 {
+				require.False(t, model.CreatedAt.Before(models[i-1].CreatedAt))
+			}
+		}
+
+		require.Empty(t, models[0].Labels)
+		require.Equal(t, map[string]string{"env": "prod", "version": "v2"}, models[1].Labels)
+		require.Empty(t, models[2].Labels)
+	})
+
+	t.Run("paginated", func(t *testing.T) {
+		firstPage, continuationToken, err := ds.ListModels(ctx, storeID, storage.NewPaginationOptions(2, ""))
+		require.NoError(t, err)
+		require.Len(t, firstPage, 2)
+		require.NotEmpty(t, continuationToken)
+
+		secondPage, continuationToken, err := ds.ListModels(ctx, storeID, storage.NewPaginationOptions(2, string(continuationToken)))
+		require.NoError(t, err)
+		require.Empty(t, continuationToken)
+		require.Len(t, secondPage, 1)
+
+		require.Equal(t, modelIDs, []string{firstPage[0].ID, firstPage[1].ID, secondPage[0].ID})
+	})
+
+	t.Run("labels_are_replaced", func(t *testing.T) {
+		err := ds.WriteAuthorizationModelLabels(ctx, storeID, modelIDs[1], map[string]string{"env": "staging"})
+		require.NoError(t, err)
+
+		models, _, err := ds.ListModels(ctx, storeID, storage.PaginationOptions{})
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"env": "staging"}, models[1].Labels)
+	})
+
+	t.Run("invalid_continuation_token", func(t *testing.T) {
+		_, _, err := ds.ListModels(ctx, storeID, storage.NewPaginationOptions(2, "invalid"))
+		require.ErrorIs(t, err, storage.ErrInvalidContinuationToken)
+	})
+
+	t.Run("unknown_model", func(t *testing.T) {
+		err := ds.WriteAuthorizationModelLabels(ctx, storeID, ulid.Make().String(), map[string]string{"env": "prod"})
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("store_without_models", func(t *testing.T) {
+		models, continuationToken, err := ds.ListModels(ctx, ulid.Make().String(), storage.PaginationOptions{})
+		require.NoError(t, err)
+		require.Empty(t, continuationToken)
+		require.Empty(t, models)
+	})
+}
+
+func TestDeleteTuples(t *testing.T) {
+	for name, atomic := range map[string]bool{"batched": false, "atomic": true} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			storeID := ulid.Make().String()
+
+			ds := New(WithMaxTuplesPerWrite(3), WithAtomicBulkDeletes(atomic)).(*MemoryBackend)
+			t.Cleanup(ds.Close)
+
+			var writes []*openfgav1.TupleKey
+			for i := 0; i < 7; i++ {
+				writes = append(writes, tuple.NewTupleKey(fmt.Sprintf("document:%d", i), "viewer", "user:anne"))
+			}
+			writes = append(writes, tuple.NewTupleKey("document:0", "viewer", "user:bob"))
+			require.NoError(t, ds.Write(ctx, storeID, nil, writes))
+
+			deleted, err := ds.DeleteTuples(ctx, storeID, tuple.NewTupleKey("document:", "viewer", "user:anne"))
+			require.NoError(t, err)
+			require.Equal(t, 7, deleted)
+
+			tuples, _, err := ds.ReadPage(ctx, storeID, tuple.NewTupleKey("document:", "", ""), storage.NewPaginationOptions(100, ""))
+			require.NoError(t, err)
+			require.Len(t, tuples, 1)
+			require.Equal(t, "user:bob", tuples[0].GetKey().GetUser())
+
+			changes, _, err := ds.ReadChanges(ctx, storeID, "", storage.NewPaginationOptions(100, ""), 0)
+			require.NoError(t, err)
+
+			var deletes int
+			for _, change := range changes {
+				if change.GetOperation() == openfgav1.TupleOperation_TUPLE_OPERATION_DELETE {
+					require.Equal(t, "user:anne", change.GetTupleKey().GetUser())
+					deletes++
+				}
+			}
+			require.Equal(t, 7, deletes)
+
+			deleted, err = ds.DeleteTuples(ctx, storeID, tuple.NewTupleKey("document:", "viewer", "user:anne"))
+			require.NoError(t, err)
+			require.Zero(t, deleted)
+		})
+	}
+}
+
+func TestPreviewDeleteTuples(t *testing.T) {
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	ds := New(WithMaxTuplesPerWrite(3)).(*MemoryBackend)
+	t.Cleanup(ds.Close)
+
+	var writes []*openfgav1.TupleKey
+	for i := 0; i < 5; i++ {
+		writes = append(writes, tuple.NewTupleKey(fmt.Sprintf("document:%d", i), "viewer", "user:anne"))
+	}
+	writes = append(writes, tuple.NewTupleKey("document:0", "viewer", "user:bob"))
+	require.NoError(t, ds.Write(ctx, storeID, nil, writes))
+
+	readKeys := func() []string {
+		tuples, _, err := ds.ReadPage(ctx, storeID, tuple.NewTupleKey("document:", "", ""), storage.NewPaginationOptions(100, ""))
+		require.NoError(t, err)
+
+		keys := make([]string, 0, len(tuples))
+		for _, tp := range tuples {
+			keys = append(keys, tuple.TupleKeyToString(tp.GetKey()))
+		}
+		return keys
+	}
+
+	filter := tuple.NewTupleKey("document:", "viewer", "user:anne")
+	before := readKeys()
+
+	preview, err := ds.PreviewDeleteTuples(ctx, storeID, filter)
+	require.NoError(t, err)
+	require.Len(t, preview, 5)
+
+	previewKeys := make([]string, 0, len(preview))
+	for _, tp := range preview {
+		previewKeys = append(previewKeys, tuple.TupleKeyToString(tp.GetKey()))
+	}
+
+	// the dry run deletes nothing
+	require.Equal(t, before, readKeys())
+
+	deleted, err := ds.DeleteTuples(ctx, storeID, filter)
+	require.NoError(t, err)
+	require.Equal(t, len(preview), deleted)
+
+	after := readKeys()
+	removed := make([]string, 0, len(before))
+	for _, key := range before {
+		if !slices.Contains(after, key) {
+			removed = append(removed, key)
+		}
+	}
+	require.ElementsMatch(t, removed, previewKeys)
+
+	preview, err = ds.PreviewDeleteTuples(ctx, storeID, filter)
+	require.NoError(t, err)
+	require.Empty(t, preview)
+}
+
+func TestCountChanges(t *testing.T) {
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	ds := New().(*MemoryBackend)
+	t.Cleanup(ds.Close)
+
+	err := ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+		tuple.NewTupleKey("document:2", "viewer", "user:jon"),
+	})
+	require.NoError(t, err)
+
+	time.Sleep(50 * time.Millisecond)
+	since := time.Now()
+
+	err = ds.Write(ctx, storeID, []*openfgav1.TupleKeyWithoutCondition{
+		tuple.TupleKeyToTupleKeyWithoutCondition(tuple.NewTupleKey("document:1", "viewer", "user:jon")),
+	}, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:3", "viewer", "user:jon"),
+	})
+	require.NoError(t, err)
+
+	count, err := ds.CountChanges(ctx, storeID, since)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	count, err = ds.CountChanges(ctx, storeID, since.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 4, count)
+
+	count, err = ds.CountChanges(ctx, storeID, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+
+	count, err = ds.CountChanges(ctx, ulid.Make().String(), since)
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+}
+
+func TestCountTuples(t *testing.T) {
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	ds := New().(*MemoryBackend)
+	t.Cleanup(ds.Close)
+
+	err := ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+		tuple.NewTupleKey("document:2", "viewer", "user:jon"),
+		tuple.NewTupleKey("document:1", "editor", "user:jon"),
+		tuple.NewTupleKey("folder:1", "viewer", "user:jon"),
+	})
+	require.NoError(t, err)
+
+	count, err := ds.CountTuples(ctx, storeID, "document", "viewer")
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	count, err = ds.CountTuples(ctx, storeID, "document", "owner")
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+
+	count, err = ds.CountTuples(ctx, ulid.Make().String(), "document", "viewer")
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+}
+
+func TestReadByActor(t *testing.T) {
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	ds := New().(*MemoryBackend)
+	t.Cleanup(ds.Close)
+
+	err := ds.Write(storage.ContextWithWriteActor(ctx, "client-a"), storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+		tuple.NewTupleKey("document:2", "viewer", "user:jon"),
+	})
+	require.NoError(t, err)
+
+	err = ds.Write(storage.ContextWithWriteActor(ctx, "client-b"), storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:3", "viewer", "user:jon"),
+	})
+	require.NoError(t, err)
+
+	err = ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:4", "viewer", "user:jon"),
+	})
+	require.NoError(t, err)
+
+	readKeys := func(actor string) []string {
+		tuples, err := ds.ReadByActor(ctx, storeID, actor)
+		require.NoError(t, err)
+
+		keys := make([]string, 0, len(tuples))
+		for _, tp := range tuples {
+			keys = append(keys, tuple.TupleKeyToString(tp.GetKey()))
+		}
+		return keys
+	}
+
+	require.Equal(t, []string{"document:1#viewer@user:jon", "document:2#viewer@user:jon"}, readKeys("client-a"))
+	require.Equal(t, []string{"document:3#viewer@user:jon"}, readKeys("client-b"))
+	require.Empty(t, readKeys(""))
+	require.Empty(t, readKeys("client-c"))
+
+	t.Run("deleted_tuples_are_not_returned", func(t *testing.T) {
+		err := ds.Write(storage.ContextWithWriteActor(ctx, "client-b"), storeID, []*openfgav1.TupleKeyWithoutCondition{
+			tuple.TupleKeyToTupleKeyWithoutCondition(tuple.NewTupleKey("document:1", "viewer", "user:jon")),
+		}, nil)
+		require.NoError(t, err)
+		require.Equal(t, []string{"document:2#viewer@user:jon"}, readKeys("client-a"))
+
+		_, err = ds.DeleteTuples(ctx, storeID, tuple.NewTupleKey("document:3", "", ""))
+		require.NoError(t, err)
+		require.Empty(t, readKeys("client-b"))
+	})
+}
+
+func TestArtificialReadLatency(t *testing.T) {
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	ds := New(WithArtificialReadLatency(50 * time.Millisecond))
+	t.Cleanup(ds.Close)
+
+	tk := tuple.NewTupleKey("document:1", "viewer", "user:jon")
+	require.NoError(t, ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tk}))
+
+	t.Run("reads_are_delayed", func(t *testing.T) {
+		start := time.Now()
+		_, err := ds.ReadUserTuple(ctx, storeID, tk)
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	})
+
+	t.Run("delay_is_cut_short_by_the_context", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err := ds.ReadUsersetTuples(ctx, storeID, storage.ReadUsersetTuplesFilter{Object: "document:1", Relation: "viewer"})
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Less(t, time.Since(start), 50*time.Millisecond)
+	})
+}
+
+func TestReadErrorInjection(t *testing.T) {
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	errInjected := errors.New("injected error")
+	ds := New(WithReadErrorInjection(func(tupleKey *openfgav1.TupleKey) error {
+		if tupleKey.GetObject() == "document:2" || tupleKey.GetObject() == "folder:" {
+			return errInjected
+		}
+		return nil
+	}))
+	t.Cleanup(ds.Close)
+
+	require.NoError(t, ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+		tuple.NewTupleKey("document:2", "viewer", "user:jon"),
+	}))
+
+	_, err := ds.ReadUserTuple(ctx, storeID, tuple.NewTupleKey("document:1", "viewer", "user:jon"))
+	require.NoError(t, err)
+
+	_, err = ds.ReadUserTuple(ctx, storeID, tuple.NewTupleKey("document:2", "viewer", "user:jon"))
+	require.ErrorIs(t, err, errInjected)
+
+	_, err = ds.Read(ctx, storeID, tuple.NewTupleKey("document:2", "", ""))
+	require.ErrorIs(t, err, errInjected)
+
+	_, _, err = ds.ReadPage(ctx, storeID, tuple.NewTupleKey("document:2", "", ""), storage.PaginationOptions{PageSize: 10})
+	require.ErrorIs(t, err, errInjected)
+
+	_, err = ds.ReadUsersetTuples(ctx, storeID, storage.ReadUsersetTuplesFilter{Object: "document:2", Relation: "viewer"})
+	require.ErrorIs(t, err, errInjected)
+
+	_, err = ds.ReadStartingWithUser(ctx, storeID, storage.ReadStartingWithUserFilter{
+		ObjectType: "folder",
+		Relation:   "viewer",
+		UserFilter: []*openfgav1.ObjectRelation{{Object: "user:jon"}},
+	})
+	require.ErrorIs(t, err, errInjected)
+}
+
+func TestPersistent(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "openfga.snapshot")
+
+	ds, err := NewPersistent(path, 0)
+	require.NoError(t, err)
+
+	store, err := ds.CreateStore(ctx, &openfgav1.Store{Id: ulid.Make().String(), Name: "demo"})
+	require.NoError(t, err)
+
+	model := &openfgav1.AuthorizationModel{
+		Id:            ulid.Make().String(),
+		SchemaVersion: "1.1",
+		TypeDefinitions: []*openfgav1.TypeDefinition{
+			{Type: "user"},
+		},
+	}
+	require.NoError(t, ds.WriteAuthorizationModel(ctx, store.GetId(), model))
+
+	conditionContext, err := structpb.NewStruct(map[string]any{"ip": "10.0.0.1"})
+	require.NoError(t, err)
+
+	require.NoError(t, ds.Write(ctx, store.GetId(), nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+		tuple.NewTupleKeyWithCondition("document:2", "viewer", "user:jon", "in_network", conditionContext),
+		tuple.NewTupleKey("document:3", "viewer", "user:jon"),
+	}))
+	require.NoError(t, ds.Write(ctx, store.GetId(), []*openfgav1.TupleKeyWithoutCondition{
+		tuple.TupleKeyToTupleKeyWithoutCondition(tuple.NewTupleKey("document:3", "viewer", "user:jon")),
+	}, nil))
+
+	assertions := []*openfgav1.Assertion{
+		{TupleKey: tuple.NewAssertionTupleKey("document:1", "viewer", "user:jon"), Expectation: true},
+	}
+	require.NoError(t, ds.WriteAssertions(ctx, store.GetId(), model.GetId(), assertions))
+	contextualModelID := ulid.Make().String()
+	require.NoError(t, ds.(storage.ContextualAssertionsBackend).WriteContextualAssertions(ctx, store.GetId(), contextualModelID, []*storage.Assertion{
+		{
+			TupleKey:         tuple.NewAssertionTupleKey("document:1", "viewer", "user:jon"),
+			ContextualTuples: []*openfgav1.TupleKey{tuple.NewTupleKey("document:1", "viewer", "user:jon")},
+			Context:          conditionContext,
+		},
+	}))
+	require.NoError(t, ds.(storage.StoreDefaultModelBackend).WriteStoreDefaultModel(ctx, store.GetId(), model.GetId()))
+
+	changes, token, err := ds.ReadChanges(ctx, store.GetId(), "", storage.PaginationOptions{PageSize: 2}, 0)
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+
+	ds.Close()
+
+	// the data survives the restart
+	ds, err = NewPersistent(path, 0)
+	require.NoError(t, err)
+	t.Cleanup(ds.Close)
+
+	gotStore, err := ds.GetStore(ctx, store.GetId())
+	require.NoError(t, err)
+	require.Equal(t, "demo", gotStore.GetName())
+
+	latest, err := ds.FindLatestAuthorizationModel(ctx, store.GetId())
+	require.NoError(t, err)
+	require.Equal(t, model.GetId(), latest.GetId())
+
+	tp, err := ds.ReadUserTuple(ctx, store.GetId(), tuple.NewTupleKey("document:2", "viewer", "user:jon"))
+	require.NoError(t, err)
+	require.Equal(t, "in_network", tp.GetKey().GetCondition().GetName())
+	require.Equal(t, "10.0.0.1", tp.GetKey().GetCondition().GetContext().GetFields()["ip"].GetStringValue())
+
+	_, err = ds.ReadUserTuple(ctx, store.GetId(), tuple.NewTupleKey("document:3", "viewer", "user:jon"))
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	gotAssertions, err := ds.ReadAssertions(ctx, store.GetId(), model.GetId())
+	require.NoError(t, err)
+	require.Len(t, gotAssertions, 1)
+
+	gotContextualAssertions, err := ds.(storage.ContextualAssertionsBackend).ReadContextualAssertions(ctx, store.GetId(), model.GetId())
+	require.NoError(t, err)
+	require.Len(t, gotContextualAssertions, 1)
+	require.Empty(t, gotContextualAssertions[0].ContextualTuples)
+
+	gotContextualAssertions, err = ds.(storage.ContextualAssertionsBackend).ReadContextualAssertions(ctx, store.GetId(), contextualModelID)
+	require.NoError(t, err)
+	require.Len(t, gotContextualAssertions, 1)
+	require.Len(t, gotContextualAssertions[0].ContextualTuples, 1)
+	require.Equal(t, "10.0.0.1", gotContextualAssertions[0].Context.GetFields()["ip"].GetStringValue())
+
+	defaultModelID, err := ds.(storage.StoreDefaultModelBackend).ReadStoreDefaultModel(ctx, store.GetId())
+	require.NoError(t, err)
+	require.Equal(t, model.GetId(), defaultModelID)
+
+	// the continuation token of the changelog read before the restart resumes it
+	changes, _, err = ds.ReadChanges(ctx, store.GetId(), "", storage.PaginationOptions{PageSize: 10, From: string(token)}, 0)
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	require.Equal(t, "document:3", changes[0].GetTupleKey().GetObject())
+	require.Equal(t, openfgav1.TupleOperation_TUPLE_OPERATION_WRITE, changes[0].GetOperation())
+	require.Equal(t, openfgav1.TupleOperation_TUPLE_OPERATION_DELETE, changes[1].GetOperation())
+}
+
+func TestPersistentPeriodicSnapshots(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "openfga.snapshot")
+
+	ds, err := NewPersistent(path, 10*time.Millisecond)
+	require.NoError(t, err)
+	t.Cleanup(ds.Close)
+
+	_, err = ds.CreateStore(context.Background(), &openfgav1.Store{Id: ulid.Make().String(), Name: "demo"})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestPersistentInvalidSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "openfga.snapshot")
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+
+	_, err := NewPersistent(path, 0)
+	require.ErrorContains(t, err, "decode snapshot file")
+}
+
+func TestReadFilteredChanges(t *testing.T) {
+	ctx := context.Background()
+	ds := New().(*MemoryBackend)
+	t.Cleanup(ds.Close)
+
+	store := ulid.Make().String()
+
+	require.NoError(t, ds.Write(ctx, store, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+		tuple.NewTupleKey("document:1", "editor", "user:jon"),
+		tuple.NewTupleKey("folder:1", "viewer", "user:maria"),
+	}))
+
+	time.Sleep(2 * time.Millisecond)
+	between := time.Now()
+	time.Sleep(2 * time.Millisecond)
+
+	require.NoError(t, ds.Write(ctx, store, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:2", "viewer", "user:maria"),
+	}))
+
+	read := func(t *testing.T, filter storage.ReadChangesFilter) []string {
+		changes, _, err := ds.ReadFilteredChanges(ctx, store, filter, storage.NewPaginationOptions(100, ""), 0)
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil
+		}
+		require.NoError(t, err)
+
+		var keys []string
+		for _, change := range changes {
+			keys = append(keys, tuple.TupleKeyToString(change.GetTupleKey()))
+		}
+		return keys
+	}
+
+	t.Run("relation", func(t *testing.T) {
+		require.Equal(t, []string{
+			"document:1#viewer@user:jon",
+			"folder:1#viewer@user:maria",
+			"document:2#viewer@user:maria",
+		}, read(t, storage.ReadChangesFilter{Relation: "viewer"}))
+	})
+
+	t.Run("type_and_user", func(t *testing.T) {
+		require.Equal(t, []string{
+			"document:2#viewer@user:maria",
+		}, read(t, storage.ReadChangesFilter{ObjectType: "document", User: "user:maria"}))
+	})
+
+	t.Run("time_range", func(t *testing.T) {
+		require.Equal(t, []string{
+			"document:1#viewer@user:jon",
+			"document:1#editor@user:jon",
+			"folder:1#viewer@user:maria",
+		}, read(t, storage.ReadChangesFilter{EndTime: between}))
+
+		require.Equal(t, []string{
+			"document:2#viewer@user:maria",
+		}, read(t, storage.ReadChangesFilter{Relation: "viewer", StartTime: between}))
+	})
+
+	t.Run("no_match", func(t *testing.T) {
+		require.Empty(t, read(t, storage.ReadChangesFilter{Relation: "owner"}))
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		filter := storage.ReadChangesFilter{Relation: "viewer"}
+
+		changes, token, err := ds.ReadFilteredChanges(ctx, store, filter, storage.NewPaginationOptions(2, ""), 0)
+		require.NoError(t, err)
+		require.Len(t, changes, 2)
+
+		changes, _, err = ds.ReadFilteredChanges(ctx, store, filter, storage.NewPaginationOptions(2, string(token)), 0)
+		require.NoError(t, err)
+		require.Len(t, changes, 1)
+		require.Equal(t, "document:2#viewer@user:maria", tuple.TupleKeyToString(changes[0].GetTupleKey()))
+	})
+}
+
+func TestTupleExpiry(t *testing.T) {
+	ctx := context.Background()
+	ds := New().(*MemoryBackend)
+	t.Cleanup(ds.Close)
+
+	store := ulid.Make().String()
+	now := time.Now()
+
+	require.NoError(t, ds.Write(ctx, store, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+	}))
+	require.NoError(t, ds.Write(storage.ContextWithTupleExpiry(ctx, now.Add(time.Hour)), store, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:2", "viewer", "user:jon"),
+	}))
+	require.NoError(t, ds.Write(storage.ContextWithTupleExpiry(ctx, now.Add(-time.Second)), store, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:3", "viewer", "user:jon"),
+	}))
+
+	t.Run("expired_tuples_are_not_read", func(t *testing.T) {
+		iter, err := ds.Read(ctx, store, &openfgav1.TupleKey{})
+		require.NoError(t, err)
+		tuples, _, err := iter.(*staticIterator).ToArray(ctx)
+		require.NoError(t, err)
+		require.Len(t, tuples, 2)
+
+		_, err = ds.ReadUserTuple(ctx, store, tuple.NewTupleKey("document:3", "viewer", "user:jon"))
+		require.ErrorIs(t, err, storage.ErrNotFound)
+
+		count, err := ds.CountTuples(ctx, store, "document", "viewer")
+		require.NoError(t, err)
+		require.Equal(t, 2, count)
+	})
+
+	t.Run("expired_tuples_are_deleted", func(t *testing.T) {
+		deleted, err := ds.DeleteExpiredTuples(ctx, time.Now())
+		require.NoError(t, err)
+		require.Equal(t, 1, deleted)
+
+		changes, _, err := ds.ReadChanges(ctx, store, "", storage.NewPaginationOptions(100, ""), 0)
+		require.NoError(t, err)
+		last := changes[len(changes)-1]
+		require.Equal(t, "document:3#viewer@user:jon", tuple.TupleKeyToString(last.GetTupleKey()))
+		require.Equal(t, openfgav1.TupleOperation_TUPLE_OPERATION_DELETE, last.GetOperation())
+
+		deleted, err = ds.DeleteExpiredTuples(ctx, time.Now())
+		require.NoError(t, err)
+		require.Zero(t, deleted)
+	})
+
+	t.Run("expired_tuples_can_be_written_again", func(t *testing.T) {
+		require.NoError(t, ds.Write(ctx, store, nil, []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:3", "viewer", "user:jon"),
+		}))
+
+		// a write deletes the expired tuples of the store first
+		require.NoError(t, ds.Write(storage.ContextWithTupleExpiry(ctx, now.Add(-time.Second)), store, nil, []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:4", "viewer", "user:jon"),
+		}))
+		require.NoError(t, ds.Write(ctx, store, nil, []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:4", "viewer", "user:jon"),
+		}))
+
+		_, err := ds.ReadUserTuple(ctx, store, tuple.NewTupleKey("document:4", "viewer", "user:jon"))
+		require.NoError(t, err)
+	})
+
+	t.Run("the_expiry_is_persisted", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "openfga.snapshot")
+
+		ds, err := NewPersistent(path, 0)
+		require.NoError(t, err)
+		require.NoError(t, ds.Write(storage.ContextWithTupleExpiry(ctx, now.Add(time.Hour)), store, nil, []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+		}))
+		ds.Close()
+
+		ds, err = NewPersistent(path, 0)
+		require.NoError(t, err)
+		t.Cleanup(ds.Close)
+
+		deleted, err := ds.(*MemoryBackend).DeleteExpiredTuples(ctx, now.Add(2*time.Hour))
+		require.NoError(t, err)
+		require.Equal(t, 1, deleted)
+	})
+}
+
+func TestSnapshotTuples(t *testing.T) {
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	t.Run("requires_atomic_bulk_deletes", func(t *testing.T) {
+		ds := New()
+		t.Cleanup(ds.Close)
+
+		_, err := ds.(storage.TupleSnapshotter).SnapshotTuples(ctx, storeID)
+		require.Error(t, err)
+	})
+
+	t.Run("does_not_see_the_later_writes", func(t *testing.T) {
+		ds := New(WithAtomicBulkDeletes(true))
+		t.Cleanup(ds.Close)
+
+		anne := tuple.NewTupleKey("document:1", "viewer", "user:anne")
+		require.NoError(t, ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{anne}))
+
+		snapshot, err := ds.(storage.TupleSnapshotter).SnapshotTuples(ctx, storeID)
+		require.NoError(t, err)
+
+		require.NoError(t, ds.Write(ctx, storeID, []*openfgav1.TupleKeyWithoutCondition{
+			tuple.TupleKeyToTupleKeyWithoutCondition(anne),
+		}, []*openfgav1.TupleKey{tuple.NewTupleKey("document:1", "viewer", "user:bob")}))
+
+		tuples, _, err := snapshot.ReadPage(ctx, storeID, tuple.NewTupleKey("document:1", "viewer", ""), storage.NewPaginationOptions(100, ""))
+		require.NoError(t, err)
+		require.Len(t, tuples, 1)
+		require.Equal(t, "user:anne", tuples[0].GetKey().GetUser())
+	})
+}
@@ -0,0 +1,490 @@
+package memory
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"go.uber.org/zap"
+	"golang.org/x/exp/maps"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/openfga/openfga/pkg/logger"
+	"github.com/openfga/openfga/pkg/storage"
+)
+
+// snapshotVersion is the version of the format of the snapshot files, bumped on incompatible changes.
+const snapshotVersion = 1
+
+// snapshot is the content of a snapshot file. The protobuf messages are encoded with protojson, so that the
+// file can be read, e.g. to debug a demo.
+type snapshot struct {
+	Version             int                                     `json:"version"`
+	Stores              []json.RawMessage                       `json:"stores"`
+	Tuples              map[string][]tupleRecordSnapshot        `json:"tuples"`
+	Changes             map[string][]json.RawMessage            `json:"changes"`
+	TimeRanges          map[string]*storage.StoreTimeRange      `json:"time_ranges"`
+	AuthorizationModels map[string][]authorizationModelSnapshot `json:"authorization_models"`
+	Assertions          map[string][]json.RawMessage            `json:"assertions"`
+	AssertionContexts   map[string][]assertionContextSnapshot   `json:"assertion_contexts,omitempty"`
+	FeatureFlags        map[string]map[string]bool              `json:"feature_flags"`
+	DefaultModels       map[string]string                       `json:"default_models,omitempty"`
+}
+
+type tupleRecordSnapshot struct {
+	ObjectType       string          `json:"object_type"`
+	ObjectID         string          `json:"object_id"`
+	Relation         string          `json:"relation"`
+	User             string          `json:"user"`
+	ConditionName    string          `json:"condition_name,omitempty"`
+	ConditionContext json.RawMessage `json:"condition_context,omitempty"`
+	Ulid             string          `json:"ulid"`
+	InsertedAt       time.Time       `json:"inserted_at"`
+	WrittenBy        string          `json:"written_by,omitempty"`
+	ExpiresAt        *time.Time      `json:"expires_at,omitempty"`
+}
+
+type authorizationModelSnapshot struct {
+	Model     json.RawMessage   `json:"model"`
+	Latest    bool              `json:"latest"`
+	Archived  bool              `json:"archived"`
+	CreatedAt time.Time         `json:"created_at"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+// assertionContextSnapshot is the context of the assertion at the same index of the assertions of a model.
+type assertionContextSnapshot struct {
+	ContextualTuples []json.RawMessage `json:"contextual_tuples,omitempty"`
+	Context          json.RawMessage   `json:"context,omitempty"`
+}
+
+// WithLogger returns a [StorageOption] that sets the logger the failures of the periodic snapshots of a persistent
+// [MemoryBackend] are logged with. See NewPersistent.
+func WithLogger(l logger.Logger) StorageOption {
+	return func(ds *MemoryBackend) { ds.logger = l }
+}
+
+// NewPersistent creates a new [MemoryBackend] given the options, that persists its data to a snapshot file, so that
+// local development and demos survive restarts without running a database. The data of the file is loaded if it
+// exists, and saved to it every interval if it changed, and when the datastore is closed. If interval is not
+// positive, the data is only saved when the datastore is closed, or with SaveSnapshot.
+//
+// The whole changelog is persisted, so that the continuation tokens of ReadChanges remain valid after a restart.
+// The writes made after the last snapshot are lost if the process is killed.
+func NewPersistent(path string, interval time.Duration, opts ...StorageOption) (storage.OpenFGADatastore, error) {
+	ds := New(opts...).(*MemoryBackend)
+	ds.snapshotPath = path
+
+	if err := ds.loadSnapshot(); err != nil {
+		return nil, err
+	}
+
+	if interval > 0 {
+		ds.stopSnapshots = make(chan struct{})
+		ds.snapshotsDone = make(chan struct{})
+		go ds.snapshotPeriodically(interval)
+	}
+
+	return ds, nil
+}
+
+func (s *MemoryBackend) snapshotPeriodically(interval time.Duration) {
+	defer close(s.snapshotsDone)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopSnapshots:
+			return
+		case <-ticker.C:
+			if err := s.SaveSnapshot(); err != nil {
+				s.logger.Error("failed to save the snapshot of the memory datastore", zap.Error(err))
+			}
+		}
+	}
+}
+
+// SaveSnapshot saves the data of a [MemoryBackend] created with NewPersistent to its snapshot file, if it changed
+// since the last snapshot. It does not do anything for the other instances.
+func (s *MemoryBackend) SaveSnapshot() error {
+	if s.snapshotPath == "" {
+		return nil
+	}
+
+	s.mutexSnapshot.Lock()
+	defer s.mutexSnapshot.Unlock()
+
+	data, err := s.encodeSnapshot()
+	if err != nil {
+		return err
+	}
+
+	if bytes.Equal(data, s.lastSnapshot) {
+		return nil
+	}
+
+	// the snapshot is written to a temporary file renamed over the previous one, so that a crash never leaves
+	// a partially written snapshot behind
+	tmp, err := os.CreateTemp(filepath.Dir(s.snapshotPath), filepath.Base(s.snapshotPath)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("create snapshot file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write snapshot file: %w", err)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write snapshot file: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write snapshot file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.snapshotPath); err != nil {
+		return fmt.Errorf("replace snapshot file: %w", err)
+	}
+
+	s.lastSnapshot = data
+
+	return nil
+}
+
+// encodeSnapshot encodes the data of the datastore. The locks of the data are held together, so that the snapshot
+// is consistent.
+func (s *MemoryBackend) encodeSnapshot() ([]byte, error) {
+	s.mutexStores.RLock()
+	defer s.mutexStores.RUnlock()
+	s.mutexTuples.RLock()
+	defer s.mutexTuples.RUnlock()
+	s.mutexModels.RLock()
+	defer s.mutexModels.RUnlock()
+	s.mutexAssertions.RLock()
+	defer s.mutexAssertions.RUnlock()
+	s.mutexFeatureFlags.RLock()
+	defer s.mutexFeatureFlags.RUnlock()
+
+	snap := snapshot{
+		Version:             snapshotVersion,
+		Tuples:              make(map[string][]tupleRecordSnapshot, len(s.tuples)),
+		Changes:             make(map[string][]json.RawMessage, len(s.changes)),
+		TimeRanges:          s.timeRanges,
+		AuthorizationModels: make(map[string][]authorizationModelSnapshot, len(s.authorizationModels)),
+		Assertions:          make(map[string][]json.RawMessage, len(s.assertions)),
+		AssertionContexts:   make(map[string][]assertionContextSnapshot),
+		FeatureFlags:        s.featureFlags,
+		DefaultModels:       s.defaultModels,
+	}
+
+	for _, store := range sortedKeys(s.stores) {
+		encoded, err := marshalProto(s.stores[store])
+		if err != nil {
+			return nil, err
+		}
+		snap.Stores = append(snap.Stores, encoded)
+	}
+
+	for store, records := range s.tuples {
+		tuples := make([]tupleRecordSnapshot, 0, len(records))
+		for _, tr := range records {
+			var conditionContext json.RawMessage
+			if tr.ConditionContext != nil {
+				encoded, err := marshalProto(tr.ConditionContext)
+				if err != nil {
+					return nil, err
+				}
+				conditionContext = encoded
+			}
+
+			var expiresAt *time.Time
+			if !tr.ExpiresAt.IsZero() {
+				expiresAt = &tr.ExpiresAt
+			}
+
+			tuples = append(tuples, tupleRecordSnapshot{
+				ObjectType:       tr.ObjectType,
+				ObjectID:         tr.ObjectID,
+				Relation:         tr.Relation,
+				User:             tr.User,
+				ConditionName:    tr.ConditionName,
+				ConditionContext: conditionContext,
+				Ulid:             tr.Ulid,
+				InsertedAt:       tr.InsertedAt,
+				WrittenBy:        tr.WrittenBy,
+				ExpiresAt:        expiresAt,
+			})
+		}
+		snap.Tuples[store] = tuples
+	}
+
+	for store, changes := range s.changes {
+		encoded, err := marshalProtos(changes)
+		if err != nil {
+			return nil, err
+		}
+		snap.Changes[store] = encoded
+	}
+
+	for store, entries := range s.authorizationModels {
+		models := make([]authorizationModelSnapshot, 0, len(entries))
+		for _, id := range sortedKeys(entries) {
+			entry := entries[id]
+
+			encoded, err := marshalProto(entry.model)
+			if err != nil {
+				return nil, err
+			}
+
+			models = append(models, authorizationModelSnapshot{
+				Model:     encoded,
+				Latest:    entry.latest,
+				Archived:  entry.archived,
+				CreatedAt: entry.createdAt,
+				Labels:    entry.labels,
+			})
+		}
+		snap.AuthorizationModels[store] = models
+	}
+
+	for id, assertions := range s.assertions {
+		encoded := make([]json.RawMessage, 0, len(assertions))
+		contexts := make([]assertionContextSnapshot, 0, len(assertions))
+		hasContext := false
+		for _, assertion := range assertions {
+			e, err := marshalProto(&openfgav1.Assertion{TupleKey: assertion.TupleKey, Expectation: assertion.Expectation})
+			if err != nil {
+				return nil, err
+			}
+			encoded = append(encoded, e)
+
+			var assertionContext assertionContextSnapshot
+			if assertionContext.ContextualTuples, err = marshalProtos(assertion.ContextualTuples); err != nil {
+				return nil, err
+			}
+			if assertion.Context != nil {
+				if assertionContext.Context, err = marshalProto(assertion.Context); err != nil {
+					return nil, err
+				}
+			}
+			contexts = append(contexts, assertionContext)
+			hasContext = hasContext || len(assertion.ContextualTuples) > 0 || assertion.Context != nil
+		}
+		snap.Assertions[id] = encoded
+
+		// the assertions without context are saved as before
+		if hasContext {
+			snap.AssertionContexts[id] = contexts
+		}
+	}
+
+	// the keys of the maps are sorted by encoding/json, which makes the snapshots of the same data equal
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return nil, fmt.Errorf("encode snapshot: %w", err)
+	}
+
+	return data, nil
+}
+
+// loadSnapshot loads the data of the snapshot file into the datastore, if the file exists.
+func (s *MemoryBackend) loadSnapshot() error {
+	data, err := os.ReadFile(s.snapshotPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("read snapshot file: %w", err)
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("decode snapshot file '%s': %w", s.snapshotPath, err)
+	}
+
+	if snap.Version != snapshotVersion {
+		return fmt.Errorf("unsupported version %d of snapshot file '%s'", snap.Version, s.snapshotPath)
+	}
+
+	for _, encoded := range snap.Stores {
+		store := &openfgav1.Store{}
+		if err := unmarshalProto(encoded, store); err != nil {
+			return err
+		}
+		s.stores[store.GetId()] = store
+	}
+
+	for store, tuples := range snap.Tuples {
+		records := make([]*storage.TupleRecord, 0, len(tuples))
+		for _, t := range tuples {
+			var conditionContext *structpb.Struct
+			if len(t.ConditionContext) > 0 {
+				conditionContext = &structpb.Struct{}
+				if err := unmarshalProto(t.ConditionContext, conditionContext); err != nil {
+					return err
+				}
+			}
+
+			var expiresAt time.Time
+			if t.ExpiresAt != nil {
+				expiresAt = *t.ExpiresAt
+			}
+
+			records = append(records, &storage.TupleRecord{
+				Store:            store,
+				ObjectType:       t.ObjectType,
+				ObjectID:         t.ObjectID,
+				Relation:         t.Relation,
+				User:             t.User,
+				ConditionName:    t.ConditionName,
+				ConditionContext: conditionContext,
+				Ulid:             t.Ulid,
+				InsertedAt:       t.InsertedAt,
+				WrittenBy:        t.WrittenBy,
+				ExpiresAt:        expiresAt,
+			})
+		}
+		s.setTuples(store, records)
+	}
+
+	for store, encoded := range snap.Changes {
+		changes, err := unmarshalProtos(encoded, func() *openfgav1.TupleChange { return &openfgav1.TupleChange{} })
+		if err != nil {
+			return err
+		}
+		s.changes[store] = changes
+	}
+
+	for store, timeRange := range snap.TimeRanges {
+		s.timeRanges[store] = timeRange
+	}
+
+	for store, models := range snap.AuthorizationModels {
+		entries := make(map[string]*AuthorizationModelEntry, len(models))
+		for _, m := range models {
+			model := &openfgav1.AuthorizationModel{}
+			if err := unmarshalProto(m.Model, model); err != nil {
+				return err
+			}
+
+			entries[model.GetId()] = &AuthorizationModelEntry{
+				model:     model,
+				latest:    m.Latest,
+				archived:  m.Archived,
+				createdAt: m.CreatedAt,
+				labels:    m.Labels,
+			}
+		}
+		s.authorizationModels[store] = entries
+	}
+
+	for id, encoded := range snap.Assertions {
+		assertions, err := unmarshalProtos(encoded, func() *openfgav1.Assertion { return &openfgav1.Assertion{} })
+		if err != nil {
+			return err
+		}
+
+		contexts := snap.AssertionContexts[id]
+		if contexts != nil && len(contexts) != len(assertions) {
+			return fmt.Errorf("decode snapshot file: the assertions of '%s' do not match their contexts", id)
+		}
+
+		contextual := make([]*storage.Assertion, 0, len(assertions))
+		for i, assertion := range assertions {
+			a := &storage.Assertion{
+				TupleKey:    assertion.GetTupleKey(),
+				Expectation: assertion.GetExpectation(),
+			}
+
+			if contexts != nil {
+				if a.ContextualTuples, err = unmarshalProtos(contexts[i].ContextualTuples, func() *openfgav1.TupleKey { return &openfgav1.TupleKey{} }); err != nil {
+					return err
+				}
+				if contexts[i].Context != nil {
+					a.Context = &structpb.Struct{}
+					if err := unmarshalProto(contexts[i].Context, a.Context); err != nil {
+						return err
+					}
+				}
+			}
+
+			contextual = append(contextual, a)
+		}
+		s.assertions[id] = contextual
+	}
+
+	for store, flags := range snap.FeatureFlags {
+		s.featureFlags[store] = flags
+	}
+
+	for store, id := range snap.DefaultModels {
+		s.defaultModels[store] = id
+	}
+
+	s.lastSnapshot = data
+
+	return nil
+}
+
+func marshalProto(m proto.Message) (json.RawMessage, error) {
+	encoded, err := protojson.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("encode snapshot: %w", err)
+	}
+
+	return encoded, nil
+}
+
+func marshalProtos[M proto.Message](messages []M) ([]json.RawMessage, error) {
+	encoded := make([]json.RawMessage, 0, len(messages))
+	for _, m := range messages {
+		e, err := marshalProto(m)
+		if err != nil {
+			return nil, err
+		}
+		encoded = append(encoded, e)
+	}
+
+	return encoded, nil
+}
+
+func unmarshalProto(encoded json.RawMessage, m proto.Message) error {
+	if err := protojson.Unmarshal(encoded, m); err != nil {
+		return fmt.Errorf("decode snapshot file: %w", err)
+	}
+
+	return nil
+}
+
+func unmarshalProtos[M proto.Message](encoded []json.RawMessage, newMessage func() M) ([]M, error) {
+	messages := make([]M, 0, len(encoded))
+	for _, e := range encoded {
+		m := newMessage()
+		if err := unmarshalProto(e, m); err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+
+	return messages, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := maps.Keys(m)
+	sort.Strings(keys)
+
+	return keys
+}
@@ -38,11 +38,33 @@ type MySQL struct {
 	dbStatsCollector       prometheus.Collector
 	maxTuplesPerWriteField int
 	maxTypesPerModelField  int
+	storeStats             *sqlcommon.StoreStatsAggregator
 }
 
 // Ensures that MySQL implements the OpenFGADatastore interface.
 var _ storage.OpenFGADatastore = (*MySQL)(nil)
 
+// Ensures that MySQL implements the ConditionalTupleWriter interface.
+var _ storage.ConditionalTupleWriter = (*MySQL)(nil)
+
+// Ensures that MySQL implements the ChangelogFilterReader interface.
+var _ storage.ChangelogFilterReader = (*MySQL)(nil)
+
+// Ensures that MySQL implements the BatchUsersetTupleReader interface.
+var _ storage.BatchUsersetTupleReader = (*MySQL)(nil)
+
+// Ensures that MySQL implements the StoreStatsReader interface.
+var _ storage.StoreStatsReader = (*MySQL)(nil)
+
+// Ensures that MySQL implements the StoreTimeRangeReader interface.
+var _ storage.StoreTimeRangeReader = (*MySQL)(nil)
+
+func init() {
+	storage.Register("mysql", func(uri string, cfg *storage.DatastoreConfig) (storage.OpenFGADatastore, error) {
+		return New(uri, cfg)
+	})
+}
+
 // New creates a new [MySQL] storage.
 func New(uri string, cfg *sqlcommon.Config) (*MySQL, error) {
 	if cfg.Username != "" || cfg.Password != "" {
@@ -117,11 +139,13 @@ func New(uri string, cfg *sqlcommon.Config) (*MySQL, error) {
 		dbStatsCollector:       collector,
 		maxTuplesPerWriteField: cfg.MaxTuplesPerWriteField,
 		maxTypesPerModelField:  cfg.MaxTypesPerModelField,
+		storeStats:             sqlcommon.NewStoreStatsAggregator(dbInfo, cfg.StoreStatsRefreshInterval, cfg.Logger),
 	}, nil
 }
 
 // Close see [storage.OpenFGADatastore].Close.
 func (m *MySQL) Close() {
+	m.storeStats.Close()
 	if m.dbStatsCollector != nil {
 		prometheus.Unregister(m.dbStatsCollector)
 	}
@@ -193,6 +217,7 @@ func (m *MySQL) read(ctx context.Context, store string, tupleKey *openfgav1.Tupl
 		sb = sb.Limit(uint64(opts.PageSize + 1)) // + 1 is used to determine whether to return a continuation token.
 	}
 
+	sqlcommon.AnnotateStatement(span, sb)
 	rows, err := sb.QueryContext(ctx)
 	if err != nil {
 		return nil, sqlcommon.HandleSQLError(err)
@@ -215,6 +240,26 @@ func (m *MySQL) Write(ctx context.Context, store string, deletes storage.Deletes
 	return sqlcommon.Write(ctx, m.dbInfo, store, deletes, writes, now)
 }
 
+// WriteWithPreconditions see [storage.ConditionalTupleWriter].WriteWithPreconditions.
+func (m *MySQL) WriteWithPreconditions(
+	ctx context.Context,
+	store string,
+	preconditions storage.WritePreconditions,
+	deletes storage.Deletes,
+	writes storage.Writes,
+) error {
+	ctx, span := tracer.Start(ctx, "mysql.WriteWithPreconditions")
+	defer span.End()
+
+	if len(deletes)+len(writes) > m.MaxTuplesPerWrite() {
+		return storage.ErrExceededWriteBatchLimit
+	}
+
+	now := time.Now().UTC()
+
+	return sqlcommon.WriteWithPreconditions(ctx, m.dbInfo, store, preconditions, deletes, writes, now)
+}
+
 // ReadUserTuple see [storage.RelationshipTupleReader].ReadUserTuple.
 func (m *MySQL) ReadUserTuple(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
 	ctx, span := tracer.Start(ctx, "mysql.ReadUserTuple")
@@ -226,7 +271,8 @@ func (m *MySQL) ReadUserTuple(ctx context.Context, store string, tupleKey *openf
 	var conditionName sql.NullString
 	var conditionContext []byte
 	var record storage.TupleRecord
-	err := m.stbl.
+
+	sb := m.stbl.
 		Select(
 			"object_type", "object_id", "relation", "_user",
 			"condition_name", "condition_context",
@@ -239,7 +285,10 @@ func (m *MySQL) ReadUserTuple(ctx context.Context, store string, tupleKey *openf
 			"relation":    tupleKey.GetRelation(),
 			"_user":       tupleKey.GetUser(),
 			"user_type":   userType,
-		}).
+		})
+
+	sqlcommon.AnnotateStatement(span, sb)
+	err := sb.
 		QueryRowContext(ctx).
 		Scan(
 			&record.ObjectType,
@@ -308,6 +357,69 @@ func (m *MySQL) ReadUsersetTuples(
 		}
 		sb = sb.Where(orConditions)
 	}
+
+	sqlcommon.AnnotateStatement(span, sb)
+	rows, err := sb.QueryContext(ctx)
+	if err != nil {
+		return nil, sqlcommon.HandleSQLError(err)
+	}
+
+	return sqlcommon.NewSQLTupleIterator(rows), nil
+}
+
+// ReadUsersetTuplesBatch see [storage.BatchUsersetTupleReader].ReadUsersetTuplesBatch. The objects are matched
+// with one IN clause per object type.
+func (m *MySQL) ReadUsersetTuplesBatch(
+	ctx context.Context,
+	store string,
+	filter storage.ReadUsersetTuplesBatchFilter,
+) (storage.TupleIterator, error) {
+	ctx, span := tracer.Start(ctx, "mysql.ReadUsersetTuplesBatch")
+	defer span.End()
+
+	objectIDsByType := map[string][]string{}
+	var objectTypes []string
+	for _, object := range filter.Objects {
+		objectType, objectID := tupleUtils.SplitObject(object)
+		if _, ok := objectIDsByType[objectType]; !ok {
+			objectTypes = append(objectTypes, objectType)
+		}
+		objectIDsByType[objectType] = append(objectIDsByType[objectType], objectID)
+	}
+
+	objectConditions := sq.Or{}
+	for _, objectType := range objectTypes {
+		objectConditions = append(objectConditions, sq.Eq{
+			"object_type": objectType,
+			"object_id":   objectIDsByType[objectType],
+		})
+	}
+
+	sb := m.stbl.
+		Select(
+			"store", "object_type", "object_id", "relation", "_user",
+			"condition_name", "condition_context", "ulid", "inserted_at",
+		).
+		From("tuple").
+		Where(sq.Eq{"store": store}).
+		Where(sq.Eq{"user_type": tupleUtils.UserSet}).
+		Where(sq.Eq{"relation": filter.Relation}).
+		Where(objectConditions)
+
+	if len(filter.AllowedUserTypeRestrictions) > 0 {
+		orConditions := sq.Or{}
+		for _, userset := range filter.AllowedUserTypeRestrictions {
+			if _, ok := userset.GetRelationOrWildcard().(*openfgav1.RelationReference_Relation); ok {
+				orConditions = append(orConditions, sq.Like{"_user": userset.GetType() + ":%#" + userset.GetRelation()})
+			}
+			if _, ok := userset.GetRelationOrWildcard().(*openfgav1.RelationReference_Wildcard); ok {
+				orConditions = append(orConditions, sq.Eq{"_user": userset.GetType() + ":*"})
+			}
+		}
+		sb = sb.Where(orConditions)
+	}
+
+	sqlcommon.AnnotateStatement(span, sb)
 	rows, err := sb.QueryContext(ctx)
 	if err != nil {
 		return nil, sqlcommon.HandleSQLError(err)
@@ -334,7 +446,7 @@ func (m *MySQL) ReadStartingWithUser(
 		targetUsersArg = append(targetUsersArg, targetUser)
 	}
 
-	rows, err := m.stbl.
+	sb := m.stbl.
 		Select(
 			"store", "object_type", "object_id", "relation", "_user",
 			"condition_name", "condition_context", "ulid", "inserted_at",
@@ -345,7 +457,10 @@ func (m *MySQL) ReadStartingWithUser(
 			"object_type": opts.ObjectType,
 			"relation":    opts.Relation,
 			"_user":       targetUsersArg,
-		}).QueryContext(ctx)
+		})
+
+	sqlcommon.AnnotateStatement(span, sb)
+	rows, err := sb.QueryContext(ctx)
 	if err != nil {
 		return nil, sqlcommon.HandleSQLError(err)
 	}
@@ -689,6 +804,32 @@ func (m *MySQL) ReadChanges(
 	ctx, span := tracer.Start(ctx, "mysql.ReadChanges")
 	defer span.End()
 
+	return m.readChanges(ctx, store, storage.ReadChangesFilter{ObjectType: objectTypeFilter}, opts, horizonOffset)
+}
+
+// ReadFilteredChanges see [storage.ChangelogFilterReader].ReadFilteredChanges.
+func (m *MySQL) ReadFilteredChanges(
+	ctx context.Context,
+	store string,
+	filter storage.ReadChangesFilter,
+	opts storage.PaginationOptions,
+	horizonOffset time.Duration,
+) ([]*openfgav1.TupleChange, []byte, error) {
+	ctx, span := tracer.Start(ctx, "mysql.ReadFilteredChanges")
+	defer span.End()
+
+	return m.readChanges(ctx, store, filter, opts, horizonOffset)
+}
+
+func (m *MySQL) readChanges(
+	ctx context.Context,
+	store string,
+	filter storage.ReadChangesFilter,
+	opts storage.PaginationOptions,
+	horizonOffset time.Duration,
+) ([]*openfgav1.TupleChange, []byte, error) {
+	objectTypeFilter := filter.ObjectType
+
 	sb := m.stbl.
 		Select(
 			"ulid", "object_type", "object_id", "relation", "_user", "operation",
@@ -702,6 +843,7 @@ func (m *MySQL) ReadChanges(
 	if objectTypeFilter != "" {
 		sb = sb.Where(sq.Eq{"object_type": objectTypeFilter})
 	}
+	sb = sqlcommon.FilterChanges(sb, filter)
 	if opts.From != "" {
 		token, err := sqlcommon.UnmarshallContToken(opts.From)
 		if err != nil {
@@ -783,6 +925,23 @@ func (m *MySQL) ReadChanges(
 	return changes, contToken, nil
 }
 
+// StoreTimeRange see [storage.StoreTimeRangeReader].StoreTimeRange.
+func (m *MySQL) StoreTimeRange(ctx context.Context, store string) (*storage.StoreTimeRange, error) {
+	ctx, span := tracer.Start(ctx, "mysql.StoreTimeRange")
+	defer span.End()
+
+	return sqlcommon.StoreTimeRange(ctx, m.dbInfo, store)
+}
+
+// ReadStoreStats see [storage.StoreStatsReader].ReadStoreStats. The statistics are computed in the background,
+// see [sqlcommon.StoreStatsAggregator].
+func (m *MySQL) ReadStoreStats(ctx context.Context, store string) (*storage.StoreStats, error) {
+	ctx, span := tracer.Start(ctx, "mysql.ReadStoreStats")
+	defer span.End()
+
+	return m.storeStats.ReadStoreStats(ctx, store)
+}
+
 // IsReady see [sqlcommon.IsReady].
 func (m *MySQL) IsReady(ctx context.Context) (storage.ReadinessStatus, error) {
 	return sqlcommon.IsReady(ctx, m.db)
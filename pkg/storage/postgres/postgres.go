@@ -39,11 +39,33 @@ type Postgres struct {
 	dbStatsCollector       prometheus.Collector
 	maxTuplesPerWriteField int
 	maxTypesPerModelField  int
+	storeStats             *sqlcommon.StoreStatsAggregator
 }
 
 // Ensures that Postgres implements the OpenFGADatastore interface.
 var _ storage.OpenFGADatastore = (*Postgres)(nil)
 
+// Ensures that Postgres implements the ConditionalTupleWriter interface.
+var _ storage.ConditionalTupleWriter = (*Postgres)(nil)
+
+// Ensures that Postgres implements the ChangelogFilterReader interface.
+var _ storage.ChangelogFilterReader = (*Postgres)(nil)
+
+// Ensures that Postgres implements the BatchUsersetTupleReader interface.
+var _ storage.BatchUsersetTupleReader = (*Postgres)(nil)
+
+// Ensures that Postgres implements the StoreStatsReader interface.
+var _ storage.StoreStatsReader = (*Postgres)(nil)
+
+// Ensures that Postgres implements the StoreTimeRangeReader interface.
+var _ storage.StoreTimeRangeReader = (*Postgres)(nil)
+
+func init() {
+	storage.Register("postgres", func(uri string, cfg *storage.DatastoreConfig) (storage.OpenFGADatastore, error) {
+		return New(uri, cfg)
+	})
+}
+
 // New creates a new [Postgres] storage.
 func New(uri string, cfg *sqlcommon.Config) (*Postgres, error) {
 	if cfg.Username != "" || cfg.Password != "" {
@@ -130,11 +152,13 @@ func New(uri string, cfg *sqlcommon.Config) (*Postgres, error) {
 		dbStatsCollector:       collector,
 		maxTuplesPerWriteField: cfg.MaxTuplesPerWriteField,
 		maxTypesPerModelField:  cfg.MaxTypesPerModelField,
+		storeStats:             sqlcommon.NewStoreStatsAggregator(dbInfo, cfg.StoreStatsRefreshInterval, cfg.Logger),
 	}, nil
 }
 
 // Close see [storage.OpenFGADatastore].Close.
 func (p *Postgres) Close() {
+	p.storeStats.Close()
 	if p.dbStatsCollector != nil {
 		prometheus.Unregister(p.dbStatsCollector)
 	}
@@ -202,6 +226,7 @@ func (p *Postgres) read(ctx context.Context, store string, tupleKey *openfgav1.T
 		sb = sb.Limit(uint64(opts.PageSize + 1)) // + 1 is used to determine whether to return a continuation token.
 	}
 
+	sqlcommon.AnnotateStatement(span, sb)
 	rows, err := sb.QueryContext(ctx)
 	if err != nil {
 		return nil, sqlcommon.HandleSQLError(err)
@@ -223,6 +248,25 @@ func (p *Postgres) Write(ctx context.Context, store string, deletes storage.Dele
 	return sqlcommon.Write(ctx, p.dbInfo, store, deletes, writes, now)
 }
 
+// WriteWithPreconditions see [storage.ConditionalTupleWriter].WriteWithPreconditions.
+func (p *Postgres) WriteWithPreconditions(
+	ctx context.Context,
+	store string,
+	preconditions storage.WritePreconditions,
+	deletes storage.Deletes,
+	writes storage.Writes,
+) error {
+	ctx, span := tracer.Start(ctx, "postgres.WriteWithPreconditions")
+	defer span.End()
+
+	if len(deletes)+len(writes) > p.MaxTuplesPerWrite() {
+		return storage.ErrExceededWriteBatchLimit
+	}
+
+	now := time.Now().UTC()
+	return sqlcommon.WriteWithPreconditions(ctx, p.dbInfo, store, preconditions, deletes, writes, now)
+}
+
 // ReadUserTuple see [storage.RelationshipTupleReader].ReadUserTuple.
 func (p *Postgres) ReadUserTuple(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
 	ctx, span := tracer.Start(ctx, "postgres.ReadUserTuple")
@@ -235,7 +279,7 @@ func (p *Postgres) ReadUserTuple(ctx context.Context, store string, tupleKey *op
 	var conditionContext []byte
 	var record storage.TupleRecord
 
-	err := p.stbl.
+	sb := p.stbl.
 		Select(
 			"object_type", "object_id", "relation", "_user",
 			"condition_name", "condition_context",
@@ -248,7 +292,10 @@ func (p *Postgres) ReadUserTuple(ctx context.Context, store string, tupleKey *op
 			"relation":    tupleKey.GetRelation(),
 			"_user":       tupleKey.GetUser(),
 			"user_type":   userType,
-		}).
+		})
+
+	sqlcommon.AnnotateStatement(span, sb)
+	err := sb.
 		QueryRowContext(ctx).
 		Scan(
 			&record.ObjectType,
@@ -313,6 +360,69 @@ func (p *Postgres) ReadUsersetTuples(ctx context.Context, store string, filter s
 		}
 		sb = sb.Where(orConditions)
 	}
+
+	sqlcommon.AnnotateStatement(span, sb)
+	rows, err := sb.QueryContext(ctx)
+	if err != nil {
+		return nil, sqlcommon.HandleSQLError(err)
+	}
+
+	return sqlcommon.NewSQLTupleIterator(rows), nil
+}
+
+// ReadUsersetTuplesBatch see [storage.BatchUsersetTupleReader].ReadUsersetTuplesBatch. The objects are matched
+// with one IN clause per object type.
+func (p *Postgres) ReadUsersetTuplesBatch(
+	ctx context.Context,
+	store string,
+	filter storage.ReadUsersetTuplesBatchFilter,
+) (storage.TupleIterator, error) {
+	ctx, span := tracer.Start(ctx, "postgres.ReadUsersetTuplesBatch")
+	defer span.End()
+
+	objectIDsByType := map[string][]string{}
+	var objectTypes []string
+	for _, object := range filter.Objects {
+		objectType, objectID := tupleUtils.SplitObject(object)
+		if _, ok := objectIDsByType[objectType]; !ok {
+			objectTypes = append(objectTypes, objectType)
+		}
+		objectIDsByType[objectType] = append(objectIDsByType[objectType], objectID)
+	}
+
+	objectConditions := sq.Or{}
+	for _, objectType := range objectTypes {
+		objectConditions = append(objectConditions, sq.Eq{
+			"object_type": objectType,
+			"object_id":   objectIDsByType[objectType],
+		})
+	}
+
+	sb := p.stbl.
+		Select(
+			"store", "object_type", "object_id", "relation", "_user",
+			"condition_name", "condition_context", "ulid", "inserted_at",
+		).
+		From("tuple").
+		Where(sq.Eq{"store": store}).
+		Where(sq.Eq{"user_type": tupleUtils.UserSet}).
+		Where(sq.Eq{"relation": filter.Relation}).
+		Where(objectConditions)
+
+	if len(filter.AllowedUserTypeRestrictions) > 0 {
+		orConditions := sq.Or{}
+		for _, userset := range filter.AllowedUserTypeRestrictions {
+			if _, ok := userset.GetRelationOrWildcard().(*openfgav1.RelationReference_Relation); ok {
+				orConditions = append(orConditions, sq.Like{"_user": userset.GetType() + ":%#" + userset.GetRelation()})
+			}
+			if _, ok := userset.GetRelationOrWildcard().(*openfgav1.RelationReference_Wildcard); ok {
+				orConditions = append(orConditions, sq.Eq{"_user": userset.GetType() + ":*"})
+			}
+		}
+		sb = sb.Where(orConditions)
+	}
+
+	sqlcommon.AnnotateStatement(span, sb)
 	rows, err := sb.QueryContext(ctx)
 	if err != nil {
 		return nil, sqlcommon.HandleSQLError(err)
@@ -335,7 +445,7 @@ func (p *Postgres) ReadStartingWithUser(ctx context.Context, store string, opts
 		targetUsersArg = append(targetUsersArg, targetUser)
 	}
 
-	rows, err := p.stbl.
+	sb := p.stbl.
 		Select(
 			"store", "object_type", "object_id", "relation", "_user",
 			"condition_name", "condition_context", "ulid", "inserted_at",
@@ -346,7 +456,10 @@ func (p *Postgres) ReadStartingWithUser(ctx context.Context, store string, opts
 			"object_type": opts.ObjectType,
 			"relation":    opts.Relation,
 			"_user":       targetUsersArg,
-		}).QueryContext(ctx)
+		})
+
+	sqlcommon.AnnotateStatement(span, sb)
+	rows, err := sb.QueryContext(ctx)
 	if err != nil {
 		return nil, sqlcommon.HandleSQLError(err)
 	}
@@ -664,6 +777,32 @@ func (p *Postgres) ReadChanges(
 	ctx, span := tracer.Start(ctx, "postgres.ReadChanges")
 	defer span.End()
 
+	return p.readChanges(ctx, store, storage.ReadChangesFilter{ObjectType: objectTypeFilter}, opts, horizonOffset)
+}
+
+// ReadFilteredChanges see [storage.ChangelogFilterReader].ReadFilteredChanges.
+func (p *Postgres) ReadFilteredChanges(
+	ctx context.Context,
+	store string,
+	filter storage.ReadChangesFilter,
+	opts storage.PaginationOptions,
+	horizonOffset time.Duration,
+) ([]*openfgav1.TupleChange, []byte, error) {
+	ctx, span := tracer.Start(ctx, "postgres.ReadFilteredChanges")
+	defer span.End()
+
+	return p.readChanges(ctx, store, filter, opts, horizonOffset)
+}
+
+func (p *Postgres) readChanges(
+	ctx context.Context,
+	store string,
+	filter storage.ReadChangesFilter,
+	opts storage.PaginationOptions,
+	horizonOffset time.Duration,
+) ([]*openfgav1.TupleChange, []byte, error) {
+	objectTypeFilter := filter.ObjectType
+
 	sb := p.stbl.
 		Select(
 			"ulid", "object_type", "object_id", "relation", "_user", "operation",
@@ -677,6 +816,7 @@ func (p *Postgres) ReadChanges(
 	if objectTypeFilter != "" {
 		sb = sb.Where(sq.Eq{"object_type": objectTypeFilter})
 	}
+	sb = sqlcommon.FilterChanges(sb, filter)
 	if opts.From != "" {
 		token, err := sqlcommon.UnmarshallContToken(opts.From)
 		if err != nil {
@@ -758,6 +898,23 @@ func (p *Postgres) ReadChanges(
 	return changes, contToken, nil
 }
 
+// StoreTimeRange see [storage.StoreTimeRangeReader].StoreTimeRange.
+func (p *Postgres) StoreTimeRange(ctx context.Context, store string) (*storage.StoreTimeRange, error) {
+	ctx, span := tracer.Start(ctx, "postgres.StoreTimeRange")
+	defer span.End()
+
+	return sqlcommon.StoreTimeRange(ctx, p.dbInfo, store)
+}
+
+// ReadStoreStats see [storage.StoreStatsReader].ReadStoreStats. The statistics are computed in the background,
+// see [sqlcommon.StoreStatsAggregator].
+func (p *Postgres) ReadStoreStats(ctx context.Context, store string) (*storage.StoreStats, error) {
+	ctx, span := tracer.Start(ctx, "postgres.ReadStoreStats")
+	defer span.End()
+
+	return p.storeStats.ReadStoreStats(ctx, store)
+}
+
 // IsReady see [sqlcommon.IsReady].
 func (p *Postgres) IsReady(ctx context.Context) (storage.ReadinessStatus, error) {
 	return sqlcommon.IsReady(ctx, p.db)
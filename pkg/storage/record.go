@@ -22,6 +22,15 @@ type TupleRecord struct {
 	ConditionContext *structpb.Struct
 	Ulid             string
 	InsertedAt       time.Time
+	// WrittenBy is the actor that wrote the tuple, if known. See [ContextWithWriteActor].
+	WrittenBy string
+	// ExpiresAt is the time the tuple expires at, zero if it never expires. See [ContextWithTupleExpiry].
+	ExpiresAt time.Time
+}
+
+// IsExpired reports whether the tuple expired at or before now.
+func (t *TupleRecord) IsExpired(now time.Time) bool {
+	return !t.ExpiresAt.IsZero() && !t.ExpiresAt.After(now)
 }
 
 // AsTuple converts a [TupleRecord] into a [*openfgav1.Tuple].
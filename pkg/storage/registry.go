@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/openfga/openfga/pkg/logger"
+)
+
+// DatastoreConfig defines the configuration parameters shared by the datastore engines,
+// such as the credentials and the limits of the connection pool. Engines ignore the ones
+// that do not apply to them.
+type DatastoreConfig struct {
+	Username               string
+	Password               string
+	Logger                 logger.Logger
+	MaxTuplesPerWriteField int
+	MaxTypesPerModelField  int
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxIdleTime time.Duration
+	ConnMaxLifetime time.Duration
+
+	ExportMetrics bool
+
+	// StoreStatsRefreshInterval is the age after which the statistics of a store are recomputed in the
+	// background by the SQL engines. See sqlcommon.StoreStatsAggregator.
+	StoreStatsRefreshInterval time.Duration
+
+	// MemorySnapshotFile is the file the data of the memory engine is persisted to, if set, and
+	// MemorySnapshotInterval the interval it is saved at. See memory.NewPersistent.
+	MemorySnapshotFile     string
+	MemorySnapshotInterval time.Duration
+}
+
+// DatastoreFactory opens a datastore of a given engine. The uri is the one the server is configured
+// with, and its format is specific to the engine. The returned datastore must implement every part of
+// [OpenFGADatastore]: reading and writing relationship tuples, the changelog, authorization models,
+// stores and assertions.
+type DatastoreFactory func(uri string, cfg *DatastoreConfig) (OpenFGADatastore, error)
+
+var (
+	datastoreFactoriesMu sync.RWMutex
+	datastoreFactories   = map[string]DatastoreFactory{}
+)
+
+// Register makes a datastore engine available under the given name, so that it can be selected
+// with the datastore engine setting of the server. It is meant to be called from the init function
+// of the package implementing the engine, which then only needs to be imported to be compiled in.
+// If Register is called twice with the same name or if factory is nil, it panics.
+func Register(engine string, factory DatastoreFactory) {
+	datastoreFactoriesMu.Lock()
+	defer datastoreFactoriesMu.Unlock()
+
+	if factory == nil {
+		panic("storage: Register factory is nil")
+	}
+
+	if _, dup := datastoreFactories[engine]; dup {
+		panic("storage: Register called twice for engine " + engine)
+	}
+
+	datastoreFactories[engine] = factory
+}
+
+// Engines returns the sorted names of the registered datastore engines.
+func Engines() []string {
+	datastoreFactoriesMu.RLock()
+	defer datastoreFactoriesMu.RUnlock()
+
+	engines := make([]string, 0, len(datastoreFactories))
+	for engine := range datastoreFactories {
+		engines = append(engines, engine)
+	}
+	sort.Strings(engines)
+
+	return engines
+}
+
+// OpenDatastore opens a datastore with the factory registered for the given engine.
+func OpenDatastore(engine, uri string, cfg *DatastoreConfig) (OpenFGADatastore, error) {
+	datastoreFactoriesMu.RLock()
+	factory, ok := datastoreFactories[engine]
+	datastoreFactoriesMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("storage engine '%s' is unsupported", engine)
+	}
+
+	datastore, err := factory(uri, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("initialize %s datastore: %w", engine, err)
+	}
+
+	return datastore, nil
+}
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegister(t *testing.T) {
+	t.Cleanup(func() {
+		datastoreFactoriesMu.Lock()
+		defer datastoreFactoriesMu.Unlock()
+		delete(datastoreFactories, "test-engine")
+		delete(datastoreFactories, "failing-engine")
+	})
+
+	var gotURI string
+	var gotCfg *DatastoreConfig
+	Register("test-engine", func(uri string, cfg *DatastoreConfig) (OpenFGADatastore, error) {
+		gotURI, gotCfg = uri, cfg
+		return nil, nil
+	})
+	Register("failing-engine", func(string, *DatastoreConfig) (OpenFGADatastore, error) {
+		return nil, errors.New("connection refused")
+	})
+
+	require.Subset(t, Engines(), []string{"failing-engine", "test-engine"})
+
+	t.Run("opens_with_the_registered_factory", func(t *testing.T) {
+		cfg := &DatastoreConfig{Username: "openfga"}
+		_, err := OpenDatastore("test-engine", "test://localhost", cfg)
+		require.NoError(t, err)
+		require.Equal(t, "test://localhost", gotURI)
+		require.Same(t, cfg, gotCfg)
+	})
+
+	t.Run("factory_error_names_the_engine", func(t *testing.T) {
+		_, err := OpenDatastore("failing-engine", "", &DatastoreConfig{})
+		require.EqualError(t, err, "initialize failing-engine datastore: connection refused")
+	})
+
+	t.Run("unregistered_engine", func(t *testing.T) {
+		_, err := OpenDatastore("undefined", "", &DatastoreConfig{})
+		require.EqualError(t, err, "storage engine 'undefined' is unsupported")
+	})
+
+	t.Run("duplicate_registration_panics", func(t *testing.T) {
+		require.Panics(t, func() {
+			Register("test-engine", func(string, *DatastoreConfig) (OpenFGADatastore, error) {
+				return nil, nil
+			})
+		})
+	})
+
+	t.Run("nil_factory_panics", func(t *testing.T) {
+		require.Panics(t, func() {
+			Register("nil-engine", nil)
+		})
+	})
+}
@@ -14,6 +14,8 @@ import (
 	"github.com/oklog/ulid/v2"
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	"github.com/pressly/goose/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/structpb"
 
@@ -25,20 +27,7 @@ import (
 
 // Config defines the configuration parameters
 // for setting up and managing a sql connection.
-type Config struct {
-	Username               string
-	Password               string
-	Logger                 logger.Logger
-	MaxTuplesPerWriteField int
-	MaxTypesPerModelField  int
-
-	MaxOpenConns    int
-	MaxIdleConns    int
-	ConnMaxIdleTime time.Duration
-	ConnMaxLifetime time.Duration
-
-	ExportMetrics bool
-}
+type Config = storage.DatastoreConfig
 
 // DatastoreOption defines a function type
 // used for configuring a Config object.
@@ -113,6 +102,14 @@ func WithConnMaxLifetime(d time.Duration) DatastoreOption {
 	}
 }
 
+// WithStoreStatsRefreshInterval returns a DatastoreOption that sets
+// the age after which the statistics of a store are recomputed in the Config.
+func WithStoreStatsRefreshInterval(d time.Duration) DatastoreOption {
+	return func(cfg *Config) {
+		cfg.StoreStatsRefreshInterval = d
+	}
+}
+
 // WithMetrics returns a DatastoreOption that
 // enables the export of metrics in the Config.
 func WithMetrics() DatastoreOption {
@@ -284,6 +281,53 @@ func (t *SQLTupleIterator) Stop() {
 	t.rows.Close()
 }
 
+// FilterChanges adds the relation, user and time range conditions of the filter to a query of the changelog.
+// The time range is turned into a range of ULIDs, whose prefix is the time of the change in milliseconds, so
+// that it is served by the primary key of the changelog.
+func FilterChanges(sb sq.SelectBuilder, filter storage.ReadChangesFilter) sq.SelectBuilder {
+	if filter.Relation != "" {
+		sb = sb.Where(sq.Eq{"relation": filter.Relation})
+	}
+
+	if filter.User != "" {
+		sb = sb.Where(sq.Eq{"_user": filter.User})
+	}
+
+	if !filter.StartTime.IsZero() {
+		sb = sb.Where(sq.GtOrEq{"ulid": timeULID(filter.StartTime)})
+	}
+
+	if !filter.EndTime.IsZero() {
+		sb = sb.Where(sq.Lt{"ulid": timeULID(filter.EndTime)})
+	}
+
+	return sb
+}
+
+// timeULID returns the smallest ULID of the millisecond of t.
+func timeULID(t time.Time) string {
+	var id ulid.ULID
+	_ = id.SetTime(ulid.Timestamp(t))
+
+	return id.String()
+}
+
+// AnnotateStatement records the SQL statement of the query on the span, with placeholders in place of its
+// arguments, so that the queries of a trace can be told apart. The arguments, which hold the IDs of the
+// objects and the users, are not recorded.
+func AnnotateStatement(span trace.Span, query sq.Sqlizer) {
+	if !span.IsRecording() {
+		return
+	}
+
+	statement, _, err := query.ToSql()
+	if err != nil {
+		return
+	}
+
+	span.SetAttributes(attribute.String("db.statement", statement))
+}
+
 // HandleSQLError processes an SQL error and converts it into a more
 // specific error type based on the nature of the SQL error.
 func HandleSQLError(err error, args ...interface{}) error {
@@ -334,6 +378,21 @@ func Write(
 	deletes storage.Deletes,
 	writes storage.Writes,
 	now time.Time,
+) error {
+	return WriteWithPreconditions(ctx, dbInfo, store, storage.WritePreconditions{}, deletes, writes, now)
+}
+
+// WriteWithPreconditions provides the common method for conditional writes across sql storage, see
+// [storage.ConditionalTupleWriter]. The preconditions are checked in the transaction of the write with
+// locking reads, so the tuples that must exist cannot be deleted before the transaction commits.
+func WriteWithPreconditions(
+	ctx context.Context,
+	dbInfo *DBInfo,
+	store string,
+	preconditions storage.WritePreconditions,
+	deletes storage.Deletes,
+	writes storage.Writes,
+	now time.Time,
 ) error {
 	txn, err := dbInfo.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -343,6 +402,28 @@ func Write(
 		_ = txn.Rollback()
 	}()
 
+	for _, tk := range preconditions.MustExist {
+		exists, err := tupleExistsForUpdate(ctx, dbInfo, txn, store, tk)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			return storage.WritePreconditionFailedError(tk, true)
+		}
+	}
+
+	for _, tk := range preconditions.MustNotExist {
+		exists, err := tupleExistsForUpdate(ctx, dbInfo, txn, store, tk)
+		if err != nil {
+			return err
+		}
+
+		if exists {
+			return storage.WritePreconditionFailedError(tk, false)
+		}
+	}
+
 	changelogBuilder := dbInfo.stbl.
 		Insert("changelog").
 		Columns(
@@ -455,6 +536,43 @@ func Write(
 	return nil
 }
 
+// tupleExistsForUpdate reports whether the tuple is in the store, regardless of its condition, and locks
+// it until the end of the transaction if it is.
+func tupleExistsForUpdate(
+	ctx context.Context,
+	dbInfo *DBInfo,
+	txn *sql.Tx,
+	store string,
+	tk *openfgav1.TupleKeyWithoutCondition,
+) (bool, error) {
+	objectType, objectID := tupleUtils.SplitObject(tk.GetObject())
+
+	var exists int
+	err := dbInfo.stbl.
+		Select("1").
+		From("tuple").
+		Where(sq.Eq{
+			"store":       store,
+			"object_type": objectType,
+			"object_id":   objectID,
+			"relation":    tk.GetRelation(),
+			"_user":       tk.GetUser(),
+			"user_type":   tupleUtils.GetUserTypeFromUser(tk.GetUser()),
+		}).
+		Suffix("FOR UPDATE").
+		RunWith(txn). // Part of a txn.
+		QueryRowContext(ctx).
+		Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, HandleSQLError(err, tk)
+	}
+
+	return true, nil
+}
+
 // WriteAuthorizationModel writes an authorization model for the given store.
 func WriteAuthorizationModel(
 	ctx context.Context,
@@ -573,6 +691,43 @@ func ReadAuthorizationModel(
 	return constructAuthorizationModelFromSQLRows(rows)
 }
 
+// StoreTimeRange returns the earliest and the latest tuple writes recorded in the changelog of the store.
+func StoreTimeRange(
+	ctx context.Context,
+	dbInfo *DBInfo,
+	store string,
+) (*storage.StoreTimeRange, error) {
+	var res storage.StoreTimeRange
+	for _, q := range []struct {
+		order string
+		ulid  *string
+		time  *time.Time
+	}{
+		{order: "ulid asc", ulid: &res.EarliestWriteUlid, time: &res.EarliestWriteTime},
+		{order: "ulid desc", ulid: &res.LatestWriteUlid, time: &res.LatestWriteTime},
+	} {
+		err := dbInfo.stbl.
+			Select("ulid", "inserted_at").
+			From("changelog").
+			Where(sq.Eq{
+				"store":     store,
+				"operation": openfgav1.TupleOperation_TUPLE_OPERATION_WRITE,
+			}).
+			OrderBy(q.order).
+			Limit(1).
+			QueryRowContext(ctx).
+			Scan(q.ulid, q.time)
+		if err != nil {
+			return nil, HandleSQLError(err)
+		}
+	}
+
+	res.EarliestWriteTime = res.EarliestWriteTime.UTC()
+	res.LatestWriteTime = res.LatestWriteTime.UTC()
+
+	return &res, nil
+}
+
 // IsReady returns true if the connection to the datastore is successful
 // and the datastore has the latest migration applied.
 func IsReady(ctx context.Context, db *sql.DB) (storage.ReadinessStatus, error) {
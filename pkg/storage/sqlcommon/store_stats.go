@@ -0,0 +1,178 @@
+package sqlcommon
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+
+	"github.com/openfga/openfga/pkg/logger"
+	"github.com/openfga/openfga/pkg/storage"
+)
+
+const defaultStoreStatsRefreshInterval = time.Minute
+
+// StoreStatsAggregator computes the statistics of the stores with aggregate queries over the tuple and the
+// changelog tables. Because the queries scan all the tuples of a store, the statistics are cached and recomputed
+// in the background: the first read of the statistics of a store computes them, and the reads made once they
+// are older than the refresh interval return them while they are being recomputed. It is safe for
+// concurrent use.
+type StoreStatsAggregator struct {
+	compute         func(ctx context.Context, store string) (*storage.StoreStats, error)
+	refreshInterval time.Duration
+	logger          logger.Logger
+
+	// ctx is canceled by Close, to stop the refreshes
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu         sync.Mutex
+	stats      map[string]*storage.StoreStats // by store
+	refreshing map[string]struct{}
+}
+
+// NewStoreStatsAggregator returns an aggregator of the statistics of the stores of the database. The refresh
+// interval defaults to 1 minute.
+func NewStoreStatsAggregator(dbInfo *DBInfo, refreshInterval time.Duration, logger logger.Logger) *StoreStatsAggregator {
+	return newStoreStatsAggregator(func(ctx context.Context, store string) (*storage.StoreStats, error) {
+		return ComputeStoreStats(ctx, dbInfo, store)
+	}, refreshInterval, logger)
+}
+
+func newStoreStatsAggregator(
+	compute func(ctx context.Context, store string) (*storage.StoreStats, error),
+	refreshInterval time.Duration,
+	logger logger.Logger,
+) *StoreStatsAggregator {
+	if refreshInterval <= 0 {
+		refreshInterval = defaultStoreStatsRefreshInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &StoreStatsAggregator{
+		compute:         compute,
+		refreshInterval: refreshInterval,
+		logger:          logger,
+		ctx:             ctx,
+		cancel:          cancel,
+		stats:           map[string]*storage.StoreStats{},
+		refreshing:      map[string]struct{}{},
+	}
+}
+
+// ReadStoreStats see [storage.StoreStatsReader].ReadStoreStats. The returned statistics are shared and must
+// not be modified.
+func (a *StoreStatsAggregator) ReadStoreStats(ctx context.Context, store string) (*storage.StoreStats, error) {
+	a.mu.Lock()
+	stats, ok := a.stats[store]
+	if ok && time.Since(stats.ComputedAt) >= a.refreshInterval {
+		a.refresh(store)
+	}
+	a.mu.Unlock()
+
+	if ok {
+		return stats, nil
+	}
+
+	stats, err := a.compute(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if cached, ok := a.stats[store]; !ok || cached.ComputedAt.Before(stats.ComputedAt) {
+		a.stats[store] = stats
+	}
+
+	return stats, nil
+}
+
+// refresh recomputes the statistics of the store in the background, unless they are already being
+// recomputed. It must be called with mu held.
+func (a *StoreStatsAggregator) refresh(store string) {
+	if _, ok := a.refreshing[store]; ok || a.ctx.Err() != nil {
+		return
+	}
+	a.refreshing[store] = struct{}{}
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+
+		stats, err := a.compute(a.ctx, store)
+
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		delete(a.refreshing, store)
+
+		if err != nil {
+			a.logger.Warn("failed to refresh the statistics of a store", zap.String("store_id", store), zap.Error(err))
+			return
+		}
+		a.stats[store] = stats
+	}()
+}
+
+// Close stops the refreshes and waits for the ones in progress.
+func (a *StoreStatsAggregator) Close() {
+	a.cancel()
+	a.wg.Wait()
+}
+
+// ComputeStoreStats counts the tuples of the store by object type and relation, and its changes of the last
+// hour and day.
+func ComputeStoreStats(ctx context.Context, dbInfo *DBInfo, store string) (*storage.StoreStats, error) {
+	stats := &storage.StoreStats{ComputedAt: time.Now()}
+
+	rows, err := dbInfo.stbl.
+		Select("object_type", "relation", "COUNT(*)").
+		From("tuple").
+		Where(sq.Eq{"store": store}).
+		GroupBy("object_type", "relation").
+		QueryContext(ctx)
+	if err != nil {
+		return nil, HandleSQLError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			objectType, relation string
+			count                int
+		)
+		if err := rows.Scan(&objectType, &relation, &count); err != nil {
+			return nil, HandleSQLError(err)
+		}
+		stats.AddTuples(objectType, relation, count)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, HandleSQLError(err)
+	}
+
+	for _, q := range []struct {
+		since time.Time
+		count *int
+	}{
+		{since: stats.ComputedAt.Add(-time.Hour), count: &stats.HourlyChangeCount},
+		{since: stats.ComputedAt.Add(-24 * time.Hour), count: &stats.DailyChangeCount},
+	} {
+		// the ULIDs of the changes start with their time, so that the range is served by the primary key
+		err := dbInfo.stbl.
+			Select("COUNT(*)").
+			From("changelog").
+			Where(sq.Eq{"store": store}).
+			Where(sq.GtOrEq{"ulid": timeULID(q.since)}).
+			QueryRowContext(ctx).
+			Scan(q.count)
+		if err != nil {
+			return nil, HandleSQLError(err)
+		}
+	}
+
+	return stats, nil
+}
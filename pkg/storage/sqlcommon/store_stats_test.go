@@ -0,0 +1,62 @@
+package sqlcommon
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/openfga/openfga/pkg/logger"
+	"github.com/openfga/openfga/pkg/storage"
+)
+
+func TestStoreStatsAggregator(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	var computed atomic.Int32
+	a := newStoreStatsAggregator(func(ctx context.Context, store string) (*storage.StoreStats, error) {
+		if store == "broken" {
+			return nil, errors.New("boom")
+		}
+
+		stats := &storage.StoreStats{ComputedAt: time.Now()}
+		stats.AddTuples("document", "viewer", int(computed.Add(1)))
+		return stats, nil
+	}, 50*time.Millisecond, logger.NewNoopLogger())
+	defer a.Close()
+
+	// the first read computes the statistics
+	stats, err := a.ReadStoreStats(ctx, "store")
+	require.NoError(t, err)
+	require.Equal(t, 1, stats.TupleCount)
+	require.Equal(t, map[string]int{"document": 1}, stats.TypeTupleCounts)
+	require.Equal(t, map[string]int{"document#viewer": 1}, stats.RelationTupleCounts)
+
+	stats, err = a.ReadStoreStats(ctx, "store")
+	require.NoError(t, err)
+	require.Equal(t, 1, stats.TupleCount)
+	require.EqualValues(t, 1, computed.Load())
+
+	// the reads of stale statistics return them while they are recomputed
+	time.Sleep(50 * time.Millisecond)
+	stats, err = a.ReadStoreStats(ctx, "store")
+	require.NoError(t, err)
+	require.Equal(t, 1, stats.TupleCount)
+
+	require.Eventually(t, func() bool {
+		stats, err := a.ReadStoreStats(ctx, "store")
+		require.NoError(t, err)
+		return stats.TupleCount == 2
+	}, time.Second, 5*time.Millisecond)
+
+	_, err = a.ReadStoreStats(ctx, "broken")
+	require.EqualError(t, err, "boom")
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 type ctxKey string
@@ -31,6 +32,22 @@ const (
 	DefaultPageSize = 50
 
 	relationshipTupleReaderCtxKey ctxKey = "relationship-tuple-reader-context-key"
+	writeActorCtxKey              ctxKey = "write-actor-context-key"
+	tupleExpiryCtxKey             ctxKey = "tuple-expiry-context-key"
+	consistencyCtxKey             ctxKey = "consistency-context-key"
+)
+
+// ConsistencyPreference is how up to date the data read by a request must be.
+type ConsistencyPreference int
+
+const (
+	// ConsistencyMinimizeLatency, the default, lets the request be served from the caches and the read
+	// replicas, which may not reflect the latest writes yet.
+	ConsistencyMinimizeLatency ConsistencyPreference = iota
+
+	// ConsistencyHigher makes the request read from the primary datastore, bypassing the caches and the
+	// read replicas, so that it observes the writes that completed before it, at the cost of latency.
+	ConsistencyHigher
 )
 
 // ContextWithRelationshipTupleReader sets the provided [[RelationshipTupleReader]]
@@ -53,6 +70,45 @@ func RelationshipTupleReaderFromContext(ctx context.Context) (RelationshipTupleR
 	return reader, ok
 }
 
+// ContextWithWriteActor sets the actor performing the writes in the context. Datastores implementing
+// [ActorTupleReader] record it as the provenance of the tuples written with the context.
+func ContextWithWriteActor(parent context.Context, actor string) context.Context {
+	return context.WithValue(parent, writeActorCtxKey, actor)
+}
+
+// WriteActorFromContext extracts the actor performing the writes from the provided context (if any).
+// If no actor is in the context a boolean false is returned.
+func WriteActorFromContext(ctx context.Context) (string, bool) {
+	actor, ok := ctx.Value(writeActorCtxKey).(string)
+	return actor, ok
+}
+
+// ContextWithTupleExpiry sets the time the tuples written with the context expire at. Datastores implementing
+// [TupleExpirer] no longer read them from that time on, and delete them once they are reaped.
+func ContextWithTupleExpiry(parent context.Context, expiresAt time.Time) context.Context {
+	return context.WithValue(parent, tupleExpiryCtxKey, expiresAt)
+}
+
+// TupleExpiryFromContext extracts the time the tuples written with the provided context expire at (if any).
+// If no expiry is in the context a boolean false is returned.
+func TupleExpiryFromContext(ctx context.Context) (time.Time, bool) {
+	expiresAt, ok := ctx.Value(tupleExpiryCtxKey).(time.Time)
+	return expiresAt, ok
+}
+
+// ContextWithConsistency sets the consistency preference of the request in the context. The caches and the
+// wrappers reading from replicas honor it for the reads made with the context.
+func ContextWithConsistency(parent context.Context, preference ConsistencyPreference) context.Context {
+	return context.WithValue(parent, consistencyCtxKey, preference)
+}
+
+// ConsistencyFromContext extracts the consistency preference of the request from the provided context. If
+// none is in the context, ConsistencyMinimizeLatency is returned.
+func ConsistencyFromContext(ctx context.Context) ConsistencyPreference {
+	preference, _ := ctx.Value(consistencyCtxKey).(ConsistencyPreference)
+	return preference
+}
+
 // PaginationOptions should not be instantiated directly. Use NewPaginationOptions.
 type PaginationOptions struct {
 	PageSize int
@@ -229,6 +285,26 @@ type AssertionsBackend interface {
 	ReadAssertions(ctx context.Context, store, modelID string) ([]*openfgav1.Assertion, error)
 }
 
+// Assertion is an assertion along with the contextual tuples and the condition context its Check is made with.
+type Assertion struct {
+	TupleKey    *openfgav1.AssertionTupleKey
+	Expectation bool
+
+	ContextualTuples []*openfgav1.TupleKey
+	Context          *structpb.Struct
+}
+
+// ContextualAssertionsBackend is an optional interface implemented by datastores that can store the contextual
+// tuples and the condition context of the assertions.
+type ContextualAssertionsBackend interface {
+	// WriteContextualAssertions overwrites the assertions for a store and modelID, like WriteAssertions.
+	WriteContextualAssertions(ctx context.Context, store, modelID string, assertions []*Assertion) error
+
+	// ReadContextualAssertions returns the assertions for a store and modelID, including the ones written with
+	// WriteAssertions, which have no context. If no assertions were ever written, it must return an empty list.
+	ReadContextualAssertions(ctx context.Context, store, modelID string) ([]*Assertion, error)
+}
+
 // ChangelogBackend is an interface for interacting with and managing changelogs.
 type ChangelogBackend interface {
 	// ReadChanges returns the writes and deletes that have occurred for tuples within a store,
@@ -247,6 +323,259 @@ type ChangelogBackend interface {
 	) ([]*openfgav1.TupleChange, []byte, error)
 }
 
+// StoreTimeRange describes the earliest and the latest tuple writes of a store.
+type StoreTimeRange struct {
+	EarliestWriteUlid string
+	EarliestWriteTime time.Time
+	LatestWriteUlid   string
+	LatestWriteTime   time.Time
+}
+
+// StoreTimeRangeReader is an optional interface implemented by datastores that can read the earliest and the
+// latest tuple writes of a store.
+type StoreTimeRangeReader interface {
+	// StoreTimeRange returns the earliest and the latest tuple writes that have occurred within a store.
+	// If no tuples have ever been written to the store, it must return ErrNotFound.
+	StoreTimeRange(ctx context.Context, store string) (*StoreTimeRange, error)
+}
+
+// StoreFeatureFlagsBackend is an optional interface implemented by datastores that persist per-store feature flags.
+type StoreFeatureFlagsBackend interface {
+	// ReadStoreFeatureFlags returns the feature flags of a store, keyed by flag name.
+	// If no flags were ever written for the store, it must return an empty map.
+	ReadStoreFeatureFlags(ctx context.Context, store string) (map[string]bool, error)
+
+	// WriteStoreFeatureFlags sets the provided feature flags of a store. Flags that are not
+	// in the provided map keep their current value.
+	WriteStoreFeatureFlags(ctx context.Context, store string, flags map[string]bool) error
+}
+
+// AuthorizationModelArchiveBackend is an optional interface implemented by datastores that can archive
+// (soft-delete) authorization models. An archived model can still be read, and archiving a model does not
+// change which model is the latest one.
+type AuthorizationModelArchiveBackend interface {
+	// ArchiveAuthorizationModel marks the model of the store as archived.
+	// If the model is not found, it must return ErrNotFound.
+	ArchiveAuthorizationModel(ctx context.Context, store, id string) error
+
+	// IsAuthorizationModelArchived returns true if the model of the store was archived.
+	// If the model is not found, it must return ErrNotFound.
+	IsAuthorizationModelArchived(ctx context.Context, store, id string) (bool, error)
+}
+
+// StoreDefaultModelBackend is an optional interface implemented by datastores that can pin the authorization
+// model used by the requests of a store that do not specify one, instead of its latest model, e.g. to write a
+// new model ahead of time and make it the default later.
+type StoreDefaultModelBackend interface {
+	// ReadStoreDefaultModel returns the ID of the model pinned as the default of the store, or an empty string
+	// if none is pinned.
+	ReadStoreDefaultModel(ctx context.Context, store string) (string, error)
+
+	// WriteStoreDefaultModel pins the model as the default of the store, replacing the one pinned before. An
+	// empty id unpins it, so that the latest model is the default again. If the model is not found, it must
+	// return ErrNotFound.
+	WriteStoreDefaultModel(ctx context.Context, store, id string) error
+}
+
+// AuthorizationModelMetadata describes an authorization model stored in a datastore.
+type AuthorizationModelMetadata struct {
+	ID        string
+	CreatedAt time.Time
+	Labels    map[string]string
+}
+
+// AuthorizationModelMetadataBackend is an optional interface implemented by datastores that can label
+// authorization models and list their metadata, e.g. for a model version picker.
+type AuthorizationModelMetadataBackend interface {
+	// ListModels returns the metadata of the models of the store, ordered by creation time from the
+	// oldest to the newest. The continuation token is empty once the last page was returned.
+	ListModels(ctx context.Context, store string, paginationOptions PaginationOptions) ([]*AuthorizationModelMetadata, []byte, error)
+
+	// WriteAuthorizationModelLabels replaces the labels of the model of the store.
+	// If the model is not found, it must return ErrNotFound.
+	WriteAuthorizationModelLabels(ctx context.Context, store, id string, labels map[string]string) error
+}
+
+// BulkTupleDeleter is an optional interface implemented by datastores that can delete every tuple matching
+// a filter at once, e.g. to clean up the tuples of an object type removed from the model.
+type BulkTupleDeleter interface {
+	// DeleteTuples deletes the tuples of the store matching the filter, which may be partially filled like the
+	// tuple key of Read, and returns the number of deleted tuples. The deletions are recorded in the changelog.
+	// Whether concurrent reads may observe a partially applied delete depends on the datastore.
+	DeleteTuples(ctx context.Context, store string, filter *openfgav1.TupleKey) (int, error)
+
+	// PreviewDeleteTuples is a dry run of DeleteTuples: it returns the tuples of the store that DeleteTuples
+	// would delete with the filter, whose count is the number DeleteTuples would return, without deleting them.
+	PreviewDeleteTuples(ctx context.Context, store string, filter *openfgav1.TupleKey) ([]*openfgav1.Tuple, error)
+}
+
+// TupleSnapshotter is an optional interface implemented by datastores that can read the tuples of a store as
+// they are at a point in time, so that a request making several reads does not observe concurrent writes.
+type TupleSnapshotter interface {
+	// SnapshotTuples returns a reader of the tuples of the store as they are at the time of the call. The writes
+	// made after the call are not visible through it. The reader must only be used to read the given store.
+	SnapshotTuples(ctx context.Context, store string) (RelationshipTupleReader, error)
+}
+
+// WritePreconditions are the tuples that must, or must not, be in a store for a conditional write to be
+// applied. The tuples are identified by their object, relation and user, regardless of their conditions.
+type WritePreconditions struct {
+	// MustExist holds the tuples that must be in the store.
+	MustExist []*openfgav1.TupleKeyWithoutCondition
+	// MustNotExist holds the tuples that must not be in the store.
+	MustNotExist []*openfgav1.TupleKeyWithoutCondition
+}
+
+// ConditionalTupleWriter is an optional interface implemented by datastores that can apply a write only if some
+// tuples are, or are not, in the store, e.g. for a client syncing relationships from another system to detect
+// that the store changed since it last read it.
+type ConditionalTupleWriter interface {
+	// WriteWithPreconditions is like Write, but only applies the deletes and the writes if the preconditions hold.
+	// The preconditions are checked atomically with the write: the tuples that must exist cannot be deleted
+	// until the write is applied. If a precondition does not hold, nothing is written and an error wrapping
+	// ErrWritePreconditionFailed is returned.
+	WriteWithPreconditions(ctx context.Context, store string, preconditions WritePreconditions, d Deletes, w Writes) error
+}
+
+// FreshnessReporter is an optional interface implemented by datastores that can report how stale the
+// data they serve may be, e.g. datastores reading from eventually consistent replicas.
+type FreshnessReporter interface {
+	// Staleness returns the maximum age of the data read from the given store, such as the
+	// replication lag of the replica serving the reads. It returns 0 if the reads always
+	// observe the latest writes.
+	Staleness(ctx context.Context, store string) (time.Duration, error)
+}
+
+// ChangeWatcher is an optional interface implemented by datastores that can stream the changes of a
+// store as they are written.
+type ChangeWatcher interface {
+	// WatchChanges returns a channel receiving the changes written to the store after the call, in the
+	// order that they occurred. If objectType is not empty, only the changes for objects of that type
+	// are sent. The channel is closed once ctx is done.
+	WatchChanges(ctx context.Context, store, objectType string) (<-chan *openfgav1.TupleChange, error)
+}
+
+// ChangeCounter is an optional interface implemented by datastores that can count the recent changes of a
+// store without reading them, e.g. to detect stores receiving an unusual amount of writes.
+type ChangeCounter interface {
+	// CountChanges returns the number of writes and deletes of tuples that occurred in the store at or
+	// after since.
+	CountChanges(ctx context.Context, store string, since time.Time) (int, error)
+}
+
+// ReadChangesFilter filters the changes read by ReadFilteredChanges. The empty fields do not filter.
+type ReadChangesFilter struct {
+	ObjectType string
+	Relation   string
+	User       string
+
+	// StartTime and EndTime bound the time of the changes, the start inclusive and the end exclusive. The SQL
+	// datastores compare them at the precision of milliseconds.
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+// ChangelogFilterReader is an optional interface implemented by datastores that can filter the changes of a
+// store by relation, user and time range in the query, e.g. for the consumers syncing the changes of one
+// relation, rather than reading the whole changelog and discarding most of it.
+type ChangelogFilterReader interface {
+	// ReadFilteredChanges returns the changes of the store matching the filter, as ReadChanges does for
+	// its object type filter. Its continuation tokens are only valid for the same filter; if the object type
+	// of the filter and the one of the token don't match, it returns ErrMismatchObjectType.
+	ReadFilteredChanges(
+		ctx context.Context,
+		store string,
+		filter ReadChangesFilter,
+		paginationOptions PaginationOptions,
+		horizonOffset time.Duration,
+	) ([]*openfgav1.TupleChange, []byte, error)
+}
+
+// TupleCounter is an optional interface implemented by datastores that can count the tuples of a relation
+// without reading them, e.g. to estimate the cost of the alternative ways of resolving a query.
+type TupleCounter interface {
+	// CountTuples returns the number of tuples of the store whose object is of the object type and whose
+	// relation is the relation.
+	CountTuples(ctx context.Context, store, objectType, relation string) (int, error)
+}
+
+// StoreStats are the cardinality statistics of a store, e.g. for capacity planning.
+type StoreStats struct {
+	// TupleCount is the number of tuples of the store.
+	TupleCount int
+	// TypeTupleCounts are the numbers of tuples of the store by object type.
+	TypeTupleCounts map[string]int
+	// RelationTupleCounts are the numbers of tuples of the store by object type and relation, keyed by
+	// 'type#relation'.
+	RelationTupleCounts map[string]int
+
+	// HourlyChangeCount and DailyChangeCount are the numbers of writes and deletes of tuples recorded in the
+	// changelog of the store during the hour and the day before ComputedAt.
+	HourlyChangeCount int
+	DailyChangeCount  int
+
+	// ComputedAt is when the statistics were computed.
+	ComputedAt time.Time
+}
+
+// AddTuples adds count tuples of the object type and relation to the statistics.
+func (s *StoreStats) AddTuples(objectType, relation string, count int) {
+	if s.TypeTupleCounts == nil {
+		s.TypeTupleCounts = map[string]int{}
+	}
+	if s.RelationTupleCounts == nil {
+		s.RelationTupleCounts = map[string]int{}
+	}
+
+	s.TupleCount += count
+	s.TypeTupleCounts[objectType] += count
+	s.RelationTupleCounts[objectType+"#"+relation] += count
+}
+
+// StoreStatsReader is an optional interface implemented by datastores that can compute the cardinality statistics
+// of a store.
+type StoreStatsReader interface {
+	// ReadStoreStats returns the statistics of the store, which are empty if the store has no tuples. Datastores
+	// may compute them in the background and return the last ones computed, see StoreStats.ComputedAt.
+	ReadStoreStats(ctx context.Context, store string) (*StoreStats, error)
+}
+
+// ActorTupleReader is an optional interface implemented by datastores that record the actor of the writes,
+// see [ContextWithWriteActor], e.g. to audit the tuples written by a client. Only the memory datastore
+// implements it.
+type ActorTupleReader interface {
+	// ReadByActor returns the tuples of the store that were written by the actor. Tuples written
+	// without an actor are never returned.
+	ReadByActor(ctx context.Context, store, actor string) ([]*openfgav1.Tuple, error)
+}
+
+// TupleExpirer is an optional interface implemented by datastores that can write tuples expiring at a point in
+// time, see [ContextWithTupleExpiry], e.g. for temporary access grants. The expired tuples are not read anymore,
+// even before they are deleted.
+type TupleExpirer interface {
+	// DeleteExpiredTuples deletes the tuples of every store that expired at or before now, and returns the number
+	// of deleted tuples. The deletions are recorded in the changelog.
+	DeleteExpiredTuples(ctx context.Context, now time.Time) (int, error)
+}
+
+// ReadUsersetTuplesBatchFilter specifies the filter options that will be used to constrain the
+// [BatchUsersetTupleReader.ReadUsersetTuplesBatch] query. It is the filter of ReadUsersetTuples for several
+// objects at once.
+type ReadUsersetTuplesBatchFilter struct {
+	Objects                     []string                       // Required, each with a type and an id.
+	Relation                    string                         // Required.
+	AllowedUserTypeRestrictions []*openfgav1.RelationReference // Optional.
+}
+
+// BatchUsersetTupleReader is an optional interface implemented by datastores that can read the userset tuples
+// of several objects in one query, e.g. to resolve the usersets of the many objects of a wide group expansion
+// with one round trip rather than one per object.
+type BatchUsersetTupleReader interface {
+	// ReadUsersetTuplesBatch returns the userset tuples of the relation of any of the objects of the filter,
+	// as ReadUsersetTuples would for each of them. There is NO guarantee on the order returned on the iterator.
+	ReadUsersetTuplesBatch(ctx context.Context, store string, filter ReadUsersetTuplesBatchFilter) (TupleIterator, error)
+}
+
 // OpenFGADatastore is an interface that defines a set of methods for interacting
 // with and managing data in an OpenFGA (Fine-Grained Authorization) system.
 type OpenFGADatastore interface {
@@ -0,0 +1,244 @@
+package storagewrappers
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/openfga/openfga/internal/build"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/tuple"
+)
+
+const (
+	defaultUsersetBatchWindow  = time.Millisecond
+	defaultUsersetBatchMaxSize = 100
+)
+
+var _ storage.RelationshipTupleReader = (*BatchingUsersetTupleReader)(nil)
+
+var usersetBatchSizeHistogram = promauto.NewHistogram(prometheus.HistogramOpts{
+	Namespace:                       build.ProjectName,
+	Name:                            "datastore_userset_batch_size",
+	Help:                            "The number of objects whose userset tuples are read by one query of a BatchingUsersetTupleReader.",
+	Buckets:                         []float64{1, 2, 5, 10, 25, 50, 100, 250, 500},
+	NativeHistogramBucketFactor:     1.1,
+	NativeHistogramMaxBucketNumber:  100,
+	NativeHistogramMinResetDuration: time.Hour,
+})
+
+// BatchingUsersetTupleReader is a wrapper over a datastore that coalesces the ReadUsersetTuples calls made
+// within a short window, e.g. by the parallel branches of a wide group expansion, into one
+// ReadUsersetTuplesBatch query per relation and type restrictions. A read made while no other read of its
+// relation and type restrictions is pending or in flight is sent right away, so that a lone read does not wait
+// for the window. The other reads are not batched.
+//
+// The results of a batch are read in full before being split by object, and the batch query is not canceled
+// when the caller that started it gives up, so that it still serves the other callers of the batch. It must
+// only be used for the duration of one request. It is safe for concurrent use.
+type BatchingUsersetTupleReader struct {
+	storage.RelationshipTupleReader
+
+	batcher storage.BatchUsersetTupleReader
+
+	window  time.Duration
+	maxSize int
+
+	mu       sync.Mutex
+	pending  map[string]*usersetBatch // by store, relation and type restrictions
+	inFlight map[string]int           // the number of reads and batches being read, by the key of pending
+}
+
+// usersetBatch is a ReadUsersetTuplesBatch query waiting for its window to end or for its maximum size.
+type usersetBatch struct {
+	store  string
+	filter storage.ReadUsersetTuplesBatchFilter
+	ctx    context.Context
+	timer  *time.Timer
+
+	done   chan struct{}
+	tuples map[string][]*openfgav1.Tuple // by object, set once done is closed
+	err    error
+}
+
+// UsersetBatchingOption configures a BatchingUsersetTupleReader.
+type UsersetBatchingOption func(*BatchingUsersetTupleReader)
+
+// WithUsersetBatchWindow sets how long the first read of a batch waits for other reads to join it. It defaults
+// to 1ms.
+func WithUsersetBatchWindow(window time.Duration) UsersetBatchingOption {
+	return func(b *BatchingUsersetTupleReader) {
+		b.window = window
+	}
+}
+
+// WithUsersetBatchMaxSize sets the maximum number of objects of a batch, which bounds the size of the IN clause
+// of its query. A batch reaching it is read without waiting for the end of its window. It defaults to 100.
+func WithUsersetBatchMaxSize(size int) UsersetBatchingOption {
+	return func(b *BatchingUsersetTupleReader) {
+		b.maxSize = size
+	}
+}
+
+// NewBatchingUsersetTupleReader returns a wrapper over the wrapped datastore that reads the userset tuples
+// with the batcher, which must read the same tuples as the wrapped datastore.
+func NewBatchingUsersetTupleReader(
+	wrapped storage.RelationshipTupleReader,
+	batcher storage.BatchUsersetTupleReader,
+	opts ...UsersetBatchingOption,
+) *BatchingUsersetTupleReader {
+	b := &BatchingUsersetTupleReader{
+		RelationshipTupleReader: wrapped,
+		batcher:                 batcher,
+		window:                  defaultUsersetBatchWindow,
+		maxSize:                 defaultUsersetBatchMaxSize,
+		pending:                 map[string]*usersetBatch{},
+		inFlight:                map[string]int{},
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	return b
+}
+
+// ReadUsersetTuples see [storage.RelationshipTupleReader].ReadUsersetTuples.
+func (b *BatchingUsersetTupleReader) ReadUsersetTuples(
+	ctx context.Context,
+	store string,
+	filter storage.ReadUsersetTuplesFilter,
+) (storage.TupleIterator, error) {
+	// the reads of all the objects of a type cannot be batched
+	if _, objectID := tuple.SplitObject(filter.Object); objectID == "" || filter.Relation == "" {
+		return b.RelationshipTupleReader.ReadUsersetTuples(ctx, store, filter)
+	}
+
+	key := usersetBatchKey(store, filter)
+
+	b.mu.Lock()
+	batch, ok := b.pending[key]
+	if !ok && b.inFlight[key] == 0 {
+		b.inFlight[key]++
+		b.mu.Unlock()
+
+		defer b.done(key)
+		return b.RelationshipTupleReader.ReadUsersetTuples(ctx, store, filter)
+	}
+	if !ok {
+		batch = &usersetBatch{
+			store: store,
+			filter: storage.ReadUsersetTuplesBatchFilter{
+				Relation:                    filter.Relation,
+				AllowedUserTypeRestrictions: filter.AllowedUserTypeRestrictions,
+			},
+			ctx:  context.WithoutCancel(ctx),
+			done: make(chan struct{}),
+		}
+		b.pending[key] = batch
+		batch.timer = time.AfterFunc(b.window, func() {
+			b.flush(key, batch)
+		})
+	}
+	if !slices.Contains(batch.filter.Objects, filter.Object) {
+		batch.filter.Objects = append(batch.filter.Objects, filter.Object)
+	}
+	full := len(batch.filter.Objects) >= b.maxSize
+	b.mu.Unlock()
+
+	if full {
+		b.flush(key, batch)
+	}
+
+	select {
+	case <-batch.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if batch.err != nil {
+		return nil, batch.err
+	}
+
+	return storage.NewStaticTupleIterator(batch.tuples[filter.Object]), nil
+}
+
+// flush reads the batch, unless another caller already did.
+func (b *BatchingUsersetTupleReader) flush(key string, batch *usersetBatch) {
+	b.mu.Lock()
+	if b.pending[key] != batch {
+		b.mu.Unlock()
+		return
+	}
+	delete(b.pending, key)
+	batch.timer.Stop()
+	b.inFlight[key]++
+	b.mu.Unlock()
+
+	defer close(batch.done)
+	defer b.done(key)
+
+	usersetBatchSizeHistogram.Observe(float64(len(batch.filter.Objects)))
+
+	// the batched reads are traced like the other reads of a request, see TracingDatastore
+	ctx, span := startReadSpan(batch.ctx, "ReadUsersetTuplesBatch", batch.store, tuple.GetType(batch.filter.Objects[0]), batch.filter.Relation)
+
+	iter, err := b.batcher.ReadUsersetTuplesBatch(ctx, batch.store, batch.filter)
+	iter, err = traceIterator(span, iter, err)
+	if err != nil {
+		batch.err = err
+		return
+	}
+	defer iter.Stop()
+
+	batch.tuples = make(map[string][]*openfgav1.Tuple, len(batch.filter.Objects))
+	for {
+		t, err := iter.Next(ctx)
+		if err != nil {
+			if !errors.Is(err, storage.ErrIteratorDone) {
+				batch.err = err
+			}
+			return
+		}
+
+		object := t.GetKey().GetObject()
+		batch.tuples[object] = append(batch.tuples[object], t)
+	}
+}
+
+// done records that a read or a batch of the key was read.
+func (b *BatchingUsersetTupleReader) done(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.inFlight[key]--
+	if b.inFlight[key] == 0 {
+		delete(b.inFlight, key)
+	}
+}
+
+func usersetBatchKey(store string, filter storage.ReadUsersetTuplesFilter) string {
+	var sb strings.Builder
+	sb.WriteString(store)
+	sb.WriteString("/")
+	sb.WriteString(filter.Relation)
+	for _, restriction := range filter.AllowedUserTypeRestrictions {
+		sb.WriteString("/")
+		sb.WriteString(restriction.GetType())
+		if restriction.GetWildcard() != nil {
+			sb.WriteString(":*")
+		} else {
+			sb.WriteString("#")
+			sb.WriteString(restriction.GetRelation())
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,252 @@
+package storagewrappers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+)
+
+// recordingBatcher records the filters of the batched reads.
+type recordingBatcher struct {
+	storage.BatchUsersetTupleReader
+
+	mu      sync.Mutex
+	filters []storage.ReadUsersetTuplesBatchFilter
+	err     error
+}
+
+func (r *recordingBatcher) ReadUsersetTuplesBatch(ctx context.Context, store string, filter storage.ReadUsersetTuplesBatchFilter) (storage.TupleIterator, error) {
+	r.mu.Lock()
+	r.filters = append(r.filters, filter)
+	r.mu.Unlock()
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return r.BatchUsersetTupleReader.ReadUsersetTuplesBatch(ctx, store, filter)
+}
+
+// gatedReader blocks the userset reads of the viewer relation until release is closed, signaling on entered
+// every time one starts.
+type gatedReader struct {
+	storage.RelationshipTupleReader
+
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (g *gatedReader) ReadUsersetTuples(ctx context.Context, store string, filter storage.ReadUsersetTuplesFilter) (storage.TupleIterator, error) {
+	if filter.Relation == "viewer" {
+		g.entered <- struct{}{}
+		<-g.release
+	}
+	return g.RelationshipTupleReader.ReadUsersetTuples(ctx, store, filter)
+}
+
+func TestBatchingUsersetTupleReader(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+	store := ulid.Make().String()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+	batchReader := ds.(storage.BatchUsersetTupleReader)
+
+	var tks []*openfgav1.TupleKey
+	for i := 0; i < 10; i++ {
+		tks = append(tks, tuple.NewTupleKey(fmt.Sprintf("folder:%d", i), "viewer", fmt.Sprintf("group:%d#member", i)))
+	}
+	tks = append(tks,
+		tuple.NewTupleKey("folder:0", "viewer", "group:eng#member"),
+		tuple.NewTupleKey("folder:0", "viewer", "user:anne"),
+		tuple.NewTupleKey("folder:0", "editor", "group:fga#member"),
+	)
+	require.NoError(t, ds.Write(ctx, store, nil, tks))
+
+	filterOf := func(object, relation string) storage.ReadUsersetTuplesFilter {
+		return storage.ReadUsersetTuplesFilter{
+			Object:   object,
+			Relation: relation,
+			AllowedUserTypeRestrictions: []*openfgav1.RelationReference{
+				typesystem.DirectRelationReference("group", "member"),
+			},
+		}
+	}
+
+	readAll := func(t *testing.T, iter storage.TupleIterator, err error) []string {
+		require.NoError(t, err)
+		defer iter.Stop()
+
+		var users []string
+		for {
+			tk, err := iter.Next(ctx)
+			if errors.Is(err, storage.ErrIteratorDone) {
+				return users
+			}
+			require.NoError(t, err)
+			users = append(users, tk.GetKey().GetUser())
+		}
+	}
+
+	// readConcurrently reads the userset tuples of the filters at once, returning the users by object
+	readConcurrently := func(t *testing.T, reader storage.RelationshipTupleReader, filters ...storage.ReadUsersetTuplesFilter) map[string][]string {
+		var (
+			wg    sync.WaitGroup
+			mu    sync.Mutex
+			users = map[string][]string{}
+		)
+		for _, filter := range filters {
+			wg.Add(1)
+			go func(filter storage.ReadUsersetTuplesFilter) {
+				defer wg.Done()
+
+				iter, err := reader.ReadUsersetTuples(ctx, store, filter)
+				got := readAll(t, iter, err)
+
+				mu.Lock()
+				defer mu.Unlock()
+				users[filter.Object+"#"+filter.Relation] = got
+			}(filter)
+		}
+		wg.Wait()
+
+		return users
+	}
+
+	// newReader returns a reader batching with the batcher, and a function starting a viewer read that is sent
+	// right away and blocks until the returned function is called, so that the viewer reads made in the meantime
+	// are batched
+	newReader := func(t *testing.T, batcher storage.BatchUsersetTupleReader, opts ...UsersetBatchingOption) (*BatchingUsersetTupleReader, func(filter storage.ReadUsersetTuplesFilter) func()) {
+		gate := &gatedReader{RelationshipTupleReader: ds, entered: make(chan struct{}), release: make(chan struct{})}
+		reader := NewBatchingUsersetTupleReader(gate, batcher, opts...)
+
+		return reader, func(filter storage.ReadUsersetTuplesFilter) func() {
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				iter, err := reader.ReadUsersetTuples(ctx, store, filter)
+				readAll(t, iter, err)
+			}()
+			<-gate.entered
+
+			return func() {
+				close(gate.release)
+				<-done
+			}
+		}
+	}
+
+	t.Run("sends_a_lone_read_right_away", func(t *testing.T) {
+		batcher := &recordingBatcher{BatchUsersetTupleReader: batchReader}
+		reader := NewBatchingUsersetTupleReader(ds, batcher, WithUsersetBatchWindow(time.Hour))
+
+		iter, err := reader.ReadUsersetTuples(ctx, store, filterOf("folder:2", "viewer"))
+		require.Equal(t, []string{"group:2#member"}, readAll(t, iter, err))
+		require.Empty(t, batcher.filters)
+	})
+
+	t.Run("coalesces_the_reads_of_a_window", func(t *testing.T) {
+		batcher := &recordingBatcher{BatchUsersetTupleReader: batchReader}
+		reader, holdRead := newReader(t, batcher, WithUsersetBatchWindow(50*time.Millisecond))
+
+		release := holdRead(filterOf("folder:9", "viewer"))
+		defer release()
+
+		var filters []storage.ReadUsersetTuplesFilter
+		for i := 0; i < 9; i++ {
+			filters = append(filters, filterOf(fmt.Sprintf("folder:%d", i), "viewer"))
+		}
+		users := readConcurrently(t, reader, filters...)
+
+		require.Len(t, batcher.filters, 1)
+		require.Len(t, batcher.filters[0].Objects, 9)
+		require.ElementsMatch(t, []string{"group:0#member", "group:eng#member"}, users["folder:0#viewer"])
+		for i := 1; i < 9; i++ {
+			require.Equal(t, []string{fmt.Sprintf("group:%d#member", i)}, users[fmt.Sprintf("folder:%d#viewer", i)])
+		}
+	})
+
+	t.Run("one_batch_per_relation", func(t *testing.T) {
+		batcher := &recordingBatcher{BatchUsersetTupleReader: batchReader}
+		reader, holdRead := newReader(t, batcher, WithUsersetBatchWindow(50*time.Millisecond))
+
+		release := holdRead(filterOf("folder:9", "viewer"))
+		defer release()
+
+		// the editor read is sent right away, since no other editor read is in flight
+		users := readConcurrently(t, reader, filterOf("folder:0", "viewer"), filterOf("folder:0", "editor"), filterOf("folder:1", "viewer"))
+
+		require.Len(t, batcher.filters, 1)
+		require.Equal(t, "viewer", batcher.filters[0].Relation)
+		require.Equal(t, []string{"group:fga#member"}, users["folder:0#editor"])
+		require.Equal(t, []string{"group:1#member"}, users["folder:1#viewer"])
+	})
+
+	t.Run("reads_a_full_batch_without_waiting", func(t *testing.T) {
+		batcher := &recordingBatcher{BatchUsersetTupleReader: batchReader}
+		reader, holdRead := newReader(t, batcher, WithUsersetBatchWindow(time.Hour), WithUsersetBatchMaxSize(1))
+
+		release := holdRead(filterOf("folder:9", "viewer"))
+		defer release()
+
+		iter, err := reader.ReadUsersetTuples(ctx, store, filterOf("folder:2", "viewer"))
+		require.Equal(t, []string{"group:2#member"}, readAll(t, iter, err))
+		require.Len(t, batcher.filters, 1)
+	})
+
+	t.Run("shares_the_error_of_the_batch", func(t *testing.T) {
+		batcher := &recordingBatcher{BatchUsersetTupleReader: batchReader, err: errors.New("boom")}
+		reader, holdRead := newReader(t, batcher)
+
+		release := holdRead(filterOf("folder:9", "viewer"))
+		defer release()
+
+		_, err := reader.ReadUsersetTuples(ctx, store, filterOf("folder:2", "viewer"))
+		require.EqualError(t, err, "boom")
+	})
+
+	t.Run("does_not_batch_the_reads_of_a_type", func(t *testing.T) {
+		batcher := &recordingBatcher{BatchUsersetTupleReader: batchReader}
+		reader := NewBatchingUsersetTupleReader(ds, batcher)
+
+		iter, err := reader.ReadUsersetTuples(ctx, store, filterOf("folder:", "editor"))
+		require.Equal(t, []string{"group:fga#member"}, readAll(t, iter, err))
+		require.Empty(t, batcher.filters)
+	})
+
+	t.Run("caller_gives_up", func(t *testing.T) {
+		batcher := &recordingBatcher{BatchUsersetTupleReader: batchReader}
+		reader, holdRead := newReader(t, batcher, WithUsersetBatchWindow(50*time.Millisecond))
+
+		release := holdRead(filterOf("folder:9", "viewer"))
+		defer release()
+
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := reader.ReadUsersetTuples(ctx, store, filterOf("folder:3", "viewer"))
+		require.ErrorIs(t, err, context.Canceled)
+
+		// the batch is still read for the other callers
+		iter, err := reader.ReadUsersetTuples(context.Background(), store, filterOf("folder:4", "viewer"))
+		require.Equal(t, []string{"group:4#member"}, readAll(t, iter, err))
+		require.Len(t, batcher.filters, 1)
+		require.ElementsMatch(t, []string{"folder:3", "folder:4"}, batcher.filters[0].Objects)
+	})
+}
@@ -2,6 +2,7 @@ package storagewrappers
 
 import (
 	"context"
+	"errors"
 
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 
@@ -9,25 +10,113 @@ import (
 	"github.com/openfga/openfga/pkg/tuple"
 )
 
+// ConflictPolicy decides which tuple is read when a contextual tuple conflicts with the persisted
+// tuples, i.e. when it has the same object, relation and user as a persisted tuple (regardless of
+// their conditions) or as a tuple removed by a [TupleOverlay].
+type ConflictPolicy int
+
+const (
+	// ContextualTuplesWin reads the conflicting contextual tuple instead of the persisted one, and
+	// reads contextual tuples even if a [TupleOverlay] removes them. This is the default policy.
+	ContextualTuplesWin ConflictPolicy = iota
+
+	// PersistedTuplesWin reads the persisted tuple instead of the conflicting contextual one, and
+	// does not read the contextual tuples that a [TupleOverlay] removes.
+	PersistedTuplesWin
+)
+
+// CombinedTupleReaderOption defines an option that can be used to change the behavior of the
+// reader returned by NewCombinedTupleReader.
+type CombinedTupleReaderOption func(c *combinedTupleReader)
+
+// WithConflictPolicy sets how conflicts between contextual and persisted tuples are resolved.
+// See [ConflictPolicy].
+func WithConflictPolicy(policy ConflictPolicy) CombinedTupleReaderOption {
+	return func(c *combinedTupleReader) {
+		c.conflictPolicy = policy
+	}
+}
+
 // NewCombinedTupleReader returns a [storage.RelationshipTupleReader] that reads from
 // a persistent datastore and from the contextual tuples specified in the request.
+// A tuple is never read twice: conflicts between contextual and persisted tuples are
+// resolved as described by the [ConflictPolicy], [ContextualTuplesWin] by default.
 func NewCombinedTupleReader(
 	ds storage.RelationshipTupleReader,
 	contextualTuples []*openfgav1.TupleKey,
+	opts ...CombinedTupleReaderOption,
 ) storage.RelationshipTupleReader {
-	return &combinedTupleReader{
+	c := &combinedTupleReader{
 		RelationshipTupleReader: ds,
 		contextualTuples:        contextualTuples,
+		conflictPolicy:          ContextualTuplesWin,
 	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
 }
 
 type combinedTupleReader struct {
 	storage.RelationshipTupleReader
 	contextualTuples []*openfgav1.TupleKey
+	conflictPolicy   ConflictPolicy
 }
 
 var _ storage.RelationshipTupleReader = (*combinedTupleReader)(nil)
 
+// tupleMasker is implemented by the readers that hide some of the persisted tuples (see [TupleOverlay]).
+type tupleMasker interface {
+	isMasked(tk *openfgav1.TupleKey) bool
+}
+
+// isMasked returns true if the wrapped reader hides the persisted tuples matching tk.
+func (c *combinedTupleReader) isMasked(tk *openfgav1.TupleKey) bool {
+	m, ok := c.RelationshipTupleReader.(tupleMasker)
+	return ok && m.isMasked(tk)
+}
+
+// combine merges the contextual tuples and the persisted tuples yielded by the provided iterator,
+// which must match the same query, resolving the conflicts between them according to the conflict policy.
+func (c *combinedTupleReader) combine(contextual []*openfgav1.Tuple, persisted storage.TupleIterator) storage.TupleIterator {
+	if c.conflictPolicy == PersistedTuplesWin {
+		unmasked := make([]*openfgav1.Tuple, 0, len(contextual))
+		for _, t := range contextual {
+			if !c.isMasked(t.GetKey()) {
+				unmasked = append(unmasked, t)
+			}
+		}
+
+		return &persistedFirstIterator{
+			persisted:  persisted,
+			contextual: unmasked,
+			seen:       make(map[string]struct{}),
+		}
+	}
+
+	if len(contextual) == 0 {
+		return persisted
+	}
+
+	keys := make(map[string]struct{}, len(contextual))
+	for _, t := range contextual {
+		keys[tuple.TupleKeyToString(t.GetKey())] = struct{}{}
+	}
+
+	return storage.NewCombinedIterator(
+		storage.NewStaticTupleIterator(contextual),
+		&maskedTupleIterator{
+			iter: persisted,
+			isMasked: func(tk *openfgav1.TupleKey) bool {
+				_, ok := keys[tuple.TupleKeyToString(tk)]
+				return ok
+			},
+		},
+	)
+}
+
 // filterTuples filters out the tuples in the provided slice by removing any tuples in the slice
 // that don't match the object and relation provided in the filterKey.
 func filterTuples(tuples []*openfgav1.TupleKey, targetObject, targetRelation string) []*openfgav1.Tuple {
@@ -49,14 +138,12 @@ func (c *combinedTupleReader) Read(
 	storeID string,
 	tk *openfgav1.TupleKey,
 ) (storage.TupleIterator, error) {
-	iter1 := storage.NewStaticTupleIterator(filterTuples(c.contextualTuples, tk.GetObject(), tk.GetRelation()))
-
-	iter2, err := c.RelationshipTupleReader.Read(ctx, storeID, tk)
+	iter, err := c.RelationshipTupleReader.Read(ctx, storeID, tk)
 	if err != nil {
 		return nil, err
 	}
 
-	return storage.NewCombinedIterator(iter1, iter2), nil
+	return c.combine(filterTuples(c.contextualTuples, tk.GetObject(), tk.GetRelation()), iter), nil
 }
 
 // ReadPage see [storage.RelationshipTupleReader.ReadPage].
@@ -76,15 +163,24 @@ func (c *combinedTupleReader) ReadUserTuple(
 	store string,
 	tk *openfgav1.TupleKey,
 ) (*openfgav1.Tuple, error) {
-	filteredContextualTuples := filterTuples(c.contextualTuples, tk.GetObject(), tk.GetRelation())
-
-	for _, t := range filteredContextualTuples {
+	var contextualTuple *openfgav1.Tuple
+	for _, t := range filterTuples(c.contextualTuples, tk.GetObject(), tk.GetRelation()) {
 		if t.GetKey().GetUser() == tk.GetUser() {
-			return t, nil
+			contextualTuple = t
+			break
 		}
 	}
 
-	return c.RelationshipTupleReader.ReadUserTuple(ctx, store, tk)
+	if c.conflictPolicy == ContextualTuplesWin && contextualTuple != nil {
+		return contextualTuple, nil
+	}
+
+	t, err := c.RelationshipTupleReader.ReadUserTuple(ctx, store, tk)
+	if !errors.Is(err, storage.ErrNotFound) || contextualTuple == nil || c.isMasked(tk) {
+		return t, err
+	}
+
+	return contextualTuple, nil
 }
 
 // ReadUsersetTuples see [storage.RelationshipTupleReader].ReadUsersetTuples.
@@ -101,14 +197,12 @@ func (c *combinedTupleReader) ReadUsersetTuples(
 		}
 	}
 
-	iter1 := storage.NewStaticTupleIterator(usersetTuples)
-
-	iter2, err := c.RelationshipTupleReader.ReadUsersetTuples(ctx, store, filter)
+	iter, err := c.RelationshipTupleReader.ReadUsersetTuples(ctx, store, filter)
 	if err != nil {
 		return nil, err
 	}
 
-	return storage.NewCombinedIterator(iter1, iter2), nil
+	return c.combine(usersetTuples, iter), nil
 }
 
 // ReadStartingWithUser see [storage.RelationshipTupleReader].ReadStartingWithUser.
@@ -141,12 +235,58 @@ func (c *combinedTupleReader) ReadStartingWithUser(
 		}
 	}
 
-	iter1 := storage.NewStaticTupleIterator(filteredTuples)
-
-	iter2, err := c.RelationshipTupleReader.ReadStartingWithUser(ctx, store, filter)
+	iter, err := c.RelationshipTupleReader.ReadStartingWithUser(ctx, store, filter)
 	if err != nil {
 		return nil, err
 	}
 
-	return storage.NewCombinedIterator(iter1, iter2), nil
+	return c.combine(filteredTuples, iter), nil
+}
+
+// persistedFirstIterator yields the tuples of the persisted iterator, followed by the contextual
+// tuples that the persisted iterator did not yield.
+type persistedFirstIterator struct {
+	persisted     storage.TupleIterator
+	persistedDone bool
+	contextual    []*openfgav1.Tuple
+	seen          map[string]struct{}
+}
+
+var _ storage.TupleIterator = (*persistedFirstIterator)(nil)
+
+// Next see [storage.Iterator].Next.
+func (p *persistedFirstIterator) Next(ctx context.Context) (*openfgav1.Tuple, error) {
+	if !p.persistedDone {
+		t, err := p.persisted.Next(ctx)
+		if err == nil {
+			p.seen[tuple.TupleKeyToString(t.GetKey())] = struct{}{}
+			return t, nil
+		}
+
+		if !errors.Is(err, storage.ErrIteratorDone) {
+			return nil, err
+		}
+
+		p.persistedDone = true
+	}
+
+	for len(p.contextual) > 0 {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		t := p.contextual[0]
+		p.contextual = p.contextual[1:]
+
+		if _, ok := p.seen[tuple.TupleKeyToString(t.GetKey())]; !ok {
+			return t, nil
+		}
+	}
+
+	return nil, storage.ErrIteratorDone
+}
+
+// Stop see [storage.Iterator].Stop.
+func (p *persistedFirstIterator) Stop() {
+	p.persisted.Stop()
 }
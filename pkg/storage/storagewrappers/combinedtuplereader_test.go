@@ -0,0 +1,161 @@
+package storagewrappers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/tuple"
+)
+
+func TestCombinedTupleReaderConflictPolicy(t *testing.T) {
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	err := ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+		tuple.NewTupleKey("document:1", "viewer", "group:eng#member"),
+		tuple.NewTupleKey("document:1", "viewer", "user:anne"),
+	})
+	require.NoError(t, err)
+
+	contextualTuples := []*openfgav1.TupleKey{
+		// conflicts with a persisted tuple
+		tuple.NewTupleKeyWithCondition("document:1", "viewer", "user:jon", "in_office", nil),
+		tuple.NewTupleKeyWithCondition("document:1", "viewer", "group:eng#member", "in_office", nil),
+		// conflicts with a tuple removed by the overlay
+		tuple.NewTupleKey("document:1", "viewer", "user:anne"),
+		// does not conflict
+		tuple.NewTupleKey("document:1", "viewer", "user:bob"),
+	}
+
+	overlay := NewOverlayTupleReader(ds, TupleOverlay{
+		RemovedTuples: []*openfgav1.TupleKeyWithoutCondition{
+			tuple.TupleKeyToTupleKeyWithoutCondition(tuple.NewTupleKey("document:1", "viewer", "user:anne")),
+		},
+	})
+
+	// readAll returns the condition read for every user, '-' if the tuple was read without a condition
+	readAll := func(t *testing.T, iter storage.TupleIterator) map[string]string {
+		defer iter.Stop()
+
+		res := map[string]string{}
+		for {
+			tp, err := iter.Next(ctx)
+			if err != nil {
+				require.ErrorIs(t, err, storage.ErrIteratorDone)
+				return res
+			}
+
+			user := tp.GetKey().GetUser()
+			require.NotContains(t, res, user, "tuple read twice")
+
+			res[user] = "-"
+			if tp.GetKey().GetCondition() != nil {
+				res[user] = tp.GetKey().GetCondition().GetName()
+			}
+		}
+	}
+
+	tests := []struct {
+		name                 string
+		policy               ConflictPolicy
+		expectedRead         map[string]string
+		expectedUsersetRead  map[string]string
+		expectedJonCondition string
+		expectAnne           bool
+	}{
+		{
+			name:   "contextual_tuples_win",
+			policy: ContextualTuplesWin,
+			expectedRead: map[string]string{
+				"user:jon":         "in_office",
+				"group:eng#member": "in_office",
+				"user:anne":        "-",
+				"user:bob":         "-",
+			},
+			expectedUsersetRead:  map[string]string{"group:eng#member": "in_office"},
+			expectedJonCondition: "in_office",
+			expectAnne:           true,
+		},
+		{
+			name:   "persisted_tuples_win",
+			policy: PersistedTuplesWin,
+			expectedRead: map[string]string{
+				"user:jon":         "-",
+				"group:eng#member": "-",
+				"user:bob":         "-",
+			},
+			expectedUsersetRead:  map[string]string{"group:eng#member": "-"},
+			expectedJonCondition: "",
+			expectAnne:           false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reader := NewCombinedTupleReader(overlay, contextualTuples, WithConflictPolicy(test.policy))
+
+			t.Run("read", func(t *testing.T) {
+				iter, err := reader.Read(ctx, storeID, tuple.NewTupleKey("document:1", "viewer", ""))
+				require.NoError(t, err)
+				require.Equal(t, test.expectedRead, readAll(t, iter))
+			})
+
+			t.Run("read_userset_tuples", func(t *testing.T) {
+				iter, err := reader.ReadUsersetTuples(ctx, storeID, storage.ReadUsersetTuplesFilter{
+					Object:   "document:1",
+					Relation: "viewer",
+				})
+				require.NoError(t, err)
+				require.Equal(t, test.expectedUsersetRead, readAll(t, iter))
+			})
+
+			t.Run("read_starting_with_user", func(t *testing.T) {
+				iter, err := reader.ReadStartingWithUser(ctx, storeID, storage.ReadStartingWithUserFilter{
+					ObjectType: "document",
+					Relation:   "viewer",
+					UserFilter: []*openfgav1.ObjectRelation{{Object: "user:jon"}, {Object: "user:anne"}},
+				})
+				require.NoError(t, err)
+
+				read := readAll(t, iter)
+				require.Equal(t, test.expectedRead["user:jon"], read["user:jon"])
+				require.Equal(t, test.expectAnne, read["user:anne"] != "")
+			})
+
+			t.Run("read_user_tuple", func(t *testing.T) {
+				tp, err := reader.ReadUserTuple(ctx, storeID, tuple.NewTupleKey("document:1", "viewer", "user:jon"))
+				require.NoError(t, err)
+				require.Equal(t, test.expectedJonCondition, tp.GetKey().GetCondition().GetName())
+
+				_, err = reader.ReadUserTuple(ctx, storeID, tuple.NewTupleKey("document:1", "viewer", "user:anne"))
+				if test.expectAnne {
+					require.NoError(t, err)
+				} else {
+					require.ErrorIs(t, err, storage.ErrNotFound)
+				}
+
+				_, err = reader.ReadUserTuple(ctx, storeID, tuple.NewTupleKey("document:1", "viewer", "user:bob"))
+				require.NoError(t, err)
+
+				_, err = reader.ReadUserTuple(ctx, storeID, tuple.NewTupleKey("document:1", "viewer", "user:maria"))
+				require.ErrorIs(t, err, storage.ErrNotFound)
+			})
+		})
+	}
+
+	t.Run("defaults_to_contextual_tuples_win", func(t *testing.T) {
+		tp, err := NewCombinedTupleReader(ds, contextualTuples).ReadUserTuple(ctx, storeID, tuple.NewTupleKey("document:1", "viewer", "user:jon"))
+		require.NoError(t, err)
+		require.Equal(t, "in_office", tp.GetKey().GetCondition().GetName())
+	})
+}
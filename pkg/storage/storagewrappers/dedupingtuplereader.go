@@ -0,0 +1,222 @@
+package storagewrappers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/openfga/openfga/internal/build"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/tuple"
+)
+
+var _ storage.RelationshipTupleReader = (*DedupingTupleReader)(nil)
+
+var dedupedReadCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: build.ProjectName,
+	Name:      "datastore_deduplicated_read_count",
+	Help:      "The total number of reads made through a request-scoped DedupingTupleReader, by whether they were served by an identical read of the same request (hit) or reached the datastore (miss).",
+}, []string{"method", "outcome"})
+
+// DedupingTupleReader is a wrapper over a datastore that coalesces the identical reads made while serving a
+// single request, e.g. by the parallel branches of the resolution of a Check. Concurrent identical reads share
+// one query, and the results are memoized, so that later identical reads are served from memory.
+//
+// The results of the queries returning an iterator are read in full before being shared, and they are kept
+// for the lifetime of the wrapper, so it must only be used for the duration of one request. Failed reads are
+// not memoized, but the concurrent identical reads share their error. ReadPage is not deduplicated.
+// It is safe for concurrent use.
+type DedupingTupleReader struct {
+	storage.RelationshipTupleReader
+
+	group singleflight.Group
+
+	mu         sync.Mutex
+	tuples     map[string][]*openfgav1.Tuple
+	userTuples map[string]*openfgav1.Tuple
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// NewDedupingTupleReader returns a [DedupingTupleReader] over the wrapped datastore.
+func NewDedupingTupleReader(wrapped storage.RelationshipTupleReader) *DedupingTupleReader {
+	return &DedupingTupleReader{
+		RelationshipTupleReader: wrapped,
+		tuples:                  map[string][]*openfgav1.Tuple{},
+		userTuples:              map[string]*openfgav1.Tuple{},
+	}
+}
+
+// Hits returns the number of reads that were served by an identical read.
+func (d *DedupingTupleReader) Hits() uint64 {
+	return d.hits.Load()
+}
+
+// Misses returns the number of reads that reached the wrapped datastore.
+func (d *DedupingTupleReader) Misses() uint64 {
+	return d.misses.Load()
+}
+
+// Read see [storage.RelationshipTupleReader].Read.
+func (d *DedupingTupleReader) Read(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (storage.TupleIterator, error) {
+	return d.dedupIterator(ctx, "Read", store+"/"+tuple.TupleKeyToString(tupleKey), func() (storage.TupleIterator, error) {
+		return d.RelationshipTupleReader.Read(ctx, store, tupleKey)
+	})
+}
+
+// ReadUserTuple see [storage.RelationshipTupleReader].ReadUserTuple.
+func (d *DedupingTupleReader) ReadUserTuple(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
+	const method = "ReadUserTuple"
+	key := store + "/" + tuple.TupleKeyToString(tupleKey)
+
+	d.mu.Lock()
+	t, ok := d.userTuples[key]
+	d.mu.Unlock()
+	if ok {
+		d.record(method, true)
+		if t == nil {
+			return nil, storage.ErrNotFound
+		}
+		return t, nil
+	}
+
+	var leader bool
+	v, err, _ := d.group.Do(method+":"+key, func() (interface{}, error) {
+		leader = true
+
+		t, err := d.RelationshipTupleReader.ReadUserTuple(ctx, store, tupleKey)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return nil, err
+		}
+
+		// a tuple that does not exist is memoized as nil
+		d.mu.Lock()
+		d.userTuples[key] = t
+		d.mu.Unlock()
+
+		return t, nil
+	})
+	d.record(method, !leader)
+	if err != nil {
+		return nil, err
+	}
+
+	if v.(*openfgav1.Tuple) == nil {
+		return nil, storage.ErrNotFound
+	}
+
+	return v.(*openfgav1.Tuple), nil
+}
+
+// ReadUsersetTuples see [storage.RelationshipTupleReader].ReadUsersetTuples.
+func (d *DedupingTupleReader) ReadUsersetTuples(
+	ctx context.Context,
+	store string,
+	filter storage.ReadUsersetTuplesFilter,
+) (storage.TupleIterator, error) {
+	userTypes := make([]string, 0, len(filter.AllowedUserTypeRestrictions))
+	for _, ref := range filter.AllowedUserTypeRestrictions {
+		switch {
+		case ref.GetWildcard() != nil:
+			userTypes = append(userTypes, tuple.TypedPublicWildcard(ref.GetType()))
+		case ref.GetRelation() != "":
+			userTypes = append(userTypes, tuple.ToObjectRelationString(ref.GetType(), ref.GetRelation()))
+		default:
+			userTypes = append(userTypes, ref.GetType())
+		}
+	}
+
+	key := store + "/" + tuple.ToObjectRelationString(filter.Object, filter.Relation) + "@" + strings.Join(userTypes, ",")
+	return d.dedupIterator(ctx, "ReadUsersetTuples", key, func() (storage.TupleIterator, error) {
+		return d.RelationshipTupleReader.ReadUsersetTuples(ctx, store, filter)
+	})
+}
+
+// ReadStartingWithUser see [storage.RelationshipTupleReader].ReadStartingWithUser.
+func (d *DedupingTupleReader) ReadStartingWithUser(
+	ctx context.Context,
+	store string,
+	filter storage.ReadStartingWithUserFilter,
+) (storage.TupleIterator, error) {
+	users := make([]string, 0, len(filter.UserFilter))
+	for _, user := range filter.UserFilter {
+		users = append(users, tuple.GetObjectRelationAsString(user))
+	}
+
+	key := store + "/" + tuple.ToObjectRelationString(filter.ObjectType, filter.Relation) + "@" + strings.Join(users, ",")
+	return d.dedupIterator(ctx, "ReadStartingWithUser", key, func() (storage.TupleIterator, error) {
+		return d.RelationshipTupleReader.ReadStartingWithUser(ctx, store, filter)
+	})
+}
+
+// dedupIterator returns an iterator over the memoized results of the read identified by the method and the key,
+// making the read with the read function if no identical read was made yet.
+func (d *DedupingTupleReader) dedupIterator(
+	ctx context.Context,
+	method, key string,
+	read func() (storage.TupleIterator, error),
+) (storage.TupleIterator, error) {
+	key = method + ":" + key
+
+	d.mu.Lock()
+	tuples, ok := d.tuples[key]
+	d.mu.Unlock()
+	if ok {
+		d.record(method, true)
+		return storage.NewStaticTupleIterator(tuples), nil
+	}
+
+	var leader bool
+	v, err, _ := d.group.Do(key, func() (interface{}, error) {
+		leader = true
+
+		iter, err := read()
+		if err != nil {
+			return nil, err
+		}
+		defer iter.Stop()
+
+		var tuples []*openfgav1.Tuple
+		for {
+			t, err := iter.Next(ctx)
+			if err != nil {
+				if errors.Is(err, storage.ErrIteratorDone) {
+					break
+				}
+				return nil, err
+			}
+			tuples = append(tuples, t)
+		}
+
+		d.mu.Lock()
+		d.tuples[key] = tuples
+		d.mu.Unlock()
+
+		return tuples, nil
+	})
+	d.record(method, !leader)
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.NewStaticTupleIterator(v.([]*openfgav1.Tuple)), nil
+}
+
+func (d *DedupingTupleReader) record(method string, hit bool) {
+	if hit {
+		d.hits.Add(1)
+		dedupedReadCounter.WithLabelValues(method, "hit").Inc()
+		return
+	}
+
+	d.misses.Add(1)
+	dedupedReadCounter.WithLabelValues(method, "miss").Inc()
+}
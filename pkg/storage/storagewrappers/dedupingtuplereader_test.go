@@ -0,0 +1,141 @@
+package storagewrappers
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+)
+
+// gatedTupleReader blocks the ReadUsersetTuples calls until the gate is closed.
+type gatedTupleReader struct {
+	storage.RelationshipTupleReader
+	gate chan struct{}
+}
+
+func (g *gatedTupleReader) ReadUsersetTuples(ctx context.Context, store string, filter storage.ReadUsersetTuplesFilter) (storage.TupleIterator, error) {
+	<-g.gate
+	return g.RelationshipTupleReader.ReadUsersetTuples(ctx, store, filter)
+}
+
+func TestDedupingTupleReader(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+	store := ulid.Make().String()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	err := ds.Write(ctx, store, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "user:anne"),
+		tuple.NewTupleKey("document:1", "viewer", "group:eng#member"),
+		tuple.NewTupleKey("document:1", "viewer", "group:fga#member"),
+	})
+	require.NoError(t, err)
+
+	usersetFilter := storage.ReadUsersetTuplesFilter{
+		Object:   "document:1",
+		Relation: "viewer",
+		AllowedUserTypeRestrictions: []*openfgav1.RelationReference{
+			typesystem.DirectRelationReference("group", "member"),
+		},
+	}
+
+	readAll := func(iter storage.TupleIterator, err error) []string {
+		require.NoError(t, err)
+		defer iter.Stop()
+
+		var tuples []string
+		for {
+			tk, err := iter.Next(ctx)
+			if err != nil {
+				require.ErrorIs(t, err, storage.ErrIteratorDone)
+				return tuples
+			}
+			tuples = append(tuples, tuple.TupleKeyToString(tk.GetKey()))
+		}
+	}
+
+	t.Run("identical_reads_reach_the_datastore_once", func(t *testing.T) {
+		recorder := NewReadPatternRecorder(ds)
+		reader := NewDedupingTupleReader(recorder)
+
+		first := readAll(reader.ReadUsersetTuples(ctx, store, usersetFilter))
+		second := readAll(reader.ReadUsersetTuples(ctx, store, usersetFilter))
+		require.Len(t, first, 2)
+		require.ElementsMatch(t, first, second)
+
+		tk := tuple.NewTupleKey("document:1", "viewer", "user:anne")
+		for i := 0; i < 2; i++ {
+			_, err := reader.ReadUserTuple(ctx, store, tk)
+			require.NoError(t, err)
+		}
+
+		// a tuple that does not exist is memoized too
+		missing := tuple.NewTupleKey("document:1", "viewer", "user:bob")
+		for i := 0; i < 2; i++ {
+			_, err := reader.ReadUserTuple(ctx, store, missing)
+			require.ErrorIs(t, err, storage.ErrNotFound)
+		}
+
+		// a different filter is a different read
+		require.Len(t, readAll(reader.Read(ctx, store, tuple.NewTupleKey("document:1", "viewer", ""))), 3)
+
+		require.Len(t, recorder.Reads(), 4)
+		require.Equal(t, uint64(3), reader.Hits())
+		require.Equal(t, uint64(4), reader.Misses())
+	})
+
+	t.Run("concurrent_identical_reads_share_one_query", func(t *testing.T) {
+		gated := &gatedTupleReader{RelationshipTupleReader: ds, gate: make(chan struct{})}
+		recorder := NewReadPatternRecorder(gated)
+		reader := NewDedupingTupleReader(recorder)
+
+		const readers = 5
+		var started, wg sync.WaitGroup
+		started.Add(readers)
+		wg.Add(readers)
+		for i := 0; i < readers; i++ {
+			go func() {
+				defer wg.Done()
+				started.Done()
+
+				iter, err := reader.ReadUsersetTuples(ctx, store, usersetFilter)
+				if !assert.NoError(t, err) {
+					return
+				}
+				defer iter.Stop()
+
+				count := 0
+				for ; ; count++ {
+					if _, err := iter.Next(ctx); err != nil {
+						break
+					}
+				}
+				assert.Equal(t, 2, count)
+			}()
+		}
+
+		started.Wait()
+		close(gated.gate)
+		wg.Wait()
+
+		// the readers arriving after the query completed are served from memory, the others share it
+		require.Len(t, recorder.Reads(), 1)
+		require.Equal(t, uint64(readers-1), reader.Hits())
+		require.Equal(t, uint64(1), reader.Misses())
+	})
+}
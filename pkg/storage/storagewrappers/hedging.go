@@ -0,0 +1,281 @@
+package storagewrappers
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/openfga/openfga/internal/build"
+	"github.com/openfga/openfga/pkg/storage"
+)
+
+const (
+	defaultHedgingPercentile = 0.99
+	defaultHedgingMinDelay   = 5 * time.Millisecond
+	defaultHedgingWindowSize = 1000
+
+	hedgedSpanAttribute = "hedged"
+)
+
+var _ storage.RelationshipTupleReader = (*HedgingTupleReader)(nil)
+
+var hedgedReadCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: build.ProjectName,
+	Name:      "datastore_hedged_read_count",
+	Help:      "The total number of reads sent to the replica datastore because the primary was slower than the hedging delay, labeled by the datastore that answered first.",
+}, []string{"method", "winner"})
+
+// HedgingTupleReader is a wrapper over a primary datastore and one of its read replicas that sends a read
+// to the replica when the primary takes longer than usual to answer it, and returns the first answer.
+type HedgingTupleReader struct {
+	primary storage.RelationshipTupleReader
+	replica storage.RelationshipTupleReader
+
+	percentile float64
+	minDelay   time.Duration
+
+	mu        sync.Mutex
+	latencies []time.Duration // ring buffer of the latencies of the last reads answered by the primary
+	next      int
+	observed  int
+
+	// delay is the hedging delay, derived from latencies every time the ring buffer is refilled
+	delay atomic.Int64
+}
+
+// HedgingOption configures a HedgingTupleReader.
+type HedgingOption func(*HedgingTupleReader)
+
+// WithHedgingPercentile sets the percentile of the latencies of the primary the hedging delay is derived from.
+// It defaults to 0.99, so that about 1% of the reads are sent to the replica.
+func WithHedgingPercentile(percentile float64) HedgingOption {
+	return func(h *HedgingTupleReader) {
+		h.percentile = percentile
+	}
+}
+
+// WithHedgingMinDelay sets the minimum hedging delay. It is the delay used until enough latencies of the primary
+// are observed, and it prevents sending most reads to the replica when the primary answers them all quickly.
+// It defaults to 5ms.
+func WithHedgingMinDelay(delay time.Duration) HedgingOption {
+	return func(h *HedgingTupleReader) {
+		h.minDelay = delay
+	}
+}
+
+// WithHedgingWindowSize sets the number of the latest latencies of the primary the hedging delay is derived from.
+// It defaults to 1000.
+func WithHedgingWindowSize(size int) HedgingOption {
+	return func(h *HedgingTupleReader) {
+		h.latencies = make([]time.Duration, size)
+	}
+}
+
+// NewHedgingTupleReader returns a wrapper that sends the reads to the primary and, if the primary has not answered
+// a read after the hedging delay, sends the same read to the replica and returns the first successful answer. The
+// answer that comes second is discarded. The hedging delay is the configured percentile of the latest latencies of
+// the primary, but no less than the minimum delay.
+//
+// The replica may lag behind the primary, so it must only be used for the reads that tolerate it.
+func NewHedgingTupleReader(
+	primary storage.RelationshipTupleReader,
+	replica storage.RelationshipTupleReader,
+	opts ...HedgingOption,
+) *HedgingTupleReader {
+	h := &HedgingTupleReader{
+		primary:    primary,
+		replica:    replica,
+		percentile: defaultHedgingPercentile,
+		minDelay:   defaultHedgingMinDelay,
+		latencies:  make([]time.Duration, defaultHedgingWindowSize),
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	if len(h.latencies) == 0 {
+		h.latencies = make([]time.Duration, defaultHedgingWindowSize)
+	}
+
+	h.delay.Store(int64(h.minDelay))
+
+	return h
+}
+
+// Delay returns the current hedging delay.
+func (h *HedgingTupleReader) Delay() time.Duration {
+	return time.Duration(h.delay.Load())
+}
+
+// Read see [storage.RelationshipTupleReader].Read.
+func (h *HedgingTupleReader) Read(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (storage.TupleIterator, error) {
+	return hedge(ctx, h, "Read", func(reader storage.RelationshipTupleReader) (storage.TupleIterator, error) {
+		return reader.Read(ctx, store, tupleKey)
+	}, storage.TupleIterator.Stop)
+}
+
+// ReadPage see [storage.RelationshipTupleReader].ReadPage.
+func (h *HedgingTupleReader) ReadPage(
+	ctx context.Context,
+	store string,
+	tupleKey *openfgav1.TupleKey,
+	opts storage.PaginationOptions,
+) ([]*openfgav1.Tuple, []byte, error) {
+	type page struct {
+		tuples []*openfgav1.Tuple
+		token  []byte
+	}
+
+	res, err := hedge(ctx, h, "ReadPage", func(reader storage.RelationshipTupleReader) (page, error) {
+		tuples, token, err := reader.ReadPage(ctx, store, tupleKey, opts)
+		return page{tuples: tuples, token: token}, err
+	}, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return res.tuples, res.token, nil
+}
+
+// ReadUserTuple see [storage.RelationshipTupleReader].ReadUserTuple.
+func (h *HedgingTupleReader) ReadUserTuple(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
+	return hedge(ctx, h, "ReadUserTuple", func(reader storage.RelationshipTupleReader) (*openfgav1.Tuple, error) {
+		return reader.ReadUserTuple(ctx, store, tupleKey)
+	}, nil)
+}
+
+// ReadUsersetTuples see [storage.RelationshipTupleReader].ReadUsersetTuples.
+func (h *HedgingTupleReader) ReadUsersetTuples(
+	ctx context.Context,
+	store string,
+	filter storage.ReadUsersetTuplesFilter,
+) (storage.TupleIterator, error) {
+	return hedge(ctx, h, "ReadUsersetTuples", func(reader storage.RelationshipTupleReader) (storage.TupleIterator, error) {
+		return reader.ReadUsersetTuples(ctx, store, filter)
+	}, storage.TupleIterator.Stop)
+}
+
+// ReadStartingWithUser see [storage.RelationshipTupleReader].ReadStartingWithUser.
+func (h *HedgingTupleReader) ReadStartingWithUser(
+	ctx context.Context,
+	store string,
+	filter storage.ReadStartingWithUserFilter,
+) (storage.TupleIterator, error) {
+	return hedge(ctx, h, "ReadStartingWithUser", func(reader storage.RelationshipTupleReader) (storage.TupleIterator, error) {
+		return reader.ReadStartingWithUser(ctx, store, filter)
+	}, storage.TupleIterator.Stop)
+}
+
+// hedge sends the read to the primary and, if it has not answered after the hedging delay, to the replica.
+// It returns the first successful answer, or the error of the primary if both fail. The answer that comes
+// second is passed to release, if it is successful and release is not nil. The reads preferring higher
+// consistency are only sent to the primary, since the replica may lag behind it.
+func hedge[T any](
+	ctx context.Context,
+	h *HedgingTupleReader,
+	method string,
+	read func(reader storage.RelationshipTupleReader) (T, error),
+	release func(T),
+) (T, error) {
+	if storage.ConsistencyFromContext(ctx) == storage.ConsistencyHigher {
+		return read(h.primary)
+	}
+
+	type result struct {
+		value   T
+		err     error
+		primary bool
+	}
+
+	// buffered so that the read answering second never blocks
+	results := make(chan result, 2)
+
+	start := time.Now()
+	go func() {
+		value, err := read(h.primary)
+		if err == nil {
+			h.observe(time.Since(start))
+		}
+		results <- result{value: value, err: err, primary: true}
+	}()
+
+	timer := time.NewTimer(h.Delay())
+	defer timer.Stop()
+
+	select {
+	case res := <-results:
+		return res.value, res.err
+	case <-timer.C:
+	}
+
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Bool(hedgedSpanAttribute, true))
+
+	go func() {
+		value, err := read(h.replica)
+		results <- result{value: value, err: err}
+	}()
+
+	first := <-results
+	if first.err == nil {
+		hedgedReadCounter.WithLabelValues(method, winner(first.primary)).Inc()
+
+		go func() {
+			if second := <-results; second.err == nil && release != nil {
+				release(second.value)
+			}
+		}()
+
+		return first.value, nil
+	}
+
+	second := <-results
+	if second.err == nil {
+		hedgedReadCounter.WithLabelValues(method, winner(second.primary)).Inc()
+		return second.value, nil
+	}
+
+	if first.primary {
+		return first.value, first.err
+	}
+
+	return second.value, second.err
+}
+
+func winner(primary bool) string {
+	if primary {
+		return "primary"
+	}
+
+	return "replica"
+}
+
+// observe records the latency of a read answered by the primary, and derives the hedging delay from the
+// latest latencies every time the ring buffer is refilled.
+func (h *HedgingTupleReader) observe(latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.latencies[h.next] = latency
+	h.next = (h.next + 1) % len(h.latencies)
+	h.observed++
+
+	if h.observed < len(h.latencies) {
+		return
+	}
+	h.observed = 0
+
+	sorted := slices.Clone(h.latencies)
+	slices.Sort(sorted)
+
+	index := min(max(int(h.percentile*float64(len(sorted)-1)), 0), len(sorted)-1)
+	h.delay.Store(int64(max(sorted[index], h.minDelay)))
+}
@@ -0,0 +1,115 @@
+package storagewrappers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/openfga/openfga/internal/mocks"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/tuple"
+)
+
+func TestHedgingTupleReader(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+	store := ulid.Make().String()
+
+	// the primary and the replica hold different tuples, to tell which one answered
+	primaryTuple := tuple.NewTupleKey("document:1", "viewer", "user:primary")
+	primary := memory.New()
+	t.Cleanup(primary.Close)
+	require.NoError(t, primary.Write(ctx, store, nil, []*openfgav1.TupleKey{primaryTuple}))
+
+	replicaTuple := tuple.NewTupleKey("document:1", "viewer", "user:replica")
+	replica := memory.New()
+	t.Cleanup(replica.Close)
+	require.NoError(t, replica.Write(ctx, store, nil, []*openfgav1.TupleKey{replicaTuple}))
+
+	filter := tuple.NewTupleKey("document:1", "viewer", "")
+
+	t.Run("fast_primary_answers", func(t *testing.T) {
+		reader := NewHedgingTupleReader(primary, replica, WithHedgingMinDelay(time.Second))
+
+		tuples, _, err := reader.ReadPage(ctx, store, filter, storage.NewPaginationOptions(10, ""))
+		require.NoError(t, err)
+		require.Len(t, tuples, 1)
+		require.Equal(t, primaryTuple.GetUser(), tuples[0].GetKey().GetUser())
+	})
+
+	t.Run("slow_primary_is_hedged_with_the_replica", func(t *testing.T) {
+		slowPrimary := mocks.NewMockSlowDataStorage(primary, 100*time.Millisecond)
+		reader := NewHedgingTupleReader(slowPrimary, replica, WithHedgingMinDelay(time.Millisecond))
+
+		start := time.Now()
+		iter, err := reader.Read(ctx, store, filter)
+		require.NoError(t, err)
+		t.Cleanup(iter.Stop)
+		require.Less(t, time.Since(start), 100*time.Millisecond)
+
+		tp, err := iter.Next(ctx)
+		require.NoError(t, err)
+		require.Equal(t, replicaTuple.GetUser(), tp.GetKey().GetUser())
+
+		userTuple, err := reader.ReadUserTuple(ctx, store, replicaTuple)
+		require.NoError(t, err)
+		require.Equal(t, replicaTuple.GetUser(), userTuple.GetKey().GetUser())
+
+		// the primary answers, eventually, the reads the replica failed
+		userTuple, err = reader.ReadUserTuple(ctx, store, primaryTuple)
+		require.NoError(t, err)
+		require.Equal(t, primaryTuple.GetUser(), userTuple.GetKey().GetUser())
+	})
+
+	t.Run("error_of_the_primary_is_returned_if_both_fail", func(t *testing.T) {
+		slowPrimary := mocks.NewMockSlowDataStorage(primary, 10*time.Millisecond)
+		reader := NewHedgingTupleReader(slowPrimary, replica, WithHedgingMinDelay(time.Millisecond))
+
+		_, err := reader.ReadUserTuple(ctx, store, tuple.NewTupleKey("document:2", "viewer", "user:jon"))
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("reads_preferring_higher_consistency_are_not_hedged", func(t *testing.T) {
+		slowPrimary := mocks.NewMockSlowDataStorage(primary, 20*time.Millisecond)
+		reader := NewHedgingTupleReader(slowPrimary, replica, WithHedgingMinDelay(time.Millisecond))
+
+		tuples, _, err := reader.ReadPage(storage.ContextWithConsistency(ctx, storage.ConsistencyHigher), store, filter, storage.NewPaginationOptions(10, ""))
+		require.NoError(t, err)
+		require.Len(t, tuples, 1)
+		require.Equal(t, primaryTuple.GetUser(), tuples[0].GetKey().GetUser())
+	})
+}
+
+func TestHedgingTupleReaderDelay(t *testing.T) {
+	reader := NewHedgingTupleReader(
+		memory.New(),
+		memory.New(),
+		WithHedgingPercentile(0.9),
+		WithHedgingMinDelay(2*time.Millisecond),
+		WithHedgingWindowSize(10),
+	)
+	require.Equal(t, 2*time.Millisecond, reader.Delay())
+
+	for i := 10; i >= 1; i-- {
+		reader.observe(time.Duration(i) * time.Millisecond)
+	}
+	require.Equal(t, 9*time.Millisecond, reader.Delay())
+
+	// the delay is only derived again once the window is refilled
+	for i := 0; i < 9; i++ {
+		reader.observe(time.Microsecond)
+	}
+	require.Equal(t, 9*time.Millisecond, reader.Delay())
+
+	reader.observe(time.Microsecond)
+	require.Equal(t, 2*time.Millisecond, reader.Delay())
+}
@@ -0,0 +1,165 @@
+package storagewrappers
+
+import (
+	"context"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/tuple"
+)
+
+// TupleOverlay describes hypothetical changes that are layered on top of the persisted
+// tuples for the duration of a single request (e.g. "would this Check pass if tuple T existed?").
+type TupleOverlay struct {
+	// AddedTuples are treated as if they had been written to the datastore.
+	AddedTuples []*openfgav1.TupleKey
+
+	// RemovedTuples mask any matching persisted tuple, regardless of its condition.
+	RemovedTuples []*openfgav1.TupleKeyWithoutCondition
+}
+
+// NewOverlayTupleReader returns a [storage.RelationshipTupleReader] that reads from the
+// provided datastore as if the changes described by the overlay had been applied.
+// Persisted tuples matching one of overlay.RemovedTuples are never yielded, and
+// overlay.AddedTuples are yielded in the same way contextual tuples are.
+func NewOverlayTupleReader(
+	ds storage.RelationshipTupleReader,
+	overlay TupleOverlay,
+) storage.RelationshipTupleReader {
+	var reader storage.RelationshipTupleReader = ds
+	if len(overlay.RemovedTuples) > 0 {
+		removed := make(map[string]struct{}, len(overlay.RemovedTuples))
+		for _, tk := range overlay.RemovedTuples {
+			removed[tuple.TupleKeyToString(tk)] = struct{}{}
+		}
+
+		reader = &maskedTupleReader{
+			RelationshipTupleReader: ds,
+			removed:                 removed,
+		}
+	}
+
+	if len(overlay.AddedTuples) > 0 {
+		reader = NewCombinedTupleReader(reader, overlay.AddedTuples)
+	}
+
+	return reader
+}
+
+// maskedTupleReader hides a fixed set of tuples from the wrapped datastore.
+type maskedTupleReader struct {
+	storage.RelationshipTupleReader
+	removed map[string]struct{}
+}
+
+var _ storage.RelationshipTupleReader = (*maskedTupleReader)(nil)
+
+func (m *maskedTupleReader) isMasked(tk *openfgav1.TupleKey) bool {
+	_, ok := m.removed[tuple.TupleKeyToString(tk)]
+	return ok
+}
+
+// Read see [storage.RelationshipTupleReader].Read.
+func (m *maskedTupleReader) Read(
+	ctx context.Context,
+	store string,
+	tk *openfgav1.TupleKey,
+) (storage.TupleIterator, error) {
+	iter, err := m.RelationshipTupleReader.Read(ctx, store, tk)
+	if err != nil {
+		return nil, err
+	}
+
+	return &maskedTupleIterator{iter: iter, isMasked: m.isMasked}, nil
+}
+
+// ReadPage see [storage.RelationshipTupleReader].ReadPage.
+func (m *maskedTupleReader) ReadPage(
+	ctx context.Context,
+	store string,
+	tk *openfgav1.TupleKey,
+	opts storage.PaginationOptions,
+) ([]*openfgav1.Tuple, []byte, error) {
+	tuples, contToken, err := m.RelationshipTupleReader.ReadPage(ctx, store, tk, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	filtered := make([]*openfgav1.Tuple, 0, len(tuples))
+	for _, t := range tuples {
+		if !m.isMasked(t.GetKey()) {
+			filtered = append(filtered, t)
+		}
+	}
+
+	return filtered, contToken, nil
+}
+
+// ReadUserTuple see [storage.RelationshipTupleReader].ReadUserTuple.
+func (m *maskedTupleReader) ReadUserTuple(
+	ctx context.Context,
+	store string,
+	tk *openfgav1.TupleKey,
+) (*openfgav1.Tuple, error) {
+	if m.isMasked(tk) {
+		return nil, storage.ErrNotFound
+	}
+
+	return m.RelationshipTupleReader.ReadUserTuple(ctx, store, tk)
+}
+
+// ReadUsersetTuples see [storage.RelationshipTupleReader].ReadUsersetTuples.
+func (m *maskedTupleReader) ReadUsersetTuples(
+	ctx context.Context,
+	store string,
+	filter storage.ReadUsersetTuplesFilter,
+) (storage.TupleIterator, error) {
+	iter, err := m.RelationshipTupleReader.ReadUsersetTuples(ctx, store, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &maskedTupleIterator{iter: iter, isMasked: m.isMasked}, nil
+}
+
+// ReadStartingWithUser see [storage.RelationshipTupleReader].ReadStartingWithUser.
+func (m *maskedTupleReader) ReadStartingWithUser(
+	ctx context.Context,
+	store string,
+	filter storage.ReadStartingWithUserFilter,
+) (storage.TupleIterator, error) {
+	iter, err := m.RelationshipTupleReader.ReadStartingWithUser(ctx, store, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &maskedTupleIterator{iter: iter, isMasked: m.isMasked}, nil
+}
+
+// maskedTupleIterator skips over the tuples of the wrapped iterator for which isMasked returns true.
+type maskedTupleIterator struct {
+	iter     storage.TupleIterator
+	isMasked func(tk *openfgav1.TupleKey) bool
+}
+
+var _ storage.TupleIterator = (*maskedTupleIterator)(nil)
+
+// Next see [storage.Iterator].Next.
+func (m *maskedTupleIterator) Next(ctx context.Context) (*openfgav1.Tuple, error) {
+	for {
+		t, err := m.iter.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if !m.isMasked(t.GetKey()) {
+			return t, nil
+		}
+	}
+}
+
+// Stop see [storage.Iterator].Stop.
+func (m *maskedTupleIterator) Stop() {
+	m.iter.Stop()
+}
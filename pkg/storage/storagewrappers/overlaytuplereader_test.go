@@ -0,0 +1,104 @@
+package storagewrappers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/tuple"
+)
+
+func TestOverlayTupleReader(t *testing.T) {
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	err := ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+		tuple.NewTupleKey("document:1", "viewer", "user:anne"),
+		tuple.NewTupleKey("document:1", "viewer", "group:eng#member"),
+	})
+	require.NoError(t, err)
+
+	reader := NewOverlayTupleReader(ds, TupleOverlay{
+		AddedTuples: []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:1", "viewer", "user:bob"),
+		},
+		RemovedTuples: []*openfgav1.TupleKeyWithoutCondition{
+			tuple.TupleKeyToTupleKeyWithoutCondition(tuple.NewTupleKey("document:1", "viewer", "user:anne")),
+			tuple.TupleKeyToTupleKeyWithoutCondition(tuple.NewTupleKey("document:1", "viewer", "group:eng#member")),
+		},
+	})
+
+	t.Run("read_user_tuple", func(t *testing.T) {
+		_, err := reader.ReadUserTuple(ctx, storeID, tuple.NewTupleKey("document:1", "viewer", "user:jon"))
+		require.NoError(t, err)
+
+		_, err = reader.ReadUserTuple(ctx, storeID, tuple.NewTupleKey("document:1", "viewer", "user:anne"))
+		require.ErrorIs(t, err, storage.ErrNotFound)
+
+		_, err = reader.ReadUserTuple(ctx, storeID, tuple.NewTupleKey("document:1", "viewer", "user:bob"))
+		require.NoError(t, err)
+	})
+
+	t.Run("read", func(t *testing.T) {
+		iter, err := reader.Read(ctx, storeID, tuple.NewTupleKey("document:1", "viewer", ""))
+		require.NoError(t, err)
+		defer iter.Stop()
+
+		var users []string
+		for {
+			tk, err := iter.Next(ctx)
+			if err != nil {
+				require.ErrorIs(t, err, storage.ErrIteratorDone)
+				break
+			}
+			users = append(users, tk.GetKey().GetUser())
+		}
+
+		require.ElementsMatch(t, []string{"user:jon", "user:bob"}, users)
+	})
+
+	t.Run("read_page", func(t *testing.T) {
+		tuples, _, err := reader.ReadPage(ctx, storeID, tuple.NewTupleKey("document:1", "viewer", ""), storage.NewPaginationOptions(50, ""))
+		require.NoError(t, err)
+		require.Len(t, tuples, 1)
+		require.Equal(t, "user:jon", tuples[0].GetKey().GetUser())
+	})
+
+	t.Run("read_userset_tuples", func(t *testing.T) {
+		iter, err := reader.ReadUsersetTuples(ctx, storeID, storage.ReadUsersetTuplesFilter{
+			Object:   "document:1",
+			Relation: "viewer",
+		})
+		require.NoError(t, err)
+		defer iter.Stop()
+
+		_, err = iter.Next(ctx)
+		require.ErrorIs(t, err, storage.ErrIteratorDone)
+	})
+
+	t.Run("read_starting_with_user", func(t *testing.T) {
+		iter, err := reader.ReadStartingWithUser(ctx, storeID, storage.ReadStartingWithUserFilter{
+			ObjectType: "document",
+			Relation:   "viewer",
+			UserFilter: []*openfgav1.ObjectRelation{{Object: "user:anne"}},
+		})
+		require.NoError(t, err)
+		defer iter.Stop()
+
+		_, err = iter.Next(ctx)
+		require.ErrorIs(t, err, storage.ErrIteratorDone)
+	})
+
+	t.Run("empty_overlay_returns_wrapped_reader", func(t *testing.T) {
+		require.Equal(t, ds, NewOverlayTupleReader(ds, TupleOverlay{}))
+	})
+}
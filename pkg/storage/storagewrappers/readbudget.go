@@ -0,0 +1,104 @@
+package storagewrappers
+
+import (
+	"context"
+	"sync/atomic"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+
+	"github.com/openfga/openfga/pkg/storage"
+)
+
+var _ storage.RelationshipTupleReader = (*ReadBudgetTupleReader)(nil)
+
+// ReadBudgetTupleReader is a wrapper over a datastore that bounds the number of reads made through it.
+type ReadBudgetTupleReader struct {
+	storage.RelationshipTupleReader
+	budget uint32
+	reads  atomic.Uint32
+}
+
+// NewReadBudgetTupleReader returns a wrapper over a datastore that allows, at most, "budget" calls to Read,
+// ReadPage, ReadUserTuple, ReadUsersetTuples and ReadStartingWithUser. The calls made after the budget is
+// consumed are not forwarded to the datastore and return a [storage.ReadBudgetExceededError].
+//
+// It is meant to be created for a single request, so that one request cannot flood the datastore with queries.
+func NewReadBudgetTupleReader(wrapped storage.RelationshipTupleReader, budget uint32) *ReadBudgetTupleReader {
+	return &ReadBudgetTupleReader{
+		RelationshipTupleReader: wrapped,
+		budget:                  budget,
+	}
+}
+
+// Reads returns the number of reads attempted through the wrapper, including the ones rejected.
+func (r *ReadBudgetTupleReader) Reads() uint32 {
+	return r.reads.Load()
+}
+
+// Read see [storage.RelationshipTupleReader].Read.
+func (r *ReadBudgetTupleReader) Read(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (storage.TupleIterator, error) {
+	if err := r.consume(); err != nil {
+		return nil, err
+	}
+
+	return r.RelationshipTupleReader.Read(ctx, store, tupleKey)
+}
+
+// ReadPage see [storage.RelationshipTupleReader].ReadPage.
+func (r *ReadBudgetTupleReader) ReadPage(
+	ctx context.Context,
+	store string,
+	tupleKey *openfgav1.TupleKey,
+	opts storage.PaginationOptions,
+) ([]*openfgav1.Tuple, []byte, error) {
+	if err := r.consume(); err != nil {
+		return nil, nil, err
+	}
+
+	return r.RelationshipTupleReader.ReadPage(ctx, store, tupleKey, opts)
+}
+
+// ReadUserTuple see [storage.RelationshipTupleReader].ReadUserTuple.
+func (r *ReadBudgetTupleReader) ReadUserTuple(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
+	if err := r.consume(); err != nil {
+		return nil, err
+	}
+
+	return r.RelationshipTupleReader.ReadUserTuple(ctx, store, tupleKey)
+}
+
+// ReadUsersetTuples see [storage.RelationshipTupleReader].ReadUsersetTuples.
+func (r *ReadBudgetTupleReader) ReadUsersetTuples(
+	ctx context.Context,
+	store string,
+	filter storage.ReadUsersetTuplesFilter,
+) (storage.TupleIterator, error) {
+	if err := r.consume(); err != nil {
+		return nil, err
+	}
+
+	return r.RelationshipTupleReader.ReadUsersetTuples(ctx, store, filter)
+}
+
+// ReadStartingWithUser see [storage.RelationshipTupleReader].ReadStartingWithUser.
+func (r *ReadBudgetTupleReader) ReadStartingWithUser(
+	ctx context.Context,
+	store string,
+	filter storage.ReadStartingWithUserFilter,
+) (storage.TupleIterator, error) {
+	if err := r.consume(); err != nil {
+		return nil, err
+	}
+
+	return r.RelationshipTupleReader.ReadStartingWithUser(ctx, store, filter)
+}
+
+// consume counts one read and returns an error if it exceeds the budget.
+func (r *ReadBudgetTupleReader) consume() error {
+	reads := r.reads.Add(1)
+	if reads > r.budget {
+		return &storage.ReadBudgetExceededError{Budget: r.budget, Reads: reads}
+	}
+
+	return nil
+}
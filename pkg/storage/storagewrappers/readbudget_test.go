@@ -0,0 +1,58 @@
+package storagewrappers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/tuple"
+)
+
+func TestReadBudgetTupleReader(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+	store := ulid.Make().String()
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	tk := tuple.NewTupleKey("obj:1", "viewer", "user:anne")
+	err := ds.Write(ctx, store, nil, []*openfgav1.TupleKey{tk})
+	require.NoError(t, err)
+
+	reader := NewReadBudgetTupleReader(ds, 3)
+
+	_, err = reader.ReadUserTuple(ctx, store, tk)
+	require.NoError(t, err)
+
+	iter, err := reader.Read(ctx, store, tuple.NewTupleKey("obj:1", "viewer", ""))
+	require.NoError(t, err)
+	iter.Stop()
+
+	_, _, err = reader.ReadPage(ctx, store, nil, storage.NewPaginationOptions(10, ""))
+	require.NoError(t, err)
+	require.Equal(t, uint32(3), reader.Reads())
+
+	_, err = reader.ReadStartingWithUser(ctx, store, storage.ReadStartingWithUserFilter{
+		ObjectType: "obj",
+		Relation:   "viewer",
+		UserFilter: []*openfgav1.ObjectRelation{{Object: "user:anne"}},
+	})
+	require.ErrorIs(t, err, storage.ErrReadBudgetExceeded)
+
+	_, err = reader.ReadUsersetTuples(ctx, store, storage.ReadUsersetTuplesFilter{Object: "obj:1", Relation: "viewer"})
+	var budgetErr *storage.ReadBudgetExceededError
+	require.ErrorAs(t, err, &budgetErr)
+	require.Equal(t, uint32(3), budgetErr.Budget)
+	require.Equal(t, uint32(5), budgetErr.Reads)
+	require.EqualError(t, err, "datastore read budget exceeded: 5 reads attempted, budget of 3")
+	require.Equal(t, uint32(5), reader.Reads())
+}
@@ -0,0 +1,116 @@
+package storagewrappers
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/tuple"
+)
+
+var _ storage.RelationshipTupleReader = (*ReadPatternRecorder)(nil)
+
+// ReadCall describes one call made to a [storage.RelationshipTupleReader].
+type ReadCall struct {
+	// Method is the name of the method called, e.g. "ReadUsersetTuples".
+	Method string
+	// Filter is a textual form of the filter the method was called with.
+	Filter string
+}
+
+// ReadPatternRecorder is a wrapper over a datastore that records the sequence of read calls made
+// through it, so that the access patterns of a single request can be analyzed. It only records the
+// calls and their filters, not the tuples they return. It is safe for concurrent use.
+type ReadPatternRecorder struct {
+	storage.RelationshipTupleReader
+
+	mu    sync.Mutex
+	reads []ReadCall
+}
+
+// NewReadPatternRecorder returns a [ReadPatternRecorder] over the wrapped datastore.
+func NewReadPatternRecorder(wrapped storage.RelationshipTupleReader) *ReadPatternRecorder {
+	return &ReadPatternRecorder{
+		RelationshipTupleReader: wrapped,
+	}
+}
+
+// Reads returns the calls recorded so far, in the order they were made.
+func (r *ReadPatternRecorder) Reads() []ReadCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	reads := make([]ReadCall, len(r.reads))
+	copy(reads, r.reads)
+	return reads
+}
+
+func (r *ReadPatternRecorder) record(method, filter string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.reads = append(r.reads, ReadCall{Method: method, Filter: filter})
+}
+
+// Read see [storage.RelationshipTupleReader].Read.
+func (r *ReadPatternRecorder) Read(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (storage.TupleIterator, error) {
+	r.record("Read", tuple.TupleKeyToString(tupleKey))
+	return r.RelationshipTupleReader.Read(ctx, store, tupleKey)
+}
+
+// ReadPage see [storage.RelationshipTupleReader].ReadPage.
+func (r *ReadPatternRecorder) ReadPage(
+	ctx context.Context,
+	store string,
+	tupleKey *openfgav1.TupleKey,
+	opts storage.PaginationOptions,
+) ([]*openfgav1.Tuple, []byte, error) {
+	r.record("ReadPage", tuple.TupleKeyToString(tupleKey))
+	return r.RelationshipTupleReader.ReadPage(ctx, store, tupleKey, opts)
+}
+
+// ReadUserTuple see [storage.RelationshipTupleReader].ReadUserTuple.
+func (r *ReadPatternRecorder) ReadUserTuple(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
+	r.record("ReadUserTuple", tuple.TupleKeyToString(tupleKey))
+	return r.RelationshipTupleReader.ReadUserTuple(ctx, store, tupleKey)
+}
+
+// ReadUsersetTuples see [storage.RelationshipTupleReader].ReadUsersetTuples.
+func (r *ReadPatternRecorder) ReadUsersetTuples(
+	ctx context.Context,
+	store string,
+	filter storage.ReadUsersetTuplesFilter,
+) (storage.TupleIterator, error) {
+	userTypes := make([]string, 0, len(filter.AllowedUserTypeRestrictions))
+	for _, ref := range filter.AllowedUserTypeRestrictions {
+		switch {
+		case ref.GetWildcard() != nil:
+			userTypes = append(userTypes, tuple.TypedPublicWildcard(ref.GetType()))
+		case ref.GetRelation() != "":
+			userTypes = append(userTypes, tuple.ToObjectRelationString(ref.GetType(), ref.GetRelation()))
+		default:
+			userTypes = append(userTypes, ref.GetType())
+		}
+	}
+
+	r.record("ReadUsersetTuples", tuple.ToObjectRelationString(filter.Object, filter.Relation)+"@"+strings.Join(userTypes, ","))
+	return r.RelationshipTupleReader.ReadUsersetTuples(ctx, store, filter)
+}
+
+// ReadStartingWithUser see [storage.RelationshipTupleReader].ReadStartingWithUser.
+func (r *ReadPatternRecorder) ReadStartingWithUser(
+	ctx context.Context,
+	store string,
+	filter storage.ReadStartingWithUserFilter,
+) (storage.TupleIterator, error) {
+	users := make([]string, 0, len(filter.UserFilter))
+	for _, user := range filter.UserFilter {
+		users = append(users, tuple.GetObjectRelationAsString(user))
+	}
+
+	r.record("ReadStartingWithUser", tuple.ToObjectRelationString(filter.ObjectType, filter.Relation)+"@"+strings.Join(users, ","))
+	return r.RelationshipTupleReader.ReadStartingWithUser(ctx, store, filter)
+}
@@ -0,0 +1,76 @@
+package storagewrappers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+)
+
+func TestReadPatternRecorder(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+	store := ulid.Make().String()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	err := ds.Write(ctx, store, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "user:anne"),
+		tuple.NewTupleKey("document:1", "viewer", "group:eng#member"),
+	})
+	require.NoError(t, err)
+
+	recorder := NewReadPatternRecorder(ds)
+
+	_, err = recorder.ReadUserTuple(ctx, store, tuple.NewTupleKey("document:1", "viewer", "user:anne"))
+	require.NoError(t, err)
+
+	iter, err := recorder.ReadUsersetTuples(ctx, store, storage.ReadUsersetTuplesFilter{
+		Object:   "document:1",
+		Relation: "viewer",
+		AllowedUserTypeRestrictions: []*openfgav1.RelationReference{
+			typesystem.DirectRelationReference("group", "member"),
+			typesystem.WildcardRelationReference("user"),
+		},
+	})
+	require.NoError(t, err)
+	iter.Stop()
+
+	iter, err = recorder.ReadStartingWithUser(ctx, store, storage.ReadStartingWithUserFilter{
+		ObjectType: "document",
+		Relation:   "viewer",
+		UserFilter: []*openfgav1.ObjectRelation{
+			{Object: "user:anne"},
+			{Object: "group:eng", Relation: "member"},
+		},
+	})
+	require.NoError(t, err)
+	iter.Stop()
+
+	iter, err = recorder.Read(ctx, store, tuple.NewTupleKey("document:1", "viewer", ""))
+	require.NoError(t, err)
+	iter.Stop()
+
+	_, _, err = recorder.ReadPage(ctx, store, tuple.NewTupleKey("document:", "", ""), storage.NewPaginationOptions(10, ""))
+	require.NoError(t, err)
+
+	require.Equal(t, []ReadCall{
+		{Method: "ReadUserTuple", Filter: "document:1#viewer@user:anne"},
+		{Method: "ReadUsersetTuples", Filter: "document:1#viewer@group#member,user:*"},
+		{Method: "ReadStartingWithUser", Filter: "document#viewer@user:anne,group:eng#member"},
+		{Method: "Read", Filter: "document:1#viewer@"},
+		{Method: "ReadPage", Filter: "document:#@"},
+	}, recorder.Reads())
+}
@@ -0,0 +1,177 @@
+package storagewrappers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/karlseguin/ccache/v3"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/openfga/openfga/internal/build"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/tuple"
+)
+
+const (
+	defaultReadStartingWithUserCacheSize = 10000
+	defaultReadStartingWithUserCacheTTL  = 10 * time.Second
+)
+
+var _ storage.RelationshipTupleReader = (*ReadStartingWithUserCache)(nil)
+
+var (
+	readStartingWithUserCacheCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: build.ProjectName,
+		Name:      "datastore_read_starting_with_user_cache_count",
+		Help:      "The total number of ReadStartingWithUser reads made through the ReadStartingWithUserCache, by whether they were served from the cache (hit) or reached the datastore (miss).",
+	}, []string{"outcome"})
+
+	readStartingWithUserCacheStaleness = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace:                       build.ProjectName,
+		Name:                            "datastore_read_starting_with_user_cache_entry_age_ms",
+		Help:                            "The age of the ReadStartingWithUser results served from the cache, i.e. how long ago they were read from the datastore.",
+		Buckets:                         []float64{1, 10, 50, 100, 500, 1000, 5000, 10000, 30000, 60000},
+		NativeHistogramBucketFactor:     1.1,
+		NativeHistogramMaxBucketNumber:  100,
+		NativeHistogramMinResetDuration: time.Hour,
+	})
+)
+
+// ReadStartingWithUserCacheOption configures a [ReadStartingWithUserCache].
+type ReadStartingWithUserCacheOption func(*ReadStartingWithUserCache)
+
+// WithReadStartingWithUserCacheTTL sets how long the results of a read are served from the cache. It defaults to
+// 10 seconds.
+func WithReadStartingWithUserCacheTTL(ttl time.Duration) ReadStartingWithUserCacheOption {
+	return func(c *ReadStartingWithUserCache) {
+		c.ttl = ttl
+	}
+}
+
+// WithReadStartingWithUserCacheMaxSize sets the maximum number of reads whose results are cached. It defaults to
+// 10000.
+func WithReadStartingWithUserCacheMaxSize(maxSize int64) ReadStartingWithUserCacheOption {
+	return func(c *ReadStartingWithUserCache) {
+		c.maxSize = maxSize
+	}
+}
+
+type readStartingWithUserCacheEntry struct {
+	tuples   []*openfgav1.Tuple
+	cachedAt time.Time
+}
+
+// ReadStartingWithUserCache is a wrapper over a datastore that caches the results of ReadStartingWithUser across
+// requests, e.g. the reads made by the reverse expansion of ListObjects, which repeats the same reads for the same
+// user, relation and type. The results of a read are read in full before being cached, and served for up to the
+// TTL of the cache, or until InvalidateStore is called for their store, which must be done after every write to
+// it. The other reads are not cached. It is safe for concurrent use.
+type ReadStartingWithUserCache struct {
+	storage.RelationshipTupleReader
+
+	cache   *ccache.Cache[*readStartingWithUserCacheEntry]
+	ttl     time.Duration
+	maxSize int64
+
+	// generations counts the invalidations of every store, so that a read that started before an invalidation of
+	// its store is not cached once it completes
+	mu          sync.Mutex
+	generations map[string]uint64
+}
+
+// NewReadStartingWithUserCache returns a [ReadStartingWithUserCache] over the wrapped datastore.
+func NewReadStartingWithUserCache(wrapped storage.RelationshipTupleReader, opts ...ReadStartingWithUserCacheOption) *ReadStartingWithUserCache {
+	c := &ReadStartingWithUserCache{
+		RelationshipTupleReader: wrapped,
+		ttl:                     defaultReadStartingWithUserCacheTTL,
+		maxSize:                 defaultReadStartingWithUserCacheSize,
+		generations:             map[string]uint64{},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	c.cache = ccache.New(ccache.Configure[*readStartingWithUserCacheEntry]().MaxSize(c.maxSize))
+
+	return c
+}
+
+// ReadStartingWithUser see [storage.RelationshipTupleReader].ReadStartingWithUser.
+func (c *ReadStartingWithUserCache) ReadStartingWithUser(
+	ctx context.Context,
+	store string,
+	filter storage.ReadStartingWithUserFilter,
+) (storage.TupleIterator, error) {
+	users := make([]string, 0, len(filter.UserFilter))
+	for _, user := range filter.UserFilter {
+		users = append(users, tuple.GetObjectRelationAsString(user))
+	}
+
+	key := store + "/" + tuple.ToObjectRelationString(filter.ObjectType, filter.Relation) + "@" + strings.Join(users, ",")
+
+	// the reads preferring higher consistency are made anew, and their results cached for the others
+	if item := c.cache.Get(key); item != nil && !item.Expired() && storage.ConsistencyFromContext(ctx) != storage.ConsistencyHigher {
+		readStartingWithUserCacheCounter.WithLabelValues("hit").Inc()
+		readStartingWithUserCacheStaleness.Observe(float64(time.Since(item.Value().cachedAt).Milliseconds()))
+		return storage.NewStaticTupleIterator(item.Value().tuples), nil
+	}
+
+	readStartingWithUserCacheCounter.WithLabelValues("miss").Inc()
+
+	generation := c.generation(store)
+	cachedAt := time.Now()
+
+	iter, err := c.RelationshipTupleReader.ReadStartingWithUser(ctx, store, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Stop()
+
+	var tuples []*openfgav1.Tuple
+	for {
+		t, err := iter.Next(ctx)
+		if err != nil {
+			if errors.Is(err, storage.ErrIteratorDone) {
+				break
+			}
+			return nil, err
+		}
+		tuples = append(tuples, t)
+	}
+
+	c.mu.Lock()
+	if c.generations[store] == generation {
+		c.cache.Set(key, &readStartingWithUserCacheEntry{tuples: tuples, cachedAt: cachedAt}, c.ttl)
+	}
+	c.mu.Unlock()
+
+	return storage.NewStaticTupleIterator(tuples), nil
+}
+
+// InvalidateStore drops the cached results of the reads of the store, so that the next reads reflect the writes
+// made to it.
+func (c *ReadStartingWithUserCache) InvalidateStore(store string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generations[store]++
+	c.cache.DeletePrefix(store + "/")
+}
+
+// Close stops the cache. It does not close the wrapped datastore.
+func (c *ReadStartingWithUserCache) Close() {
+	c.cache.Stop()
+}
+
+func (c *ReadStartingWithUserCache) generation(store string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.generations[store]
+}
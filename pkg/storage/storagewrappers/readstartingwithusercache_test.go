@@ -0,0 +1,141 @@
+package storagewrappers
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/tuple"
+)
+
+// countingTupleReader counts the ReadStartingWithUser calls reaching the wrapped datastore.
+type countingTupleReader struct {
+	storage.RelationshipTupleReader
+	reads atomic.Int64
+}
+
+func (c *countingTupleReader) ReadStartingWithUser(ctx context.Context, store string, filter storage.ReadStartingWithUserFilter) (storage.TupleIterator, error) {
+	c.reads.Add(1)
+	return c.RelationshipTupleReader.ReadStartingWithUser(ctx, store, filter)
+}
+
+func TestReadStartingWithUserCache(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	filter := storage.ReadStartingWithUserFilter{
+		ObjectType: "document",
+		Relation:   "viewer",
+		UserFilter: []*openfgav1.ObjectRelation{{Object: "user:anne"}},
+	}
+
+	readObjects := func(t *testing.T, reader storage.RelationshipTupleReader, store string) []string {
+		iter, err := reader.ReadStartingWithUser(ctx, store, filter)
+		require.NoError(t, err)
+		defer iter.Stop()
+
+		var objects []string
+		for {
+			tp, err := iter.Next(ctx)
+			if err != nil {
+				require.ErrorIs(t, err, storage.ErrIteratorDone)
+				return objects
+			}
+			objects = append(objects, tp.GetKey().GetObject())
+		}
+	}
+
+	newStore := func(t *testing.T) string {
+		store := ulid.Make().String()
+		require.NoError(t, ds.Write(ctx, store, nil, []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:1", "viewer", "user:anne"),
+		}))
+		return store
+	}
+
+	t.Run("identical_reads_are_served_from_the_cache", func(t *testing.T) {
+		store := newStore(t)
+		counter := &countingTupleReader{RelationshipTupleReader: ds}
+		cache := NewReadStartingWithUserCache(counter)
+		t.Cleanup(cache.Close)
+
+		require.Equal(t, []string{"document:1"}, readObjects(t, cache, store))
+		require.Equal(t, []string{"document:1"}, readObjects(t, cache, store))
+		require.Equal(t, int64(1), counter.reads.Load())
+
+		// a different relation is a different read
+		iter, err := cache.ReadStartingWithUser(ctx, store, storage.ReadStartingWithUserFilter{
+			ObjectType: "document",
+			Relation:   "editor",
+			UserFilter: filter.UserFilter,
+		})
+		require.NoError(t, err)
+		iter.Stop()
+		require.Equal(t, int64(2), counter.reads.Load())
+	})
+
+	t.Run("results_expire_after_the_ttl", func(t *testing.T) {
+		store := newStore(t)
+		counter := &countingTupleReader{RelationshipTupleReader: ds}
+		cache := NewReadStartingWithUserCache(counter, WithReadStartingWithUserCacheTTL(10*time.Millisecond))
+		t.Cleanup(cache.Close)
+
+		readObjects(t, cache, store)
+		time.Sleep(20 * time.Millisecond)
+		readObjects(t, cache, store)
+		require.Equal(t, int64(2), counter.reads.Load())
+	})
+
+	t.Run("invalidation_drops_the_results_of_the_store_only", func(t *testing.T) {
+		store := newStore(t)
+		otherStore := newStore(t)
+		counter := &countingTupleReader{RelationshipTupleReader: ds}
+		cache := NewReadStartingWithUserCache(counter)
+		t.Cleanup(cache.Close)
+
+		readObjects(t, cache, store)
+		readObjects(t, cache, otherStore)
+
+		require.NoError(t, ds.Write(ctx, store, nil, []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:2", "viewer", "user:anne"),
+		}))
+		require.Equal(t, []string{"document:1"}, readObjects(t, cache, store))
+
+		cache.InvalidateStore(store)
+		require.ElementsMatch(t, []string{"document:1", "document:2"}, readObjects(t, cache, store))
+		readObjects(t, cache, otherStore)
+		require.Equal(t, int64(3), counter.reads.Load())
+	})
+
+	t.Run("reads_preferring_higher_consistency_bypass_the_cache", func(t *testing.T) {
+		store := newStore(t)
+		counter := &countingTupleReader{RelationshipTupleReader: ds}
+		cache := NewReadStartingWithUserCache(counter)
+		t.Cleanup(cache.Close)
+
+		readObjects(t, cache, store)
+
+		iter, err := cache.ReadStartingWithUser(storage.ContextWithConsistency(ctx, storage.ConsistencyHigher), store, filter)
+		require.NoError(t, err)
+		iter.Stop()
+		require.Equal(t, int64(2), counter.reads.Load())
+
+		// the result of the read is cached for the others
+		readObjects(t, cache, store)
+		require.Equal(t, int64(2), counter.reads.Load())
+	})
+}
@@ -0,0 +1,202 @@
+package storagewrappers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+
+	"github.com/openfga/openfga/pkg/storage"
+)
+
+var _ storage.RelationshipTupleReader = (*timeoutTupleReader)(nil)
+
+type timeoutTupleReader struct {
+	storage.RelationshipTupleReader
+	timeout time.Duration
+}
+
+// NewTimeoutTupleReader returns a wrapper over a datastore that bounds every individual query to the given
+// timeout, independently of the deadline of the request it is made for. The query is given a context derived
+// with that timeout and, in case the wrapped datastore does not honor it, the wrapper stops waiting for the
+// query after the timeout. A query that times out returns an error wrapping [storage.ErrStorageTimeout].
+//
+// For the queries returning an iterator, the timeout also bounds the iteration.
+func NewTimeoutTupleReader(wrapped storage.RelationshipTupleReader, timeout time.Duration) *timeoutTupleReader {
+	return &timeoutTupleReader{
+		RelationshipTupleReader: wrapped,
+		timeout:                 timeout,
+	}
+}
+
+// Read see [storage.RelationshipTupleReader].Read.
+func (t *timeoutTupleReader) Read(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (storage.TupleIterator, error) {
+	return t.iteratorWithTimeout(ctx, "Read", func(queryCtx context.Context) (storage.TupleIterator, error) {
+		return t.RelationshipTupleReader.Read(queryCtx, store, tupleKey)
+	})
+}
+
+// ReadPage see [storage.RelationshipTupleReader].ReadPage.
+func (t *timeoutTupleReader) ReadPage(
+	ctx context.Context,
+	store string,
+	tupleKey *openfgav1.TupleKey,
+	opts storage.PaginationOptions,
+) ([]*openfgav1.Tuple, []byte, error) {
+	type page struct {
+		tuples []*openfgav1.Tuple
+		token  []byte
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	res, err := withTimeout(ctx, queryCtx, t.timeout, "ReadPage", func() (page, error) {
+		tuples, token, err := t.RelationshipTupleReader.ReadPage(queryCtx, store, tupleKey, opts)
+		return page{tuples: tuples, token: token}, err
+	}, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return res.tuples, res.token, nil
+}
+
+// ReadUserTuple see [storage.RelationshipTupleReader].ReadUserTuple.
+func (t *timeoutTupleReader) ReadUserTuple(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	return withTimeout(ctx, queryCtx, t.timeout, "ReadUserTuple", func() (*openfgav1.Tuple, error) {
+		return t.RelationshipTupleReader.ReadUserTuple(queryCtx, store, tupleKey)
+	}, nil)
+}
+
+// ReadUsersetTuples see [storage.RelationshipTupleReader].ReadUsersetTuples.
+func (t *timeoutTupleReader) ReadUsersetTuples(
+	ctx context.Context,
+	store string,
+	filter storage.ReadUsersetTuplesFilter,
+) (storage.TupleIterator, error) {
+	return t.iteratorWithTimeout(ctx, "ReadUsersetTuples", func(queryCtx context.Context) (storage.TupleIterator, error) {
+		return t.RelationshipTupleReader.ReadUsersetTuples(queryCtx, store, filter)
+	})
+}
+
+// ReadStartingWithUser see [storage.RelationshipTupleReader].ReadStartingWithUser.
+func (t *timeoutTupleReader) ReadStartingWithUser(
+	ctx context.Context,
+	store string,
+	filter storage.ReadStartingWithUserFilter,
+) (storage.TupleIterator, error) {
+	return t.iteratorWithTimeout(ctx, "ReadStartingWithUser", func(queryCtx context.Context) (storage.TupleIterator, error) {
+		return t.RelationshipTupleReader.ReadStartingWithUser(queryCtx, store, filter)
+	})
+}
+
+// iteratorWithTimeout runs a query returning an iterator. The context of the query is only released
+// once the iterator is stopped, because the wrapped datastore may still use it to stream the results.
+func (t *timeoutTupleReader) iteratorWithTimeout(
+	ctx context.Context,
+	method string,
+	query func(queryCtx context.Context) (storage.TupleIterator, error),
+) (storage.TupleIterator, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, t.timeout)
+
+	iter, err := withTimeout(ctx, queryCtx, t.timeout, method, func() (storage.TupleIterator, error) {
+		return query(queryCtx)
+	}, storage.TupleIterator.Stop)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &timeoutTupleIterator{
+		TupleIterator: iter,
+		parent:        ctx,
+		queryCtx:      queryCtx,
+		cancel:        cancel,
+		timeout:       t.timeout,
+		method:        method,
+	}, nil
+}
+
+// withTimeout waits for the query to return or for its context to be done, whichever happens first.
+// If the query returns a value after having been abandoned, release is called on it.
+func withTimeout[T any](
+	ctx, queryCtx context.Context,
+	timeout time.Duration,
+	method string,
+	query func() (T, error),
+	release func(T),
+) (T, error) {
+	type result struct {
+		value T
+		err   error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		value, err := query()
+		done <- result{value: value, err: err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.value, timeoutError(ctx, queryCtx, timeout, method, res.err)
+	case <-queryCtx.Done():
+		if release != nil {
+			go func() {
+				if res := <-done; res.err == nil {
+					release(res.value)
+				}
+			}()
+		}
+
+		var zero T
+		return zero, timeoutError(ctx, queryCtx, timeout, method, queryCtx.Err())
+	}
+}
+
+// timeoutError returns an error wrapping ErrStorageTimeout if the query failed after its timeout expired,
+// and err otherwise. The errors caused by the request context being done are returned as-is.
+func timeoutError(ctx, queryCtx context.Context, timeout time.Duration, method string, err error) error {
+	if err == nil || ctx.Err() != nil || !errors.Is(queryCtx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+
+	return fmt.Errorf("%w: %s exceeded %s", storage.ErrStorageTimeout, method, timeout)
+}
+
+// timeoutTupleIterator reports the per-query timeout expiring during the iteration as ErrStorageTimeout
+// and releases the context of the query when stopped.
+type timeoutTupleIterator struct {
+	storage.TupleIterator
+	parent   context.Context
+	queryCtx context.Context
+	cancel   context.CancelFunc
+	timeout  time.Duration
+	method   string
+}
+
+// Next see [storage.Iterator].Next.
+func (t *timeoutTupleIterator) Next(ctx context.Context) (*openfgav1.Tuple, error) {
+	tup, err := t.TupleIterator.Next(ctx)
+	if err != nil {
+		if errors.Is(err, storage.ErrIteratorDone) {
+			return nil, err
+		}
+
+		return nil, timeoutError(t.parent, t.queryCtx, t.timeout, t.method, err)
+	}
+
+	return tup, nil
+}
+
+// Stop see [storage.Iterator].Stop.
+func (t *timeoutTupleIterator) Stop() {
+	t.TupleIterator.Stop()
+	t.cancel()
+}
@@ -0,0 +1,113 @@
+package storagewrappers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+	"go.uber.org/mock/gomock"
+
+	"github.com/openfga/openfga/internal/mocks"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/tuple"
+)
+
+func TestTimeoutTupleReader(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	ctx := context.Background()
+	store := ulid.Make().String()
+	tk := tuple.NewTupleKey("document:1", "viewer", "user:anne")
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	err := ds.Write(ctx, store, nil, []*openfgav1.TupleKey{tk})
+	require.NoError(t, err)
+
+	t.Run("fast_queries_succeed", func(t *testing.T) {
+		reader := NewTimeoutTupleReader(mocks.NewMockSlowDataStorage(ds, 0), time.Second)
+
+		tup, err := reader.ReadUserTuple(ctx, store, tk)
+		require.NoError(t, err)
+		require.Equal(t, tk.GetUser(), tup.GetKey().GetUser())
+
+		iter, err := reader.Read(ctx, store, tuple.NewTupleKey("document:1", "viewer", ""))
+		require.NoError(t, err)
+		defer iter.Stop()
+
+		tup, err = iter.Next(ctx)
+		require.NoError(t, err)
+		require.Equal(t, tk.GetUser(), tup.GetKey().GetUser())
+
+		_, err = iter.Next(ctx)
+		require.ErrorIs(t, err, storage.ErrIteratorDone)
+	})
+
+	t.Run("slow_queries_ignoring_the_context_time_out", func(t *testing.T) {
+		delay := 200 * time.Millisecond
+		reader := NewTimeoutTupleReader(mocks.NewMockSlowDataStorage(ds, delay), 10*time.Millisecond)
+
+		start := time.Now()
+		_, err := reader.ReadUserTuple(ctx, store, tk)
+		require.ErrorIs(t, err, storage.ErrStorageTimeout)
+		require.Less(t, time.Since(start), delay)
+
+		_, err = reader.Read(ctx, store, tuple.NewTupleKey("document:1", "viewer", ""))
+		require.ErrorIs(t, err, storage.ErrStorageTimeout)
+
+		_, err = reader.ReadUsersetTuples(ctx, store, storage.ReadUsersetTuplesFilter{Object: "document:1", Relation: "viewer"})
+		require.ErrorIs(t, err, storage.ErrStorageTimeout)
+
+		_, err = reader.ReadStartingWithUser(ctx, store, storage.ReadStartingWithUserFilter{
+			ObjectType: "document",
+			Relation:   "viewer",
+			UserFilter: []*openfgav1.ObjectRelation{{Object: "user:anne"}},
+		})
+		require.ErrorIs(t, err, storage.ErrStorageTimeout)
+
+		_, _, err = reader.ReadPage(ctx, store, tuple.NewTupleKey("document:1", "viewer", ""), storage.NewPaginationOptions(10, ""))
+		require.ErrorIs(t, err, storage.ErrStorageTimeout)
+
+		// let the abandoned queries complete
+		time.Sleep(2 * delay)
+	})
+
+	t.Run("slow_queries_honoring_the_context_time_out", func(t *testing.T) {
+		mockController := gomock.NewController(t)
+		defer mockController.Finish()
+
+		mockReader := mocks.NewMockRelationshipTupleReader(mockController)
+		mockReader.EXPECT().ReadUserTuple(gomock.Any(), store, tk).DoAndReturn(
+			func(ctx context.Context, _ string, _ *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
+				_, ok := ctx.Deadline()
+				require.True(t, ok)
+
+				<-ctx.Done()
+				return nil, ctx.Err()
+			})
+
+		_, err := NewTimeoutTupleReader(mockReader, 10*time.Millisecond).ReadUserTuple(ctx, store, tk)
+		require.ErrorIs(t, err, storage.ErrStorageTimeout)
+	})
+
+	t.Run("request_context_errors_are_kept", func(t *testing.T) {
+		reader := NewTimeoutTupleReader(mocks.NewMockSlowDataStorage(ds, 100*time.Millisecond), time.Second)
+
+		cancelledCtx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		_, err := reader.ReadUserTuple(cancelledCtx, store, tk)
+		require.ErrorIs(t, err, context.Canceled)
+		require.NotErrorIs(t, err, storage.ErrStorageTimeout)
+
+		time.Sleep(200 * time.Millisecond)
+	})
+}
@@ -0,0 +1,174 @@
+package storagewrappers
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/telemetry"
+	"github.com/openfga/openfga/pkg/tuple"
+)
+
+var tracer = otel.Tracer("openfga/pkg/storage/storagewrappers")
+
+const rowsSpanAttribute = "rows"
+
+var _ storage.OpenFGADatastore = (*TracingDatastore)(nil)
+
+// TracingDatastore is a wrapper over a datastore that traces its tuple reads with a span per read, a child
+// of the span of the request making it, e.g. a Check. The spans are annotated with the store, the object type
+// and the relation the read filters on, and the number of tuples it returns. The span of a read returning an
+// iterator lasts until the iterator is stopped or exhausted, so it covers the time spent fetching the rows.
+type TracingDatastore struct {
+	storage.OpenFGADatastore
+}
+
+// NewTracingDatastore returns a [TracingDatastore] over the wrapped datastore.
+func NewTracingDatastore(wrapped storage.OpenFGADatastore) *TracingDatastore {
+	return &TracingDatastore{OpenFGADatastore: wrapped}
+}
+
+// Read see [storage.RelationshipTupleReader].Read.
+func (t *TracingDatastore) Read(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (storage.TupleIterator, error) {
+	ctx, span := startReadSpan(ctx, "Read", store, tuple.GetType(tupleKey.GetObject()), tupleKey.GetRelation())
+
+	iter, err := t.OpenFGADatastore.Read(ctx, store, tupleKey)
+	return traceIterator(span, iter, err)
+}
+
+// ReadPage see [storage.RelationshipTupleReader].ReadPage.
+func (t *TracingDatastore) ReadPage(
+	ctx context.Context,
+	store string,
+	tupleKey *openfgav1.TupleKey,
+	opts storage.PaginationOptions,
+) ([]*openfgav1.Tuple, []byte, error) {
+	ctx, span := startReadSpan(ctx, "ReadPage", store, tuple.GetType(tupleKey.GetObject()), tupleKey.GetRelation())
+	defer span.End()
+
+	tuples, contToken, err := t.OpenFGADatastore.ReadPage(ctx, store, tupleKey, opts)
+	if err != nil {
+		telemetry.TraceError(span, err)
+		return nil, nil, err
+	}
+
+	span.SetAttributes(attribute.Int(rowsSpanAttribute, len(tuples)))
+
+	return tuples, contToken, nil
+}
+
+// ReadUserTuple see [storage.RelationshipTupleReader].ReadUserTuple.
+func (t *TracingDatastore) ReadUserTuple(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
+	ctx, span := startReadSpan(ctx, "ReadUserTuple", store, tuple.GetType(tupleKey.GetObject()), tupleKey.GetRelation())
+	defer span.End()
+
+	tp, err := t.OpenFGADatastore.ReadUserTuple(ctx, store, tupleKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			span.SetAttributes(attribute.Int(rowsSpanAttribute, 0))
+		} else {
+			telemetry.TraceError(span, err)
+		}
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Int(rowsSpanAttribute, 1))
+
+	return tp, nil
+}
+
+// ReadUsersetTuples see [storage.RelationshipTupleReader].ReadUsersetTuples.
+func (t *TracingDatastore) ReadUsersetTuples(
+	ctx context.Context,
+	store string,
+	filter storage.ReadUsersetTuplesFilter,
+) (storage.TupleIterator, error) {
+	ctx, span := startReadSpan(ctx, "ReadUsersetTuples", store, tuple.GetType(filter.Object), filter.Relation)
+
+	iter, err := t.OpenFGADatastore.ReadUsersetTuples(ctx, store, filter)
+	return traceIterator(span, iter, err)
+}
+
+// ReadStartingWithUser see [storage.RelationshipTupleReader].ReadStartingWithUser.
+func (t *TracingDatastore) ReadStartingWithUser(
+	ctx context.Context,
+	store string,
+	filter storage.ReadStartingWithUserFilter,
+) (storage.TupleIterator, error) {
+	ctx, span := startReadSpan(ctx, "ReadStartingWithUser", store, filter.ObjectType, filter.Relation)
+	span.SetAttributes(attribute.Int("user_filter_count", len(filter.UserFilter)))
+
+	iter, err := t.OpenFGADatastore.ReadStartingWithUser(ctx, store, filter)
+	return traceIterator(span, iter, err)
+}
+
+func startReadSpan(ctx context.Context, method, store, objectType, relation string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "datastore."+method, trace.WithAttributes(
+		attribute.String("store_id", store),
+		attribute.String("object_type", objectType),
+		attribute.String("relation", relation),
+	))
+}
+
+// traceIterator returns an iterator ending the span once it is stopped or exhausted, or ends the span right away
+// if the read failed.
+func traceIterator(span trace.Span, iter storage.TupleIterator, err error) (storage.TupleIterator, error) {
+	if err != nil {
+		telemetry.TraceError(span, err)
+		span.End()
+		return nil, err
+	}
+
+	return &tracingTupleIterator{TupleIterator: iter, span: span}, nil
+}
+
+// tracingTupleIterator counts the tuples returned by the iterator, and records the count on the span of the read
+// when it ends it.
+type tracingTupleIterator struct {
+	storage.TupleIterator
+
+	span    trace.Span
+	mu      sync.Mutex
+	rows    int
+	endOnce sync.Once
+}
+
+// Next see [storage.Iterator].Next.
+func (t *tracingTupleIterator) Next(ctx context.Context) (*openfgav1.Tuple, error) {
+	tp, err := t.TupleIterator.Next(ctx)
+	if err != nil {
+		if errors.Is(err, storage.ErrIteratorDone) {
+			t.end()
+		}
+		return nil, err
+	}
+
+	t.mu.Lock()
+	t.rows++
+	t.mu.Unlock()
+
+	return tp, nil
+}
+
+// Stop see [storage.Iterator].Stop.
+func (t *tracingTupleIterator) Stop() {
+	t.TupleIterator.Stop()
+	t.end()
+}
+
+func (t *tracingTupleIterator) end() {
+	t.endOnce.Do(func() {
+		t.mu.Lock()
+		rows := t.rows
+		t.mu.Unlock()
+
+		t.span.SetAttributes(attribute.Int(rowsSpanAttribute, rows))
+		t.span.End()
+	})
+}
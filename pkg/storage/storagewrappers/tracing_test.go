@@ -0,0 +1,116 @@
+package storagewrappers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/goleak"
+
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/tuple"
+)
+
+func TestTracingDatastore(t *testing.T) {
+	t.Cleanup(func() {
+		goleak.VerifyNone(t)
+	})
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	ctx := context.Background()
+	store := ulid.Make().String()
+
+	mem := memory.New()
+	t.Cleanup(mem.Close)
+	require.NoError(t, mem.Write(ctx, store, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+		tuple.NewTupleKey("document:1", "viewer", "user:maria"),
+		tuple.NewTupleKey("document:1", "viewer", "group:eng#member"),
+	}))
+
+	ds := NewTracingDatastore(NewContextWrapper(mem))
+
+	// read runs the read under a caller's span, and returns the attributes of the span of the read, which must
+	// be its child
+	read := func(t *testing.T, spanName string, fn func(ctx context.Context)) map[attribute.Key]attribute.Value {
+		ctx, root := otel.Tracer("caller").Start(ctx, "caller")
+		fn(ctx)
+		root.End()
+
+		for _, span := range recorder.Ended() {
+			if span.Name() != spanName || span.SpanContext().TraceID() != root.SpanContext().TraceID() {
+				continue
+			}
+			require.Equal(t, root.SpanContext().SpanID(), span.Parent().SpanID())
+
+			attrs := map[attribute.Key]attribute.Value{}
+			for _, kv := range span.Attributes() {
+				attrs[kv.Key] = kv.Value
+			}
+			return attrs
+		}
+
+		require.FailNow(t, "span not found", spanName)
+		return nil
+	}
+
+	t.Run("iterator_rows_are_counted_until_done", func(t *testing.T) {
+		attrs := read(t, "datastore.Read", func(ctx context.Context) {
+			iter, err := ds.Read(ctx, store, tuple.NewTupleKey("document:1", "viewer", ""))
+			require.NoError(t, err)
+			defer iter.Stop()
+
+			for {
+				_, err := iter.Next(ctx)
+				if err != nil {
+					require.ErrorIs(t, err, storage.ErrIteratorDone)
+					break
+				}
+			}
+		})
+		require.Equal(t, store, attrs["store_id"].AsString())
+		require.Equal(t, "document", attrs["object_type"].AsString())
+		require.Equal(t, "viewer", attrs["relation"].AsString())
+		require.Equal(t, int64(3), attrs[rowsSpanAttribute].AsInt64())
+	})
+
+	t.Run("iterator_rows_are_counted_until_stopped", func(t *testing.T) {
+		attrs := read(t, "datastore.ReadUsersetTuples", func(ctx context.Context) {
+			iter, err := ds.ReadUsersetTuples(ctx, store, storage.ReadUsersetTuplesFilter{
+				Object:   "document:1",
+				Relation: "viewer",
+			})
+			require.NoError(t, err)
+
+			_, err = iter.Next(ctx)
+			require.NoError(t, err)
+			iter.Stop()
+		})
+		require.Equal(t, int64(1), attrs[rowsSpanAttribute].AsInt64())
+	})
+
+	t.Run("page_rows", func(t *testing.T) {
+		attrs := read(t, "datastore.ReadPage", func(ctx context.Context) {
+			_, _, err := ds.ReadPage(ctx, store, tuple.NewTupleKey("document:1", "viewer", ""), storage.NewPaginationOptions(2, ""))
+			require.NoError(t, err)
+		})
+		require.Equal(t, int64(2), attrs[rowsSpanAttribute].AsInt64())
+	})
+
+	t.Run("user_tuple_not_found", func(t *testing.T) {
+		attrs := read(t, "datastore.ReadUserTuple", func(ctx context.Context) {
+			_, err := ds.ReadUserTuple(ctx, store, tuple.NewTupleKey("document:1", "viewer", "user:bob"))
+			require.ErrorIs(t, err, storage.ErrNotFound)
+		})
+		require.Equal(t, int64(0), attrs[rowsSpanAttribute].AsInt64())
+	})
+}
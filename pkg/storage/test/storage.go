@@ -35,8 +35,12 @@ func RunAllTests(t *testing.T, ds storage.OpenFGADatastore) {
 	// Tuples.
 	t.Run("TestTupleWriteAndRead", func(t *testing.T) { TupleWritingAndReadingTest(t, ds) })
 	t.Run("TestReadChanges", func(t *testing.T) { ReadChangesTest(t, ds) })
+	t.Run("TestStoreTimeRange", func(t *testing.T) { StoreTimeRangeTest(t, ds) })
 	t.Run("TestReadStartingWithUser", func(t *testing.T) { ReadStartingWithUserTest(t, ds) })
 	t.Run("TestReadAndReadPages", func(t *testing.T) { ReadAndReadPageTest(t, ds) })
+	t.Run("TestConditionalWrite", func(t *testing.T) { ConditionalWriteTest(t, ds) })
+	t.Run("TestReadUsersetTuplesBatch", func(t *testing.T) { ReadUsersetTuplesBatchTest(t, ds) })
+	t.Run("TestStoreStats", func(t *testing.T) { StoreStatsTest(t, ds) })
 
 	// Authorization models.
 	t.Run("TestWriteAndReadAuthorizationModel", func(t *testing.T) { WriteAndReadAuthorizationModelTest(t, ds) })
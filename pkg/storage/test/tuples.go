@@ -472,6 +472,137 @@ func ReadChangesTest(t *testing.T, datastore storage.OpenFGADatastore) {
 	})
 }
 
+func StoreTimeRangeTest(t *testing.T, datastore storage.OpenFGADatastore) {
+	reader, ok := datastore.(storage.StoreTimeRangeReader)
+	if !ok {
+		t.Skip("the datastore does not support reading the time range of a store")
+	}
+
+	ctx := context.Background()
+
+	t.Run("store_without_writes_returns_not_found", func(t *testing.T) {
+		_, err := reader.StoreTimeRange(ctx, ulid.Make().String())
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("returns_earliest_and_latest_writes", func(t *testing.T) {
+		storeID := ulid.Make().String()
+
+		tk1 := tuple.NewTupleKey("document:1", "viewer", "user:jon")
+		tk2 := tuple.NewTupleKey("document:2", "viewer", "user:jon")
+		tk3 := tuple.NewTupleKey("document:3", "viewer", "user:jon")
+
+		err := datastore.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tk1})
+		require.NoError(t, err)
+		time.Sleep(2 * time.Millisecond)
+
+		err = datastore.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tk2})
+		require.NoError(t, err)
+		time.Sleep(2 * time.Millisecond)
+
+		err = datastore.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tk3})
+		require.NoError(t, err)
+		time.Sleep(2 * time.Millisecond)
+
+		// deleting a tuple is not a write and must not move the range
+		err = datastore.Write(ctx, storeID, []*openfgav1.TupleKeyWithoutCondition{tuple.TupleKeyToTupleKeyWithoutCondition(tk1)}, nil)
+		require.NoError(t, err)
+
+		changes := readChangesWithPageSize(t, datastore, storeID, 10, "")
+		require.Len(t, changes, 4)
+
+		timeRange, err := reader.StoreTimeRange(ctx, storeID)
+		require.NoError(t, err)
+
+		require.NotEmpty(t, timeRange.EarliestWriteUlid)
+		require.NotEmpty(t, timeRange.LatestWriteUlid)
+		require.Less(t, timeRange.EarliestWriteUlid, timeRange.LatestWriteUlid)
+		require.True(t, timeRange.EarliestWriteTime.Before(timeRange.LatestWriteTime))
+
+		require.WithinDuration(t, changes[0].GetTimestamp().AsTime(), timeRange.EarliestWriteTime, time.Millisecond)
+		require.WithinDuration(t, changes[2].GetTimestamp().AsTime(), timeRange.LatestWriteTime, time.Millisecond)
+	})
+}
+
+func ConditionalWriteTest(t *testing.T, datastore storage.OpenFGADatastore) {
+	writer, ok := datastore.(storage.ConditionalTupleWriter)
+	if !ok {
+		t.Skip("the datastore does not support conditional writes")
+	}
+
+	ctx := context.Background()
+
+	existing := tuple.NewTupleKeyWithCondition("document:1", "viewer", "user:jon", "condx", nil)
+	missing := tuple.NewTupleKey("document:1", "viewer", "user:bob")
+	written := tuple.NewTupleKey("document:2", "viewer", "user:jon")
+
+	setup := func(t *testing.T) string {
+		storeID := ulid.Make().String()
+		err := datastore.Write(ctx, storeID, nil, []*openfgav1.TupleKey{existing})
+		require.NoError(t, err)
+		return storeID
+	}
+
+	assertWritten := func(t *testing.T, storeID string, expected bool) {
+		_, err := datastore.ReadUserTuple(ctx, storeID, written)
+		if expected {
+			require.NoError(t, err)
+		} else {
+			require.ErrorIs(t, err, storage.ErrNotFound)
+		}
+	}
+
+	t.Run("preconditions_hold", func(t *testing.T) {
+		storeID := setup(t)
+
+		// the condition of a tuple is not part of its identity
+		err := writer.WriteWithPreconditions(ctx, storeID, storage.WritePreconditions{
+			MustExist:    []*openfgav1.TupleKeyWithoutCondition{tuple.TupleKeyToTupleKeyWithoutCondition(existing)},
+			MustNotExist: []*openfgav1.TupleKeyWithoutCondition{tuple.TupleKeyToTupleKeyWithoutCondition(missing)},
+		}, nil, []*openfgav1.TupleKey{written})
+		require.NoError(t, err)
+		assertWritten(t, storeID, true)
+	})
+
+	t.Run("tuple_which_must_exist_does_not", func(t *testing.T) {
+		storeID := setup(t)
+
+		err := writer.WriteWithPreconditions(ctx, storeID, storage.WritePreconditions{
+			MustExist: []*openfgav1.TupleKeyWithoutCondition{tuple.TupleKeyToTupleKeyWithoutCondition(missing)},
+		}, nil, []*openfgav1.TupleKey{written})
+		require.ErrorIs(t, err, storage.ErrWritePreconditionFailed)
+		require.ErrorContains(t, err, "tuple which must exist does not exist: user: 'user:bob'")
+		assertWritten(t, storeID, false)
+	})
+
+	t.Run("tuple_which_must_not_exist_does", func(t *testing.T) {
+		storeID := setup(t)
+
+		err := writer.WriteWithPreconditions(ctx, storeID, storage.WritePreconditions{
+			MustNotExist: []*openfgav1.TupleKeyWithoutCondition{tuple.TupleKeyToTupleKeyWithoutCondition(existing)},
+		}, []*openfgav1.TupleKeyWithoutCondition{tuple.TupleKeyToTupleKeyWithoutCondition(existing)}, []*openfgav1.TupleKey{written})
+		require.ErrorIs(t, err, storage.ErrWritePreconditionFailed)
+		assertWritten(t, storeID, false)
+
+		// the delete was not applied either
+		_, err = datastore.ReadUserTuple(ctx, storeID, existing)
+		require.NoError(t, err)
+
+		changes := readChangesWithPageSize(t, datastore, storeID, 10, "")
+		require.Len(t, changes, 1)
+	})
+
+	t.Run("invalid_write_is_not_applied", func(t *testing.T) {
+		storeID := setup(t)
+
+		err := writer.WriteWithPreconditions(ctx, storeID, storage.WritePreconditions{
+			MustExist: []*openfgav1.TupleKeyWithoutCondition{tuple.TupleKeyToTupleKeyWithoutCondition(existing)},
+		}, nil, []*openfgav1.TupleKey{written, existing})
+		require.ErrorIs(t, err, storage.ErrInvalidWriteInput)
+		assertWritten(t, storeID, false)
+	})
+}
+
 func TupleWritingAndReadingTest(t *testing.T, datastore storage.OpenFGADatastore) {
 	ctx := context.Background()
 
@@ -1350,6 +1481,14 @@ func ReadAndReadPageTest(t *testing.T, datastore storage.OpenFGADatastore) {
 				tuple.NewTupleKey("document:1", "writer", "user:github.com|bob@test.com"),
 			},
 		},
+		`filter_by_relation_and_objectType`: {
+			filter: tuple.NewTupleKey("document:", "writer", ""),
+			expectedTuples: []*openfgav1.TupleKey{
+				tuple.NewTupleKey("document:1", "writer", "user:github.com|bob@test.com"),
+				tuple.NewTupleKey("document:2", "writer", "user:github.com|charlie@test.com"),
+				tuple.NewTupleKey("document:1|special", "writer", "user:github.com|charlie@test.com"),
+			},
+		},
 		`filter_by_relation_and_objectID`: {
 			filter: tuple.NewTupleKey("document:1", "reader", ""),
 			expectedTuples: []*openfgav1.TupleKey{
@@ -1547,3 +1686,112 @@ func writeTuplesConcurrently(t *testing.T, store string, datastore storage.OpenF
 
 	wg.Wait()
 }
+
+func ReadUsersetTuplesBatchTest(t *testing.T, datastore storage.OpenFGADatastore) {
+	reader, ok := datastore.(storage.BatchUsersetTupleReader)
+	if !ok {
+		t.Skip("the datastore does not support batched userset reads")
+	}
+
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	err := datastore.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "group:eng#member"),
+		tuple.NewTupleKey("document:1", "viewer", "user:*"),
+		tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+		tuple.NewTupleKey("document:1", "editor", "group:eng#member"),
+		tuple.NewTupleKey("document:2", "viewer", "group:fga#member"),
+		tuple.NewTupleKey("document:3", "viewer", "group:other#member"),
+		tuple.NewTupleKey("folder:1", "viewer", "group:eng#member"),
+		tuple.NewTupleKey("folder:2", "viewer", "team:eng#member"),
+	})
+	require.NoError(t, err)
+
+	t.Run("all_userset_types", func(t *testing.T) {
+		iter, err := reader.ReadUsersetTuplesBatch(ctx, storeID, storage.ReadUsersetTuplesBatchFilter{
+			Objects:  []string{"document:1", "document:2", "folder:2"},
+			Relation: "viewer",
+		})
+		require.NoError(t, err)
+		defer iter.Stop()
+
+		expected := []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:1", "viewer", "group:eng#member"),
+			tuple.NewTupleKey("document:1", "viewer", "user:*"),
+			tuple.NewTupleKey("document:2", "viewer", "group:fga#member"),
+			tuple.NewTupleKey("folder:2", "viewer", "team:eng#member"),
+		}
+		if diff := cmp.Diff(expected, iterateThroughAllTuples(t, iter), cmpSortTupleKeys...); diff != "" {
+			t.Fatalf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("with_type_restrictions", func(t *testing.T) {
+		iter, err := reader.ReadUsersetTuplesBatch(ctx, storeID, storage.ReadUsersetTuplesBatchFilter{
+			Objects:  []string{"document:1", "document:2", "folder:1", "folder:2"},
+			Relation: "viewer",
+			AllowedUserTypeRestrictions: []*openfgav1.RelationReference{
+				typesystem.DirectRelationReference("group", "member"),
+			},
+		})
+		require.NoError(t, err)
+		defer iter.Stop()
+
+		expected := []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:1", "viewer", "group:eng#member"),
+			tuple.NewTupleKey("document:2", "viewer", "group:fga#member"),
+			tuple.NewTupleKey("folder:1", "viewer", "group:eng#member"),
+		}
+		if diff := cmp.Diff(expected, iterateThroughAllTuples(t, iter), cmpSortTupleKeys...); diff != "" {
+			t.Fatalf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func StoreStatsTest(t *testing.T, datastore storage.OpenFGADatastore) {
+	reader, ok := datastore.(storage.StoreStatsReader)
+	if !ok {
+		t.Skip("the datastore does not support the statistics of a store")
+	}
+
+	ctx := context.Background()
+
+	t.Run("empty_store", func(t *testing.T) {
+		stats, err := reader.ReadStoreStats(ctx, ulid.Make().String())
+		require.NoError(t, err)
+		require.Zero(t, stats.TupleCount)
+		require.Empty(t, stats.RelationTupleCounts)
+		require.Zero(t, stats.DailyChangeCount)
+	})
+
+	t.Run("counts_the_tuples_and_the_changes", func(t *testing.T) {
+		storeID := ulid.Make().String()
+
+		err := datastore.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:1", "viewer", "user:anne"),
+			tuple.NewTupleKey("document:1", "viewer", "user:bob"),
+			tuple.NewTupleKey("document:2", "editor", "user:anne"),
+			tuple.NewTupleKey("folder:1", "viewer", "group:eng#member"),
+		})
+		require.NoError(t, err)
+
+		err = datastore.Write(ctx, storeID, []*openfgav1.TupleKeyWithoutCondition{
+			tuple.TupleKeyToTupleKeyWithoutCondition(tuple.NewTupleKey("document:1", "viewer", "user:bob")),
+		}, nil)
+		require.NoError(t, err)
+
+		stats, err := reader.ReadStoreStats(ctx, storeID)
+		require.NoError(t, err)
+		require.Equal(t, 3, stats.TupleCount)
+		require.Equal(t, map[string]int{"document": 2, "folder": 1}, stats.TypeTupleCounts)
+		require.Equal(t, map[string]int{
+			"document#viewer": 1,
+			"document#editor": 1,
+			"folder#viewer":   1,
+		}, stats.RelationTupleCounts)
+		require.Equal(t, 5, stats.HourlyChangeCount)
+		require.Equal(t, 5, stats.DailyChangeCount)
+		require.False(t, stats.ComputedAt.IsZero())
+	})
+}
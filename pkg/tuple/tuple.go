@@ -303,6 +303,34 @@ func IsValidUser(user string) bool {
 	return false
 }
 
+// ValidateIDLength returns an *IDTooLongError if the ID of the object of the tuple is longer than maxObjectIDLength
+// bytes, or if the ID of its user (without the type and the userset relation) is longer than maxUserIDLength bytes.
+// A maximum length of 0 disables the validation of the corresponding ID.
+func ValidateIDLength(tk TupleWithoutCondition, maxObjectIDLength, maxUserIDLength int) error {
+	userObject, _ := SplitObjectRelation(tk.GetUser())
+
+	for _, field := range []struct {
+		name      string
+		object    string
+		maxLength int
+	}{
+		{name: "object", object: tk.GetObject(), maxLength: maxObjectIDLength},
+		{name: "user", object: userObject, maxLength: maxUserIDLength},
+	} {
+		_, id := SplitObject(field.object)
+		if field.maxLength > 0 && len(id) > field.maxLength {
+			return &IDTooLongError{
+				Field:     field.name,
+				Length:    len(id),
+				MaxLength: field.maxLength,
+				TupleKey:  tk,
+			}
+		}
+	}
+
+	return nil
+}
+
 // IsWildcard returns true if the string 's' could be interpreted as a typed or untyped wildcard (e.g. '*' or 'type:*').
 func IsWildcard(s string) bool {
 	return s == Wildcard || IsTypedWildcard(s)
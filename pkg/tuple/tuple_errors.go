@@ -2,6 +2,7 @@ package tuple
 
 import (
 	"fmt"
+	"strings"
 
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 )
@@ -68,3 +69,71 @@ func (i *RelationNotFoundError) Is(target error) bool {
 	_, ok := target.(*RelationNotFoundError)
 	return ok
 }
+
+// IDTooLongError is returned if the object or user ID of a tuple is longer than the maximum length.
+type IDTooLongError struct {
+	// Field is the part of the tuple whose ID is too long: 'object' or 'user'.
+	Field     string
+	Length    int
+	MaxLength int
+	TupleKey  TupleWithoutCondition
+}
+
+func (i *IDTooLongError) Error() string {
+	return fmt.Sprintf("Invalid tuple '%s'. Reason: the '%s' field ID is %d bytes long, which exceeds the maximum of %d bytes",
+		TupleKeyToString(i.TupleKey), i.Field, i.Length, i.MaxLength)
+}
+
+func (i *IDTooLongError) Is(target error) bool {
+	_, ok := target.(*IDTooLongError)
+	return ok
+}
+
+// InvalidContextualTupleError is returned if a contextual tuple of a request is invalid.
+type InvalidContextualTupleError struct {
+	// Index is the position of the tuple among the contextual tuples of the request.
+	Index    int
+	TupleKey *openfgav1.TupleKey
+	Cause    error
+}
+
+func (i *InvalidContextualTupleError) Error() string {
+	reason := i.Cause.Error()
+	switch cause := i.Cause.(type) {
+	case *InvalidTupleError:
+		reason = cause.Cause.Error()
+	case *InvalidConditionalTupleError:
+		reason = cause.Cause.Error()
+	}
+
+	return fmt.Sprintf("contextual tuple %d '%s' (type '%s', relation '%s'): %s",
+		i.Index, TupleKeyToString(i.TupleKey), GetType(i.TupleKey.GetObject()), i.TupleKey.GetRelation(), reason)
+}
+
+func (i *InvalidContextualTupleError) Unwrap() error {
+	return i.Cause
+}
+
+// InvalidContextualTuplesError is returned if some contextual tuples of a request are invalid,
+// it lists the error of each of them.
+type InvalidContextualTuplesError struct {
+	Errors []*InvalidContextualTupleError
+}
+
+func (i *InvalidContextualTuplesError) Error() string {
+	msgs := make([]string, 0, len(i.Errors))
+	for _, err := range i.Errors {
+		msgs = append(msgs, err.Error())
+	}
+
+	return fmt.Sprintf("%d invalid contextual tuples: %s", len(i.Errors), strings.Join(msgs, "; "))
+}
+
+func (i *InvalidContextualTuplesError) Unwrap() []error {
+	errs := make([]error, 0, len(i.Errors))
+	for _, err := range i.Errors {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
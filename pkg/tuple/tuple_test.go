@@ -1,6 +1,7 @@
 package tuple
 
 import (
+	"strings"
 	"testing"
 
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
@@ -324,6 +325,18 @@ func TestGetUsertypeFromUser(t *testing.T) {
 			name: "github|jon.allie@openfga",
 			want: User,
 		},
+		{
+			name: "group1#member",
+			want: User,
+		},
+		{
+			name: "group:1#",
+			want: User,
+		},
+		{
+			name: "",
+			want: User,
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			got := GetUserTypeFromUser(tc.name)
@@ -469,6 +482,67 @@ func TestTypedPublicWildcard(t *testing.T) {
 	require.Equal(t, ":*", TypedPublicWildcard("")) // Does not panic
 }
 
+func TestValidateIDLength(t *testing.T) {
+	id := func(n int) string { return strings.Repeat("a", n) }
+
+	tests := []struct {
+		name          string
+		tupleKey      *openfgav1.TupleKey
+		expectedField string
+		expectedLen   int
+	}{
+		{
+			name:     "ids_at_the_maximum_length",
+			tupleKey: NewTupleKey("document:"+id(10), "viewer", "user:"+id(8)),
+		},
+		{
+			name:     "userset_id_at_the_maximum_length",
+			tupleKey: NewTupleKey("document:1", "viewer", "group:"+id(8)+"#member"),
+		},
+		{
+			name:     "wildcard",
+			tupleKey: NewTupleKey("document:1", "viewer", "user:*"),
+		},
+		{
+			name:          "object_id_above_the_maximum_length",
+			tupleKey:      NewTupleKey("document:"+id(11), "viewer", "user:jon"),
+			expectedField: "object",
+			expectedLen:   11,
+		},
+		{
+			name:          "user_id_above_the_maximum_length",
+			tupleKey:      NewTupleKey("document:1", "viewer", "user:"+id(9)),
+			expectedField: "user",
+			expectedLen:   9,
+		},
+		{
+			name:          "userset_id_above_the_maximum_length",
+			tupleKey:      NewTupleKey("document:1", "viewer", "group:"+id(9)+"#member"),
+			expectedField: "user",
+			expectedLen:   9,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateIDLength(test.tupleKey, 10, 8)
+			if test.expectedField == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			var idTooLongErr *IDTooLongError
+			require.ErrorAs(t, err, &idTooLongErr)
+			require.Equal(t, test.expectedField, idTooLongErr.Field)
+			require.Equal(t, test.expectedLen, idTooLongErr.Length)
+		})
+	}
+
+	t.Run("validation_disabled", func(t *testing.T) {
+		require.NoError(t, ValidateIDLength(NewTupleKey("document:"+id(1000), "viewer", "user:"+id(1000)), 0, 0))
+	})
+}
+
 func TestParseTupleString(t *testing.T) {
 	tests := []struct {
 		name        string
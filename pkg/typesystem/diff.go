@@ -0,0 +1,241 @@
+package typesystem
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openfga/openfga/pkg/tuple"
+)
+
+// ModelChangeKind identifies the kind of change a ModelChange reports.
+type ModelChangeKind string
+
+const (
+	// ModelChangeTypeAdded reports a type defined by the new model only.
+	ModelChangeTypeAdded ModelChangeKind = "type_added"
+	// ModelChangeTypeRemoved reports a type defined by the old model only. The tuples of its objects can no longer be
+	// written nor checked.
+	ModelChangeTypeRemoved ModelChangeKind = "type_removed"
+	// ModelChangeRelationAdded reports a relation defined by the new model only, on a type defined by both models.
+	ModelChangeRelationAdded ModelChangeKind = "relation_added"
+	// ModelChangeRelationRemoved reports a relation defined by the old model only, on a type defined by both models.
+	ModelChangeRelationRemoved ModelChangeKind = "relation_removed"
+	// ModelChangeRewriteChanged reports a relation defined by both models with different rewrites, e.g. 'viewer: [user]'
+	// becoming 'viewer: [user] or editor'. The users having the relation with an object may change.
+	ModelChangeRewriteChanged ModelChangeKind = "rewrite_changed"
+	// ModelChangeTypeRestrictionsNarrowed reports a relation whose type restrictions no longer allow some of the
+	// users the old model allowed, e.g. 'viewer: [user, group#member]' becoming 'viewer: [user]'. The tuples relating
+	// these users can no longer be written, and the existing ones are ignored.
+	ModelChangeTypeRestrictionsNarrowed ModelChangeKind = "type_restrictions_narrowed"
+	// ModelChangeTypeRestrictionsWidened reports a relation whose type restrictions allow users the old model did not.
+	ModelChangeTypeRestrictionsWidened ModelChangeKind = "type_restrictions_widened"
+	// ModelChangeConditionAdded reports a condition defined by the new model only.
+	ModelChangeConditionAdded ModelChangeKind = "condition_added"
+	// ModelChangeConditionRemoved reports a condition defined by the old model only.
+	ModelChangeConditionRemoved ModelChangeKind = "condition_removed"
+	// ModelChangeConditionChanged reports a condition defined by both models with a different expression or
+	// different parameters.
+	ModelChangeConditionChanged ModelChangeKind = "condition_changed"
+)
+
+// ModelChange describes a difference between two models found by Diff.
+type ModelChange struct {
+	Kind ModelChangeKind
+	// ObjectType is the type the change is about, empty for the changes of conditions.
+	ObjectType string
+	// Relation is the relation the change is about, empty for the changes of types and conditions.
+	Relation string
+	// Condition is the condition the change is about, empty for the changes of types and relations.
+	Condition string
+	// TypeRestrictions are the type restrictions removed from the relation, or added to it, for the changes of
+	// type restrictions, e.g. 'user', 'user:*', 'group#member' or 'user with condition'.
+	TypeRestrictions []string
+	// Breaking tells whether the change may deny access that the old model grants, or make existing tuples
+	// invalid, so that it deserves a review before the model is written.
+	Breaking bool
+}
+
+func (c ModelChange) String() string {
+	subject := c.ObjectType
+	switch {
+	case c.Condition != "":
+		subject = "condition " + c.Condition
+	case c.Relation != "":
+		subject = tuple.ToObjectRelationString(c.ObjectType, c.Relation)
+	}
+
+	if len(c.TypeRestrictions) > 0 {
+		return fmt.Sprintf("%s: %s: %s", c.Kind, subject, strings.Join(c.TypeRestrictions, ", "))
+	}
+
+	return fmt.Sprintf("%s: %s", c.Kind, subject)
+}
+
+// Diff returns the changes from the model of the from TypeSystem to the model of the to TypeSystem, sorted by
+// type, relation, condition and kind. The from TypeSystem may be nil, in which case all the types and conditions
+// of the to model are reported added. The IDs of the models, the order of their declarations and their source
+// information are not compared.
+func Diff(from, to *TypeSystem) []ModelChange {
+	var changes []ModelChange
+
+	var oldTypes map[string]map[string]*openfgav1.Relation
+	var oldConditions map[string]*openfgav1.Condition
+	if from != nil {
+		oldTypes = from.relations
+		oldConditions = conditionDefinitions(from)
+	}
+
+	for objectType, newRelations := range to.relations {
+		oldRelations, ok := oldTypes[objectType]
+		if !ok {
+			changes = append(changes, ModelChange{Kind: ModelChangeTypeAdded, ObjectType: objectType})
+			continue
+		}
+
+		for relationName, newRelation := range newRelations {
+			oldRelation, ok := oldRelations[relationName]
+			if !ok {
+				changes = append(changes, ModelChange{Kind: ModelChangeRelationAdded, ObjectType: objectType, Relation: relationName})
+				continue
+			}
+
+			changes = append(changes, diffRelation(objectType, relationName, oldRelation, newRelation)...)
+		}
+
+		for relationName := range oldRelations {
+			if _, ok := newRelations[relationName]; !ok {
+				changes = append(changes, ModelChange{
+					Kind:       ModelChangeRelationRemoved,
+					ObjectType: objectType,
+					Relation:   relationName,
+					Breaking:   true,
+				})
+			}
+		}
+	}
+
+	for objectType := range oldTypes {
+		if _, ok := to.relations[objectType]; !ok {
+			changes = append(changes, ModelChange{Kind: ModelChangeTypeRemoved, ObjectType: objectType, Breaking: true})
+		}
+	}
+
+	newConditions := conditionDefinitions(to)
+	for name, newCondition := range newConditions {
+		oldCondition, ok := oldConditions[name]
+		switch {
+		case !ok:
+			changes = append(changes, ModelChange{Kind: ModelChangeConditionAdded, Condition: name})
+		case strings.TrimSpace(oldCondition.GetExpression()) != strings.TrimSpace(newCondition.GetExpression()) ||
+			!proto.Equal(&openfgav1.Condition{Parameters: oldCondition.GetParameters()}, &openfgav1.Condition{Parameters: newCondition.GetParameters()}):
+			changes = append(changes, ModelChange{Kind: ModelChangeConditionChanged, Condition: name, Breaking: true})
+		}
+	}
+
+	for name := range oldConditions {
+		if _, ok := newConditions[name]; !ok {
+			changes = append(changes, ModelChange{Kind: ModelChangeConditionRemoved, Condition: name, Breaking: true})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.ObjectType != b.ObjectType {
+			return a.ObjectType < b.ObjectType
+		}
+		if a.Relation != b.Relation {
+			return a.Relation < b.Relation
+		}
+		if a.Condition != b.Condition {
+			return a.Condition < b.Condition
+		}
+		return a.Kind < b.Kind
+	})
+
+	return changes
+}
+
+// diffRelation returns the changes of a relation defined by both models.
+func diffRelation(objectType, relationName string, oldRelation, newRelation *openfgav1.Relation) []ModelChange {
+	var changes []ModelChange
+
+	if !proto.Equal(oldRelation.GetRewrite(), newRelation.GetRewrite()) {
+		changes = append(changes, ModelChange{
+			Kind:       ModelChangeRewriteChanged,
+			ObjectType: objectType,
+			Relation:   relationName,
+			Breaking:   true,
+		})
+	}
+
+	oldRestrictions := typeRestrictionSet(oldRelation)
+	newRestrictions := typeRestrictionSet(newRelation)
+
+	if removed := setDifference(oldRestrictions, newRestrictions); len(removed) > 0 {
+		changes = append(changes, ModelChange{
+			Kind:             ModelChangeTypeRestrictionsNarrowed,
+			ObjectType:       objectType,
+			Relation:         relationName,
+			TypeRestrictions: removed,
+			Breaking:         true,
+		})
+	}
+
+	if added := setDifference(newRestrictions, oldRestrictions); len(added) > 0 {
+		changes = append(changes, ModelChange{
+			Kind:             ModelChangeTypeRestrictionsWidened,
+			ObjectType:       objectType,
+			Relation:         relationName,
+			TypeRestrictions: added,
+		})
+	}
+
+	return changes
+}
+
+func typeRestrictionSet(relation *openfgav1.Relation) map[string]struct{} {
+	restrictions := map[string]struct{}{}
+	for _, ref := range relation.GetTypeInfo().GetDirectlyRelatedUserTypes() {
+		restriction := ref.GetType()
+		switch {
+		case ref.GetWildcard() != nil:
+			restriction += ":*"
+		case ref.GetRelation() != "":
+			restriction += "#" + ref.GetRelation()
+		}
+
+		if ref.GetCondition() != "" {
+			restriction += " with " + ref.GetCondition()
+		}
+
+		restrictions[restriction] = struct{}{}
+	}
+
+	return restrictions
+}
+
+// setDifference returns the sorted elements of a that are not in b.
+func setDifference(a, b map[string]struct{}) []string {
+	var difference []string
+	for element := range a {
+		if _, ok := b[element]; !ok {
+			difference = append(difference, element)
+		}
+	}
+	sort.Strings(difference)
+
+	return difference
+}
+
+func conditionDefinitions(t *TypeSystem) map[string]*openfgav1.Condition {
+	conditions := make(map[string]*openfgav1.Condition, len(t.conditions))
+	for name, cond := range t.conditions {
+		conditions[name] = cond.Condition
+	}
+
+	return conditions
+}
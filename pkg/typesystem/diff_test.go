@@ -0,0 +1,122 @@
+package typesystem
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/openfga/openfga/pkg/testutils"
+)
+
+func TestDiff(t *testing.T) {
+	typesystem := func(t *testing.T, dsl string) *TypeSystem {
+		typesys, err := NewAndValidate(context.Background(), testutils.MustTransformDSLToProtoWithID(dsl))
+		require.NoError(t, err)
+		return typesys
+	}
+
+	diff := func(from, to *TypeSystem) []string {
+		var changes []string
+		for _, c := range Diff(from, to) {
+			changes = append(changes, c.String())
+		}
+		return changes
+	}
+
+	base := typesystem(t, `
+		model
+			schema 1.1
+
+		type user
+		type group
+			relations
+				define member: [user]
+		type document
+			relations
+				define owner: [user]
+				define editor: [user, group#member]
+				define viewer: [user] or editor
+
+		condition non_expired(current_time: timestamp, expires_at: timestamp) {
+			current_time < expires_at
+		}`)
+
+	t.Run("same_model_declared_in_another_order", func(t *testing.T) {
+		require.Empty(t, Diff(base, typesystem(t, `
+			model
+				schema 1.1
+
+			type document
+				relations
+					define editor: [group#member, user]
+					define owner: [user]
+					define viewer: [user] or editor
+			type group
+				relations
+					define member: [user]
+			type user
+
+			condition non_expired(current_time: timestamp, expires_at: timestamp) {
+				current_time < expires_at
+			}`)))
+	})
+
+	t.Run("changes", func(t *testing.T) {
+		changes := Diff(base, typesystem(t, `
+			model
+				schema 1.1
+
+			type user
+			type team
+				relations
+					define member: [user]
+			type document
+				relations
+					define editor: [user, team#member]
+					define viewer: [user, user:*] or editor or commenter
+					define commenter: [user]
+
+			condition non_expired(current_time: timestamp, expires_at: timestamp) {
+				current_time <= expires_at
+			}`))
+
+		var all []string
+		var breaking []string
+		for _, c := range changes {
+			all = append(all, c.String())
+			if c.Breaking {
+				breaking = append(breaking, c.String())
+			}
+		}
+
+		require.Equal(t, []string{
+			"condition_changed: condition non_expired",
+			"relation_added: document#commenter",
+			"type_restrictions_narrowed: document#editor: group#member",
+			"type_restrictions_widened: document#editor: team#member",
+			"relation_removed: document#owner",
+			"rewrite_changed: document#viewer",
+			"type_restrictions_widened: document#viewer: user:*",
+			"type_removed: group",
+			"type_added: team",
+		}, all)
+
+		require.Equal(t, []string{
+			"condition_changed: condition non_expired",
+			"type_restrictions_narrowed: document#editor: group#member",
+			"relation_removed: document#owner",
+			"rewrite_changed: document#viewer",
+			"type_removed: group",
+		}, breaking)
+	})
+
+	t.Run("first_model", func(t *testing.T) {
+		require.Equal(t, []string{
+			"condition_added: condition non_expired",
+			"type_added: document",
+			"type_added: group",
+			"type_added: user",
+		}, diff(nil, base))
+	})
+}
@@ -3,6 +3,7 @@ package typesystem
 import (
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/openfga/openfga/pkg/tuple"
 )
@@ -47,6 +48,14 @@ var (
 
 	// ErrNoConditionForRelation is returned when no condition is defined for a relation in the authorization model.
 	ErrNoConditionForRelation = errors.New("no condition defined for relation")
+
+	// ErrModelMergeConflict is returned when model fragments cannot be merged because they define
+	// the same relation or condition differently.
+	ErrModelMergeConflict = errors.New("conflicting definitions across model fragments")
+
+	// ErrInvalidSubjectType is returned, wrapped in an InvalidSubjectTypeError, when the user of a request
+	// is of a type that no rewrite of the relation can ever relate to the object.
+	ErrInvalidSubjectType = errors.New("invalid subject type")
 )
 
 // InvalidTypeError represents an error indicating an invalid object type.
@@ -155,3 +164,24 @@ func InvalidRelationTypeError(objectType, relation, relatedObjectType, relatedRe
 
 	return fmt.Errorf("the relation type '%s' on '%s' in object type '%s' is not valid", relationType, relation, objectType)
 }
+
+// InvalidSubjectTypeError describes the subject type that cannot have a relation, and the types that can.
+type InvalidSubjectTypeError struct {
+	ObjectType string
+	Relation   string
+	// SubjectType is the type of the user, e.g. 'user', 'user:*' or 'group#member'.
+	SubjectType string
+	// ExpectedTypes are the subject types that may have the relation, see [TypeSystem.SubjectTypes].
+	ExpectedTypes []string
+}
+
+// Error implements the error interface for InvalidSubjectTypeError.
+func (e *InvalidSubjectTypeError) Error() string {
+	return fmt.Sprintf("%s: '%s' cannot have the relation '%s#%s', expected one of: %s",
+		ErrInvalidSubjectType, e.SubjectType, e.ObjectType, e.Relation, strings.Join(e.ExpectedTypes, ", "))
+}
+
+// Unwrap returns ErrInvalidSubjectType.
+func (e *InvalidSubjectTypeError) Unwrap() error {
+	return ErrInvalidSubjectType
+}
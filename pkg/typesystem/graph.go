@@ -0,0 +1,74 @@
+package typesystem
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+)
+
+// RelationGraphDOT returns the relation dependency graph of the model in the GraphViz DOT language.
+// Every relation of the model is a node named 'type#relation'. A relation has an edge labeled 'computed'
+// to every relation of the same type it is computed from ('viewer'), and an edge labeled 'from tupleset' to
+// the relation it is computed from on each of the types related through the tupleset ('viewer from parent').
+// Nodes and edges are sorted, so that the same model is always exported identically.
+func (t *TypeSystem) RelationGraphDOT() string {
+	var nodes []string
+	edges := map[string]struct{}{}
+
+	for objectType, relations := range t.relations {
+		for relationName, relation := range relations {
+			source := fmt.Sprintf("%s#%s", objectType, relationName)
+			nodes = append(nodes, fmt.Sprintf("\t%q;\n", source))
+
+			_, _ = WalkUsersetRewrite(relation.GetRewrite(), func(rewrite *openfgav1.Userset) interface{} {
+				switch rw := rewrite.GetUserset().(type) {
+				case *openfgav1.Userset_ComputedUserset:
+					target := fmt.Sprintf("%s#%s", objectType, rw.ComputedUserset.GetRelation())
+					edges[fmt.Sprintf("\t%q -> %q [label=\"computed\"];\n", source, target)] = struct{}{}
+				case *openfgav1.Userset_TupleToUserset:
+					tupleset := rw.TupleToUserset.GetTupleset().GetRelation()
+					computedRelation := rw.TupleToUserset.GetComputedUserset().GetRelation()
+
+					relatedTypes, err := t.GetDirectlyRelatedUserTypes(objectType, tupleset)
+					if err != nil {
+						return nil
+					}
+
+					for _, relatedType := range relatedTypes {
+						// the computed relation only has to be defined on some of the related types
+						if _, err := t.GetRelation(relatedType.GetType(), computedRelation); err != nil {
+							continue
+						}
+
+						target := fmt.Sprintf("%s#%s", relatedType.GetType(), computedRelation)
+						edges[fmt.Sprintf("\t%q -> %q [label=\"from %s\"];\n", source, target, tupleset)] = struct{}{}
+					}
+				}
+
+				return nil
+			})
+		}
+	}
+
+	sort.Strings(nodes)
+
+	sortedEdges := make([]string, 0, len(edges))
+	for edge := range edges {
+		sortedEdges = append(sortedEdges, edge)
+	}
+	sort.Strings(sortedEdges)
+
+	var sb strings.Builder
+	sb.WriteString("digraph {\n")
+	for _, node := range nodes {
+		sb.WriteString(node)
+	}
+	for _, edge := range sortedEdges {
+		sb.WriteString(edge)
+	}
+	sb.WriteString("}\n")
+
+	return sb.String()
+}
@@ -0,0 +1,68 @@
+package typesystem
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/openfga/openfga/pkg/testutils"
+)
+
+func TestRelationGraphDOT(t *testing.T) {
+	t.Run("group_blocked_member", func(t *testing.T) {
+		typesys := New(testutils.MustTransformDSLToProtoWithID(`
+			model
+				schema 1.1
+
+			type user
+			type group
+				relations
+					define blocked: [user]
+					define member: [user] but not blocked
+					define viewer: member
+			type folder
+				relations
+					define owner: [group]
+					define viewer: member from owner or viewer from owner`))
+
+		require.Equal(t, `digraph {
+	"folder#owner";
+	"folder#viewer";
+	"group#blocked";
+	"group#member";
+	"group#viewer";
+	"folder#viewer" -> "group#member" [label="from owner"];
+	"folder#viewer" -> "group#viewer" [label="from owner"];
+	"group#member" -> "group#blocked" [label="computed"];
+	"group#viewer" -> "group#member" [label="computed"];
+}
+`, typesys.RelationGraphDOT())
+	})
+
+	t.Run("tuple_to_userset_skips_related_types_without_the_relation", func(t *testing.T) {
+		typesys := New(testutils.MustTransformDSLToProtoWithID(`
+			model
+				schema 1.1
+
+			type user
+			type organization
+				relations
+					define member: [user]
+			type team
+			type document
+				relations
+					define parent: [organization, team]
+					define viewer: member from parent
+					define editor: viewer`))
+
+		require.Equal(t, `digraph {
+	"document#editor";
+	"document#parent";
+	"document#viewer";
+	"organization#member";
+	"document#editor" -> "document#viewer" [label="computed"];
+	"document#viewer" -> "organization#member" [label="from parent"];
+}
+`, typesys.RelationGraphDOT())
+	})
+}
@@ -0,0 +1,80 @@
+package typesystem
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// ModelHash returns a deterministic hash of the semantic content of the model the TypeSystem
+// was constructed for. The hash does not depend on the model ID, on the order in which types,
+// relations, conditions or directly related user types are declared, nor on source file information
+// or whitespace surrounding condition expressions.
+// Two semantically identical models hash equally, which makes the hash suitable for cache keying
+// and for detecting model changes across environments.
+func ModelHash(t *TypeSystem) (string, error) {
+	typeNames := make([]string, 0, len(t.typeDefinitions))
+	for typeName := range t.typeDefinitions {
+		typeNames = append(typeNames, typeName)
+	}
+	sort.Strings(typeNames)
+
+	model := &openfgav1.AuthorizationModel{
+		SchemaVersion:   t.schemaVersion,
+		TypeDefinitions: make([]*openfgav1.TypeDefinition, 0, len(typeNames)),
+		Conditions:      make(map[string]*openfgav1.Condition, len(t.conditions)),
+	}
+
+	for _, typeName := range typeNames {
+		td := proto.Clone(t.typeDefinitions[typeName]).(*openfgav1.TypeDefinition)
+		if metadata := td.GetMetadata(); metadata != nil {
+			metadata.SourceInfo = nil
+			for _, relationMetadata := range metadata.GetRelations() {
+				relationMetadata.SourceInfo = nil
+				sort.Slice(relationMetadata.GetDirectlyRelatedUserTypes(), func(i, j int) bool {
+					return relationReferenceSortKey(relationMetadata.GetDirectlyRelatedUserTypes()[i]) <
+						relationReferenceSortKey(relationMetadata.GetDirectlyRelatedUserTypes()[j])
+				})
+			}
+		}
+
+		model.TypeDefinitions = append(model.TypeDefinitions, td)
+	}
+
+	for name, cond := range t.conditions {
+		c := proto.Clone(cond.Condition).(*openfgav1.Condition)
+		c.Expression = strings.TrimSpace(c.GetExpression())
+		if metadata := c.GetMetadata(); metadata != nil {
+			metadata.SourceInfo = nil
+		}
+
+		model.Conditions[name] = c
+	}
+
+	// Deterministic marshaling sorts map entries by key, which makes the output independent of
+	// the declaration order of relations and conditions.
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(model)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal authorization model: %w", err)
+	}
+
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func relationReferenceSortKey(ref *openfgav1.RelationReference) string {
+	key := ref.GetType()
+	switch {
+	case ref.GetWildcard() != nil:
+		key += ":*"
+	case ref.GetRelation() != "":
+		key += "#" + ref.GetRelation()
+	}
+
+	return key + " with " + ref.GetCondition()
+}
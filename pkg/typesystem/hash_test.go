@@ -0,0 +1,153 @@
+package typesystem
+
+import (
+	"testing"
+
+	parser "github.com/openfga/language/pkg/go/transformer"
+	"github.com/stretchr/testify/require"
+
+	"github.com/openfga/openfga/pkg/testutils"
+)
+
+func TestModelHash(t *testing.T) {
+	baseModel := `
+		model
+			schema 1.1
+
+		type user
+
+		type group
+			relations
+				define member: [user, group#member]
+
+		type document
+			relations
+				define owner: [user]
+				define editor: [user, group#member with x_less_than] or owner
+				define viewer: [user:*, group#member] or editor
+
+		condition x_less_than(x: int) {
+			x < 100
+		}`
+
+	tests := map[string]struct {
+		model        string
+		expectedSame bool
+	}{
+		`reordered_types_relations_and_directly_related_types`: {
+			model: `
+				model
+					schema 1.1
+
+				type document
+					relations
+						define viewer: [group#member, user:*] or editor
+						define owner: [user]
+						define editor: [group#member with x_less_than, user] or owner
+
+				type group
+					relations
+						define member: [group#member, user]
+
+				type user
+
+				condition x_less_than(x: int) {
+					x < 100
+				}`,
+			expectedSame: true,
+		},
+		`changed_rewrite`: {
+			model: `
+				model
+					schema 1.1
+
+				type user
+
+				type group
+					relations
+						define member: [user, group#member]
+
+				type document
+					relations
+						define owner: [user]
+						define editor: [user, group#member with x_less_than] and owner
+						define viewer: [user:*, group#member] or editor
+
+				condition x_less_than(x: int) {
+					x < 100
+				}`,
+			expectedSame: false,
+		},
+		`changed_directly_related_types`: {
+			model: `
+				model
+					schema 1.1
+
+				type user
+
+				type group
+					relations
+						define member: [user, group#member]
+
+				type document
+					relations
+						define owner: [user]
+						define editor: [user, group#member] or owner
+						define viewer: [user:*, group#member] or editor
+
+				condition x_less_than(x: int) {
+					x < 100
+				}`,
+			expectedSame: false,
+		},
+		`changed_condition`: {
+			model: `
+				model
+					schema 1.1
+
+				type user
+
+				type group
+					relations
+						define member: [user, group#member]
+
+				type document
+					relations
+						define owner: [user]
+						define editor: [user, group#member with x_less_than] or owner
+						define viewer: [user:*, group#member] or editor
+
+				condition x_less_than(x: int) {
+					x < 10
+				}`,
+			expectedSame: false,
+		},
+	}
+
+	baseHash, err := ModelHash(New(testutils.MustTransformDSLToProtoWithID(baseModel)))
+	require.NoError(t, err)
+	require.NotEmpty(t, baseHash)
+
+	t.Run("model_id_does_not_affect_hash", func(t *testing.T) {
+		hash, err := ModelHash(New(testutils.MustTransformDSLToProtoWithID(baseModel)))
+		require.NoError(t, err)
+		require.Equal(t, baseHash, hash)
+
+		hash, err = ModelHash(New(parser.MustTransformDSLToProto(baseModel)))
+		require.NoError(t, err)
+		require.Equal(t, baseHash, hash)
+	})
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			hash, err := ModelHash(New(testutils.MustTransformDSLToProtoWithID(test.model)))
+			require.NoError(t, err)
+
+			if test.expectedSame {
+				require.Equal(t, baseHash, hash)
+			} else {
+				require.NotEqual(t, baseHash, hash)
+			}
+		})
+	}
+}
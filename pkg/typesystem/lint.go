@@ -0,0 +1,447 @@
+package typesystem
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openfga/openfga/pkg/tuple"
+)
+
+// DiagnosticCode identifies the kind of issue a Diagnostic reports.
+type DiagnosticCode string
+
+const (
+	// DiagnosticUnreachableRelation reports a tuple to userset rewrite ('viewer from parent') through which some of
+	// the types related by the tupleset can never be reached, because they do not define the computed relation.
+	DiagnosticUnreachableRelation DiagnosticCode = "unreachable_relation"
+	// DiagnosticUnsatisfiableRelation reports a relation that no user can ever have with an object, e.g. because it
+	// subtracts its own base ('blocked but not blocked') or intersects relations with disjoint subject types.
+	DiagnosticUnsatisfiableRelation DiagnosticCode = "unsatisfiable_relation"
+	// DiagnosticPotentialCycle reports a relation that is part of a cycle going through other relations, e.g.
+	// 'group#member' and 'team#member' related to each other's usersets, so that tuples can relate them in a loop.
+	DiagnosticPotentialCycle DiagnosticCode = "potential_cycle"
+	// DiagnosticUnboundedRecursion reports a relation that refers to itself through usersets or tuplesets, e.g.
+	// nested groups ('define member: [user, group#member]'), so that the depth of its resolution is only bounded
+	// by the tuples, up to the resolve node limit.
+	DiagnosticUnboundedRecursion DiagnosticCode = "unbounded_recursion"
+	// DiagnosticExpensiveWildcardExclusion reports an intersection or an exclusion involving a typed wildcard, for
+	// which ListObjects and ListUsers have to check every candidate object.
+	DiagnosticExpensiveWildcardExclusion DiagnosticCode = "expensive_wildcard_exclusion"
+)
+
+// DiagnosticSeverity tells how likely a Diagnostic is to be a mistake in the model.
+type DiagnosticSeverity string
+
+const (
+	// DiagnosticError is the severity of the issues that make a relation useless.
+	DiagnosticError DiagnosticSeverity = "error"
+	// DiagnosticWarning is the severity of the issues that are likely unintended or costly.
+	DiagnosticWarning DiagnosticSeverity = "warning"
+	// DiagnosticInfo is the severity of the patterns worth knowing about, which are often intended.
+	DiagnosticInfo DiagnosticSeverity = "info"
+)
+
+// SourcePosition locates the definition of a relation in the source of a modular model, as recorded in the
+// metadata of the model. It is empty for the models that were not written from source files.
+type SourcePosition struct {
+	Module string
+	File   string
+}
+
+// Diagnostic describes an issue Lint found with a relation of the model.
+type Diagnostic struct {
+	Code       DiagnosticCode
+	Severity   DiagnosticSeverity
+	ObjectType string
+	Relation   string
+	Message    string
+	Position   SourcePosition
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s: %s", d.Severity, tuple.ToObjectRelationString(d.ObjectType, d.Relation), d.Message)
+}
+
+// Lint analyses the relations of the model beyond the validation of NewAndValidate, and returns the issues it
+// found, sorted by type, relation and code. It assumes that the model is valid.
+func (t *TypeSystem) Lint() []Diagnostic {
+	l := &linter{typesys: t, reported: map[Diagnostic]struct{}{}}
+
+	edges := l.relationEdges()
+	l.lintCycles(edges)
+	l.lintUnsatisfiable()
+	l.lintWildcards()
+
+	sort.SliceStable(l.diagnostics, func(i, j int) bool {
+		a, b := l.diagnostics[i], l.diagnostics[j]
+		if a.ObjectType != b.ObjectType {
+			return a.ObjectType < b.ObjectType
+		}
+		if a.Relation != b.Relation {
+			return a.Relation < b.Relation
+		}
+		return a.Code < b.Code
+	})
+
+	return l.diagnostics
+}
+
+type linter struct {
+	typesys     *TypeSystem
+	diagnostics []Diagnostic
+	// reported holds the diagnostics already reported, since WalkUsersetRewrite may visit a rewrite twice
+	reported map[Diagnostic]struct{}
+}
+
+func (l *linter) report(code DiagnosticCode, severity DiagnosticSeverity, objectType, relation, format string, args ...any) {
+	td, _ := l.typesys.GetTypeDefinition(objectType)
+
+	position := SourcePosition{
+		Module: td.GetMetadata().GetModule(),
+		File:   td.GetMetadata().GetSourceInfo().GetFile(),
+	}
+	// the relations of a type may be extended in other modules
+	if metadata, ok := td.GetMetadata().GetRelations()[relation]; ok && metadata.GetSourceInfo().GetFile() != "" {
+		position = SourcePosition{
+			Module: metadata.GetModule(),
+			File:   metadata.GetSourceInfo().GetFile(),
+		}
+	}
+
+	diagnostic := Diagnostic{
+		Code:       code,
+		Severity:   severity,
+		ObjectType: objectType,
+		Relation:   relation,
+		Message:    fmt.Sprintf(format, args...),
+		Position:   position,
+	}
+	if _, ok := l.reported[diagnostic]; ok {
+		return
+	}
+	l.reported[diagnostic] = struct{}{}
+
+	l.diagnostics = append(l.diagnostics, diagnostic)
+}
+
+// relationEdges returns the relations ('type#relation') each relation refers to, through its computed usersets,
+// the usersets of its type restrictions and its tuplesets. It reports the unreachable tuple to userset rewrites
+// along the way.
+func (l *linter) relationEdges() map[string][]string {
+	edges := map[string][]string{}
+
+	for objectType, relations := range l.typesys.relations {
+		for relationName, relation := range relations {
+			source := tuple.ToObjectRelationString(objectType, relationName)
+			targets := map[string]struct{}{}
+
+			_, _ = WalkUsersetRewrite(relation.GetRewrite(), func(rewrite *openfgav1.Userset) interface{} {
+				switch rw := rewrite.GetUserset().(type) {
+				case *openfgav1.Userset_This:
+					for _, ref := range relation.GetTypeInfo().GetDirectlyRelatedUserTypes() {
+						if ref.GetRelation() != "" {
+							targets[tuple.ToObjectRelationString(ref.GetType(), ref.GetRelation())] = struct{}{}
+						}
+					}
+				case *openfgav1.Userset_ComputedUserset:
+					targets[tuple.ToObjectRelationString(objectType, rw.ComputedUserset.GetRelation())] = struct{}{}
+				case *openfgav1.Userset_TupleToUserset:
+					tupleset := rw.TupleToUserset.GetTupleset().GetRelation()
+					computedRelation := rw.TupleToUserset.GetComputedUserset().GetRelation()
+
+					relatedTypes, err := l.typesys.GetDirectlyRelatedUserTypes(objectType, tupleset)
+					if err != nil {
+						return nil
+					}
+
+					for _, relatedType := range relatedTypes {
+						if _, err := l.typesys.GetRelation(relatedType.GetType(), computedRelation); err != nil {
+							l.report(DiagnosticUnreachableRelation, DiagnosticWarning, objectType, relationName,
+								"'%s from %s' never reaches the objects of type '%s' related by '%s', since '%s' is undefined",
+								computedRelation, tupleset, relatedType.GetType(), tupleset,
+								tuple.ToObjectRelationString(relatedType.GetType(), computedRelation))
+							continue
+						}
+
+						targets[tuple.ToObjectRelationString(relatedType.GetType(), computedRelation)] = struct{}{}
+					}
+				}
+
+				return nil
+			})
+
+			for target := range targets {
+				edges[source] = append(edges[source], target)
+			}
+			sort.Strings(edges[source])
+		}
+	}
+
+	return edges
+}
+
+// lintCycles reports the relations that refer to themselves, and the ones that are part of a cycle going through
+// other relations, i.e. of a strongly connected component of the relation graph with more than one relation.
+func (l *linter) lintCycles(edges map[string][]string) {
+	for _, component := range stronglyConnectedComponents(edges) {
+		if len(component) > 1 {
+			sort.Strings(component)
+			for _, node := range component {
+				objectType, relation := tuple.SplitObjectRelation(node)
+				l.report(DiagnosticPotentialCycle, DiagnosticWarning, objectType, relation,
+					"it is part of the cycle '%s', tuples may relate these relations in a loop and the depth of their resolution is unbounded",
+					strings.Join(component, "', '"))
+			}
+			continue
+		}
+
+		node := component[0]
+		for _, target := range edges[node] {
+			if target == node {
+				objectType, relation := tuple.SplitObjectRelation(node)
+				l.report(DiagnosticUnboundedRecursion, DiagnosticInfo, objectType, relation,
+					"it refers to itself, the depth of its resolution is only bounded by the tuples and the resolve node limit")
+			}
+		}
+	}
+}
+
+// stronglyConnectedComponents returns the strongly connected components of the graph, with Tarjan's algorithm.
+func stronglyConnectedComponents(edges map[string][]string) [][]string {
+	nodes := make([]string, 0, len(edges))
+	for node := range edges {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	index := map[string]int{}
+	lowlink := map[string]int{}
+	onStack := map[string]bool{}
+	var stack []string
+	var components [][]string
+
+	var visit func(node string)
+	visit = func(node string) {
+		index[node] = len(index)
+		lowlink[node] = index[node]
+		stack = append(stack, node)
+		onStack[node] = true
+
+		for _, target := range edges[node] {
+			if _, ok := index[target]; !ok {
+				visit(target)
+				lowlink[node] = min(lowlink[node], lowlink[target])
+			} else if onStack[target] {
+				lowlink[node] = min(lowlink[node], index[target])
+			}
+		}
+
+		if lowlink[node] == index[node] {
+			var component []string
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == node {
+					break
+				}
+			}
+			components = append(components, component)
+		}
+	}
+
+	for _, node := range nodes {
+		if _, ok := index[node]; !ok {
+			visit(node)
+		}
+	}
+
+	return components
+}
+
+// lintUnsatisfiable reports the relations that no user can ever have. The satisfiable relations are computed as
+// a fixpoint, starting from none, so that the relations that only depend on unsatisfiable ones are reported too.
+func (l *linter) lintUnsatisfiable() {
+	satisfiable := map[string]bool{}
+	for changed := true; changed; {
+		changed = false
+		for objectType, relations := range l.typesys.relations {
+			for relationName, relation := range relations {
+				key := tuple.ToObjectRelationString(objectType, relationName)
+				if !satisfiable[key] && l.canSatisfy(objectType, relation, relation.GetRewrite(), satisfiable) {
+					satisfiable[key] = true
+					changed = true
+				}
+			}
+		}
+	}
+
+	for objectType, relations := range l.typesys.relations {
+		for relationName, relation := range relations {
+			if satisfiable[tuple.ToObjectRelationString(objectType, relationName)] {
+				continue
+			}
+
+			reason := l.contradiction(objectType, relation, relation.GetRewrite())
+			if reason == "" {
+				reason = "it only depends on relations that can never be satisfied"
+			}
+
+			l.report(DiagnosticUnsatisfiableRelation, DiagnosticError, objectType, relationName,
+				"no user can ever have the relation, %s", reason)
+		}
+	}
+}
+
+// canSatisfy returns whether the rewrite may hold for some user, given the relations known to be satisfiable.
+func (l *linter) canSatisfy(objectType string, relation *openfgav1.Relation, rewrite *openfgav1.Userset, satisfiable map[string]bool) bool {
+	switch rw := rewrite.GetUserset().(type) {
+	case *openfgav1.Userset_This:
+		for _, ref := range relation.GetTypeInfo().GetDirectlyRelatedUserTypes() {
+			if ref.GetRelation() == "" || satisfiable[tuple.ToObjectRelationString(ref.GetType(), ref.GetRelation())] {
+				return true
+			}
+		}
+		return false
+	case *openfgav1.Userset_ComputedUserset:
+		return satisfiable[tuple.ToObjectRelationString(objectType, rw.ComputedUserset.GetRelation())]
+	case *openfgav1.Userset_TupleToUserset:
+		relatedTypes, err := l.typesys.GetDirectlyRelatedUserTypes(objectType, rw.TupleToUserset.GetTupleset().GetRelation())
+		if err != nil {
+			return false
+		}
+		for _, relatedType := range relatedTypes {
+			if satisfiable[tuple.ToObjectRelationString(relatedType.GetType(), rw.TupleToUserset.GetComputedUserset().GetRelation())] {
+				return true
+			}
+		}
+		return false
+	case *openfgav1.Userset_Union:
+		for _, child := range rw.Union.GetChild() {
+			if l.canSatisfy(objectType, relation, child, satisfiable) {
+				return true
+			}
+		}
+		return false
+	case *openfgav1.Userset_Intersection:
+		for _, child := range rw.Intersection.GetChild() {
+			if !l.canSatisfy(objectType, relation, child, satisfiable) {
+				return false
+			}
+		}
+		return !l.disjointIntersection(objectType, relation, rw.Intersection.GetChild())
+	case *openfgav1.Userset_Difference:
+		return l.canSatisfy(objectType, relation, rw.Difference.GetBase(), satisfiable) &&
+			!proto.Equal(rw.Difference.GetBase(), rw.Difference.GetSubtract())
+	default:
+		return false
+	}
+}
+
+// contradiction returns why the rewrite can never hold by itself, or an empty string if it is not contradictory.
+func (l *linter) contradiction(objectType string, relation *openfgav1.Relation, rewrite *openfgav1.Userset) string {
+	switch rw := rewrite.GetUserset().(type) {
+	case *openfgav1.Userset_Union:
+		var reasons []string
+		for _, child := range rw.Union.GetChild() {
+			reason := l.contradiction(objectType, relation, child)
+			if reason == "" {
+				return ""
+			}
+			reasons = append(reasons, reason)
+		}
+		return strings.Join(reasons, ", and ")
+	case *openfgav1.Userset_Intersection:
+		if l.disjointIntersection(objectType, relation, rw.Intersection.GetChild()) {
+			return "the sides of its intersection have no subject type in common"
+		}
+		for _, child := range rw.Intersection.GetChild() {
+			if reason := l.contradiction(objectType, relation, child); reason != "" {
+				return reason
+			}
+		}
+	case *openfgav1.Userset_Difference:
+		if proto.Equal(rw.Difference.GetBase(), rw.Difference.GetSubtract()) {
+			return "its exclusion subtracts its own base"
+		}
+		return l.contradiction(objectType, relation, rw.Difference.GetBase())
+	}
+
+	return ""
+}
+
+// disjointIntersection returns whether some sides of the intersection have no subject type in common, see
+// SubjectTypes. Since the subject types are an over-approximation, no user can then satisfy every side.
+func (l *linter) disjointIntersection(objectType string, relation *openfgav1.Relation, children []*openfgav1.Userset) bool {
+	var common map[string]struct{}
+	for _, child := range children {
+		types := map[string]struct{}{}
+		if err := l.typesys.collectRewriteSubjectTypes(objectType, relation, child, types); err != nil {
+			return false
+		}
+
+		// a typed wildcard stands for any object of its type
+		for subjectType := range types {
+			if strings.HasSuffix(subjectType, ":*") {
+				types[strings.TrimSuffix(subjectType, ":*")] = struct{}{}
+			}
+		}
+
+		if common == nil {
+			common = types
+			continue
+		}
+
+		for subjectType := range common {
+			if _, ok := types[subjectType]; !ok {
+				delete(common, subjectType)
+			}
+		}
+	}
+
+	return common != nil && len(common) == 0
+}
+
+// lintWildcards reports the intersections and exclusions a typed wildcard is involved in.
+func (l *linter) lintWildcards() {
+	for objectType, relations := range l.typesys.relations {
+		for relationName, relation := range relations {
+			_, _ = WalkUsersetRewrite(relation.GetRewrite(), func(rewrite *openfgav1.Userset) interface{} {
+				var operator string
+				var operands []*openfgav1.Userset
+				switch rw := rewrite.GetUserset().(type) {
+				case *openfgav1.Userset_Intersection:
+					operator, operands = "intersection", rw.Intersection.GetChild()
+				case *openfgav1.Userset_Difference:
+					operator, operands = "exclusion", []*openfgav1.Userset{rw.Difference.GetBase(), rw.Difference.GetSubtract()}
+				default:
+					return nil
+				}
+
+				for _, operand := range operands {
+					types := map[string]struct{}{}
+					if err := l.typesys.collectRewriteSubjectTypes(objectType, relation, operand, types); err != nil {
+						continue
+					}
+
+					for subjectType := range types {
+						if !strings.HasSuffix(subjectType, ":*") {
+							continue
+						}
+
+						l.report(DiagnosticExpensiveWildcardExclusion, DiagnosticWarning, objectType, relationName,
+							"its %s involves the typed wildcard '%s', ListObjects and ListUsers have to check every candidate object",
+							operator, subjectType)
+					}
+				}
+
+				return nil
+			})
+		}
+	}
+}
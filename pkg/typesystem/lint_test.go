@@ -0,0 +1,168 @@
+package typesystem
+
+import (
+	"context"
+	"testing"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/openfga/openfga/pkg/testutils"
+)
+
+func TestLint(t *testing.T) {
+	lint := func(t *testing.T, dsl string) []string {
+		typesys, err := NewAndValidate(context.Background(), testutils.MustTransformDSLToProtoWithID(dsl))
+		require.NoError(t, err)
+
+		var diagnostics []string
+		for _, d := range typesys.Lint() {
+			diagnostics = append(diagnostics, d.String())
+		}
+		return diagnostics
+	}
+
+	t.Run("no_issue", func(t *testing.T) {
+		require.Empty(t, lint(t, `
+			model
+				schema 1.1
+
+			type user
+			type document
+				relations
+					define owner: [user]
+					define viewer: [user] or owner`))
+	})
+
+	t.Run("unreachable_relation", func(t *testing.T) {
+		require.Equal(t, []string{
+			"warning: document#viewer: 'viewer from parent' never reaches the objects of type 'organization' related by 'parent', " +
+				"since 'organization#viewer' is undefined",
+		}, lint(t, `
+			model
+				schema 1.1
+
+			type user
+			type organization
+				relations
+					define member: [user]
+			type folder
+				relations
+					define viewer: [user]
+			type document
+				relations
+					define parent: [folder, organization]
+					define viewer: viewer from parent`))
+	})
+
+	t.Run("unsatisfiable_relation", func(t *testing.T) {
+		require.Equal(t, []string{
+			"error: document#blocked_viewer: no user can ever have the relation, its exclusion subtracts its own base",
+			"error: document#editor_viewer: no user can ever have the relation, the sides of its intersection have no subject type in common",
+			"error: document#reader: no user can ever have the relation, it only depends on relations that can never be satisfied",
+		}, lint(t, `
+			model
+				schema 1.1
+
+			type user
+			type employee
+			type document
+				relations
+					define blocked: [user]
+					define editor: [employee]
+					define viewer: [user]
+					define blocked_viewer: blocked but not blocked
+					define editor_viewer: editor and viewer
+					define reader: blocked_viewer`))
+	})
+
+	t.Run("intersection_with_common_wildcard_type_is_satisfiable", func(t *testing.T) {
+		require.Equal(t, []string{
+			"warning: document#viewer: its intersection involves the typed wildcard 'user:*', ListObjects and ListUsers have to check every candidate object",
+		}, lint(t, `
+			model
+				schema 1.1
+
+			type user
+			type document
+				relations
+					define allowed: [user:*]
+					define member: [user]
+					define viewer: allowed and member`))
+	})
+
+	t.Run("expensive_wildcard_exclusion", func(t *testing.T) {
+		require.Equal(t, []string{
+			"warning: document#viewer: its exclusion involves the typed wildcard 'user:*', ListObjects and ListUsers have to check every candidate object",
+		}, lint(t, `
+			model
+				schema 1.1
+
+			type user
+			type document
+				relations
+					define blocked: [user]
+					define viewer: [user:*] but not blocked`))
+	})
+
+	t.Run("unbounded_recursion", func(t *testing.T) {
+		require.Equal(t, []string{
+			"info: folder#viewer: it refers to itself, the depth of its resolution is only bounded by the tuples and the resolve node limit",
+			"info: group#member: it refers to itself, the depth of its resolution is only bounded by the tuples and the resolve node limit",
+		}, lint(t, `
+			model
+				schema 1.1
+
+			type user
+			type group
+				relations
+					define member: [user, group#member]
+			type folder
+				relations
+					define parent: [folder]
+					define viewer: [user] or viewer from parent`))
+	})
+
+	t.Run("potential_cycle", func(t *testing.T) {
+		const msg = "it is part of the cycle 'group#member', 'team#member', tuples may relate these relations in a loop " +
+			"and the depth of their resolution is unbounded"
+		require.Equal(t, []string{
+			"warning: group#member: " + msg,
+			"warning: team#member: " + msg,
+		}, lint(t, `
+			model
+				schema 1.1
+
+			type user
+			type group
+				relations
+					define member: [user, team#member]
+			type team
+				relations
+					define member: [user, group#member]`))
+	})
+
+	t.Run("source_position", func(t *testing.T) {
+		model := testutils.MustTransformDSLToProtoWithID(`
+			model
+				schema 1.1
+
+			type user
+			type document
+				relations
+					define blocked: [user]
+					define viewer: blocked but not blocked`)
+
+		for _, td := range model.GetTypeDefinitions() {
+			if td.GetType() == "document" {
+				td.Metadata.Module = "docs"
+				td.Metadata.SourceInfo = &openfgav1.SourceInfo{File: "docs.fga"}
+			}
+		}
+
+		diagnostics := New(model).Lint()
+		require.Len(t, diagnostics, 1)
+		require.Equal(t, DiagnosticUnsatisfiableRelation, diagnostics[0].Code)
+		require.Equal(t, SourcePosition{Module: "docs", File: "docs.fga"}, diagnostics[0].Position)
+	})
+}
@@ -0,0 +1,132 @@
+package typesystem
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// MergeModels merges model fragments, e.g. the modules an authorization model is split into, into the
+// model they compose. A type may be defined by several fragments, each one adding relations to it, like a
+// module extending a type of another module. A relation or a condition may only be defined by several
+// fragments if the definitions are identical; otherwise an error wrapping ErrModelMergeConflict is
+// returned. All the fragments must have the same schema version.
+//
+// The merged model has no ID and is not validated, see NewAndValidate.
+func MergeModels(fragments ...*openfgav1.AuthorizationModel) (*openfgav1.AuthorizationModel, error) {
+	merged := &openfgav1.AuthorizationModel{}
+	typeDefs := map[string]*openfgav1.TypeDefinition{}
+
+	for _, fragment := range fragments {
+		if merged.GetSchemaVersion() == "" {
+			merged.SchemaVersion = fragment.GetSchemaVersion()
+		} else if fragment.GetSchemaVersion() != merged.GetSchemaVersion() {
+			return nil, fmt.Errorf("%w: schema versions '%s' and '%s'", ErrModelMergeConflict,
+				merged.GetSchemaVersion(), fragment.GetSchemaVersion())
+		}
+
+		for _, td := range fragment.GetTypeDefinitions() {
+			existing, ok := typeDefs[td.GetType()]
+			if !ok {
+				existing = proto.Clone(td).(*openfgav1.TypeDefinition)
+				typeDefs[td.GetType()] = existing
+				merged.TypeDefinitions = append(merged.TypeDefinitions, existing)
+				continue
+			}
+
+			if err := mergeTypeDefinition(existing, td); err != nil {
+				return nil, err
+			}
+		}
+
+		for name, cond := range fragment.GetConditions() {
+			existing, ok := merged.GetConditions()[name]
+			if !ok {
+				if merged.Conditions == nil {
+					merged.Conditions = map[string]*openfgav1.Condition{}
+				}
+				merged.Conditions[name] = proto.Clone(cond).(*openfgav1.Condition)
+				continue
+			}
+
+			if !conditionsEqual(existing, cond) {
+				return nil, fmt.Errorf("%w: condition '%s' is defined differently", ErrModelMergeConflict, name)
+			}
+		}
+	}
+
+	return merged, nil
+}
+
+// mergeTypeDefinition adds the relations of td to the existing definition of its type.
+func mergeTypeDefinition(existing, td *openfgav1.TypeDefinition) error {
+	for relation, rewrite := range td.GetRelations() {
+		relationMetadata := td.GetMetadata().GetRelations()[relation]
+
+		if existingRewrite, ok := existing.GetRelations()[relation]; ok {
+			existingMetadata := existing.GetMetadata().GetRelations()[relation]
+			if !proto.Equal(existingRewrite, rewrite) ||
+				!relationReferencesEqual(existingMetadata.GetDirectlyRelatedUserTypes(), relationMetadata.GetDirectlyRelatedUserTypes()) {
+				return fmt.Errorf("%w: relation '%s' of type '%s' is defined differently", ErrModelMergeConflict, relation, td.GetType())
+			}
+			continue
+		}
+
+		if existing.Relations == nil {
+			existing.Relations = map[string]*openfgav1.Userset{}
+		}
+		existing.Relations[relation] = proto.Clone(rewrite).(*openfgav1.Userset)
+
+		if relationMetadata != nil {
+			if existing.Metadata == nil {
+				existing.Metadata = &openfgav1.Metadata{}
+			}
+			if existing.Metadata.Relations == nil {
+				existing.Metadata.Relations = map[string]*openfgav1.RelationMetadata{}
+			}
+			existing.Metadata.Relations[relation] = proto.Clone(relationMetadata).(*openfgav1.RelationMetadata)
+		}
+	}
+
+	return nil
+}
+
+// relationReferencesEqual reports whether both lists hold the same directly related user types, in any order.
+func relationReferencesEqual(a, b []*openfgav1.RelationReference) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	sortKeys := func(refs []*openfgav1.RelationReference) []string {
+		keys := make([]string, 0, len(refs))
+		for _, ref := range refs {
+			keys = append(keys, relationReferenceSortKey(ref))
+		}
+		sort.Strings(keys)
+		return keys
+	}
+
+	aKeys, bKeys := sortKeys(a), sortKeys(b)
+	for i := range aKeys {
+		if aKeys[i] != bKeys[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// conditionsEqual reports whether both conditions have the same definition, regardless of their metadata.
+func conditionsEqual(a, b *openfgav1.Condition) bool {
+	a = proto.Clone(a).(*openfgav1.Condition)
+	b = proto.Clone(b).(*openfgav1.Condition)
+	for _, c := range []*openfgav1.Condition{a, b} {
+		c.Expression = strings.TrimSpace(c.GetExpression())
+		c.Metadata = nil
+	}
+
+	return proto.Equal(a, b)
+}
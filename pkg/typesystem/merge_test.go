@@ -0,0 +1,125 @@
+package typesystem
+
+import (
+	"context"
+	"testing"
+
+	parser "github.com/openfga/language/pkg/go/transformer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeModels(t *testing.T) {
+	coreModule := parser.MustTransformDSLToProto(`
+		model
+			schema 1.1
+
+		type user
+
+		type group
+			relations
+				define member: [user, group#member]
+
+		type document
+			relations
+				define owner: [user]`)
+
+	t.Run("non_conflicting_modules", func(t *testing.T) {
+		sharingModule := parser.MustTransformDSLToProto(`
+			model
+				schema 1.1
+
+			type user
+
+			type document
+				relations
+					define owner: [user]
+					define viewer: [user, group#member with non_expired] or owner
+
+			condition non_expired(expires_at: timestamp, now: timestamp) {
+				now < expires_at
+			}`)
+
+		model, err := MergeModels(coreModule, sharingModule)
+		require.NoError(t, err)
+		require.Equal(t, SchemaVersion1_1, model.GetSchemaVersion())
+		require.Empty(t, model.GetId())
+		require.Contains(t, model.GetConditions(), "non_expired")
+
+		typesys, err := NewAndValidate(context.Background(), model)
+		require.NoError(t, err)
+
+		require.Len(t, typesys.GetAllRelations()["document"], 2)
+
+		directlyRelated, err := typesys.GetDirectlyRelatedUserTypes("document", "viewer")
+		require.NoError(t, err)
+		require.Len(t, directlyRelated, 2)
+
+		_, err = typesys.GetRelation("group", "member")
+		require.NoError(t, err)
+	})
+
+	t.Run("merging_does_not_modify_the_fragments", func(t *testing.T) {
+		extension := parser.MustTransformDSLToProto(`
+			model
+				schema 1.1
+
+			type document
+				relations
+					define editor: [user]`)
+
+		_, err := MergeModels(coreModule, extension)
+		require.NoError(t, err)
+
+		require.Len(t, coreModule.GetTypeDefinitions()[2].GetRelations(), 1)
+	})
+
+	t.Run("conflicting_relation", func(t *testing.T) {
+		conflicting := parser.MustTransformDSLToProto(`
+			model
+				schema 1.1
+
+			type document
+				relations
+					define owner: [user, group#member]`)
+
+		_, err := MergeModels(coreModule, conflicting)
+		require.ErrorIs(t, err, ErrModelMergeConflict)
+		require.ErrorContains(t, err, "relation 'owner' of type 'document'")
+	})
+
+	t.Run("conflicting_condition", func(t *testing.T) {
+		first := parser.MustTransformDSLToProto(`
+			model
+				schema 1.1
+
+			type user
+
+			condition in_region(region: string) {
+				region == "eu"
+			}`)
+		second := parser.MustTransformDSLToProto(`
+			model
+				schema 1.1
+
+			type user
+
+			condition in_region(region: string) {
+				region == "us"
+			}`)
+
+		_, err := MergeModels(first, second)
+		require.ErrorIs(t, err, ErrModelMergeConflict)
+		require.ErrorContains(t, err, "condition 'in_region'")
+	})
+
+	t.Run("different_schema_versions", func(t *testing.T) {
+		other := parser.MustTransformDSLToProto(`
+			model
+				schema 1.2
+
+			type team`)
+
+		_, err := MergeModels(coreModule, other)
+		require.ErrorIs(t, err, ErrModelMergeConflict)
+	})
+}
@@ -0,0 +1,269 @@
+package typesystem
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+
+	"github.com/openfga/openfga/pkg/tuple"
+)
+
+// The kinds of the nodes of a ModelGraph.
+const (
+	ModelGraphNodeType         = "type"
+	ModelGraphNodeWildcard     = "wildcard"
+	ModelGraphNodeRelation     = "relation"
+	ModelGraphNodeUnion        = "union"
+	ModelGraphNodeIntersection = "intersection"
+	ModelGraphNodeExclusion    = "exclusion"
+)
+
+// The kinds of the edges of a ModelGraph.
+const (
+	// ModelGraphEdgeRewrite links a relation or an operator to an operator it is defined with.
+	ModelGraphEdgeRewrite = "rewrite"
+
+	// ModelGraphEdgeDirect links a directly assignable relation to a type, a wildcard or a userset of its type
+	// restrictions.
+	ModelGraphEdgeDirect = "direct"
+
+	// ModelGraphEdgeComputed links a relation to the relation of the same type it is computed from ('viewer').
+	ModelGraphEdgeComputed = "computed"
+
+	// ModelGraphEdgeTupleToUserset links a relation to the relation it is computed from on one of the types
+	// related through the tupleset ('viewer from parent').
+	ModelGraphEdgeTupleToUserset = "tuple_to_userset"
+)
+
+// ModelGraphNode is a type, a wildcard, a relation or a rewrite operator of an authorization model.
+type ModelGraphNode struct {
+	// ID is the name of the type ('document'), of the wildcard ('user:*') or of the relation
+	// ('document#viewer'). The ID of an operator is the ID of its relation followed by its position in the
+	// rewrite of the relation, e.g. 'document#viewer/0' for the operator of the rewrite and 'document#viewer/0/1'
+	// for the operator of its second child.
+	ID   string `json:"id"`
+	Kind string `json:"kind"`
+
+	Type string `json:"type"`
+
+	// Relation is the relation of a relation or an operator node.
+	Relation string `json:"relation,omitempty"`
+}
+
+// ModelGraphEdge is a dependency of a relation or an operator of an authorization model.
+type ModelGraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Kind string `json:"kind"`
+
+	// Tupleset is the tupleset relation of a tuple to userset edge.
+	Tupleset string `json:"tupleset,omitempty"`
+
+	// Condition is the condition of the type restriction of a direct edge, if any.
+	Condition string `json:"condition,omitempty"`
+
+	// Subtracted is set on the edges of the subtracted side of an exclusion ('but not blocked').
+	Subtracted bool `json:"subtracted,omitempty"`
+}
+
+// ModelGraph is the structure of an authorization model, annotated with its rewrite operators and type
+// restrictions, e.g. to render it in design docs. Nodes and edges are sorted, so that the same model is
+// always exported identically.
+type ModelGraph struct {
+	Nodes []ModelGraphNode `json:"nodes"`
+	Edges []ModelGraphEdge `json:"edges"`
+}
+
+// ModelGraph returns the graph of the model. Every type and relation of the model is a node, and so is every
+// union, intersection and exclusion of the rewrites of the relations. The direct, computed and tuple to
+// userset edges of a rewrite start from the relation, or from the operator they are an operand of.
+func (t *TypeSystem) ModelGraph() *ModelGraph {
+	b := &modelGraphBuilder{
+		typesys: t,
+		nodes:   map[string]ModelGraphNode{},
+		edges:   map[ModelGraphEdge]struct{}{},
+	}
+
+	for objectType := range t.typeDefinitions {
+		b.nodes[objectType] = ModelGraphNode{ID: objectType, Kind: ModelGraphNodeType, Type: objectType}
+
+		for relationName, relation := range t.relations[objectType] {
+			id := objectType + "#" + relationName
+			b.nodes[id] = ModelGraphNode{ID: id, Kind: ModelGraphNodeRelation, Type: objectType, Relation: relationName}
+			b.addRewrite(objectType, relationName, id, id+"/0", relation.GetRewrite(), false)
+		}
+	}
+
+	graph := &ModelGraph{
+		Nodes: make([]ModelGraphNode, 0, len(b.nodes)),
+		Edges: make([]ModelGraphEdge, 0, len(b.edges)),
+	}
+	for _, node := range b.nodes {
+		graph.Nodes = append(graph.Nodes, node)
+	}
+	for edge := range b.edges {
+		graph.Edges = append(graph.Edges, edge)
+	}
+
+	slices.SortFunc(graph.Nodes, func(a, b ModelGraphNode) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	slices.SortFunc(graph.Edges, func(a, b ModelGraphEdge) int {
+		return cmp.Or(
+			strings.Compare(a.From, b.From),
+			strings.Compare(a.To, b.To),
+			strings.Compare(a.Kind, b.Kind),
+			strings.Compare(a.Tupleset, b.Tupleset),
+			strings.Compare(a.Condition, b.Condition),
+			compareBool(a.Subtracted, b.Subtracted),
+		)
+	})
+
+	return graph
+}
+
+// DOT returns the graph in the GraphViz DOT language. Types and wildcards are boxes, relations are ellipses and
+// operators are diamonds; the edges are labeled with their kind, their tupleset and their condition.
+func (g *ModelGraph) DOT() string {
+	var sb strings.Builder
+	sb.WriteString("digraph {\n")
+
+	for _, node := range g.Nodes {
+		switch node.Kind {
+		case ModelGraphNodeType, ModelGraphNodeWildcard:
+			fmt.Fprintf(&sb, "\t%q [shape=box];\n", node.ID)
+		case ModelGraphNodeUnion, ModelGraphNodeIntersection, ModelGraphNodeExclusion:
+			fmt.Fprintf(&sb, "\t%q [label=%q, shape=diamond];\n", node.ID, node.Kind)
+		default:
+			fmt.Fprintf(&sb, "\t%q;\n", node.ID)
+		}
+	}
+
+	for _, edge := range g.Edges {
+		var label string
+		switch edge.Kind {
+		case ModelGraphEdgeDirect:
+			label = "direct"
+			if edge.Condition != "" {
+				label += " with " + edge.Condition
+			}
+		case ModelGraphEdgeComputed:
+			label = "computed"
+		case ModelGraphEdgeTupleToUserset:
+			label = "from " + edge.Tupleset
+		}
+
+		if edge.Subtracted {
+			label = strings.TrimSpace("but not " + label)
+		}
+
+		if label == "" {
+			fmt.Fprintf(&sb, "\t%q -> %q;\n", edge.From, edge.To)
+		} else {
+			fmt.Fprintf(&sb, "\t%q -> %q [label=%q];\n", edge.From, edge.To, label)
+		}
+	}
+
+	sb.WriteString("}\n")
+
+	return sb.String()
+}
+
+type modelGraphBuilder struct {
+	typesys *TypeSystem
+	nodes   map[string]ModelGraphNode
+	edges   map[ModelGraphEdge]struct{}
+}
+
+// addRewrite adds the edges of the rewrite of the relation starting from the node from. id is the ID of the
+// node of the rewrite if it is an operator.
+func (b *modelGraphBuilder) addRewrite(objectType, relation, from, id string, rewrite *openfgav1.Userset, subtracted bool) {
+	switch rw := rewrite.GetUserset().(type) {
+	case *openfgav1.Userset_This:
+		relatedTypes, err := b.typesys.GetDirectlyRelatedUserTypes(objectType, relation)
+		if err != nil {
+			return
+		}
+
+		for _, relatedType := range relatedTypes {
+			to := relatedType.GetType()
+			switch {
+			case relatedType.GetWildcard() != nil:
+				to = tuple.TypedPublicWildcard(relatedType.GetType())
+				b.nodes[to] = ModelGraphNode{ID: to, Kind: ModelGraphNodeWildcard, Type: relatedType.GetType()}
+			case relatedType.GetRelation() != "":
+				to = relatedType.GetType() + "#" + relatedType.GetRelation()
+			}
+
+			b.edges[ModelGraphEdge{
+				From:       from,
+				To:         to,
+				Kind:       ModelGraphEdgeDirect,
+				Condition:  relatedType.GetCondition(),
+				Subtracted: subtracted,
+			}] = struct{}{}
+		}
+	case *openfgav1.Userset_ComputedUserset:
+		b.edges[ModelGraphEdge{
+			From:       from,
+			To:         objectType + "#" + rw.ComputedUserset.GetRelation(),
+			Kind:       ModelGraphEdgeComputed,
+			Subtracted: subtracted,
+		}] = struct{}{}
+	case *openfgav1.Userset_TupleToUserset:
+		tupleset := rw.TupleToUserset.GetTupleset().GetRelation()
+		computedRelation := rw.TupleToUserset.GetComputedUserset().GetRelation()
+
+		relatedTypes, err := b.typesys.GetDirectlyRelatedUserTypes(objectType, tupleset)
+		if err != nil {
+			return
+		}
+
+		for _, relatedType := range relatedTypes {
+			// the computed relation only has to be defined on some of the related types
+			if _, err := b.typesys.GetRelation(relatedType.GetType(), computedRelation); err != nil {
+				continue
+			}
+
+			b.edges[ModelGraphEdge{
+				From:       from,
+				To:         relatedType.GetType() + "#" + computedRelation,
+				Kind:       ModelGraphEdgeTupleToUserset,
+				Tupleset:   tupleset,
+				Subtracted: subtracted,
+			}] = struct{}{}
+		}
+	case *openfgav1.Userset_Union:
+		b.addOperator(objectType, relation, from, id, ModelGraphNodeUnion, subtracted, rw.Union.GetChild())
+	case *openfgav1.Userset_Intersection:
+		b.addOperator(objectType, relation, from, id, ModelGraphNodeIntersection, subtracted, rw.Intersection.GetChild())
+	case *openfgav1.Userset_Difference:
+		b.addOperator(objectType, relation, from, id, ModelGraphNodeExclusion, subtracted, []*openfgav1.Userset{rw.Difference.GetBase()})
+		b.addRewrite(objectType, relation, id, id+"/1", rw.Difference.GetSubtract(), true)
+	}
+}
+
+// addOperator adds the node of the operator, linked from the node from, and the edges of its children.
+func (b *modelGraphBuilder) addOperator(objectType, relation, from, id, kind string, subtracted bool, children []*openfgav1.Userset) {
+	b.nodes[id] = ModelGraphNode{ID: id, Kind: kind, Type: objectType, Relation: relation}
+	b.edges[ModelGraphEdge{From: from, To: id, Kind: ModelGraphEdgeRewrite, Subtracted: subtracted}] = struct{}{}
+
+	for i, child := range children {
+		b.addRewrite(objectType, relation, id, id+"/"+strconv.Itoa(i), child, false)
+	}
+}
+
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	default:
+		return -1
+	}
+}
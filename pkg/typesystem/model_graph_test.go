@@ -0,0 +1,62 @@
+package typesystem
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/openfga/openfga/pkg/testutils"
+)
+
+func TestModelGraph(t *testing.T) {
+	typesys := New(testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type group
+			relations
+				define blocked: [user]
+				define member: [user, user:*, group#member with non_expired] but not blocked
+		type folder
+			relations
+				define owner: [group]
+				define viewer: member from owner or (owner and viewer from owner)
+
+		condition non_expired(expired: bool) {
+			!expired
+		}`))
+
+	graph := typesys.ModelGraph()
+
+	require.Contains(t, graph.Nodes, ModelGraphNode{ID: "folder#viewer/0/1", Kind: ModelGraphNodeIntersection, Type: "folder", Relation: "viewer"})
+	require.Contains(t, graph.Edges, ModelGraphEdge{From: "group#member/0", To: "group#blocked", Kind: ModelGraphEdgeComputed, Subtracted: true})
+	require.Contains(t, graph.Edges, ModelGraphEdge{From: "folder#viewer/0", To: "group#member", Kind: ModelGraphEdgeTupleToUserset, Tupleset: "owner"})
+
+	// group has no viewer relation, so 'viewer from owner' has no edge
+	require.Equal(t, `digraph {
+	"folder" [shape=box];
+	"folder#owner";
+	"folder#viewer";
+	"folder#viewer/0" [label="union", shape=diamond];
+	"folder#viewer/0/1" [label="intersection", shape=diamond];
+	"group" [shape=box];
+	"group#blocked";
+	"group#member";
+	"group#member/0" [label="exclusion", shape=diamond];
+	"user" [shape=box];
+	"user:*" [shape=box];
+	"folder#owner" -> "group" [label="direct"];
+	"folder#viewer" -> "folder#viewer/0";
+	"folder#viewer/0" -> "folder#viewer/0/1";
+	"folder#viewer/0" -> "group#member" [label="from owner"];
+	"folder#viewer/0/1" -> "folder#owner" [label="computed"];
+	"group#blocked" -> "user" [label="direct"];
+	"group#member" -> "group#member/0";
+	"group#member/0" -> "group#blocked" [label="but not computed"];
+	"group#member/0" -> "group#member" [label="direct with non_expired"];
+	"group#member/0" -> "user" [label="direct"];
+	"group#member/0" -> "user:*" [label="direct"];
+}
+`, graph.DOT())
+}
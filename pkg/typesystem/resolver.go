@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/google/cel-go/cel"
 	"github.com/karlseguin/ccache/v3"
 	"github.com/oklog/ulid/v2"
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
@@ -22,17 +23,61 @@ const (
 // can use to provide lookup and resolution of a Typesystem.
 type TypesystemResolverFunc func(ctx context.Context, storeID, modelID string) (*TypeSystem, error)
 
+// ResolverOption configures the TypesystemResolverFunc returned by MemoizedTypesystemResolverFunc.
+type ResolverOption func(*resolverOptions)
+
+type resolverOptions struct {
+	storeConditionEnv  func(storeID string) (*cel.Env, error)
+	modelHashCacheSize int64
+}
+
+// WithStoreConditionEnv makes the resolver compile the conditions of the models of a store in the
+// CEL environment returned for the store, e.g. by [condition.StoreEnvs.Get].
+func WithStoreConditionEnv(env func(storeID string) (*cel.Env, error)) ResolverOption {
+	return func(o *resolverOptions) {
+		o.storeConditionEnv = env
+	}
+}
+
+// WithModelHashCache makes the resolver share the TypeSystem of semantically identical models, see ModelHash,
+// across stores and model IDs, so that such models are validated and their conditions compiled only once. At most
+// size TypeSystems are shared, evicting the least recently used ones. The TypeSystems of stores with their own
+// condition environment, see WithStoreConditionEnv, are never shared.
+func WithModelHashCache(size int64) ResolverOption {
+	return func(o *resolverOptions) {
+		o.modelHashCacheSize = size
+	}
+}
+
 // MemoizedTypesystemResolverFunc returns a TypesystemResolverFunc that fetches the provided authorization
 // model (if provided) or looks up the latest authorization model. It then constructs a TypeSystem from
 // the resolved model, and memoizes the type-system resolution. If another lookup of the same model occurs,
 // the earlier constructed TypeSystem will be used.
 //
 // The memoized resolver function is designed for concurrent use.
-func MemoizedTypesystemResolverFunc(datastore storage.AuthorizationModelReadBackend) (TypesystemResolverFunc, func()) {
+func MemoizedTypesystemResolverFunc(datastore storage.AuthorizationModelReadBackend, opts ...ResolverOption) (TypesystemResolverFunc, func()) {
+	var options resolverOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	lookupGroup := singleflight.Group{}
 
 	cache := ccache.New(ccache.Configure[*TypeSystem]())
 
+	// [model hash] => TypeSystem shared by the identical models
+	var hashCache *ccache.Cache[*TypeSystem]
+	if options.modelHashCacheSize > 0 && options.storeConditionEnv == nil {
+		hashCache = ccache.New(ccache.Configure[*TypeSystem]().MaxSize(options.modelHashCacheSize))
+	}
+
+	stop := func() {
+		cache.Stop()
+		if hashCache != nil {
+			hashCache.Stop()
+		}
+	}
+
 	return func(ctx context.Context, storeID, modelID string) (*TypeSystem, error) {
 		ctx, span := tracer.Start(ctx, "MemoizedTypesystemResolverFunc")
 		defer span.End()
@@ -82,13 +127,40 @@ func MemoizedTypesystemResolverFunc(datastore storage.AuthorizationModelReadBack
 
 		model := v.(*openfgav1.AuthorizationModel)
 
-		typesys, err := NewAndValidate(ctx, model)
+		var hash string
+		if hashCache != nil {
+			hash, err = ModelHash(New(model))
+			if err != nil {
+				return nil, err
+			}
+
+			if item := hashCache.Get(hash); item != nil {
+				typesys := item.Value().withAuthorizationModelID(model.GetId())
+				cache.Set(key, typesys, typesystemCacheTTL)
+				return typesys, nil
+			}
+		}
+
+		var typesysOpts []TypeSystemOption
+		if options.storeConditionEnv != nil {
+			env, err := options.storeConditionEnv(storeID)
+			if err != nil {
+				return nil, err
+			}
+
+			typesysOpts = append(typesysOpts, WithConditionEnv(env))
+		}
+
+		typesys, err := NewAndValidate(ctx, model, typesysOpts...)
 		if err != nil {
 			return nil, fmt.Errorf("%w: %v", ErrInvalidModel, err)
 		}
 
 		cache.Set(key, typesys, typesystemCacheTTL)
+		if hashCache != nil {
+			hashCache.Set(hash, typesys, typesystemCacheTTL)
+		}
 
 		return typesys, nil
-	}, cache.Stop
+	}, stop
 }
@@ -115,3 +115,83 @@ func TestSingleFlightMemoizedTypesystemResolverFunc(t *testing.T) {
 	err := wg.Wait()
 	require.NoError(t, err)
 }
+
+func TestMemoizedTypesystemResolverFuncWithModelHashCache(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mockDatastore := mockstorage.NewMockOpenFGADatastore(mockController)
+
+	model := parser.MustTransformDSLToProto(`
+		model
+			schema 1.1
+		type user
+		type document
+			relations
+				define viewer: [user with non_expired]
+
+		condition non_expired(expires: timestamp, now: timestamp) {
+			now < expires
+		}`)
+	otherModel := parser.MustTransformDSLToProto(`
+		model
+			schema 1.1
+		type user
+		type folder
+			relations
+				define viewer: [user]`)
+
+	withID := func(model *openfgav1.AuthorizationModel, id string) *openfgav1.AuthorizationModel {
+		return &openfgav1.AuthorizationModel{
+			Id:              id,
+			SchemaVersion:   model.GetSchemaVersion(),
+			TypeDefinitions: model.GetTypeDefinitions(),
+			Conditions:      model.GetConditions(),
+		}
+	}
+
+	// store1 and store2 were written the same model, store3 an identical model, and store4 another model
+	sharedModelID := ulid.Make().String()
+	storeModels := map[string]*openfgav1.AuthorizationModel{
+		"store1": withID(model, sharedModelID),
+		"store2": withID(model, sharedModelID),
+		"store3": withID(model, ulid.Make().String()),
+		"store4": withID(otherModel, ulid.Make().String()),
+	}
+	mockDatastore.EXPECT().
+		ReadAuthorizationModel(gomock.Any(), gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, storeID, _ string) (*openfgav1.AuthorizationModel, error) {
+			return storeModels[storeID], nil
+		})
+
+	resolve := func(t *testing.T, resolver TypesystemResolverFunc, storeID string) *TypeSystem {
+		modelID := storeModels[storeID].GetId()
+		typesys, err := resolver(context.Background(), storeID, modelID)
+		require.NoError(t, err)
+		require.Equal(t, modelID, typesys.GetAuthorizationModelID())
+		return typesys
+	}
+
+	t.Run("identical_models_share_a_typesystem", func(t *testing.T) {
+		resolver, resolverStop := MemoizedTypesystemResolverFunc(mockDatastore, WithModelHashCache(10))
+		defer resolverStop()
+
+		typesys := resolve(t, resolver, "store1")
+		require.Same(t, typesys, resolve(t, resolver, "store2"))
+
+		// the identical model has another ID, so it has its own typesystem, which shares the compiled conditions
+		identical := resolve(t, resolver, "store3")
+		require.NotSame(t, typesys, identical)
+		require.Same(t, typesys.GetConditions()["non_expired"], identical.GetConditions()["non_expired"])
+
+		require.NotSame(t, typesys, resolve(t, resolver, "store4"))
+	})
+
+	t.Run("without_cache_identical_models_do_not_share", func(t *testing.T) {
+		resolver, resolverStop := MemoizedTypesystemResolverFunc(mockDatastore)
+		defer resolverStop()
+
+		require.NotSame(t, resolve(t, resolver, "store1"), resolve(t, resolver, "store2"))
+	})
+}
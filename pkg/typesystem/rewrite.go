@@ -0,0 +1,165 @@
+package typesystem
+
+import (
+	"fmt"
+	"strings"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+)
+
+// RewriteExpressionType is the kind of a node of a [RewriteExpression].
+type RewriteExpressionType string
+
+const (
+	// RewriteDirect grants the relation to the users of the tuples written for it ('[user, group#member]').
+	RewriteDirect RewriteExpressionType = "direct"
+	// RewriteComputedUserset grants the relation to the users of another relation of the same object ('viewer').
+	RewriteComputedUserset RewriteExpressionType = "computedUserset"
+	// RewriteTupleToUserset grants the relation to the users of a relation of the related objects ('viewer from parent').
+	RewriteTupleToUserset RewriteExpressionType = "tupleToUserset"
+	// RewriteUnion grants the relation to the users of any of its children ('a or b').
+	RewriteUnion RewriteExpressionType = "union"
+	// RewriteIntersection grants the relation to the users of all of its children ('a and b').
+	RewriteIntersection RewriteExpressionType = "intersection"
+	// RewriteDifference grants the relation to the users of its first child that are not users of its second child ('a but not b').
+	RewriteDifference RewriteExpressionType = "difference"
+)
+
+// RewriteExpression is a normalized and serializable representation of the rewrite of a relation.
+type RewriteExpression struct {
+	Type RewriteExpressionType `json:"type"`
+
+	// DirectlyRelatedTypes are the type restrictions of a RewriteDirect node, as written in the
+	// DSL (e.g. 'user', 'user:*', 'group#member' or 'user with condition').
+	DirectlyRelatedTypes []string `json:"directlyRelatedTypes,omitempty"`
+
+	// Relation is the relation of a RewriteComputedUserset node, or the relation computed on
+	// the related objects of a RewriteTupleToUserset node.
+	Relation string `json:"relation,omitempty"`
+
+	// Tupleset is the relation used to find the related objects of a RewriteTupleToUserset node.
+	Tupleset string `json:"tupleset,omitempty"`
+
+	// Children are the operands of a RewriteUnion, RewriteIntersection or RewriteDifference node.
+	// The children of a RewriteDifference node are always the base and the subtracted expressions,
+	// in that order.
+	Children []*RewriteExpression `json:"children,omitempty"`
+}
+
+// String returns the expression in the DSL syntax, with every operation enclosed in parentheses.
+func (r *RewriteExpression) String() string {
+	switch r.Type {
+	case RewriteDirect:
+		return "[" + strings.Join(r.DirectlyRelatedTypes, ", ") + "]"
+	case RewriteComputedUserset:
+		return r.Relation
+	case RewriteTupleToUserset:
+		return fmt.Sprintf("%s from %s", r.Relation, r.Tupleset)
+	}
+
+	operator := map[RewriteExpressionType]string{
+		RewriteUnion:        " or ",
+		RewriteIntersection: " and ",
+		RewriteDifference:   " but not ",
+	}[r.Type]
+
+	children := make([]string, 0, len(r.Children))
+	for _, child := range r.Children {
+		children = append(children, child.String())
+	}
+
+	return "(" + strings.Join(children, operator) + ")"
+}
+
+// GetRelationRewrite returns the normalized rewrite of the relation of the given object type.
+// Nested unions and intersections are flattened into their parent when it has the same operator,
+// so 'a or (b or c)' and '(a or b) or c' are both returned as a single union of 'a', 'b' and 'c'.
+func (t *TypeSystem) GetRelationRewrite(objectType, relation string) (*RewriteExpression, error) {
+	r, err := t.GetRelation(objectType, relation)
+	if err != nil {
+		return nil, err
+	}
+
+	return normalizeRewrite(r.GetRewrite(), r.GetTypeInfo().GetDirectlyRelatedUserTypes())
+}
+
+func normalizeRewrite(rewrite *openfgav1.Userset, directlyRelatedTypes []*openfgav1.RelationReference) (*RewriteExpression, error) {
+	switch rw := rewrite.GetUserset().(type) {
+	case *openfgav1.Userset_This:
+		types := make([]string, 0, len(directlyRelatedTypes))
+		for _, ref := range directlyRelatedTypes {
+			types = append(types, relationReferenceAsDSL(ref))
+		}
+
+		return &RewriteExpression{Type: RewriteDirect, DirectlyRelatedTypes: types}, nil
+	case *openfgav1.Userset_ComputedUserset:
+		return &RewriteExpression{
+			Type:     RewriteComputedUserset,
+			Relation: rw.ComputedUserset.GetRelation(),
+		}, nil
+	case *openfgav1.Userset_TupleToUserset:
+		return &RewriteExpression{
+			Type:     RewriteTupleToUserset,
+			Relation: rw.TupleToUserset.GetComputedUserset().GetRelation(),
+			Tupleset: rw.TupleToUserset.GetTupleset().GetRelation(),
+		}, nil
+	case *openfgav1.Userset_Union:
+		return normalizeSetOperation(RewriteUnion, rw.Union.GetChild(), directlyRelatedTypes)
+	case *openfgav1.Userset_Intersection:
+		return normalizeSetOperation(RewriteIntersection, rw.Intersection.GetChild(), directlyRelatedTypes)
+	case *openfgav1.Userset_Difference:
+		base, err := normalizeRewrite(rw.Difference.GetBase(), directlyRelatedTypes)
+		if err != nil {
+			return nil, err
+		}
+
+		subtract, err := normalizeRewrite(rw.Difference.GetSubtract(), directlyRelatedTypes)
+		if err != nil {
+			return nil, err
+		}
+
+		return &RewriteExpression{
+			Type:     RewriteDifference,
+			Children: []*RewriteExpression{base, subtract},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unexpected userset rewrite type encountered")
+	}
+}
+
+func normalizeSetOperation(
+	operator RewriteExpressionType,
+	children []*openfgav1.Userset,
+	directlyRelatedTypes []*openfgav1.RelationReference,
+) (*RewriteExpression, error) {
+	res := &RewriteExpression{Type: operator}
+	for _, child := range children {
+		normalized, err := normalizeRewrite(child, directlyRelatedTypes)
+		if err != nil {
+			return nil, err
+		}
+
+		if normalized.Type == operator {
+			res.Children = append(res.Children, normalized.Children...)
+			continue
+		}
+
+		res.Children = append(res.Children, normalized)
+	}
+
+	return res, nil
+}
+
+// relationReferenceAsDSL returns 'user', 'user:*', 'group#member' or 'user with condition'.
+func relationReferenceAsDSL(ref *openfgav1.RelationReference) string {
+	res := ref.GetType()
+	if ref.GetRelationOrWildcard() != nil {
+		res = GetRelationReferenceAsString(ref)
+	}
+
+	if ref.GetCondition() != "" {
+		res += " with " + ref.GetCondition()
+	}
+
+	return res
+}
@@ -0,0 +1,122 @@
+package typesystem
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/openfga/openfga/pkg/testutils"
+)
+
+func TestGetRelationRewrite(t *testing.T) {
+	typesys := New(testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type group
+			relations
+				define member: [user, user:*]
+		type folder
+			relations
+				define viewer: [user, group#member]
+		type document
+			relations
+				define parent: [folder]
+				define blocked: [user with non_expired]
+				define owner: [user]
+				define editor: [user] or owner
+				define viewer: (editor or viewer from parent) or owner
+				define can_view: (owner or viewer from parent) but not blocked
+				define can_share: owner and (editor or viewer from parent)
+
+		condition non_expired(expired: bool) {
+			!expired
+		}`))
+
+	t.Run("difference_with_tuple_to_userset", func(t *testing.T) {
+		rewrite, err := typesys.GetRelationRewrite("document", "can_view")
+		require.NoError(t, err)
+
+		require.Equal(t, &RewriteExpression{
+			Type: RewriteDifference,
+			Children: []*RewriteExpression{
+				{
+					Type: RewriteUnion,
+					Children: []*RewriteExpression{
+						{Type: RewriteComputedUserset, Relation: "owner"},
+						{Type: RewriteTupleToUserset, Relation: "viewer", Tupleset: "parent"},
+					},
+				},
+				{Type: RewriteComputedUserset, Relation: "blocked"},
+			},
+		}, rewrite)
+		require.Equal(t, "((owner or viewer from parent) but not blocked)", rewrite.String())
+	})
+
+	t.Run("nested_unions_are_flattened", func(t *testing.T) {
+		rewrite, err := typesys.GetRelationRewrite("document", "viewer")
+		require.NoError(t, err)
+
+		require.Equal(t, &RewriteExpression{
+			Type: RewriteUnion,
+			Children: []*RewriteExpression{
+				{Type: RewriteComputedUserset, Relation: "editor"},
+				{Type: RewriteTupleToUserset, Relation: "viewer", Tupleset: "parent"},
+				{Type: RewriteComputedUserset, Relation: "owner"},
+			},
+		}, rewrite)
+		require.Equal(t, "(editor or viewer from parent or owner)", rewrite.String())
+	})
+
+	t.Run("intersection_of_union", func(t *testing.T) {
+		rewrite, err := typesys.GetRelationRewrite("document", "can_share")
+		require.NoError(t, err)
+		require.Equal(t, "(owner and (editor or viewer from parent))", rewrite.String())
+	})
+
+	t.Run("direct_type_restrictions", func(t *testing.T) {
+		rewrite, err := typesys.GetRelationRewrite("document", "editor")
+		require.NoError(t, err)
+		require.Equal(t, "([user] or owner)", rewrite.String())
+
+		rewrite, err = typesys.GetRelationRewrite("group", "member")
+		require.NoError(t, err)
+		require.Equal(t, &RewriteExpression{Type: RewriteDirect, DirectlyRelatedTypes: []string{"user", "user:*"}}, rewrite)
+
+		rewrite, err = typesys.GetRelationRewrite("folder", "viewer")
+		require.NoError(t, err)
+		require.Equal(t, []string{"user", "group#member"}, rewrite.DirectlyRelatedTypes)
+
+		rewrite, err = typesys.GetRelationRewrite("document", "blocked")
+		require.NoError(t, err)
+		require.Equal(t, []string{"user with non_expired"}, rewrite.DirectlyRelatedTypes)
+	})
+
+	t.Run("serializable", func(t *testing.T) {
+		rewrite, err := typesys.GetRelationRewrite("document", "can_view")
+		require.NoError(t, err)
+
+		bytes, err := json.Marshal(rewrite)
+		require.NoError(t, err)
+		require.JSONEq(t, `{
+			"type": "difference",
+			"children": [
+				{"type": "union", "children": [
+					{"type": "computedUserset", "relation": "owner"},
+					{"type": "tupleToUserset", "relation": "viewer", "tupleset": "parent"}
+				]},
+				{"type": "computedUserset", "relation": "blocked"}
+			]
+		}`, string(bytes))
+	})
+
+	t.Run("undefined_relation", func(t *testing.T) {
+		_, err := typesys.GetRelationRewrite("document", "undefined")
+		require.ErrorIs(t, err, ErrRelationUndefined)
+
+		_, err = typesys.GetRelationRewrite("undefined", "viewer")
+		require.ErrorIs(t, err, ErrObjectTypeUndefined)
+	})
+}
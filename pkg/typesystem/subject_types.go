@@ -0,0 +1,150 @@
+package typesystem
+
+import (
+	"fmt"
+	"sort"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+
+	"github.com/openfga/openfga/pkg/tuple"
+)
+
+// SubjectTypes returns the sorted types of the users that may have the relation with an object of the type,
+// through any of the rewrites the relation depends on: object types (e.g. 'user'), typed wildcards ('user:*')
+// and usersets ('group#member'). The usersets include the relation itself and the relations it is computed from.
+// The types are an over-approximation: the subtracted side of an exclusion is ignored, and every side of an
+// intersection is considered.
+func (t *TypeSystem) SubjectTypes(objectType, relation string) ([]string, error) {
+	if _, err := t.GetRelation(objectType, relation); err != nil {
+		return nil, err
+	}
+
+	types := map[string]struct{}{}
+	if err := t.collectSubjectTypes(objectType, relation, types); err != nil {
+		return nil, err
+	}
+
+	res := make([]string, 0, len(types))
+	for subjectType := range types {
+		res = append(res, subjectType)
+	}
+	sort.Strings(res)
+
+	return res, nil
+}
+
+func (t *TypeSystem) collectSubjectTypes(objectType, relation string, types map[string]struct{}) error {
+	userset := tuple.ToObjectRelationString(objectType, relation)
+	if _, ok := types[userset]; ok {
+		return nil
+	}
+	types[userset] = struct{}{}
+
+	r, err := t.GetRelation(objectType, relation)
+	if err != nil {
+		return err
+	}
+
+	return t.collectRewriteSubjectTypes(objectType, r, r.GetRewrite(), types)
+}
+
+func (t *TypeSystem) collectRewriteSubjectTypes(objectType string, r *openfgav1.Relation, rewrite *openfgav1.Userset, types map[string]struct{}) error {
+	switch rw := rewrite.GetUserset().(type) {
+	case *openfgav1.Userset_This:
+		for _, ref := range r.GetTypeInfo().GetDirectlyRelatedUserTypes() {
+			switch {
+			case ref.GetRelation() != "":
+				if err := t.collectSubjectTypes(ref.GetType(), ref.GetRelation(), types); err != nil {
+					return err
+				}
+			case ref.GetWildcard() != nil:
+				types[tuple.TypedPublicWildcard(ref.GetType())] = struct{}{}
+			default:
+				types[ref.GetType()] = struct{}{}
+			}
+		}
+	case *openfgav1.Userset_ComputedUserset:
+		return t.collectSubjectTypes(objectType, rw.ComputedUserset.GetRelation(), types)
+	case *openfgav1.Userset_TupleToUserset:
+		relatedTypes, err := t.GetDirectlyRelatedUserTypes(objectType, rw.TupleToUserset.GetTupleset().GetRelation())
+		if err != nil {
+			return err
+		}
+
+		computedRelation := rw.TupleToUserset.GetComputedUserset().GetRelation()
+		for _, relatedType := range relatedTypes {
+			// the computed relation only has to be defined on some of the related types
+			if _, err := t.GetRelation(relatedType.GetType(), computedRelation); err != nil {
+				continue
+			}
+
+			if err := t.collectSubjectTypes(relatedType.GetType(), computedRelation, types); err != nil {
+				return err
+			}
+		}
+	case *openfgav1.Userset_Union:
+		for _, child := range rw.Union.GetChild() {
+			if err := t.collectRewriteSubjectTypes(objectType, r, child, types); err != nil {
+				return err
+			}
+		}
+	case *openfgav1.Userset_Intersection:
+		for _, child := range rw.Intersection.GetChild() {
+			if err := t.collectRewriteSubjectTypes(objectType, r, child, types); err != nil {
+				return err
+			}
+		}
+	case *openfgav1.Userset_Difference:
+		return t.collectRewriteSubjectTypes(objectType, r, rw.Difference.GetBase(), types)
+	default:
+		return fmt.Errorf("unexpected userset rewrite type encountered")
+	}
+
+	return nil
+}
+
+// ValidateSubjectType returns an *InvalidSubjectTypeError if the user (e.g. 'user:jon', 'user:*' or 'group:eng#member')
+// is of a type that can never have the relation with an object of the type, see SubjectTypes. An object user is valid
+// if its type, or the typed wildcard of its type, is a subject type. Models of schema 1.0, which do not declare the
+// types related to their relations, accept any user.
+func (t *TypeSystem) ValidateSubjectType(objectType, relation, user string) error {
+	if t.GetSchemaVersion() == SchemaVersion1_0 {
+		return nil
+	}
+
+	subjectTypes, err := t.SubjectTypes(objectType, relation)
+	if err != nil {
+		return err
+	}
+
+	valid := map[string]struct{}{}
+	for _, subjectType := range subjectTypes {
+		valid[subjectType] = struct{}{}
+	}
+
+	userObject, userRelation := tuple.SplitObjectRelation(user)
+	userType := tuple.GetType(userObject)
+
+	subjectType := userType
+	candidates := []string{userType, tuple.TypedPublicWildcard(userType)}
+	switch {
+	case userRelation != "":
+		subjectType = tuple.ToObjectRelationString(userType, userRelation)
+		candidates = []string{subjectType}
+	case tuple.IsTypedWildcard(userObject):
+		subjectType = userObject
+	}
+
+	for _, candidate := range candidates {
+		if _, ok := valid[candidate]; ok {
+			return nil
+		}
+	}
+
+	return &InvalidSubjectTypeError{
+		ObjectType:    objectType,
+		Relation:      relation,
+		SubjectType:   subjectType,
+		ExpectedTypes: subjectTypes,
+	}
+}
@@ -0,0 +1,84 @@
+package typesystem
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/openfga/openfga/pkg/testutils"
+)
+
+func TestSubjectTypes(t *testing.T) {
+	typesys := New(testutils.MustTransformDSLToProtoWithID(`
+		model
+			schema 1.1
+
+		type user
+		type employee
+		type widget
+		type group
+			relations
+				define member: [user, group#member]
+		type folder
+			relations
+				define viewer: [employee, user:*]
+		type document
+			relations
+				define parent: [folder]
+				define blocked: [widget]
+				define owner: [user]
+				define editor: [group#member] or owner
+				define viewer: (editor or viewer from parent) but not blocked`))
+
+	subjectTypes, err := typesys.SubjectTypes("document", "viewer")
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"document#editor",
+		"document#owner",
+		"document#viewer",
+		"employee",
+		"folder#viewer",
+		"group#member",
+		"user",
+		"user:*",
+	}, subjectTypes)
+
+	_, err = typesys.SubjectTypes("document", "undefined")
+	require.ErrorIs(t, err, ErrRelationUndefined)
+
+	tests := []struct {
+		name  string
+		user  string
+		valid bool
+	}{
+		{name: "direct_type", user: "user:jon", valid: true},
+		{name: "type_through_tuple_to_userset", user: "employee:1", valid: true},
+		{name: "typed_wildcard", user: "user:*", valid: true},
+		{name: "userset", user: "group:eng#member", valid: true},
+		{name: "relation_itself", user: "document:2#viewer", valid: true},
+		{name: "subtracted_type", user: "widget:1"},
+		{name: "type_without_relation", user: "folder:1"},
+		{name: "undeclared_userset", user: "group:eng#undefined"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := typesys.ValidateSubjectType("document", "viewer", test.user)
+			if test.valid {
+				require.NoError(t, err)
+				return
+			}
+
+			require.ErrorIs(t, err, ErrInvalidSubjectType)
+
+			var invalidSubjectTypeErr *InvalidSubjectTypeError
+			require.ErrorAs(t, err, &invalidSubjectTypeErr)
+			require.Equal(t, subjectTypes, invalidSubjectTypeErr.ExpectedTypes)
+		})
+	}
+
+	t.Run("error_names_the_expected_types", func(t *testing.T) {
+		err := typesys.ValidateSubjectType("document", "owner", "widget:1")
+		require.EqualError(t, err, "invalid subject type: 'widget' cannot have the relation 'document#owner', expected one of: document#owner, user")
+	})
+}
@@ -8,6 +8,7 @@ import (
 	"reflect"
 	"sort"
 
+	"github.com/google/cel-go/cel"
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	"go.opentelemetry.io/otel"
 
@@ -169,9 +170,29 @@ type TypeSystem struct {
 	schemaVersion string
 }
 
+// TypeSystemOption configures the construction of a *TypeSystem.
+type TypeSystemOption func(*typeSystemOptions)
+
+type typeSystemOptions struct {
+	conditionEnv *cel.Env
+}
+
+// WithConditionEnv sets the CEL environment the conditions of the model are compiled in, e.g. to make
+// the custom functions of a store available to its conditions. See [condition.NewEnv].
+func WithConditionEnv(env *cel.Env) TypeSystemOption {
+	return func(o *typeSystemOptions) {
+		o.conditionEnv = env
+	}
+}
+
 // New creates a *TypeSystem from an *openfgav1.AuthorizationModel.
 // It assumes that the input model is valid. If you need to run validations, use NewAndValidate.
-func New(model *openfgav1.AuthorizationModel) *TypeSystem {
+func New(model *openfgav1.AuthorizationModel, opts ...TypeSystemOption) *TypeSystem {
+	var options typeSystemOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	tds := make(map[string]*openfgav1.TypeDefinition, len(model.GetTypeDefinitions()))
 	relations := make(map[string]map[string]*openfgav1.Relation, len(model.GetTypeDefinitions()))
 	ttuRelations := make(map[string]map[string][]*openfgav1.TupleToUserset, len(model.GetTypeDefinitions()))
@@ -204,6 +225,7 @@ func New(model *openfgav1.AuthorizationModel) *TypeSystem {
 	uncompiledConditions := make(map[string]*condition.EvaluableCondition, len(model.GetConditions()))
 	for name, cond := range model.GetConditions() {
 		uncompiledConditions[name] = condition.NewUncompiled(cond).
+			WithEnv(options.conditionEnv).
 			WithTrackEvaluationCost().
 			WithMaxEvaluationCost(config.MaxConditionEvaluationCost()).
 			WithInterruptCheckFrequency(config.DefaultInterruptCheckFrequency)
@@ -225,6 +247,18 @@ func (t *TypeSystem) GetAuthorizationModelID() string {
 	return t.modelID
 }
 
+// withAuthorizationModelID returns a TypeSystem for the model with the ID, sharing the definitions and
+// the compiled conditions of t, which must have been constructed for a semantically identical model.
+func (t *TypeSystem) withAuthorizationModelID(modelID string) *TypeSystem {
+	if t.modelID == modelID {
+		return t
+	}
+
+	clone := *t
+	clone.modelID = modelID
+	return &clone
+}
+
 // GetSchemaVersion returns the schema version associated with the TypeSystem instance.
 func (t *TypeSystem) GetSchemaVersion() string {
 	return t.schemaVersion
@@ -724,11 +758,11 @@ func hasEntrypoints(
 //     a) For a type (e.g. user) this means checking that this type is in the *TypeSystem
 //     b) For a type#relation this means checking that this type with this relation is in the *TypeSystem
 //  4. Check that a relation is assignable if and only if it has a non-zero list of types
-func NewAndValidate(ctx context.Context, model *openfgav1.AuthorizationModel) (*TypeSystem, error) {
+func NewAndValidate(ctx context.Context, model *openfgav1.AuthorizationModel, opts ...TypeSystemOption) (*TypeSystem, error) {
 	_, span := tracer.Start(ctx, "typesystem.NewAndValidate")
 	defer span.End()
 
-	t := New(model)
+	t := New(model, opts...)
 	schemaVersion := t.GetSchemaVersion()
 
 	if !IsSchemaVersionSupported(schemaVersion) {